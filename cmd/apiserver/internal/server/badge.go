@@ -0,0 +1,96 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/badge"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitutil/normalize"
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/openapi"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/emicklei/go-restful"
+)
+
+const (
+	MIME_SVG = "image/svg+xml"
+	// BADGE_MAX_AGE is the seconds badges are cached by browsers and
+	// image proxies, scores change once per run
+	BADGE_MAX_AGE = 3600
+)
+
+func registerBadgeRoutes(service *restful.WebService) {
+	service.Route(service.GET("/badge").To(getBadge).
+		Doc("svg badge of the criticality score and the rank of a project, to embed in its README").
+		Produces(MIME_SVG).
+		Metadata(openapi.KeyTags, []string{"projects"}).
+		Param(service.QueryParameter("link", "git link of the project").Required(true)).
+		Param(service.QueryParameter("label", "label of the badge").DefaultValue("criticality")).
+		Returns(http.StatusBadRequest, "missing link", nil))
+}
+
+// badgeColor returns the color of a project by its percentile, the most
+// critical projects are red.
+func badgeColor(percentile *float64) string {
+	switch {
+	case percentile == nil:
+		return badge.ColorBlue
+	case *percentile >= 99:
+		return badge.ColorRed
+	case *percentile >= 90:
+		return badge.ColorOrange
+	case *percentile >= 50:
+		return badge.ColorYellow
+	default:
+		return badge.ColorGreen
+	}
+}
+
+// scoreBadge returns the badge of the latest score of a project, a grey
+// badge if it is not ranked.
+func scoreBadge(ac storage.AppDatabaseContext, link, label string) (badge.Badge, error) {
+	b := badge.Badge{Label: label, Message: "not ranked", Color: badge.ColorLightGrey}
+	repo := repository.NewScoreRepository(ac)
+	score, err := repo.GetByGitLink(link)
+	if err != nil || score == nil || score.Score == nil {
+		return b, err
+	}
+	rank, _, err := repo.GetRank(link)
+	if err != nil {
+		return b, err
+	}
+	b.Message = fmt.Sprintf("%.2f", *score.Score)
+	if rank > 0 {
+		b.Message += fmt.Sprintf(" #%d", rank)
+	}
+	b.Color = badgeColor(score.Percentile)
+	return b, nil
+}
+
+func getBadge(request *restful.Request, response *restful.Response) {
+	link := request.QueryParameter("link")
+	if link == "" {
+		response.WriteErrorString(http.StatusBadRequest, "Missing link parameter")
+		return
+	}
+	// badges are linked by hand, e.g. with .git or in another case
+	if normalized, err := normalize.URL(link); err == nil {
+		link = normalized
+	}
+	label := request.QueryParameter("label")
+	if label == "" {
+		label = "criticality"
+	}
+
+	b, err := scoreBadge(storage.GetDefaultReadOnlyAppDatabaseContext(), link, label)
+	if err != nil {
+		response.WriteErrorString(http.StatusInternalServerError, "Fetch data error")
+		logger.Info(err)
+		return
+	}
+	response.AddHeader("Content-Type", MIME_SVG)
+	response.AddHeader("Cache-Control", fmt.Sprintf("public, max-age=%d", BADGE_MAX_AGE))
+	response.WriteHeader(http.StatusOK)
+	response.Write(b.SVG())
+}
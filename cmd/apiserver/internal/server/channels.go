@@ -0,0 +1,61 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/gitutil/normalize"
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/openapi"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/emicklei/go-restful"
+)
+
+type distributionChannelsVO struct {
+	Link string `json:"link"`
+	// Channels are the language ecosystems and the distributions
+	// distributing the project, and release if its releases have binary
+	// assets
+	Channels   []string   `json:"channels"`
+	Breadth    int        `json:"breadth"`
+	UpdateTime *time.Time `json:"updateTime"`
+}
+
+func registerChannelRoutes(service *restful.WebService) {
+	service.Route(service.GET("/channels").To(getDistributionChannels).
+		Doc("the package managers and distributions distributing a project, by the packages collected").
+		Metadata(openapi.KeyTags, []string{"projects"}).
+		Param(service.QueryParameter("link", "git link of the project").Required(true)).
+		Writes(distributionChannelsVO{}).
+		Returns(http.StatusBadRequest, "missing link", nil).
+		Returns(http.StatusNotFound, "project not distributed by any channel", nil))
+}
+
+func getDistributionChannels(request *restful.Request, response *restful.Response) {
+	link := request.QueryParameter("link")
+	if link == "" {
+		response.WriteErrorString(http.StatusBadRequest, "Missing link parameter")
+		return
+	}
+	if normalized, err := normalize.URL(link); err == nil {
+		link = normalized
+	}
+
+	dc, err := repository.NewMaterializedViewRepository(storage.GetDefaultReadOnlyAppDatabaseContext()).GetDistributionChannels(link)
+	if err != nil {
+		response.WriteErrorString(http.StatusInternalServerError, "Fetch data error")
+		logger.Info(err)
+		return
+	}
+	if dc == nil {
+		response.WriteErrorString(http.StatusNotFound, "No distribution channel")
+		return
+	}
+	response.WriteAsJson(distributionChannelsVO{
+		Link:       link,
+		Channels:   deref(dc.Channels).Names(),
+		Breadth:    deref(dc.Breadth),
+		UpdateTime: dc.UpdateTime,
+	})
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/ratelimit"
+	"github.com/HUSTSecLab/criticality_score/pkg/respcache"
+	"github.com/emicklei/go-restful"
+)
+
+// cacheKey is the key of the cached response of a request.
+func cacheKey(request *restful.Request) string {
+	return request.Request.URL.RequestURI()
+}
+
+// rateLimitFilter limits the requests of every client IP, cached responses
+// do not query the database, so they are not limited, e.g. badges fetched
+// by image proxies for many pages.
+func rateLimitFilter(request *restful.Request, response *restful.Response, chain *restful.FilterChain) {
+	if request.Request.Method == http.MethodGet && respcache.Default().Cached(cacheKey(request)) {
+		chain.ProcessFilter(request, response)
+		return
+	}
+	if ok, wait := ratelimit.Default().AllowRequest(request.Request); !ok {
+		response.AddHeader("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		response.WriteErrorString(http.StatusTooManyRequests, "Too many requests")
+		return
+	}
+	chain.ProcessFilter(request, response)
+}
+
+// recorder records a response to cache it.
+type recorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) Header() http.Header         { return r.header }
+func (r *recorder) Write(b []byte) (int, error) { return r.body.Write(b) }
+func (r *recorder) WriteHeader(status int)      { r.status = status }
+func (r *recorder) Flush()                      {}
+
+// cacheFilter serves GET requests from the response cache, with ETag and
+// Cache-Control headers, so CDNs and browsers revalidate responses instead
+// of fetching them again.
+func cacheFilter(request *restful.Request, response *restful.Response, chain *restful.FilterChain) {
+	if request.Request.Method != http.MethodGet {
+		chain.ProcessFilter(request, response)
+		return
+	}
+
+	cache := respcache.Default()
+	w := response.ResponseWriter
+	entry, hit, err := cache.Get(cacheKey(request), func() (*respcache.Entry, error) {
+		rec := &recorder{header: make(http.Header), status: http.StatusOK}
+		response.ResponseWriter = rec
+		defer func() { response.ResponseWriter = w }()
+		chain.ProcessFilter(request, response)
+		return &respcache.Entry{StatusCode: rec.status, Header: rec.header, Body: rec.body.Bytes()}, nil
+	})
+	if err != nil {
+		logger.Errorf("Failed to serve %s: %v", request.Request.URL, err)
+		http.Error(w, "Fetch data error", http.StatusInternalServerError)
+		return
+	}
+
+	header := w.Header()
+	for k, v := range entry.Header {
+		header[k] = v
+	}
+	if hit {
+		header.Set("X-Cache", "HIT")
+	} else {
+		header.Set("X-Cache", "MISS")
+	}
+	if entry.StatusCode != http.StatusOK {
+		w.WriteHeader(entry.StatusCode)
+		w.Write(entry.Body)
+		return
+	}
+
+	// routes may set their own max-age, e.g. badges
+	if header.Get("Cache-Control") == "" {
+		if ttl := cache.TTL(); ttl > 0 {
+			header.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(ttl.Seconds())))
+		} else {
+			header.Set("Cache-Control", "no-cache")
+		}
+	}
+	header.Set("ETag", entry.ETag)
+	if etagMatch(request.Request.Header.Get("If-None-Match"), entry.ETag) {
+		header.Del("Content-Type")
+		header.Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(entry.Body)
+}
+
+// etagMatch returns whether the If-None-Match header matches etag.
+func etagMatch(ifNoneMatch, etag string) bool {
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag || tag == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/freshness"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitutil/normalize"
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/openapi"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/emicklei/go-restful"
+)
+
+type signalVO struct {
+	Signal      string     `json:"signal"`
+	Source      *string    `json:"source"`
+	CollectedAt *time.Time `json:"collectedAt"`
+	Confidence  *float64   `json:"confidence"`
+	// Discount is by the default decay policy, the stored scores may be
+	// computed with another one
+	Discount float64 `json:"discount"`
+}
+
+type freshnessVO struct {
+	GitLink         string     `json:"link"`
+	Score           *float64   `json:"score"`
+	GitScore        *float64   `json:"gitScore"`
+	LangEcoScore    *float64   `json:"langEcoScore"`
+	GitDiscount     *float64   `json:"gitDiscount"`
+	LangEcoDiscount *float64   `json:"langEcoDiscount"`
+	UpdateTime      *time.Time `json:"updateTime"`
+	Signals         []signalVO `json:"signals"`
+}
+
+func registerFreshnessRoutes(service *restful.WebService) {
+	service.Route(service.GET("/freshness").To(getFreshness).
+		Doc("source, collection time and confidence of every signal of a project, with the discounts of its latest score").
+		Metadata(openapi.KeyTags, []string{"projects"}).
+		Param(service.QueryParameter("link", "git link of the project").Required(true)).
+		Writes(freshnessVO{}).
+		Returns(http.StatusBadRequest, "missing link", nil).
+		Returns(http.StatusNotFound, "unknown project", nil))
+}
+
+func getFreshness(request *restful.Request, response *restful.Response) {
+	link := request.QueryParameter("link")
+	if link == "" {
+		response.WriteErrorString(http.StatusBadRequest, "Missing link parameter")
+		return
+	}
+	if normalized, err := normalize.URL(link); err == nil {
+		link = normalized
+	}
+
+	ac := storage.GetDefaultReadOnlyAppDatabaseContext()
+	vo := freshnessVO{GitLink: link, Signals: make([]signalVO, 0)}
+	score, err := repository.NewScoreRepository(ac).GetByGitLink(link)
+	if err != nil {
+		response.WriteErrorString(http.StatusInternalServerError, "Fetch data error")
+		logger.Info(err)
+		return
+	}
+	if score != nil {
+		vo.Score, vo.GitScore, vo.LangEcoScore = score.Score, score.GitScore, score.DevScore
+		vo.GitDiscount, vo.LangEcoDiscount = score.GitDiscount, score.LangEcoDiscount
+		vo.UpdateTime = score.UpdateTime
+	}
+
+	provs, err := repository.NewSignalProvenanceRepository(ac).QueryByLink(link)
+	if err != nil {
+		response.WriteErrorString(http.StatusInternalServerError, "Fetch data error")
+		logger.Info(err)
+		return
+	}
+	now := time.Now()
+	for p := range provs {
+		vo.Signals = append(vo.Signals, signalVO{
+			Signal:      string(*p.Signal),
+			Source:      p.Source,
+			CollectedAt: p.CollectedAt,
+			Confidence:  p.Confidence,
+			Discount:    freshness.DefaultPolicy.Discount(p, now),
+		})
+	}
+	if score == nil && len(vo.Signals) == 0 {
+		response.WriteErrorString(http.StatusNotFound, "Unknown project")
+		return
+	}
+	response.WriteAsJson(vo)
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/emicklei/go-restful"
+)
+
+// newFreshnessServer serves the freshness route from a mocked database.
+func newFreshnessServer(t *testing.T) (*httptest.Server, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	storage.InitDefaultDatabaseContext(&storage.Config{})
+	storage.InitDefaultReadOnlyDatabaseContext(nil)
+	storage.WrapDefaultDatabaseContext(func(storage.AppDatabaseContext) storage.AppDatabaseContext {
+		return storage.NewAppDatabaseWithDb(db)
+	})
+
+	service := new(restful.WebService)
+	service.Path("/" + SERVICE_VERSION).Produces(restful.MIME_JSON)
+	registerFreshnessRoutes(service)
+	container := restful.NewContainer()
+	container.Add(service)
+
+	srv := httptest.NewServer(container)
+	t.Cleanup(srv.Close)
+	return srv, mock
+}
+
+func TestGetFreshness(t *testing.T) {
+	srv, mock := newFreshnessServer(t)
+	link := "https://github.com/madler/zlib"
+	collectedAt := time.Date(2025, 1, 30, 0, 0, 0, 0, time.UTC)
+
+	// the link is normalized, as it is given by hand
+	mock.ExpectQuery("FROM scores").WithArgs(link).
+		WillReturnRows(sqlmock.NewRows([]string{"git_link", "score", "git_discount"}).AddRow(link, 0.8, 0.9))
+	mock.ExpectQuery("FROM signal_provenance").WithArgs(link).
+		WillReturnRows(sqlmock.NewRows([]string{"git_link", "signal", "source", "confidence", "collected_at"}).
+			AddRow(link, "git_metadata", "git-clone", 1.0, collectedAt))
+
+	var vo freshnessVO
+	if code := get(t, srv, "/freshness?link=http://github.com/madler/zlib.git", &vo); code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+	if vo.GitLink != link || vo.Score == nil || *vo.Score != 0.8 || vo.GitDiscount == nil || *vo.GitDiscount != 0.9 {
+		t.Errorf("freshness = %+v", vo)
+	}
+	if len(vo.Signals) != 1 || vo.Signals[0].Signal != "git_metadata" || vo.Signals[0].Source == nil ||
+		*vo.Signals[0].Source != "git-clone" || vo.Signals[0].CollectedAt == nil || !vo.Signals[0].CollectedAt.Equal(collectedAt) {
+		t.Errorf("signals = %+v", vo.Signals)
+	}
+
+	// a project without a score nor signals is unknown
+	mock.ExpectQuery("FROM scores").WithArgs("https://github.com/madler/unknown").
+		WillReturnRows(sqlmock.NewRows([]string{"git_link"}))
+	mock.ExpectQuery("FROM signal_provenance").WithArgs("https://github.com/madler/unknown").
+		WillReturnRows(sqlmock.NewRows([]string{"git_link"}))
+	if code := get(t, srv, "/freshness?link=https://github.com/madler/unknown", nil); code != http.StatusNotFound {
+		t.Errorf("status of an unknown project = %d, want 404", code)
+	}
+
+	if code := get(t, srv, "/freshness", nil); code != http.StatusBadRequest {
+		t.Errorf("status without link = %d, want 400", code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/graph"
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/openapi"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/emicklei/go-restful"
+)
+
+const DEFAULT_REACHABILITY_DEPTH = 16
+
+type dependentVO struct {
+	Package string `json:"package"`
+	Depth   int    `json:"depth"`
+}
+
+type dependentsVO struct {
+	Total int           `json:"total"`
+	Data  []dependentVO `json:"data"`
+}
+
+type dependencyPathVO struct {
+	Path []string `json:"path"`
+}
+
+func registerGraphRoutes(service *restful.WebService) {
+	service.Route(service.GET("/dist/{dist}/dependents").To(getDependents).
+		Doc("transitive reverse dependencies of a package").
+		Metadata(openapi.KeyTags, []string{"distributions"}).
+		Param(service.PathParameter("dist", "distribution, e.g. debian")).
+		Param(service.QueryParameter("package", "package name").Required(true)).
+		Param(service.QueryParameter("maxDepth", "max depth").DataType("integer").DefaultValue(strconv.Itoa(DEFAULT_REACHABILITY_DEPTH))).
+		Writes(dependentsVO{}).
+		Returns(http.StatusBadRequest, "missing package or invalid maxDepth", nil).
+		Returns(http.StatusNotFound, "unknown distribution", nil))
+
+	service.Route(service.GET("/dist/{dist}/path").To(getDependencyPath).
+		Doc("shortest dependency path between two packages").
+		Metadata(openapi.KeyTags, []string{"distributions"}).
+		Param(service.PathParameter("dist", "distribution, e.g. debian")).
+		Param(service.QueryParameter("from", "the depending package").Required(true)).
+		Param(service.QueryParameter("to", "the depended package").Required(true)).
+		Param(service.QueryParameter("maxDepth", "max depth").DataType("integer").DefaultValue(strconv.Itoa(DEFAULT_REACHABILITY_DEPTH))).
+		Writes(dependencyPathVO{}).
+		Returns(http.StatusBadRequest, "missing from or to, or invalid maxDepth", nil).
+		Returns(http.StatusNotFound, "unknown distribution or no dependency path", nil))
+}
+
+// parseGraphParams parses the dist path parameter and the maxDepth query
+// parameter, it writes the error response and returns false if invalid.
+func parseGraphParams(request *restful.Request, response *restful.Response) (repository.DistPackageTablePrefix, int, bool) {
+	dist, ok := repository.ParseDistPackageTablePrefix(request.PathParameter("dist"))
+	if !ok {
+		response.WriteErrorString(http.StatusNotFound, "Unknown distribution")
+		return "", 0, false
+	}
+
+	maxDepth := DEFAULT_REACHABILITY_DEPTH
+	if s := request.QueryParameter("maxDepth"); s != "" {
+		d, err := strconv.Atoi(s)
+		if err != nil || d <= 0 || d > repository.MaxReachabilityDepth {
+			response.WriteErrorString(http.StatusBadRequest, "Invalid maxDepth parameter")
+			return "", 0, false
+		}
+		maxDepth = d
+	}
+	return dist, maxDepth, true
+}
+
+func getDependents(request *restful.Request, response *restful.Response) {
+	dist, maxDepth, ok := parseGraphParams(request, response)
+	if !ok {
+		return
+	}
+
+	pkg := request.QueryParameter("package")
+	if pkg == "" {
+		response.WriteErrorString(http.StatusBadRequest, "Missing package parameter")
+		return
+	}
+
+	data, err := queryDependents(dist, pkg, maxDepth)
+	if err != nil {
+		response.WriteErrorString(http.StatusInternalServerError, "Fetch data error")
+		logger.Info(err)
+		return
+	}
+
+	vo := dependentsVO{Data: data}
+	vo.Total = len(vo.Data)
+
+	response.WriteAsJson(vo)
+}
+
+func getDependencyPath(request *restful.Request, response *restful.Response) {
+	dist, maxDepth, ok := parseGraphParams(request, response)
+	if !ok {
+		return
+	}
+
+	from, to := request.QueryParameter("from"), request.QueryParameter("to")
+	if from == "" || to == "" {
+		response.WriteErrorString(http.StatusBadRequest, "Missing from or to parameter")
+		return
+	}
+
+	path, err := queryDependencyPath(dist, from, to, maxDepth)
+	if err != nil {
+		response.WriteErrorString(http.StatusInternalServerError, "Fetch data error")
+		logger.Info(err)
+		return
+	}
+	if path == nil {
+		response.WriteErrorString(http.StatusNotFound, "No dependency path found")
+		return
+	}
+
+	response.WriteAsJson(dependencyPathVO{Path: path})
+}
+
+// queryDependents returns the transitive reverse dependencies of pkg, from
+// the shared graph if the graph service is enabled, otherwise from the
+// database.
+func queryDependents(dist repository.DistPackageTablePrefix, pkg string, maxDepth int) ([]dependentVO, error) {
+	data := make([]dependentVO, 0)
+	if s := graph.Default(); s != nil {
+		g, err := s.Get(dist)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range g.ReverseClosure(pkg, maxDepth) {
+			data = append(data, dependentVO{Package: r.Name, Depth: r.Depth})
+		}
+		return data, nil
+	}
+
+	repo := repository.NewDistRelationshipRepository(storage.GetDefaultReadOnlyAppDatabaseContext(), dist)
+	result, err := repo.QueryReverseClosure(pkg, maxDepth)
+	if err != nil {
+		return nil, err
+	}
+	for r := range result {
+		data = append(data, dependentVO{Package: *r.Package, Depth: *r.Depth})
+	}
+	return data, nil
+}
+
+// queryDependencyPath returns the shortest dependency path, like
+// queryDependents it prefers the shared graph.
+func queryDependencyPath(dist repository.DistPackageTablePrefix, from, to string, maxDepth int) ([]string, error) {
+	if s := graph.Default(); s != nil {
+		g, err := s.Get(dist)
+		if err != nil {
+			return nil, err
+		}
+		return g.ShortestPath(from, to, maxDepth), nil
+	}
+
+	repo := repository.NewDistRelationshipRepository(storage.GetDefaultReadOnlyAppDatabaseContext(), dist)
+	return repo.GetShortestPath(from, to, maxDepth)
+}
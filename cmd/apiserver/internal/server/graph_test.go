@@ -0,0 +1,143 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/graph"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/emicklei/go-restful"
+)
+
+// newGraphServer serves the graph routes, the graph of debian is served
+// from a file by the graph service, so no database is needed.
+func newGraphServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	g := graph.New()
+	g.AddEdge("bash", "libc6")
+	g.AddEdge("vim", "libc6")
+	g.AddEdge("vim", "libtinfo")
+	g.AddEdge("libtinfo", "libc6")
+	g.AddEdge("app", "vim")
+
+	dir := t.TempDir()
+	if err := graph.NewCompact(g).WriteFile(filepath.Join(dir, string(repository.DistLinkTablePrefixDebian)+".graph")); err != nil {
+		t.Fatal(err)
+	}
+	graph.InitDefault(nil, &graph.ServiceConfig{Enabled: true, Dir: dir})
+	t.Cleanup(func() { graph.InitDefault(nil, nil) })
+
+	service := new(restful.WebService)
+	service.Path("/" + SERVICE_VERSION).Produces(restful.MIME_JSON)
+	registerGraphRoutes(service)
+	container := restful.NewContainer()
+	container.Add(service)
+
+	srv := httptest.NewServer(container)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// get requests path and decodes the response into v if it is 200.
+func get(t *testing.T, srv *httptest.Server, path string, v any) int {
+	t.Helper()
+	resp, err := http.Get(srv.URL + "/" + SERVICE_VERSION + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK && v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("decoding %s: %v", path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestGetDependents(t *testing.T) {
+	srv := newGraphServer(t)
+
+	var vo dependentsVO
+	if code := get(t, srv, "/dist/debian/dependents?package=libc6", &vo); code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+	want := []dependentVO{{"bash", 1}, {"libtinfo", 1}, {"vim", 1}, {"app", 2}}
+	if vo.Total != len(want) || !reflect.DeepEqual(vo.Data, want) {
+		t.Errorf("dependents = %+v, want %+v", vo, want)
+	}
+
+	vo = dependentsVO{}
+	if code := get(t, srv, "/dist/debian/dependents?package=libc6&maxDepth=1", &vo); code != http.StatusOK {
+		t.Fatalf("status with maxDepth = %d, want 200", code)
+	}
+	if vo.Total != 3 || vo.Data[len(vo.Data)-1].Depth != 1 {
+		t.Errorf("dependents within 1 hop = %+v", vo)
+	}
+
+	// an unknown package has no dependents
+	vo = dependentsVO{}
+	if code := get(t, srv, "/dist/debian/dependents?package=unknown", &vo); code != http.StatusOK {
+		t.Fatalf("status of an unknown package = %d, want 200", code)
+	}
+	if vo.Total != 0 || vo.Data == nil || len(vo.Data) != 0 {
+		t.Errorf("dependents of an unknown package = %+v, want an empty list", vo)
+	}
+}
+
+func TestGetDependencyPath(t *testing.T) {
+	srv := newGraphServer(t)
+
+	var vo dependencyPathVO
+	if code := get(t, srv, "/dist/debian/path?from=app&to=libc6", &vo); code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+	if want := []string{"app", "vim", "libc6"}; !reflect.DeepEqual(vo.Path, want) {
+		t.Errorf("path = %v, want %v", vo.Path, want)
+	}
+
+	tests := []string{
+		// libc6 does not depend on vim
+		"/dist/debian/path?from=libc6&to=vim",
+		// the path is longer than maxDepth
+		"/dist/debian/path?from=app&to=libc6&maxDepth=1",
+		"/dist/debian/path?from=unknown&to=libc6",
+		"/dist/debian/path?from=app&to=unknown",
+	}
+	for _, path := range tests {
+		if code := get(t, srv, path, nil); code != http.StatusNotFound {
+			t.Errorf("%s: status = %d, want 404", path, code)
+		}
+	}
+}
+
+func TestGraphParams(t *testing.T) {
+	srv := newGraphServer(t)
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/dist/unknown/dependents?package=libc6", http.StatusNotFound},
+		{"/dist/unknown/path?from=app&to=libc6", http.StatusNotFound},
+		{"/dist/debian/dependents", http.StatusBadRequest},
+		{"/dist/debian/path?from=app", http.StatusBadRequest},
+		{"/dist/debian/path?to=libc6", http.StatusBadRequest},
+		{"/dist/debian/dependents?package=libc6&maxDepth=0", http.StatusBadRequest},
+		{"/dist/debian/dependents?package=libc6&maxDepth=-1", http.StatusBadRequest},
+		{"/dist/debian/dependents?package=libc6&maxDepth=abc", http.StatusBadRequest},
+		{"/dist/debian/dependents?package=libc6&maxDepth=" + strconv.Itoa(repository.MaxReachabilityDepth+1), http.StatusBadRequest},
+		{"/dist/debian/path?from=app&to=libc6&maxDepth=" + strconv.Itoa(repository.MaxReachabilityDepth+1), http.StatusBadRequest},
+		{"/dist/debian/dependents?package=libc6&maxDepth=" + strconv.Itoa(repository.MaxReachabilityDepth), http.StatusOK},
+		{"/dist/debian/path?from=app&to=libc6&maxDepth=" + strconv.Itoa(repository.MaxReachabilityDepth), http.StatusOK},
+	}
+	for _, tt := range tests {
+		if code := get(t, srv, tt.path, nil); code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.path, code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/gitutil/normalize"
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/openapi"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/emicklei/go-restful"
+)
+
+type projectLabelVO struct {
+	// Kind is country or affiliation
+	Kind       string  `json:"kind"`
+	Value      string  `json:"value"`
+	Confidence float64 `json:"confidence"`
+	// Source and Version are the import the label comes from
+	Source    string     `json:"source"`
+	Version   int        `json:"version"`
+	CreatedAt *time.Time `json:"createdAt"`
+}
+
+type projectLabelsVO struct {
+	Link   string           `json:"link"`
+	Labels []projectLabelVO `json:"labels"`
+}
+
+func registerLabelRoutes(service *restful.WebService) {
+	service.Route(service.GET("/labels").To(getProjectLabels).
+		Doc("the current country and affiliation labels of a project, with their sources").
+		Metadata(openapi.KeyTags, []string{"projects"}).
+		Param(service.QueryParameter("link", "git link of the project").Required(true)).
+		Writes(projectLabelsVO{}).
+		Returns(http.StatusBadRequest, "missing link", nil).
+		Returns(http.StatusNotFound, "project not labeled", nil))
+}
+
+func getProjectLabels(request *restful.Request, response *restful.Response) {
+	link := request.QueryParameter("link")
+	if link == "" {
+		response.WriteErrorString(http.StatusBadRequest, "Missing link parameter")
+		return
+	}
+	if normalized, err := normalize.URL(link); err == nil {
+		link = normalized
+	}
+
+	labels, err := repository.NewProjectLabelRepository(storage.GetDefaultReadOnlyAppDatabaseContext()).QueryByLink(link)
+	if err != nil {
+		response.WriteErrorString(http.StatusInternalServerError, "Fetch data error")
+		logger.Info(err)
+		return
+	}
+	ret := projectLabelsVO{Link: link, Labels: []projectLabelVO{}}
+	for l := range labels {
+		ret.Labels = append(ret.Labels, projectLabelVO{
+			Kind:       deref(l.Kind),
+			Value:      deref(l.Value),
+			Confidence: deref(l.Confidence),
+			Source:     deref(l.Source),
+			Version:    deref(l.Version),
+			CreatedAt:  l.CreatedAt,
+		})
+	}
+	if len(ret.Labels) == 0 {
+		response.WriteErrorString(http.StatusNotFound, "No label")
+		return
+	}
+	response.WriteAsJson(ret)
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/league"
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/openapi"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/emicklei/go-restful"
+)
+
+type leagueVO struct {
+	Kind     string         `json:"kind"`
+	Name     string         `json:"name"`
+	Title    string         `json:"title"`
+	Projects []league.Entry `json:"projects"`
+}
+
+func registerLeagueRoutes(service *restful.WebService) {
+	service.Route(service.GET("/leagues/{kind}/{name}").To(getLeague).
+		Doc("top projects of a language, a distribution, the projects only packaged by a distribution, or a tag, with their percentile ranks").
+		Metadata(openapi.KeyTags, []string{"projects"}).
+		Param(service.PathParameter("kind", "language, distro, distro-only or tag")).
+		Param(service.PathParameter("name", "name of the language, the distribution or the tag, e.g. Rust")).
+		Param(service.QueryParameter("take", "number of projects").DataType("integer").DefaultValue(strconv.Itoa(DEFAULT_TOP_TAKE))).
+		Writes(leagueVO{}).
+		Returns(http.StatusBadRequest, "unknown kind or invalid take", nil))
+}
+
+func getLeague(request *restful.Request, response *restful.Response) {
+	take := DEFAULT_TOP_TAKE
+	if s := request.QueryParameter("take"); s != "" {
+		t, err := strconv.Atoi(s)
+		if err != nil || t <= 0 || t > MAX_ALLOWED_TAKE {
+			response.WriteErrorString(http.StatusBadRequest, "Invalid take parameter")
+			return
+		}
+		take = t
+	}
+	l, err := league.New(request.PathParameter("kind"), request.PathParameter("name"))
+	if err != nil {
+		response.WriteErrorString(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	entries, err := l.Load(storage.GetDefaultReadOnlyAppDatabaseContext(), take)
+	if err != nil {
+		response.WriteErrorString(http.StatusInternalServerError, "Fetch data error")
+		logger.Info(err)
+		return
+	}
+	response.WriteEntity(leagueVO{Kind: l.Kind, Name: l.Name, Title: l.Title(), Projects: entries})
+}
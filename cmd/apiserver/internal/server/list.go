@@ -0,0 +1,102 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+	"github.com/emicklei/go-restful"
+)
+
+// listParams adds the parameters of the list options to a route, they are
+// parsed by parseListOptions. take and start are the older names of limit
+// and offset.
+func listParams(service *restful.WebService, route *restful.RouteBuilder, spec *sqlutil.ListSpec) *restful.RouteBuilder {
+	sortable := append([]string{spec.Key}, spec.Sortable...)
+	return route.
+		Param(service.QueryParameter("limit", fmt.Sprintf("number of rows, at most %d", spec.MaxLimit)).DataType("integer").DefaultValue(strconv.Itoa(spec.DefaultLimit))).
+		Param(service.QueryParameter("take", "alias of limit").DataType("integer")).
+		Param(service.QueryParameter("offset", "number of rows skipped, can not be combined with cursor").DataType("integer").DefaultValue("0")).
+		Param(service.QueryParameter("start", "alias of offset").DataType("integer")).
+		Param(service.QueryParameter("cursor", "next of the previous page")).
+		Param(service.QueryParameter("sort", "sort field, descending with a - prefix: "+strings.Join(sortable, ", ")).DefaultValue(spec.DefaultSort)).
+		Param(service.QueryParameter("fields", "comma separated fields of the rows, all fields by default"))
+}
+
+func intParam(request *restful.Request, names ...string) (int, error) {
+	for _, name := range names {
+		if s := request.QueryParameter(name); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				return 0, fmt.Errorf("%w: invalid %s", sqlutil.ErrInvalidOption, name)
+			}
+			return n, nil
+		}
+	}
+	return 0, nil
+}
+
+// parseListOptions returns the list options of the query parameters, they
+// are checked against the spec by the query.
+func parseListOptions(request *restful.Request) (opts sqlutil.ListOptions, err error) {
+	if opts.Limit, err = intParam(request, "limit", "take"); err != nil {
+		return opts, err
+	}
+	if opts.Offset, err = intParam(request, "offset", "start"); err != nil {
+		return opts, err
+	}
+	opts.Cursor = request.QueryParameter("cursor")
+	opts.Sort = request.QueryParameter("sort")
+	if s := request.QueryParameter("fields"); s != "" {
+		for _, f := range strings.Split(s, ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				opts.Fields = append(opts.Fields, f)
+			}
+		}
+	}
+	return opts, nil
+}
+
+// writeListError writes the error of a list query, a bad request if the
+// options are invalid.
+func writeListError(response *restful.Response, err error) {
+	if errors.Is(err, sqlutil.ErrInvalidOption) {
+		response.WriteErrorString(http.StatusBadRequest, err.Error())
+		return
+	}
+	response.WriteErrorString(http.StatusInternalServerError, "Fetch data error")
+	logger.Info(err)
+}
+
+// selectFields returns the rows with only the json fields of fields, or
+// the rows as they are if fields is empty.
+func selectFields[T any](rows []T, fields []string) ([]any, error) {
+	ret := make([]any, 0, len(rows))
+	for _, row := range rows {
+		if len(fields) == 0 {
+			ret = append(ret, row)
+			continue
+		}
+		data, err := json.Marshal(row)
+		if err != nil {
+			return nil, err
+		}
+		var all map[string]json.RawMessage
+		if err := json.Unmarshal(data, &all); err != nil {
+			return nil, err
+		}
+		selected := make(map[string]json.RawMessage, len(fields))
+		for _, f := range fields {
+			if v, ok := all[f]; ok {
+				selected[f] = v
+			}
+		}
+		ret = append(ret, selected)
+	}
+	return ret, nil
+}
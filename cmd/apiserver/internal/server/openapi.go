@@ -0,0 +1,37 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/openapi"
+	"github.com/emicklei/go-restful"
+)
+
+const (
+	OPENAPI_PATH    = "/openapi.json"
+	SWAGGER_UI_PATH = "/docs/"
+)
+
+// Spec returns the OpenAPI specification of the routes of service.
+func Spec(service *restful.WebService) *openapi.Document {
+	doc := openapi.Build(openapi.Info{
+		Title:       "Criticality Score API",
+		Description: "Criticality scores of open source projects, with their metrics and the dependency graphs of distributions.",
+		Version:     SERVICE_VERSION,
+	}, service)
+	// responses of the filters and of every handler
+	plainText := map[string]openapi.MediaType{"text/plain": {Schema: &openapi.Schema{Type: "string"}}}
+	doc.AddResponse(http.StatusTooManyRequests, &openapi.Response{
+		Description: "rate limited, retry after the seconds of Retry-After",
+		Headers:     map[string]*openapi.Header{"Retry-After": {Schema: &openapi.Schema{Type: "integer"}}},
+		Content:     plainText,
+	})
+	doc.AddResponse(http.StatusInternalServerError, &openapi.Response{Description: "database error", Content: plainText})
+	return doc
+}
+
+// registerOpenAPI serves the specification and Swagger UI of service.
+func registerOpenAPI(mux *http.ServeMux, service *restful.WebService) {
+	mux.Handle(OPENAPI_PATH, openapi.Handler(Spec(service)))
+	mux.Handle(SWAGGER_UI_PATH, openapi.SwaggerUI("Criticality Score API", OPENAPI_PATH))
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/openapi"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/emicklei/go-restful"
+)
+
+type orgScoreVO struct {
+	Rank int `json:"rank"`
+	// Owner is the organization or user, e.g. github.com/curl, or the
+	// host of repos not on a forge
+	Owner       string     `json:"owner"`
+	Projects    int        `json:"projects"`
+	ScoreSum    *float64   `json:"scoreSum"`
+	ScoreMax    *float64   `json:"scoreMax"`
+	TopLink     *string    `json:"topLink"`
+	TopProjects *int       `json:"topProjects"`
+	UpdateTime  *time.Time `json:"updateTime"`
+}
+
+func registerOrgRoutes(service *restful.WebService) {
+	service.Route(listParams(service, service.GET("/orgs").To(getOrgScores), repository.OrgScoreListSpec).
+		Doc("organizations by the sum of the scores of their projects in the latest scoring run, the cursor of the next page is the X-Next-Cursor header").
+		Metadata(openapi.KeyTags, []string{"projects"}).
+		Writes([]orgScoreVO{}).
+		Returns(http.StatusBadRequest, "invalid parameter", nil))
+}
+
+func getOrgScores(request *restful.Request, response *restful.Response) {
+	opts, err := parseListOptions(request)
+	if err != nil {
+		writeListError(response, err)
+		return
+	}
+
+	page, err := repository.NewOrgScoreRepository(storage.GetDefaultReadOnlyAppDatabaseContext()).List(opts)
+	if err != nil {
+		writeListError(response, err)
+		return
+	}
+
+	rows := make([]orgScoreVO, 0, len(page.Items))
+	for _, row := range page.Items {
+		rows = append(rows, orgScoreVO{
+			Rank:        deref(row.Rank),
+			Owner:       deref(row.Owner),
+			Projects:    deref(row.Projects),
+			ScoreSum:    row.ScoreSum,
+			ScoreMax:    row.ScoreMax,
+			TopLink:     row.TopLink,
+			TopProjects: row.TopProjects,
+			UpdateTime:  row.UpdateTime,
+		})
+	}
+	ret, err := selectFields(rows, opts.Fields)
+	if err != nil {
+		writeListError(response, err)
+		return
+	}
+	if page.Next != "" {
+		response.Header().Set(NEXT_CURSOR_HEADER, page.Next)
+	}
+	response.WriteEntity(ret)
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/openapi"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/HUSTSecLab/criticality_score/pkg/translate"
+	"github.com/emicklei/go-restful"
+	"golang.org/x/text/language"
+)
+
+type packageVO struct {
+	Package     string  `json:"package"`
+	Version     *string `json:"version"`
+	HomePage    *string `json:"homepage"`
+	GitLink     *string `json:"link"`
+	Description *string `json:"description"`
+	// DescriptionLanguage is the detected language of the description
+	DescriptionLanguage *string `json:"descriptionLanguage"`
+	// TranslatedDescription is the description in TranslatedLanguage, nil
+	// if it is already in that language or it was not translated
+	TranslatedDescription *string `json:"translatedDescription"`
+	TranslatedLanguage    *string `json:"translatedLanguage"`
+}
+
+func registerPackageRoutes(service *restful.WebService) {
+	service.Route(service.GET("/dist/{dist}/package").To(getPackage).
+		Doc("a package of a distribution, with its description translated if the server has a translation provider").
+		Metadata(openapi.KeyTags, []string{"distributions"}).
+		Param(service.PathParameter("dist", "distribution, e.g. debian")).
+		Param(service.QueryParameter("package", "package name").Required(true)).
+		Param(service.QueryParameter("lang", "language the description is translated to, e.g. zh, default is the language of the server")).
+		Writes(packageVO{}).
+		Returns(http.StatusBadRequest, "missing package or invalid lang", nil).
+		Returns(http.StatusNotFound, "unknown distribution or package", nil))
+}
+
+func getPackage(request *restful.Request, response *restful.Response) {
+	dist, ok := repository.ParseDistPackageTablePrefix(request.PathParameter("dist"))
+	if !ok {
+		response.WriteErrorString(http.StatusNotFound, "Unknown distribution")
+		return
+	}
+	name := request.QueryParameter("package")
+	if name == "" {
+		response.WriteErrorString(http.StatusBadRequest, "Missing package parameter")
+		return
+	}
+	lang := request.QueryParameter("lang")
+	if lang != "" {
+		if _, err := language.Parse(lang); err != nil {
+			response.WriteErrorString(http.StatusBadRequest, "Invalid lang parameter")
+			return
+		}
+	}
+
+	pkg, err := repository.NewDistPackageRepository(storage.GetDefaultReadOnlyAppDatabaseContext(), dist).GetByName(name)
+	if err != nil {
+		response.WriteErrorString(http.StatusInternalServerError, "Fetch data error")
+		logger.Info(err)
+		return
+	}
+	if pkg == nil {
+		response.WriteErrorString(http.StatusNotFound, "Package not found")
+		return
+	}
+
+	vo := packageVO{Package: name, Version: pkg.Version, HomePage: pkg.HomePage, GitLink: pkg.GitLink, Description: pkg.Description}
+	if pkg.Description != nil && *pkg.Description != "" {
+		// a failed translation does not fail the request, the description
+		// is served as it is
+		d, err := translate.Default().Translate(context.Background(), *pkg.Description, lang)
+		if err != nil {
+			logger.Warnf("Translating the description of %s/%s Failed: %v", dist, name, err)
+		}
+		if d.Language != "" {
+			vo.DescriptionLanguage = &d.Language
+		}
+		if d.Translation != "" {
+			vo.TranslatedDescription, vo.TranslatedLanguage = &d.Translation, &d.TranslationLanguage
+		}
+	}
+	response.WriteAsJson(vo)
+}
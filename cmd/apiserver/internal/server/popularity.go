@@ -0,0 +1,81 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/gitutil/normalize"
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/openapi"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/emicklei/go-restful"
+)
+
+type popularityPointVO struct {
+	Time  time.Time `json:"time"`
+	Stars *int      `json:"stars"`
+	Forks *int      `json:"forks"`
+}
+
+type popularityVO struct {
+	Link string `json:"link"`
+	// Points are the snapshots of the stars and forks by ascending time,
+	// the old ones are thinned to a point per week or month
+	Points []popularityPointVO `json:"points"`
+	// StarsGrowth and ForksGrowth are the relative growth per month of the
+	// latest trends, nil if the series is too short
+	StarsGrowth *float64 `json:"starsGrowth"`
+	ForksGrowth *float64 `json:"forksGrowth"`
+}
+
+func registerPopularityRoutes(service *restful.WebService) {
+	service.Route(service.GET("/popularity").To(getPopularity).
+		Doc("time series of the stars and forks of a project, with their growth per month").
+		Metadata(openapi.KeyTags, []string{"projects"}).
+		Param(service.QueryParameter("link", "git link of the project").Required(true)).
+		Writes(popularityVO{}).
+		Returns(http.StatusBadRequest, "missing link", nil).
+		Returns(http.StatusNotFound, "project without snapshots", nil))
+}
+
+func getPopularity(request *restful.Request, response *restful.Response) {
+	link := request.QueryParameter("link")
+	if link == "" {
+		response.WriteErrorString(http.StatusBadRequest, "Missing link parameter")
+		return
+	}
+	if normalized, err := normalize.URL(link); err == nil {
+		link = normalized
+	}
+
+	ac := storage.GetDefaultReadOnlyAppDatabaseContext()
+	snapshots, err := repository.NewPopularitySnapshotRepository(ac).QueryByLink(link)
+	if err != nil {
+		response.WriteErrorString(http.StatusInternalServerError, "Fetch data error")
+		logger.Info(err)
+		return
+	}
+	vo := popularityVO{Link: link, Points: []popularityPointVO{}}
+	for s := range snapshots {
+		if s.SnapshotTime == nil {
+			continue
+		}
+		vo.Points = append(vo.Points, popularityPointVO{Time: *s.SnapshotTime, Stars: s.Stars, Forks: s.Forks})
+	}
+	if len(vo.Points) == 0 {
+		response.WriteErrorString(http.StatusNotFound, "No snapshot")
+		return
+	}
+
+	t, err := repository.NewMetricTrendRepository(ac).GetByLink(link)
+	if err != nil {
+		response.WriteErrorString(http.StatusInternalServerError, "Fetch data error")
+		logger.Info(err)
+		return
+	}
+	if t != nil {
+		vo.StarsGrowth, vo.ForksGrowth = t.StarsGrowth, t.ForksGrowth
+	}
+	response.WriteAsJson(vo)
+}
@@ -1,13 +1,15 @@
 package server
 
 import (
-	"encoding/json"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/openapi"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/HUSTSecLab/criticality_score/pkg/tagging"
 	"github.com/emicklei/go-restful"
 )
 
@@ -20,17 +22,38 @@ func RegisterService() *restful.WebService {
 
 	service.Path("/" + SERVICE_VERSION).
 		Consumes(restful.MIME_JSON).
-		Produces(restful.MIME_JSON)
-
-	service.Route(service.GET("/metrics").To(getMetrics))
+		Produces(restful.MIME_JSON).
+		Filter(rateLimitFilter).
+		Filter(cacheFilter)
+
+	service.Route(listParams(service, service.GET("/metrics").To(getMetrics), repository.ProjectMetricListSpec).
+		Doc("metrics and scores of the projects, by descending score by default").
+		Metadata(openapi.KeyTags, []string{"projects"}).
+		Param(service.QueryParameter("tag", "only projects with the tag")).
+		Writes(metricsPageVO{}).
+		Returns(http.StatusBadRequest, "invalid parameter", nil))
+	registerGraphRoutes(service)
+	registerTagRoutes(service)
+	registerViewRoutes(service)
+	registerLeagueRoutes(service)
+	registerFreshnessRoutes(service)
+	registerBadgeRoutes(service)
+	registerPackageRoutes(service)
+	registerChannelRoutes(service)
+	registerPopularityRoutes(service)
+	registerOrgRoutes(service)
+	registerLabelRoutes(service)
+	registerSignalRoutes(service)
 
 	return service
 
 }
 
 func StartWebServer(host string, port int) {
-	logger.Infof("Starting server on %d, endpoint is %s", port, "/"+SERVICE_VERSION)
-	restful.Add(RegisterService())
+	logger.Infof("Starting server on %d, endpoint is %s, OpenAPI specification is %s", port, "/"+SERVICE_VERSION, OPENAPI_PATH)
+	service := RegisterService()
+	restful.Add(service)
+	registerOpenAPI(http.DefaultServeMux, service)
 	logger.Fatal(http.ListenAndServe(host+":"+strconv.Itoa(port), nil))
 }
 
@@ -49,122 +72,87 @@ type metricsVO struct {
 	Industry         *string    `json:"industry"`
 	Domestic         *bool      `json:"domestic"`
 	Score            *float64   `json:"score"`
+	MaintenanceRisk  *string    `json:"maintenanceRisk"`
+	// DistributionBreadth is the number of channels distributing the
+	// project, see /channels
+	DistributionBreadth *int `json:"distributionBreadth"`
 	// Rank             int       `json:"rank"`
 }
 
+// metricsPageVO is the response of /metrics, it only documents the
+// response, the rows only have the selected fields.
+type metricsPageVO struct {
+	Total int `json:"total"`
+	// Next is the cursor of the next page, empty on the last page
+	Next string      `json:"next,omitempty"`
+	Data []metricsVO `json:"data"`
+}
+
 const MAX_ALLOWED_TAKE = 10000
 
-func getMetrics(request *restful.Request, response *restful.Response) {
+// NEXT_CURSOR_HEADER is the header of the cursor of the next page of the
+// list routes, it is not set on the last page.
+const NEXT_CURSOR_HEADER = "X-Next-Cursor"
 
-	startStr := request.QueryParameter("start")
-	if startStr == "" {
-		startStr = "0"
-	}
-	start, err := strconv.Atoi(startStr)
+func getMetrics(request *restful.Request, response *restful.Response) {
+	opts, err := parseListOptions(request)
 	if err != nil {
-		response.WriteErrorString(http.StatusBadRequest, "Invalid start parameter")
+		writeListError(response, err)
 		return
 	}
-
-	takeStr := request.QueryParameter("take")
-	if takeStr == "" {
-		takeStr = "100"
+	tag := request.QueryParameter("tag")
+	if tag != "" {
+		if err := tagging.Validate(tag); err != nil {
+			response.WriteErrorString(http.StatusBadRequest, "Invalid tag parameter")
+			return
+		}
 	}
-	take, err := strconv.Atoi(takeStr)
 
-	conn, err := storage.GetDefaultAppDatabaseContext().GetDatabaseConnection()
-	defer conn.Close()
+	repo := repository.NewProjectMetricRepository(storage.GetDefaultReadOnlyAppDatabaseContext())
+	page, err := repo.List(tag, opts)
 	if err != nil {
-		response.WriteErrorString(http.StatusInternalServerError, err.Error())
+		writeListError(response, err)
 		return
 	}
-
-	var total int
-
-	if take > MAX_ALLOWED_TAKE {
-		response.WriteErrorString(http.StatusBadRequest, "take parameter is too large")
-	}
-
-	r := conn.QueryRow(`SELECT COUNT(*) FROM git_metrics_prod WHERE scores IS NOT NULL`)
-	if r == nil {
-		response.WriteErrorString(http.StatusInternalServerError, "No data found")
+	total, err := repo.Count(tag)
+	if err != nil {
+		writeListError(response, err)
 		return
 	}
-	r.Scan(&total)
-
-	rows, err := conn.Query(`SELECT
-		gm.git_link AS git_link,
-		ecosystem,
-		created_since,
-		updated_since,
-		contributor_count,
-		org_count,
-		commit_frequency,
-		depsdev_count,
-		dist_impact,
-		license,
-		language,
-		CASE WHEN industry IS NULL
-			THEN 'unknown'
-			WHEN industry = 0
-			THEN 'test0'
-			ELSE 'other'
-		END AS industry,
-		gr.domestic AS domestic,
-		scores
-	FROM git_metrics_prod gm
-	LEFT JOIN git_repositories gr ON gm.git_link = gr.git_link
-	WHERE scores IS NOT NULL
-	ORDER BY scores DESC
-	OFFSET $1 LIMIT $2`, start, take)
 
+	rows := make([]metricsVO, 0, len(page.Items))
+	for _, m := range page.Items {
+		rows = append(rows, metricsVO{
+			GitLink:             deref(m.GitLink),
+			Ecosystems:          m.Ecosystem,
+			CreatedSince:        m.CreatedSince,
+			UpdatedSince:        m.UpdatedSince,
+			ContributorCount:    m.ContributorCount,
+			OrgCount:            m.OrgCount,
+			CommitFrequency:     m.CommitFrequency,
+			DepsDevCount:        m.DepsdevCount,
+			DepsDistroScore:     m.DistImpact,
+			License:             m.License,
+			Language:            m.Language,
+			Industry:            m.Industry,
+			Domestic:            m.Domestic,
+			Score:               m.Scores,
+			MaintenanceRisk:     m.MaintenanceRisk,
+			DistributionBreadth: m.DistributionBreadth,
+		})
+	}
+	data, err := selectFields(rows, opts.Fields)
 	if err != nil {
-		response.WriteErrorString(http.StatusInternalServerError, "Fetch data error")
-		logger.Info(err)
+		writeListError(response, err)
 		return
 	}
-
-	response.Header().Set("Content-Type", "application/json")
-	response.Header().Set("X-From", "criticality_score")
-	response.WriteHeader(200)
-	response.Write([]byte(`{"total":` + strconv.Itoa(total) + `,"data":[`))
-
-	var first = true
-
-	for rows.Next() {
-		if !first {
-			response.Write([]byte(","))
-		}
-		first = false
-
-		var metrics metricsVO
-		err = rows.Scan(
-			&metrics.GitLink,
-			&metrics.Ecosystems,
-			&metrics.CreatedSince,
-			&metrics.UpdatedSince,
-			&metrics.ContributorCount,
-			&metrics.OrgCount,
-			&metrics.CommitFrequency,
-			&metrics.DepsDevCount,
-			&metrics.DepsDistroScore,
-			&metrics.License,
-			&metrics.Language,
-			&metrics.Industry,
-			&metrics.Domestic,
-			&metrics.Score)
-		if err != nil {
-			logger.Error("err at scan: ", err)
-			return
-		}
-		jsonBytes, err := json.Marshal(metrics)
-		if err != nil {
-			logger.Error("err at json marshal: ", err)
-			return
-		}
-		response.Write(jsonBytes)
-		response.Flush()
+	if page.Next != "" {
+		response.Header().Set(NEXT_CURSOR_HEADER, page.Next)
 	}
-	response.Write([]byte("]}"))
-	response.Flush()
+	response.Header().Set("X-From", "criticality_score")
+	response.WriteEntity(struct {
+		Total int    `json:"total"`
+		Next  string `json:"next,omitempty"`
+		Data  []any  `json:"data"`
+	}{total, page.Next, data})
 }
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/gitutil/normalize"
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/openapi"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/emicklei/go-restful"
+)
+
+type signalValueVO struct {
+	// Collector is the signal collector, e.g. scorecard, Name the signal of
+	// it, e.g. checks.maintained
+	Collector   string     `json:"collector"`
+	Name        string     `json:"name"`
+	Value       float64    `json:"value"`
+	CollectedAt *time.Time `json:"collectedAt"`
+}
+
+type signalValuesVO struct {
+	Link    string          `json:"link"`
+	Signals []signalValueVO `json:"signals"`
+}
+
+func registerSignalRoutes(service *restful.WebService) {
+	service.Route(service.GET("/signals").To(getSignalValues).
+		Doc("the signals of a project collected by the pluggable signal collectors").
+		Metadata(openapi.KeyTags, []string{"projects"}).
+		Param(service.QueryParameter("link", "git link of the project").Required(true)).
+		Writes(signalValuesVO{}).
+		Returns(http.StatusBadRequest, "missing link", nil).
+		Returns(http.StatusNotFound, "project without signals", nil))
+}
+
+func getSignalValues(request *restful.Request, response *restful.Response) {
+	link := request.QueryParameter("link")
+	if link == "" {
+		response.WriteErrorString(http.StatusBadRequest, "Missing link parameter")
+		return
+	}
+	if normalized, err := normalize.URL(link); err == nil {
+		link = normalized
+	}
+
+	values, err := repository.NewSignalValueRepository(storage.GetDefaultReadOnlyAppDatabaseContext()).QueryByLink(link)
+	if err != nil {
+		response.WriteErrorString(http.StatusInternalServerError, "Fetch data error")
+		logger.Info(err)
+		return
+	}
+	ret := signalValuesVO{Link: link, Signals: []signalValueVO{}}
+	for v := range values {
+		ret.Signals = append(ret.Signals, signalValueVO{
+			Collector:   deref(v.Collector),
+			Name:        deref(v.Name),
+			Value:       deref(v.Value),
+			CollectedAt: v.CollectedAt,
+		})
+	}
+	if len(ret.Signals) == 0 {
+		response.WriteErrorString(http.StatusNotFound, "No signal")
+		return
+	}
+	response.WriteAsJson(ret)
+}
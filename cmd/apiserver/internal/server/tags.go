@@ -0,0 +1,40 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/openapi"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/emicklei/go-restful"
+)
+
+type tagVO struct {
+	Tag      string `json:"tag"`
+	Projects int    `json:"projects"`
+}
+
+func registerTagRoutes(service *restful.WebService) {
+	service.Route(service.GET("/tags").To(getTags).
+		Doc("all tags with the number of their projects, use /metrics?tag= for the projects of a tag").
+		Metadata(openapi.KeyTags, []string{"projects"}).
+		Writes([]tagVO{}))
+}
+
+func getTags(request *restful.Request, response *restful.Response) {
+	tags, err := repository.NewProjectTagRepository(storage.GetDefaultReadOnlyAppDatabaseContext()).QueryTags()
+	if err != nil {
+		response.WriteErrorString(http.StatusInternalServerError, "Fetch data error")
+		logger.Info(err)
+		return
+	}
+
+	ret := make([]tagVO, 0, len(tags))
+	for tag, count := range tags {
+		ret = append(ret, tagVO{Tag: tag, Projects: count})
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Tag < ret[j].Tag })
+	response.WriteEntity(ret)
+}
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/openapi"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/emicklei/go-restful"
+)
+
+const DEFAULT_TOP_TAKE = 100
+
+type topProjectVO struct {
+	Rank         int64      `json:"rank"`
+	GitLink      string     `json:"link"`
+	Score        *float64   `json:"score"`
+	DistScore    *float64   `json:"distScore"`
+	LangEcoScore *float64   `json:"langEcoScore"`
+	GitScore     *float64   `json:"gitScore"`
+	UpdateTime   *time.Time `json:"updateTime"`
+}
+
+type distroSummaryVO struct {
+	Dist         string     `json:"dist"`
+	Projects     int64      `json:"projects"`
+	Dependents   int64      `json:"dependents"`
+	AvgPageRank  *float64   `json:"avgPageRank"`
+	AvgDistScore *float64   `json:"avgDistScore"`
+	UpdateTime   *time.Time `json:"updateTime"`
+}
+
+type coverageStatVO struct {
+	Source          string     `json:"source"`
+	Ecosystem       string     `json:"ecosystem"`
+	Packages        int64      `json:"packages"`
+	WithGitLink     int64      `json:"withGitLink"`
+	WithGitMetrics  int64      `json:"withGitMetrics"`
+	WithDependents  int64      `json:"withDependents"`
+	GitLinkShare    *float64   `json:"gitLinkShare"`
+	GitMetricsShare *float64   `json:"gitMetricsShare"`
+	DependentsShare *float64   `json:"dependentsShare"`
+	UpdateTime      *time.Time `json:"updateTime"`
+}
+
+// the routes read the materialized views refreshed after every scoring run
+func registerViewRoutes(service *restful.WebService) {
+	service.Route(listParams(service, service.GET("/ecosystems/{ecosystem}/top").To(getEcosystemTop), repository.TopProjectListSpec).
+		Doc("top projects of an ecosystem, by rank by default, the cursor of the next page is the X-Next-Cursor header").
+		Metadata(openapi.KeyTags, []string{"projects"}).
+		Param(service.PathParameter("ecosystem", "ecosystem, e.g. npm")).
+		Writes([]topProjectVO{}).
+		Returns(http.StatusBadRequest, "invalid parameter", nil))
+
+	service.Route(service.GET("/dist/summaries").To(getDistroSummaries).
+		Doc("number of projects, dependents and average scores of every distribution").
+		Metadata(openapi.KeyTags, []string{"distributions"}).
+		Writes([]distroSummaryVO{}))
+
+	service.Route(service.GET("/coverage").To(getCoverageStats).
+		Doc("shares of the packages of every ecosystem with a git link, git metrics and dependents").
+		Metadata(openapi.KeyTags, []string{"distributions"}).
+		Writes([]coverageStatVO{}))
+}
+
+func deref[T any](p *T) T {
+	var zero T
+	if p == nil {
+		return zero
+	}
+	return *p
+}
+
+func getEcosystemTop(request *restful.Request, response *restful.Response) {
+	opts, err := parseListOptions(request)
+	if err != nil {
+		writeListError(response, err)
+		return
+	}
+
+	repo := repository.NewMaterializedViewRepository(storage.GetDefaultReadOnlyAppDatabaseContext())
+	page, err := repo.ListTopProjects(request.PathParameter("ecosystem"), opts)
+	if err != nil {
+		writeListError(response, err)
+		return
+	}
+
+	rows := make([]topProjectVO, 0, len(page.Items))
+	for _, row := range page.Items {
+		rows = append(rows, topProjectVO{
+			Rank:         deref(row.Rank),
+			GitLink:      deref(row.GitLink),
+			Score:        row.Score,
+			DistScore:    row.DistScore,
+			LangEcoScore: row.DevScore,
+			GitScore:     row.GitScore,
+			UpdateTime:   row.UpdateTime,
+		})
+	}
+	ret, err := selectFields(rows, opts.Fields)
+	if err != nil {
+		writeListError(response, err)
+		return
+	}
+	if page.Next != "" {
+		response.Header().Set(NEXT_CURSOR_HEADER, page.Next)
+	}
+	response.WriteEntity(ret)
+}
+
+func getDistroSummaries(request *restful.Request, response *restful.Response) {
+	repo := repository.NewMaterializedViewRepository(storage.GetDefaultReadOnlyAppDatabaseContext())
+	rows, err := repo.QueryDistroSummaries()
+	if err != nil {
+		response.WriteErrorString(http.StatusInternalServerError, "Fetch data error")
+		logger.Info(err)
+		return
+	}
+
+	ret := make([]distroSummaryVO, 0)
+	for row := range rows {
+		ret = append(ret, distroSummaryVO{
+			Dist:         deref(row.Type).String(),
+			Projects:     deref(row.Projects),
+			Dependents:   deref(row.Dependents),
+			AvgPageRank:  row.AvgPageRank,
+			AvgDistScore: row.AvgDistScore,
+			UpdateTime:   row.UpdateTime,
+		})
+	}
+	response.WriteEntity(ret)
+}
+
+func getCoverageStats(request *restful.Request, response *restful.Response) {
+	repo := repository.NewMaterializedViewRepository(storage.GetDefaultReadOnlyAppDatabaseContext())
+	rows, err := repo.QueryCoverageStats()
+	if err != nil {
+		response.WriteErrorString(http.StatusInternalServerError, "Fetch data error")
+		logger.Info(err)
+		return
+	}
+
+	ret := make([]coverageStatVO, 0)
+	for row := range rows {
+		ret = append(ret, coverageStatVO{
+			Source:          deref(row.Source),
+			Ecosystem:       deref(row.Ecosystem),
+			Packages:        deref(row.Packages),
+			WithGitLink:     deref(row.WithGitLink),
+			WithGitMetrics:  deref(row.WithGitMetrics),
+			WithDependents:  deref(row.WithDependents),
+			GitLinkShare:    row.GitLinkShare,
+			GitMetricsShare: row.GitMetricsShare,
+			DependentsShare: row.DependentsShare,
+			UpdateTime:      row.UpdateTime,
+		})
+	}
+	response.WriteEntity(ret)
+}
@@ -1,21 +1,47 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"os"
+
 	"github.com/HUSTSecLab/criticality_score/cmd/apiserver/internal/server"
 	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/graph"
 	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
 	"github.com/spf13/pflag"
 )
 
+var flagOpenAPI = pflag.Bool("openapi", false, "print the OpenAPI specification and exit, e.g. to generate clients")
+
 func main() {
 	config.RegistCommonFlags(pflag.CommandLine)
+	config.RegistGraphFlags(pflag.CommandLine)
+	config.RegistAPIServerFlags(pflag.CommandLine)
+	config.RegistTranslationFlags(pflag.CommandLine)
 	config.ParseFlags(pflag.CommandLine)
 
+	if *flagOpenAPI {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(server.Spec(server.RegisterService())); err != nil {
+			logger.Fatal(err)
+		}
+		return
+	}
+
 	logger.Config(&logger.AppLoggerConfig{
 		Level:      logger.LoggerLevelInfo,
 		FormatType: logger.LoggerFormatJSON,
 	})
 
+	// the graphs are loaded in the background, requests before they are
+	// loaded wait for them
+	if s := graph.Default(); s != nil {
+		go s.Run(context.Background(), repository.DistPackageTablePrefixes...)
+	}
+
 	server.RegisterService()
 	server.StartWebServer("0.0.0.0", 8080)
 }
@@ -4,11 +4,14 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"net/http"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/google/go-github/github"
+	"github.com/HUSTSecLab/criticality_score/pkg/linkenumerator/githubapi"
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/google/go-github/v47/github"
 	"github.com/shurcooL/githubv4"
 	"golang.org/x/oauth2"
 )
@@ -96,11 +99,9 @@ func Run(ctx context.Context, db *sql.DB, owner string, repo string, config Conf
 		return nil
 	}
 
-	// 初始化 GitHub API 客户端
-	src := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: config.GitHubToken},
-	)
-	tc := oauth2.NewClient(ctx, src)
+	// 初始化 GitHub API 客户端，速率限制由 tc 的 RoundTripper 处理
+	l := logger.GetDefaultLogger()
+	tc := newGitHubHTTPClient(ctx, config.GitHubToken, l)
 	client := github.NewClient(tc) // 使用 v3 API 客户端来验证仓库链接
 
 	// 检查仓库是否存在且可访问
@@ -153,17 +154,10 @@ func Run(ctx context.Context, db *sql.DB, owner string, repo string, config Conf
 		}
 
 		// 执行查询
+		// 速率限制由 tc 的 RoundTripper 处理
 		err = clientV4.Query(ctx, &combinedQuery, vars)
 		if err != nil {
-			fmt.Println(err)
-			wait := handleRateLimitError(err)
-			if wait > 0 {
-				time.Sleep(wait)
-				err = clientV4.Query(ctx, &combinedQuery, vars)
-			}
-			if err != nil {
-				return err
-			}
+			return err
 		}
 
 		// 设置查询结果
@@ -192,7 +186,7 @@ func Run(ctx context.Context, db *sql.DB, owner string, repo string, config Conf
 	}
 
 	if opts.UpdateOrgCount {
-		orgCount, err := FetchOrgCount(ctx, client, owner, repo, config.GitHubToken)
+		orgCount, err := FetchOrgCount(ctx, client, clientV4, owner, repo, l)
 		if err != nil {
 			return fmt.Errorf("error fetching organization count for %s/%s: %v", owner, repo, err)
 		}
@@ -258,24 +252,17 @@ func updateDatabase(ctx context.Context, db *sql.DB, owner, repo string, stats G
 	return err
 }
 
-func handleRateLimitError(err error) time.Duration {
-	if rateLimitError, ok := err.(*github.RateLimitError); ok {
-		// 检查 Reset 时间是否有效
-		if !rateLimitError.Rate.Reset.IsZero() {
-			resetTimestamp := rateLimitError.Rate.Reset.Time
-			waitDuration := time.Until(resetTimestamp)
-			if waitDuration > 0 {
-				fmt.Printf("GitHub API rate limit exceeded. Waiting %v before retrying...\n", waitDuration)
-				return waitDuration
-			}
-		}
-	}
-	// 如果没有找到重置时间，则休眠一小时
-	fmt.Println("Unable to determine rate limit reset time. Waiting 1 hour before retrying...")
-	return time.Hour
+// newGitHubHTTPClient 返回带 token 的 HTTP 客户端，
+// 主速率限制和次级速率限制（403/429）均会等待后重试
+func newGitHubHTTPClient(ctx context.Context, token string, l logger.AppLogger) *http.Client {
+	tc := oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}))
+	tc.Transport = githubapi.NewRetryRoundTripper(tc.Transport, l)
+	return tc
 }
 
-func FetchOrgCount(ctx context.Context, client *github.Client, owner, repo string, Token string) (int, error) {
+// FetchOrgCount 返回仓库贡献者所属的不同组织数量，client 和 clientV4
+// 须使用 newGitHubHTTPClient 创建的 HTTP 客户端，由其处理速率限制
+func FetchOrgCount(ctx context.Context, client *github.Client, clientV4 *githubv4.Client, owner, repo string, l logger.AppLogger) (int, error) {
 	// 初始化组织名称过滤器
 	orgFilter := strings.NewReplacer(
 		"inc.", "",
@@ -294,27 +281,13 @@ func FetchOrgCount(ctx context.Context, client *github.Client, owner, repo strin
 	// 获取贡献者列表
 	contributors, _, err := client.Repositories.ListContributors(ctx, owner, repo, opts)
 	if err != nil {
-		if wait := handleRateLimitError(err); wait > 0 {
-			fmt.Printf("Waiting for %v before retrying...\n", wait)
-			time.Sleep(wait)
-
-			// 重试 ListContributors
-			contributors, _, err = client.Repositories.ListContributors(ctx, owner, repo, opts)
-			if err != nil {
-				return 0, err // 重试后仍然出错，返回错误
-			}
-		} else {
-			return 0, err // 其他类型的错误，直接返回
-		}
+		return 0, err
 	}
 
 	if len(contributors) == 0 {
 		return 0, nil // 没有有效的贡献者
 	}
 
-	// 初始化 GitHub GraphQL 客户端
-	clientV4 := githubv4.NewClient(oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: Token})))
-
 	// 提取和去重组织名称
 	orgSet := make(map[string]struct{})
 	var mu sync.Mutex // 用于保护 orgSet 的并发访问
@@ -335,47 +308,35 @@ func FetchOrgCount(ctx context.Context, client *github.Client, owner, repo strin
 		go func(login string) {
 			defer wg.Done() // 协程结束时减少计数器
 
-			for {
-				// 构建 GraphQL 查询
-				var query struct {
-					User struct {
-						Company *string
-					} `graphql:"user(login: $login)"`
-				}
+			// 构建 GraphQL 查询
+			var query struct {
+				User struct {
+					Company *string
+				} `graphql:"user(login: $login)"`
+			}
 
-				variables := map[string]interface{}{
-					"login": githubv4.String(login),
-				}
+			variables := map[string]interface{}{
+				"login": githubv4.String(login),
+			}
 
-				// 执行查询
-				err := clientV4.Query(ctx, &query, variables)
-				if err != nil {
-					waitDuration := handleRateLimitError(err)
-					if waitDuration > 0 {
-						// 如果触发速率限制，等待指定时间后重试
-						time.Sleep(waitDuration)
-						continue
-					} else {
-						fmt.Printf("Error querying user %s: %v\n", login, err)
-						return
-					}
-				}
+			// 执行查询，速率限制由 clientV4 的 RoundTripper 处理
+			err := clientV4.Query(ctx, &query, variables)
+			if err != nil {
+				l.Warnf("Error querying user %s: %v", login, err)
+				return
+			}
 
-				// 处理查询结果
-				if query.User.Company != nil {
-					org := strings.ToLower(*query.User.Company)
-					org = strings.TrimRight(orgFilter.Replace(org), ",")
+			// 处理查询结果
+			if query.User.Company != nil {
+				org := strings.ToLower(*query.User.Company)
+				org = strings.TrimRight(orgFilter.Replace(org), ",")
 
-					if org != "" {
-						// 使用 mutex 锁保护共享资源 orgSet
-						mu.Lock()
-						orgSet[org] = struct{}{}
-						mu.Unlock()
-					}
+				if org != "" {
+					// 使用 mutex 锁保护共享资源 orgSet
+					mu.Lock()
+					orgSet[org] = struct{}{}
+					mu.Unlock()
 				}
-
-				// 如果没有错误，退出循环
-				break
 			}
 		}(login) // 将 login 传递给协程
 	}
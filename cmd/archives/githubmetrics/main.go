@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/HUSTSecLab/criticality_score/cmd/archives/githubmetrics/internal/githubmetrics"
+	"github.com/HUSTSecLab/criticality_score/pkg/progress"
 	_ "github.com/lib/pq"
 )
 
@@ -27,8 +28,11 @@ func main() {
 	updateCommitFrequency := flag.Bool("commitfreq", false, "Update commit frequency")
 	updateOrgCount := flag.Bool("orgcount", false, "Update unique organization count")
 	forceUpdate := flag.Bool("force", false, "Force update all fields, even if data exists") // 新增的参数
+	tui := flag.Bool("tui", false, "Show progress bars, throughput, ETA and recent errors on terminals, plain logs otherwise")
 
 	flag.Parse()
+	progress.InitDefault(&progress.Config{TUI: *tui, Interval: progress.DefaultInterval})
+	defer progress.Default().Stop()
 
 	config := readConfig(*configPath)
 	ctx := context.Background()
@@ -48,11 +52,13 @@ func main() {
 	}
 
 	// 遍历git_links并更新它们的统计信息
+	phase := progress.Default().Phase("update", len(links))
 	for _, link := range links {
+		phase.Add(1)
 		link = strings.TrimSuffix(link, ".git") // 删除末尾的 ".git"
 		parts := strings.Split(link, "/")
 		if len(parts) < 5 {
-			log.Printf("Invalid git link format: %s", link)
+			progress.Default().Error(fmt.Errorf("invalid git link format: %s", link))
 			continue
 		}
 		owner := parts[3]
@@ -72,9 +78,10 @@ func main() {
 
 		// 执行更新
 		if err := githubmetrics.Run(ctx, db, owner, repo, config, opts); err != nil {
-			log.Printf("Failed to update metrics for %s/%s: %v", owner, repo, err)
+			progress.Default().Error(fmt.Errorf("failed to update metrics for %s/%s: %w", owner, repo, err))
 		}
 	}
+	phase.Done()
 
 	// 复制为生产表
 	db.Exec(fmt.Sprintf("ALTER TABLE IF EXISTS git_metrics_prod RENAME TO git_metrics_old_%s", time.Now().Format("20060102_150405")))
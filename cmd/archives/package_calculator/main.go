@@ -1,16 +1,17 @@
 package main
 
 import (
-	"fmt"
 	"log"
 
 	"github.com/HUSTSecLab/criticality_score/cmd/archives/package_calculator/internal/package_calculator"
 	"github.com/HUSTSecLab/criticality_score/pkg/config"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
 	"github.com/spf13/pflag"
 )
 
-var flagRepoName = pflag.String("repo", "", "name of the repository")
+var flagRepoName = pflag.String("repo", "", "name of the distribution, e.g. debian")
 var flagMethod = pflag.String("method", "", "method to use for calculation (bfs or dfs)")
 
 func main() {
@@ -20,6 +21,18 @@ func main() {
 	if *flagRepoName == "" {
 		log.Fatal("Repository name must be provided")
 	}
+	prefix, ok := repository.ParseDistPackageTablePrefix(*flagRepoName)
+	if !ok {
+		log.Fatalf("Unknown distribution: %s", *flagRepoName)
+	}
+	packages, err := sqlutil.Table(repository.DistPackageTableName(prefix))
+	if err != nil {
+		log.Fatal(err)
+	}
+	relationships, err := sqlutil.Table(repository.DistRelationshipTableName(prefix))
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	if *flagMethod != "bfs" && *flagMethod != "dfs" {
 		log.Fatal("Method must be either 'bfs' or 'dfs'")
@@ -31,14 +44,14 @@ func main() {
 	}
 	defer db.Close()
 
-	Countquery := fmt.Sprintf("SELECT count(*) FROM %s_packages", *flagRepoName)
+	Countquery := "SELECT count(*) FROM " + packages
 	var count int
 	err = db.QueryRow(Countquery).Scan(&count)
 	if err != nil {
 		log.Fatalf("Error querying database: %v", err)
 	}
 
-	query := fmt.Sprintf("SELECT frompackage, topackage FROM %s_relationships", *flagRepoName)
+	query := "SELECT frompackage, topackage FROM " + relationships
 	rows, err := db.Query(query)
 	if err != nil {
 		log.Fatalf("Error querying database: %v", err)
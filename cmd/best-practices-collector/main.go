@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/bestpractices"
+	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/failure"
+	"github.com/HUSTSecLab/criticality_score/pkg/freshness"
+	"github.com/HUSTSecLab/criticality_score/pkg/httpcache"
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/output"
+	"github.com/HUSTSecLab/criticality_score/pkg/priority"
+	"github.com/HUSTSecLab/criticality_score/pkg/sampling"
+	"github.com/HUSTSecLab/criticality_score/pkg/scope"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+	"github.com/HUSTSecLab/criticality_score/pkg/tagging"
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/spf13/pflag"
+)
+
+var (
+	flagJobsCount = pflag.IntP("jobs", "j", 4, "jobs count")
+	flagBaseURL   = pflag.String("best-practices-url", bestpractices.DefaultBaseURL, "base url of the bestpractices.dev api")
+	flagDryRun    = pflag.Bool("dry-run", false, "only print the badges, do not update the database")
+)
+
+// getLinks returns the known git links.
+func getLinks(ac storage.AppDatabaseContext) ([]string, error) {
+	query, args, err := sqlutil.From(repository.GitRepositoryTableName).Select("git_link")
+	if err != nil {
+		return nil, err
+	}
+	rows, err := ac.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ret []string
+	for rows.Next() {
+		var link string
+		if err := rows.Scan(&link); err != nil {
+			return nil, err
+		}
+		ret = append(ret, link)
+	}
+	return ret, rows.Err()
+}
+
+func main() {
+	pflag.Usage = func() {
+		fmt.Println("This tool is used to look up the OpenSSF Best Practices badges of git repositories at bestpractices.dev.")
+		pflag.PrintDefaults()
+	}
+
+	config.RegistCommonFlags(pflag.CommandLine)
+	config.RegistSpoolFlags(pflag.CommandLine)
+	config.RegistSampleFlags(pflag.CommandLine)
+	config.RegistTagFlags(pflag.CommandLine)
+	config.RegistScopeFlags(pflag.CommandLine)
+	config.RegistFreshnessFlags(pflag.CommandLine)
+	config.RegistPriorityFlags(pflag.CommandLine)
+	config.RegistHTTPCacheFlags(pflag.CommandLine)
+	config.RegistResponseArchiveFlags(pflag.CommandLine)
+	config.RegistOutputFlags(pflag.CommandLine)
+	config.RegistFailureFlags(pflag.CommandLine)
+	config.ParseFlags(pflag.CommandLine)
+	defer failure.Default().Exit()
+
+	ctx := context.Background()
+	ac := storage.GetDefaultAppDatabaseContext()
+	tsRepo := repository.NewCollectionTimestampRepository(ac)
+
+	var links []string
+	var err error
+	if config.UsesDatabase() {
+		links, err = getLinks(ac)
+	} else {
+		links, err = output.ReadLinks(config.GetInputPath(), config.GetInputURLColumn())
+	}
+	if err != nil {
+		failure.Default().Fatal(fmt.Errorf("failed to fetch git links: %w", err))
+	}
+	links = sampling.Slice(scope.Slice(tagging.Slice(links)))
+
+	if config.UsesDatabase() {
+		if window := config.GetFreshnessWindow(); window > 0 {
+			stale, err := tsRepo.FilterStale(repository.SignalBestPractices, links, time.Now().Add(-window))
+			if err != nil {
+				failure.Default().Fatal(err)
+			}
+			logger.Infof("Skipping %d links collected within %s", len(links)-len(stale), window)
+			links = stale
+		}
+		links, err = priority.Schedule(ac, repository.SignalBestPractices, links)
+		if err != nil {
+			failure.Default().Fatal(err)
+		}
+	}
+	logger.Infof("%d links in total", len(links))
+
+	writer := freshness.MetricWriter(ac, repository.SignalBestPractices, freshness.SourceBestPractices, "best_practices_badge")
+	sink, err := output.Open(config.GetOutputConfig(), output.NewQueue(writer, config.GetQueueConfig()))
+	if err != nil {
+		failure.Default().Fatal(err)
+	}
+
+	client := bestpractices.New(httpcache.Client(), *flagBaseURL)
+
+	var wg sync.WaitGroup
+	wg.Add(len(links))
+	gopool.SetCap(int32(*flagJobsCount))
+	for _, link := range links {
+		gopool.Go(func() {
+			defer wg.Done()
+			badge, err := client.Badge(ctx, link)
+			if err != nil {
+				logger.Errorf("Collecting %s Failed: %v", link, err)
+				failure.Default().Fail(link, err)
+				return
+			}
+
+			if *flagDryRun {
+				fmt.Printf("%s\t%s\n", link, badge)
+				failure.Default().Success()
+				return
+			}
+			badgeStr := string(badge)
+			if err := sink.Write(repository.GitMetricTableName, &repository.GitMetric{
+				GitLink:            &link,
+				BestPracticesBadge: &badgeStr,
+			}); err != nil {
+				logger.Errorf("Update %s Failed: %v", link, err)
+				failure.Default().Fail(link, err)
+				return
+			}
+			failure.Default().Success()
+		})
+	}
+	wg.Wait()
+	if err := sink.Close(); err != nil {
+		failure.Default().Fatal(err)
+	}
+}
@@ -0,0 +1,210 @@
+// collect-all runs the collectors of distributions and of language
+// ecosystems in parallel for a full refresh. Collectors mostly download and
+// parse package indexes, so running them side by side and only limiting how
+// many of them write to the database at the same time takes far less wall
+// clock time than running them one after another.
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/collector"
+	"github.com/HUSTSecLab/criticality_score/pkg/collector/distros"
+	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/depsdev"
+	"github.com/HUSTSecLab/criticality_score/pkg/failure"
+	"github.com/HUSTSecLab/criticality_score/pkg/freshness"
+	"github.com/HUSTSecLab/criticality_score/pkg/graph"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/spf13/pflag"
+)
+
+// langEcosystemJob is the name of the job of the language ecosystems, also
+// its collector in collector_runs.
+const langEcosystemJob = freshness.CollectorLangEcosystem
+
+var (
+	flagDistros     = pflag.StringSlice("distros", distros.Names, "distributions to collect, empty collects none")
+	flagLangEco     = pflag.Bool("lang-ecosystem", true, "collect the dependents of the packages of language ecosystems from deps.dev")
+	flagParallel    = pflag.Int("parallel", 4, "max number of collectors running at the same time, 0 runs all at once")
+	flagWriteBudget = pflag.Int("db-write-budget", 2, "max number of collectors writing to the database at the same time, 0 means no limit")
+
+	workerCount = pflag.Int("worker", 1, "number of workers of nix")
+	batchSize   = pflag.Int("batch", 1000, "batch size of nix")
+	downloadDir = pflag.String("downloadDir", "./download", "download directory of archlinux")
+	extractDir  = pflag.String("extractDir", "./extract", "extract directory of archlinux")
+
+	langBatchSize   = pflag.Int("lang-batch", 100, "batch size of language ecosystems")
+	langWorkerCount = pflag.Int("lang-workers", 10, "number of workers of language ecosystems")
+	flagAggregate   = pflag.String("aggregate", "sum", "how to aggregate dependents of repos publishing multiple packages: sum, max, list")
+)
+
+// job is a collector run by the orchestrator.
+type job struct {
+	name string
+	// tracker records the failed items of the job, if any, so the failure
+	// rate of one job does not fail the others
+	tracker *failure.Tracker
+	run     func() error
+}
+
+// check returns err, or an error if the failure rate of the tracker of the
+// job is over the max rate.
+func (j *job) check(err error) error {
+	if err != nil || j.tracker == nil {
+		return err
+	}
+	s := j.tracker.Summary()
+	if s.Failed > 0 {
+		log.Printf("%d of %d items of %s failed", s.Failed, s.Items, j.name)
+	}
+	if s.Status == failure.StatusFatal {
+		return fmt.Errorf("failure rate %.2f is over %.2f", s.FailureRate, s.MaxFailureRate)
+	}
+	return nil
+}
+
+// result is the outcome of a job.
+type result struct {
+	name     string
+	duration time.Duration
+	err      error
+}
+
+// runJobs runs jobs with at most parallel of them at the same time, 0 runs
+// all at once. Results are in the order of jobs.
+func runJobs(jobs []job, parallel int) []result {
+	if parallel <= 0 {
+		parallel = len(jobs)
+	}
+	results := make([]result, len(jobs))
+	slots := make(chan struct{}, max(parallel, 1))
+	var wg sync.WaitGroup
+	for i, j := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			log.Printf("Collecting %s...", j.name)
+			start := time.Now()
+			err := j.check(j.run())
+			results[i] = result{name: j.name, duration: time.Since(start), err: err}
+			if err != nil {
+				log.Printf("Collecting %s failed after %s: %v", j.name, results[i].duration, err)
+			} else {
+				log.Printf("Collected %s in %s", j.name, results[i].duration)
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// buildJobs returns the jobs of the configured collectors. The language
+// ecosystems are first, they take the longest.
+func buildJobs() ([]job, error) {
+	var jobs []job
+	if *flagLangEco {
+		policy, err := depsdev.ParseAggregatePolicy(*flagAggregate)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job{name: langEcosystemJob, run: func() error {
+			return depsdev.Depsdev(*langBatchSize, *langWorkerCount, false, policy, config.GetFreshnessWindow())
+		}})
+	}
+	opts := distros.Options{
+		DownloadDir: *downloadDir,
+		ExtractDir:  *extractDir,
+		Workers:     *workerCount,
+		BatchSize:   *batchSize,
+	}
+	seen := make(map[string]bool)
+	for _, name := range *flagDistros {
+		if !slices.Contains(distros.Names, name) {
+			return nil, fmt.Errorf("%w: %s", distros.ErrUnknown, name)
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		tracker := failure.New(config.GetFailureConfig())
+		jobs = append(jobs, job{name: name, tracker: tracker, run: func() error {
+			return distros.Collect(name, tracker, opts)
+		}})
+	}
+	return jobs, nil
+}
+
+// refreshGraphs reloads the graphs of the distributions collected without
+// errors in the shared graph service, if enabled.
+func refreshGraphs(results []result) {
+	s := graph.Default()
+	if s == nil {
+		return
+	}
+	for _, r := range results {
+		prefix, ok := distros.Prefix(r.name)
+		if !ok || r.err != nil {
+			continue
+		}
+		if err := s.Refresh(prefix); err != nil {
+			log.Printf("Failed to refresh dependency graph of %s: %v", r.name, err)
+		}
+	}
+}
+
+func main() {
+	config.RegistCommonFlags(pflag.CommandLine)
+	config.RegistMirrorFlags(pflag.CommandLine)
+	config.RegistSampleFlags(pflag.CommandLine)
+	config.RegistTagFlags(pflag.CommandLine)
+	config.RegistFailureFlags(pflag.CommandLine)
+	config.RegistHTTPCacheFlags(pflag.CommandLine)
+	config.RegistDepsDevFlags(pflag.CommandLine)
+	config.RegistFreshnessFlags(pflag.CommandLine)
+	config.RegistPriorityFlags(pflag.CommandLine)
+	config.RegistGitStorageFlags(pflag.CommandLine)
+	config.RegistResponseArchiveFlags(pflag.CommandLine)
+	config.RegistGraphFlags(pflag.CommandLine)
+	config.RegistPageRankFlags(pflag.CommandLine)
+	config.ParseFlags(pflag.CommandLine)
+	defer failure.Default().Exit()
+
+	jobs, err := buildJobs()
+	if err != nil {
+		failure.Default().Fatal(err)
+	}
+	collector.SetWriteBudget(*flagWriteBudget)
+
+	start := time.Now()
+	results := runJobs(jobs, *flagParallel)
+	var busy time.Duration
+	for _, r := range results {
+		busy += r.duration
+		// every job is an item of the run, the failed items of a job are
+		// only in the log
+		if r.err != nil {
+			failure.Default().Fail(r.name, r.err)
+		} else {
+			failure.Default().Success()
+		}
+		if err := freshness.RecordRun(storage.GetDefaultAppDatabaseContext(), r.name, r.err); err != nil {
+			log.Printf("Failed to record the run of %s: %v", r.name, err)
+		}
+	}
+	log.Printf("Collected %d sources in %s, %s if run one after another", len(results), time.Since(start), busy)
+	refreshGraphs(results)
+
+	// only the owner of the view can refresh it, a failure is not fatal
+	if err := repository.NewMaterializedViewRepository(storage.GetDefaultAppDatabaseContext()).Refresh(repository.CoverageStatsViewName); err != nil {
+		log.Printf("Failed to refresh coverage stats: %v", err)
+	}
+}
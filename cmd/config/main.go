@@ -0,0 +1,93 @@
+// config checks the config of the tools, i.e. the flags, the environment
+// variables and the config file, without running any of them.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/spf13/pflag"
+)
+
+var flagRequire = pflag.StringSlice("require", nil, "config keys which must be set, e.g. git.storage,token.github")
+
+func runCheck() error {
+	config.MarkRequired(*flagRequire...)
+
+	failed := 0
+	if err := config.ReadConfigFile(); err != nil {
+		fmt.Printf("FAIL config file: %v\n", err)
+		return err
+	}
+
+	if err := config.Validate(); err != nil {
+		errs := err.(interface{ Unwrap() []error }).Unwrap()
+		for _, e := range errs {
+			fmt.Printf("FAIL %v\n", e)
+		}
+		// connections are not checked with an invalid config
+		return fmt.Errorf("%d invalid config values", len(errs))
+	}
+	fmt.Println("ok   config")
+
+	for _, r := range config.Check() {
+		if r.Err != nil {
+			fmt.Printf("FAIL %s: %v\n", r.Name, r.Err)
+			failed++
+			continue
+		}
+		fmt.Printf("ok   %s\n", r.Name)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d checks failed", failed)
+	}
+	return nil
+}
+
+func main() {
+	pflag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s check [flags]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Validates the config and tests the database connection, the github token and the bundle bucket.\n")
+		pflag.PrintDefaults()
+	}
+
+	// the same flags as the tools, so that the same environment and config
+	// file can be checked
+	config.RegistCommonFlags(pflag.CommandLine)
+	config.RegistGitStorageFlags(pflag.CommandLine)
+	config.RegistGithubTokenFlags(pflag.CommandLine)
+	config.RegistSampleFlags(pflag.CommandLine)
+	config.RegistHTTPCacheFlags(pflag.CommandLine)
+	config.RegistDepsDevFlags(pflag.CommandLine)
+	config.RegistLibrariesIOFlags(pflag.CommandLine)
+	config.RegistStackExchangeFlags(pflag.CommandLine)
+	config.RegistResponseArchiveFlags(pflag.CommandLine)
+	config.RegistOutputFlags(pflag.CommandLine)
+	config.RegistFreshnessFlags(pflag.CommandLine)
+	config.RegistPriorityFlags(pflag.CommandLine)
+	config.RegistBundleFlags(pflag.CommandLine)
+	config.RegistScoreFlags(pflag.CommandLine)
+	config.RegistGraphFlags(pflag.CommandLine)
+	config.RegistPageRankFlags(pflag.CommandLine)
+	config.RegistSLAFlags(pflag.CommandLine)
+	config.RegistProgressFlags(pflag.CommandLine)
+	config.RegistFailureFlags(pflag.CommandLine)
+	config.RegistSpoolFlags(pflag.CommandLine)
+	config.RegistMirrorFlags(pflag.CommandLine)
+	// ParseFlags exits on an invalid config, which is reported here instead
+	pflag.Parse()
+	logger.ConfigAsCommandLineTool()
+
+	switch pflag.Arg(0) {
+	case "check":
+		if err := runCheck(); err != nil {
+			logger.Errorf("Config check failed: %v", err)
+			os.Exit(1)
+		}
+	default:
+		pflag.Usage()
+		os.Exit(1)
+	}
+}
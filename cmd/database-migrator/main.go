@@ -1,36 +1,57 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"regexp"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/HUSTSecLab/criticality_score/pkg/config"
 	"github.com/HUSTSecLab/criticality_score/pkg/logger"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/grants"
 	"github.com/spf13/pflag"
 )
 
-func main() {
-	config.RegistCommonFlags(pflag.CommandLine)
-	config.ParseFlags(pflag.CommandLine)
-	logger.ConfigAsCommandLineTool()
+var flagPrint = pflag.Bool("print", false, "print the grants instead of writing a migration, used by the grants subcommand")
 
-	ctx := storage.GetDefaultAppDatabaseContext()
+var (
+	ErrInvalidMigrationName = errors.New("invalid migration name")
+	ErrUnknownLastMigration = errors.New("last applied migration not found in migration files")
+)
 
-	result, err := os.ReadDir("migrations")
-	if err != nil {
-		fmt.Printf("Failed to read migrations directory: %v\n", err)
-		fmt.Println("Please make sure you are running this command in the root directory of the project")
-		panic(err)
-	}
+// MigrationError is returned when a migration fails to be applied.
+type MigrationError struct {
+	Version string
+	Name    string
+	Err     error
+}
 
-	type MigrationItem struct {
-		Version  string
-		Name     string
-		FileName string
+func (e *MigrationError) Error() string {
+	return fmt.Sprintf("migration %s (%s): %v", e.Version, e.Name, e.Err)
+}
+
+func (e *MigrationError) Unwrap() error {
+	return e.Err
+}
+
+type MigrationItem struct {
+	Version  string
+	Name     string
+	FileName string
+}
+
+var migrationNameRegexp = regexp.MustCompile(`^(\d{4}_\d{2}_\d{2}_\d{2})_(.+)$`)
+
+// loadMigrations returns the migrations in dir sorted by version.
+func loadMigrations(dir string) ([]MigrationItem, error) {
+	result, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations directory, please make sure you are running this command in the root directory of the project: %w", err)
 	}
 
 	migrations := make([]MigrationItem, 0)
@@ -40,19 +61,16 @@ func main() {
 			continue
 		}
 
-		filePath := fmt.Sprintf("migrations/%s/migration.sql", file.Name())
+		filePath := fmt.Sprintf("%s/%s/migration.sql", dir, file.Name())
 
 		stat, err := os.Stat(filePath)
 		if err != nil || stat.IsDir() {
 			continue
 		}
 
-		reg := regexp.MustCompile(`^(\d{4}_\d{2}_\d{2}_\d{2})_(.+)$`)
-		matches := reg.FindStringSubmatch(file.Name())
-
+		matches := migrationNameRegexp.FindStringSubmatch(file.Name())
 		if len(matches) != 3 {
-			logger.Errorf("Invalid migration file name: %s", file.Name())
-			continue
+			return nil, fmt.Errorf("%w: %s", ErrInvalidMigrationName, file.Name())
 		}
 
 		migrations = append(migrations, MigrationItem{
@@ -65,18 +83,63 @@ func main() {
 	sort.Slice(migrations, func(i, j int) bool {
 		return migrations[i].Version < migrations[j].Version
 	})
+	return migrations, nil
+}
+
+// lastMigrationVersion returns the version of the last applied migration,
+// or an empty string if the database is not set up yet.
+func lastMigrationVersion(ctx storage.AppDatabaseContext) (string, error) {
+	dbResult, err := ctx.Query("SELECT version FROM _migrations_history ORDER BY id DESC LIMIT 1")
+	if err != nil {
+		// the history table does not exist before the first migration
+		return "", nil
+	}
+	defer dbResult.Close()
+
+	var lastVersion string
+	if dbResult.Next() {
+		if err := dbResult.Scan(&lastVersion); err != nil {
+			return "", fmt.Errorf("failed to read migration history: %w", err)
+		}
+	}
+	return lastVersion, dbResult.Err()
+}
+
+func applyMigration(ctx storage.AppDatabaseContext, migration MigrationItem) error {
+	file, err := os.ReadFile(migration.FileName)
+	if err != nil {
+		return &MigrationError{Version: migration.Version, Name: migration.Name, Err: err}
+	}
+
+	if _, err = ctx.Exec(string(file)); err != nil {
+		return &MigrationError{Version: migration.Version, Name: migration.Name, Err: err}
+	}
+
+	_, err = ctx.Exec("INSERT INTO _migrations_history (version, name, time) VALUES ($1, $2, $3)", migration.Version, migration.Name, time.Now())
+	if err != nil {
+		return &MigrationError{Version: migration.Version, Name: migration.Name, Err: fmt.Errorf("failed to update migration history: %w", err)}
+	}
+	return nil
+}
+
+func run() error {
+	ctx := storage.GetDefaultAppDatabaseContext()
+
+	migrations, err := loadMigrations("migrations")
+	if err != nil {
+		return err
+	}
 
 	fmt.Println("Found migrations:")
 	for _, migration := range migrations {
 		fmt.Printf("  %s (%s) in `%s`\n", migration.Version, migration.Name, migration.FileName)
 	}
 
-	dbResult, err := ctx.Query("SELECT version FROM _migrations_history ORDER BY id DESC LIMIT 1")
-
-	var lastVersion string = ""
-
-	if err == nil && dbResult.Next() {
-		dbResult.Scan(&lastVersion)
+	lastVersion, err := lastMigrationVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if lastVersion != "" {
 		fmt.Printf("Last migration version: %s\n", lastVersion)
 	} else {
 		fmt.Printf("No migration history found, the migration will setup database.\n")
@@ -92,13 +155,12 @@ func main() {
 	}
 
 	if lastVersion != "" && fromIdx == 0 {
-		fmt.Printf("Migration history is not found, please check the migration files.\n")
-		return
+		return fmt.Errorf("%w: %s", ErrUnknownLastMigration, lastVersion)
 	}
 
 	if fromIdx == len(migrations) {
 		fmt.Printf("Database is up to date, no migration needed.\n")
-		return
+		return nil
 	}
 
 	fmt.Printf("Following migrations will be applied:\n")
@@ -112,34 +174,82 @@ func main() {
 
 	if confirm != "y" {
 		fmt.Printf("Migration cancelled\n")
-		return
+		return nil
 	}
 
 	for i := fromIdx; i < len(migrations); i++ {
 		migration := migrations[i]
 
 		fmt.Printf("Applying migration %s (%s)...\n", migration.Version, migration.Name)
-
-		file, err := os.ReadFile(migration.FileName)
-		if err != nil {
-			fmt.Printf("Failed to read migration file: %v\n", err)
-			return
+		if err := applyMigration(ctx, migration); err != nil {
+			return err
 		}
+		fmt.Printf("Migration %s (%s) applied successfully\n", migration.Version, migration.Name)
+	}
+	return nil
+}
 
-		_, err = ctx.Exec(string(file))
-		if err != nil {
-			fmt.Printf("Failed to execute migration: %v\n", err)
-			return
+// nextVersion returns the version of a new migration created at now, e.g.
+// 2025_01_14_02 if 2025_01_14_01 exists.
+func nextVersion(migrations []MigrationItem, now time.Time) string {
+	date := now.Format("2006_01_02")
+	next := 0
+	for _, m := range migrations {
+		var n int
+		if strings.HasPrefix(m.Version, date+"_") {
+			if _, err := fmt.Sscanf(m.Version[len(date)+1:], "%d", &n); err == nil && n >= next {
+				next = n + 1
+			}
 		}
+	}
+	return fmt.Sprintf("%s_%02d", date, next)
+}
 
-		_, err = ctx.Exec("INSERT INTO _migrations_history (version, name, time) VALUES ($1, $2, $3)", migration.Version, migration.Name, time.Now())
+// runGrants writes the grants of all components into a new migration, so
+// roles are updated with the schema.
+func runGrants() error {
+	sql := grants.SQL(grants.Components)
+	if *flagPrint {
+		fmt.Print(sql)
+		return nil
+	}
 
-		if err != nil {
-			fmt.Printf("Failed to update migration history: %v\n", err)
-			return
-		}
+	migrations, err := loadMigrations("migrations")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join("migrations", nextVersion(migrations, time.Now())+"_grants")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	file := filepath.Join(dir, "migration.sql")
+	if err := os.WriteFile(file, []byte(sql), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Grants are written into `%s`, apply them by running the migrator\n", file)
+	return nil
+}
 
-		fmt.Printf("Migration %s (%s) applied successfully\n", migration.Version, migration.Name)
+func main() {
+	config.RegistCommonFlags(pflag.CommandLine)
+	config.ParseFlags(pflag.CommandLine)
+	logger.ConfigAsCommandLineTool()
+
+	switch pflag.Arg(0) {
+	case "":
+	case "grants":
+		if err := runGrants(); err != nil {
+			logger.Errorf("Generating grants failed: %v", err)
+			os.Exit(1)
+		}
+		return
+	default:
+		logger.Errorf("Unknown subcommand: %s", pflag.Arg(0))
+		os.Exit(1)
 	}
 
+	if err := run(); err != nil {
+		logger.Errorf("Migration failed: %v", err)
+		os.Exit(1)
+	}
 }
@@ -2,25 +2,39 @@ package checkvalid
 
 import (
 	"database/sql"
-	"encoding/csv"
 	"os"
 	"os/exec"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
 )
 
+// Report reports an invalid link and the reason, it is called concurrently.
+type Report func(link, reason string)
+
 type Metrics struct {
 	CreatedSince time.Time
 	UpdatedSince time.Time
 	Score        float64
 }
 
-var repoList = []string{"debian_packages", "arch_packages", "gentoo_packages", "nix_packages", "homebrew_packages"}
+var repoList = []string{
+	repository.DistPackageTableName(repository.DistLinkTablePrefixDebian),
+	repository.DistPackageTableName(repository.DistLinkTablePrefixArchlinux),
+	repository.DistPackageTableName(repository.DistLinkTablePrefixGentoo),
+	repository.DistPackageTableName(repository.DistLinkTablePrefixNix),
+	repository.DistPackageTableName(repository.DistLinkTablePrefixHomebrew),
+}
 
 func fetchDistroGitlink(gitlink *sql.DB, repo string) []string {
-	query := "SELECT git_link FROM " + repo
-	rows, err := gitlink.Query(query)
+	query, args, err := sqlutil.From(repo).Select("git_link")
+	if err != nil {
+		panic(err)
+	}
+	rows, err := gitlink.Query(query, args...)
 	if err != nil {
 		panic(err)
 	}
@@ -39,20 +53,18 @@ func fetchDistroGitlink(gitlink *sql.DB, repo string) []string {
 	return gitLinks
 }
 
-func checkDistroValid(gitlink *sql.DB, repo string) [][]string {
+func checkDistroValid(gitlink *sql.DB, repo string, report Report) {
 	gitLinks := fetchDistroGitlink(gitlink, repo)
-	var invalidLinks [][]string
 	for _, link := range gitLinks {
 		if link == "" || link == "NA" || link == "NaN" {
 			continue
 		}
 		if !strings.HasPrefix(link, "http://") && !strings.HasPrefix(link, "https://") && !strings.HasPrefix(link, "git://") {
-			invalidLinks = append(invalidLinks, []string{link, "invalid protocol"})
+			report(link, "invalid protocol")
 		} else if strings.Contains(link, "/tree/") {
-			invalidLinks = append(invalidLinks, []string{link, "invalid link"})
+			report(link, "invalid link")
 		}
 	}
-	return invalidLinks
 }
 
 func fetchMetrics(db *sql.DB) map[string]Metrics {
@@ -91,21 +103,19 @@ func fetchMetrics(db *sql.DB) map[string]Metrics {
 	return MetricsList
 }
 
-func checkMetricsValid(db *sql.DB) [][]string {
+func checkMetricsValid(db *sql.DB, report Report) {
 	MetricsList := fetchMetrics(db)
-	var invalidLinks [][]string
 	for link, metrics := range MetricsList {
 		duration := metrics.CreatedSince.Sub(metrics.UpdatedSince)
 		if duration > 0 {
-			invalidLinks = append(invalidLinks, []string{link, "created_since is after updated_since"})
+			report(link, "created_since is after updated_since")
 		} else if metrics.Score < 0 {
-			invalidLinks = append(invalidLinks, []string{link, "score is less than 0"})
+			report(link, "score is less than 0")
 		}
 	}
-	return invalidLinks
 }
 
-func checkCloneValid(db *sql.DB, maxThreads int) [][]string {
+func checkCloneValid(db *sql.DB, maxThreads int, report Report) {
 	query := "SELECT git_link FROM git_metrics WHERE clone_valid = false"
 	rows, err := db.Query(query)
 	if err != nil {
@@ -121,10 +131,8 @@ func checkCloneValid(db *sql.DB, maxThreads int) [][]string {
 		}
 		gitLinks = append(gitLinks, gitLink)
 	}
-	var invalidLinks [][]string
 	sem := make(chan struct{}, maxThreads)
 	var wg sync.WaitGroup
-	var mu sync.Mutex
 
 	for _, link := range gitLinks {
 		wg.Add(1)
@@ -135,9 +143,7 @@ func checkCloneValid(db *sql.DB, maxThreads int) [][]string {
 
 			tempDir, err := os.MkdirTemp("", "test_repo_*")
 			if err != nil {
-				mu.Lock()
-				invalidLinks = append(invalidLinks, []string{gitLink, "failed to create temp directory"})
-				mu.Unlock()
+				report(gitLink, "failed to create temp directory")
 				return
 			}
 			defer os.RemoveAll(tempDir)
@@ -145,9 +151,7 @@ func checkCloneValid(db *sql.DB, maxThreads int) [][]string {
 			cmd := exec.Command("git", "clone", "--depth=1", gitLink, tempDir)
 			err = cmd.Start()
 			if err != nil {
-				mu.Lock()
-				invalidLinks = append(invalidLinks, []string{gitLink, "failed to clone"})
-				mu.Unlock()
+				report(gitLink, "failed to clone")
 				return
 			}
 			done := make(chan error, 1)
@@ -157,30 +161,24 @@ func checkCloneValid(db *sql.DB, maxThreads int) [][]string {
 			select {
 			case <-time.After(15 * time.Second):
 				cmd.Process.Kill()
-				mu.Lock()
-				invalidLinks = append(invalidLinks, []string{gitLink, "clone timed out"})
-				mu.Unlock()
+				report(gitLink, "clone timed out")
 			case err := <-done:
 				if err != nil {
-					mu.Lock()
-					invalidLinks = append(invalidLinks, []string{gitLink, "failed to clone"})
-					mu.Unlock()
+					report(gitLink, "failed to clone")
 				}
 			}
 		}(link)
 	}
 	wg.Wait()
-	return invalidLinks
 }
 
-func checkCloneValidDefault(db *sql.DB, maxThreads int) [][]string {
+func checkCloneValidDefault(db *sql.DB, maxThreads int, report Report) {
 	query := "SELECT git_link, created_since FROM git_metrics"
 	rows, err := db.Query(query)
 	if err != nil {
 		panic(err)
 	}
 	defer rows.Close()
-	var invalidLinks [][]string
 
 	sem := make(chan struct{}, maxThreads)
 	var wg sync.WaitGroup
@@ -203,7 +201,7 @@ func checkCloneValidDefault(db *sql.DB, maxThreads int) [][]string {
 			if strings.Contains(gitLink, "sourceforge.net") || strings.Contains(gitLink, "sf.net") {
 				tempDir, err := os.MkdirTemp("", "test_repo_*")
 				if err != nil {
-					invalidLinks = append(invalidLinks, []string{gitLink, "failed to create temp directory"})
+					report(gitLink, "failed to create temp directory")
 					return
 				}
 				defer os.RemoveAll(tempDir)
@@ -211,7 +209,7 @@ func checkCloneValidDefault(db *sql.DB, maxThreads int) [][]string {
 				cmd := exec.Command("git", "clone", "--depth=1", gitLink, tempDir)
 				err = cmd.Start()
 				if err != nil {
-					invalidLinks = append(invalidLinks, []string{gitLink, "failed to clone"})
+					report(gitLink, "failed to clone")
 					return
 				}
 				done := make(chan error, 1)
@@ -221,48 +219,32 @@ func checkCloneValidDefault(db *sql.DB, maxThreads int) [][]string {
 				select {
 				case <-time.After(15 * time.Second):
 					cmd.Process.Kill()
-					invalidLinks = append(invalidLinks, []string{gitLink, "clone timed out"})
+					report(gitLink, "clone timed out")
 				case err := <-done:
 					if err != nil {
-						invalidLinks = append(invalidLinks, []string{gitLink, "failed to clone"})
+						report(gitLink, "failed to clone")
 					}
 				}
 			} else if createdSince.Valid {
 				if createdSince.Time.Sub(time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)) == 0 {
-					invalidLinks = append(invalidLinks, []string{gitLink, "created_since is 0001-01-01, maybe cannot clone"})
+					report(gitLink, "created_since is 0001-01-01, maybe cannot clone")
 				}
 			}
 		}(gitLink, createdSince)
 	}
 
 	wg.Wait()
-	return invalidLinks
 }
-func CheckVaild(db *sql.DB, checkCloneValidflag bool, maxThreads int) [][]string {
-	var invalidLinks [][]string
+
+// CheckVaild reports the invalid links as they are found.
+func CheckVaild(db *sql.DB, checkCloneValidflag bool, maxThreads int, report Report) {
 	for _, repo := range repoList {
-		invalidLinks = append(invalidLinks, checkDistroValid(db, repo)...)
+		checkDistroValid(db, repo, report)
 	}
-	invalidLinks = append(invalidLinks, checkMetricsValid(db)...)
+	checkMetricsValid(db, report)
 	if checkCloneValidflag {
-		invalidLinks = append(invalidLinks, checkCloneValid(db, maxThreads)...)
+		checkCloneValid(db, maxThreads, report)
 	} else {
-		invalidLinks = append(invalidLinks, checkCloneValidDefault(db, maxThreads)...)
-	}
-	return invalidLinks
-}
-
-func WriteCsv(invalidLinks [][]string, outputFile string) {
-	file, err := os.Create(outputFile)
-	if err != nil {
-		panic(err)
-	}
-	defer file.Close()
-	writer := csv.NewWriter(file)
-	for _, value := range invalidLinks {
-		link := value[0]
-		reason := value[1]
-		writer.Write([]string{link, reason})
-		writer.Flush()
+		checkCloneValidDefault(db, maxThreads, report)
 	}
 }
@@ -5,24 +5,38 @@ import (
 
 	"github.com/HUSTSecLab/criticality_score/cmd/database-validator/internal/checkvalid"
 	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	gitUtil "github.com/HUSTSecLab/criticality_score/pkg/gitfile/util"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
 	"github.com/spf13/pflag"
 )
 
-var flagOutputFile = pflag.String("output", "output.csv", "path to the output file")
+var flagOutputFile = pflag.String("output", "output.csv", "path to the output file, - for stdout")
+var flagOutputMode = pflag.String("output-mode", string(gitUtil.ModeTruncate), "how an existing output file is written: truncate, append or rotate")
 var flagCheckCloneValid = pflag.Bool("checkCloneValid", false, "check clone valid")
 var flagMaxThreads = pflag.Int("maxThreads", 10, "max threads")
 
 func main() {
 	config.RegistCommonFlags(pflag.CommandLine)
 	config.ParseFlags(pflag.CommandLine)
-	db, err := storage.GetDefaultAppDatabaseContext().GetDatabaseConnection()
+	db, err := storage.GetDefaultReadOnlyAppDatabaseContext().GetDatabaseConnection()
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
-
 	defer db.Close()
-	invalidLinks := checkvalid.CheckVaild(db, *flagCheckCloneValid, *flagMaxThreads)
-	checkvalid.WriteCsv(invalidLinks, *flagOutputFile)
+
+	// rows are written as they are found, so a long clone check keeps its
+	// results if it is interrupted
+	w, err := gitUtil.NewCSVWriter(*flagOutputFile, gitUtil.WriteMode(*flagOutputMode), nil)
+	if err != nil {
+		log.Fatalf("Failed to open %s: %v", *flagOutputFile, err)
+	}
+	checkvalid.CheckVaild(db, *flagCheckCloneValid, *flagMaxThreads, func(link, reason string) {
+		if err := w.Write([]string{link, reason}); err != nil {
+			log.Printf("Failed to write %s: %v", link, err)
+		}
+	})
+	if err := w.Close(); err != nil {
+		log.Fatalf("Failed to write %s: %v", *flagOutputFile, err)
+	}
 	log.Println("checkvalid finished")
 }
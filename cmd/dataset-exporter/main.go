@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/export"
+	"github.com/HUSTSecLab/criticality_score/pkg/schema"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/spf13/pflag"
+)
+
+var (
+	flagOutput   = pflag.StringP("output", "o", "", "write newline-delimited JSON files into the directory")
+	flagBucket   = pflag.String("gcs-bucket", "", "upload newline-delimited JSON files into the GCS bucket")
+	flagPrefix   = pflag.String("prefix", "", "prefix of the exported file names, e.g. criticality/")
+	flagProject  = pflag.String("bq-project", "", "load the uploaded files into BigQuery tables of the project, requires --gcs-bucket")
+	flagDataset  = pflag.String("bq-dataset", "criticality_score", "BigQuery dataset of the tables")
+	flagToken    = pflag.String("gcp-token", os.Getenv("GCP_ACCESS_TOKEN"), "OAuth2 access token of GCP, e.g. from `gcloud auth print-access-token`,\ncan set by environment GCP_ACCESS_TOKEN")
+	flagInterval = pflag.Duration("interval", 0, "export again after the interval, 0 exports once")
+	flagBaseline = pflag.String("schema-baseline", "", "schema.json of the last export, the export fails if the schema changes without a new version")
+)
+
+func main() {
+	pflag.Usage = func() {
+		fmt.Println("This tool exports the scores and metrics tables as newline-delimited JSON, and loads them into BigQuery.")
+		fmt.Printf("Usage: %s [options...]\n", os.Args[0])
+		pflag.PrintDefaults()
+	}
+
+	config.RegistCommonFlags(pflag.CommandLine)
+	config.ParseFlags(pflag.CommandLine)
+
+	var sink export.Sink
+	switch {
+	case *flagBucket != "":
+		sink = &export.GCSSink{Bucket: *flagBucket, Token: *flagToken}
+	case *flagOutput != "":
+		sink = &export.DirSink{Dir: *flagOutput}
+	default:
+		log.Fatal("Either --output or --gcs-bucket is required")
+	}
+
+	var loader *export.BigQueryLoader
+	if *flagProject != "" {
+		if *flagBucket == "" {
+			log.Fatal("--bq-project requires --gcs-bucket")
+		}
+		loader = &export.BigQueryLoader{Project: *flagProject, Dataset: *flagDataset, Token: *flagToken}
+	}
+
+	tables, err := export.Tables(storage.GetDefaultReadOnlyAppDatabaseContext())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *flagBaseline != "" {
+		released, err := schema.Read(*flagBaseline)
+		if err != nil {
+			log.Fatalf("Failed to read schema baseline: %v", err)
+		}
+		if err := schema.Check(released, export.Descriptor(tables)); err != nil {
+			log.Fatalf("Incompatible schema: %v", err)
+		}
+	}
+
+	for {
+		if err := export.Export(tables, sink, loader, *flagPrefix); err != nil {
+			if *flagInterval == 0 {
+				log.Fatal(err)
+			}
+			log.Printf("Export failed: %v", err)
+		}
+		if *flagInterval == 0 {
+			return
+		}
+		time.Sleep(*flagInterval)
+	}
+}
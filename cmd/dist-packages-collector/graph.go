@@ -0,0 +1,100 @@
+package main
+
+import (
+	"log"
+	"strings"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/collector/distros"
+	"github.com/HUSTSecLab/criticality_score/pkg/graph"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+	"github.com/lib/pq"
+)
+
+// maxReportedCycles is the max number of cycles printed in the report.
+const maxReportedCycles = 10
+
+// refreshGraph reloads the graph of the collected distribution in the
+// shared graph service, if enabled, so its file is up to date for others.
+func refreshGraph(distType string) {
+	s := graph.Default()
+	prefix, ok := distros.Prefix(distType)
+	if s == nil || !ok {
+		return
+	}
+	if err := s.Refresh(prefix); err != nil {
+		log.Printf("Failed to refresh dependency graph: %v", err)
+	}
+}
+
+// loadGraph loads the graph of the distribution from the shared graph
+// service if enabled, otherwise from the database.
+func loadGraph(ac storage.AppDatabaseContext, prefix repository.DistPackageTablePrefix) (*graph.Graph, error) {
+	if s := graph.Default(); s != nil {
+		c, err := s.Get(prefix)
+		if err != nil {
+			return nil, err
+		}
+		return c.Graph(), nil
+	}
+	return graph.LoadDist(ac, prefix)
+}
+
+// analyzeGraph reports dependency cycles of the collected distribution, and
+// rewrites depends_count with the condensed graph if condense is true.
+func analyzeGraph(distType string, condense bool) {
+	prefix, ok := distros.Prefix(distType)
+	if !ok {
+		return
+	}
+
+	ac := storage.GetDefaultAppDatabaseContext()
+	g, err := loadGraph(ac, prefix)
+	if err != nil {
+		log.Printf("Failed to load dependency graph: %v", err)
+		return
+	}
+
+	cycles := g.Cycles()
+	inCycles := 0
+	for _, c := range cycles {
+		inCycles += len(c)
+	}
+	log.Printf("Found %d dependency cycles, %d of %d packages are in cycles", len(cycles), inCycles, g.NodeCount())
+	for i, c := range cycles {
+		if i >= maxReportedCycles {
+			log.Printf("  ... and %d more", len(cycles)-maxReportedCycles)
+			break
+		}
+		if len(c) > maxReportedCycles {
+			log.Printf("  [%d] %s, ...", len(c), strings.Join(c[:maxReportedCycles], ", "))
+		} else {
+			log.Printf("  [%d] %s", len(c), strings.Join(c, ", "))
+		}
+	}
+
+	if !condense {
+		return
+	}
+
+	log.Println("Updating depends_count with condensed graph...")
+	names := make([]string, 0, g.NodeCount())
+	counts := make([]int64, 0, g.NodeCount())
+	for name, count := range g.TransitiveDependentCounts(true) {
+		names = append(names, name)
+		counts = append(counts, int64(count))
+	}
+	table, err := sqlutil.Table(repository.DistPackageTableName(prefix))
+	if err != nil {
+		log.Printf("Failed to update depends_count: %v", err)
+		return
+	}
+	_, err = ac.Exec(`UPDATE `+table+` p
+		SET depends_count = c.count
+		FROM UNNEST($1::text[], $2::bigint[]) AS c(package, count)
+		WHERE p.package = c.package`, pq.Array(names), pq.Array(counts))
+	if err != nil {
+		log.Printf("Failed to update depends_count: %v", err)
+	}
+}
@@ -1,61 +1,75 @@
-package main
-
-import (
-	"log"
-
-	"github.com/HUSTSecLab/criticality_score/pkg/collector/alpine"
-	"github.com/HUSTSecLab/criticality_score/pkg/collector/archlinux"
-	"github.com/HUSTSecLab/criticality_score/pkg/collector/aur"
-	"github.com/HUSTSecLab/criticality_score/pkg/collector/centos"
-	"github.com/HUSTSecLab/criticality_score/pkg/collector/debian"
-	"github.com/HUSTSecLab/criticality_score/pkg/collector/deepin"
-	"github.com/HUSTSecLab/criticality_score/pkg/collector/fedora"
-	"github.com/HUSTSecLab/criticality_score/pkg/collector/gentoo"
-	"github.com/HUSTSecLab/criticality_score/pkg/collector/homebrew"
-	"github.com/HUSTSecLab/criticality_score/pkg/collector/nix"
-	"github.com/HUSTSecLab/criticality_score/pkg/collector/ubuntu"
-	"github.com/HUSTSecLab/criticality_score/pkg/config"
-	"github.com/spf13/pflag"
-)
-
-var (
-	flagType    = pflag.String("type", "", "type of the distribution")
-	flagGenDot  = pflag.String("gendot", "", "output dot file")
-	workerCount = pflag.Int("worker", 1, "number of workers")
-	batchSize   = pflag.Int("batch", 1000, "batch size")
-	downloadDir = pflag.String("downloadDir", "./download", "download directory")
-	extractDir  = pflag.String("extractDir", "./extract", "extract directory")
-)
-
-func main() {
-	config.RegistCommonFlags(pflag.CommandLine)
-	config.ParseFlags(pflag.CommandLine)
-
-	switch *flagType {
-	case "archlinux":
-		archlinux.NewArchLinux(*downloadDir, *extractDir).Collect(*flagGenDot)
-	case "debian":
-		debian.NewDebianCollector().Collect(*flagGenDot)
-	case "deepin":
-		deepin.NewDeepinCollector().Collect(*flagGenDot)
-	case "ubuntu":
-		ubuntu.NewUbuntuCollector().Collect(*flagGenDot)
-	case "nix":
-		if *flagGenDot == "" {
-			log.Fatal("Nix not support gendot")
-		}
-		nix.NewNixCollector().Collect(*workerCount, *batchSize)
-	case "homebrew":
-		homebrew.NewHomebrewCollector().Collect(*flagGenDot)
-	case "gentoo":
-		gentoo.NewGentooCollector().Collect(*flagGenDot)
-	case "fedora":
-		fedora.NewFedoraCollector().Collect(*flagGenDot)
-	case "centos":
-		centos.NewCentosCollector().Collect(*flagGenDot)
-	case "alpine":
-		alpine.NewAlpineCollector().Collect(*flagGenDot)
-	case "aur":
-		aur.NewAurCollector().Collect(*flagGenDot)
-	}
-}
+package main
+
+import (
+	"log"
+	"strings"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/collector/distros"
+	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/failure"
+	"github.com/HUSTSecLab/criticality_score/pkg/freshness"
+	"github.com/HUSTSecLab/criticality_score/pkg/graph"
+	"github.com/HUSTSecLab/criticality_score/pkg/progress"
+	"github.com/HUSTSecLab/criticality_score/pkg/scope"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/spf13/pflag"
+)
+
+var (
+	flagType    = pflag.String("type", "", "type of the distribution: "+strings.Join(distros.Names, ", "))
+	flagGenDot  = pflag.String("gendot", "", "output graph file, in GraphML format if ends with .graphml, otherwise DOT")
+	workerCount = pflag.Int("worker", 1, "number of workers")
+	batchSize   = pflag.Int("batch", 1000, "batch size")
+	downloadDir = pflag.String("downloadDir", "./download", "download directory")
+	extractDir  = pflag.String("extractDir", "./extract", "extract directory")
+	flagCycles  = pflag.Bool("report-cycles", true, "report dependency cycles after collecting")
+	condense    = pflag.Bool("condense", false, "condense dependency cycles when computing depends_count")
+	gendotColor = pflag.Bool("gendot-color", false, "color nodes in the dot file by PageRank decile")
+)
+
+func main() {
+	config.RegistCommonFlags(pflag.CommandLine)
+	config.RegistMirrorFlags(pflag.CommandLine)
+	config.RegistSampleFlags(pflag.CommandLine)
+	config.RegistProgressFlags(pflag.CommandLine)
+	config.RegistFailureFlags(pflag.CommandLine)
+	config.RegistGraphFlags(pflag.CommandLine)
+	config.RegistPageRankFlags(pflag.CommandLine)
+	config.RegistScopeFlags(pflag.CommandLine)
+	config.ParseFlags(pflag.CommandLine)
+	// deferred first, so it exits after the progress view is stopped
+	defer failure.Default().Exit()
+	defer progress.Default().Stop()
+	graph.ColorByPageRank = *gendotColor
+
+	if !scope.Default().Distro(*flagType) {
+		log.Printf("Skipping %s, the distribution is out of scope", *flagType)
+		return
+	}
+
+	err := distros.Collect(*flagType, failure.Default(), distros.Options{
+		GenDot:      *flagGenDot,
+		DownloadDir: *downloadDir,
+		ExtractDir:  *extractDir,
+		Workers:     *workerCount,
+		BatchSize:   *batchSize,
+	})
+	// recorded for the freshness monitor, a failure is not fatal
+	if err := freshness.RecordRun(storage.GetDefaultAppDatabaseContext(), *flagType, err); err != nil {
+		log.Printf("Failed to record the run of %s: %v", *flagType, err)
+	}
+	if err != nil {
+		failure.Default().Fatal(err)
+	}
+
+	refreshGraph(*flagType)
+	if *flagCycles || *condense {
+		analyzeGraph(*flagType, *condense)
+	}
+
+	// only the owner of the view can refresh it, a failure is not fatal
+	if err := repository.NewMaterializedViewRepository(storage.GetDefaultAppDatabaseContext()).Refresh(repository.CoverageStatsViewName); err != nil {
+		log.Printf("Failed to refresh coverage stats: %v", err)
+	}
+}
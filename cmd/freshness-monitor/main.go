@@ -0,0 +1,123 @@
+// freshness-monitor checks the freshness SLA of the collected data: the
+// last successful run of every collector in scope, and the rate of projects
+// whose signals are older than their max age. The status command prints the
+// checks and exits with 1 if any is breached, so it can run from cron, and
+// the serve command exposes them to Prometheus, whose alerting rules are in
+// docs/tools/freshness_monitor.md.
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/collector/distros"
+	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/freshness"
+	"github.com/HUSTSecLab/criticality_score/pkg/scope"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/spf13/pflag"
+)
+
+var (
+	flagListen  = pflag.String("listen", ":9108", "address of the metrics endpoint of serve")
+	flagLangEco = pflag.Bool("lang-ecosystem", true, "check the collector of language ecosystems")
+)
+
+// collectors returns the collectors checked, the distributions in scope
+// and the language ecosystems.
+func collectors() []string {
+	var ret []string
+	for _, name := range distros.Names {
+		if scope.Default().Distro(name) {
+			ret = append(ret, name)
+		}
+	}
+	if *flagLangEco {
+		ret = append(ret, freshness.CollectorLangEcosystem)
+	}
+	return ret
+}
+
+func check() ([]freshness.Status, error) {
+	sla, err := config.GetSLAConfig()
+	if err != nil {
+		return nil, err
+	}
+	return sla.Check(storage.GetDefaultAppDatabaseContext(), collectors(), time.Now())
+}
+
+// status prints the checks and returns the number of breached ones.
+func status() int {
+	statuses, err := check()
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tNAME\tMAX AGE\tLAST SUCCESS\tSTALE\tSTATUS")
+	for _, st := range statuses {
+		last := "never"
+		if st.LastSuccess != nil {
+			last = st.LastSuccess.Format(time.DateTime)
+		}
+		stale := "-"
+		if st.Kind == freshness.KindSignal {
+			stale = fmt.Sprintf("%d/%d", st.Stale, st.Projects)
+		}
+		state := "ok"
+		if st.Breached {
+			state = "BREACHED: " + st.Reason
+		}
+		if st.LastError != "" {
+			state += " (last error: " + st.LastError + ")"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", st.Kind, st.Name, st.MaxAge, last, stale, state)
+	}
+	w.Flush()
+	return len(freshness.Breached(statuses))
+}
+
+// serve checks on every scrape, the queries only aggregate two tables.
+func serve() {
+	http.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		statuses, err := check()
+		if err != nil {
+			log.Printf("Checking freshness Failed: %v", err)
+			http.Error(w, "checking freshness failed", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		freshness.WritePrometheus(w, statuses)
+	})
+	log.Printf("Serving metrics on %s/metrics", *flagListen)
+	log.Fatal(http.ListenAndServe(*flagListen, nil))
+}
+
+func main() {
+	pflag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s status|serve [flags]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Checks the freshness SLA of the collectors (%s, %s) and of the signals of projects.\n", strings.Join(distros.Names, ", "), freshness.CollectorLangEcosystem)
+		pflag.PrintDefaults()
+	}
+	config.RegistCommonFlags(pflag.CommandLine)
+	config.RegistScopeFlags(pflag.CommandLine)
+	config.RegistSLAFlags(pflag.CommandLine)
+	config.ParseFlags(pflag.CommandLine)
+
+	switch pflag.Arg(0) {
+	case "status":
+		if n := status(); n > 0 {
+			log.Printf("%d checks breach the freshness SLA", n)
+			os.Exit(1)
+		}
+	case "serve":
+		serve()
+	default:
+		pflag.Usage()
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,167 @@
+// funding-allocator proposes how to spend an annual funding budget on the
+// projects of the latest scores, so that the funded projects cover the most
+// criticality. Projects are valued by their score raised by their risk
+// flags, e.g. a dormant project with a single maintainer, and cost the
+// --default-cost unless --costs has their own estimate. The report lists
+// the funded projects, ready to be handed to a foundation.
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/analysis/funding"
+	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/scope"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/HUSTSecLab/criticality_score/pkg/tagging"
+	"github.com/spf13/pflag"
+)
+
+var (
+	flagBudget      = pflag.Float64("budget", 0, "funding budget, e.g. 1000000")
+	flagDefaultCost = pflag.Float64("default-cost", 50000, "cost of funding a project without an estimate in --costs")
+	flagCosts       = pflag.String("costs", "", "csv file of the estimated costs of projects, rows of git link and cost")
+	flagMethod      = pflag.String("method", string(funding.MethodGreedy), "allocation method: greedy, knapsack")
+	flagRiskWeights = pflag.StringToString("risk-weight", nil, "weights of risk flags overriding the defaults, e.g. abandoned=1,eol-runtime=0")
+	flagTop         = pflag.Int("top", 1000, "only the projects of the highest scores are candidates, 0 for all")
+	flagFormat      = pflag.String("format", "markdown", "report format: markdown, csv, json")
+	flagOutput      = pflag.StringP("output", "o", "", "path to the report, default is stdout")
+)
+
+func riskWeights() (map[funding.Risk]float64, error) {
+	ret := make(map[funding.Risk]float64)
+	for risk, w := range funding.DefaultRiskWeights {
+		ret[risk] = w
+	}
+	for name, s := range *flagRiskWeights {
+		if !slices.Contains(funding.Risks, funding.Risk(name)) {
+			return nil, fmt.Errorf("unknown risk flag %q", name)
+		}
+		w, err := strconv.ParseFloat(s, 64)
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("invalid weight %q of %s", s, name)
+		}
+		ret[funding.Risk(name)] = w
+	}
+	return ret, nil
+}
+
+// loadProjects returns the candidates, the projects of the --top latest
+// scores in scope with their risk flags and costs.
+func loadProjects(ac storage.AppDatabaseContext, costs map[string]float64) ([]funding.Project, error) {
+	scores, err := repository.NewScoreRepository(ac).Query()
+	if err != nil {
+		return nil, err
+	}
+	var projects []funding.Project
+	for s := range scores {
+		if s.GitLink == nil || s.Score == nil {
+			continue
+		}
+		projects = append(projects, funding.Project{GitLink: *s.GitLink, Score: *s.Score})
+	}
+	link := func(p funding.Project) string { return p.GitLink }
+	projects = scope.SliceFunc(scope.Default(), tagging.SliceFunc(tagging.Default(), projects, link), link)
+	sort.Slice(projects, func(i, j int) bool { return projects[i].Score > projects[j].Score })
+	if *flagTop > 0 && len(projects) > *flagTop {
+		projects = projects[:*flagTop]
+	}
+
+	index := make(map[string]int, len(projects))
+	for i, p := range projects {
+		index[p.GitLink] = i
+		projects[i].Cost = *flagDefaultCost
+		if c, ok := costs[p.GitLink]; ok {
+			projects[i].Cost = c
+		}
+	}
+	metrics, err := repository.NewGitMetricsRepository(ac).Query()
+	if err != nil {
+		return nil, err
+	}
+	for m := range metrics {
+		if i, ok := index[*m.GitLink]; ok {
+			projects[i].Risks = funding.Flags(m)
+		}
+	}
+	return projects, nil
+}
+
+func main() {
+	pflag.Usage = func() {
+		fmt.Println("This tool is used to propose an allocation of a funding budget covering the most criticality.")
+		pflag.PrintDefaults()
+	}
+
+	config.RegistCommonFlags(pflag.CommandLine)
+	config.RegistTagFlags(pflag.CommandLine)
+	config.RegistScopeFlags(pflag.CommandLine)
+	config.ParseFlags(pflag.CommandLine)
+
+	if *flagBudget <= 0 {
+		log.Fatal("--budget must be positive")
+	}
+	method := funding.Method(*flagMethod)
+	if !slices.Contains(funding.Methods, method) {
+		log.Fatalf("Unknown method %q", *flagMethod)
+	}
+	weights, err := riskWeights()
+	if err != nil {
+		log.Fatal(err)
+	}
+	var write func(r *funding.Report, w io.Writer) error
+	switch *flagFormat {
+	case "markdown":
+		write = (*funding.Report).WriteMarkdown
+	case "csv":
+		write = (*funding.Report).WriteCSV
+	case "json":
+		write = (*funding.Report).WriteJSON
+	default:
+		log.Fatalf("Unknown format %q", *flagFormat)
+	}
+
+	costs := make(map[string]float64)
+	if *flagCosts != "" {
+		file, err := os.Open(*flagCosts)
+		if err != nil {
+			log.Fatalf("Failed to open costs: %v", err)
+		}
+		costs, err = funding.ReadCosts(file)
+		file.Close()
+		if err != nil {
+			log.Fatalf("Failed to read costs %s: %v", *flagCosts, err)
+		}
+	}
+
+	projects, err := loadProjects(storage.GetDefaultReadOnlyAppDatabaseContext(), costs)
+	if err != nil {
+		log.Fatalf("Failed to load projects: %v", err)
+	}
+	report, err := funding.Allocate(projects, &funding.Config{Budget: *flagBudget, Method: method, RiskWeights: weights})
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Allocated %.0f of %.0f to %d of %d projects, covering %.1f%% of their criticality",
+		report.Spent, report.Budget, len(report.Funded), report.Candidates, 100*report.Coverage())
+
+	var out io.Writer = os.Stdout
+	if *flagOutput != "" {
+		file, err := os.Create(*flagOutput)
+		if err != nil {
+			log.Fatalf("Failed to create output file: %v", err)
+		}
+		defer file.Close()
+		out = file
+	}
+	if err := write(report, out); err != nil {
+		log.Fatalf("Failed to write report: %v", err)
+	}
+}
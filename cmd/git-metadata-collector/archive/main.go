@@ -0,0 +1,95 @@
+// This file is used to archive the top critical repositories as versioned
+// git bundles, so they are preserved even if the upstream disappears.
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/archive"
+	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/collector"
+	url "github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser/url"
+	gitUtil "github.com/HUSTSecLab/criticality_score/pkg/gitfile/util"
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/objectstore"
+	"github.com/HUSTSecLab/criticality_score/pkg/publish"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/HUSTSecLab/criticality_score/pkg/tagging"
+	"github.com/spf13/pflag"
+)
+
+var (
+	flagTop        = pflag.Int("top", 100, "number of top critical repositories to archive")
+	flagArchiveDir = pflag.String("archive-dir", "./archives", "directory of archives if no S3 bucket is configured")
+	flagPrefix     = pflag.String("prefix", archive.DefaultPrefix, "prefix of the object keys of archives")
+)
+
+func main() {
+	pflag.Usage = func() {
+		fmt.Println("This tool archives the top critical repositories as versioned git bundles.")
+		fmt.Printf("Usage: %s [options...]\n", os.Args[0])
+		pflag.PrintDefaults()
+	}
+
+	config.RegistCommonFlags(pflag.CommandLine)
+	config.RegistGitStorageFlags(pflag.CommandLine)
+	config.MarkRequired("git.storage")
+	config.RegistBundleFlags(pflag.CommandLine)
+	config.RegistTagFlags(pflag.CommandLine)
+	config.ParseFlags(pflag.CommandLine)
+
+	ac := storage.GetDefaultAppDatabaseContext()
+
+	var store objectstore.Store = &objectstore.DirStore{Dir: *flagArchiveDir}
+	if storeConfig := config.GetBundleConfig().Store; storeConfig.Endpoint != "" && storeConfig.Bucket != "" {
+		store = objectstore.NewS3Store(storeConfig)
+	}
+	archiver := &archive.Archiver{
+		Store:  store,
+		Repo:   repository.NewRepoArchiveRepository(ac),
+		Prefix: *flagPrefix,
+	}
+
+	projects, err := publish.Load(ac)
+	if err != nil {
+		log.Fatalf("Failed to load scores: %v", err)
+	}
+	projects = tagging.SliceFunc(tagging.Default(), projects, func(p *publish.Project) string { return p.GitLink })
+	publish.Rank(projects)
+	if len(projects) > *flagTop {
+		projects = projects[:*flagTop]
+	}
+
+	var created, failed int
+	for _, project := range projects {
+		u := url.ParseURL(project.GitLink)
+		_, lock, err := collector.Collect(&u, config.GetGitStoragePath())
+		if err != nil {
+			failed++
+			continue
+		}
+
+		a, isNew, err := archiver.Archive(&u, gitUtil.GetGitRepositoryPath(config.GetGitStoragePath(), &u), time.Now())
+		collector.Unlock(&u, lock)
+		if err != nil {
+			logger.Errorf("Archiving %s Failed: %v", u.URL, err)
+			failed++
+			continue
+		}
+		if isNew {
+			created++
+			logger.Infof("[*] %s archived to %s, sha256 %s", u.URL, *a.Location, *a.Sha256)
+		} else {
+			logger.Infof("[*] %s unchanged since %s", u.URL, a.ArchivedAt.Format(time.RFC3339))
+		}
+	}
+
+	logger.Infof("Archived %d of %d repositories, %d failed", created, len(projects), failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
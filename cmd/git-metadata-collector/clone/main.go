@@ -7,10 +7,16 @@ import (
 	"sync"
 	"time"
 
+	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/bundle"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/clonestore"
 	collector "github.com/HUSTSecLab/criticality_score/pkg/gitfile/collector"
 	url "github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser/url"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/pathmap"
 	gitUtil "github.com/HUSTSecLab/criticality_score/pkg/gitfile/util"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/vcs"
 	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/sampling"
 	"github.com/bytedance/gopkg/util/gopool"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
@@ -24,13 +30,23 @@ func main() {
 	pflag.Usage = func() {
 		logger.Printf("This tool is used to clone the git repository from the input csv file.\n")
 		logger.Printf("Usage: %s [options...] [path]\n", os.Args[0])
+		logger.Printf("The path is a csv file of git links, - for stdin.\n")
 		pflag.PrintDefaults()
 	}
 
 	pflag.StringP(viperStorageKey, "s", "./storage", "path to git storage location")
+	pflag.String("storage-layout", "", "layout of clones in git storage: plain or hashed, default is plain on linux and hashed on others")
+	flagURLColumn := pflag.String("url-column", "", "column of the git links in the csv file, a header name or an index from 1, default is a url column of the header or the first column")
+	pflag.Int64("storage-max-size", 0, "max bytes of clones in git storage, the least recently analyzed clones are removed, 0 means unlimited")
+	config.RegistSampleFlags(pflag.CommandLine)
+	config.RegistBundleFlags(pflag.CommandLine)
 	pflag.Parse()
 	viper.BindPFlag(viperStorageKey, pflag.Lookup("storage"))
 	viper.BindEnv(viperStorageKey, "STORAGE_PATH")
+	viper.BindPFlag("storage-layout", pflag.Lookup("storage-layout"))
+	viper.BindEnv("storage-layout", "STORAGE_LAYOUT")
+	viper.BindPFlag("storage-max-size", pflag.Lookup("storage-max-size"))
+	viper.BindEnv("storage-max-size", "STORAGE_MAX_SIZE")
 
 	if pflag.NArg() == 0 || pflag.NArg() > 1 {
 		pflag.Usage()
@@ -39,10 +55,20 @@ func main() {
 
 	path := pflag.Arg(0)
 
-	urls, err := gitUtil.GetCSVInput(path)
+	urls, err := gitUtil.ReadURLs(path, *flagURLColumn)
 	if err != nil {
-		log.Fatalf("Failed to read %s", path)
+		log.Fatalf("Failed to read %s: %v", path, err)
 	}
+	if err := sampling.InitDefault(config.GetSampleConfig()); err != nil {
+		log.Fatalf("Invalid sample config: %v", err)
+	}
+	bundle.InitDefault(config.GetBundleConfig())
+	if err := pathmap.InitDefault(pathmap.Layout(viper.GetString("storage-layout"))); err != nil {
+		log.Fatal(err)
+	}
+	clonestore.InitDefault(&clonestore.Config{MaxBytes: viper.GetInt64("storage-max-size")})
+	urls = sampling.Slice(urls)
+
 	var wg sync.WaitGroup
 	wg.Add(len(urls))
 
@@ -55,11 +81,16 @@ func main() {
 
 		gopool.Go(func() {
 			defer wg.Done()
-			u := url.ParseURL(input[0])
-			_, err := collector.Collect(&u, viper.GetString(viperStorageKey))
+			u := url.ParseURL(input)
+			if vcs.KindOf(&u) != vcs.KindGit {
+				logger.Infof("Skipping %s, it is collected from its log by integrate", input)
+				return
+			}
+			_, lock, err := collector.Collect(&u, viper.GetString(viperStorageKey))
 			if err != nil {
 				logger.Panicf("Cloning %s Failed", input)
 			} else {
+				collector.Unlock(&u, lock)
 				logger.Infof("%s Cloned", input)
 			}
 		})
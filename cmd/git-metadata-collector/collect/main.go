@@ -2,17 +2,27 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
-	"log"
 	"sync"
+	"time"
 
 	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/failure"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/clonestore"
 	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/collector"
 	git "github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser/git"
 	url "github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser/url"
 	gitUtil "github.com/HUSTSecLab/criticality_score/pkg/gitfile/util"
 	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/maintainer"
+	"github.com/HUSTSecLab/criticality_score/pkg/sampling"
+	"github.com/HUSTSecLab/criticality_score/pkg/scope"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/HUSTSecLab/criticality_score/pkg/tagging"
 	"github.com/bytedance/gopkg/util/gopool"
 	"github.com/spf13/pflag"
 )
@@ -57,13 +67,20 @@ func main() {
 	}
 
 	config.RegistGitStorageFlags(pflag.CommandLine)
+	config.MarkRequired("git.storage")
 	config.RegistCommonFlags(pflag.CommandLine)
+	config.RegistSampleFlags(pflag.CommandLine)
+	config.RegistTagFlags(pflag.CommandLine)
+	config.RegistScopeFlags(pflag.CommandLine)
+	config.RegistFailureFlags(pflag.CommandLine)
 	config.ParseFlags(pflag.CommandLine)
+	defer failure.Default().Exit()
 
 	urls, err := getUrls()
 	if err != nil {
-		log.Fatal(err)
+		failure.Default().Fatal(err)
 	}
+	urls = sampling.Slice(scope.Slice(tagging.Slice(urls)))
 
 	var wg sync.WaitGroup
 	logger.Infof("%d urls in total", len(urls))
@@ -71,9 +88,11 @@ func main() {
 
 	db, err := storage.GetDefaultAppDatabaseContext().GetDatabaseConnection()
 	if err != nil {
-		logger.Fatal("Connecting Database Failed")
+		failure.Default().Fatal(fmt.Errorf("connecting database failed: %w", err))
 	}
 
+	maintainerRepo := repository.NewMaintainerRepository(storage.GetDefaultAppDatabaseContext())
+
 	gopool.SetCap(int32(*flagJobsCount))
 
 	for _, input := range urls {
@@ -83,10 +102,23 @@ func main() {
 			u := url.ParseURL(input)
 
 			path := gitUtil.GetGitRepositoryPath(config.GetGitStoragePath(), &u)
+			// keep the clone from the storage GC until it is analyzed
+			lock, err := clonestore.Lock(context.Background(), path)
+			if err != nil {
+				logger.Errorf("Lock %s failed: %s", u.URL, err)
+				failure.Default().Fail(input, err)
+				return
+			}
+			defer collector.Unlock(&u, lock)
+
 			r, err := collector.Open(path)
 
 			if err != nil || r == nil {
 				logger.Errorf("Open %s failed: %s", u.URL, err)
+				if err == nil {
+					err = fmt.Errorf("no repository at %s", path)
+				}
+				failure.Default().Fail(input, err)
 				return
 			}
 
@@ -95,21 +127,42 @@ func main() {
 
 			if err != nil {
 				logger.Errorf("WalkRepo %s failed: %s", input, err)
+				failure.Default().Fail(input, err)
 				return
 			}
+			if err := clonestore.Touch(path); err != nil {
+				logger.Warnf("Failed to mark %s as analyzed: %v", path, err)
+			}
 
+			// NULL if the repo has no source files
+			perKLOC, hasCode := result.Tests.PerKLOC()
 			sqlResult, err := db.Exec(`UPDATE git_metrics SET
 				ecosystem = $1,
 				license = $2,
-				language = $3
-				WHERE git_link = $4`,
+				language = $3,
+				security_policy = $4,
+				security_contact = NULLIF($5, ''),
+				embargo_policy = $6,
+				dependency_update_tools = $7,
+				test_file_count = $8,
+				test_loc = $9,
+				tests_per_kloc = $10
+				WHERE git_link = $11`,
 				result.Ecosystems,
 				result.License,
 				result.Languages,
+				result.Disclosure.Present,
+				result.Disclosure.Contact,
+				result.Disclosure.Embargo,
+				result.UpdateTools,
+				sql.NullInt64{Int64: int64(result.Tests.TestFiles), Valid: hasCode},
+				sql.NullInt64{Int64: result.Tests.TestLines, Valid: hasCode},
+				sql.NullFloat64{Float64: perKLOC, Valid: hasCode},
 				input)
 
 			if err != nil {
 				logger.Errorf("Update database for %s failed: %v", input, err)
+				failure.Default().Fail(input, err)
 				return
 			}
 
@@ -117,15 +170,23 @@ func main() {
 
 			if err != nil {
 				logger.Errorf("Get RowsAffected for %s Failed: %v", input, err)
+				failure.Default().Fail(input, err)
 				return
 			}
 
 			if rowAffected == 0 {
 				logger.Warnf("Update %s failed: row affected = 0", input)
+				failure.Default().Fail(input, errors.New("row affected = 0"))
 				return
 			}
 
+			err = maintainer.Save(maintainerRepo, maintainer.SourceGit, map[string][]maintainer.Contact{input: result.Contacts}, time.Now())
+			if err != nil {
+				logger.Warnf("Storing the contacts of %s failed: %v", input, err)
+			}
+
 			logger.Infof("Success: %s", input)
+			failure.Default().Success()
 
 		})
 	}
@@ -0,0 +1,69 @@
+// This file is used to remove the least recently analyzed clones from the
+// git storage until it fits into the disk budget.
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/clonestore"
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/spf13/pflag"
+)
+
+var flagDryRun = pflag.Bool("dry-run", false, "only list the clones which would be removed")
+
+func main() {
+	pflag.Usage = func() {
+		fmt.Println("This tool removes the least recently analyzed clones until the git storage fits into --git-storage-max-size.")
+		fmt.Printf("Usage: %s [options...]\n", os.Args[0])
+		pflag.PrintDefaults()
+	}
+
+	config.RegistConfigFileFlags(pflag.CommandLine)
+	config.RegistGitStorageFlags(pflag.CommandLine)
+	config.MarkRequired("git.storage")
+	config.ParseFlags(pflag.CommandLine)
+	logger.ConfigAsCommandLineTool()
+
+	storagePath := config.GetGitStoragePath()
+	maxBytes := config.GetCloneStoreConfig().MaxBytes
+	if maxBytes <= 0 {
+		logger.Fatal("--git-storage-max-size is required")
+	}
+
+	clones, err := clonestore.List(storagePath)
+	if err != nil {
+		logger.Fatalf("Failed to list clones: %v", err)
+	}
+	var total int64
+	for _, c := range clones {
+		total += c.Size
+	}
+	logger.Infof("%d clones, %d bytes, budget %d bytes", len(clones), total, maxBytes)
+
+	if *flagDryRun {
+		sort.Slice(clones, func(i, j int) bool {
+			return clones[i].LastAnalyzed.Before(clones[j].LastAnalyzed)
+		})
+		for _, c := range clones {
+			if total <= maxBytes {
+				break
+			}
+			fmt.Printf("%s\t%d\t%s\n", c.Path, c.Size, c.LastAnalyzed.Format("2006-01-02 15:04:05"))
+			total -= c.Size
+		}
+		return
+	}
+
+	removed, err := clonestore.GC(storagePath, maxBytes)
+	for _, c := range removed {
+		logger.Infof("Removed %s, %d bytes, last analyzed at %s", c.Path, c.Size, c.LastAnalyzed.Format("2006-01-02 15:04:05"))
+	}
+	if err != nil {
+		logger.Fatalf("Failed to remove clones: %v", err)
+	}
+	logger.Infof("Removed %d clones", len(removed))
+}
@@ -1,17 +1,38 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
 	"log"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/HUSTSecLab/criticality_score/pkg/analysis/eol"
+	"github.com/HUSTSecLab/criticality_score/pkg/analysis/testcode"
 	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/freshness"
 	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/collector"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/history"
 	git "github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser/git"
 	url "github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser/url"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/probe"
+	gitUtil "github.com/HUSTSecLab/criticality_score/pkg/gitfile/util"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/vcs"
 	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/maintainer"
+	"github.com/HUSTSecLab/criticality_score/pkg/manifest"
+	"github.com/HUSTSecLab/criticality_score/pkg/output"
+	"github.com/HUSTSecLab/criticality_score/pkg/priority"
+	"github.com/HUSTSecLab/criticality_score/pkg/sampling"
+	"github.com/HUSTSecLab/criticality_score/pkg/scope"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/HUSTSecLab/criticality_score/pkg/tagging"
 	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/lib/pq"
+	"github.com/samber/lo"
 	"github.com/spf13/pflag"
 )
 
@@ -45,23 +66,254 @@ func getUrls() ([]string, error) {
 	return ret, nil
 }
 
+// updateProbed collects a very large repo from its HEAD only, the metrics
+// which need the history keep their previous values.
+func updateProbed(db *sql.DB, sink output.Sink, prober *probe.Prober, u *url.RepoURL, info *probe.Info, input string) error {
+	logger.Infof("[*] Probing %s of %d bytes from HEAD", input, info.Size)
+	result, err := prober.Probe(context.Background(), u, info)
+	if err != nil {
+		return err
+	}
+	repo := result.Repo
+	logger.Infof("[*] %s Probed, %d branches, %d tags, HEAD %s", input, result.Branches, result.Tags, result.Head)
+	if sink != nil {
+		return sink.Write(repository.GitMetricTableName, metricOf(input, &repo, true, false))
+	}
+
+	res, err := db.Exec(`UPDATE git_metrics SET
+		_name = $1,
+		_owner = $2,
+		_source = $3,
+		ecosystem = $4,
+		created_since = COALESCE($5, created_since),
+		updated_since = $6,
+		license = $7,
+		language = $8,
+		need_update = FALSE WHERE git_link = $9`,
+		repo.Name,
+		repo.Owner,
+		repo.Source,
+		repo.Ecosystems,
+		sql.NullTime{Time: repo.CreatedSince, Valid: !repo.CreatedSince.IsZero()},
+		repo.UpdatedSince,
+		repo.License,
+		repo.Languages,
+		input)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("no row of %s", input)
+	}
+	return nil
+}
+
+// metricOf returns the metrics of repo written to the output sink. A
+// probed repo has no history and a svn or hg repo has no files, so their
+// metrics are left out.
+func metricOf(input string, repo *git.Repo, files, history bool) *repository.GitMetric {
+	m := &repository.GitMetric{
+		GitLink:      &input,
+		UpdatedSince: &repo.UpdatedSince,
+	}
+	if !repo.CreatedSince.IsZero() {
+		m.CreatedSince = &repo.CreatedSince
+	}
+	if files {
+		m.EcoSystem = &repo.Ecosystems
+		m.License = &repo.License
+		m.Language = lo.ToPtr(pq.StringArray(strings.Fields(repo.Languages)))
+		m.SecurityPolicy = &repo.Disclosure.Present
+		m.SecurityContact = optional(repo.Disclosure.Contact)
+		m.EmbargoPolicy = &repo.Disclosure.Embargo
+		m.DependencyUpdateTools = &repo.UpdateTools
+		// the probe does not read the files, it has no source lines
+		if perKLOC, ok := repo.Tests.PerKLOC(); ok {
+			m.TestFileCount = &repo.Tests.TestFiles
+			m.TestLOC = &repo.Tests.TestLines
+			m.TestsPerKLOC = &perKLOC
+		}
+	}
+	if history {
+		m.ContributorCount = &repo.ContributorCount
+		m.CommitFrequency = &repo.CommitFrequency
+		m.HumanContributorCount = &repo.HumanContributorCount
+		m.HumanCommitFrequency = &repo.HumanCommitFrequency
+		if repo.TimezoneDiversity > 0 {
+			m.TimezoneDiversity = &repo.TimezoneDiversity
+		}
+	}
+	return m
+}
+
+// testColumns returns the test signals of counts, NULL if the repo has no
+// source files.
+func testColumns(counts testcode.Counts) (sql.NullInt64, sql.NullInt64, sql.NullFloat64) {
+	perKLOC, ok := counts.PerKLOC()
+	return sql.NullInt64{Int64: int64(counts.TestFiles), Valid: ok},
+		sql.NullInt64{Int64: counts.TestLines, Valid: ok},
+		sql.NullFloat64{Float64: perKLOC, Valid: ok}
+}
+
+// optional returns nil for an empty string, which is stored as NULL.
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// storeCommitLog keeps the log the history metrics are derived from, so
+// metrics-recomputer can recompute them without cloning the repo again.
+func storeCommitLog(logRepo repository.CommitLogRepository, input string, commits []history.Commit) {
+	data, err := history.Encode(commits)
+	if err == nil {
+		err = logRepo.InsertOrUpdate(&repository.CommitLog{
+			GitLink:     &input,
+			Commits:     &data,
+			CommitCount: lo.ToPtr(len(commits)),
+		})
+	}
+	if err != nil {
+		logger.Warnf("Storing the commit log of %s Failed: %v", input, err)
+	}
+}
+
+// storeContacts replaces the contacts found in CODEOWNERS and the security
+// policy of the repo.
+func storeContacts(maintainerRepo repository.MaintainerRepository, input string, contacts []maintainer.Contact) {
+	err := maintainer.Save(maintainerRepo, maintainer.SourceGit, map[string][]maintainer.Contact{input: contacts}, time.Now())
+	if err != nil {
+		logger.Warnf("Storing the contacts of %s Failed: %v", input, err)
+	}
+}
+
+// updateHistory collects a svn or hg repo from its log, the metrics which
+// need the files keep their previous values.
+func updateHistory(db *sql.DB, sink output.Sink, logRepo repository.CommitLogRepository, u *url.RepoURL, input string) error {
+	repo, err := vcs.Parse(context.Background(), u, gitUtil.GetGitRepositoryPath(config.GetGitStoragePath(), u))
+	if err != nil {
+		return err
+	}
+	logger.Infof("[*] %s Collected from its %s log", input, vcs.KindOf(u))
+	if sink != nil {
+		return sink.Write(repository.GitMetricTableName, metricOf(input, repo, false, true))
+	}
+
+	res, err := db.Exec(`UPDATE git_metrics SET
+		_name = $1,
+		_owner = $2,
+		_source = $3,
+		created_since = $4,
+		updated_since = $5,
+		contributor_count = $6,
+		commit_frequency = $7,
+		timezone_diversity = $8,
+		human_contributor_count = $9,
+		human_commit_frequency = $10,
+		need_update = FALSE WHERE git_link = $11`,
+		repo.Name,
+		repo.Owner,
+		repo.Source,
+		repo.CreatedSince,
+		repo.UpdatedSince,
+		repo.ContributorCount,
+		repo.CommitFrequency,
+		sql.NullFloat64{Float64: repo.TimezoneDiversity, Valid: repo.TimezoneDiversity > 0},
+		repo.HumanContributorCount,
+		repo.HumanCommitFrequency,
+		input)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("no row of %s", input)
+	}
+	storeCommitLog(logRepo, input, repo.Commits)
+	return nil
+}
+
 func main() {
 	config.RegistCommonFlags(pflag.CommandLine)
 	config.RegistGitStorageFlags(pflag.CommandLine)
+	config.MarkRequired("git.storage")
+	config.RegistSampleFlags(pflag.CommandLine)
+	config.RegistTagFlags(pflag.CommandLine)
+	config.RegistScopeFlags(pflag.CommandLine)
+	config.RegistFreshnessFlags(pflag.CommandLine)
+	config.RegistBundleFlags(pflag.CommandLine)
+	config.RegistPriorityFlags(pflag.CommandLine)
+	config.RegistGithubTokenFlags(pflag.CommandLine)
+	config.RegistProbeFlags(pflag.CommandLine)
+	config.RegistResponseArchiveFlags(pflag.CommandLine)
+	config.RegistOutputFlags(pflag.CommandLine)
 	config.ParseFlags(pflag.CommandLine)
 
-	urls, err := getUrls()
+	var urls []string
+	var err error
+	if config.UsesDatabase() {
+		urls, err = getUrls()
+	} else {
+		urls, err = output.ReadLinks(config.GetInputPath(), config.GetInputURLColumn())
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
+	urls = sampling.Slice(scope.Slice(tagging.Slice(urls)))
+
+	tsRepo := repository.NewCollectionTimestampRepository(storage.GetDefaultAppDatabaseContext())
+	provRepo := repository.NewSignalProvenanceRepository(storage.GetDefaultAppDatabaseContext())
+	logRepo := repository.NewCommitLogRepository(storage.GetDefaultAppDatabaseContext())
+	maintainerRepo := repository.NewMaintainerRepository(storage.GetDefaultAppDatabaseContext())
+	if config.UsesDatabase() {
+		if window := config.GetFreshnessWindow(); window > 0 && !*flagForceUpdateAll {
+			stale, err := tsRepo.FilterStale(repository.SignalGitMetadata, urls, time.Now().Add(-window))
+			if err != nil {
+				log.Fatal(err)
+			}
+			logger.Infof("Skipping %d urls collected within %s", len(urls)-len(stale), window)
+			urls = stale
+		}
+		urls, err = priority.Schedule(storage.GetDefaultAppDatabaseContext(), repository.SignalGitMetadata, urls)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
 
 	var wg sync.WaitGroup
 	logger.Infof("%d urls in total", len(urls))
 	wg.Add(len(urls))
 
-	db, err := storage.GetDefaultAppDatabaseContext().GetDatabaseConnection()
-	if err != nil {
-		logger.Fatal("Connecting Database Failed")
+	// sink is nil if the metrics are written to the database
+	var db *sql.DB
+	var sink output.Sink
+	if config.UsesDatabase() {
+		db, err = storage.GetDefaultAppDatabaseContext().GetDatabaseConnection()
+		if err != nil {
+			logger.Fatal("Connecting Database Failed")
+		}
+	} else {
+		sink, err = output.Open(config.GetOutputConfig(), nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	// source is freshness.SourceGitClone, SourceVCSLog or SourceProbe
+	markCollected := func(input, source string) {
+		if sink != nil {
+			return
+		}
+		now := time.Now()
+		if err := freshness.Record(provRepo, repository.SignalGitMetadata, []string{input}, source, now); err != nil {
+			logger.Errorf("Record the provenance of %s Failed: %v", input, err)
+		}
+		if err := tsRepo.MarkCollected(repository.SignalGitMetadata, []string{input}, now); err != nil {
+			logger.Errorf("Mark %s collected Failed: %v", input, err)
+		}
 	}
 	// psql.CreateTable(db)
 	gopool.SetCap(int32(*flagJobsCount))
@@ -76,10 +328,31 @@ func main() {
 		gopool.Go(func() {
 			defer wg.Done()
 			u := url.ParseURL(input)
-			r, err := collector.Collect(&u, config.GetGitStoragePath())
+			if vcs.KindOf(&u) != vcs.KindGit {
+				if err := updateHistory(db, sink, logRepo, &u, input); err != nil {
+					logger.Errorf("Collecting %s Failed: %v", input, err)
+					return
+				}
+				markCollected(input, freshness.SourceVCSLog)
+				return
+			}
+			if prober := probe.Default(); prober != nil {
+				if ok, info := prober.ShouldProbe(context.Background(), &u); ok {
+					if err := updateProbed(db, sink, prober, &u, info, input); err != nil {
+						logger.Errorf("Probing %s Failed: %v", input, err)
+						return
+					}
+					markCollected(input, freshness.SourceProbe)
+					return
+				}
+			}
+
+			r, lock, err := collector.Collect(&u, config.GetGitStoragePath())
 			if err != nil {
 				logger.Panicf("Collecting %s Failed", u.URL)
 			}
+			// keep the clone from the storage GC until it is analyzed
+			defer collector.Unlock(&u, lock)
 			logger.Infof("[*] %s Collected", input)
 
 			repo, err := git.ParseRepo(r)
@@ -87,6 +360,17 @@ func main() {
 				logger.Panicf("Parsing %s Failed", input)
 			}
 
+			if sink != nil {
+				m := metricOf(input, repo, true, true)
+				m.Runtimes = lo.ToPtr(manifest.FormatRuntimes(repo.Runtimes))
+				m.EOLRuntime = eol.Check(repo.Runtimes, time.Now())
+				if err := sink.Write(repository.GitMetricTableName, m); err != nil {
+					logger.Errorf("Writing %s Failed: %v", input, err)
+				}
+				return
+			}
+
+			testFileCount, testLOC, testsPerKLOC := testColumns(repo.Tests)
 			result, err := db.Exec(`UPDATE git_metrics SET
 				_name = $1,
 				_owner = $2,
@@ -98,7 +382,19 @@ func main() {
 				commit_frequency = $8,
 				license = $9,
 				language = $10,
-				need_update = FALSE WHERE git_link = $11`,
+				runtimes = $11,
+				eol_runtime = $12,
+				timezone_diversity = $13,
+				human_contributor_count = $14,
+				human_commit_frequency = $15,
+				security_policy = $16,
+				security_contact = $17,
+				embargo_policy = $18,
+				dependency_update_tools = $19,
+				test_file_count = $20,
+				test_loc = $21,
+				tests_per_kloc = $22,
+				need_update = FALSE WHERE git_link = $23`,
 				repo.Name,
 				repo.Owner,
 				repo.Source,
@@ -109,6 +405,19 @@ func main() {
 				repo.CommitFrequency,
 				repo.License,
 				repo.Languages,
+				manifest.FormatRuntimes(repo.Runtimes),
+				eol.Check(repo.Runtimes, time.Now()),
+				// no contributor in the last year
+				sql.NullFloat64{Float64: repo.TimezoneDiversity, Valid: repo.TimezoneDiversity > 0},
+				repo.HumanContributorCount,
+				repo.HumanCommitFrequency,
+				repo.Disclosure.Present,
+				optional(repo.Disclosure.Contact),
+				repo.Disclosure.Embargo,
+				repo.UpdateTools,
+				testFileCount,
+				testLOC,
+				testsPerKLOC,
 				input)
 
 			if err != nil {
@@ -125,8 +434,17 @@ func main() {
 
 			if rowAffected == 0 {
 				logger.Errorf("Update %s Failed", input)
+				return
 			}
+			storeCommitLog(logRepo, input, repo.Commits)
+			storeContacts(maintainerRepo, input, repo.Contacts)
+			markCollected(input, freshness.SourceGitClone)
 		})
 	}
 	wg.Wait()
+	if sink != nil {
+		if err := sink.Close(); err != nil {
+			log.Fatal(err)
+		}
+	}
 }
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/HUSTSecLab/criticality_score/pkg/config"
 	collector "github.com/HUSTSecLab/criticality_score/pkg/gitfile/collector"
@@ -32,6 +33,7 @@ func main() {
 	}
 
 	config.RegistCommonFlags(pflag.CommandLine)
+	config.RegistScoreFlags(pflag.CommandLine)
 	config.ParseFlags(pflag.CommandLine)
 	ac := storage.GetDefaultAppDatabaseContext()
 
@@ -55,29 +57,36 @@ func main() {
 
 	repo.Show()
 	gitMetadata := &scores.GitMetadata{
-		CommitFrequency:  repo.CommitFrequency,
-		ContributorCount: repo.ContributorCount,
+		CommitFrequency:  repo.HumanCommitFrequency,
+		ContributorCount: repo.HumanContributorCount,
 		CreatedSince:     repo.CreatedSince,
 		UpdatedSince:     repo.UpdatedSince,
 		Org_Count:        repo.OrgCount,
+		Ecosystems:       strings.Fields(repo.Ecosystems),
 	}
+	profiles, err := config.GetScoreProfiles()
+	if err != nil {
+		logger.Fatalf("Failed to load scoring profiles: %v", err)
+	}
+	distMetadata := scores.FetchDistMetadataSingle(ac, link)
+	profile, weights := profiles.SelectProject(gitMetadata, distMetadata[link])
+
 	gitMetadataScore := scores.NewGitMetadataScore()
-	gitMetadataScore.CalculateGitMetadataScore(gitMetadata)
+	gitMetadataScore.CalculateGitMetadataScore(gitMetadata, weights)
 
 	distScore := scores.NewDistScore()
-	distMetadata := scores.FetchDistMetadataSingle(ac, link)
 	distScore.CalculateDistMerics(distMetadata[link], scores.PackageList[distMetadata[link].Type])
-	distScore.CalculateDistScore()
+	distScore.CalculateDistScore(weights)
 
 	langEcoScore := scores.NewLangEcoScore()
 	langEcoMetadata := scores.FetchLangEcoMetadataSingle(ac, link)
 	langEcoScore.CalulateLangEcoMeritcs(langEcoMetadata[link], scores.PackageCounts[langEcoMetadata[link].Type])
-	langEcoScore.CalculateLangEcoScore()
+	langEcoScore.CalculateLangEcoScore(weights)
 
 	if updateDB {
-		scores.UpdateScore(ac, map[string]*scores.LinkScore{
-			link: scores.NewLinkScore(gitMetadataScore, distScore, langEcoScore),
-		})
+		linkScore := scores.NewLinkScore(gitMetadataScore, distScore, langEcoScore)
+		linkScore.Profile = profile
+		scores.UpdateScore(ac, map[string]*scores.LinkScore{link: linkScore}, "", "")
 	}
 }
 func updateGitMetrics(db *sql.DB, repo *git.Repo, score float64, depsDistro float64) error {
@@ -95,9 +104,9 @@ func updateGitMetrics(db *sql.DB, repo *git.Repo, score float64, depsDistro floa
 }
 
 func FetchDepsdev(db *sql.DB, git_link string) int {
-	query := fmt.Sprintf("SELECT depsdev_count FROM git_metrics WHERE git_link = '%s'", git_link)
+	query := "SELECT depsdev_count FROM git_metrics WHERE git_link = $1"
 	var depsdev_count sql.NullInt64
-	err := db.QueryRow(query).Scan(&depsdev_count)
+	err := db.QueryRow(query, git_link).Scan(&depsdev_count)
 	if err != nil {
 		log.Fatal(err)
 	}
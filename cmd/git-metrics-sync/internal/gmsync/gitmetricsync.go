@@ -2,11 +2,14 @@ package gmsync
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
-	"strings"
 
+	"github.com/HUSTSecLab/criticality_score/pkg/gitutil/normalize"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
 	_ "github.com/lib/pq"
 )
 
@@ -17,111 +20,153 @@ type DependentInfo struct {
 }
 
 var unionTables = [][]string{
-	[]string{"debian_packages", "arch_packages", "gentoo_packages", "homebrew_packages", "nix_packages", "ubuntu_packages", "deepin_packages"},
-	[]string{"github_links"},
+	{
+		repository.DistPackageTableName(repository.DistLinkTablePrefixDebian),
+		repository.DistPackageTableName(repository.DistLinkTablePrefixArchlinux),
+		repository.DistPackageTableName(repository.DistLinkTablePrefixGentoo),
+		repository.DistPackageTableName(repository.DistLinkTablePrefixHomebrew),
+		repository.DistPackageTableName(repository.DistLinkTablePrefixNix),
+		repository.DistPackageTableName(repository.DistLinkTablePrefixUbuntu),
+		repository.DistPackageTableName(repository.DistLinkTablePrefixDeepin),
+	},
+	{repository.PlatformLinkTableName(repository.PlatformLinkTablePrefixGithub)},
 }
 
-func Run() {
+// ErrSync is wrapped by the errors of writing git_metrics, the sync goes on
+// with the other links when a single link fails.
+var ErrSync = errors.New("failed to sync git_metrics")
+
+// Run syncs git_metrics with the git links of all union tables.
+func Run() error {
+	// the connection pool is shared, do not close it here
 	db, err := storage.GetDefaultAppDatabaseContext().GetDatabaseConnection()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	defer db.Close()
 
+	var errs []error
 	for i := 0; i < len(unionTables); i++ {
-		gitLinks := fetchGitLinks(db, i)
-		syncGitMetrics(db, gitLinks, i)
+		gitLinks, err := fetchGitLinks(db, i)
+		if err != nil {
+			return err
+		}
+		if err := syncGitMetrics(db, gitLinks, i); err != nil {
+			errs = append(errs, err)
+		}
 	}
-
+	return errors.Join(errs...)
 }
 
-func fetchGitLinks(db *sql.DB, from int) map[string]string {
+func fetchGitLinks(db *sql.DB, from int) (map[string]string, error) {
 	gitLinks := make(map[string]string)
 	for _, table := range unionTables[from] {
-		rows, err := db.Query(fmt.Sprintf("SELECT git_link FROM %s", table))
-		if err != nil {
-			log.Fatal(err)
+		if err := fetchTableGitLinks(db, table, gitLinks); err != nil {
+			return nil, err
 		}
-		defer rows.Close()
+	}
+	return gitLinks, nil
+}
 
-		var gitLink sql.NullString
-		for rows.Next() {
-			if err := rows.Scan(&gitLink); err != nil {
-				log.Fatal(err)
-			}
-			if gitLink.Valid {
-				link := strings.TrimSpace(gitLink.String)
-				if link == "" || link == "NA" || link == "NaN" {
-					continue
-				}
-				if !strings.HasPrefix(link, "git://") && !strings.HasPrefix(link, "https://") && !strings.HasPrefix(link, "http://") {
-					continue
-				}
-				if !strings.HasSuffix(link, ".git") {
-					link += ".git"
-				}
-				gitLinks[strings.ToLower(link)] = link
-			}
+func fetchTableGitLinks(db *sql.DB, table string, gitLinks map[string]string) error {
+	query, args, err := sqlutil.From(table).Select("git_link")
+	if err != nil {
+		return err
+	}
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to fetch git_links from %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var gitLink sql.NullString
+	for rows.Next() {
+		if err := rows.Scan(&gitLink); err != nil {
+			return fmt.Errorf("failed to scan git_link from %s: %w", table, err)
+		}
+		if !gitLink.Valid {
+			continue
 		}
+		// links of the same repo, e.g. with and without .git, are synced once
+		link, err := normalize.CloneURL(gitLink.String)
+		if err != nil {
+			continue
+		}
+		key, _ := normalize.Key(link)
+		gitLinks[key] = link
 	}
-	return gitLinks
+	return rows.Err()
 }
 
-func syncGitMetrics(db *sql.DB, gitLinks map[string]string, from int) {
-	normalizedLinks := make(map[string]string)
-	for link := range gitLinks {
-		lowercaseLink := strings.ToLower(gitLinks[link])
-		normalizedLinks[lowercaseLink] = gitLinks[link]
-	}
+// syncGitMetrics inserts and deletes the links of git_metrics from the
+// union table from, gitLinks are keyed by normalize.Key.
+func syncGitMetrics(db *sql.DB, gitLinks map[string]string, from int) error {
 	dbLinks := make(map[string]string)
 	query := `SELECT git_link FROM git_metrics WHERE "from" = $1`
 	rows, err := db.Query(query, from)
 	if err != nil {
-		log.Fatalf("Failed to fetch git_links from git_metrics: %v", err)
+		return fmt.Errorf("failed to fetch git_links from git_metrics: %w", err)
 	}
 	defer rows.Close()
 
 	var gitLink string
 	for rows.Next() {
 		if err := rows.Scan(&gitLink); err != nil {
-			log.Fatalf("Failed to scan git_link from git_metrics: %v", err)
-		}
-		dbLinks[strings.ToLower(gitLink)] = gitLink
-	}
-
-	for dbLinkLower, dbLinkOriginal := range normalizedLinks {
-		if _, exists := dbLinks[dbLinkLower]; !exists {
-			if from == 0 {
-				_, err := db.Exec(`
-					INSERT INTO git_metrics (git_link, "from", need_update)
-					VALUES ($1, $2, $3)
-					ON CONFLICT (git_link) 
-					DO UPDATE SET "from" = EXCLUDED."from"`,
-					dbLinkOriginal, from, true)
-				if err != nil {
-					log.Printf("Failed to insert or update git_link %s: %v", dbLinkOriginal, err)
-				}
-			} else {
-				_, err := db.Exec(`
-					INSERT INTO git_metrics (git_link, "from", need_update)
-					VALUES ($1, $2, $3)
-					ON CONFLICT (git_link) DO NOTHING`,
-					dbLinkOriginal, from, true)
-				if err != nil {
-					log.Printf("Failed to insert git_link %s: %v", dbLinkOriginal, err)
-				}
+			return fmt.Errorf("failed to scan git_link from git_metrics: %w", err)
+		}
+		key, err := normalize.Key(gitLink)
+		if err != nil {
+			key = gitLink
+		}
+		dbLinks[key] = gitLink
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to fetch git_links from git_metrics: %w", err)
+	}
+
+	failed := 0
+
+	// distributions take over the links found on the platforms
+	onConflict := `DO NOTHING`
+	if from == 0 {
+		onConflict = `DO UPDATE SET "from" = EXCLUDED."from"`
+	}
+	insertStmt, err := db.Prepare(`
+		INSERT INTO git_metrics (git_link, "from", need_update)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (git_link) ` + onConflict)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert of git_metrics: %w", err)
+	}
+	defer insertStmt.Close()
+
+	deleteStmt, err := db.Prepare(`DELETE FROM git_metrics WHERE git_link = $1 AND "from" = $2`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare delete of git_metrics: %w", err)
+	}
+	defer deleteStmt.Close()
+
+	for key, link := range gitLinks {
+		if _, exists := dbLinks[key]; !exists {
+			if _, err := insertStmt.Exec(link, from, true); err != nil {
+				log.Printf("Failed to insert git_link %s: %v", link, err)
+				failed++
 			}
 		}
 	}
 
-	for normLinkLower, normLinkOriginal := range dbLinks {
-		if _, exists := normalizedLinks[normLinkLower]; !exists {
-			_, err := db.Exec(`DELETE FROM git_metrics WHERE LOWER(git_link) = $1 AND "from" = $2`, normLinkLower, from)
-			if err != nil {
-				log.Printf("Failed to delete git_link %s: %v", normLinkOriginal, err)
+	for key, dbLink := range dbLinks {
+		if _, exists := gitLinks[key]; !exists {
+			if _, err := deleteStmt.Exec(dbLink, from); err != nil {
+				log.Printf("Failed to delete git_link %s: %v", dbLink, err)
+				failed++
 			}
 		}
 	}
+
+	if failed > 0 {
+		return fmt.Errorf("%w: %d links from union table %d", ErrSync, failed, from)
+	}
+	return nil
 }
 
 func getKeys(m map[string]bool) []string {
@@ -139,7 +184,6 @@ func fetchMetricsLinks() (map[string]string, error) {
 	if err != nil {
 		return nil, err
 	}
-	defer db.Close()
 
 	rows, err := db.Query("SELECT git_link FROM git_metrics")
 	if err != nil {
@@ -163,7 +207,6 @@ func fetchUnionRepoLinks() (map[string]string, error) {
 	if err != nil {
 		return nil, err
 	}
-	defer db.Close()
 
 	rows, err := db.Query("SELECT git_link FROM git_repositories")
 	if err != nil {
@@ -186,7 +229,6 @@ func batchInsertLinks(links []string, batchSize int) error {
 	if err != nil {
 		return err
 	}
-	defer db.Close()
 
 	for i := 0; i < len(links); i += batchSize {
 		end := i + batchSize
@@ -202,29 +244,29 @@ func batchInsertLinks(links []string, batchSize int) error {
 }
 
 func insertBatch(db *sql.DB, links []string) error {
-	valueStrings := make([]string, 0, len(links))
-	valueArgs := make([]interface{}, 0, len(links))
-
-	for i, link := range links {
-		valueStrings = append(valueStrings, fmt.Sprintf("($%d)", i+1))
-		valueArgs = append(valueArgs, link)
+	rows := make([][]interface{}, 0, len(links))
+	for _, link := range links {
+		rows = append(rows, []interface{}{link})
 	}
 
-	query := fmt.Sprintf("INSERT INTO git_repositories (git_link) VALUES %s", strings.Join(valueStrings, ","))
-	_, err := db.Exec(query, valueArgs...)
+	query, args, err := sqlutil.From(repository.GitRepositoryTableName).InsertValues([]string{"git_link"}, rows)
+	if err != nil {
+		return err
+	}
+	// links inserted by a concurrent or interrupted run are kept
+	_, err = db.Exec(query+" ON CONFLICT (git_link) DO NOTHING", args...)
 	return err
 }
 
-func Union_repo(batchSize int) {
+// Union_repo adds the links of git_metrics missing from git_repositories.
+func Union_repo(batchSize int) error {
 	linkMetrics, err := fetchMetricsLinks()
 	if err != nil {
-		fmt.Println("Error fetching links from git_metrics:", err)
-		return
+		return fmt.Errorf("failed to fetch links from git_metrics: %w", err)
 	}
 	linkUnion, err := fetchUnionRepoLinks()
 	if err != nil {
-		fmt.Println("Error fetching links from git_repositories:", err)
-		return
+		return fmt.Errorf("failed to fetch links from git_repositories: %w", err)
 	}
 
 	newLinks := make([]string, 0)
@@ -234,13 +276,12 @@ func Union_repo(batchSize int) {
 		}
 	}
 
-	if len(newLinks) > 0 {
-		err := batchInsertLinks(newLinks, batchSize)
-		if err != nil {
-			fmt.Println("Error batch inserting links:", err)
-			return
-		}
-	} else {
+	if len(newLinks) == 0 {
 		fmt.Println("No new links to insert.")
+		return nil
+	}
+	if err := batchInsertLinks(newLinks, batchSize); err != nil {
+		return fmt.Errorf("failed to batch insert links: %w", err)
 	}
+	return nil
 }
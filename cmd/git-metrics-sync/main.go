@@ -17,7 +17,11 @@ func main() {
 	config.ParseFlags(pflag.CommandLine)
 
 	log.Println("Starting synchronization...")
-	gmsync.Run()
+	if err := gmsync.Run(); err != nil {
+		log.Fatalf("Synchronization failed: %v", err)
+	}
 	log.Println("Synchronization complete.")
-	gmsync.Union_repo(*batchSize)
+	if err := gmsync.Union_repo(*batchSize); err != nil {
+		log.Fatal(err)
+	}
 }
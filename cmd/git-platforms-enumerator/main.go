@@ -1,12 +1,16 @@
 package main
 
 import (
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/linkenumerator/compliance"
 	"github.com/HUSTSecLab/criticality_score/pkg/linkenumerator/enumerator"
 	"github.com/HUSTSecLab/criticality_score/pkg/linkenumerator/writer"
+	"github.com/HUSTSecLab/criticality_score/pkg/scope"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
@@ -39,6 +43,21 @@ func (d *dateFlag) Type() string {
 	return "date"
 }
 
+// scopeQuery narrows down the github query to the languages in scope.
+// Repeated language qualifiers are all required, so a single allowed language
+// is added, while repos of other allowlists are filtered by collectors later.
+func scopeQuery(query string, s *scope.Scope) string {
+	allow, block := s.Languages()
+	if len(allow) == 1 {
+		query += " language:" + strconv.Quote(allow[0])
+	}
+	sort.Strings(block)
+	for _, lang := range block {
+		query += " -language:" + strconv.Quote(lang)
+	}
+	return query
+}
+
 func main() {
 	// flags
 	var (
@@ -47,6 +66,7 @@ func main() {
 		flagOutputFilev = pflag.String("output-file", "", "output file")
 		flagJobs        = pflag.IntP("jobs", "j", 10, "number of concurrent jobs")
 		flagTake        = pflag.Int("take", 1000, "number of repositories to enumerate, only for gitlab and bitbucket")
+		flagCompliance  = pflag.Bool("compliance", false, "respect robots.txt, crawl-delay and documented rate limits of each forge, with request budgets tracked in the database")
 	)
 
 	// github flags
@@ -62,6 +82,7 @@ func main() {
 	pflag.Var(&flagStartDate, "start-date", "start date for the search")
 	pflag.Var(&flagEndDate, "end-date", "end date for the search")
 	config.RegistCommonFlags(pflag.CommandLine)
+	config.RegistScopeFlags(pflag.CommandLine)
 	config.ParseFlags(pflag.CommandLine)
 
 	platforms := strings.Split(*flagPlatforms, ",")
@@ -78,7 +99,7 @@ func main() {
 				MinStars:        *flagMinStars,
 				StarOverlap:     *flagStarOverlap,
 				RequireMinStars: *flagRequireMinStars,
-				Query:           *flagQuery,
+				Query:           scopeQuery(*flagQuery, scope.Default()),
 				StartDate:       flagStartDate.Time(),
 				EndDate:         flagEndDate.Time(),
 				Workers:         *flagJobs,
@@ -105,8 +126,19 @@ func main() {
 			panic("unknown output type")
 		}
 
+		if scope.Default().Enabled() {
+			w = writer.NewFilterWriter(w, scope.Default().Name)
+		}
 		en.SetWriter(w)
 
+		if *flagCompliance {
+			guard, err := compliance.NewDefaultGuard(platform, compliance.NewDBBudgetStore(storage.GetDefaultAppDatabaseContext()))
+			if err != nil {
+				log.WithError(err).Fatalf("failed to enable compliance mode for %s", platform)
+			}
+			en.SetGuard(guard)
+		}
+
 		err := en.Enumerate()
 		if err != nil {
 			log.WithError(err).Errorf("failed to enumerate %s", platform)
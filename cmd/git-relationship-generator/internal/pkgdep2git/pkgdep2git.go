@@ -4,6 +4,9 @@ import (
 	"log"
 	"strings"
 	"fmt"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
 )
 var repoList = []string{
 	"arch",
@@ -16,7 +19,11 @@ func FetchAlldep(db *sql.DB) map[string]map[[2]string]struct{} {
 	var repodepSet = make(map[string]map[[2]string]struct{})
 
 	for _, repo := range repoList {
-		rows, err := db.Query("SELECT frompackage, topackage FROM " + repo + "_relationships")
+		query, args, err := sqlutil.From(repository.DistRelationshipTableName(repository.DistPackageTablePrefix(repo))).Select("frompackage", "topackage")
+		if err != nil {
+			log.Fatal(err)
+		}
+		rows, err := db.Query(query, args...)
 		if err != nil {
 			log.Println("Error querying " + repo + "_relationships:", err)
 			log.Fatal(err)	
@@ -68,10 +75,14 @@ func FetchPkg2Git(db *sql.DB, frompkg string, topkg string, repo string) (string
 	var fromgit sql.NullString
 	var togit sql.NullString
 
-	queryFrom := fmt.Sprintf("SELECT git_link FROM %s WHERE package = $1", repo + "_packages")
-	queryTo := fmt.Sprintf("SELECT git_link FROM %s WHERE package = $1", repo + "_packages")
+	table, err := sqlutil.Table(repository.DistPackageTableName(repository.DistPackageTablePrefix(repo)))
+	if err != nil {
+		log.Fatal(err)
+	}
+	queryFrom := "SELECT git_link FROM " + table + " WHERE package = $1"
+	queryTo := "SELECT git_link FROM " + table + " WHERE package = $1"
 
-	err := db.QueryRow(queryFrom, frompkg).Scan(&fromgit)
+	err = db.QueryRow(queryFrom, frompkg).Scan(&fromgit)
 	if err != nil {
 		log.Printf("No git_link found for package %s in repo %s, setting to empty, from\n", frompkg, repo)
 		log.Fatal(err)
@@ -114,7 +125,7 @@ func BatchUpdate(db *sql.DB, batchSize int, gitdepMap map[[2]string]struct{}) er
 			values = append(values, key[0], key[1])
 		}
 
-		query += strings.Join(placeholders, ", ")
+		query += strings.Join(placeholders, ", ") + " ON CONFLICT DO NOTHING"
 
 		_, err := db.Exec(query, values...)
 		if err != nil {
@@ -0,0 +1,213 @@
+// homepage-checker probes the homepages of the distribution packages and
+// records whether they are alive, so reports can flag critical packages
+// whose upstream web presence has vanished. Homepages checked within
+// --recheck are skipped, and with --interval it keeps running in the
+// background.
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/failure"
+	"github.com/HUSTSecLab/criticality_score/pkg/homepage"
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/spf13/pflag"
+)
+
+// batchSize is the number of probes recorded at once.
+const batchSize = 100
+
+var (
+	flagDists     = pflag.StringSlice("dist", nil, "distribution table prefixes whose homepages are checked, e.g. debian, arch, all if empty")
+	flagJobsCount = pflag.IntP("jobs", "j", 16, "jobs count")
+	flagTimeout   = pflag.Duration("timeout", homepage.DefaultTimeout, "timeout of a probe")
+	flagRecheck   = pflag.Duration("recheck", 7*24*time.Hour, "skip homepages checked within the duration")
+	flagInterval  = pflag.Duration("interval", 0, "check again after the duration, 0 checks once")
+	flagDryRun    = pflag.Bool("dry-run", false, "only print the probes, do not update the database")
+
+	flagReport  = pflag.Bool("report", false, "only print the most critical packages whose homepage is dead, do not check")
+	flagDeadFor = pflag.Duration("dead-for", 30*24*time.Hour, "report homepages which have not been alive for the duration")
+	flagTop     = pflag.Int("top", 50, "number of packages reported per distribution")
+)
+
+// prefixes returns the distributions of --dist.
+func prefixes() ([]repository.DistPackageTablePrefix, error) {
+	if len(*flagDists) == 0 {
+		return repository.DistPackageTablePrefixes, nil
+	}
+	var ret []repository.DistPackageTablePrefix
+	for _, name := range *flagDists {
+		prefix, ok := repository.ParseDistPackageTablePrefix(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown distribution: %s", name)
+		}
+		ret = append(ret, prefix)
+	}
+	return ret, nil
+}
+
+// dueHomepages returns the distinct homepages of the distributions which
+// were not checked within --recheck.
+func dueHomepages(repo repository.HomepageLivenessRepository, dists []repository.DistPackageTablePrefix) ([]string, error) {
+	before := time.Now().Add(-*flagRecheck)
+	seen := make(map[string]bool)
+	var ret []string
+	for _, prefix := range dists {
+		homepages, err := repo.QueryDue(prefix, before)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch homepages of %s: %w", prefix, err)
+		}
+		for _, h := range homepages {
+			if !seen[h] {
+				seen[h] = true
+				ret = append(ret, h)
+			}
+		}
+	}
+	return ret, nil
+}
+
+func toLiveness(r *homepage.Result) *repository.HomepageLiveness {
+	alive := r.Alive()
+	l := &repository.HomepageLiveness{
+		Homepage:  &r.URL,
+		Alive:     &alive,
+		CheckedAt: &r.CheckedAt,
+	}
+	if r.StatusCode != 0 {
+		l.StatusCode = &r.StatusCode
+	}
+	if r.Err != nil {
+		msg := r.Err.Error()
+		l.Error = &msg
+	}
+	return l
+}
+
+// check probes the due homepages once.
+func check(ctx context.Context, repo repository.HomepageLivenessRepository, checker *homepage.Checker, dists []repository.DistPackageTablePrefix) {
+	homepages, err := dueHomepages(repo, dists)
+	if err != nil {
+		failure.Default().Fatal(err)
+	}
+	logger.Infof("%d homepages to check", len(homepages))
+
+	var mu sync.Mutex
+	var batch []*repository.HomepageLiveness
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := repo.Record(batch); err != nil {
+			logger.Errorf("Recording %d probes Failed: %v", len(batch), err)
+			for _, l := range batch {
+				failure.Default().Fail(*l.Homepage, err)
+			}
+		} else {
+			for range batch {
+				failure.Default().Success()
+			}
+		}
+		batch = nil
+	}
+
+	dead := 0
+	var wg sync.WaitGroup
+	wg.Add(len(homepages))
+	for _, u := range homepages {
+		gopool.Go(func() {
+			defer wg.Done()
+			r := checker.Check(ctx, u)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if !r.Alive() {
+				dead++
+			}
+			if *flagDryRun {
+				fmt.Printf("%s\t%d\t%v\t%v\n", u, r.StatusCode, r.Alive(), r.Err)
+				failure.Default().Success()
+				return
+			}
+			batch = append(batch, toLiveness(r))
+			if len(batch) >= batchSize {
+				flush()
+			}
+		})
+	}
+	wg.Wait()
+	flush()
+	logger.Infof("%d of %d homepages are dead", dead, len(homepages))
+}
+
+// report prints the most critical packages whose homepage is dead.
+func report(repo repository.HomepageLivenessRepository, dists []repository.DistPackageTablePrefix) {
+	before := time.Now().Add(-*flagDeadFor)
+	for _, prefix := range dists {
+		packages, err := repo.QueryDead(prefix, before, *flagTop)
+		if err != nil {
+			failure.Default().Fatal(fmt.Errorf("failed to query dead homepages of %s: %w", prefix, err))
+		}
+		fmt.Printf("%s:\n", prefix)
+		for p := range packages {
+			lastAlive := "never"
+			if p.LastAliveAt != nil {
+				lastAlive = p.LastAliveAt.Format(time.DateOnly)
+			}
+			status := "-"
+			if p.StatusCode != nil {
+				status = fmt.Sprint(*p.StatusCode)
+			} else if p.Error != nil {
+				status = *p.Error
+			}
+			pageRank := 0.0
+			if p.PageRank != nil {
+				pageRank = *p.PageRank
+			}
+			fmt.Printf("  %-40s %.6f  %s  last alive %s  %s\n", *p.Package, pageRank, *p.Homepage, lastAlive, status)
+		}
+	}
+}
+
+func main() {
+	pflag.Usage = func() {
+		fmt.Println("This tool is used to check whether the homepages of distribution packages are still alive.")
+		pflag.PrintDefaults()
+	}
+
+	config.RegistCommonFlags(pflag.CommandLine)
+	config.RegistFailureFlags(pflag.CommandLine)
+	config.ParseFlags(pflag.CommandLine)
+	defer failure.Default().Exit()
+
+	dists, err := prefixes()
+	if err != nil {
+		failure.Default().Fatal(err)
+	}
+	repo := repository.NewHomepageLivenessRepository(storage.GetDefaultAppDatabaseContext())
+
+	if *flagReport {
+		report(repo, dists)
+		return
+	}
+
+	ctx := context.Background()
+	checker := homepage.NewChecker(&http.Client{Timeout: *flagTimeout})
+	gopool.SetCap(int32(*flagJobsCount))
+	for {
+		check(ctx, repo, checker, dists)
+		if *flagInterval <= 0 {
+			return
+		}
+		logger.Infof("Checking again in %s", *flagInterval)
+		time.Sleep(*flagInterval)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/graph"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/spf13/pflag"
+)
+
+var (
+	flagDist       = pflag.String("dist", "debian", "distribution table prefix, e.g. debian, arch")
+	flagTop        = pflag.Int("top", 20, "number of most affected packages to print")
+	flagIterations = pflag.Int("iterations", 20, "max iterations of PageRank")
+	flagDamping    = pflag.Float64("damping", 0.85, "damping factor of PageRank")
+)
+
+type change struct {
+	name   string
+	before float64
+	after  float64
+}
+
+func printChanges(title string, changes []change, top int, format string) {
+	sort.SliceStable(changes, func(i, j int) bool {
+		return math.Abs(changes[i].after-changes[i].before) > math.Abs(changes[j].after-changes[j].before)
+	})
+	if len(changes) > top {
+		changes = changes[:top]
+	}
+
+	fmt.Println(title)
+	for _, c := range changes {
+		fmt.Printf("  %-40s "+format+" -> "+format+" (%+.2f%%)\n",
+			c.name, c.before, c.after, (c.after-c.before)/c.before*100)
+	}
+}
+
+// loadGraph loads the graph of the distribution from the shared graph
+// service if enabled, which maps the file written by the last collection
+// instead of loading the graph from the database.
+func loadGraph(prefix repository.DistPackageTablePrefix) (*graph.Graph, error) {
+	if s := graph.Default(); s != nil {
+		c, err := s.Get(prefix)
+		if err != nil {
+			return nil, err
+		}
+		return c.Graph(), nil
+	}
+	return graph.LoadDist(storage.GetDefaultReadOnlyAppDatabaseContext(), prefix)
+}
+
+func main() {
+	pflag.Usage = func() {
+		fmt.Println("This tool is used to simulate the impact if some packages disappear from a distribution.")
+		fmt.Printf("Usage: %s [options...] package...\n", os.Args[0])
+		pflag.PrintDefaults()
+	}
+
+	config.RegistCommonFlags(pflag.CommandLine)
+	config.RegistGraphFlags(pflag.CommandLine)
+	config.ParseFlags(pflag.CommandLine)
+
+	if pflag.NArg() == 0 {
+		pflag.Usage()
+		os.Exit(1)
+	}
+	removed := pflag.Args()
+
+	prefix, ok := repository.ParseDistPackageTablePrefix(*flagDist)
+	if !ok {
+		log.Fatalf("Unknown distribution: %s", *flagDist)
+	}
+
+	g, err := loadGraph(prefix)
+	if err != nil {
+		log.Fatalf("Failed to load dependency graph: %v", err)
+	}
+	log.Printf("Loaded %d packages and %d dependencies", g.NodeCount(), g.EdgeCount())
+
+	for _, name := range removed {
+		if !g.HasNode(name) {
+			log.Fatalf("Package %s not found in %s", name, *flagDist)
+		}
+	}
+
+	after := g.Without(removed...)
+
+	// blast radius: packages which directly or transitively depend on the removed ones
+	blast := make(map[string]struct{})
+	// affected: dependencies of the removed ones, whose dependent count and PageRank drop
+	affected := make(map[string]struct{})
+	for _, name := range removed {
+		for _, n := range g.ReverseClosure(name) {
+			blast[n] = struct{}{}
+		}
+		for _, n := range g.Closure(name) {
+			affected[n] = struct{}{}
+		}
+	}
+	for _, name := range removed {
+		delete(blast, name)
+		delete(affected, name)
+	}
+
+	rankBefore := g.PageRank(*flagIterations, *flagDamping)
+	rankAfter := after.PageRank(*flagIterations, *flagDamping)
+
+	var removedRank, blastRank float64
+	for _, name := range removed {
+		removedRank += rankBefore[name]
+	}
+	blastList := make([]change, 0, len(blast))
+	for name := range blast {
+		blastRank += rankBefore[name]
+		blastList = append(blastList, change{name: name, before: rankBefore[name]})
+	}
+	sort.Slice(blastList, func(i, j int) bool {
+		if blastList[i].before != blastList[j].before {
+			return blastList[i].before > blastList[j].before
+		}
+		return blastList[i].name < blastList[j].name
+	})
+
+	// closure tables count the dependents without walking the graph for
+	// every affected package
+	countsBefore, countsAfter := graph.NewAncestors(g), graph.NewAncestors(after)
+	depChanges := make([]change, 0, len(affected))
+	rankChanges := make([]change, 0, len(affected))
+	for name := range affected {
+		depChanges = append(depChanges, change{
+			name:   name,
+			before: float64(countsBefore.Count(name)),
+			after:  float64(countsAfter.Count(name)),
+		})
+		rankChanges = append(rankChanges, change{
+			name:   name,
+			before: rankBefore[name],
+			after:  rankAfter[name],
+		})
+	}
+
+	fmt.Printf("Removed packages: %v, total PageRank %.6f\n", removed, removedRank)
+	fmt.Printf("Blast radius: %d of %d packages (%.2f%%), total PageRank %.6f\n",
+		len(blast), g.NodeCount(), float64(len(blast))/float64(g.NodeCount())*100, blastRank)
+	fmt.Printf("Top %d packages in blast radius:\n", min(*flagTop, len(blastList)))
+	for _, b := range blastList[:min(*flagTop, len(blastList))] {
+		fmt.Printf("  %-40s %.6f\n", b.name, b.before)
+	}
+
+	fmt.Printf("Dependencies affected: %d\n", len(affected))
+	printChanges("Most changed transitive dependent counts:", depChanges, *flagTop, "%.0f")
+	printChanges("Most changed PageRank:", rankChanges, *flagTop, "%.6f")
+}
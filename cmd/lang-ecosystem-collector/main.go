@@ -1,8 +1,21 @@
 package main
 
 import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/changefeed"
 	"github.com/HUSTSecLab/criticality_score/pkg/config"
 	"github.com/HUSTSecLab/criticality_score/pkg/depsdev"
+	"github.com/HUSTSecLab/criticality_score/pkg/freshness"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
 	"github.com/spf13/pflag"
 )
 
@@ -10,11 +23,72 @@ var (
 	flagBatchSize     = pflag.Int("batch", 100, "batch size")
 	workerCount       = pflag.Int("workers", 10, "number of workers")
 	calculatePageRank = pflag.Bool("pagerank", false, "calculate page rank")
+	flagAggregate     = pflag.String("aggregate", "sum", "how to aggregate dependents of repos publishing multiple packages: sum, max, list")
+
+	flagWatch        = pflag.Bool("watch", false, "run as a service refreshing the packages changed in the change feeds of registries, instead of a full pass")
+	flagFeeds        = pflag.StringSlice("feeds", changefeed.Names, "change feeds followed by --watch: "+strings.Join(changefeed.Names, ", "))
+	flagPollInterval = pflag.Duration("poll-interval", time.Minute, "interval of polling the change feeds")
 )
 
 func main() {
 	config.RegistCommonFlags(pflag.CommandLine)
+	config.RegistSampleFlags(pflag.CommandLine)
+	config.RegistTagFlags(pflag.CommandLine)
+	config.RegistScopeFlags(pflag.CommandLine)
+	config.RegistHTTPCacheFlags(pflag.CommandLine)
+	config.RegistDepsDevFlags(pflag.CommandLine)
+	config.RegistPageRankFlags(pflag.CommandLine)
+	config.RegistFreshnessFlags(pflag.CommandLine)
+	config.RegistPriorityFlags(pflag.CommandLine)
+	// local clones are used to read package names from manifests
+	config.RegistGitStorageFlags(pflag.CommandLine)
+	config.RegistResponseArchiveFlags(pflag.CommandLine)
+	// the crates feed reads the commits of the index from github
+	config.RegistGithubTokenFlags(pflag.CommandLine)
 	config.ParseFlags(pflag.CommandLine)
 
-	depsdev.Depsdev(*flagBatchSize, *workerCount, *calculatePageRank)
+	policy, err := depsdev.ParseAggregatePolicy(*flagAggregate)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *flagWatch {
+		watch(policy)
+		return
+	}
+
+	err = depsdev.Depsdev(*flagBatchSize, *workerCount, *calculatePageRank, policy, config.GetFreshnessWindow())
+	// only full passes are recorded for the freshness monitor, a failure is
+	// not fatal
+	if err := freshness.RecordRun(storage.GetDefaultAppDatabaseContext(), freshness.CollectorLangEcosystem, err); err != nil {
+		log.Printf("Failed to record the run: %v", err)
+	}
+	// only the owner of the view can refresh it, a failure is not fatal
+	if err := repository.NewMaterializedViewRepository(storage.GetDefaultAppDatabaseContext()).Refresh(repository.CoverageStatsViewName); err != nil {
+		log.Printf("Failed to refresh coverage stats: %v", err)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// watch follows the change feeds until interrupted.
+func watch(policy depsdev.AggregatePolicy) {
+	if *flagPollInterval <= 0 {
+		log.Fatal("--poll-interval must be positive")
+	}
+	client := &http.Client{Timeout: time.Minute}
+	var feeds []changefeed.Feed
+	for _, name := range *flagFeeds {
+		feed, err := changefeed.New(name, client, config.GetGithubToken())
+		if err != nil {
+			log.Fatal(err)
+		}
+		feeds = append(feeds, feed)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := depsdev.Watch(ctx, feeds, *flagPollInterval, policy); err != nil {
+		log.Fatal(err)
+	}
 }
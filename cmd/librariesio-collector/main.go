@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/failure"
+	"github.com/HUSTSecLab/criticality_score/pkg/librariesio"
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/sampling"
+	"github.com/HUSTSecLab/criticality_score/pkg/scope"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/HUSTSecLab/criticality_score/pkg/tagging"
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/samber/lo"
+	"github.com/spf13/pflag"
+)
+
+var (
+	flagJobsCount = pflag.IntP("jobs", "j", 2, "jobs count, requests are throttled by --librariesio-interval anyway")
+	flagOnlyGaps  = pflag.Bool("only-gaps", false, "only look up the packages without dependents in deps.dev")
+	flagDryRun    = pflag.Bool("dry-run", false, "only print the packages, do not update the database")
+)
+
+// systems are the deps.dev systems of the package types, see
+// librariesio.Platforms.
+var systems = map[repository.LangEcosystemType]string{
+	repository.Npm:   "npm",
+	repository.Go:    "go",
+	repository.Maven: "maven",
+	repository.Pypi:  "pypi",
+	repository.NuGet: "nuget",
+	repository.Cargo: "cargo",
+}
+
+func main() {
+	pflag.Usage = func() {
+		fmt.Println("This tool is used to collect the SourceRank and dependent counts of the packages published from git repositories by the libraries.io api.")
+		pflag.PrintDefaults()
+	}
+
+	config.RegistCommonFlags(pflag.CommandLine)
+	config.RegistSpoolFlags(pflag.CommandLine)
+	config.RegistSampleFlags(pflag.CommandLine)
+	config.RegistTagFlags(pflag.CommandLine)
+	config.RegistScopeFlags(pflag.CommandLine)
+	config.RegistLibrariesIOFlags(pflag.CommandLine)
+	config.MarkRequired("token.librariesio")
+	config.RegistFailureFlags(pflag.CommandLine)
+	config.ParseFlags(pflag.CommandLine)
+	defer failure.Default().Exit()
+
+	ctx := context.Background()
+	ac := storage.GetDefaultAppDatabaseContext()
+	pkgRepo := repository.NewLibrariesIOPackageRepository(ac)
+
+	latest, err := repository.NewLangEcosystemPackageRepository(ac).QueryLatest()
+	if err != nil {
+		failure.Default().Fatal(fmt.Errorf("failed to fetch packages: %w", err))
+	}
+	var pkgs []*repository.LangEcosystemPackage
+	for pkg := range latest {
+		if _, ok := systems[*pkg.Type]; !ok || !scope.Default().Ecosystem(pkg.Type.String()) {
+			continue
+		}
+		// deps.dev knows no dependents of the package
+		if *flagOnlyGaps && pkg.DepCount != nil && *pkg.DepCount > 0 {
+			continue
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	pkgs = tagging.SliceFunc(tagging.Default(), pkgs, func(p *repository.LangEcosystemPackage) string { return *p.GitLink })
+	pkgs = scope.SliceFunc(scope.Default(), pkgs, func(p *repository.LangEcosystemPackage) string { return *p.GitLink })
+	pkgs = sampling.SliceFunc(sampling.Default(), pkgs, func(p *repository.LangEcosystemPackage) string { return *p.GitLink })
+	logger.Infof("%d packages in total", len(pkgs))
+
+	// the api key is in the urls, so responses are neither cached nor
+	// archived
+	client := librariesio.New(nil, config.GetLibrariesIOConfig())
+
+	var wg sync.WaitGroup
+	wg.Add(len(pkgs))
+	gopool.SetCap(int32(*flagJobsCount))
+	for _, pkg := range pkgs {
+		gopool.Go(func() {
+			defer wg.Done()
+			system := systems[*pkg.Type]
+			item := system + "/" + *pkg.Package
+			info, err := client.GetPackage(ctx, librariesio.Platforms[system], *pkg.Package)
+			if errors.Is(err, librariesio.ErrNotFound) {
+				logger.Debugf("%s is not known by libraries.io", item)
+				failure.Default().Success()
+				return
+			}
+			if err != nil {
+				logger.Errorf("Collecting %s Failed: %v", item, err)
+				failure.Default().Fail(item, err)
+				return
+			}
+
+			if *flagDryRun {
+				fmt.Printf("%s\t%s\tsourcerank=%d\tdependents=%d\tdependent_repos=%d\n", *pkg.GitLink, item,
+					info.Rank, info.DependentsCount, info.DependentReposCount)
+				failure.Default().Success()
+				return
+			}
+			if err := pkgRepo.InsertOrUpdate(&repository.LibrariesIOPackage{
+				Type:                pkg.Type,
+				Package:             pkg.Package,
+				GitLink:             pkg.GitLink,
+				SourceRank:          lo.ToPtr(info.Rank),
+				DependentsCount:     lo.ToPtr(info.DependentsCount),
+				DependentReposCount: lo.ToPtr(info.DependentReposCount),
+			}); err != nil {
+				logger.Errorf("Update %s Failed: %v", item, err)
+				failure.Default().Fail(item, err)
+				return
+			}
+			failure.Default().Success()
+		})
+	}
+	wg.Wait()
+}
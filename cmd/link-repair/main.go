@@ -0,0 +1,227 @@
+// link-repair revalidates the git links of git_metrics, which join the
+// metrics of every collector, and records whether they still serve their
+// repo. Links which moved, e.g. renamed repos or drifted schemes and hosts,
+// are renamed in the current state of repos with --apply, and replacements
+// of dead links are suggested from the homepages and the source repos of
+// their packages.
+// Links checked within --recheck are skipped, and with --interval it keeps
+// running in the background.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/failure"
+	"github.com/HUSTSecLab/criticality_score/pkg/linkrepair"
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/sampling"
+	"github.com/HUSTSecLab/criticality_score/pkg/scope"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/HUSTSecLab/criticality_score/pkg/tagging"
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/lib/pq"
+	"github.com/spf13/pflag"
+)
+
+// batchSize is the number of checks recorded at once.
+const batchSize = 100
+
+var (
+	flagJobsCount = pflag.IntP("jobs", "j", 16, "jobs count")
+	flagTimeout   = pflag.Duration("timeout", linkrepair.DefaultTimeout, "timeout of a probe")
+	flagRecheck   = pflag.Duration("recheck", 7*24*time.Hour, "skip links checked within the duration")
+	flagInterval  = pflag.Duration("interval", 0, "check again after the duration, 0 checks once")
+	flagApply     = pflag.Bool("apply", false, "rename moved links to their target in the tables of the current state of repos")
+	flagSuggest   = pflag.Bool("suggest", true, "suggest replacements of dead links from the homepages and the source repos of their packages")
+	flagDryRun    = pflag.Bool("dry-run", false, "only print the checks, do not update the database")
+
+	flagReport = pflag.Bool("report", false, "only print the dead and the moved links of the highest scores, do not check")
+	flagTop    = pflag.Int("top", 50, "number of links reported per status")
+)
+
+func toLinkCheck(r *linkrepair.Result, suggestions []string) *repository.LinkCheck {
+	status := string(r.Status)
+	c := &repository.LinkCheck{
+		GitLink:   &r.Link,
+		Status:    &status,
+		CheckedAt: &r.CheckedAt,
+	}
+	if r.Target != "" {
+		c.Target = &r.Target
+	}
+	if r.StatusCode != 0 {
+		c.StatusCode = &r.StatusCode
+	}
+	if r.Err != nil {
+		msg := r.Err.Error()
+		c.Error = &msg
+	}
+	if len(suggestions) > 0 {
+		c.Suggestions = (*pq.StringArray)(&suggestions)
+	}
+	return c
+}
+
+// repair checks a link, renames it if it moved and --apply is set, and
+// suggests replacements if it is dead.
+func repair(ctx context.Context, ac storage.AppDatabaseContext, repo repository.LinkCheckRepository, checker *linkrepair.Checker, link string) (*linkrepair.Result, []string) {
+	r := checker.Check(ctx, link)
+	switch r.Status {
+	case linkrepair.StatusMoved:
+		if !*flagApply || *flagDryRun {
+			break
+		}
+		// on conflicts both links were collected, the duplicate is left to
+		// review and the link stays moved
+		if err := repo.RenameGitLink(link, r.Target); err != nil {
+			if !errors.Is(err, repository.ErrConflict) {
+				logger.Errorf("Renaming %s to %s Failed: %v", link, r.Target, err)
+			}
+			r.Err = err
+			break
+		}
+		logger.Infof("Renamed %s to %s", link, r.Target)
+		r.Status = linkrepair.StatusRepaired
+	case linkrepair.StatusDead:
+		if !*flagSuggest {
+			break
+		}
+		candidates, err := linkrepair.Candidates(ctx, ac, link)
+		if err != nil {
+			logger.Warnf("Fetching candidates of %s Failed: %v", link, err)
+			break
+		}
+		return r, checker.Suggest(ctx, link, candidates)
+	}
+	return r, nil
+}
+
+// check checks the due links once.
+func check(ctx context.Context, ac storage.AppDatabaseContext, repo repository.LinkCheckRepository, checker *linkrepair.Checker) {
+	links, err := repo.QueryDue(time.Now().Add(-*flagRecheck))
+	if err != nil {
+		failure.Default().Fatal(fmt.Errorf("failed to fetch links: %w", err))
+	}
+	links = sampling.Slice(scope.Slice(tagging.Slice(links)))
+	logger.Infof("%d links to check", len(links))
+
+	var mu sync.Mutex
+	var batch []*repository.LinkCheck
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := repo.Record(batch); err != nil {
+			logger.Errorf("Recording %d checks Failed: %v", len(batch), err)
+			for _, c := range batch {
+				failure.Default().Fail(*c.GitLink, err)
+			}
+		} else {
+			for range batch {
+				failure.Default().Success()
+			}
+		}
+		batch = nil
+	}
+
+	counts := make(map[linkrepair.Status]int)
+	var wg sync.WaitGroup
+	wg.Add(len(links))
+	for _, link := range links {
+		gopool.Go(func() {
+			defer wg.Done()
+			r, suggestions := repair(ctx, ac, repo, checker, link)
+
+			mu.Lock()
+			defer mu.Unlock()
+			counts[r.Status]++
+			if *flagDryRun {
+				fmt.Printf("%s\t%s\t%s\t%d\t%v\t%s\n", link, r.Status, r.Target, r.StatusCode, r.Err, strings.Join(suggestions, ","))
+				failure.Default().Success()
+				return
+			}
+			batch = append(batch, toLinkCheck(r, suggestions))
+			if len(batch) >= batchSize {
+				flush()
+			}
+		})
+	}
+	wg.Wait()
+	flush()
+	logger.Infof("%d of %d links are ok, %d moved, %d repaired, %d dead, %d unknown",
+		counts[linkrepair.StatusOK], len(links), counts[linkrepair.StatusMoved], counts[linkrepair.StatusRepaired],
+		counts[linkrepair.StatusDead], counts[linkrepair.StatusUnknown])
+}
+
+// report prints the dead and the moved links of the highest scores.
+func report(repo repository.LinkCheckRepository) {
+	for _, status := range []linkrepair.Status{linkrepair.StatusDead, linkrepair.StatusMoved} {
+		checks, err := repo.QueryByStatus(string(status), *flagTop)
+		if err != nil {
+			failure.Default().Fatal(fmt.Errorf("failed to query %s links: %w", status, err))
+		}
+		fmt.Printf("%s:\n", status)
+		for c := range checks {
+			lastOK := "never"
+			if c.LastOkAt != nil {
+				lastOK = c.LastOkAt.Format(time.DateOnly)
+			}
+			detail := "-"
+			switch {
+			case c.Target != nil:
+				detail = "-> " + *c.Target
+			case c.Suggestions != nil && len(*c.Suggestions) > 0:
+				detail = "suggested " + strings.Join(*c.Suggestions, ", ")
+			case c.StatusCode != nil:
+				detail = fmt.Sprint(*c.StatusCode)
+			case c.Error != nil:
+				detail = *c.Error
+			}
+			fmt.Printf("  %-60s last ok %s  %s\n", *c.GitLink, lastOK, detail)
+		}
+	}
+}
+
+func main() {
+	pflag.Usage = func() {
+		fmt.Println("This tool is used to revalidate the git links, repair moved ones and suggest replacements of dead ones.")
+		pflag.PrintDefaults()
+	}
+
+	config.RegistCommonFlags(pflag.CommandLine)
+	config.RegistSampleFlags(pflag.CommandLine)
+	config.RegistTagFlags(pflag.CommandLine)
+	config.RegistScopeFlags(pflag.CommandLine)
+	config.RegistDepsDevFlags(pflag.CommandLine)
+	config.RegistFailureFlags(pflag.CommandLine)
+	config.ParseFlags(pflag.CommandLine)
+	defer failure.Default().Exit()
+
+	ac := storage.GetDefaultAppDatabaseContext()
+	repo := repository.NewLinkCheckRepository(ac)
+
+	if *flagReport {
+		report(repo)
+		return
+	}
+
+	ctx := context.Background()
+	checker := linkrepair.NewChecker(&http.Client{Timeout: *flagTimeout})
+	gopool.SetCap(int32(*flagJobsCount))
+	for {
+		check(ctx, ac, repo, checker)
+		if *flagInterval <= 0 {
+			return
+		}
+		logger.Infof("Checking again in %s", *flagInterval)
+		time.Sleep(*flagInterval)
+	}
+}
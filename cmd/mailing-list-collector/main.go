@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/failure"
+	"github.com/HUSTSecLab/criticality_score/pkg/freshness"
+	gitUtil "github.com/HUSTSecLab/criticality_score/pkg/gitfile/util"
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/mailinglist"
+	"github.com/HUSTSecLab/criticality_score/pkg/output"
+	"github.com/HUSTSecLab/criticality_score/pkg/priority"
+	"github.com/HUSTSecLab/criticality_score/pkg/sampling"
+	"github.com/HUSTSecLab/criticality_score/pkg/scope"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/HUSTSecLab/criticality_score/pkg/tagging"
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/samber/lo"
+	"github.com/spf13/pflag"
+)
+
+var (
+	flagLists     = pflag.StringP("lists", "l", "", "csv file of the mailing lists of projects, one git link, archive url and optional kind per row")
+	flagJobsCount = pflag.IntP("jobs", "j", 4, "jobs count")
+	flagWindow    = pflag.Duration("window", 365*24*time.Hour, "count the messages sent within the duration")
+	flagDryRun    = pflag.Bool("dry-run", false, "only print the activity, do not update the database")
+)
+
+// readLists returns the archives of every project in the csv file, in the
+// order of their first row. A project may have several lists.
+func readLists(path string) ([]string, map[string][]mailinglist.Archive, error) {
+	rows, err := gitUtil.GetCSVInput(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var links []string
+	lists := make(map[string][]mailinglist.Archive)
+	for i, row := range rows {
+		if len(row) < 2 || row[0] == "" {
+			continue
+		}
+		kind := ""
+		if len(row) > 2 {
+			kind = row[2]
+		}
+		archive, err := mailinglist.NewArchive(row[1], mailinglist.Kind(kind))
+		if err != nil {
+			return nil, nil, fmt.Errorf("row %d: %w", i+1, err)
+		}
+		if _, ok := lists[row[0]]; !ok {
+			links = append(links, row[0])
+		}
+		lists[row[0]] = append(lists[row[0]], archive)
+	}
+	return links, lists, nil
+}
+
+func main() {
+	pflag.Usage = func() {
+		fmt.Println("This tool is used to collect the activity of projects coordinating on mailing lists, from public-inbox and mailman archives.")
+		pflag.PrintDefaults()
+	}
+
+	config.RegistCommonFlags(pflag.CommandLine)
+	config.RegistSpoolFlags(pflag.CommandLine)
+	config.RegistSampleFlags(pflag.CommandLine)
+	config.RegistTagFlags(pflag.CommandLine)
+	config.RegistScopeFlags(pflag.CommandLine)
+	config.RegistFreshnessFlags(pflag.CommandLine)
+	config.RegistPriorityFlags(pflag.CommandLine)
+	config.RegistOutputFlags(pflag.CommandLine)
+	config.RegistFailureFlags(pflag.CommandLine)
+	config.ParseFlags(pflag.CommandLine)
+	defer failure.Default().Exit()
+
+	if *flagLists == "" {
+		failure.Default().Fatal(errors.New("--lists is required"))
+	}
+	links, lists, err := readLists(*flagLists)
+	if err != nil {
+		failure.Default().Fatal(fmt.Errorf("failed to read mailing lists: %w", err))
+	}
+
+	ctx := context.Background()
+	ac := storage.GetDefaultAppDatabaseContext()
+	links = sampling.Slice(scope.Slice(tagging.Slice(links)))
+
+	tsRepo := repository.NewCollectionTimestampRepository(ac)
+	if config.UsesDatabase() {
+		if window := config.GetFreshnessWindow(); window > 0 {
+			stale, err := tsRepo.FilterStale(repository.SignalMailingList, links, time.Now().Add(-window))
+			if err != nil {
+				failure.Default().Fatal(err)
+			}
+			logger.Infof("Skipping %d links collected within %s", len(links)-len(stale), window)
+			links = stale
+		}
+		links, err = priority.Schedule(ac, repository.SignalMailingList, links)
+		if err != nil {
+			failure.Default().Fatal(err)
+		}
+	}
+	logger.Infof("%d links in total", len(links))
+
+	writer := freshness.MetricWriter(ac, repository.SignalMailingList, freshness.SourceMailingList,
+		"mailing_list_messages", "mailing_list_senders", "mailing_list_patches", "mailing_list_reviewers")
+	sink, err := output.Open(config.GetOutputConfig(), output.NewQueue(writer, config.GetQueueConfig()))
+	if err != nil {
+		failure.Default().Fatal(err)
+	}
+
+	fetcher := mailinglist.NewFetcher(nil)
+	until := time.Now()
+	since := until.Add(-*flagWindow)
+
+	var wg sync.WaitGroup
+	wg.Add(len(links))
+	gopool.SetCap(int32(*flagJobsCount))
+	for _, link := range links {
+		gopool.Go(func() {
+			defer wg.Done()
+			activity, err := fetcher.Collect(ctx, lists[link], since, until)
+			if err != nil {
+				logger.Errorf("Collecting %s Failed: %v", link, err)
+				failure.Default().Fail(link, err)
+				return
+			}
+
+			if *flagDryRun {
+				fmt.Printf("%s\tmessages=%d\tsenders=%d\tpatches=%d\treviewers=%d\n", link,
+					activity.Messages, activity.Senders, activity.Patches, activity.Reviewers)
+				failure.Default().Success()
+				return
+			}
+			if err := sink.Write(repository.GitMetricTableName, &repository.GitMetric{
+				GitLink:              &link,
+				MailingListMessages:  lo.ToPtr(activity.Messages),
+				MailingListSenders:   lo.ToPtr(activity.Senders),
+				MailingListPatches:   lo.ToPtr(activity.Patches),
+				MailingListReviewers: lo.ToPtr(activity.Reviewers),
+			}); err != nil {
+				logger.Errorf("Update %s Failed: %v", link, err)
+				failure.Default().Fail(link, err)
+				return
+			}
+			failure.Default().Success()
+		})
+	}
+	wg.Wait()
+	if err := sink.Close(); err != nil {
+		failure.Default().Fatal(err)
+	}
+}
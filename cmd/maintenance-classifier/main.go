@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/analysis/maintenance"
+	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/spf13/pflag"
+)
+
+var flagDryRun = pflag.Bool("dry-run", false, "only print the summary, do not update the database")
+
+// fetchPreviousContributors returns the contributor count of the second
+// latest snapshot of each link, which is used to compute contributor trend.
+func fetchPreviousContributors(ac storage.AppDatabaseContext) (map[string]int, error) {
+	rows, err := ac.Query(fmt.Sprintf(`SELECT git_link, contributor_count FROM (
+		SELECT git_link, contributor_count,
+			ROW_NUMBER() OVER (PARTITION BY git_link ORDER BY id DESC) AS rn
+		FROM %s) t
+	WHERE rn = 2 AND contributor_count IS NOT NULL`, repository.GitMetricTableName))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ret := make(map[string]int)
+	for rows.Next() {
+		var link string
+		var count int
+		if err := rows.Scan(&link, &count); err != nil {
+			return nil, err
+		}
+		ret[link] = count
+	}
+	return ret, rows.Err()
+}
+
+func main() {
+	pflag.Usage = func() {
+		fmt.Println("This tool is used to classify the maintenance risk of each git repository.")
+		pflag.PrintDefaults()
+	}
+
+	config.RegistCommonFlags(pflag.CommandLine)
+	config.ParseFlags(pflag.CommandLine)
+
+	ac := storage.GetDefaultAppDatabaseContext()
+	repo := repository.NewGitMetricsRepository(ac)
+
+	previous, err := fetchPreviousContributors(ac)
+	if err != nil {
+		log.Fatalf("Failed to fetch previous contributor count: %v", err)
+	}
+
+	metrics, err := repo.Query()
+	if err != nil {
+		log.Fatalf("Failed to fetch git metrics: %v", err)
+	}
+
+	now := time.Now()
+	summary := make(map[maintenance.Risk]int)
+
+	for m := range metrics {
+		if m.GitLink == nil {
+			continue
+		}
+
+		signals := &maintenance.Signals{
+			LastCommit:      m.UpdatedSince,
+			CommitFrequency: m.CommitFrequency,
+		}
+		if prev, ok := previous[*m.GitLink]; ok && prev > 0 && m.ContributorCount != nil {
+			trend := float64(*m.ContributorCount-prev) / float64(prev)
+			signals.ContributorTrend = &trend
+		}
+
+		risk := maintenance.Classify(signals, now)
+		summary[risk]++
+
+		if *flagDryRun || risk == maintenance.RiskUnknown {
+			continue
+		}
+		if err := repo.UpdateMaintenanceRisk(*m.GitLink, string(risk)); err != nil {
+			log.Printf("Failed to update maintenance risk of %s: %v", *m.GitLink, err)
+		}
+	}
+
+	for _, risk := range []maintenance.Risk{
+		maintenance.RiskActive,
+		maintenance.RiskDeclining,
+		maintenance.RiskDormant,
+		maintenance.RiskAbandoned,
+		maintenance.RiskUnknown,
+	} {
+		name := string(risk)
+		if risk == maintenance.RiskUnknown {
+			name = "unknown"
+		}
+		log.Printf("%s: %d", name, summary[risk])
+	}
+}
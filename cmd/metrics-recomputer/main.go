@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/history"
+	url "github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser/url"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/vcs"
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/sampling"
+	"github.com/HUSTSecLab/criticality_score/pkg/scope"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/HUSTSecLab/criticality_score/pkg/tagging"
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/samber/lo"
+	"github.com/spf13/pflag"
+)
+
+var (
+	flagJobsCount = pflag.IntP("jobs", "j", 8, "jobs count")
+	flagWindow    = pflag.Duration("window", history.DefaultWindow, "window of the commit frequency and the timezones")
+	flagDryRun    = pflag.Bool("dry-run", false, "only print the metrics, do not update the database")
+)
+
+// recompute derives the metrics of the stored log of link, at the time the
+// log was collected.
+func recompute(logRepo repository.CommitLogRepository, link string) (*repository.GitMetric, error) {
+	commitLog, err := logRepo.GetByLink(link)
+	if err != nil {
+		return nil, err
+	}
+	commits, err := history.Decode(*commitLog.Commits)
+	if err != nil {
+		return nil, err
+	}
+	m, err := history.Summarize(commits, *commitLog.CollectedAt, *flagWindow)
+	if err != nil {
+		return nil, err
+	}
+
+	metric := &repository.GitMetric{
+		CreatedSince:          &m.CreatedSince,
+		UpdatedSince:          &m.UpdatedSince,
+		ContributorCount:      &m.ContributorCount,
+		CommitFrequency:       &m.CommitFrequency,
+		HumanContributorCount: &m.HumanContributorCount,
+		HumanCommitFrequency:  &m.HumanCommitFrequency,
+	}
+	// no contributor in the window, or Subversion, which records all dates
+	// in UTC
+	u := url.ParseURL(link)
+	if m.TimezoneDiversity > 0 && vcs.KindOf(&u) != vcs.KindSubversion {
+		metric.TimezoneDiversity = lo.ToPtr(m.TimezoneDiversity)
+	}
+	return metric, nil
+}
+
+func main() {
+	pflag.Usage = func() {
+		fmt.Println("This tool is used to recompute the metrics derived from commit logs, from the logs stored by git-metadata-collector integrate, without cloning the repos again.")
+		pflag.PrintDefaults()
+	}
+
+	config.RegistCommonFlags(pflag.CommandLine)
+	config.RegistSampleFlags(pflag.CommandLine)
+	config.RegistTagFlags(pflag.CommandLine)
+	config.RegistScopeFlags(pflag.CommandLine)
+	config.ParseFlags(pflag.CommandLine)
+
+	if *flagWindow <= 0 {
+		log.Fatal("--window must be positive")
+	}
+
+	ac := storage.GetDefaultAppDatabaseContext()
+	logRepo := repository.NewCommitLogRepository(ac)
+	metricRepo := repository.NewGitMetricsRepository(ac)
+
+	links, err := logRepo.QueryLinks()
+	if err != nil {
+		log.Fatal(err)
+	}
+	links = sampling.Slice(scope.Slice(tagging.Slice(links)))
+	logger.Infof("%d links in total", len(links))
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	wg.Add(len(links))
+	gopool.SetCap(int32(*flagJobsCount))
+	for _, link := range links {
+		gopool.Go(func() {
+			defer wg.Done()
+			metric, err := recompute(logRepo, link)
+			if err != nil {
+				logger.Errorf("Recomputing %s Failed: %v", link, err)
+				return
+			}
+
+			if *flagDryRun {
+				fmt.Printf("%s\tcontributors=%d\tcommit_frequency=%.4f\thuman_contributors=%d\thuman_commit_frequency=%.4f\ttimezone_diversity=%.4f\n",
+					link, *metric.ContributorCount, *metric.CommitFrequency, *metric.HumanContributorCount,
+					*metric.HumanCommitFrequency, lo.FromPtr(metric.TimezoneDiversity))
+				return
+			}
+			if err := metricRepo.UpdateHistoryMetrics(link, metric); err != nil {
+				logger.Errorf("Update %s Failed: %v", link, err)
+			}
+		})
+	}
+	wg.Wait()
+	logger.Infof("Recomputed %d links in %s", len(links), time.Since(start))
+}
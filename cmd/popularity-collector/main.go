@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/analysis/trend"
+	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/failure"
+	url "github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser/url"
+	"github.com/HUSTSecLab/criticality_score/pkg/linkenumerator/githubapi"
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/output"
+	"github.com/HUSTSecLab/criticality_score/pkg/rawresponse"
+	"github.com/HUSTSecLab/criticality_score/pkg/sampling"
+	"github.com/HUSTSecLab/criticality_score/pkg/scope"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+	"github.com/HUSTSecLab/criticality_score/pkg/tagging"
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/google/go-github/v47/github"
+	"github.com/samber/lo"
+	"github.com/spf13/pflag"
+	"golang.org/x/oauth2"
+)
+
+var (
+	flagJobsCount = pflag.IntP("jobs", "j", 8, "jobs count")
+	flagCompact   = pflag.Bool("compact", true, "thin the old snapshots after collecting, see trend.DefaultRetention")
+	flagBatch     = pflag.Int("batch", 1000, "max number of snapshots deleted by a statement when compacting")
+	flagDryRun    = pflag.Bool("dry-run", false, "only print the stars and forks, do not update the database")
+)
+
+// getGithubLinks returns the known git links hosted on github.
+func getGithubLinks(ac storage.AppDatabaseContext) ([]string, error) {
+	query, args, err := sqlutil.From(repository.GitRepositoryTableName).Select("git_link")
+	if err != nil {
+		return nil, err
+	}
+	rows, err := ac.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ret []string
+	for rows.Next() {
+		var link string
+		if err := rows.Scan(&link); err != nil {
+			return nil, err
+		}
+		if _, _, ok := ownerRepo(link); ok {
+			ret = append(ret, link)
+		}
+	}
+	return ret, rows.Err()
+}
+
+// ownerRepo returns the owner and the name of a github repo link.
+func ownerRepo(link string) (string, string, bool) {
+	u := url.ParseURL(link)
+	if !strings.EqualFold(u.Resource, "github.com") {
+		return "", "", false
+	}
+	parts := strings.Split(strings.Trim(u.Pathname, "/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], strings.TrimSuffix(parts[1], ".git"), true
+}
+
+func newGitHubHTTPClient(ctx context.Context, token string) *http.Client {
+	tc := oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}))
+	tc.Transport = rawresponse.Wrap(githubapi.NewRetryRoundTripper(tc.Transport, logger.GetDefaultLogger()))
+	return tc
+}
+
+// compact deletes the snapshots the retention drops at now, the snapshots
+// of a link are thinned together.
+func compact(repo repository.PopularitySnapshotRepository, now time.Time) (int, error) {
+	snapshots, err := repo.QuerySince(time.Time{})
+	if err != nil {
+		return 0, err
+	}
+
+	var drop []int64
+	var link string
+	var ids []int64
+	var times []time.Time
+	flush := func() {
+		for _, i := range trend.Compact(times, now, trend.DefaultRetention) {
+			drop = append(drop, ids[i])
+		}
+		ids, times = ids[:0], times[:0]
+	}
+	for s := range snapshots {
+		if s.ID == nil || s.GitLink == nil || s.SnapshotTime == nil {
+			continue
+		}
+		if *s.GitLink != link {
+			flush()
+			link = *s.GitLink
+		}
+		ids = append(ids, *s.ID)
+		times = append(times, *s.SnapshotTime)
+	}
+	flush()
+
+	for _, chunk := range lo.Chunk(drop, max(*flagBatch, 1)) {
+		if err := repo.Delete(chunk); err != nil {
+			return 0, err
+		}
+	}
+	return len(drop), nil
+}
+
+func main() {
+	pflag.Usage = func() {
+		fmt.Println("This tool is used to take snapshots of the stars and forks of github repositories, the time series of their growth.")
+		pflag.PrintDefaults()
+	}
+
+	config.RegistCommonFlags(pflag.CommandLine)
+	config.RegistGithubTokenFlags(pflag.CommandLine)
+	config.MarkRequired("token.github")
+	config.RegistSampleFlags(pflag.CommandLine)
+	config.RegistTagFlags(pflag.CommandLine)
+	config.RegistScopeFlags(pflag.CommandLine)
+	config.RegistResponseArchiveFlags(pflag.CommandLine)
+	config.RegistOutputFlags(pflag.CommandLine)
+	config.RegistFailureFlags(pflag.CommandLine)
+	config.ParseFlags(pflag.CommandLine)
+	defer failure.Default().Exit()
+
+	ctx := context.Background()
+	ac := storage.GetDefaultAppDatabaseContext()
+	snapshotRepo := repository.NewPopularitySnapshotRepository(ac)
+
+	var links []string
+	var err error
+	if config.UsesDatabase() {
+		links, err = getGithubLinks(ac)
+	} else {
+		links, err = output.ReadLinks(config.GetInputPath(), config.GetInputURLColumn())
+		links = lo.Filter(links, func(link string, _ int) bool {
+			_, _, ok := ownerRepo(link)
+			return ok
+		})
+	}
+	if err != nil {
+		failure.Default().Fatal(fmt.Errorf("failed to fetch git links: %w", err))
+	}
+	links = sampling.Slice(scope.Slice(tagging.Slice(links)))
+	logger.Infof("%d links in total", len(links))
+
+	sink, err := output.Open(config.GetOutputConfig(), output.NewQueue(func(_ string, rows []any) error {
+		snapshots := make([]*repository.PopularitySnapshot, len(rows))
+		for i, row := range rows {
+			snapshots[i] = row.(*repository.PopularitySnapshot)
+		}
+		return snapshotRepo.BatchInsert(snapshots)
+	}, config.GetQueueConfig()))
+	if err != nil {
+		failure.Default().Fatal(err)
+	}
+
+	client := github.NewClient(newGitHubHTTPClient(ctx, config.GetGithubToken()))
+	var wg sync.WaitGroup
+	wg.Add(len(links))
+	gopool.SetCap(int32(*flagJobsCount))
+	for _, link := range links {
+		gopool.Go(func() {
+			defer wg.Done()
+			owner, name, _ := ownerRepo(link)
+			repo, _, err := client.Repositories.Get(ctx, owner, name)
+			if err != nil {
+				logger.Errorf("Collecting %s Failed: %v", link, err)
+				failure.Default().Fail(link, err)
+				return
+			}
+
+			if *flagDryRun {
+				fmt.Printf("%s\tstars=%d\tforks=%d\n", link, repo.GetStargazersCount(), repo.GetForksCount())
+				failure.Default().Success()
+				return
+			}
+			if err := sink.Write(repository.PopularitySnapshotTableName, &repository.PopularitySnapshot{
+				GitLink:      &link,
+				Stars:        lo.ToPtr(repo.GetStargazersCount()),
+				Forks:        lo.ToPtr(repo.GetForksCount()),
+				SnapshotTime: lo.ToPtr(time.Now()),
+			}); err != nil {
+				logger.Errorf("Update %s Failed: %v", link, err)
+				failure.Default().Fail(link, err)
+				return
+			}
+			failure.Default().Success()
+		})
+	}
+	wg.Wait()
+	if err := sink.Close(); err != nil {
+		failure.Default().Fatal(err)
+	}
+
+	if !*flagCompact || *flagDryRun || !config.UsesDatabase() {
+		return
+	}
+	n, err := compact(snapshotRepo, time.Now())
+	if err != nil {
+		failure.Default().Fatal(fmt.Errorf("failed to compact snapshots: %w", err))
+	}
+	logger.Infof("%d snapshots compacted", n)
+}
@@ -0,0 +1,234 @@
+// This tool labels projects with the countries and the organizations they
+// are affiliated with, from csv files curated by hand or by the heuristics
+// of package affiliation. Every import is a new version of its source, the
+// labels of the previous version are retired but kept, see
+// docs/tools/project_labels.md.
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/affiliation"
+	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/history"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitutil/normalize"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/samber/lo"
+	"github.com/spf13/pflag"
+)
+
+var (
+	flagFile       = pflag.StringP("file", "f", "", "csv file of the labels to import, link,kind,value[,confidence], - for stdin")
+	flagDomains    = pflag.String("domains", "", "csv file of the email domains of organizations, domain,name[,country], added to the default ones")
+	flagCommitters = pflag.Int("committers", 0, "also infer from the emails of the top n committers of the collected commit logs")
+	flagMinShare   = pflag.Float64("min-share", affiliation.DefaultMinShare, "min share of the emails of a project agreeing on a label")
+	flagDryRun     = pflag.Bool("dry-run", false, "print the labels instead of storing them")
+)
+
+func toProjectLabels(source string, labels []affiliation.Label) []*repository.ProjectLabel {
+	ret := make([]*repository.ProjectLabel, 0, len(labels))
+	for _, l := range labels {
+		ret = append(ret, &repository.ProjectLabel{
+			GitLink:    lo.ToPtr(l.GitLink),
+			Kind:       lo.ToPtr(string(l.Kind)),
+			Value:      lo.ToPtr(l.Value),
+			Confidence: lo.ToPtr(l.Confidence),
+			Source:     lo.ToPtr(source),
+		})
+	}
+	return ret
+}
+
+// store replaces the labels of source, or prints them if --dry-run.
+func store(repo repository.ProjectLabelRepository, source string, labels []affiliation.Label) {
+	if *flagDryRun {
+		for _, l := range labels {
+			fmt.Printf("%s\t%s\t%s\t%.2f\n", l.GitLink, l.Kind, l.Value, l.Confidence)
+		}
+		return
+	}
+	version, err := repo.Replace(source, toProjectLabels(source, labels))
+	if err != nil {
+		log.Fatalf("Failed to store labels: %v", err)
+	}
+	log.Printf("Stored %d labels as version %d of %s", len(labels), version, source)
+}
+
+func importCSV(repo repository.ProjectLabelRepository, source string) {
+	if *flagFile == "" {
+		log.Fatal("--file is required")
+	}
+	f := os.Stdin
+	if *flagFile != "-" {
+		var err error
+		if f, err = os.Open(*flagFile); err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+	}
+	labels, err := affiliation.ReadCSV(f)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", *flagFile, err)
+	}
+	for i := range labels {
+		if link, err := normalize.URL(labels[i].GitLink); err == nil {
+			labels[i].GitLink = link
+		}
+	}
+	store(repo, source, labels)
+}
+
+// emails returns the emails of the maintainers of every repo, and of the
+// top committers if --committers.
+func emails(ac storage.AppDatabaseContext) map[string][]string {
+	ret := make(map[string][]string)
+	maintainers, err := repository.NewMaintainerRepository(ac).Query()
+	if err != nil {
+		log.Fatalf("Failed to query maintainers: %v", err)
+	}
+	for m := range maintainers {
+		if m.Source != nil && *m.Source == "git" && m.Subject != nil && m.Email != nil {
+			ret[*m.Subject] = append(ret[*m.Subject], *m.Email)
+		}
+	}
+	if *flagCommitters <= 0 {
+		return ret
+	}
+
+	commitLogs := repository.NewCommitLogRepository(ac)
+	links, err := commitLogs.QueryLinks()
+	if err != nil {
+		log.Fatalf("Failed to query commit logs: %v", err)
+	}
+	for _, link := range links {
+		commitLog, err := commitLogs.GetByLink(link)
+		if err != nil || commitLog == nil || commitLog.Commits == nil {
+			log.Printf("Failed to get commit log of %s: %v", link, err)
+			continue
+		}
+		commits, err := history.Decode(*commitLog.Commits)
+		if err != nil {
+			log.Printf("Failed to decode commit log of %s: %v", link, err)
+			continue
+		}
+		ret[link] = append(ret[link], affiliation.TopCommitters(commits, *flagCommitters)...)
+	}
+	return ret
+}
+
+func infer(ac storage.AppDatabaseContext, repo repository.ProjectLabelRepository) {
+	domains := affiliation.Domains{}
+	for domain, org := range affiliation.DefaultDomains {
+		domains[domain] = org
+	}
+	if *flagDomains != "" {
+		f, err := os.Open(*flagDomains)
+		if err != nil {
+			log.Fatal(err)
+		}
+		extra, err := affiliation.ReadDomains(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("Failed to read %s: %v", *flagDomains, err)
+		}
+		for domain, org := range extra {
+			domains[domain] = org
+		}
+	}
+	h := &affiliation.DomainHeuristic{Domains: domains, MinShare: *flagMinShare}
+
+	projects := emails(ac)
+	links := make([]string, 0, len(projects))
+	for link := range projects {
+		links = append(links, link)
+	}
+	sort.Strings(links)
+	var labels []affiliation.Label
+	for _, link := range links {
+		labels = append(labels, h.Infer(link, projects[link])...)
+	}
+	log.Printf("Inferred %d labels of %d projects", len(labels), len(links))
+	store(repo, h.Source(), labels)
+}
+
+func main() {
+	pflag.Usage = func() {
+		fmt.Println("This tool labels projects with countries and affiliations.")
+		fmt.Printf("Usage: %s [options...] import <source> -f <labels.csv>\n", os.Args[0])
+		fmt.Printf("       %s [options...] infer\n", os.Args[0])
+		fmt.Printf("       %s [options...] list\n", os.Args[0])
+		fmt.Printf("       %s [options...] history <link>\n", os.Args[0])
+		fmt.Printf("       %s [options...] retire <source>\n", os.Args[0])
+		pflag.PrintDefaults()
+	}
+
+	config.RegistCommonFlags(pflag.CommandLine)
+	config.ParseFlags(pflag.CommandLine)
+
+	ac := storage.GetDefaultAppDatabaseContext()
+	repo := repository.NewProjectLabelRepository(ac)
+
+	switch pflag.Arg(0) {
+	case "import":
+		if pflag.NArg() < 2 {
+			pflag.Usage()
+			os.Exit(1)
+		}
+		if pflag.Arg(1) == affiliation.SourceMaintainerDomain {
+			log.Fatalf("%s is the source of infer", affiliation.SourceMaintainerDomain)
+		}
+		importCSV(repo, pflag.Arg(1))
+	case "infer":
+		infer(ac, repo)
+	case "list":
+		sources, err := repo.QuerySources()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, s := range sources {
+			status := "current"
+			if s.RetiredAt != nil {
+				status = "retired"
+			}
+			fmt.Printf("%s\tv%d\t%d\t%s\t%s\n", *s.Source, *s.Version, *s.Labels,
+				s.CreatedAt.Format("2006-01-02"), status)
+		}
+	case "history":
+		if pflag.NArg() < 2 {
+			pflag.Usage()
+			os.Exit(1)
+		}
+		link := pflag.Arg(1)
+		if normalized, err := normalize.URL(link); err == nil {
+			link = normalized
+		}
+		labels, err := repo.QueryHistory(link)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for l := range labels {
+			retired := "-"
+			if l.RetiredAt != nil {
+				retired = l.RetiredAt.Format("2006-01-02")
+			}
+			fmt.Printf("%s\t%s\t%.2f\t%s v%d\t%s\t%s\n", *l.Kind, *l.Value, *l.Confidence, *l.Source, *l.Version,
+				l.CreatedAt.Format("2006-01-02"), retired)
+		}
+	case "retire":
+		if pflag.NArg() < 2 {
+			pflag.Usage()
+			os.Exit(1)
+		}
+		if err := repo.Retire(pflag.Arg(1)); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Retired the labels of %s", pflag.Arg(1))
+	default:
+		pflag.Usage()
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,101 @@
+// This tool manages project tags, which group projects into lists such as
+// the dependencies of a company, so they can be collected, scored and
+// reported separately with the --tag flag of other tools.
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	gitUtil "github.com/HUSTSecLab/criticality_score/pkg/gitfile/util"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/HUSTSecLab/criticality_score/pkg/tagging"
+	"github.com/spf13/pflag"
+)
+
+var (
+	flagFile      = pflag.StringP("file", "f", "", "read links from the csv file, - for stdin")
+	flagURLColumn = pflag.String("url-column", "", "column of the links in --file, a header name or an index from 1, default is a url column of the header or the first column")
+)
+
+// readLinks returns the links in args and in the file of --file.
+func readLinks(args []string) ([]string, error) {
+	links := append([]string{}, args...)
+	if *flagFile == "" {
+		return links, nil
+	}
+	urls, err := gitUtil.ReadURLs(*flagFile, *flagURLColumn)
+	if err != nil {
+		return nil, err
+	}
+	return append(links, urls...), nil
+}
+
+func main() {
+	pflag.Usage = func() {
+		fmt.Println("This tool manages project tags.")
+		fmt.Printf("Usage: %s [options...] list\n", os.Args[0])
+		fmt.Printf("       %s [options...] add <tag> [links...]\n", os.Args[0])
+		fmt.Printf("       %s [options...] remove <tag> [links...]\n", os.Args[0])
+		fmt.Println("remove without links and --file removes the whole tag.")
+		pflag.PrintDefaults()
+	}
+
+	config.RegistCommonFlags(pflag.CommandLine)
+	config.ParseFlags(pflag.CommandLine)
+
+	repo := repository.NewProjectTagRepository(storage.GetDefaultAppDatabaseContext())
+
+	switch pflag.Arg(0) {
+	case "list":
+		tags, err := repo.QueryTags()
+		if err != nil {
+			log.Fatal(err)
+		}
+		names := make([]string, 0, len(tags))
+		for tag := range tags {
+			names = append(names, tag)
+		}
+		sort.Strings(names)
+		for _, tag := range names {
+			fmt.Printf("%s\t%d\n", tag, tags[tag])
+		}
+	case "add", "remove":
+		if pflag.NArg() < 2 {
+			pflag.Usage()
+			os.Exit(1)
+		}
+		tag := pflag.Arg(1)
+		if err := tagging.Validate(tag); err != nil {
+			log.Fatal(err)
+		}
+		links, err := readLinks(pflag.Args()[2:])
+		if err != nil {
+			log.Fatalf("Failed to read links: %v", err)
+		}
+
+		if pflag.Arg(0) == "add" {
+			err = repo.AddLinks(tag, links)
+		} else {
+			if len(links) == 0 {
+				links = nil
+			}
+			err = repo.RemoveLinks(tag, links)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if links == nil {
+			log.Printf("Removed tag %s", tag)
+		} else {
+			log.Printf("%s %d links of tag %s done", pflag.Arg(0), len(links), tag)
+		}
+	default:
+		pflag.Usage()
+		os.Exit(1)
+	}
+}
@@ -1,47 +1,413 @@
 package main
 
 import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
 	"log"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"time"
 
 	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/evaluate"
+	"github.com/HUSTSecLab/criticality_score/pkg/league"
+	"github.com/HUSTSecLab/criticality_score/pkg/orgscore"
+	"github.com/HUSTSecLab/criticality_score/pkg/publish"
+	"github.com/HUSTSecLab/criticality_score/pkg/rawresponse"
+	"github.com/HUSTSecLab/criticality_score/pkg/schema"
+	"github.com/HUSTSecLab/criticality_score/pkg/scope"
 	scores "github.com/HUSTSecLab/criticality_score/pkg/score"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+	"github.com/HUSTSecLab/criticality_score/pkg/tagging"
 	_ "github.com/lib/pq"
+	"github.com/samber/lo"
 	"github.com/spf13/pflag"
 )
 
 var (
 	batchSize = pflag.Int("batch", 1000, "batch size")
 	calcType  = pflag.String("calc", "all", "calculation type: distro, git, langeco, all")
+	output    = pflag.StringP("output", "o", "./site", "output directory of the publish subcommand")
+	top       = pflag.Int("top", publish.DefaultTop, "number of projects in the top file of the publish subcommand, in the tables of the report subcommand and in the top list of the diff subcommand")
+	baseline  = pflag.String("schema-baseline", "", "schema.json of the last published artifacts, publish fails if the schema changes without a new version")
+
+	compareReport = pflag.String("compare-report", "", "markdown file of the comparison of the other formulas with the stored one, stdout if empty")
+	compareTop    = pflag.Int("compare-top", 20, "number of the largest rank gains and drops in the comparison of formulas and the evaluate subcommand, and of the projects of every section of the diff subcommand")
+	leagues       = pflag.StringSlice("league", nil, "league tables of the report subcommand, <kind>=<name> where kind is language, distro, distro-only or tag, e.g. language=Rust")
+	upstream      = pflag.String("upstream", evaluate.DefaultURL, "url or file of the csv dataset of the upstream OpenSSF criticality_score project, read by the evaluate subcommand")
+
+	diffFrom   = pflag.String("from", "", "scoring run of the diff subcommand to compare from, the run before --to by default")
+	diffTo     = pflag.String("to", "", "scoring run of the diff subcommand to compare to, the latest run by default")
+	diffFormat = pflag.String("format", "markdown", "format of the diff subcommand, markdown or csv")
+	replayRun  = pflag.String("run", "", "scoring run of the replay subcommand")
+	orgTop     = pflag.Int("org-top", orgscore.DefaultTop, "number of the top projects counted for every organization")
 )
 
+// runPublish renders the latest scores into static artifacts.
+func runPublish(ac storage.AppDatabaseContext) {
+	if *baseline != "" {
+		released, err := schema.Read(*baseline)
+		if err != nil {
+			log.Fatalf("Failed to read schema baseline: %v", err)
+		}
+		current, err := publish.Descriptor()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := schema.Check(released, current); err != nil {
+			log.Fatalf("Incompatible schema: %v", err)
+		}
+	}
+	projects, err := publish.Load(ac)
+	if err != nil {
+		log.Fatalf("Failed to load scores: %v", err)
+	}
+	projects = tagging.SliceFunc(tagging.Default(), projects, func(p *publish.Project) string { return p.GitLink })
+	index, err := publish.Write(*output, projects, *top, time.Now())
+	if err != nil {
+		log.Fatalf("Failed to publish scores: %v", err)
+	}
+	log.Printf("Published %d projects into %d files in %s", index.Projects, len(index.Files), *output)
+}
+
+// runEvaluate compares the latest scores with the upstream dataset.
+func runEvaluate(ac storage.AppDatabaseContext) {
+	log.Printf("Loading upstream dataset %s", *upstream)
+	upstreamScores, err := evaluate.Load(context.Background(), nil, *upstream)
+	if err != nil {
+		log.Fatalf("Failed to load upstream dataset: %v", err)
+	}
+	ours, err := evaluate.LoadScores(ac)
+	if err != nil {
+		log.Fatalf("Failed to load scores: %v", err)
+	}
+	e := evaluate.Evaluate(ours, upstreamScores, *compareTop)
+	log.Printf("Joined %d of %d upstream projects: spearman %.4f, kendall %.4f", e.Projects, e.Upstream, e.Spearman, e.Kendall)
+	if err := e.WriteReport(os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// runReport prints the league tables of the latest scores.
+func runReport(ac storage.AppDatabaseContext) {
+	if len(*leagues) == 0 {
+		log.Fatal("No league to report, set --league")
+	}
+	for _, s := range *leagues {
+		l, err := league.Parse(s)
+		if err != nil {
+			log.Fatal(err)
+		}
+		entries, err := l.Load(ac, *top)
+		if err != nil {
+			log.Fatalf("Failed to load league %s: %v", l, err)
+		}
+		if err := l.WriteTable(os.Stdout, entries); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// runOrgs prints the top organizations of the latest run.
+func runOrgs(ac storage.AppDatabaseContext) {
+	page, err := repository.NewOrgScoreRepository(ac).List(sqlutil.ListOptions{Limit: *top})
+	if err != nil {
+		log.Fatalf("Failed to load org scores: %v", err)
+	}
+	orgs := make([]*orgscore.Org, 0, len(page.Items))
+	for _, o := range page.Items {
+		orgs = append(orgs, &orgscore.Org{
+			Owner:       *o.Owner,
+			Rank:        *o.Rank,
+			Projects:    *o.Projects,
+			Sum:         lo.FromPtr(o.ScoreSum),
+			Max:         lo.FromPtr(o.ScoreMax),
+			TopLink:     lo.FromPtr(o.TopLink),
+			TopProjects: lo.FromPtr(o.TopProjects),
+		})
+	}
+	if err := orgscore.WriteTable(os.Stdout, orgs, *orgTop); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// saveOrgScores aggregates the scores of a run by their owners.
+func saveOrgScores(ac storage.AppDatabaseContext, runID string, linkScores map[string]*scores.LinkScore) error {
+	values := make(map[string]float64, len(linkScores))
+	for link, s := range linkScores {
+		values[link] = s.Score
+	}
+	orgs := orgscore.Aggregate(values, *orgTop)
+	rows := make([]*repository.OrgScore, 0, len(orgs))
+	for _, org := range orgs {
+		rows = append(rows, &repository.OrgScore{
+			RunID:       &runID,
+			Owner:       &org.Owner,
+			Rank:        &org.Rank,
+			Projects:    &org.Projects,
+			ScoreSum:    &org.Sum,
+			ScoreMax:    &org.Max,
+			TopLink:     &org.TopLink,
+			TopProjects: &org.TopProjects,
+		})
+	}
+	if err := repository.NewOrgScoreRepository(ac).BatchInsert(rows); err != nil {
+		return err
+	}
+	log.Printf("Aggregated the scores of %d organizations", len(rows))
+	return nil
+}
+
+// runRuns prints the scoring runs, the latest first.
+func runRuns(ac storage.AppDatabaseContext) {
+	runs, err := repository.NewScoreRepository(ac).QueryRuns()
+	if err != nil {
+		log.Fatalf("Failed to load runs: %v", err)
+	}
+	for _, r := range runs {
+		fmt.Printf("%s\t%s\t%d\n", *r.RunID, r.UpdateTime.Format(time.RFC3339), *r.Projects)
+	}
+}
+
+// runDiff prints the changes of the scores between two runs.
+func runDiff(ac storage.AppDatabaseContext) {
+	if *diffFormat != "markdown" && *diffFormat != "csv" {
+		log.Fatalf("Unknown format %s, expect markdown or csv", *diffFormat)
+	}
+	from, to := *diffFrom, *diffTo
+	if from == "" || to == "" {
+		runs, err := repository.NewScoreRepository(ac).QueryRuns()
+		if err != nil {
+			log.Fatalf("Failed to load runs: %v", err)
+		}
+		ids := make([]string, 0, len(runs))
+		for _, r := range runs {
+			ids = append(ids, *r.RunID)
+		}
+		if to == "" && len(ids) > 0 {
+			to = ids[0]
+		}
+		if i := slices.Index(ids, to); from == "" && i >= 0 && i+1 < len(ids) {
+			from = ids[i+1]
+		}
+		if from == "" || to == "" {
+			log.Fatal("No runs to compare, set --from and --to")
+		}
+	}
+
+	fromScores, err := scores.LoadRun(ac, from)
+	if err != nil {
+		log.Fatalf("Failed to load run %s: %v", from, err)
+	}
+	toScores, err := scores.LoadRun(ac, to)
+	if err != nil {
+		log.Fatalf("Failed to load run %s: %v", to, err)
+	}
+	if len(fromScores) == 0 || len(toScores) == 0 {
+		log.Fatalf("No scores of run %s or %s, list the runs by the runs subcommand", from, to)
+	}
+	d := scores.DiffRuns(from, fromScores, to, toScores, *top, *compareTop)
+	log.Printf("Diff of %s and %s: %d added, %d removed, %d new in the top %d", from, to, len(d.Added), len(d.Removed), len(d.Entrants), *top)
+	if *diffFormat == "csv" {
+		err = d.WriteCSV(os.Stdout)
+	} else {
+		err = d.WriteMarkdown(os.Stdout, *compareTop)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// runReplay scores the inputs of a run again with its config, prints the
+// scores as csv and fails if they differ from the stored ones.
+func runReplay(ac storage.AppDatabaseContext) {
+	if *replayRun == "" {
+		log.Fatal("No run to replay, set --run")
+	}
+	run, err := scores.LoadRunSnapshot(ac, *replayRun)
+	if err != nil {
+		log.Fatalf("Failed to load the snapshot of run %s: %v", *replayRun, err)
+	}
+	if run == nil {
+		log.Fatalf("Run %s has no snapshot, only runs since snapshots were added can be replayed", *replayRun)
+	}
+	hash, err := run.Config.Hash()
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Replaying %d projects of run %s, config %s, at %s", len(run.Inputs), run.ID, hash, run.StartedAt.Format(time.RFC3339Nano))
+	replayed := run.Score()
+	stored, err := scores.LoadRun(ac, run.ID)
+	if err != nil {
+		log.Fatalf("Failed to load the scores of run %s: %v", run.ID, err)
+	}
+
+	w := csv.NewWriter(os.Stdout)
+	w.Write([]string{"git_link", "score", "percentile"})
+	differ := 0
+	for _, link := range slices.Sorted(maps.Keys(replayed)) {
+		s := replayed[link]
+		if old, ok := stored[link]; !ok || old != s.Score {
+			differ++
+		}
+		w.Write([]string{link, strconv.FormatFloat(s.Score, 'g', -1, 64), strconv.FormatFloat(s.Percentile, 'g', -1, 64)})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		log.Fatal(err)
+	}
+	if differ > 0 || len(stored) != len(replayed) {
+		log.Fatalf("%d of %d replayed scores differ from the %d stored ones of run %s", differ, len(replayed), len(stored), run.ID)
+	}
+	log.Printf("All %d scores of run %s are reproduced", len(replayed), run.ID)
+}
+
+// refreshViews refreshes the materialized views of dashboards, it is also
+// run after every scoring run.
+func refreshViews(ac storage.AppDatabaseContext) error {
+	start := time.Now()
+	if err := repository.NewMaterializedViewRepository(ac).Refresh(); err != nil {
+		return err
+	}
+	log.Printf("Refreshed %d materialized views in %s", len(repository.MaterializedViewNames), time.Since(start))
+	return nil
+}
+
+// writeComparison writes the comparison of every other formula of names
+// with the stored one.
+func writeComparison(stored string, names []string, formulaScores map[string]map[string]float64) error {
+	var w io.Writer = os.Stdout
+	if *compareReport != "" {
+		f, err := os.Create(*compareReport)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	for _, name := range names {
+		if name == stored {
+			continue
+		}
+		c := scores.Compare(stored, formulaScores[stored], name, formulaScores[name], *compareTop)
+		log.Printf("Formula %s vs %s: spearman %.4f, %d of %d projects changed rank", name, stored, c.Spearman, c.Changed, c.Projects)
+		if err := c.WriteReport(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func main() {
 	config.RegistCommonFlags(pflag.CommandLine)
+	config.RegistTagFlags(pflag.CommandLine)
+	config.RegistScopeFlags(pflag.CommandLine)
+	config.RegistScoreFlags(pflag.CommandLine)
 	config.ParseFlags(pflag.CommandLine)
 	ac := storage.GetDefaultAppDatabaseContext()
+
+	switch pflag.Arg(0) {
+	case "":
+	case "publish":
+		// publishing only reads scores
+		runPublish(storage.GetDefaultReadOnlyAppDatabaseContext())
+		return
+	case "evaluate":
+		runEvaluate(storage.GetDefaultReadOnlyAppDatabaseContext())
+		return
+	case "report":
+		runReport(storage.GetDefaultReadOnlyAppDatabaseContext())
+		return
+	case "runs":
+		runRuns(storage.GetDefaultReadOnlyAppDatabaseContext())
+		return
+	case "orgs":
+		runOrgs(storage.GetDefaultReadOnlyAppDatabaseContext())
+		return
+	case "diff":
+		runDiff(storage.GetDefaultReadOnlyAppDatabaseContext())
+		return
+	case "replay":
+		runReplay(storage.GetDefaultReadOnlyAppDatabaseContext())
+		return
+	case "refresh-views":
+		if err := refreshViews(ac); err != nil {
+			log.Fatalf("Failed to refresh views: %v", err)
+		}
+		return
+	default:
+		log.Fatalf("Unknown subcommand: %s", pflag.Arg(0))
+	}
+
+	// the run id keys the scores of the run, see the diff subcommand
+	started := time.Now()
+	runID := started.UTC().Format(rawresponse.RunIDFormat)
 	scores.UpdatePackageList(ac)
-	linksMap := scores.FetchGitLink(ac)
+	linksMap := tagging.Slice(scores.FetchGitLink(ac))
 	gitMeticMap := scores.FetchGitMetrics(ac)
 	langEcoMetricMap := scores.FetchLangEcoMetadata(ac)
 	distMetricMap := scores.FetchDistMetadata(ac)
+	linksMap = scores.ScopeLinks(scope.Default(), linksMap, gitMeticMap, distMetricMap, langEcoMetricMap)
+	scores.SetDiscounts(gitMeticMap, langEcoMetricMap, scores.FetchProvenance(ac), config.GetDecayPolicy(), started)
+	scores.SetTrends(gitMeticMap, scores.FetchTrends(ac))
 
+	formulas, err := config.GetScoreFormulas()
+	if err != nil {
+		log.Fatalf("Failed to load scoring formulas: %v", err)
+	}
+	stored := config.GetScoreFormula()
+	if formulas[stored] == nil {
+		log.Fatalf("Unknown scoring formula %s", stored)
+	}
+	// the config and the inputs of the run are stored to replay it, the
+	// projects are scored at the start of the run
+	run := scores.NewRun(runID, started, scores.NewConfig(stored, formulas[stored], config.GetDecayPolicy()),
+		linksMap, gitMeticMap, distMetricMap, langEcoMetricMap)
+	scores.Pin(run.StartedAt)
 	packageScore := make(map[string]*scores.LinkScore)
+	// scores of every formula, to compare them with the stored one
+	formulaScores := make(map[string]map[string]float64, len(formulas))
+	for name := range formulas {
+		formulaScores[name] = make(map[string]float64, len(linksMap))
+	}
 
 	for _, link := range linksMap {
-		distScore := scores.NewDistScore()
-		distScore.CalculateDistMerics(distMetricMap[link], scores.PackageList[distMetricMap[link].Type])
-		distScore.CalculateDistScore()
-
-		langEcoScore := scores.NewLangEcoScore()
-		langEcoScore.CalulateLangEcoMeritcs(langEcoMetricMap[link], scores.PackageCounts[langEcoMetricMap[link].Type])
-		langEcoScore.CalculateLangEcoScore()
-
-		gitMetadataScore := scores.NewGitMetadataScore()
-		gitMetadataScore.CalculateGitMetadataScore(gitMeticMap[link])
+		for name, profiles := range formulas {
+			linkScore := scores.ScoreLink(profiles, gitMeticMap[link], distMetricMap[link], langEcoMetricMap[link])
+			formulaScores[name][link] = linkScore.Score
+			if name == stored {
+				packageScore[link] = linkScore
+			}
+		}
+	}
+	if len(formulas) > 1 {
+		if err := writeComparison(stored, scores.FormulaNames(formulas), formulaScores); err != nil {
+			log.Printf("Failed to write the comparison of formulas: %v", err)
+		}
+	}
+	scores.SetPercentiles(packageScore)
+	configHash, err := run.Config.Hash()
+	if err != nil {
+		log.Fatalf("Failed to hash the scoring config: %v", err)
+	}
+	if err := run.Save(ac); err != nil {
+		log.Fatalf("Failed to save the snapshot of run %s: %v", runID, err)
+	}
+	log.Printf("Updating database, run %s, config %s...", runID, configHash)
+	scores.UpdateScore(ac, packageScore, runID, configHash)
+	if err := saveOrgScores(ac, runID, packageScore); err != nil {
+		log.Fatalf("Failed to save org scores: %v", err)
+	}
 
-		packageScore[link] = scores.NewLinkScore(gitMetadataScore, distScore, langEcoScore)
-		packageScore[link].CalculateScore()
+	// views are only refreshable by their owner, a failure does not lose
+	// the scores
+	if err := refreshViews(ac); err != nil {
+		log.Printf("Failed to refresh views, run the refresh-views subcommand as the owner of the views: %v", err)
 	}
-	log.Println("Updating database...")
-	scores.UpdateScore(ac, packageScore)
 }
@@ -0,0 +1,14 @@
+// This tool runs the signal collectors bundled with the project, and those
+// of --exec, see package signals. Third parties add collectors by building
+// the same main with their packages imported.
+package main
+
+import (
+	_ "github.com/HUSTSecLab/criticality_score/pkg/signals/scorecard"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/signals/runner"
+)
+
+func main() {
+	runner.Main()
+}
@@ -0,0 +1,77 @@
+// spool shows and replays the writes the collectors spooled to --spool-dir
+// while the database was unreachable. The status command lists the spool
+// files, and the replay command executes their records once the database
+// is back, it can be run again until no file is left. Replay runs as the
+// database user of the collectors, as it writes the same tables.
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/spool"
+	"github.com/spf13/pflag"
+)
+
+func status(dir string) {
+	files, err := spool.List(dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FILE\tRECORDS\tMODIFIED\tSTATE")
+	for _, f := range files {
+		state := "closed"
+		if f.Open {
+			state = "open"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", f.Path, f.Records, f.ModTime.Format(time.DateTime), state)
+	}
+	w.Flush()
+}
+
+func replay(dir string) {
+	// the default context spools its writes, the spooled statements are
+	// replayed without it
+	ac := storage.NewAppDatabase(config.GetDatabaseConfig())
+	defer ac.Close()
+
+	report, err := spool.Replay(ac, dir)
+	if report != nil {
+		log.Printf("Replayed %d records, %d statements, of %d files, %d records are rejected, %d files are skipped",
+			report.Records, report.Statements, report.Files, report.Rejected, report.Skipped)
+	}
+	if err != nil {
+		log.Fatalf("Replay stopped, run it again once the database is reachable: %v", err)
+	}
+}
+
+func main() {
+	pflag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s status|replay [flags]\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "Shows and replays the writes of the collectors spooled while the database was unreachable.")
+		pflag.PrintDefaults()
+	}
+	config.RegistCommonFlags(pflag.CommandLine)
+	config.RegistSpoolFlags(pflag.CommandLine)
+	config.ParseFlags(pflag.CommandLine)
+
+	dir := config.GetSpoolDir()
+	if dir == "" {
+		log.Fatal("--spool-dir is required")
+	}
+	switch pflag.Arg(0) {
+	case "status":
+		status(dir)
+	case "replay":
+		replay(dir)
+	default:
+		pflag.Usage()
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/failure"
+	"github.com/HUSTSecLab/criticality_score/pkg/freshness"
+	gitUtil "github.com/HUSTSecLab/criticality_score/pkg/gitfile/util"
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/output"
+	"github.com/HUSTSecLab/criticality_score/pkg/priority"
+	"github.com/HUSTSecLab/criticality_score/pkg/sampling"
+	"github.com/HUSTSecLab/criticality_score/pkg/scope"
+	"github.com/HUSTSecLab/criticality_score/pkg/stackexchange"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/HUSTSecLab/criticality_score/pkg/tagging"
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/samber/lo"
+	"github.com/spf13/pflag"
+)
+
+var (
+	flagTagMap    = pflag.String("tag-map", "", "csv file of the stack overflow tags of projects, one git link and tag per row")
+	flagJobsCount = pflag.IntP("jobs", "j", 4, "jobs count")
+	flagWindow    = pflag.Duration("window", 365*24*time.Hour, "count the questions asked within the duration")
+	flagDryRun    = pflag.Bool("dry-run", false, "only print the question counts, do not update the database")
+)
+
+// readTagMap returns the tags of every project in the csv file, in the
+// order of their first row. A project may have several tags.
+func readTagMap(path string) ([]string, map[string][]string, error) {
+	rows, err := gitUtil.GetCSVInput(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var links []string
+	tags := make(map[string][]string)
+	for i, row := range rows {
+		if len(row) < 2 || row[0] == "" {
+			continue
+		}
+		tag := strings.ToLower(strings.TrimSpace(row[1]))
+		if tag == "" || strings.ContainsAny(tag, "; ") {
+			return nil, nil, fmt.Errorf("row %d: invalid tag %q", i+1, row[1])
+		}
+		if _, ok := tags[row[0]]; !ok {
+			links = append(links, row[0])
+		}
+		if lo.Contains(tags[row[0]], tag) {
+			continue
+		}
+		if len(tags[row[0]]) == stackexchange.MaxTags {
+			return nil, nil, fmt.Errorf("row %d: %s has more than %d tags", i+1, row[0], stackexchange.MaxTags)
+		}
+		tags[row[0]] = append(tags[row[0]], tag)
+	}
+	return links, tags, nil
+}
+
+func main() {
+	pflag.Usage = func() {
+		fmt.Println("This tool is used to count the stack overflow questions of the tags of projects, as a proxy of their user base.")
+		pflag.PrintDefaults()
+	}
+
+	config.RegistCommonFlags(pflag.CommandLine)
+	config.RegistSpoolFlags(pflag.CommandLine)
+	config.RegistSampleFlags(pflag.CommandLine)
+	config.RegistTagFlags(pflag.CommandLine)
+	config.RegistScopeFlags(pflag.CommandLine)
+	config.RegistFreshnessFlags(pflag.CommandLine)
+	config.RegistPriorityFlags(pflag.CommandLine)
+	config.RegistStackExchangeFlags(pflag.CommandLine)
+	config.RegistOutputFlags(pflag.CommandLine)
+	config.RegistFailureFlags(pflag.CommandLine)
+	config.ParseFlags(pflag.CommandLine)
+	defer failure.Default().Exit()
+
+	if *flagTagMap == "" {
+		failure.Default().Fatal(errors.New("--tag-map is required"))
+	}
+	links, tags, err := readTagMap(*flagTagMap)
+	if err != nil {
+		failure.Default().Fatal(fmt.Errorf("failed to read tag map: %w", err))
+	}
+
+	ctx := context.Background()
+	ac := storage.GetDefaultAppDatabaseContext()
+	links = sampling.Slice(scope.Slice(tagging.Slice(links)))
+
+	tsRepo := repository.NewCollectionTimestampRepository(ac)
+	if config.UsesDatabase() {
+		if window := config.GetFreshnessWindow(); window > 0 {
+			stale, err := tsRepo.FilterStale(repository.SignalStackOverflow, links, time.Now().Add(-window))
+			if err != nil {
+				failure.Default().Fatal(err)
+			}
+			logger.Infof("Skipping %d links collected within %s", len(links)-len(stale), window)
+			links = stale
+		}
+		links, err = priority.Schedule(ac, repository.SignalStackOverflow, links)
+		if err != nil {
+			failure.Default().Fatal(err)
+		}
+	}
+	logger.Infof("%d links in total", len(links))
+
+	writer := freshness.MetricWriter(ac, repository.SignalStackOverflow, freshness.SourceStackExchange,
+		"stackoverflow_questions")
+	sink, err := output.Open(config.GetOutputConfig(), output.NewQueue(writer, config.GetQueueConfig()))
+	if err != nil {
+		failure.Default().Fatal(err)
+	}
+
+	// the window moves every run, so responses are not cached
+	client := stackexchange.New(nil, config.GetStackExchangeConfig())
+	until := time.Now()
+	since := until.Add(-*flagWindow)
+
+	var wg sync.WaitGroup
+	wg.Add(len(links))
+	gopool.SetCap(int32(*flagJobsCount))
+	for _, link := range links {
+		gopool.Go(func() {
+			defer wg.Done()
+
+			questions, err := client.QuestionCount(ctx, tags[link], since, until)
+			if err != nil {
+				logger.Errorf("Counting %s Failed: %v", link, err)
+				failure.Default().Fail(link, err)
+				return
+			}
+			if *flagDryRun {
+				fmt.Printf("%s\t%s\tquestions=%d\n", link, strings.Join(tags[link], ";"), questions)
+				failure.Default().Success()
+				return
+			}
+			if err := sink.Write(repository.GitMetricTableName, &repository.GitMetric{
+				GitLink:                &link,
+				StackOverflowQuestions: lo.ToPtr(questions),
+			}); err != nil {
+				logger.Errorf("Update %s Failed: %v", link, err)
+				failure.Default().Fail(link, err)
+				return
+			}
+			failure.Default().Success()
+		})
+	}
+	wg.Wait()
+	if err := sink.Close(); err != nil {
+		failure.Default().Fatal(err)
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/analysis/supplychain"
+	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/failure"
+	"github.com/HUSTSecLab/criticality_score/pkg/freshness"
+	url "github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser/url"
+	"github.com/HUSTSecLab/criticality_score/pkg/linkenumerator/githubapi"
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/output"
+	"github.com/HUSTSecLab/criticality_score/pkg/priority"
+	"github.com/HUSTSecLab/criticality_score/pkg/rawresponse"
+	"github.com/HUSTSecLab/criticality_score/pkg/sampling"
+	"github.com/HUSTSecLab/criticality_score/pkg/scope"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+	"github.com/HUSTSecLab/criticality_score/pkg/tagging"
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/google/go-github/v47/github"
+	"github.com/samber/lo"
+	"github.com/spf13/pflag"
+	"golang.org/x/oauth2"
+)
+
+var (
+	flagJobsCount = pflag.IntP("jobs", "j", 8, "jobs count")
+	flagCommits   = pflag.Int("commits", supplychain.DefaultCommits, "number of recent commits of the default branch to check for signatures")
+	flagReleases  = pflag.Int("releases", supplychain.DefaultReleases, "number of recent releases to check for signed tags and artifacts")
+	flagWorkflows = pflag.Int("workflows", supplychain.DefaultWorkflows, "max number of ci workflows to check for slsa provenance and sigstore")
+	flagOSSFuzz   = pflag.String("oss-fuzz-projects", supplychain.DefaultOSSFuzzProjects, "url or path of a tarball of the oss-fuzz repo listing its projects, empty to skip the oss-fuzz signal")
+	flagDryRun    = pflag.Bool("dry-run", false, "only print the signals, do not update the database")
+)
+
+// getGithubLinks returns the known git links hosted on github.
+func getGithubLinks(ac storage.AppDatabaseContext) ([]string, error) {
+	query, args, err := sqlutil.From(repository.GitRepositoryTableName).Select("git_link")
+	if err != nil {
+		return nil, err
+	}
+	rows, err := ac.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ret []string
+	for rows.Next() {
+		var link string
+		if err := rows.Scan(&link); err != nil {
+			return nil, err
+		}
+		if _, _, ok := ownerRepo(link); ok {
+			ret = append(ret, link)
+		}
+	}
+	return ret, rows.Err()
+}
+
+// ownerRepo returns the owner and the name of a github repo link.
+func ownerRepo(link string) (string, string, bool) {
+	u := url.ParseURL(link)
+	if !strings.EqualFold(u.Resource, "github.com") {
+		return "", "", false
+	}
+	parts := strings.Split(strings.Trim(u.Pathname, "/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], strings.TrimSuffix(parts[1], ".git"), true
+}
+
+func newGitHubHTTPClient(ctx context.Context, token string) *http.Client {
+	tc := oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}))
+	tc.Transport = rawresponse.Wrap(githubapi.NewRetryRoundTripper(tc.Transport, logger.GetDefaultLogger()))
+	return tc
+}
+
+func format[T float64 | bool](v *T) string {
+	if v == nil {
+		return "-"
+	}
+	if f, ok := any(*v).(float64); ok {
+		return fmt.Sprintf("%.2f", f)
+	}
+	return fmt.Sprint(*v)
+}
+
+func main() {
+	pflag.Usage = func() {
+		fmt.Println("This tool is used to collect supply-chain signals of github repositories, e.g. signed commits, tags and releases, slsa provenance, sigstore and fuzzing.")
+		pflag.PrintDefaults()
+	}
+
+	config.RegistCommonFlags(pflag.CommandLine)
+	config.RegistSpoolFlags(pflag.CommandLine)
+	config.RegistGithubTokenFlags(pflag.CommandLine)
+	config.MarkRequired("token.github")
+	config.RegistSampleFlags(pflag.CommandLine)
+	config.RegistTagFlags(pflag.CommandLine)
+	config.RegistScopeFlags(pflag.CommandLine)
+	config.RegistFreshnessFlags(pflag.CommandLine)
+	config.RegistPriorityFlags(pflag.CommandLine)
+	config.RegistResponseArchiveFlags(pflag.CommandLine)
+	config.RegistOutputFlags(pflag.CommandLine)
+	config.RegistFailureFlags(pflag.CommandLine)
+	config.ParseFlags(pflag.CommandLine)
+	defer failure.Default().Exit()
+
+	ctx := context.Background()
+	ac := storage.GetDefaultAppDatabaseContext()
+	tsRepo := repository.NewCollectionTimestampRepository(ac)
+
+	var links []string
+	var err error
+	if config.UsesDatabase() {
+		links, err = getGithubLinks(ac)
+	} else {
+		links, err = output.ReadLinks(config.GetInputPath(), config.GetInputURLColumn())
+		links = lo.Filter(links, func(link string, _ int) bool {
+			_, _, ok := ownerRepo(link)
+			return ok
+		})
+	}
+	if err != nil {
+		failure.Default().Fatal(fmt.Errorf("failed to fetch git links: %w", err))
+	}
+	links = sampling.Slice(scope.Slice(tagging.Slice(links)))
+
+	if config.UsesDatabase() {
+		if window := config.GetFreshnessWindow(); window > 0 {
+			stale, err := tsRepo.FilterStale(repository.SignalSupplyChain, links, time.Now().Add(-window))
+			if err != nil {
+				failure.Default().Fatal(err)
+			}
+			logger.Infof("Skipping %d links collected within %s", len(links)-len(stale), window)
+			links = stale
+		}
+		links, err = priority.Schedule(ac, repository.SignalSupplyChain, links)
+		if err != nil {
+			failure.Default().Fatal(err)
+		}
+	}
+	logger.Infof("%d links in total", len(links))
+
+	writer := freshness.MetricWriter(ac, repository.SignalSupplyChain, freshness.SourceForgeAPI,
+		"signed_commit_ratio", "signed_tag_ratio", "signed_releases", "slsa_provenance",
+		"sigstore", "oss_fuzz", "cluster_fuzz_lite", "binary_releases")
+	sink, err := output.Open(config.GetOutputConfig(), output.NewQueue(writer, config.GetQueueConfig()))
+	if err != nil {
+		failure.Default().Fatal(err)
+	}
+
+	collector := supplychain.NewCollector(github.NewClient(newGitHubHTTPClient(ctx, config.GetGithubToken())))
+	collector.Commits = *flagCommits
+	collector.Releases = *flagReleases
+	collector.Workflows = *flagWorkflows
+	if *flagOSSFuzz != "" {
+		collector.OSSFuzz, err = supplychain.LoadOSSFuzzProjects(ctx, http.DefaultClient, *flagOSSFuzz)
+		if err != nil {
+			failure.Default().Fatal(fmt.Errorf("failed to load oss-fuzz projects: %w", err))
+		}
+		logger.Infof("%d oss-fuzz projects loaded", collector.OSSFuzz.Len())
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(links))
+	gopool.SetCap(int32(*flagJobsCount))
+	for _, link := range links {
+		gopool.Go(func() {
+			defer wg.Done()
+			owner, repo, _ := ownerRepo(link)
+			signals, err := collector.Collect(ctx, owner, repo)
+			if err != nil {
+				logger.Errorf("Collecting %s Failed: %v", link, err)
+				failure.Default().Fail(link, err)
+				return
+			}
+
+			if *flagDryRun {
+				fmt.Printf("%s\tcommits=%s\ttags=%s\treleases=%s\tbinaries=%s\tslsa=%s\tsigstore=%s\toss-fuzz=%s\tcflite=%s\n", link,
+					format(signals.SignedCommitRatio), format(signals.SignedTagRatio), format(signals.SignedReleases), format(signals.BinaryReleases),
+					format(signals.SLSAProvenance), format(signals.Sigstore), format(signals.OSSFuzz), format(signals.ClusterFuzzLite))
+				failure.Default().Success()
+				return
+			}
+			if err := sink.Write(repository.GitMetricTableName, &repository.GitMetric{
+				GitLink:           &link,
+				SignedCommitRatio: signals.SignedCommitRatio,
+				SignedTagRatio:    signals.SignedTagRatio,
+				SignedReleases:    signals.SignedReleases,
+				BinaryReleases:    signals.BinaryReleases,
+				SLSAProvenance:    signals.SLSAProvenance,
+				Sigstore:          signals.Sigstore,
+				OSSFuzz:           signals.OSSFuzz,
+				ClusterFuzzLite:   signals.ClusterFuzzLite,
+			}); err != nil {
+				logger.Errorf("Update %s Failed: %v", link, err)
+				failure.Default().Fail(link, err)
+				return
+			}
+			failure.Default().Success()
+		})
+	}
+	wg.Wait()
+	if err := sink.Close(); err != nil {
+		failure.Default().Fatal(err)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/analysis/trend"
+	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/spf13/pflag"
+)
+
+var (
+	flagSnapshot = pflag.Bool("snapshot", true, "take a snapshot of current metrics before computing trends")
+	flagWindow   = pflag.Int("window", 30, "window of growth rates in days")
+	flagHistory  = pflag.Int("history", 180, "only use snapshots in the last n days")
+	flagMovers   = pflag.Int("movers", 20, "number of top movers to print, 0 means do not print")
+	flagBatch    = pflag.Int("batch", 1000, "batch size")
+)
+
+type series struct {
+	dependents      []trend.Point
+	stars           []trend.Point
+	forks           []trend.Point
+	commitFrequency []trend.Point
+}
+
+// seriesOf returns the series of link in seriesMap, added if absent.
+func seriesOf(seriesMap map[string]*series, link string) *series {
+	sr, ok := seriesMap[link]
+	if !ok {
+		sr = &series{}
+		seriesMap[link] = sr
+	}
+	return sr
+}
+
+func growth(points []trend.Point, window time.Duration) *float64 {
+	g, ok := trend.Growth(points, window)
+	if !ok {
+		return nil
+	}
+	return &g
+}
+
+func main() {
+	pflag.Usage = func() {
+		fmt.Println("This tool is used to take metric snapshots and compute month-over-month growth rates.")
+		pflag.PrintDefaults()
+	}
+
+	config.RegistCommonFlags(pflag.CommandLine)
+	config.ParseFlags(pflag.CommandLine)
+
+	ac := storage.GetDefaultAppDatabaseContext()
+	snapshotRepo := repository.NewMetricSnapshotRepository(ac)
+	popularityRepo := repository.NewPopularitySnapshotRepository(ac)
+	trendRepo := repository.NewMetricTrendRepository(ac)
+
+	if *flagSnapshot {
+		log.Println("Taking snapshot...")
+		if err := snapshotRepo.TakeSnapshot(); err != nil {
+			log.Fatalf("Failed to take snapshot: %v", err)
+		}
+	}
+
+	since := time.Now().AddDate(0, 0, -*flagHistory)
+	snapshots, err := snapshotRepo.QuerySince(since)
+	if err != nil {
+		log.Fatalf("Failed to fetch snapshots: %v", err)
+	}
+
+	seriesMap := make(map[string]*series)
+	for s := range snapshots {
+		if s.GitLink == nil || s.SnapshotTime == nil {
+			continue
+		}
+		sr := seriesOf(seriesMap, *s.GitLink)
+		if s.Dependents != nil {
+			sr.dependents = append(sr.dependents, trend.Point{Time: *s.SnapshotTime, Value: float64(*s.Dependents)})
+		}
+		if s.CommitFrequency != nil {
+			sr.commitFrequency = append(sr.commitFrequency, trend.Point{Time: *s.SnapshotTime, Value: *s.CommitFrequency})
+		}
+	}
+
+	// stars and forks are snapshotted by popularity-collector on every
+	// collection
+	popularity, err := popularityRepo.QuerySince(since)
+	if err != nil {
+		log.Fatalf("Failed to fetch popularity snapshots: %v", err)
+	}
+	for s := range popularity {
+		if s.GitLink == nil || s.SnapshotTime == nil {
+			continue
+		}
+		sr := seriesOf(seriesMap, *s.GitLink)
+		if s.Stars != nil {
+			sr.stars = append(sr.stars, trend.Point{Time: *s.SnapshotTime, Value: float64(*s.Stars)})
+		}
+		if s.Forks != nil {
+			sr.forks = append(sr.forks, trend.Point{Time: *s.SnapshotTime, Value: float64(*s.Forks)})
+		}
+	}
+
+	window := time.Duration(*flagWindow) * 24 * time.Hour
+	trends := make([]*repository.MetricTrend, 0, *flagBatch)
+	total := 0
+
+	for link, sr := range seriesMap {
+		t := &repository.MetricTrend{
+			GitLink:               &link,
+			DependentsGrowth:      growth(sr.dependents, window),
+			StarsGrowth:           growth(sr.stars, window),
+			ForksGrowth:           growth(sr.forks, window),
+			CommitFrequencyGrowth: growth(sr.commitFrequency, window),
+		}
+		if t.DependentsGrowth == nil && t.StarsGrowth == nil && t.ForksGrowth == nil && t.CommitFrequencyGrowth == nil {
+			continue
+		}
+
+		trends = append(trends, t)
+		if len(trends) >= *flagBatch {
+			if err := trendRepo.BatchInsertOrUpdate(trends); err != nil {
+				log.Fatalf("Failed to update trends: %v", err)
+			}
+			total += len(trends)
+			trends = make([]*repository.MetricTrend, 0, *flagBatch)
+		}
+	}
+	if err := trendRepo.BatchInsertOrUpdate(trends); err != nil {
+		log.Fatalf("Failed to update trends: %v", err)
+	}
+	total += len(trends)
+	log.Printf("%d trends updated", total)
+
+	if *flagMovers <= 0 {
+		return
+	}
+
+	movers, err := trendRepo.QueryTopMovers(*flagMovers)
+	if err != nil {
+		log.Fatalf("Failed to fetch top movers: %v", err)
+	}
+	fmt.Println("Top movers by dependents growth:")
+	for m := range movers {
+		fmt.Printf("  %-60s %+.2f%%\n", *m.GitLink, *m.DependentsGrowth*100)
+	}
+}
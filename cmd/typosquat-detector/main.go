@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/analysis/typosquat"
+	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+	"github.com/spf13/pflag"
+)
+
+var (
+	flagDists       = pflag.StringSlice("dist", []string{"debian", "arch", "nix", "homebrew", "gentoo"}, "distribution table prefixes to check")
+	flagOutput      = pflag.StringP("output", "o", "", "path to the output csv file, default is stdout")
+	flagTopN        = pflag.Int("top", 1000, "number of top-ranked packages used as targets in each distribution")
+	flagMaxDistance = pflag.Int("distance", 2, "max edit distance between a candidate and its target")
+	flagMinRatio    = pflag.Float64("ratio", 100, "min ratio of target page rank to candidate page rank")
+	flagMinLength   = pflag.Int("min-length", 4, "min length of target package names")
+)
+
+func fetchPackages(ac storage.AppDatabaseContext, prefix string) ([]typosquat.Package, error) {
+	table, err := sqlutil.Table(repository.DistPackageTableName(repository.DistPackageTablePrefix(prefix)))
+	if err != nil {
+		return nil, err
+	}
+	rows, err := ac.Query("SELECT package, COALESCE(page_rank, 0) FROM " + table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	packages := make([]typosquat.Package, 0)
+	for rows.Next() {
+		var pkg typosquat.Package
+		if err := rows.Scan(&pkg.Name, &pkg.Criticality); err != nil {
+			return nil, err
+		}
+		packages = append(packages, pkg)
+	}
+	return packages, rows.Err()
+}
+
+func main() {
+	pflag.Usage = func() {
+		fmt.Println("This tool is used to find typosquat candidates in the collected distribution packages.")
+		pflag.PrintDefaults()
+	}
+
+	config.RegistCommonFlags(pflag.CommandLine)
+	config.ParseFlags(pflag.CommandLine)
+
+	var out io.Writer = os.Stdout
+	if *flagOutput != "" {
+		file, err := os.Create(*flagOutput)
+		if err != nil {
+			log.Fatalf("Failed to create output file: %v", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	cfg := &typosquat.Config{
+		TopN:        *flagTopN,
+		MaxDistance: *flagMaxDistance,
+		MinRatio:    *flagMinRatio,
+		MinLength:   *flagMinLength,
+	}
+
+	writer := csv.NewWriter(out)
+	defer writer.Flush()
+	writer.Write([]string{"dist", "package", "page_rank", "target", "target_page_rank", "distance"})
+
+	ac := storage.GetDefaultReadOnlyAppDatabaseContext()
+	for _, dist := range *flagDists {
+		dist = strings.TrimSpace(dist)
+		packages, err := fetchPackages(ac, dist)
+		if err != nil {
+			log.Printf("Failed to fetch packages of %s: %v", dist, err)
+			continue
+		}
+
+		candidates := typosquat.Detect(packages, cfg)
+		log.Printf("%s: %d packages, %d typosquat candidates", dist, len(packages), len(candidates))
+
+		for _, c := range candidates {
+			writer.Write([]string{
+				dist,
+				c.Name,
+				fmt.Sprintf("%g", c.Criticality),
+				c.Target,
+				fmt.Sprintf("%g", c.TargetCriticality),
+				fmt.Sprintf("%d", c.Distance),
+			})
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/failure"
+	"github.com/HUSTSecLab/criticality_score/pkg/freshness"
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/output"
+	"github.com/HUSTSecLab/criticality_score/pkg/sampling"
+	"github.com/HUSTSecLab/criticality_score/pkg/scope"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+	"github.com/HUSTSecLab/criticality_score/pkg/tagging"
+	"github.com/HUSTSecLab/criticality_score/pkg/wikidata"
+	"github.com/spf13/pflag"
+)
+
+var (
+	flagEndpoint = pflag.String("wikidata-endpoint", wikidata.DefaultEndpoint, "url of the wikidata sparql endpoint")
+	flagDryRun   = pflag.Bool("dry-run", false, "only print the items, do not update the database")
+)
+
+// getLinks returns the known git links.
+func getLinks(ac storage.AppDatabaseContext) ([]string, error) {
+	query, args, err := sqlutil.From(repository.GitRepositoryTableName).Select("git_link")
+	if err != nil {
+		return nil, err
+	}
+	rows, err := ac.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ret []string
+	for rows.Next() {
+		var link string
+		if err := rows.Scan(&link); err != nil {
+			return nil, err
+		}
+		ret = append(ret, link)
+	}
+	return ret, rows.Err()
+}
+
+func main() {
+	pflag.Usage = func() {
+		fmt.Println("This tool is used to find the wikidata items of git repositories and their number of wikipedia articles.")
+		pflag.PrintDefaults()
+	}
+
+	config.RegistCommonFlags(pflag.CommandLine)
+	config.RegistSpoolFlags(pflag.CommandLine)
+	config.RegistSampleFlags(pflag.CommandLine)
+	config.RegistTagFlags(pflag.CommandLine)
+	config.RegistScopeFlags(pflag.CommandLine)
+	config.RegistFreshnessFlags(pflag.CommandLine)
+	config.RegistOutputFlags(pflag.CommandLine)
+	config.RegistFailureFlags(pflag.CommandLine)
+	config.ParseFlags(pflag.CommandLine)
+	defer failure.Default().Exit()
+
+	ac := storage.GetDefaultAppDatabaseContext()
+	tsRepo := repository.NewCollectionTimestampRepository(ac)
+
+	var links []string
+	var err error
+	if config.UsesDatabase() {
+		links, err = getLinks(ac)
+	} else {
+		links, err = output.ReadLinks(config.GetInputPath(), config.GetInputURLColumn())
+	}
+	if err != nil {
+		failure.Default().Fatal(fmt.Errorf("failed to fetch git links: %w", err))
+	}
+	links = sampling.Slice(scope.Slice(tagging.Slice(links)))
+
+	if config.UsesDatabase() {
+		if window := config.GetFreshnessWindow(); window > 0 {
+			stale, err := tsRepo.FilterStale(repository.SignalWikidata, links, time.Now().Add(-window))
+			if err != nil {
+				failure.Default().Fatal(err)
+			}
+			logger.Infof("Skipping %d links collected within %s", len(links)-len(stale), window)
+			links = stale
+		}
+	}
+	logger.Infof("%d links in total", len(links))
+
+	// one sparql query for all links, it is a POST which is not cached by
+	// httpcache
+	index, err := wikidata.LoadIndex(context.Background(), http.DefaultClient, *flagEndpoint)
+	if err != nil {
+		failure.Default().Fatal(fmt.Errorf("failed to query wikidata: %w", err))
+	}
+	logger.Infof("%d repos with a wikidata item", index.Len())
+
+	writer := freshness.MetricWriter(ac, repository.SignalWikidata, freshness.SourceWikidata,
+		"wikidata_item", "wikidata_sitelinks")
+	sink, err := output.Open(config.GetOutputConfig(), output.NewQueue(writer, config.GetQueueConfig()))
+	if err != nil {
+		failure.Default().Fatal(err)
+	}
+
+	found := 0
+	for _, link := range links {
+		// repos without an item have no sitelinks
+		metric := &repository.GitMetric{GitLink: &link, WikidataSitelinks: new(int)}
+		if item, ok := index.Lookup(link); ok {
+			found++
+			metric.WikidataItem, metric.WikidataSitelinks = &item.ID, &item.Sitelinks
+		}
+
+		if *flagDryRun {
+			if metric.WikidataItem != nil {
+				fmt.Printf("%s\t%s\tsitelinks=%d\n", link, *metric.WikidataItem, *metric.WikidataSitelinks)
+			}
+			failure.Default().Success()
+			continue
+		}
+		if err := sink.Write(repository.GitMetricTableName, metric); err != nil {
+			logger.Errorf("Update %s Failed: %v", link, err)
+			failure.Default().Fail(link, err)
+			continue
+		}
+		failure.Default().Success()
+	}
+	if err := sink.Close(); err != nil {
+		failure.Default().Fatal(err)
+	}
+	logger.Infof("%d of %d links have a wikidata item", found, len(links))
+}
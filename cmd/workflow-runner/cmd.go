@@ -8,6 +8,7 @@ var (
 	cmdUpdateDepsDev    []string
 	cmdSyncGitMetrics   []string
 	cmdEnumPlatforms    []string
+	cmdCollectSignals   []string
 )
 
 var (
@@ -17,6 +18,7 @@ var (
 	binUpdateDepsDev    = "deps-dev-collector"
 	binSyncGitMetrics   = "git-metrics-sync"
 	binEnumPlatforms    = "git-platforms-enumerator"
+	binCollectSignals   = "signal-collector"
 )
 
 func initCmds() {
@@ -31,5 +33,6 @@ func initCmds() {
 	cmdUpdateDepsDev = append([]string{dir + binUpdateDepsDev}, commonArgs...)
 	cmdSyncGitMetrics = append([]string{dir + binSyncGitMetrics}, commonArgs...)
 	cmdEnumPlatforms = append([]string{dir + binEnumPlatforms}, commonArgs...)
+	cmdCollectSignals = append([]string{dir + binCollectSignals}, commonArgs...)
 
 }
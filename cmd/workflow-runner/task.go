@@ -12,6 +12,7 @@ var (
 	taskUpdateDepsDev           workflow.WorkflowNode
 	taskSyncGitMetrics          workflow.WorkflowNode
 	taskEnumeratePlatforms      workflow.WorkflowNode
+	taskCollectSignals          workflow.WorkflowNode
 
 	srcDistributionNeedUpdate  workflow.WorkflowNode
 	srcGitlinkNeedUpdate       workflow.WorkflowNode // triggered manually
@@ -30,6 +31,7 @@ func initTasks() {
 		&taskUpdateGitMetrics,
 		&taskUpdateGitMetricsPartial,
 		&taskUpdateDepsDev,
+		&taskCollectSignals,
 	}
 
 	/** update distribution **/
@@ -76,6 +78,14 @@ func initTasks() {
 		&taskEnumeratePlatforms,
 	}
 
+	/** collect pluggable signals **/
+	taskCollectSignals.Name = "collect-signals"
+	taskCollectSignals.Description = "Collect the signals of the registered signal collectors"
+	taskCollectSignals.Cmd = []string{"bash", "-c", "sleep 1; echo 'taskCollectSignals'"}
+	taskCollectSignals.Dependencies = []*workflow.WorkflowNode{
+		&taskSyncGitMetrics,
+	}
+
 	/** enumerate platforms **/
 	taskEnumeratePlatforms.Name = "enumerate-platforms"
 	taskEnumeratePlatforms.Description = "Enumerate the platforms"
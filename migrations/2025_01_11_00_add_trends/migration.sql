@@ -0,0 +1,26 @@
+create table if not exists metric_snapshots
+(
+    id               integer generated always as identity
+        primary key,
+    git_link         varchar(255) not null,
+    dependents       integer,
+    commit_frequency double precision,
+    snapshot_time    timestamp    not null
+);
+
+create index if not exists idx_metric_snapshots_git_link
+    on metric_snapshots (git_link, snapshot_time);
+
+create table if not exists metric_trends
+(
+    id                       integer generated always as identity
+        primary key,
+    git_link                 varchar(255) not null,
+    dependents_growth        double precision,
+    stars_growth             double precision,
+    commit_frequency_growth  double precision,
+    update_time              timestamp
+);
+
+create index if not exists idx_metric_trends_git_link
+    on metric_trends (git_link);
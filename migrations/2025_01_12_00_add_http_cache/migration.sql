@@ -0,0 +1,12 @@
+create table if not exists http_cache
+(
+    url         text      not null
+        primary key,
+    status_code integer   not null,
+    body        bytea,
+    fetched_at  timestamp not null,
+    expires_at  timestamp not null
+);
+
+create index if not exists idx_http_cache_expires_at
+    on http_cache (expires_at);
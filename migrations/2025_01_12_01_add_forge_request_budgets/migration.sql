@@ -0,0 +1,7 @@
+create table if not exists forge_request_budgets
+(
+    forge        varchar(64) not null,
+    window_start timestamp   not null,
+    requests     integer     not null,
+    primary key (forge, window_start)
+);
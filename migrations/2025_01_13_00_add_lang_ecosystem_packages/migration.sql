@@ -0,0 +1,14 @@
+create table if not exists lang_ecosystem_packages
+(
+    id          integer generated always as identity
+        primary key,
+    git_link    varchar(255) not null,
+    type        integer      not null,
+    package     varchar(255) not null,
+    version     varchar(255),
+    dep_count   integer,
+    update_time timestamp
+);
+
+create index if not exists idx_lang_ecosystem_packages_git_link
+    on lang_ecosystem_packages (git_link, type);
@@ -0,0 +1,7 @@
+create table if not exists collection_timestamps
+(
+    git_link                    varchar(255) not null
+        primary key,
+    git_metadata_collected_at   timestamp,
+    lang_ecosystem_collected_at timestamp
+);
@@ -0,0 +1,14 @@
+create table if not exists repo_archives
+(
+    id          serial
+        primary key,
+    git_link    varchar(255) not null,
+    archived_at timestamp    not null,
+    refs_hash   varchar(64)  not null,
+    sha256      varchar(64)  not null,
+    size        bigint,
+    location    text         not null
+);
+
+create index if not exists repo_archives_git_link_archived_at_index
+    on repo_archives (git_link, archived_at);
@@ -0,0 +1,10 @@
+create table if not exists project_tags
+(
+    tag        varchar(255)            not null,
+    git_link   varchar(255)            not null,
+    created_at timestamp default now() not null,
+    primary key (tag, git_link)
+);
+
+create index if not exists project_tags_git_link_index
+    on project_tags (git_link);
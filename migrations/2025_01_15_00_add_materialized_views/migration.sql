@@ -0,0 +1,46 @@
+-- top projects of every ecosystem, by the latest scores
+create materialized view if not exists mv_top_projects_per_ecosystem as
+select *
+from (select eco.ecosystem,
+             row_number() over (partition by eco.ecosystem order by s.score desc, s.git_link) as rank,
+             s.git_link,
+             s.score,
+             s.dist_score,
+             s.dev_score,
+             s.git_score,
+             s.update_time
+      from (select distinct on (git_link) *
+            from scores
+            order by git_link, id desc) s
+               join (select distinct on (git_link) git_link, ecosystem
+                     from git_metrics
+                     order by git_link, id desc) gm on gm.git_link = s.git_link
+               cross join lateral regexp_split_to_table(gm.ecosystem, '\s+') as eco(ecosystem)
+      where s.score is not null
+        and eco.ecosystem <> '') ranked
+where rank <= 1000;
+
+create unique index if not exists mv_top_projects_per_ecosystem_ecosystem_git_link_index
+    on mv_top_projects_per_ecosystem (ecosystem, git_link);
+
+create index if not exists mv_top_projects_per_ecosystem_ecosystem_rank_index
+    on mv_top_projects_per_ecosystem (ecosystem, rank);
+
+-- summary of every distribution, type is the DistType of repository
+create materialized view if not exists mv_distro_summaries as
+select d.type,
+       count(*)                          as projects,
+       coalesce(sum(d.dep_count), 0)     as dependents,
+       coalesce(avg(d.page_rank), 0)     as avg_page_rank,
+       coalesce(avg(s.dist_score), 0)    as avg_dist_score,
+       max(d.update_time)                as update_time
+from (select distinct on (git_link, type) *
+      from distribution_dependencies
+      order by git_link, type, id desc) d
+         left join (select distinct on (git_link) git_link, dist_score
+                    from scores
+                    order by git_link, id desc) s on s.git_link = d.git_link
+group by d.type;
+
+create unique index if not exists mv_distro_summaries_type_index
+    on mv_distro_summaries (type);
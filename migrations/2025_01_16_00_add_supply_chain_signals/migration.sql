@@ -0,0 +1,17 @@
+alter table git_metrics
+    add column if not exists signed_commit_ratio double precision,
+    add column if not exists signed_tag_ratio    double precision,
+    add column if not exists signed_releases     boolean;
+
+alter table git_metrics_prod
+    add column if not exists signed_commit_ratio double precision,
+    add column if not exists signed_tag_ratio    double precision,
+    add column if not exists signed_releases     boolean;
+
+alter table git_metrics_history
+    add column if not exists signed_commit_ratio double precision,
+    add column if not exists signed_tag_ratio    double precision,
+    add column if not exists signed_releases     boolean;
+
+alter table collection_timestamps
+    add column if not exists supply_chain_collected_at timestamp;
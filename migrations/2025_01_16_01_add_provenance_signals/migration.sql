@@ -0,0 +1,11 @@
+alter table git_metrics
+    add column if not exists slsa_provenance boolean,
+    add column if not exists sigstore        boolean;
+
+alter table git_metrics_prod
+    add column if not exists slsa_provenance boolean,
+    add column if not exists sigstore        boolean;
+
+alter table git_metrics_history
+    add column if not exists slsa_provenance boolean,
+    add column if not exists sigstore        boolean;
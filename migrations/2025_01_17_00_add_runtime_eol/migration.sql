@@ -0,0 +1,11 @@
+alter table git_metrics
+    add column if not exists runtimes    text,
+    add column if not exists eol_runtime boolean;
+
+alter table git_metrics_prod
+    add column if not exists runtimes    text,
+    add column if not exists eol_runtime boolean;
+
+alter table git_metrics_history
+    add column if not exists runtimes    text,
+    add column if not exists eol_runtime boolean;
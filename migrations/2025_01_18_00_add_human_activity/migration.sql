@@ -0,0 +1,11 @@
+alter table git_metrics
+    add column if not exists human_contributor_count integer,
+    add column if not exists human_commit_frequency double precision;
+
+alter table git_metrics_prod
+    add column if not exists human_contributor_count integer,
+    add column if not exists human_commit_frequency double precision;
+
+alter table git_metrics_history
+    add column if not exists human_contributor_count integer,
+    add column if not exists human_commit_frequency double precision;
@@ -0,0 +1,20 @@
+alter table git_metrics
+    add column if not exists mailing_list_messages  integer,
+    add column if not exists mailing_list_senders   integer,
+    add column if not exists mailing_list_patches   integer,
+    add column if not exists mailing_list_reviewers integer;
+
+alter table git_metrics_prod
+    add column if not exists mailing_list_messages  integer,
+    add column if not exists mailing_list_senders   integer,
+    add column if not exists mailing_list_patches   integer,
+    add column if not exists mailing_list_reviewers integer;
+
+alter table git_metrics_history
+    add column if not exists mailing_list_messages  integer,
+    add column if not exists mailing_list_senders   integer,
+    add column if not exists mailing_list_patches   integer,
+    add column if not exists mailing_list_reviewers integer;
+
+alter table collection_timestamps
+    add column if not exists mailing_list_collected_at timestamp;
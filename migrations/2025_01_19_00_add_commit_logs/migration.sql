@@ -0,0 +1,8 @@
+create table if not exists commit_logs
+(
+    git_link     text      not null
+        primary key,
+    commits      bytea     not null,
+    commit_count integer   not null,
+    collected_at timestamp not null
+);
@@ -0,0 +1,15 @@
+create table if not exists raw_responses
+(
+    id          bigserial
+        primary key,
+    run_id      varchar(64) not null,
+    method      varchar(16) not null,
+    url         text        not null,
+    request     bytea,
+    status_code integer     not null,
+    body        bytea       not null,
+    fetched_at  timestamp   not null
+);
+
+create index if not exists raw_responses_run_id_url_index
+    on raw_responses (run_id, url);
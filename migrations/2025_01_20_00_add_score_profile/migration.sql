@@ -0,0 +1,3 @@
+-- the scoring profile whose weights produced the score, see score.Profile
+alter table scores
+    add column if not exists profile text;
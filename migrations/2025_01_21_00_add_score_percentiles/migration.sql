@@ -0,0 +1,14 @@
+-- the percentile ranks of a score overall, within the primary language and
+-- within the source distribution, see score.SetPercentiles
+alter table scores
+    add column if not exists language text,
+    add column if not exists distro text,
+    add column if not exists distro_count integer,
+    add column if not exists percentile double precision,
+    add column if not exists language_percentile double precision,
+    add column if not exists distro_percentile double precision;
+
+create index if not exists idx_scores_language
+    on scores (language);
+create index if not exists idx_scores_distro
+    on scores (distro);
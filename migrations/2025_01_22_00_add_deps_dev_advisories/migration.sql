@@ -0,0 +1,12 @@
+alter table lang_ecosystem_packages
+    add column if not exists licenses       varchar(255)[],
+    add column if not exists advisory_count integer;
+
+alter table git_metrics
+    add column if not exists advisory_count integer;
+
+alter table git_metrics_prod
+    add column if not exists advisory_count integer;
+
+alter table git_metrics_history
+    add column if not exists advisory_count integer;
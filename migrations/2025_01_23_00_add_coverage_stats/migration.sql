@@ -0,0 +1,86 @@
+-- coverage of the pipeline for every ecosystem: of the packages collected,
+-- the shares with a resolved git link, with git metrics and with dependents
+create materialized view if not exists mv_coverage_stats as
+with packages as (
+      -- distribution packages, type is the DistType of repository
+      select 'debian' as ecosystem, 0 as type, package, nullif(git_link, '') as git_link
+      from debian_packages
+      union all
+      select 'arch' as ecosystem, 1 as type, package, nullif(git_link, '') as git_link
+      from arch_packages
+      union all
+      select 'homebrew' as ecosystem, 2 as type, package, nullif(git_link, '') as git_link
+      from homebrew_packages
+      union all
+      select 'nix' as ecosystem, 3 as type, package, nullif(git_link, '') as git_link
+      from nix_packages
+      union all
+      select 'alpine' as ecosystem, 4 as type, package, nullif(git_link, '') as git_link
+      from alpine_packages
+      union all
+      select 'centos' as ecosystem, 5 as type, package, nullif(git_link, '') as git_link
+      from centos_packages
+      union all
+      select 'aur' as ecosystem, 6 as type, package, nullif(git_link, '') as git_link
+      from aur_packages
+      union all
+      select 'deepin' as ecosystem, 7 as type, package, nullif(git_link, '') as git_link
+      from deepin_packages
+      union all
+      select 'fedora' as ecosystem, 8 as type, package, nullif(git_link, '') as git_link
+      from fedora_packages
+      union all
+      select 'gentoo' as ecosystem, 9 as type, package, nullif(git_link, '') as git_link
+      from gentoo_packages
+      union all
+      select 'ubuntu' as ecosystem, 10 as type, package, nullif(git_link, '') as git_link
+      from ubuntu_packages
+),
+     lang_packages as (
+         -- language ecosystem packages, type is the LangEcosystemType of
+         -- repository
+         select distinct on (git_link, type, package) *
+         from lang_ecosystem_packages
+         order by git_link, type, package, id desc
+     ),
+     coverage as (
+         select 'distribution'                                              as source,
+                p.ecosystem,
+                p.git_link is not null                                      as has_git_link,
+                exists (select 1 from git_metrics gm where gm.git_link = p.git_link) as has_git_metrics,
+                exists (select 1
+                        from distribution_dependencies dd
+                        where dd.git_link = p.git_link
+                          and dd.type = p.type
+                          and dd.dep_count is not null)                     as has_dependents
+         from packages p
+         union all
+         select 'language',
+                case lp.type
+                    when 0 then 'npm'
+                    when 1 then 'go'
+                    when 2 then 'maven'
+                    when 3 then 'pypi'
+                    when 4 then 'nuget'
+                    when 5 then 'cargo'
+                    end,
+                true,
+                exists (select 1 from git_metrics gm where gm.git_link = lp.git_link),
+                lp.dep_count is not null
+         from lang_packages lp
+     )
+select source,
+       ecosystem,
+       count(*)                                                        as packages,
+       count(*) filter (where has_git_link)                            as with_git_link,
+       count(*) filter (where has_git_metrics)                         as with_git_metrics,
+       count(*) filter (where has_dependents)                          as with_dependents,
+       avg(has_git_link::int)::double precision                        as git_link_share,
+       avg(has_git_metrics::int)::double precision                     as git_metrics_share,
+       avg(has_dependents::int)::double precision                      as dependents_share,
+       now()::timestamp                                                as update_time
+from coverage
+group by source, ecosystem;
+
+create unique index if not exists mv_coverage_stats_source_ecosystem_index
+    on mv_coverage_stats (source, ecosystem);
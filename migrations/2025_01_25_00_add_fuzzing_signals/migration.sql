@@ -0,0 +1,11 @@
+alter table git_metrics
+    add column if not exists oss_fuzz          boolean,
+    add column if not exists cluster_fuzz_lite boolean;
+
+alter table git_metrics_prod
+    add column if not exists oss_fuzz          boolean,
+    add column if not exists cluster_fuzz_lite boolean;
+
+alter table git_metrics_history
+    add column if not exists oss_fuzz          boolean,
+    add column if not exists cluster_fuzz_lite boolean;
@@ -0,0 +1,11 @@
+alter table git_metrics
+    add column if not exists best_practices_badge text;
+
+alter table git_metrics_prod
+    add column if not exists best_practices_badge text;
+
+alter table git_metrics_history
+    add column if not exists best_practices_badge text;
+
+alter table collection_timestamps
+    add column if not exists best_practices_collected_at timestamp;
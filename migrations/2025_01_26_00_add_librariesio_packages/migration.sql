@@ -0,0 +1,14 @@
+create table if not exists librariesio_packages
+(
+    type                  integer      not null,
+    package               varchar(255) not null,
+    git_link              varchar(255),
+    source_rank           integer,
+    dependents_count      integer,
+    dependent_repos_count integer,
+    update_time           timestamp,
+    primary key (type, package)
+);
+
+create index if not exists idx_librariesio_packages_git_link
+    on librariesio_packages (git_link);
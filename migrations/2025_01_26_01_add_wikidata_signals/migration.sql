@@ -0,0 +1,14 @@
+alter table git_metrics
+    add column if not exists wikidata_item      text,
+    add column if not exists wikidata_sitelinks integer;
+
+alter table git_metrics_prod
+    add column if not exists wikidata_item      text,
+    add column if not exists wikidata_sitelinks integer;
+
+alter table git_metrics_history
+    add column if not exists wikidata_item      text,
+    add column if not exists wikidata_sitelinks integer;
+
+alter table collection_timestamps
+    add column if not exists wikidata_collected_at timestamp;
@@ -0,0 +1,11 @@
+alter table git_metrics
+    add column if not exists stackoverflow_questions integer;
+
+alter table git_metrics_prod
+    add column if not exists stackoverflow_questions integer;
+
+alter table git_metrics_history
+    add column if not exists stackoverflow_questions integer;
+
+alter table collection_timestamps
+    add column if not exists stackoverflow_collected_at timestamp;
@@ -0,0 +1,17 @@
+-- where and when every signal of a repo was collected from, and how far it
+-- can be trusted, see package freshness
+create table if not exists signal_provenance
+(
+    git_link     varchar(255)     not null,
+    signal       varchar(32)      not null,
+    source       varchar(64)      not null,
+    collected_at timestamp        not null,
+    confidence   double precision not null,
+    primary key (git_link, signal)
+);
+
+-- the discounts of the git and language ecosystem scores by the freshness
+-- and the confidence of their signals, see score.Discounts
+alter table scores
+    add column if not exists git_discount double precision,
+    add column if not exists lang_eco_discount double precision;
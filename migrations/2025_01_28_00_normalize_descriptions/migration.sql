@@ -0,0 +1,71 @@
+-- descriptions may have been created as varchar(255) by old deployments,
+-- and were truncated at byte 254 by some collectors, which cut multi-byte
+-- runes of CJK descriptions. Collectors now truncate them at rune
+-- boundaries and keep them in Unicode normalization form C, see
+-- collector.Description, and existing rows are normalized the same way.
+
+alter table alpine_packages
+    alter column description type text;
+update alpine_packages
+set description = normalize(description, NFC)
+where description is not nfc normalized;
+
+alter table arch_packages
+    alter column description type text;
+update arch_packages
+set description = normalize(description, NFC)
+where description is not nfc normalized;
+
+alter table aur_packages
+    alter column description type text;
+update aur_packages
+set description = normalize(description, NFC)
+where description is not nfc normalized;
+
+alter table centos_packages
+    alter column description type text;
+update centos_packages
+set description = normalize(description, NFC)
+where description is not nfc normalized;
+
+alter table debian_packages
+    alter column description type text;
+update debian_packages
+set description = normalize(description, NFC)
+where description is not nfc normalized;
+
+alter table deepin_packages
+    alter column description type text;
+update deepin_packages
+set description = normalize(description, NFC)
+where description is not nfc normalized;
+
+alter table fedora_packages
+    alter column description type text;
+update fedora_packages
+set description = normalize(description, NFC)
+where description is not nfc normalized;
+
+alter table gentoo_packages
+    alter column description type text;
+update gentoo_packages
+set description = normalize(description, NFC)
+where description is not nfc normalized;
+
+alter table homebrew_packages
+    alter column description type text;
+update homebrew_packages
+set description = normalize(description, NFC)
+where description is not nfc normalized;
+
+alter table nix_packages
+    alter column description type text;
+update nix_packages
+set description = normalize(description, NFC)
+where description is not nfc normalized;
+
+alter table ubuntu_packages
+    alter column description type text;
+update ubuntu_packages
+set description = normalize(description, NFC)
+where description is not nfc normalized;
@@ -0,0 +1,12 @@
+-- the last probe of every homepage of the distribution packages, and when it
+-- was last alive, see package homepage
+create table if not exists homepage_liveness
+(
+    homepage      text      not null
+        primary key,
+    status_code   integer,
+    error         text,
+    alive         boolean   not null,
+    checked_at    timestamp not null,
+    last_alive_at timestamp
+);
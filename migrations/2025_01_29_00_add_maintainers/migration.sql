@@ -0,0 +1,18 @@
+-- the contacts of distribution packages and of repos, see package
+-- maintainer. source is the table prefix of the distribution, or git for
+-- the CODEOWNERS and security policies of repos, and subject is the package
+-- or the git link
+create table if not exists maintainers
+(
+    source     varchar(32) not null,
+    subject    text        not null,
+    role       varchar(32) not null,
+    contact    text        not null,
+    name       text,
+    email      text,
+    handle     text,
+    updated_at timestamp   not null,
+    primary key (source, subject, role, contact)
+);
+
+create index if not exists maintainers_email_idx on maintainers (lower(email));
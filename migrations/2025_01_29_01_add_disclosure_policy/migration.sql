@@ -0,0 +1,14 @@
+alter table git_metrics
+    add column if not exists security_policy  boolean,
+    add column if not exists security_contact text,
+    add column if not exists embargo_policy   boolean;
+
+alter table git_metrics_prod
+    add column if not exists security_policy  boolean,
+    add column if not exists security_contact text,
+    add column if not exists embargo_policy   boolean;
+
+alter table git_metrics_history
+    add column if not exists security_policy  boolean,
+    add column if not exists security_contact text,
+    add column if not exists embargo_policy   boolean;
@@ -0,0 +1,8 @@
+alter table git_metrics
+    add column if not exists dependency_update_tools text;
+
+alter table git_metrics_prod
+    add column if not exists dependency_update_tools text;
+
+alter table git_metrics_history
+    add column if not exists dependency_update_tools text;
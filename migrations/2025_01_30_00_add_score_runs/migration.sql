@@ -0,0 +1,12 @@
+-- the id of the scoring run of a score, rows of a run are inserted at once
+-- with the same update_time, so the runs before are keyed by it, see
+-- rawresponse.RunIDFormat
+alter table scores
+    add column if not exists run_id varchar(64);
+
+update scores
+    set run_id = to_char(update_time, 'YYYYMMDD-HH24MISS')
+    where run_id is null and update_time is not null;
+
+create index if not exists idx_scores_run_id
+    on scores (run_id);
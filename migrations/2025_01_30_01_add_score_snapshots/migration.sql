@@ -0,0 +1,22 @@
+-- the config and the inputs of every scoring run, so the scores of a run
+-- can be replayed later, see scores-caculator replay
+alter table scores
+    add column if not exists config_hash varchar(64);
+
+create table if not exists score_snapshots (
+    run_id      varchar(64) primary key,
+    started_at  timestamp   not null,
+    config_hash varchar(64) not null,
+    -- json of score.Config
+    config      text        not null,
+    -- json of the number of packages of every distribution
+    dist_counts text        not null
+);
+
+create table if not exists score_snapshot_inputs (
+    run_id   varchar(64) not null,
+    git_link text        not null,
+    -- json of score.Inputs
+    inputs   text        not null,
+    primary key (run_id, git_link)
+);
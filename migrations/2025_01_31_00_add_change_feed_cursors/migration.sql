@@ -0,0 +1,6 @@
+create table if not exists change_feed_cursors
+(
+    feed        varchar(64) not null primary key,
+    cursor      text        not null,
+    update_time timestamp   not null
+);
@@ -0,0 +1,15 @@
+-- the last check of every git link, whether it still serves its repo, where
+-- it moved to, and the replacements suggested for dead ones, see package
+-- linkrepair
+create table if not exists link_checks
+(
+    git_link    text        not null
+        primary key,
+    status      varchar(16) not null,
+    target      text,
+    status_code integer,
+    error       text,
+    suggestions text[],
+    checked_at  timestamp   not null,
+    last_ok_at  timestamp
+);
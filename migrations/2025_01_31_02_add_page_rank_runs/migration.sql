@@ -0,0 +1,17 @@
+-- the PageRank parameters of every run of a collector, so the page_rank of
+-- its packages can be reproduced, see graph.PageRankParams
+create table if not exists page_rank_runs
+(
+    id         bigserial
+        primary key,
+    collector  varchar(64)      not null,
+    iterations integer          not null,
+    damping    double precision not null,
+    epsilon    double precision not null,
+    weighting  varchar(16)      not null,
+    packages   integer          not null,
+    run_time   timestamp        not null
+);
+
+create index if not exists page_rank_runs_collector_run_time_index
+    on page_rank_runs (collector, run_time desc);
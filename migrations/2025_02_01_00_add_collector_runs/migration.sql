@@ -0,0 +1,11 @@
+-- the last run of every collector of a whole ecosystem, e.g. a distribution
+-- or the language ecosystems, which the freshness monitor checks against
+-- its SLA
+create table if not exists collector_runs
+(
+    collector       varchar(64) not null
+        primary key,
+    last_run_at     timestamp   not null,
+    last_success_at timestamp,
+    last_error      text
+);
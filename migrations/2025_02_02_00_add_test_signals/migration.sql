@@ -0,0 +1,14 @@
+alter table git_metrics
+    add column if not exists test_file_count integer,
+    add column if not exists test_loc bigint,
+    add column if not exists tests_per_kloc double precision;
+
+alter table git_metrics_prod
+    add column if not exists test_file_count integer,
+    add column if not exists test_loc bigint,
+    add column if not exists tests_per_kloc double precision;
+
+alter table git_metrics_history
+    add column if not exists test_file_count integer,
+    add column if not exists test_loc bigint,
+    add column if not exists tests_per_kloc double precision;
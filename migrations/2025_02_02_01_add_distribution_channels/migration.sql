@@ -0,0 +1,73 @@
+alter table git_metrics
+    add column if not exists binary_releases boolean;
+
+alter table git_metrics_prod
+    add column if not exists binary_releases boolean;
+
+alter table git_metrics_history
+    add column if not exists binary_releases boolean;
+
+-- the channels distributing every repo as a bitmap, the bits are the
+-- Channel of repository: the LangEcosystemType, 16 + the DistType, and 32
+-- for binary release assets. breadth is the number of channels.
+create materialized view if not exists mv_distribution_channels as
+with channels as (
+      select git_link, type as bit
+      from lang_ecosystems
+      union
+      select git_link, type
+      from lang_ecosystem_packages
+      union
+      select git_link, type
+      from librariesio_packages
+      union
+      select git_link, 16 + 0
+      from debian_packages
+      union
+      select git_link, 16 + 1
+      from arch_packages
+      union
+      select git_link, 16 + 2
+      from homebrew_packages
+      union
+      select git_link, 16 + 3
+      from nix_packages
+      union
+      select git_link, 16 + 4
+      from alpine_packages
+      union
+      select git_link, 16 + 5
+      from centos_packages
+      union
+      select git_link, 16 + 6
+      from aur_packages
+      union
+      select git_link, 16 + 7
+      from deepin_packages
+      union
+      select git_link, 16 + 8
+      from fedora_packages
+      union
+      select git_link, 16 + 9
+      from gentoo_packages
+      union
+      select git_link, 16 + 10
+      from ubuntu_packages
+      union
+      -- binary assets in the recent releases of the latest git metrics
+      select git_link, 32
+      from (select distinct on (git_link) git_link, binary_releases
+            from git_metrics
+            order by git_link, id desc) gm
+      where binary_releases
+)
+select git_link,
+       bit_or(1::bigint << bit) as channels,
+       count(*)::int            as breadth,
+       now()::timestamp         as update_time
+from channels
+where nullif(git_link, '') is not null
+group by git_link;
+
+create unique index if not exists mv_distribution_channels_git_link_index
+    on mv_distribution_channels (git_link);
@@ -0,0 +1,17 @@
+-- stars and forks of the repos, a row per collection, thinned by
+-- popularity-collector --compact so the series stays small
+create table if not exists popularity_snapshots
+(
+    id            integer generated always as identity
+        primary key,
+    git_link      varchar(255) not null,
+    stars         integer,
+    forks         integer,
+    snapshot_time timestamp    not null
+);
+
+create index if not exists idx_popularity_snapshots_git_link
+    on popularity_snapshots (git_link, snapshot_time);
+
+alter table metric_trends
+    add column if not exists forks_growth double precision;
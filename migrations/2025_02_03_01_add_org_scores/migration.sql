@@ -0,0 +1,18 @@
+-- the scores of the projects aggregated by the organization or user owning
+-- them, a row per owner per scoring run
+create table if not exists org_scores
+(
+    run_id       varchar(32)  not null,
+    owner        varchar(255) not null,
+    rank         integer      not null,
+    projects     integer      not null,
+    score_sum    double precision,
+    score_max    double precision,
+    top_link     varchar(255),
+    top_projects integer,
+    update_time  timestamp,
+    primary key (run_id, owner)
+);
+
+create index if not exists idx_org_scores_run_rank
+    on org_scores (run_id, rank);
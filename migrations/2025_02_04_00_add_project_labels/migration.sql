@@ -0,0 +1,23 @@
+-- country and affiliation labels of the projects, see package affiliation.
+-- every import of a source is a new version, the labels of the previous
+-- version are retired rather than deleted so they can be traced back
+create table if not exists project_labels
+(
+    id         integer generated always as identity
+        primary key,
+    git_link   varchar(255)     not null,
+    kind       varchar(32)      not null,
+    value      varchar(255)     not null,
+    confidence double precision not null,
+    source     varchar(64)      not null,
+    version    integer          not null,
+    created_at timestamp        not null,
+    retired_at timestamp
+);
+
+create index if not exists idx_project_labels_git_link
+    on project_labels (git_link)
+    where retired_at is null;
+
+create index if not exists idx_project_labels_source_version
+    on project_labels (source, version);
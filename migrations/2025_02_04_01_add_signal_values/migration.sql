@@ -0,0 +1,19 @@
+-- values of the signals of the pluggable collectors of package signals, a
+-- row per project, collector and signal name
+create table if not exists signal_values
+(
+    git_link     varchar(255)     not null,
+    collector    varchar(64)      not null,
+    name         varchar(128)     not null,
+    value        double precision not null,
+    collected_at timestamp        not null,
+    primary key (git_link, collector, name)
+);
+
+create index if not exists idx_signal_values_collector
+    on signal_values (collector, collected_at);
+
+-- the latest collection of a project by any of the collectors, which
+-- orders the projects of signal-collector by the priority scheduler
+alter table collection_timestamps
+    add column if not exists signal_values_collected_at timestamp;
@@ -0,0 +1,102 @@
+// Package affiliation labels projects with the countries and the
+// organizations they are affiliated with, for policy-oriented analyses,
+// e.g. the critical projects maintained in a country. Labels come from csv
+// files curated by hand and from heuristics, e.g. the email domains of the
+// maintainers of a project. Every label is attributed to its source and
+// versioned by the project_labels table, so a label can be traced back and
+// a bad import rolled back.
+package affiliation
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Kind is the kind of a label.
+type Kind string
+
+const (
+	// KindCountry labels are ISO 3166-1 alpha-2 codes, e.g. CN
+	KindCountry Kind = "country"
+	// KindAffiliation labels are names of organizations, e.g. Red Hat
+	KindAffiliation Kind = "affiliation"
+)
+
+// Kinds are all kinds of labels.
+var Kinds = []Kind{KindCountry, KindAffiliation}
+
+// ErrInvalidLabel is wrapped by the errors of invalid labels.
+var ErrInvalidLabel = errors.New("invalid label")
+
+var countryCode = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// Label labels a project. Confidence is in [0, 1], 1 for labels curated by
+// hand.
+type Label struct {
+	GitLink    string
+	Kind       Kind
+	Value      string
+	Confidence float64
+}
+
+// Validate returns an error wrapping ErrInvalidLabel if the label has no
+// link, an unknown kind, or a country which is not an upper case alpha-2
+// code.
+func (l Label) Validate() error {
+	switch {
+	case l.GitLink == "":
+		return fmt.Errorf("%w: empty link", ErrInvalidLabel)
+	case l.Kind != KindCountry && l.Kind != KindAffiliation:
+		return fmt.Errorf("%w: unknown kind %q of %s", ErrInvalidLabel, l.Kind, l.GitLink)
+	case l.Kind == KindCountry && !countryCode.MatchString(l.Value):
+		return fmt.Errorf("%w: country %q of %s is not an ISO 3166-1 alpha-2 code", ErrInvalidLabel, l.Value, l.GitLink)
+	case l.Value == "":
+		return fmt.Errorf("%w: empty %s of %s", ErrInvalidLabel, l.Kind, l.GitLink)
+	case l.Confidence < 0 || l.Confidence > 1:
+		return fmt.Errorf("%w: confidence %v of %s", ErrInvalidLabel, l.Confidence, l.GitLink)
+	}
+	return nil
+}
+
+// ReadCSV reads labels curated by hand, one per row: the git link, the
+// kind, the value and an optional confidence, 1 if empty. A first row
+// starting with link is a header. Countries are upper cased.
+func ReadCSV(r io.Reader) ([]Label, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	var ret []Label
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return ret, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line == 1 && strings.EqualFold(record[0], "link") {
+			continue
+		}
+		if len(record) < 3 {
+			return nil, fmt.Errorf("%w: line %d has %d columns, want link,kind,value[,confidence]", ErrInvalidLabel, line, len(record))
+		}
+		l := Label{GitLink: record[0], Kind: Kind(strings.ToLower(record[1])), Value: strings.TrimSpace(record[2]), Confidence: 1}
+		if l.Kind == KindCountry {
+			l.Value = strings.ToUpper(l.Value)
+		}
+		if len(record) > 3 && record[3] != "" {
+			if l.Confidence, err = strconv.ParseFloat(record[3], 64); err != nil {
+				return nil, fmt.Errorf("%w: line %d: confidence %q", ErrInvalidLabel, line, record[3])
+			}
+		}
+		if err := l.Validate(); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		ret = append(ret, l)
+	}
+}
@@ -0,0 +1,113 @@
+package affiliation
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/history"
+)
+
+func TestReadCSV(t *testing.T) {
+	in := `link,kind,value,confidence
+https://github.com/madler/zlib, country, us
+https://github.com/openeuler-mirror/A-Tune,affiliation,Huawei,0.8
+`
+	got, err := ReadCSV(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Label{
+		{"https://github.com/madler/zlib", KindCountry, "US", 1},
+		{"https://github.com/openeuler-mirror/A-Tune", KindAffiliation, "Huawei", 0.8},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadCSV() = %+v, want %+v", got, want)
+	}
+
+	for _, in := range []string{
+		"https://github.com/a/b,country,China\n",
+		"https://github.com/a/b,license,MIT\n",
+		"https://github.com/a/b,country\n",
+		"https://github.com/a/b,affiliation,Huawei,2\n",
+	} {
+		if _, err := ReadCSV(strings.NewReader(in)); !errors.Is(err, ErrInvalidLabel) {
+			t.Errorf("ReadCSV(%q) error = %v, want ErrInvalidLabel", in, err)
+		}
+	}
+}
+
+func TestCountryOfDomain(t *testing.T) {
+	tests := []struct {
+		domain string
+		want   string
+		ok     bool
+	}{
+		{"suse.de", "DE", true},
+		{"cs.ox.ac.uk", "GB", true},
+		{"iscas.ac.cn", "CN", true},
+		{"fly.io", "", false},
+		{"kernel.org", "", false},
+	}
+	for _, tt := range tests {
+		if got, ok := CountryOfDomain(tt.domain); got != tt.want || ok != tt.ok {
+			t.Errorf("CountryOfDomain(%s) = %s, %v, want %s, %v", tt.domain, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestDomainHeuristic(t *testing.T) {
+	h := &DomainHeuristic{Domains: DefaultDomains, MinShare: DefaultMinShare}
+	link := "https://github.com/a/b"
+	tests := []struct {
+		name   string
+		emails []string
+		want   []Label
+	}{
+		{"subdomain", []string{"a@us.ibm.com", "b@ibm.com", "c@gmail.com"}, []Label{
+			{link, KindCountry, "US", 2.0 / 3},
+			{link, KindAffiliation, "IBM", 2.0 / 3},
+		}},
+		{"freemail country", []string{"a@qq.com", "b@163.com", "c@redhat.com"}, []Label{
+			{link, KindCountry, "CN", 2.0 / 3},
+		}},
+		{"cctld", []string{"a@example.de", "A@example.de", "b@gmail.com"}, []Label{
+			{link, KindCountry, "DE", 0.5},
+		}},
+		{"no majority", []string{"a@redhat.com", "b@suse.com", "c@gmail.com", "d@fly.io"}, nil},
+		{"no email", []string{"", "nobody"}, nil},
+	}
+	for _, tt := range tests {
+		if got := h.Infer(link, tt.emails); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Infer() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReadDomains(t *testing.T) {
+	got, err := ReadDomains(strings.NewReader("domain,name,country\nHUST.edu.cn,HUST,cn\nkernel.org,Linux Foundation\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if org, ok := got.Lookup("mail.hust.edu.cn"); !ok || org != (Org{"HUST", "CN"}) {
+		t.Errorf("Lookup() = %+v, %v", org, ok)
+	}
+	if _, err := ReadDomains(strings.NewReader("a.com,A,China\n")); err == nil {
+		t.Error("ReadDomains() of an invalid country is not an error")
+	}
+}
+
+func TestTopCommitters(t *testing.T) {
+	commits := []history.Commit{
+		{Author: "a", Email: "a@x.org"},
+		{Author: "b", Email: "b@x.org"},
+		{Author: "A", Email: "A@x.org"},
+		{Author: "dependabot[bot]", Email: "49699333+dependabot[bot]@users.noreply.github.com"},
+		{Author: "dependabot[bot]", Email: "49699333+dependabot[bot]@users.noreply.github.com"},
+		{Author: "c", Email: "c@x.org"},
+	}
+	if got, want := TopCommitters(commits, 2), []string{"a@x.org", "b@x.org"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("TopCommitters() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,255 @@
+package affiliation
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/analysis/bots"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/history"
+)
+
+// SourceMaintainerDomain is the source of the labels of DomainHeuristic.
+const SourceMaintainerDomain = "maintainer-domain"
+
+// DefaultMinShare is the default min share of the emails of a project
+// agreeing on a label.
+const DefaultMinShare = 0.5
+
+// Org is the organization of an email domain.
+type Org struct {
+	Name string
+	// Country is the ISO 3166-1 alpha-2 code of the headquarters, empty if
+	// unknown
+	Country string
+}
+
+// Domains maps email domains to their organizations, subdomains belong to
+// the organization of their domain, e.g. us.ibm.com.
+type Domains map[string]Org
+
+// DefaultDomains are the domains of the organizations maintaining many
+// packages of the distributions.
+var DefaultDomains = Domains{
+	"alibaba-inc.com": {"Alibaba", "CN"},
+	"amazon.com":      {"Amazon", "US"},
+	"apple.com":       {"Apple", "US"},
+	"bytedance.com":   {"ByteDance", "CN"},
+	"canonical.com":   {"Canonical", "GB"},
+	"fb.com":          {"Meta", "US"},
+	"google.com":      {"Google", "US"},
+	"huawei.com":      {"Huawei", "CN"},
+	"ibm.com":         {"IBM", "US"},
+	"intel.com":       {"Intel", "US"},
+	"iscas.ac.cn":     {"ISCAS", "CN"},
+	"kylinos.cn":      {"KylinSoft", "CN"},
+	"loongson.cn":     {"Loongson", "CN"},
+	"meta.com":        {"Meta", "US"},
+	"microsoft.com":   {"Microsoft", "US"},
+	"nvidia.com":      {"NVIDIA", "US"},
+	"oracle.com":      {"Oracle", "US"},
+	"redhat.com":      {"Red Hat", "US"},
+	"suse.com":        {"SUSE", "DE"},
+	"suse.de":         {"SUSE", "DE"},
+	"tencent.com":     {"Tencent", "CN"},
+	"uniontech.com":   {"UnionTech", "CN"},
+}
+
+// ReadDomains reads domains, one per row: the domain, the name of the
+// organization and its optional country. A first row starting with domain
+// is a header.
+func ReadDomains(r io.Reader) (Domains, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	ret := make(Domains)
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return ret, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line == 1 && strings.EqualFold(record[0], "domain") {
+			continue
+		}
+		if len(record) < 2 || record[0] == "" || record[1] == "" {
+			return nil, fmt.Errorf("line %d: want domain,name[,country]", line)
+		}
+		org := Org{Name: record[1]}
+		if len(record) > 2 {
+			org.Country = strings.ToUpper(record[2])
+			if org.Country != "" && !countryCode.MatchString(org.Country) {
+				return nil, fmt.Errorf("line %d: country %q is not an ISO 3166-1 alpha-2 code", line, record[2])
+			}
+		}
+		ret[strings.ToLower(record[0])] = org
+	}
+}
+
+// Lookup returns the organization of domain or of its nearest parent.
+func (d Domains) Lookup(domain string) (Org, bool) {
+	domain = strings.ToLower(domain)
+	for {
+		if org, ok := d[domain]; ok {
+			return org, true
+		}
+		_, parent, ok := strings.Cut(domain, ".")
+		if !ok {
+			return Org{}, false
+		}
+		domain = parent
+	}
+}
+
+// freemail are the domains of mail providers, which tell nothing of the
+// affiliation, with the country of the provider if its users mostly live
+// there.
+var freemail = map[string]string{
+	"gmail.com":                "",
+	"googlemail.com":           "",
+	"outlook.com":              "",
+	"hotmail.com":              "",
+	"live.com":                 "",
+	"yahoo.com":                "",
+	"icloud.com":               "",
+	"me.com":                   "",
+	"protonmail.com":           "",
+	"proton.me":                "",
+	"users.noreply.github.com": "",
+	"qq.com":                   "CN",
+	"foxmail.com":              "CN",
+	"163.com":                  "CN",
+	"126.com":                  "CN",
+	"sina.com":                 "CN",
+	"naver.com":                "KR",
+	"yandex.ru":                "RU",
+	"gmx.de":                   "DE",
+	"web.de":                   "DE",
+}
+
+// genericTLDs are country code top-level domains used as generic ones,
+// e.g. by tech startups.
+var genericTLDs = map[string]bool{
+	"ai": true, "co": true, "fm": true, "gg": true, "io": true, "ly": true,
+	"me": true, "sh": true, "so": true, "tv": true, "ws": true,
+}
+
+// CountryOfDomain returns the country of the country code top-level domain
+// of domain, e.g. DE for suse.de. Generic uses of country codes, e.g. .io,
+// have no country.
+func CountryOfDomain(domain string) (string, bool) {
+	tld := strings.ToLower(domain[strings.LastIndex(domain, ".")+1:])
+	if len(tld) != 2 || genericTLDs[tld] {
+		return "", false
+	}
+	if tld == "uk" {
+		return "GB", true
+	}
+	return strings.ToUpper(tld), true
+}
+
+// Heuristic infers the labels of a project from the emails of its
+// maintainers.
+type Heuristic interface {
+	// Source is the source of the labels, e.g. maintainer-domain
+	Source() string
+	Infer(link string, emails []string) []Label
+}
+
+// DomainHeuristic labels a project with the organization and the country
+// most of its maintainers have their emails at, by Domains and the
+// country code top-level domains. Freemail domains only tell a country for
+// providers whose users mostly live in it, e.g. qq.com.
+type DomainHeuristic struct {
+	Domains Domains
+	// MinShare is the min share of the emails agreeing on a label, the
+	// share is the confidence of the label
+	MinShare float64
+}
+
+var _ Heuristic = (*DomainHeuristic)(nil)
+
+// Source implements Heuristic.
+func (h *DomainHeuristic) Source() string {
+	return SourceMaintainerDomain
+}
+
+// Infer implements Heuristic.
+func (h *DomainHeuristic) Infer(link string, emails []string) []Label {
+	votes := map[Kind]map[string]int{KindCountry: {}, KindAffiliation: {}}
+	total := 0
+	seen := make(map[string]bool)
+	for _, email := range emails {
+		email = strings.ToLower(strings.TrimSpace(email))
+		_, domain, ok := strings.Cut(email, "@")
+		if !ok || domain == "" || seen[email] {
+			continue
+		}
+		seen[email] = true
+		total++
+		if country, ok := freemail[domain]; ok {
+			if country != "" {
+				votes[KindCountry][country]++
+			}
+			continue
+		}
+		org, ok := h.Domains.Lookup(domain)
+		if ok {
+			votes[KindAffiliation][org.Name]++
+		}
+		if ok && org.Country != "" {
+			votes[KindCountry][org.Country]++
+		} else if country, ok := CountryOfDomain(domain); ok {
+			votes[KindCountry][country]++
+		}
+	}
+
+	var ret []Label
+	for _, kind := range Kinds {
+		if value, n := majority(votes[kind]); n > 0 && float64(n)/float64(total) >= h.MinShare {
+			ret = append(ret, Label{GitLink: link, Kind: kind, Value: value, Confidence: float64(n) / float64(total)})
+		}
+	}
+	return ret
+}
+
+// majority returns the value of the most votes, ties are broken by value.
+func majority(votes map[string]int) (string, int) {
+	var value string
+	n := 0
+	for v, c := range votes {
+		if c > n || (c == n && v < value) {
+			value, n = v, c
+		}
+	}
+	return value, n
+}
+
+// TopCommitters returns the emails of the n human authors of the most
+// commits, the de facto maintainers of projects without contacts.
+func TopCommitters(commits []history.Commit, n int) []string {
+	counts := make(map[string]int)
+	for _, c := range commits {
+		if c.Email != "" && !bots.IsBotAuthor(c.Author, c.Email) {
+			counts[strings.ToLower(c.Email)]++
+		}
+	}
+	emails := make([]string, 0, len(counts))
+	for email := range counts {
+		emails = append(emails, email)
+	}
+	sort.Slice(emails, func(i, j int) bool {
+		if counts[emails[i]] != counts[emails[j]] {
+			return counts[emails[i]] > counts[emails[j]]
+		}
+		return emails[i] < emails[j]
+	})
+	if len(emails) > n {
+		emails = emails[:n]
+	}
+	return emails
+}
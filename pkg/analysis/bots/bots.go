@@ -0,0 +1,85 @@
+// Package bots detects automated accounts, e.g. dependabot or renovate,
+// whose commits and comments inflate the activity metrics of a project.
+//
+// A name is a commit author name, a GitHub login or an email address. Bots
+// are detected by the `[bot]` suffix of GitHub apps, by common bot name
+// patterns and by a list of well-known bots which do not follow them.
+package bots
+
+import (
+	"strings"
+)
+
+// Known are well-known bots, lower cased, without the `[bot]` suffix.
+var Known = map[string]bool{
+	"dependabot":            true,
+	"dependabot-preview":    true,
+	"renovate":              true,
+	"renovatebot":           true,
+	"renovate-bot":          true,
+	"greenkeeper":           true,
+	"greenkeeperio-bot":     true,
+	"github-actions":        true,
+	"snyk-bot":              true,
+	"pre-commit-ci":         true,
+	"codecov":               true,
+	"codecov-io":            true,
+	"mergify":               true,
+	"allcontributors":       true,
+	"imgbot":                true,
+	"pyup-bot":              true,
+	"copybara-service":      true,
+	"gitter-badger":         true,
+	"semantic-release-bot":  true,
+	"whitesource-bolt":      true,
+	"azure-pipelines":       true,
+	"netlify":               true,
+	"vercel":                true,
+	"stale":                 true,
+	"release-please":        true,
+	"k8s-ci-robot":          true,
+	"googlebot":             true,
+	"tensorflower-gardener": true,
+}
+
+// botSuffixes are the suffixes of bot names, e.g. `dependabot[bot]`,
+// `k8s-ci-robot`, `ci-bot` or `Renovate Bot`. A bare `bot` suffix is not
+// one of them, as it ends names of people too, e.g. `talbot`.
+var botSuffixes = []string{"[bot]", "-bot", "_bot", ".bot", " bot", "-robot", "_robot"}
+
+// local returns the lower cased name, or the local part of an email
+// without the `id+` prefix of GitHub noreply emails, e.g. `49699333+
+// dependabot[bot]@users.noreply.github.com` is `dependabot[bot]`.
+func local(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if l, _, ok := strings.Cut(name, "@"); ok {
+		name = l
+		if _, after, ok := strings.Cut(name, "+"); ok {
+			name = after
+		}
+	}
+	return name
+}
+
+// IsBot reports whether the name is a bot.
+func IsBot(name string) bool {
+	n := local(name)
+	if n == "" {
+		return false
+	}
+	if Known[strings.TrimSuffix(n, "[bot]")] {
+		return true
+	}
+	for _, s := range botSuffixes {
+		if strings.HasSuffix(n, s) && len(n) > len(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsBotAuthor reports whether a commit author is a bot, by either the name
+// or the email.
+func IsBotAuthor(name, email string) bool {
+	return IsBot(name) || IsBot(email)
+}
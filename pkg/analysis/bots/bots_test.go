@@ -0,0 +1,42 @@
+package bots
+
+import "testing"
+
+func TestIsBot(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"dependabot[bot]", true},
+		{"Dependabot", true},
+		{"49699333+dependabot[bot]@users.noreply.github.com", true},
+		{"bot@renovateapp.com", false},
+		{"renovate@whitesourcesoftware.com", true},
+		{"github-actions[bot]", true},
+		{"k8s-ci-robot", true},
+		{"my-ci-bot", true},
+		{"pre-commit-ci[bot]", true},
+		{"talbot", false},
+		{"bot", false},
+		{"alice", false},
+		{"1234+alice@users.noreply.github.com", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsBot(tt.name); got != tt.want {
+			t.Errorf("IsBot(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIsBotAuthor(t *testing.T) {
+	if !IsBotAuthor("Renovate Bot", "bot@renovateapp.com") {
+		t.Error("IsBotAuthor() of renovate = false, want true")
+	}
+	if !IsBotAuthor("someone", "29139614+renovate[bot]@users.noreply.github.com") {
+		t.Error("IsBotAuthor() of a bot email = false, want true")
+	}
+	if IsBotAuthor("Alice", "alice@example.com") {
+		t.Error("IsBotAuthor() of a person = true, want false")
+	}
+}
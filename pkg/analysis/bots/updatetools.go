@@ -0,0 +1,67 @@
+package bots
+
+import (
+	"encoding/json"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Dependency update tools detected by UpdateTool, their commits are left
+// out of the human activity by IsBot.
+const (
+	ToolDependabot = "dependabot"
+	ToolRenovate   = "renovate"
+)
+
+// updateToolConfigs are the config files of the tools, relative to the
+// root of the repo.
+var updateToolConfigs = map[string]string{
+	".github/dependabot.yml":  ToolDependabot,
+	".github/dependabot.yaml": ToolDependabot,
+	// dependabot preview, before it moved into github
+	".dependabot/config.yml": ToolDependabot,
+
+	"renovate.json":          ToolRenovate,
+	"renovate.json5":         ToolRenovate,
+	".github/renovate.json":  ToolRenovate,
+	".github/renovate.json5": ToolRenovate,
+	".gitlab/renovate.json":  ToolRenovate,
+	".gitlab/renovate.json5": ToolRenovate,
+	".renovaterc":            ToolRenovate,
+	".renovaterc.json":       ToolRenovate,
+	".renovaterc.json5":      ToolRenovate,
+}
+
+// UpdateTool returns the dependency update tool configured by the file of a
+// repo at name, e.g. .github/dependabot.yml.
+func UpdateTool(name string) (string, bool) {
+	tool, ok := updateToolConfigs[path.Clean(name)]
+	return tool, ok
+}
+
+// RenovateInPackageJSON reports whether a root package.json configures
+// renovate by its renovate key.
+func RenovateInPackageJSON(content []byte) bool {
+	var pkg map[string]json.RawMessage
+	if err := json.Unmarshal(content, &pkg); err != nil {
+		return false
+	}
+	_, ok := pkg["renovate"]
+	return ok
+}
+
+// FormatUpdateTools returns the distinct tools sorted and separated by
+// spaces, e.g. "dependabot renovate", or an empty string if there is none.
+func FormatUpdateTools(tools []string) string {
+	seen := make(map[string]bool, len(tools))
+	var ret []string
+	for _, t := range tools {
+		if t != "" && !seen[t] {
+			seen[t] = true
+			ret = append(ret, t)
+		}
+	}
+	sort.Strings(ret)
+	return strings.Join(ret, " ")
+}
@@ -0,0 +1,45 @@
+package bots
+
+import "testing"
+
+func TestUpdateTool(t *testing.T) {
+	tests := []struct {
+		name string
+		tool string
+		ok   bool
+	}{
+		{".github/dependabot.yml", ToolDependabot, true},
+		{".github/dependabot.yaml", ToolDependabot, true},
+		{"renovate.json", ToolRenovate, true},
+		{".gitlab/renovate.json5", ToolRenovate, true},
+		{".renovaterc", ToolRenovate, true},
+		{"dependabot.yml", "", false},
+		{"docs/renovate.json", "", false},
+	}
+	for _, tt := range tests {
+		if tool, ok := UpdateTool(tt.name); tool != tt.tool || ok != tt.ok {
+			t.Errorf("UpdateTool(%q) = %q, %v, want %q, %v", tt.name, tool, ok, tt.tool, tt.ok)
+		}
+	}
+}
+
+func TestRenovateInPackageJSON(t *testing.T) {
+	if !RenovateInPackageJSON([]byte(`{"name": "x", "renovate": {"extends": ["config:base"]}}`)) {
+		t.Error("renovate key not detected")
+	}
+	if RenovateInPackageJSON([]byte(`{"name": "x", "devDependencies": {"renovate": "^37"}}`)) {
+		t.Error("renovate dependency detected as config")
+	}
+	if RenovateInPackageJSON([]byte(`not json`)) {
+		t.Error("invalid package.json detected as config")
+	}
+}
+
+func TestFormatUpdateTools(t *testing.T) {
+	if got := FormatUpdateTools([]string{ToolRenovate, ToolDependabot, ToolRenovate}); got != "dependabot renovate" {
+		t.Errorf("FormatUpdateTools() = %q", got)
+	}
+	if got := FormatUpdateTools(nil); got != "" {
+		t.Errorf("FormatUpdateTools(nil) = %q", got)
+	}
+}
@@ -0,0 +1,75 @@
+// Package disclosure detects whether a repo has a vulnerability disclosure
+// policy, e.g. SECURITY.md, how to report a vulnerability privately, and
+// whether the policy keeps reports under embargo until a fix is released.
+// Critical projects without a disclosure channel leave reporters no choice
+// but to open public issues.
+package disclosure
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/maintainer"
+)
+
+// Policy is the disclosure policy of a repo.
+type Policy struct {
+	// Present is true if the repo has a security policy, see
+	// maintainer.ContactFile for the files looked up
+	Present bool
+	// Contact is where to report vulnerabilities, the first email of the
+	// policy, otherwise the first link of a reporting channel, e.g. private
+	// vulnerability reporting of GitHub or a bug bounty platform
+	Contact string
+	// Embargo is true if the policy asks to keep reports private until a
+	// fix is released, e.g. coordinated disclosure
+	Embargo bool
+}
+
+// reportLinkPattern matches links of private reporting channels.
+var reportLinkPattern = regexp.MustCompile(`https?://[^\s)<>"'\]]*(/security/advisories/new|/security/policy|hackerone\.com/[^\s)<>"'\]]+|bugcrowd\.com/[^\s)<>"'\]]+|huntr\.(dev|com)/[^\s)<>"'\]]*|intigriti\.com/[^\s)<>"'\]]+)`)
+
+// embargoTerms are the lower cased terms of embargo policies.
+var embargoTerms = []string{
+	"embargo",
+	"coordinated disclosure",
+	"coordinated vulnerability disclosure",
+	"responsible disclosure",
+	"do not open a public",
+	"do not report security vulnerabilities through public",
+	"please do not report security",
+	"not disclose",
+	"before public disclosure",
+	"until a fix",
+	"disclosure deadline",
+}
+
+// Parse returns the policy of the content of a security policy file.
+func Parse(content string) Policy {
+	p := Policy{Present: true}
+	if contacts := maintainer.ParseSecurity(content); len(contacts) > 0 {
+		p.Contact = contacts[0].Email
+	} else if link := reportLinkPattern.FindString(content); link != "" {
+		p.Contact = link
+	}
+
+	lower := strings.ToLower(content)
+	for _, term := range embargoTerms {
+		if strings.Contains(lower, term) {
+			p.Embargo = true
+			break
+		}
+	}
+	return p
+}
+
+// Merge merges the policies of several files of a repo, e.g. SECURITY.md
+// and .well-known/security.txt, the contact of the first file having one
+// is kept.
+func (p *Policy) Merge(q Policy) {
+	p.Present = p.Present || q.Present
+	p.Embargo = p.Embargo || q.Embargo
+	if p.Contact == "" {
+		p.Contact = q.Contact
+	}
+}
@@ -0,0 +1,48 @@
+package disclosure
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    Policy
+	}{
+		{
+			"email and embargo",
+			"# Security\n\nPlease do not open a public issue. Email security@project.org, we follow coordinated disclosure.\n",
+			Policy{Present: true, Contact: "security@project.org", Embargo: true},
+		},
+		{
+			"private reporting",
+			"Report vulnerabilities [here](https://github.com/org/repo/security/advisories/new).",
+			Policy{Present: true, Contact: "https://github.com/org/repo/security/advisories/new"},
+		},
+		{
+			"bug bounty",
+			"We run a program at https://hackerone.com/project and keep reports under embargo.",
+			Policy{Present: true, Contact: "https://hackerone.com/project", Embargo: true},
+		},
+		{
+			"template",
+			"Report to security@example.com.\n\nSupported versions: 1.x",
+			Policy{Present: true},
+		},
+	}
+	for _, tt := range tests {
+		if got := Parse(tt.content); got != tt.want {
+			t.Errorf("%s: Parse() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	var p Policy
+	p.Merge(Policy{Present: true})
+	p.Merge(Policy{Present: true, Contact: "security@project.org"})
+	p.Merge(Policy{Present: true, Contact: "other@project.org", Embargo: true})
+	want := Policy{Present: true, Contact: "security@project.org", Embargo: true}
+	if p != want {
+		t.Errorf("Merge() = %+v, want %+v", p, want)
+	}
+}
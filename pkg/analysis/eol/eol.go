@@ -0,0 +1,324 @@
+// Package eol flags projects pinned to end-of-life runtimes, i.e. whose
+// declared runtime constraints only allow release cycles which no longer
+// receive security fixes, e.g. `python_requires = "<3.7"`.
+//
+// A constraint only setting a min version, like the go directive of
+// go.mod, always allows the latest cycle and is never pinned.
+package eol
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/errors"
+	"github.com/HUSTSecLab/criticality_score/pkg/manifest"
+)
+
+// ErrInvalidConstraint is returned for constraints which can not be parsed.
+var ErrInvalidConstraint = errors.Mark(errors.New("invalid version constraint"), errors.ParseError)
+
+// Cycle is a release cycle of a runtime, e.g. python 3.8 or node 18.
+type Cycle struct {
+	Version string
+	// EOL is the end of security support, zero means still supported
+	EOL time.Time
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// Cycles are the release cycles of every runtime in ascending order, from
+// https://endoflife.date. The last cycle of every runtime stands for all
+// the cycles released later.
+var Cycles = map[string][]Cycle{
+	manifest.RuntimePython: {
+		{"2.6", date(2013, 10, 29)},
+		{"2.7", date(2020, 1, 1)},
+		{"3.0", date(2009, 6, 27)},
+		{"3.1", date(2012, 4, 9)},
+		{"3.2", date(2016, 2, 20)},
+		{"3.3", date(2017, 9, 29)},
+		{"3.4", date(2019, 3, 18)},
+		{"3.5", date(2020, 9, 30)},
+		{"3.6", date(2021, 12, 23)},
+		{"3.7", date(2023, 6, 27)},
+		{"3.8", date(2024, 10, 7)},
+		{"3.9", date(2025, 10, 31)},
+		{"3.10", date(2026, 10, 31)},
+		{"3.11", date(2027, 10, 31)},
+		{"3.12", date(2028, 10, 31)},
+		{"3.13", date(2029, 10, 31)},
+		{"3.14", date(2030, 10, 31)},
+		{"3.99", time.Time{}},
+	},
+	manifest.RuntimeNode: {
+		{"0.10", date(2016, 10, 31)},
+		{"0.12", date(2016, 12, 31)},
+		{"4", date(2018, 4, 30)},
+		{"5", date(2016, 6, 30)},
+		{"6", date(2019, 4, 30)},
+		{"7", date(2017, 6, 30)},
+		{"8", date(2019, 12, 31)},
+		{"9", date(2018, 6, 30)},
+		{"10", date(2021, 4, 30)},
+		{"11", date(2019, 6, 1)},
+		{"12", date(2022, 4, 30)},
+		{"13", date(2020, 6, 1)},
+		{"14", date(2023, 4, 30)},
+		{"15", date(2021, 6, 1)},
+		{"16", date(2023, 9, 11)},
+		{"17", date(2022, 6, 1)},
+		{"18", date(2025, 4, 30)},
+		{"19", date(2023, 6, 1)},
+		{"20", date(2026, 4, 30)},
+		{"21", date(2024, 6, 1)},
+		{"22", date(2027, 4, 30)},
+		{"23", date(2025, 6, 1)},
+		{"24", date(2028, 4, 30)},
+		{"99", time.Time{}},
+	},
+	manifest.RuntimeGo: {
+		{"1.11", date(2019, 9, 3)},
+		{"1.12", date(2020, 2, 25)},
+		{"1.13", date(2020, 8, 11)},
+		{"1.14", date(2021, 2, 16)},
+		{"1.15", date(2021, 8, 16)},
+		{"1.16", date(2022, 3, 15)},
+		{"1.17", date(2022, 8, 2)},
+		{"1.18", date(2023, 2, 1)},
+		{"1.19", date(2023, 8, 8)},
+		{"1.20", date(2024, 2, 6)},
+		{"1.21", date(2024, 8, 13)},
+		{"1.22", date(2025, 2, 11)},
+		{"1.23", date(2025, 8, 12)},
+		{"1.99", time.Time{}},
+	},
+}
+
+// version is a version with up to 3 numeric parts, n is the number of
+// parts given, e.g. 3.8 has n = 2 and matches 3.8.x as a prefix.
+type version struct {
+	parts [3]int
+	n     int
+}
+
+func parseVersion(s string) (version, error) {
+	var v version
+	s = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(s), "v"), "go")
+	for _, p := range strings.Split(s, ".") {
+		if p == "*" || p == "x" || p == "X" {
+			break
+		}
+		// pre-release and local suffixes, e.g. 3.8.0rc1 or 14.0.0-beta
+		digits := len(p) - len(strings.TrimLeft(p, "0123456789"))
+		if digits == 0 || v.n == 3 {
+			if v.n == 0 {
+				return v, ErrInvalidConstraint
+			}
+			break
+		}
+		v.parts[v.n], _ = strconv.Atoi(p[:digits])
+		v.n++
+		if digits < len(p) {
+			break
+		}
+	}
+	return v, nil
+}
+
+func compare(a, b version) int {
+	for i := 0; i < 3; i++ {
+		if a.parts[i] != b.parts[i] {
+			if a.parts[i] < b.parts[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// bump returns the exclusive upper bound of the prefix of v with i + 1
+// parts, e.g. bump(3.8.1, 0) is 4.0.0.
+func bump(v version, i int) version {
+	u := version{n: 3}
+	copy(u.parts[:i], v.parts[:i])
+	u.parts[i] = v.parts[i] + 1
+	return u
+}
+
+// matchPrefix reports whether v matches c as a prefix, e.g. 3.8.2 matches
+// 3.8, an empty c matches every version.
+func matchPrefix(v, c version) bool {
+	if c.n == 0 {
+		return true
+	}
+	return compare(v, c) >= 0 && compare(v, bump(c, c.n-1)) < 0
+}
+
+var operators = []string{"~=", "===", "==", "!=", ">=", "<=", ">", "<", "^", "~", "="}
+
+// matchComparator reports whether v satisfies one comparator, e.g. ">=3.8".
+func matchComparator(v version, comparator string) (bool, error) {
+	op := ""
+	for _, o := range operators {
+		if strings.HasPrefix(comparator, o) {
+			op = o
+			break
+		}
+	}
+	c, err := parseVersion(comparator[len(op):])
+	if err != nil {
+		return false, err
+	}
+
+	switch op {
+	case "", "=", "==", "===":
+		return matchPrefix(v, c), nil
+	case "!=":
+		return !matchPrefix(v, c), nil
+	case ">=":
+		return compare(v, c) >= 0, nil
+	case ">":
+		return compare(v, c) > 0, nil
+	case "<=":
+		return compare(v, c) <= 0, nil
+	case "<":
+		return compare(v, c) < 0, nil
+	case "^":
+		// the first non-zero part is kept
+		i := 0
+		for i < c.n-1 && c.parts[i] == 0 {
+			i++
+		}
+		return c.n == 0 || (compare(v, c) >= 0 && compare(v, bump(c, i)) < 0), nil
+	case "~":
+		// the minor is kept if given
+		i := 0
+		if c.n >= 2 {
+			i = 1
+		}
+		return c.n == 0 || (compare(v, c) >= 0 && compare(v, bump(c, i)) < 0), nil
+	case "~=":
+		if c.n < 2 {
+			return false, ErrInvalidConstraint
+		}
+		return compare(v, c) >= 0 && compare(v, bump(c, c.n-2)) < 0, nil
+	}
+	return false, ErrInvalidConstraint
+}
+
+// Allows reports whether v satisfies the constraint, which is in the
+// syntax of PEP 440, e.g. ">=3.8, !=3.9.*", or of npm, e.g.
+// ">=14 <17 || 18.x" and "12 - 16".
+func Allows(constraint string, v string) (bool, error) {
+	ver, err := parseVersion(v)
+	if err != nil {
+		return false, err
+	}
+	for _, alternative := range strings.Split(constraint, "||") {
+		// npm hyphen ranges
+		if lo, hi, ok := strings.Cut(alternative, " - "); ok {
+			alternative = ">=" + strings.TrimSpace(lo) + " " + upperOfHyphen(strings.TrimSpace(hi))
+		}
+
+		// operators may be separated from their versions by spaces
+		tokens := strings.Fields(strings.ReplaceAll(alternative, ",", " "))
+		comparators := make([]string, 0, len(tokens))
+		for i := 0; i < len(tokens); i++ {
+			t := tokens[i]
+			if strings.Trim(t, "=<>!~^") == "" && i+1 < len(tokens) {
+				t += tokens[i+1]
+				i++
+			}
+			comparators = append(comparators, t)
+		}
+
+		all := true
+		for _, c := range comparators {
+			ok, err := matchComparator(ver, c)
+			if err != nil {
+				return false, err
+			}
+			all = all && ok
+		}
+		if all {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// upperOfHyphen returns the upper comparator of a hyphen range, a partial
+// version includes the whole prefix, e.g. "1 - 2" allows 2.9.
+func upperOfHyphen(hi string) string {
+	v, err := parseVersion(hi)
+	if err != nil || v.n == 3 {
+		return "<=" + hi
+	}
+	if v.n == 0 {
+		return ""
+	}
+	u := bump(v, v.n-1)
+	return fmt.Sprintf("<%d.%d.%d", u.parts[0], u.parts[1], u.parts[2])
+}
+
+// cycleBounds returns the lowest and the highest version of a cycle.
+func cycleBounds(cycle string) (string, string) {
+	n := strings.Count(cycle, ".") + 1
+	lo, hi := cycle, cycle
+	for ; n < 3; n++ {
+		lo += ".0"
+		hi += ".999"
+	}
+	return lo, hi
+}
+
+// Pinned reports whether the constraint of the runtime only allows cycles
+// which are end-of-life at now. ok is false if the runtime is unknown or
+// the constraint can not be parsed.
+func Pinned(r manifest.Runtime, now time.Time) (pinned bool, ok bool) {
+	cycles, known := Cycles[r.Name]
+	if !known {
+		return false, false
+	}
+	allowed := 0
+	for _, c := range cycles {
+		lo, hi := cycleBounds(c.Version)
+		okLo, err := Allows(r.Constraint, lo)
+		if err != nil {
+			return false, false
+		}
+		okHi, err := Allows(r.Constraint, hi)
+		if err != nil {
+			return false, false
+		}
+		if !okLo && !okHi {
+			continue
+		}
+		allowed++
+		if c.EOL.IsZero() || c.EOL.After(now) {
+			return false, true
+		}
+	}
+	return allowed > 0, true
+}
+
+// Check reports whether any of the runtimes is pinned to end-of-life
+// cycles, nil if none of the constraints can be checked.
+func Check(runtimes []manifest.Runtime, now time.Time) *bool {
+	var ret *bool
+	for _, r := range runtimes {
+		pinned, ok := Pinned(r, now)
+		if !ok {
+			continue
+		}
+		if ret == nil || pinned {
+			ret = &pinned
+		}
+	}
+	return ret
+}
@@ -0,0 +1,96 @@
+package eol
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/manifest"
+)
+
+func TestAllows(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{">=3.8", "3.12.0", true},
+		{">=3.8", "3.7.9", false},
+		{">= 3.8, <3.11", "3.10.999", true},
+		{">=3.8,<3.11", "3.11.0", false},
+		{"~=3.7", "3.12.0", true},
+		{"~=3.7", "4.0.0", false},
+		{"~=3.7.1", "3.8.0", false},
+		{"==2.7.*", "2.7.18", true},
+		{"!=3.0.*, >=2.7", "3.0.1", false},
+		{"^14.17.0", "14.99.0", true},
+		{"^14.17.0", "15.0.0", false},
+		{"~16.1", "16.2.0", false},
+		{"14.x || 16.x", "16.3.0", true},
+		{"14.x || 16.x", "18.0.0", false},
+		{">=12 <17", "16.999.999", true},
+		{"12 - 16", "16.20.0", true},
+		{"12 - 16", "17.0.0", false},
+		{"*", "20.0.0", true},
+		{">=v1.21", "1.23.0", true},
+		{">=1.21rc1", "1.20.0", false},
+	}
+	for _, tt := range tests {
+		got, err := Allows(tt.constraint, tt.version)
+		if err != nil {
+			t.Errorf("Allows(%q, %q) error = %v", tt.constraint, tt.version, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Allows(%q, %q) = %v, want %v", tt.constraint, tt.version, got, tt.want)
+		}
+	}
+
+	if _, err := Allows(">=latest", "1.0.0"); err == nil {
+		t.Error("Allows(>=latest) error = nil, want ErrInvalidConstraint")
+	}
+}
+
+func TestPinned(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		runtime manifest.Runtime
+		pinned  bool
+		ok      bool
+	}{
+		{manifest.Runtime{Name: manifest.RuntimePython, Constraint: ">=3.6"}, false, true},
+		{manifest.Runtime{Name: manifest.RuntimePython, Constraint: ">=2.7, <3.8"}, true, true},
+		{manifest.Runtime{Name: manifest.RuntimePython, Constraint: "==2.7.*"}, true, true},
+		{manifest.Runtime{Name: manifest.RuntimePython, Constraint: ">=3.8,<3.10"}, false, true},
+		{manifest.Runtime{Name: manifest.RuntimeNode, Constraint: "^14 || ^16"}, true, true},
+		{manifest.Runtime{Name: manifest.RuntimeNode, Constraint: ">=16"}, false, true},
+		// the go directive is a min version
+		{manifest.Runtime{Name: manifest.RuntimeGo, Constraint: ">=1.13"}, false, true},
+		// no release allowed
+		{manifest.Runtime{Name: manifest.RuntimePython, Constraint: ">=4"}, false, true},
+		{manifest.Runtime{Name: "ruby", Constraint: ">=2.7"}, false, false},
+		{manifest.Runtime{Name: manifest.RuntimeNode, Constraint: "lts"}, false, false},
+	}
+	for _, tt := range tests {
+		pinned, ok := Pinned(tt.runtime, now)
+		if pinned != tt.pinned || ok != tt.ok {
+			t.Errorf("Pinned(%s %s) = %v, %v, want %v, %v", tt.runtime.Name, tt.runtime.Constraint, pinned, ok, tt.pinned, tt.ok)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	if got := Check(nil, now); got != nil {
+		t.Errorf("Check(nil) = %v, want nil", *got)
+	}
+	runtimes := []manifest.Runtime{
+		{Name: manifest.RuntimeGo, Constraint: ">=1.21"},
+		{Name: manifest.RuntimeNode, Constraint: "^14"},
+	}
+	if got := Check(runtimes, now); got == nil || !*got {
+		t.Errorf("Check() = %v, want true as node is pinned", got)
+	}
+	if got := Check(runtimes[:1], now); got == nil || *got {
+		t.Errorf("Check() = %v, want false", got)
+	}
+}
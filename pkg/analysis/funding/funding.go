@@ -0,0 +1,343 @@
+// Package funding proposes how to spend a funding budget on the projects
+// whose failure would hurt most: every project has a value, its criticality
+// score raised by its risk flags, e.g. a critical project with a single
+// maintainer, and a cost, and the allocation funds the projects of the
+// highest total value within the budget.
+package funding
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/analysis/maintenance"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+)
+
+// Risk is a flag of a project raising the value of funding it.
+type Risk string
+
+const (
+	// RiskAbandoned is the maintenance risk class abandoned
+	RiskAbandoned Risk = "abandoned"
+	// RiskDormant is the maintenance risk class dormant
+	RiskDormant Risk = "dormant"
+	// RiskSingleMaintainer is a project with at most one human contributor
+	RiskSingleMaintainer Risk = "single-maintainer"
+	// RiskNoSecurityPolicy is a project without a security policy
+	RiskNoSecurityPolicy Risk = "no-security-policy"
+	// RiskAdvisories is a project whose packages have security advisories
+	RiskAdvisories Risk = "advisories"
+	// RiskEOLRuntime is a project only allowing end-of-life runtimes
+	RiskEOLRuntime Risk = "eol-runtime"
+)
+
+// Risks are all risk flags.
+var Risks = []Risk{RiskAbandoned, RiskDormant, RiskSingleMaintainer, RiskNoSecurityPolicy, RiskAdvisories, RiskEOLRuntime}
+
+// DefaultRiskWeights are the weights of the risk flags, a project is worth
+// its score times one plus the weights of its flags.
+var DefaultRiskWeights = map[Risk]float64{
+	RiskAbandoned:        0.5,
+	RiskDormant:          0.25,
+	RiskSingleMaintainer: 0.5,
+	RiskNoSecurityPolicy: 0.1,
+	RiskAdvisories:       0.25,
+	RiskEOLRuntime:       0.1,
+}
+
+// Flags returns the risk flags of the latest metrics of a project, flags
+// of signals which were not collected are not set.
+func Flags(m *repository.GitMetric) []Risk {
+	var ret []Risk
+	if m.MaintenanceRisk != nil {
+		switch maintenance.Risk(*m.MaintenanceRisk) {
+		case maintenance.RiskAbandoned:
+			ret = append(ret, RiskAbandoned)
+		case maintenance.RiskDormant:
+			ret = append(ret, RiskDormant)
+		}
+	}
+	if m.HumanContributorCount != nil && *m.HumanContributorCount <= 1 {
+		ret = append(ret, RiskSingleMaintainer)
+	}
+	if m.SecurityPolicy != nil && !*m.SecurityPolicy {
+		ret = append(ret, RiskNoSecurityPolicy)
+	}
+	if m.AdvisoryCount != nil && *m.AdvisoryCount > 0 {
+		ret = append(ret, RiskAdvisories)
+	}
+	if m.EOLRuntime != nil && *m.EOLRuntime {
+		ret = append(ret, RiskEOLRuntime)
+	}
+	return ret
+}
+
+// ReadCosts reads the costs of projects from csv rows of a git link and a
+// cost, e.g. "https://github.com/madler/zlib,120000". A header row is
+// skipped.
+func ReadCosts(r io.Reader) (map[string]float64, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+	ret := make(map[string]float64)
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return ret, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		cost, err := strconv.ParseFloat(record[1], 64)
+		if err != nil {
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: invalid cost %q", line, record[1])
+		}
+		if cost < 0 {
+			return nil, fmt.Errorf("line %d: negative cost %v", line, cost)
+		}
+		ret[record[0]] = cost
+	}
+}
+
+// Method is how the funded projects are chosen.
+type Method string
+
+const (
+	// MethodGreedy funds the projects by value per cost, it is fast and
+	// at least half as good as the best allocation
+	MethodGreedy Method = "greedy"
+	// MethodKnapsack funds the best allocation, with costs rounded up to
+	// a KnapsackSteps-th of the budget
+	MethodKnapsack Method = "knapsack"
+)
+
+// Methods are all methods.
+var Methods = []Method{MethodGreedy, MethodKnapsack}
+
+// KnapsackSteps is the resolution of the budget of MethodKnapsack.
+const KnapsackSteps = 10000
+
+// Project is a candidate of funding.
+type Project struct {
+	GitLink string  `json:"gitLink"`
+	Score   float64 `json:"score"`
+	Risks   []Risk  `json:"risks,omitempty"`
+	// Cost is the funding the project needs, e.g. a year of a maintainer
+	Cost float64 `json:"cost"`
+	// Value is the score raised by the risks, set by Allocate
+	Value float64 `json:"value"`
+}
+
+// Config is the config of an allocation.
+type Config struct {
+	Budget float64
+	Method Method
+	// RiskWeights are the weights of the risk flags, missing flags weigh 0
+	RiskWeights map[Risk]float64
+}
+
+// Report is a proposed allocation.
+type Report struct {
+	Budget float64 `json:"budget"`
+	Method Method  `json:"method"`
+	// Spent is the cost of the funded projects
+	Spent float64 `json:"spent"`
+	// CoveredValue is the value of the funded projects, and TotalValue
+	// the value of all candidates
+	CoveredValue float64 `json:"coveredValue"`
+	TotalValue   float64 `json:"totalValue"`
+	Candidates   int     `json:"candidates"`
+	// Funded are the funded projects by value, the highest first
+	Funded []Project `json:"funded"`
+}
+
+// Coverage returns the share of the total value covered.
+func (r *Report) Coverage() float64 {
+	if r.TotalValue == 0 {
+		return 0
+	}
+	return r.CoveredValue / r.TotalValue
+}
+
+// Allocate values the projects and proposes the allocation of the budget
+// by the method of config. Projects without a positive cost or value are
+// not funded.
+func Allocate(projects []Project, config *Config) (*Report, error) {
+	r := &Report{Budget: config.Budget, Method: config.Method, Candidates: len(projects)}
+	var candidates []Project
+	for _, p := range projects {
+		weight := 1.0
+		for _, risk := range p.Risks {
+			weight += config.RiskWeights[risk]
+		}
+		p.Value = p.Score * weight
+		r.TotalValue += p.Value
+		if p.Cost > 0 && p.Value > 0 && p.Cost <= config.Budget {
+			candidates = append(candidates, p)
+		}
+	}
+	// a fixed order, so that ties are funded reproducibly
+	sortByValue(candidates)
+
+	switch config.Method {
+	case MethodGreedy, "":
+		r.Funded = greedy(candidates, config.Budget)
+	case MethodKnapsack:
+		r.Funded = knapsack(candidates, config.Budget)
+	default:
+		return nil, fmt.Errorf("unknown method %q", config.Method)
+	}
+	sortByValue(r.Funded)
+	for _, p := range r.Funded {
+		r.Spent += p.Cost
+		r.CoveredValue += p.Value
+	}
+	return r, nil
+}
+
+// sortByValue sorts projects by value, the highest first, and by link.
+func sortByValue(projects []Project) {
+	sort.Slice(projects, func(i, j int) bool {
+		if projects[i].Value != projects[j].Value {
+			return projects[i].Value > projects[j].Value
+		}
+		return projects[i].GitLink < projects[j].GitLink
+	})
+}
+
+// greedy funds the candidates by value per cost, or the most valuable
+// candidate alone if it is worth more, which bounds the loss to half.
+func greedy(candidates []Project, budget float64) []Project {
+	byRatio := append([]Project{}, candidates...)
+	sort.SliceStable(byRatio, func(i, j int) bool {
+		return byRatio[i].Value/byRatio[i].Cost > byRatio[j].Value/byRatio[j].Cost
+	})
+	var ret []Project
+	var spent, value float64
+	for _, p := range byRatio {
+		if spent+p.Cost <= budget {
+			ret = append(ret, p)
+			spent += p.Cost
+			value += p.Value
+		}
+	}
+	if len(candidates) > 0 && candidates[0].Value > value {
+		return candidates[:1]
+	}
+	return ret
+}
+
+// knapsack funds the allocation of the highest value by dynamic
+// programming over the budget in KnapsackSteps steps. Costs are rounded up,
+// so the allocation never exceeds the budget.
+func knapsack(candidates []Project, budget float64) []Project {
+	steps := KnapsackSteps
+	unit := budget / float64(steps)
+	costs := make([]int, len(candidates))
+	for i, p := range candidates {
+		// costs are at most the budget, the min only drops float errors
+		costs[i] = min(int(math.Ceil(p.Cost/unit-1e-9)), steps)
+	}
+
+	// best[c] is the highest value of the candidates so far costing at
+	// most c steps, and taken[i] the bitset of the costs at which the
+	// candidate i is taken
+	best := make([]float64, steps+1)
+	words := (steps + 64) / 64
+	taken := make([][]uint64, len(candidates))
+	for i, p := range candidates {
+		taken[i] = make([]uint64, words)
+		for c := steps; c >= costs[i]; c-- {
+			if v := best[c-costs[i]] + p.Value; v > best[c] {
+				best[c] = v
+				taken[i][c/64] |= 1 << (c % 64)
+			}
+		}
+	}
+
+	var ret []Project
+	c := steps
+	for i := len(candidates) - 1; i >= 0; i-- {
+		if taken[i][c/64]&(1<<(c%64)) != 0 {
+			ret = append(ret, candidates[i])
+			c -= costs[i]
+		}
+	}
+	return ret
+}
+
+// WriteMarkdown writes the report as a markdown document.
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("# Criticality Funding Allocation\n\n")
+	fmt.Fprintf(&b, "- Budget: %s\n", formatAmount(r.Budget))
+	fmt.Fprintf(&b, "- Allocated: %s to %d of %d projects\n", formatAmount(r.Spent), len(r.Funded), r.Candidates)
+	fmt.Fprintf(&b, "- Covered criticality: %.1f%% (%.4g of %.4g)\n", 100*r.Coverage(), r.CoveredValue, r.TotalValue)
+	fmt.Fprintf(&b, "- Method: %s\n\n", r.Method)
+	b.WriteString("| # | Project | Score | Risks | Value | Cost |\n| ---: | --- | ---: | --- | ---: | ---: |\n")
+	for i, p := range r.Funded {
+		risks := make([]string, len(p.Risks))
+		for j, risk := range p.Risks {
+			risks[j] = string(risk)
+		}
+		fmt.Fprintf(&b, "| %d | %s | %.4f | %s | %.4f | %s |\n", i+1, p.GitLink, p.Score, strings.Join(risks, ", "), p.Value, formatAmount(p.Cost))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteCSV writes the funded projects as csv.
+func (r *Report) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"rank", "git_link", "score", "risks", "value", "cost"})
+	for i, p := range r.Funded {
+		risks := make([]string, len(p.Risks))
+		for j, risk := range p.Risks {
+			risks[j] = string(risk)
+		}
+		writer.Write([]string{
+			strconv.Itoa(i + 1),
+			p.GitLink,
+			strconv.FormatFloat(p.Score, 'g', -1, 64),
+			strings.Join(risks, " "),
+			strconv.FormatFloat(p.Value, 'g', -1, 64),
+			strconv.FormatFloat(p.Cost, 'f', 2, 64),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteJSON writes the report as json.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// formatAmount formats an amount with thousands separators, e.g. 1,250,000.
+func formatAmount(amount float64) string {
+	s := strconv.FormatFloat(math.Round(amount), 'f', 0, 64)
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	var b strings.Builder
+	for i, c := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(c)
+	}
+	if neg {
+		return "-" + b.String()
+	}
+	return b.String()
+}
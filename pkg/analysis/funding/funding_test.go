@@ -0,0 +1,104 @@
+package funding
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/samber/lo"
+)
+
+func TestFlags(t *testing.T) {
+	m := &repository.GitMetric{
+		MaintenanceRisk:       lo.ToPtr("abandoned"),
+		HumanContributorCount: lo.ToPtr(1),
+		SecurityPolicy:        lo.ToPtr(true),
+		AdvisoryCount:         lo.ToPtr(2),
+	}
+	if got, want := Flags(m), []Risk{RiskAbandoned, RiskSingleMaintainer, RiskAdvisories}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Flags() = %v, want %v", got, want)
+	}
+	if got := Flags(&repository.GitMetric{}); got != nil {
+		t.Errorf("Flags() of no signals = %v", got)
+	}
+}
+
+func funded(r *Report) []string {
+	links := make([]string, len(r.Funded))
+	for i, p := range r.Funded {
+		links[i] = p.GitLink
+	}
+	return links
+}
+
+func TestAllocate(t *testing.T) {
+	projects := []Project{
+		{GitLink: "big", Score: 0.55, Cost: 60},
+		{GitLink: "a", Score: 0.5, Cost: 50},
+		{GitLink: "b", Score: 0.5, Cost: 50},
+		{GitLink: "risky", Score: 0.2, Risks: []Risk{RiskSingleMaintainer}, Cost: 10},
+		{GitLink: "free", Score: 0.8},
+		{GitLink: "costly", Score: 1, Cost: 1000},
+	}
+	config := &Config{Budget: 100, Method: MethodGreedy, RiskWeights: DefaultRiskWeights}
+
+	// by value per cost: risky 0.03, a and b 0.01, then b does not fit
+	r, err := Allocate(projects, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := funded(r), []string{"a", "risky"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("greedy funded %v, want %v", got, want)
+	}
+	if r.Spent != 60 || math.Abs(r.Funded[1].Value-0.3) > 1e-9 {
+		t.Errorf("greedy report %+v", r)
+	}
+
+	// a and b are worth 1, big and risky 0.85
+	config.Method = MethodKnapsack
+	if r, err = Allocate(projects, config); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := funded(r), []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("knapsack funded %v, want %v", got, want)
+	}
+	if r.Spent != 100 || r.CoveredValue != 1 || r.Candidates != 6 {
+		t.Errorf("knapsack report %+v", r)
+	}
+
+	config.Method = "random"
+	if _, err := Allocate(projects, config); err == nil {
+		t.Error("unknown method is not an error")
+	}
+}
+
+func TestReport(t *testing.T) {
+	costs, err := ReadCosts(strings.NewReader("git_link,cost\nhttps://github.com/madler/zlib, 120000\n"))
+	if err != nil || costs["https://github.com/madler/zlib"] != 120000 {
+		t.Fatalf("ReadCosts() = %v, %v", costs, err)
+	}
+	if _, err := ReadCosts(strings.NewReader("a,1\nb,x\n")); err == nil {
+		t.Error("invalid cost is not an error")
+	}
+
+	r, _ := Allocate([]Project{{GitLink: "https://github.com/madler/zlib", Score: 0.8, Risks: []Risk{RiskDormant}, Cost: 120000}},
+		&Config{Budget: 1500000, RiskWeights: DefaultRiskWeights})
+	var b strings.Builder
+	if err := r.WriteMarkdown(&b); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"- Budget: 1,500,000\n", "- Covered criticality: 100.0%", "| 1 | https://github.com/madler/zlib | 0.8000 | dormant | 1.0000 | 120,000 |\n"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("markdown does not contain %q:\n%s", want, b.String())
+		}
+	}
+	b.Reset()
+	if err := r.WriteCSV(&b); err != nil {
+		t.Fatal(err)
+	}
+	if want := "rank,git_link,score,risks,value,cost\n1,https://github.com/madler/zlib,0.8,dormant,1,120000.00\n"; b.String() != want {
+		t.Errorf("csv = %q, want %q", b.String(), want)
+	}
+}
@@ -0,0 +1,113 @@
+// Package maintenance derives a maintenance risk class of a project from
+// the activity signals of its git metrics, i.e. commit recency, commit
+// frequency and contributor trend.
+//
+// The commit recency decides the base class, other signals can only move
+// the class by one level, so a project with a recent commit is never
+// classified as abandoned.
+package maintenance
+
+import (
+	"time"
+)
+
+type Risk string
+
+const (
+	RiskUnknown   Risk = ""
+	RiskActive    Risk = "active"
+	RiskDeclining Risk = "declining"
+	RiskDormant   Risk = "dormant"
+	RiskAbandoned Risk = "abandoned"
+)
+
+var riskLevels = []Risk{RiskActive, RiskDeclining, RiskDormant, RiskAbandoned}
+
+const day = 24 * time.Hour
+
+// Thresholds of the commit recency, a project whose last commit is older
+// than the threshold falls into the next class.
+const (
+	ActiveWithin    = 180 * day
+	DecliningWithin = 365 * day
+	DormantWithin   = 730 * day
+)
+
+// Signals are the inputs of the classifier, nil means the signal is not
+// collected and it will be ignored.
+type Signals struct {
+	// LastCommit is the time of the latest commit
+	LastCommit *time.Time
+	// CommitFrequency is the average number of commits per week in the last year
+	CommitFrequency *float64
+	// ContributorTrend is the relative change of contributor count
+	// between two snapshots, e.g. -0.5 means half of contributors left
+	ContributorTrend *float64
+}
+
+func (r Risk) level() int {
+	for i, l := range riskLevels {
+		if l == r {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r Risk) shift(n int) Risk {
+	i := r.level() + n
+	i = max(i, 0)
+	i = min(i, len(riskLevels)-1)
+	return riskLevels[i]
+}
+
+// negativeSignals counts signals showing the project is slowing down.
+func (s *Signals) negativeSignals() int {
+	n := 0
+	if s.CommitFrequency != nil && *s.CommitFrequency < 0.2 {
+		n++
+	}
+	if s.ContributorTrend != nil && *s.ContributorTrend < -0.5 {
+		n++
+	}
+	return n
+}
+
+// positiveSignals counts signals showing the project is still maintained
+// although the commit recency says otherwise.
+func (s *Signals) positiveSignals() int {
+	n := 0
+	if s.ContributorTrend != nil && *s.ContributorTrend > 0.2 {
+		n++
+	}
+	return n
+}
+
+// Classify returns the maintenance risk class of the signals at now,
+// RiskUnknown is returned if the commit recency is not available.
+func Classify(s *Signals, now time.Time) Risk {
+	if s == nil || s.LastCommit == nil {
+		return RiskUnknown
+	}
+
+	var risk Risk
+	switch age := now.Sub(*s.LastCommit); {
+	case age <= ActiveWithin:
+		risk = RiskActive
+	case age <= DecliningWithin:
+		risk = RiskDeclining
+	case age <= DormantWithin:
+		risk = RiskDormant
+	default:
+		risk = RiskAbandoned
+	}
+
+	neg, pos := s.negativeSignals(), s.positiveSignals()
+	switch {
+	case neg >= 2 && neg > pos:
+		risk = risk.shift(1)
+	case risk == RiskActive && neg > pos:
+		risk = RiskDeclining
+	}
+	return risk
+}
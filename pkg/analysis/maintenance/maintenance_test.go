@@ -0,0 +1,56 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+func TestClassify(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	ago := func(days int) *time.Time {
+		return lo.ToPtr(now.Add(-time.Duration(days) * day))
+	}
+
+	tests := []struct {
+		name    string
+		signals *Signals
+		want    Risk
+	}{
+		{"nil", nil, RiskUnknown},
+		{"no commit", &Signals{CommitFrequency: lo.ToPtr(10.0)}, RiskUnknown},
+		{"recent commit", &Signals{LastCommit: ago(10)}, RiskActive},
+		{"one year", &Signals{LastCommit: ago(300)}, RiskDeclining},
+		{"two years", &Signals{LastCommit: ago(500)}, RiskDormant},
+		{"long ago", &Signals{LastCommit: ago(1000)}, RiskAbandoned},
+		{
+			"recent commit but slowing down",
+			&Signals{LastCommit: ago(10), CommitFrequency: lo.ToPtr(0.1)},
+			RiskDeclining,
+		},
+		{
+			"contributors left",
+			&Signals{LastCommit: ago(300), CommitFrequency: lo.ToPtr(0.1), ContributorTrend: lo.ToPtr(-0.8)},
+			RiskDormant,
+		},
+		{
+			"contributors joined",
+			&Signals{LastCommit: ago(10), CommitFrequency: lo.ToPtr(0.1), ContributorTrend: lo.ToPtr(0.5)},
+			RiskActive,
+		},
+		{
+			"abandoned stays abandoned",
+			&Signals{LastCommit: ago(1000), CommitFrequency: lo.ToPtr(0.1), ContributorTrend: lo.ToPtr(-0.8)},
+			RiskAbandoned,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Classify(tt.signals, now); got != tt.want {
+				t.Errorf("Classify() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package supplychain
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/gitutil/normalize"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultOSSFuzzProjects is the tarball of the oss-fuzz repo, whose
+// projects/<name>/project.yaml list the integrated projects.
+const DefaultOSSFuzzProjects = "https://codeload.github.com/google/oss-fuzz/tar.gz/refs/heads/master"
+
+// OSSFuzzProjects are the projects integrated with OSS-Fuzz, by the repos
+// they fuzz.
+type OSSFuzzProjects struct {
+	// repos maps the normalize.Key of main_repo to the project name
+	repos map[string]string
+}
+
+// projectYAML is the part of project.yaml of oss-fuzz used.
+type projectYAML struct {
+	MainRepo string `yaml:"main_repo"`
+}
+
+// ReadOSSFuzzProjects reads the projects from a gzipped tarball of the
+// oss-fuzz repo. Projects without a main_repo, or with a main_repo which is
+// not a repo url, are skipped.
+func ReadOSSFuzzProjects(r io.Reader) (*OSSFuzzProjects, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	ret := &OSSFuzzProjects{repos: map[string]string{}}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		// the tarball has a top directory, e.g. oss-fuzz-master/
+		parts := strings.Split(hdr.Name, "/")
+		if hdr.Typeflag != tar.TypeReg || len(parts) != 4 || parts[1] != "projects" || parts[3] != "project.yaml" {
+			continue
+		}
+		var project projectYAML
+		// a malformed project.yaml is skipped, oss-fuzz does not build it
+		// either
+		if err := yaml.NewDecoder(tr).Decode(&project); err != nil || project.MainRepo == "" {
+			continue
+		}
+		if key, err := normalize.Key(project.MainRepo); err == nil {
+			ret.repos[key] = parts[2]
+		}
+	}
+	if len(ret.repos) == 0 {
+		return nil, fmt.Errorf("no oss-fuzz projects found")
+	}
+	return ret, nil
+}
+
+// LoadOSSFuzzProjects reads the projects from src, an url or a path of a
+// gzipped tarball of the oss-fuzz repo, see DefaultOSSFuzzProjects.
+func LoadOSSFuzzProjects(ctx context.Context, client *http.Client, src string) (*OSSFuzzProjects, error) {
+	if !strings.HasPrefix(src, "http://") && !strings.HasPrefix(src, "https://") {
+		f, err := os.Open(src)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return ReadOSSFuzzProjects(f)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: %s", src, resp.Status)
+	}
+	return ReadOSSFuzzProjects(resp.Body)
+}
+
+// Len returns the number of projects.
+func (p *OSSFuzzProjects) Len() int {
+	return len(p.repos)
+}
+
+// Project returns the name of the OSS-Fuzz project fuzzing the repo of
+// link.
+func (p *OSSFuzzProjects) Project(link string) (string, bool) {
+	key, err := normalize.Key(link)
+	if err != nil {
+		return "", false
+	}
+	name, ok := p.repos[key]
+	return name, ok
+}
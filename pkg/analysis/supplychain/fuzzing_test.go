@@ -0,0 +1,133 @@
+package supplychain
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// ossFuzzTarball returns a tarball of an oss-fuzz repo with the files.
+func ossFuzzTarball(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+var ossFuzzFiles = map[string]string{
+	"oss-fuzz-master/projects/curl/project.yaml":     "homepage: \"https://curl.se\"\nlanguage: c\nmain_repo: \"https://github.com/curl/curl.git\"\n",
+	"oss-fuzz-master/projects/glib/project.yaml":     "language: c\nmain_repo: 'https://gitlab.gnome.org/GNOME/glib'\n",
+	"oss-fuzz-master/projects/curl/Dockerfile":       "FROM gcr.io/oss-fuzz-base/base-builder\n",
+	"oss-fuzz-master/projects/norepo/project.yaml":   "language: c\n",
+	"oss-fuzz-master/projects/broken/project.yaml":   "main_repo: [\n",
+	"oss-fuzz-master/docs/example/project.yaml":      "main_repo: https://github.com/example/example\n",
+	"oss-fuzz-master/projects/nested/x/project.yaml": "main_repo: https://github.com/nested/nested\n",
+}
+
+func TestReadOSSFuzzProjects(t *testing.T) {
+	projects, err := ReadOSSFuzzProjects(bytes.NewReader(ossFuzzTarball(t, ossFuzzFiles)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if projects.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", projects.Len())
+	}
+	tests := []struct {
+		link, want string
+		ok         bool
+	}{
+		{"https://github.com/curl/curl", "curl", true},
+		{"git@github.com:Curl/Curl.git", "curl", true},
+		{"https://gitlab.gnome.org/GNOME/glib.git", "glib", true},
+		{"https://github.com/example/example", "", false},
+		{"https://github.com/nested/nested", "", false},
+		{"https://github.com/foo/bar", "", false},
+	}
+	for _, tt := range tests {
+		if got, ok := projects.Project(tt.link); got != tt.want || ok != tt.ok {
+			t.Errorf("Project(%q) = %q, %v, want %q, %v", tt.link, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	if _, err := ReadOSSFuzzProjects(bytes.NewReader(ossFuzzTarball(t, nil))); err == nil {
+		t.Error("ReadOSSFuzzProjects() of an empty tarball succeeded, want an error")
+	}
+}
+
+func TestLoadOSSFuzzProjects(t *testing.T) {
+	tarball := ossFuzzTarball(t, ossFuzzFiles)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(tarball)
+	}))
+	defer server.Close()
+
+	projects, err := LoadOSSFuzzProjects(context.Background(), server.Client(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := projects.Project("https://github.com/curl/curl"); !ok {
+		t.Error("curl is not an oss-fuzz project")
+	}
+}
+
+func TestCollectFuzzing(t *testing.T) {
+	projects, err := ReadOSSFuzzProjects(bytes.NewReader(ossFuzzTarball(t, ossFuzzFiles)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	routes := map[string]string{
+		"/repos/curl/curl/commits":  `[]`,
+		"/repos/curl/curl/releases": `[]`,
+		"/repos/foo/bar/commits":    `[]`,
+		"/repos/foo/bar/releases":   `[]`,
+	}
+	c := newTestCollector(t, routes)
+
+	// the signal is unknown without the projects
+	signals, err := c.Collect(context.Background(), "curl", "curl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signals.OSSFuzz != nil {
+		t.Errorf("OSSFuzz = %v, want nil", *signals.OSSFuzz)
+	}
+	if signals.ClusterFuzzLite == nil || *signals.ClusterFuzzLite {
+		t.Errorf("ClusterFuzzLite = %v, want false without workflows", signals.ClusterFuzzLite)
+	}
+
+	c.OSSFuzz = projects
+	for _, tt := range []struct {
+		owner, repo string
+		want        bool
+	}{
+		{"curl", "curl", true},
+		{"foo", "bar", false},
+	} {
+		signals, err := c.Collect(context.Background(), tt.owner, tt.repo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if signals.OSSFuzz == nil || *signals.OSSFuzz != tt.want {
+			t.Errorf("%s/%s: OSSFuzz = %v, want %v", tt.owner, tt.repo, signals.OSSFuzz, tt.want)
+		}
+	}
+}
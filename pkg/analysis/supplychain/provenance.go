@@ -0,0 +1,141 @@
+package supplychain
+
+import (
+	"context"
+	"path"
+	"strings"
+)
+
+// DefaultWorkflows is the max number of CI workflows inspected per repo.
+const DefaultWorkflows = 20
+
+// WorkflowsDir is the directory of GitHub Actions workflows.
+const WorkflowsDir = ".github/workflows"
+
+// Adoption reports which of the provenance, signing and fuzzing tools a
+// repo uses.
+type Adoption struct {
+	// SLSAProvenance is true if releases ship SLSA provenance or the CI
+	// generates it
+	SLSAProvenance bool
+	// Sigstore is true if releases ship sigstore bundles or the CI signs
+	// with sigstore/cosign
+	Sigstore bool
+	// ClusterFuzzLite is true if the CI runs ClusterFuzzLite
+	ClusterFuzzLite bool
+}
+
+func (a *Adoption) merge(b Adoption) {
+	a.SLSAProvenance = a.SLSAProvenance || b.SLSAProvenance
+	a.Sigstore = a.Sigstore || b.Sigstore
+	a.ClusterFuzzLite = a.ClusterFuzzLite || b.ClusterFuzzLite
+}
+
+// DetectAssets detects the adoption from the names of release assets.
+// Provenance generated by the slsa-github-generator is an in-toto
+// attestation, and keyless cosign signs a blob with a .sig and a
+// certificate.
+func DetectAssets(names []string) Adoption {
+	var a Adoption
+	var sig, cert bool
+	for _, name := range names {
+		name = strings.ToLower(path.Base(name))
+		switch {
+		case strings.HasSuffix(name, ".intoto.jsonl"):
+			a.SLSAProvenance = true
+		case strings.HasSuffix(name, ".sigstore"), strings.HasSuffix(name, ".sigstore.json"),
+			strings.HasSuffix(name, ".cosign.bundle"):
+			a.Sigstore = true
+		case strings.HasSuffix(name, ".sig"):
+			sig = true
+		case strings.HasSuffix(name, ".pem"), strings.HasSuffix(name, ".crt"), strings.HasSuffix(name, ".cert"):
+			cert = true
+		}
+	}
+	a.Sigstore = a.Sigstore || (sig && cert)
+	return a
+}
+
+// workflow patterns, matched against the lower cased workflow
+var (
+	provenancePatterns = []string{
+		"slsa-framework/slsa-github-generator",
+		"actions/attest-build-provenance",
+		// npm and pnpm publish provenance signed by sigstore
+		"--provenance",
+	}
+	sigstorePatterns = []string{
+		"sigstore/cosign-installer",
+		"sigstore/gh-action-sigstore-python",
+		"cosign sign",
+		"cosign attest",
+		// github artifact attestations are signed by sigstore
+		"actions/attest",
+		"--provenance",
+	}
+	clusterFuzzLitePatterns = []string{
+		// e.g. google/clusterfuzzlite/actions/run_fuzzers@v1
+		"google/clusterfuzzlite/actions/",
+	}
+)
+
+func containsAny(s string, patterns []string) bool {
+	for _, p := range patterns {
+		if strings.Contains(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// DetectWorkflow detects the adoption from the content of a CI workflow.
+func DetectWorkflow(content string) Adoption {
+	content = strings.ToLower(content)
+	return Adoption{
+		SLSAProvenance:  containsAny(content, provenancePatterns),
+		Sigstore:        containsAny(content, sigstorePatterns),
+		ClusterFuzzLite: containsAny(content, clusterFuzzLitePatterns),
+	}
+}
+
+// detectWorkflows detects the adoption from the CI workflows of the
+// default branch, a repo without workflows adopts nothing.
+func (c *Collector) detectWorkflows(ctx context.Context, owner, repo string) (Adoption, error) {
+	var a Adoption
+	_, dir, resp, err := c.client.Repositories.GetContents(ctx, owner, repo, WorkflowsDir, nil)
+	if isNotFound(resp) {
+		return a, nil
+	}
+	if err != nil {
+		return a, err
+	}
+
+	inspected := 0
+	for _, entry := range dir {
+		name := entry.GetName()
+		if entry.GetType() != "file" || !(strings.HasSuffix(name, ".yml") || strings.HasSuffix(name, ".yaml")) {
+			continue
+		}
+		if inspected >= c.Workflows {
+			break
+		}
+		inspected++
+
+		file, _, resp, err := c.client.Repositories.GetContents(ctx, owner, repo, entry.GetPath(), nil)
+		if isNotFound(resp) {
+			continue
+		}
+		if err != nil {
+			return a, err
+		}
+		content, err := file.GetContent()
+		if err != nil {
+			return a, err
+		}
+		a.merge(DetectWorkflow(content))
+		if a.SLSAProvenance && a.Sigstore && a.ClusterFuzzLite {
+			break
+		}
+	}
+	return a, nil
+}
@@ -0,0 +1,84 @@
+package supplychain
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+)
+
+func TestDetectAssets(t *testing.T) {
+	tests := []struct {
+		name   string
+		assets []string
+		want   Adoption
+	}{
+		{"none", []string{"tool.tar.gz", "tool.tar.gz.asc"}, Adoption{}},
+		{"slsa", []string{"tool.tar.gz", "tool.intoto.jsonl"}, Adoption{SLSAProvenance: true}},
+		{"bundle", []string{"tool.tar.gz", "tool.tar.gz.sigstore.json"}, Adoption{Sigstore: true}},
+		{"keyless cosign", []string{"tool.tar.gz", "tool.tar.gz.sig", "tool.tar.gz.pem"}, Adoption{Sigstore: true}},
+		{"signature without certificate", []string{"tool.tar.gz", "tool.tar.gz.sig"}, Adoption{}},
+	}
+	for _, tt := range tests {
+		if got := DetectAssets(tt.assets); got != tt.want {
+			t.Errorf("%s: DetectAssets() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDetectWorkflow(t *testing.T) {
+	tests := []struct {
+		name     string
+		workflow string
+		want     Adoption
+	}{
+		{"test only", "steps:\n  - uses: actions/checkout@v4\n  - run: go test ./...\n", Adoption{}},
+		{
+			"slsa generator",
+			"uses: slsa-framework/slsa-github-generator/.github/workflows/generator_generic_slsa3.yml@v2.0.0\n",
+			Adoption{SLSAProvenance: true},
+		},
+		{
+			"cosign",
+			"- uses: sigstore/cosign-installer@v3\n- run: cosign sign --yes $IMAGE\n",
+			Adoption{Sigstore: true},
+		},
+		{"attestation", "- uses: actions/attest-build-provenance@v1\n", Adoption{SLSAProvenance: true, Sigstore: true}},
+		{"npm provenance", "- run: npm publish --provenance --access public\n", Adoption{SLSAProvenance: true, Sigstore: true}},
+		{
+			"clusterfuzzlite",
+			"- uses: google/clusterfuzzlite/actions/build_fuzzers@v1\n- uses: google/clusterfuzzlite/actions/run_fuzzers@v1\n",
+			Adoption{ClusterFuzzLite: true},
+		},
+	}
+	for _, tt := range tests {
+		if got := DetectWorkflow(tt.workflow); got != tt.want {
+			t.Errorf("%s: DetectWorkflow() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCollectAdoption(t *testing.T) {
+	workflow := base64.StdEncoding.EncodeToString([]byte("- uses: sigstore/cosign-installer@v3\n"))
+	c := newTestCollector(t, map[string]string{
+		"/repos/owner/repo/commits":         `[]`,
+		"/repos/owner/repo/releases":        `[{"tag_name": "v1", "assets": [{"name": "tool.intoto.jsonl"}]}]`,
+		"/repos/owner/repo/git/ref/tags/v1": `{"ref": "refs/tags/v1", "object": {"type": "commit", "sha": "bbb"}}`,
+		"/repos/owner/repo/contents/.github/workflows": `[
+			{"type": "file", "name": "test.yml", "path": ".github/workflows/test.yml"},
+			{"type": "file", "name": "release.yml", "path": ".github/workflows/release.yml"},
+			{"type": "file", "name": "README.md", "path": ".github/workflows/README.md"}
+		]`,
+		"/repos/owner/repo/contents/.github/workflows/release.yml": `{"type": "file", "encoding": "base64", "content": "` + workflow + `"}`,
+	})
+
+	signals, err := c.Collect(context.Background(), "owner", "repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signals.SLSAProvenance == nil || !*signals.SLSAProvenance {
+		t.Errorf("SLSAProvenance = %v, want true from the release assets", signals.SLSAProvenance)
+	}
+	if signals.Sigstore == nil || !*signals.Sigstore {
+		t.Errorf("Sigstore = %v, want true from the workflow", signals.Sigstore)
+	}
+}
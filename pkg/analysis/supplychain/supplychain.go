@@ -0,0 +1,228 @@
+// Package supplychain collects supply-chain hygiene signals of GitHub
+// repos, e.g. whether recent commits and release tags are signed, whether
+// releases ship signatures of their artifacts, and whether the repo adopts
+// SLSA provenance and sigstore in its releases or CI, and whether it is
+// fuzzed by OSS-Fuzz or ClusterFuzzLite. The signals do not change the
+// criticality score, they tell how far the artifacts of a critical project
+// can be verified, and fuzzed critical projects are materially lower risk.
+package supplychain
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/google/go-github/v47/github"
+)
+
+// Default number of recent commits and releases inspected per repo.
+const (
+	DefaultCommits  = 30
+	DefaultReleases = 10
+)
+
+// Signals are the supply-chain signals of a repo, nil means the signal
+// can not be collected, e.g. a repo without releases has no tag signals.
+type Signals struct {
+	// SignedCommitRatio is the ratio of verified commits among the recent
+	// commits of the default branch
+	SignedCommitRatio *float64
+	// SignedTagRatio is the ratio of verified annotated tags among the tags
+	// of recent releases, lightweight tags are never signed
+	SignedTagRatio *float64
+	// SignedReleases reports whether any recent release has a signature or
+	// an attestation of its artifacts
+	SignedReleases *bool
+	// BinaryReleases reports whether any recent release ships a binary,
+	// an asset which is not a signature, checksum, SBOM or source archive
+	BinaryReleases *bool
+	// SLSAProvenance and Sigstore report the adoption in release assets or
+	// CI workflows, see Adoption
+	SLSAProvenance *bool
+	Sigstore       *bool
+	// OSSFuzz reports whether the repo is integrated with OSS-Fuzz, nil if
+	// the OSS-Fuzz projects are not loaded
+	OSSFuzz *bool
+	// ClusterFuzzLite reports whether the CI runs ClusterFuzzLite
+	ClusterFuzzLite *bool
+}
+
+// signatureSuffixes are the suffixes of release assets signing other
+// assets, detached gpg/minisign/sigstore signatures and in-toto
+// attestations.
+var signatureSuffixes = []string{
+	".asc", ".sig", ".sign", ".minisig", ".sigstore", ".sigstore.json", ".intoto.jsonl",
+}
+
+// IsSignatureAsset reports whether the release asset name is a signature or
+// an attestation of other assets.
+func IsSignatureAsset(name string) bool {
+	name = strings.ToLower(path.Base(name))
+	for _, suffix := range signatureSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// metadataSuffixes are the suffixes of release assets describing other
+// assets, checksums and SBOMs, and of source archives, which are not
+// binaries.
+var metadataSuffixes = []string{
+	".sha1", ".sha256", ".sha512", ".md5", "sums", "sums.txt", "checksums.txt",
+	".spdx", ".spdx.json", ".cdx.json", ".cdx.xml", ".sbom", ".sbom.json",
+	".pem", ".crt", ".cert", ".cosign.bundle",
+	"-src.tar.gz", "-src.tar.xz", "-src.zip", "-source.tar.gz", "-source.tar.xz", "-source.zip",
+}
+
+// IsBinaryAsset reports whether the release asset name is a binary, i.e.
+// neither a signature or attestation nor a checksum, SBOM or source
+// archive.
+func IsBinaryAsset(name string) bool {
+	if IsSignatureAsset(name) {
+		return false
+	}
+	name = strings.ToLower(path.Base(name))
+	for _, suffix := range metadataSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return false
+		}
+	}
+	return true
+}
+
+// Collector collects the signals by the GitHub REST API.
+type Collector struct {
+	client *github.Client
+	// Commits, Releases and Workflows are the max numbers of recent
+	// commits, recent releases and CI workflows inspected
+	Commits   int
+	Releases  int
+	Workflows int
+	// OSSFuzz are the projects integrated with OSS-Fuzz, see
+	// LoadOSSFuzzProjects, the OSSFuzz signal is not collected if nil
+	OSSFuzz *OSSFuzzProjects
+}
+
+func NewCollector(client *github.Client) *Collector {
+	return &Collector{
+		client:    client,
+		Commits:   DefaultCommits,
+		Releases:  DefaultReleases,
+		Workflows: DefaultWorkflows,
+	}
+}
+
+func ratio(n, total int) *float64 {
+	if total == 0 {
+		return nil
+	}
+	r := float64(n) / float64(total)
+	return &r
+}
+
+func isNotFound(resp *github.Response) bool {
+	return resp != nil && resp.StatusCode == http.StatusNotFound
+}
+
+// Collect collects the signals of owner/repo.
+func (c *Collector) Collect(ctx context.Context, owner, repo string) (*Signals, error) {
+	signals := &Signals{}
+
+	commits, resp, err := c.client.Repositories.ListCommits(ctx, owner, repo, &github.CommitsListOptions{
+		ListOptions: github.ListOptions{PerPage: c.Commits},
+	})
+	// an empty repo has no commits
+	if err != nil && (resp == nil || resp.StatusCode != http.StatusConflict) {
+		return nil, err
+	}
+	signed := 0
+	for _, commit := range commits {
+		if commit.GetCommit().GetVerification().GetVerified() {
+			signed++
+		}
+	}
+	signals.SignedCommitRatio = ratio(signed, len(commits))
+
+	releases, _, err := c.client.Repositories.ListReleases(ctx, owner, repo, &github.ListOptions{PerPage: c.Releases})
+	if err != nil {
+		return nil, err
+	}
+
+	adoption, err := c.detectWorkflows(ctx, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	var assets []string
+	for _, release := range releases {
+		for _, asset := range release.Assets {
+			assets = append(assets, asset.GetName())
+		}
+	}
+	adoption.merge(DetectAssets(assets))
+	signals.SLSAProvenance = &adoption.SLSAProvenance
+	signals.Sigstore = &adoption.Sigstore
+	signals.ClusterFuzzLite = &adoption.ClusterFuzzLite
+	if c.OSSFuzz != nil {
+		_, ok := c.OSSFuzz.Project("https://github.com/" + owner + "/" + repo)
+		signals.OSSFuzz = &ok
+	}
+
+	if len(releases) == 0 {
+		return signals, nil
+	}
+
+	signedReleases, binaryReleases := false, false
+	signed, tags := 0, 0
+	for _, release := range releases {
+		for _, asset := range release.Assets {
+			if IsSignatureAsset(asset.GetName()) {
+				signedReleases = true
+			}
+			if IsBinaryAsset(asset.GetName()) {
+				binaryReleases = true
+			}
+		}
+
+		ok, counted, err := c.tagSigned(ctx, owner, repo, release.GetTagName())
+		if err != nil {
+			return nil, err
+		}
+		if counted {
+			tags++
+		}
+		if ok {
+			signed++
+		}
+	}
+	signals.SignedReleases = &signedReleases
+	signals.BinaryReleases = &binaryReleases
+	signals.SignedTagRatio = ratio(signed, tags)
+	return signals, nil
+}
+
+// tagSigned reports whether the tag is a verified annotated tag, counted is
+// false if the tag does not exist, e.g. a draft release.
+func (c *Collector) tagSigned(ctx context.Context, owner, repo, tag string) (signed bool, counted bool, err error) {
+	if tag == "" {
+		return false, false, nil
+	}
+	ref, resp, err := c.client.Git.GetRef(ctx, owner, repo, "tags/"+tag)
+	if isNotFound(resp) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+	// a lightweight tag points to the commit directly
+	if ref.GetObject().GetType() != "tag" {
+		return false, true, nil
+	}
+	t, _, err := c.client.Git.GetTag(ctx, owner, repo, ref.GetObject().GetSHA())
+	if err != nil {
+		return false, true, err
+	}
+	return t.GetVerification().GetVerified(), true, nil
+}
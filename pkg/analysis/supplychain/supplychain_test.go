@@ -0,0 +1,127 @@
+package supplychain
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-github/v47/github"
+)
+
+func TestIsSignatureAsset(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"tool-1.0.tar.gz.asc", true},
+		{"tool-1.0.tar.gz.sig", true},
+		{"checksums.txt.minisig", true},
+		{"tool.sigstore.json", true},
+		{"multiple.intoto.jsonl", true},
+		{"TOOL.ASC", true},
+		{"tool-1.0.tar.gz", false},
+		{"signature-tool.zip", false},
+	}
+	for _, tt := range tests {
+		if got := IsSignatureAsset(tt.name); got != tt.want {
+			t.Errorf("IsSignatureAsset(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIsBinaryAsset(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"tool-1.0-linux-amd64.tar.gz", true},
+		{"tool_1.0_amd64.deb", true},
+		{"tool-setup.exe", true},
+		{"tool-1.0.tar.gz.asc", false},
+		{"tool_1.0_checksums.txt", false},
+		{"SHA256SUMS", false},
+		{"tool.exe.sha256", false},
+		{"tool.spdx.json", false},
+		{"tool-1.0-src.tar.gz", false},
+	}
+	for _, tt := range tests {
+		if got := IsBinaryAsset(tt.name); got != tt.want {
+			t.Errorf("IsBinaryAsset(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func newTestCollector(t *testing.T, routes map[string]string) *Collector {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := routes[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	client := github.NewClient(server.Client())
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	return NewCollector(client)
+}
+
+func TestCollect(t *testing.T) {
+	c := newTestCollector(t, map[string]string{
+		"/repos/owner/repo/commits": `[
+			{"commit": {"verification": {"verified": true}}},
+			{"commit": {"verification": {"verified": false}}},
+			{"commit": {"verification": {"verified": true}}},
+			{"commit": {}}
+		]`,
+		"/repos/owner/repo/releases": `[
+			{"tag_name": "v2", "assets": [{"name": "tool.tar.gz"}, {"name": "tool.tar.gz.asc"}]},
+			{"tag_name": "v1", "assets": [{"name": "tool.tar.gz"}]},
+			{"tag_name": "draft"}
+		]`,
+		"/repos/owner/repo/git/ref/tags/v2": `{"ref": "refs/tags/v2", "object": {"type": "tag", "sha": "aaa"}}`,
+		"/repos/owner/repo/git/ref/tags/v1": `{"ref": "refs/tags/v1", "object": {"type": "commit", "sha": "bbb"}}`,
+		"/repos/owner/repo/git/tags/aaa":    `{"tag": "v2", "verification": {"verified": true}}`,
+	})
+
+	signals, err := c.Collect(context.Background(), "owner", "repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signals.SignedCommitRatio == nil || *signals.SignedCommitRatio != 0.5 {
+		t.Errorf("SignedCommitRatio = %v, want 0.5", signals.SignedCommitRatio)
+	}
+	// the draft tag does not exist and is not counted
+	if signals.SignedTagRatio == nil || *signals.SignedTagRatio != 0.5 {
+		t.Errorf("SignedTagRatio = %v, want 0.5", signals.SignedTagRatio)
+	}
+	if signals.SignedReleases == nil || !*signals.SignedReleases {
+		t.Errorf("SignedReleases = %v, want true", signals.SignedReleases)
+	}
+	if signals.BinaryReleases == nil || !*signals.BinaryReleases {
+		t.Errorf("BinaryReleases = %v, want true", signals.BinaryReleases)
+	}
+}
+
+func TestCollectWithoutReleases(t *testing.T) {
+	c := newTestCollector(t, map[string]string{
+		"/repos/owner/repo/commits":  `[{"commit": {}}]`,
+		"/repos/owner/repo/releases": `[]`,
+	})
+
+	signals, err := c.Collect(context.Background(), "owner", "repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signals.SignedCommitRatio == nil || *signals.SignedCommitRatio != 0 {
+		t.Errorf("SignedCommitRatio = %v, want 0", signals.SignedCommitRatio)
+	}
+	if signals.SignedTagRatio != nil || signals.SignedReleases != nil {
+		t.Errorf("release signals = %v, %v, want nil", signals.SignedTagRatio, signals.SignedReleases)
+	}
+}
@@ -0,0 +1,153 @@
+// Package testcode estimates the presence and the rough volume of the
+// tests of a repo from its files, by the naming conventions of the test
+// files of every language, e.g. foo_test.go, test_foo.py, foo.spec.ts or
+// any source file in a tests/ or spec/ directory.
+//
+// Only the source files of programming languages count, so fixtures in
+// json or markdown do not inflate the tests. Tests inline in the source,
+// e.g. the #[cfg(test)] modules of Rust, are counted as code, so the
+// ratio underestimates such projects.
+package testcode
+
+import (
+	"bytes"
+	"io"
+	"path"
+	"strings"
+)
+
+// MaxFileSize is the max size of a source file whose lines are counted,
+// larger files are generated, e.g. bundles or parser tables.
+const MaxFileSize = 1 << 20
+
+// sourceExtensions are the extensions of the source files of programming
+// languages.
+var sourceExtensions = map[string]bool{
+	".c": true, ".h": true, ".cc": true, ".cpp": true, ".cxx": true, ".hh": true, ".hpp": true,
+	".go": true, ".rs": true, ".zig": true,
+	".java": true, ".kt": true, ".kts": true, ".scala": true, ".groovy": true, ".clj": true,
+	".cs": true, ".fs": true, ".vb": true,
+	".js": true, ".jsx": true, ".mjs": true, ".cjs": true, ".ts": true, ".tsx": true, ".vue": true,
+	".py": true, ".rb": true, ".php": true, ".pl": true, ".pm": true, ".t": true, ".lua": true,
+	".sh": true, ".bash": true,
+	".swift": true, ".m": true, ".mm": true, ".dart": true,
+	".ex": true, ".exs": true, ".erl": true, ".hs": true, ".ml": true, ".r": true, ".jl": true,
+}
+
+// testDirs are the directories every source file in is a test, compared
+// in lower case.
+var testDirs = map[string]bool{
+	"test":      true,
+	"tests":     true,
+	"testdata":  true,
+	"spec":      true,
+	"specs":     true,
+	"__tests__": true,
+	"e2e":       true,
+}
+
+// testNames, testPrefixes and testSuffixes are the names of test files
+// without their extensions, compared in lower case, e.g. test.py,
+// test_foo.py, foo_test.go, foo.spec.ts or FooTest.java. The suffixes
+// without a separator only match a capitalized word, so latest.go is not
+// a test.
+var (
+	testNames    = map[string]bool{"test": true, "tests": true, "spec": true, "conftest": true}
+	testPrefixes = []string{"test_"}
+	testSuffixes = []string{"_test", "_tests", "_spec", "_unittest", "_suite", ".test", ".spec", "test", "tests", "spec"}
+)
+
+// IsSource reports whether the file at name is a source file of a
+// programming language.
+func IsSource(name string) bool {
+	return sourceExtensions[strings.ToLower(path.Ext(name))]
+}
+
+// IsTest reports whether the source file at name, slash separated and
+// relative to the repo root, is a test.
+func IsTest(name string) bool {
+	dir, file := path.Split(strings.ToLower(name))
+	for _, d := range strings.Split(dir, "/") {
+		if testDirs[d] {
+			return true
+		}
+	}
+	ext := path.Ext(file)
+	base := strings.TrimSuffix(file, ext)
+	// perl tests are .t files
+	if ext == ".t" || testNames[base] {
+		return true
+	}
+	for _, p := range testPrefixes {
+		if strings.HasPrefix(base, p) {
+			return true
+		}
+	}
+	for _, s := range testSuffixes {
+		if strings.HasSuffix(base, s) && (s[0] == '_' || s[0] == '.' || camelSuffix(name, s)) {
+			return true
+		}
+	}
+	return false
+}
+
+// camelSuffix reports whether the suffix s of the file at name starts a
+// word by its case, e.g. FooTest.java but not Latest.java.
+func camelSuffix(name, s string) bool {
+	file := path.Base(name)
+	base := strings.TrimSuffix(file, path.Ext(file))
+	i := len(base) - len(s)
+	return i > 0 && base[i] >= 'A' && base[i] <= 'Z'
+}
+
+// CountLines returns the number of lines of r, a last line without a
+// newline included.
+func CountLines(r io.Reader) (int64, error) {
+	var n int64
+	buf := make([]byte, 32*1024)
+	last := byte('\n')
+	for {
+		c, err := r.Read(buf)
+		if c > 0 {
+			n += int64(bytes.Count(buf[:c], []byte{'\n'}))
+			last = buf[c-1]
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+	if last != '\n' {
+		n++
+	}
+	return n, nil
+}
+
+// Counts are the test files and the lines of the tests and of the other
+// source files of a repo.
+type Counts struct {
+	TestFiles int
+	TestLines int64
+	CodeLines int64
+}
+
+// Add counts the source file at name of lines lines.
+func (c *Counts) Add(name string, lines int64) {
+	if IsTest(name) {
+		c.TestFiles++
+		c.TestLines += lines
+		return
+	}
+	c.CodeLines += lines
+}
+
+// PerKLOC returns the lines of tests per 1000 lines of other code, and
+// false if the repo has no other code.
+func (c Counts) PerKLOC() (float64, bool) {
+	if c.CodeLines == 0 {
+		return 0, false
+	}
+	return float64(c.TestLines) * 1000 / float64(c.CodeLines), true
+}
@@ -0,0 +1,64 @@
+package testcode
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsTest(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"pkg/foo/foo_test.go", true},
+		{"pkg/foo/foo.go", false},
+		{"tests/helpers.py", true},
+		{"src/test/java/org/example/Foo.java", true},
+		{"src/main/java/org/example/FooTest.java", true},
+		{"src/main/java/org/example/Latest.java", false},
+		{"lib/test_parser.py", true},
+		{"lib/conftest.py", true},
+		{"lib/contest.py", false},
+		{"src/app.spec.ts", true},
+		{"src/__tests__/app.tsx", true},
+		{"spec/models/user_spec.rb", true},
+		{"t/basic.t", true},
+		{"Tests/FooTests/FooTests.swift", true},
+		{"test.sh", true},
+		{"src/attestation.rs", false},
+	}
+	for _, tt := range tests {
+		if got := IsTest(tt.name); got != tt.want {
+			t.Errorf("IsTest(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIsSource(t *testing.T) {
+	for name, want := range map[string]bool{"a/b.go": true, "B.JAVA": true, "tests/data.json": false, "README.md": false} {
+		if got := IsSource(name); got != want {
+			t.Errorf("IsSource(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestCountLines(t *testing.T) {
+	for s, want := range map[string]int64{"": 0, "a": 1, "a\n": 1, "a\nb": 2, "a\n\nb\n": 3} {
+		if got, err := CountLines(strings.NewReader(s)); err != nil || got != want {
+			t.Errorf("CountLines(%q) = %d, %v, want %d", s, got, err, want)
+		}
+	}
+}
+
+func TestCounts(t *testing.T) {
+	var c Counts
+	if _, ok := c.PerKLOC(); ok {
+		t.Error("PerKLOC() of no code is ok")
+	}
+	c.Add("main.go", 2000)
+	c.Add("main_test.go", 500)
+	c.Add("tests/e2e.py", 300)
+	if r, ok := c.PerKLOC(); !ok || r != 400 || c.TestFiles != 2 || c.TestLines != 800 || c.CodeLines != 2000 {
+		t.Errorf("Counts = %+v, PerKLOC() = %v", c, r)
+	}
+}
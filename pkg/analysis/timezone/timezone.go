@@ -0,0 +1,79 @@
+// Package timezone estimates how widely the active contributors of a
+// project are spread over timezones, from the UTC offsets recorded in
+// their commits. A project maintained from a single timezone is more
+// vulnerable to regional disruptions, e.g. holidays, outages or sanctions.
+//
+// Every contributor counts once with the offset of most of their commits,
+// so a prolific contributor does not outweigh the others.
+package timezone
+
+import (
+	"math"
+	"time"
+)
+
+// Tracker collects the offsets of the commits of every contributor.
+type Tracker struct {
+	// contributor -> offset in hours -> commits
+	offsets map[string]map[int]int
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{offsets: make(map[string]map[int]int)}
+}
+
+// hourOffset returns the UTC offset of t rounded to hours, e.g. +05:30 is
+// rounded to +6, so half-hour zones do not count as separate zones.
+func hourOffset(t time.Time) int {
+	_, seconds := t.Zone()
+	return int(math.Round(float64(seconds) / 3600))
+}
+
+// Add records a commit of the contributor at when.
+func (t *Tracker) Add(contributor string, when time.Time) {
+	m, ok := t.offsets[contributor]
+	if !ok {
+		m = make(map[int]int)
+		t.offsets[contributor] = m
+	}
+	m[hourOffset(when)]++
+}
+
+// Contributors returns the number of contributors recorded.
+func (t *Tracker) Contributors() int {
+	return len(t.offsets)
+}
+
+// Offsets returns the number of contributors of every hour offset, the
+// offset of a contributor is the one of most of their commits, ties are
+// broken by the smaller offset.
+func (t *Tracker) Offsets() map[int]int {
+	ret := make(map[int]int)
+	for _, m := range t.offsets {
+		best, bestCount := 0, -1
+		for offset, count := range m {
+			if count > bestCount || (count == bestCount && offset < best) {
+				best, bestCount = offset, count
+			}
+		}
+		ret[best]++
+	}
+	return ret
+}
+
+// Diversity returns the effective number of timezones of the contributors,
+// the exponential of the Shannon entropy of their offsets. It is 1 if all
+// contributors share one timezone, n if they are evenly spread over n
+// timezones, and 0 if no contributor is recorded.
+func (t *Tracker) Diversity() float64 {
+	total := t.Contributors()
+	if total == 0 {
+		return 0
+	}
+	entropy := 0.0
+	for _, n := range t.Offsets() {
+		p := float64(n) / float64(total)
+		entropy -= p * math.Log(p)
+	}
+	return math.Exp(entropy)
+}
@@ -0,0 +1,58 @@
+package timezone
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func at(offsetMinutes int) time.Time {
+	return time.Date(2025, 1, 1, 12, 0, 0, 0, time.FixedZone("", offsetMinutes*60))
+}
+
+func TestDiversity(t *testing.T) {
+	tr := NewTracker()
+	if d := tr.Diversity(); d != 0 {
+		t.Errorf("Diversity() of no contributors = %v, want 0", d)
+	}
+
+	// one timezone, however many contributors
+	tr.Add("alice", at(480))
+	tr.Add("bob", at(480))
+	if d := tr.Diversity(); math.Abs(d-1) > 1e-9 {
+		t.Errorf("Diversity() of one timezone = %v, want 1", d)
+	}
+
+	// the offset of most commits counts, +05:30 is rounded to +6
+	tr.Add("carol", at(-300))
+	tr.Add("dave", at(330))
+	tr.Add("dave", at(330))
+	tr.Add("dave", at(0))
+	tr.Add("erin", at(360))
+	got := tr.Offsets()
+	want := map[int]int{8: 2, -5: 1, 6: 2}
+	if len(got) != len(want) {
+		t.Fatalf("Offsets() = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("Offsets()[%d] = %d, want %d", k, got[k], v)
+		}
+	}
+
+	// 2/5, 2/5 and 1/5 of the contributors
+	entropy := -(0.4*math.Log(0.4)*2 + 0.2*math.Log(0.2))
+	if d := tr.Diversity(); math.Abs(d-math.Exp(entropy)) > 1e-9 {
+		t.Errorf("Diversity() = %v, want %v", d, math.Exp(entropy))
+	}
+}
+
+func TestEvenSpread(t *testing.T) {
+	tr := NewTracker()
+	for i, name := range []string{"a", "b", "c", "d"} {
+		tr.Add(name, at(i*360))
+	}
+	if d := tr.Diversity(); math.Abs(d-4) > 1e-9 {
+		t.Errorf("Diversity() of 4 timezones = %v, want 4", d)
+	}
+}
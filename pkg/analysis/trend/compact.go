@@ -0,0 +1,65 @@
+package trend
+
+import (
+	"sort"
+	"time"
+)
+
+// Tier is a tier of the retention of a series, the points older than Age
+// are thinned to the latest point of every Interval.
+type Tier struct {
+	Age      time.Duration
+	Interval time.Duration
+}
+
+// DefaultRetention keeps every point of the last month, a point per week
+// of the last year and a point per month of the older ones, so a series
+// collected daily grows by about 12 points per year.
+var DefaultRetention = []Tier{
+	{Age: Month, Interval: 7 * 24 * time.Hour},
+	{Age: 365 * 24 * time.Hour, Interval: Month},
+}
+
+// Compact returns the indexes, ascending, of the points of a series taken
+// at times which the retention tiers drop at now. Points younger than the
+// first tier are kept. The intervals are aligned to the unix epoch, so
+// compacting a compacted series again drops nothing until the points age
+// into the next tier.
+func Compact(times []time.Time, now time.Time, tiers []Tier) []int {
+	sorted := make([]Tier, len(tiers))
+	copy(sorted, tiers)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Age < sorted[j].Age
+	})
+
+	type bucket struct {
+		tier  int
+		index int64
+	}
+	latest := make(map[bucket]int)
+	var drop []int
+	for i, t := range times {
+		tier := -1
+		for j, tr := range sorted {
+			if tr.Interval > 0 && now.Sub(t) >= tr.Age {
+				tier = j
+			}
+		}
+		if tier < 0 {
+			continue
+		}
+		b := bucket{tier, t.UnixNano() / int64(sorted[tier].Interval)}
+		prev, ok := latest[b]
+		switch {
+		case !ok:
+			latest[b] = i
+		case t.After(times[prev]):
+			drop = append(drop, prev)
+			latest[b] = i
+		default:
+			drop = append(drop, i)
+		}
+	}
+	sort.Ints(drop)
+	return drop
+}
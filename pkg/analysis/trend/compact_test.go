@@ -0,0 +1,57 @@
+package trend
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCompact(t *testing.T) {
+	now := time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	// daily points of 400 days, twice on the latest day
+	var times []time.Time
+	for i := 400; i >= 0; i-- {
+		times = append(times, now.Add(-time.Duration(i)*day))
+	}
+	times = append(times, now.Add(-time.Hour))
+
+	drop := Compact(times, now, DefaultRetention)
+	dropped := make(map[int]bool)
+	for i, idx := range drop {
+		if i > 0 && idx <= drop[i-1] {
+			t.Fatalf("Compact() = %v, not ascending", drop)
+		}
+		dropped[idx] = true
+	}
+	var kept []time.Time
+	recent, weekly, monthly := 0, 0, 0
+	for i, at := range times {
+		if dropped[i] {
+			continue
+		}
+		kept = append(kept, at)
+		switch age := now.Sub(at); {
+		case age < Month:
+			recent++
+		case age < 365*day:
+			weekly++
+		default:
+			monthly++
+		}
+	}
+	// all of the last month, one per week of the year, one per month of
+	// the older 35 days
+	if recent != 31 || weekly < 47 || weekly > 49 || monthly < 1 || monthly > 3 {
+		t.Errorf("kept %d recent, %d weekly, %d monthly points", recent, weekly, monthly)
+	}
+	if !dropped[0] && !dropped[1] {
+		t.Error("the oldest points are all kept")
+	}
+
+	if again := Compact(kept, now, DefaultRetention); len(again) != 0 {
+		t.Errorf("Compact() of a compacted series = %v", again)
+	}
+	if got := Compact(times, now, nil); len(got) != 0 {
+		t.Errorf("Compact() without tiers = %v", got)
+	}
+}
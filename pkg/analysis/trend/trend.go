@@ -0,0 +1,56 @@
+// Package trend computes growth rates from time-series snapshots, so that
+// fast-rising projects can be told apart from plateaued ones.
+package trend
+
+import (
+	"sort"
+	"time"
+)
+
+// Month is the default window of growth rates.
+const Month = 30 * 24 * time.Hour
+
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// Growth returns the relative growth of the series per window, e.g. 0.1
+// means the value grows by 10% per window. The latest point is compared
+// with the latest point which is at least one window older, and the
+// growth is scaled to one window if the points are further apart.
+//
+// ok is false if there are not enough points or the base value is zero.
+func Growth(series []Point, window time.Duration) (growth float64, ok bool) {
+	if len(series) < 2 || window <= 0 {
+		return 0, false
+	}
+
+	sorted := make([]Point, len(series))
+	copy(sorted, series)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time.Before(sorted[j].Time)
+	})
+
+	latest := sorted[len(sorted)-1]
+	baseIdx := -1
+	for i := len(sorted) - 2; i >= 0; i-- {
+		if latest.Time.Sub(sorted[i].Time) >= window {
+			baseIdx = i
+			break
+		}
+	}
+	if baseIdx < 0 {
+		return 0, false
+	}
+
+	base := sorted[baseIdx]
+	if base.Value == 0 {
+		return 0, false
+	}
+
+	gap := latest.Time.Sub(base.Time)
+	growth = (latest.Value - base.Value) / base.Value
+	growth *= float64(window) / float64(gap)
+	return growth, true
+}
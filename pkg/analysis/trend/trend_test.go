@@ -0,0 +1,42 @@
+package trend
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestGrowth(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(days int, v float64) Point {
+		return Point{Time: t0.Add(time.Duration(days) * 24 * time.Hour), Value: v}
+	}
+
+	tests := []struct {
+		name   string
+		series []Point
+		want   float64
+		wantOk bool
+	}{
+		{"empty", nil, 0, false},
+		{"single", []Point{at(0, 10)}, 0, false},
+		{"too close", []Point{at(0, 10), at(10, 20)}, 0, false},
+		{"zero base", []Point{at(0, 0), at(30, 20)}, 0, false},
+		{"one month", []Point{at(0, 100), at(30, 120)}, 0.2, true},
+		{"two months scaled", []Point{at(0, 100), at(60, 140)}, 0.2, true},
+		{"latest base is used", []Point{at(0, 50), at(30, 100), at(45, 105), at(60, 110)}, 0.1, true},
+		{"unsorted", []Point{at(30, 90), at(0, 100)}, -0.1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Growth(tt.series, Month)
+			if ok != tt.wantOk {
+				t.Fatalf("Growth() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Growth() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
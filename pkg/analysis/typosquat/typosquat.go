@@ -0,0 +1,150 @@
+// Package typosquat finds packages whose names are suspiciously close to
+// the names of top-ranked packages in the same ecosystem, while being far
+// less critical themselves. Such packages are candidates of typosquatting
+// or dependency confusion, and should be reviewed by security teams.
+package typosquat
+
+import (
+	"sort"
+	"strings"
+)
+
+type Package struct {
+	Name        string
+	Criticality float64
+}
+
+type Config struct {
+	// TopN is the number of top-ranked packages used as targets
+	TopN int
+	// MaxDistance is the max edit distance between a candidate and its target
+	MaxDistance int
+	// MinRatio is the min ratio of target criticality to candidate criticality
+	MinRatio float64
+	// MinLength is the min length of target names, short names are too noisy
+	MinLength int
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		TopN:        1000,
+		MaxDistance: 2,
+		MinRatio:    100,
+		MinLength:   4,
+	}
+}
+
+type Candidate struct {
+	Name              string
+	Criticality       float64
+	Target            string
+	TargetCriticality float64
+	Distance          int
+}
+
+// normalize makes names differing only in case or separators equal,
+// e.g. python-dateutil and Python_DateUtil.
+func normalize(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '-', '_', '.':
+			return '-'
+		}
+		return r
+	}, strings.ToLower(name))
+}
+
+// Distance returns the optimal string alignment distance between a and b,
+// that is the Levenshtein distance with adjacent transpositions counted as
+// a single edit.
+func Distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	la, lb := len(ra), len(rb)
+
+	// d[i][j] is the distance between ra[:i] and rb[:j]
+	d := make([][]int, la+1)
+	for i := range d {
+		d[i] = make([]int, lb+1)
+		d[i][0] = i
+	}
+	for j := 0; j <= lb; j++ {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= la; i++ {
+		for j := 1; j <= lb; j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[la][lb]
+}
+
+// Detect returns typosquat candidates of the given packages, sorted by
+// target name and then candidate name.
+func Detect(packages []Package, config *Config) []Candidate {
+	if config == nil {
+		config = DefaultConfig()
+	}
+
+	sorted := make([]Package, len(packages))
+	copy(sorted, packages)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Criticality > sorted[j].Criticality
+	})
+
+	targets := sorted
+	if config.TopN > 0 && len(targets) > config.TopN {
+		targets = targets[:config.TopN]
+	}
+
+	ret := make([]Candidate, 0)
+	for _, target := range targets {
+		if target.Criticality <= 0 || len([]rune(target.Name)) < config.MinLength {
+			continue
+		}
+		targetName := normalize(target.Name)
+
+		for _, pkg := range sorted {
+			if pkg.Name == target.Name {
+				continue
+			}
+			if pkg.Criticality*config.MinRatio > target.Criticality {
+				continue
+			}
+
+			name := normalize(pkg.Name)
+			diff := len([]rune(name)) - len([]rune(targetName))
+			if diff > config.MaxDistance || -diff > config.MaxDistance {
+				continue
+			}
+
+			dist := Distance(name, targetName)
+			if dist > config.MaxDistance {
+				continue
+			}
+
+			ret = append(ret, Candidate{
+				Name:              pkg.Name,
+				Criticality:       pkg.Criticality,
+				Target:            target.Name,
+				TargetCriticality: target.Criticality,
+				Distance:          dist,
+			})
+		}
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Target != ret[j].Target {
+			return ret[i].Target < ret[j].Target
+		}
+		return ret[i].Name < ret[j].Name
+	})
+	return ret
+}
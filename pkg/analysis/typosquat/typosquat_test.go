@@ -0,0 +1,53 @@
+package typosquat
+
+import (
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"requests", "requests", 0},
+		{"requests", "request", 1},
+		{"requests", "reqeusts", 1},
+		{"lodash", "1odash", 1},
+		{"kitten", "sitting", 3},
+		{"", "abc", 3},
+	}
+
+	for _, tt := range tests {
+		if got := Distance(tt.a, tt.b); got != tt.want {
+			t.Errorf("Distance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	packages := []Package{
+		{Name: "openssl", Criticality: 0.9},
+		{Name: "opensll", Criticality: 0.001},
+		{Name: "open-ssl", Criticality: 0.0005},
+		{Name: "openssh", Criticality: 0.8},
+		{Name: "zlib", Criticality: 0.7},
+		{Name: "zlibc", Criticality: 0.001},
+		{Name: "curl", Criticality: 0.6},
+	}
+
+	got := Detect(packages, &Config{TopN: 3, MaxDistance: 1, MinRatio: 100, MinLength: 4})
+
+	want := []struct{ name, target string }{
+		{"open-ssl", "openssl"},
+		{"opensll", "openssl"},
+		{"zlibc", "zlib"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Detect() = %v, want %d candidates", got, len(want))
+	}
+	for i, w := range want {
+		if got[i].Name != w.name || got[i].Target != w.target {
+			t.Errorf("Detect()[%d] = %s -> %s, want %s -> %s", i, got[i].Name, got[i].Target, w.name, w.target)
+		}
+	}
+}
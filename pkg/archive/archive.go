@@ -0,0 +1,122 @@
+// Package archive keeps versioned git bundle snapshots of critical repos, so
+// they are preserved even if the upstream disappears.
+package archive
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/bundle"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser/url"
+	"github.com/HUSTSecLab/criticality_score/pkg/objectstore"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+)
+
+// DefaultPrefix is the default prefix of the object keys of archives.
+const DefaultPrefix = "archives/"
+
+// RefsHash returns the sha256 of all refs of the repo, which changes
+// whenever a branch or tag is moved, created or deleted.
+func RefsHash(repoPath string) (string, error) {
+	out, err := exec.Command("git", "-C", repoPath, "show-ref", "--head").Output()
+	if err != nil {
+		return "", fmt.Errorf("git show-ref: %w", err)
+	}
+	sum := sha256.Sum256(out)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Key returns the object key of the archive of a repo taken at the time, e.g.
+// archives/github.com/owner/repo/20250114T000000Z.bundle.
+func Key(prefix string, u *url.RepoURL, at time.Time) string {
+	repo := strings.TrimSuffix(bundle.Key(u), bundle.Extension)
+	return prefix + path.Join(repo, at.UTC().Format("20060102T150405Z")) + bundle.Extension
+}
+
+// fileSHA256 returns the sha256 and the size of a file.
+func fileSHA256(name string) (string, int64, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, file)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// Archiver uploads bundles of repos into a store and records them.
+type Archiver struct {
+	Store objectstore.Store
+	Repo  repository.RepoArchiveRepository
+	// Prefix is prepended to the object keys of archives
+	Prefix string
+}
+
+// Archive creates an archive of the repo cloned at repoPath. If the refs of
+// the repo did not change since the latest archive, no archive is created
+// and the latest archive is returned with created set to false.
+func (a *Archiver) Archive(u *url.RepoURL, repoPath string, now time.Time) (archive *repository.RepoArchive, created bool, err error) {
+	refsHash, err := RefsHash(repoPath)
+	if err != nil {
+		return nil, false, err
+	}
+	latest, err := a.Repo.QueryLatest(u.URL)
+	if err != nil {
+		return nil, false, err
+	}
+	if latest != nil && latest.RefsHash != nil && *latest.RefsHash == refsHash {
+		return latest, false, nil
+	}
+
+	tmp, err := os.CreateTemp("", "archive-*"+bundle.Extension)
+	if err != nil {
+		return nil, false, err
+	}
+	tmp.Close()
+	// git refuses to write into an existing file
+	os.Remove(tmp.Name())
+	defer os.Remove(tmp.Name())
+
+	if err := bundle.Create(repoPath, tmp.Name()); err != nil {
+		return nil, false, err
+	}
+	sum, size, err := fileSHA256(tmp.Name())
+	if err != nil {
+		return nil, false, err
+	}
+
+	file, err := os.Open(tmp.Name())
+	if err != nil {
+		return nil, false, err
+	}
+	defer file.Close()
+	key := Key(a.Prefix, u, now)
+	if err := a.Store.Put(key, file, size); err != nil {
+		return nil, false, err
+	}
+
+	link, location := u.URL, a.Store.URI(key)
+	archive = &repository.RepoArchive{
+		GitLink:    &link,
+		ArchivedAt: &now,
+		RefsHash:   &refsHash,
+		Sha256:     &sum,
+		Size:       &size,
+		Location:   &location,
+	}
+	if err := a.Repo.Insert(archive); err != nil {
+		return nil, false, err
+	}
+	return archive, true, nil
+}
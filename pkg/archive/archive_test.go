@@ -0,0 +1,117 @@
+package archive
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"iter"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser/url"
+	"github.com/HUSTSecLab/criticality_score/pkg/objectstore"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+)
+
+type memoryRepo struct {
+	archives []*repository.RepoArchive
+}
+
+func (m *memoryRepo) QueryByLink(link string) (iter.Seq[*repository.RepoArchive], error) {
+	return func(yield func(*repository.RepoArchive) bool) {
+		for _, a := range m.archives {
+			if *a.GitLink == link && !yield(a) {
+				return
+			}
+		}
+	}, nil
+}
+
+func (m *memoryRepo) QueryLatest(link string) (*repository.RepoArchive, error) {
+	var latest *repository.RepoArchive
+	for _, a := range m.archives {
+		if *a.GitLink == link {
+			latest = a
+		}
+	}
+	return latest, nil
+}
+
+func (m *memoryRepo) Insert(data *repository.RepoArchive) error {
+	m.archives = append(m.archives, data)
+	return nil
+}
+
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v: %s", args, err, out)
+	}
+}
+
+func TestKey(t *testing.T) {
+	u := url.ParseURL("https://github.com/owner/repo.git")
+	got := Key(DefaultPrefix, &u, time.Date(2025, 1, 14, 8, 30, 0, 0, time.UTC))
+	if want := "archives/github.com/owner/repo/20250114T083000Z.bundle"; got != want {
+		t.Errorf("Key() = %q, want %q", got, want)
+	}
+}
+
+func TestArchive(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	repoPath := t.TempDir()
+	git(t, repoPath, "init", "--quiet")
+	git(t, repoPath, "commit", "--quiet", "--allow-empty", "-m", "first")
+
+	store := &objectstore.DirStore{Dir: t.TempDir()}
+	repo := &memoryRepo{}
+	archiver := &Archiver{Store: store, Repo: repo, Prefix: DefaultPrefix}
+	u := url.ParseURL("https://github.com/owner/repo.git")
+	now := time.Date(2025, 1, 14, 0, 0, 0, 0, time.UTC)
+
+	first, created, err := archiver.Archive(&u, repoPath, now)
+	if err != nil {
+		t.Fatalf("Archive() error = %v", err)
+	}
+	if !created {
+		t.Fatal("Archive() did not create the first archive")
+	}
+	data, err := os.ReadFile(*first.Location)
+	if err != nil {
+		t.Fatalf("archive is not stored: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "# v2 git bundle") && !strings.HasPrefix(string(data), "# v3 git bundle") {
+		t.Errorf("archive is not a git bundle")
+	}
+	sum := sha256.Sum256(data)
+	if *first.Sha256 != hex.EncodeToString(sum[:]) || *first.Size != int64(len(data)) {
+		t.Errorf("Archive() sha256 = %s, size = %d, mismatch the stored bundle", *first.Sha256, *first.Size)
+	}
+
+	// unchanged refs reuse the latest archive
+	again, created, err := archiver.Archive(&u, repoPath, now.Add(time.Hour))
+	if err != nil || created || again != first {
+		t.Fatalf("Archive() of unchanged repo = %v, %v, %v", again, created, err)
+	}
+
+	git(t, repoPath, "commit", "--quiet", "--allow-empty", "-m", "second")
+	second, created, err := archiver.Archive(&u, repoPath, now.Add(2*time.Hour))
+	if err != nil || !created {
+		t.Fatalf("Archive() of changed repo = %v, %v, %v", second, created, err)
+	}
+	if *second.Location == *first.Location || *second.RefsHash == *first.RefsHash {
+		t.Errorf("Archive() did not create a new version")
+	}
+	if len(repo.archives) != 2 {
+		t.Errorf("recorded %d archives, want 2", len(repo.archives))
+	}
+}
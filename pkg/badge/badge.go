@@ -0,0 +1,80 @@
+// Package badge renders flat SVG badges in the style of shields.io, e.g.
+// the criticality score of a project to embed in its README.
+package badge
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+)
+
+// Colors of shields.io.
+const (
+	ColorRed       = "#e05d44"
+	ColorOrange    = "#fe7d37"
+	ColorYellow    = "#dfb317"
+	ColorGreen     = "#97ca00"
+	ColorBlue      = "#007ec6"
+	ColorLightGrey = "#9f9f9f"
+	labelColor     = "#555"
+)
+
+// Badge is a badge of a label and a message, e.g. criticality | 0.72.
+type Badge struct {
+	Label   string
+	Message string
+	// Color is the background of the message, e.g. ColorRed
+	Color string
+}
+
+// charWidths are the widths of the narrow and wide ascii characters in
+// Verdana 11px, the font of shields.io, other characters are 7px.
+var charWidths = map[rune]float64{
+	' ': 3.9, '.': 4.4, ',': 4.4, ':': 4.7, ';': 4.7, '|': 5, '!': 4.7, '\'': 3.4,
+	'i': 3.1, 'j': 3.4, 'l': 3.1, 'f': 3.9, 't': 4.4, 'r': 4.7, 'I': 4.6,
+	'(': 5, ')': 5, '[': 5, ']': 5, '-': 5, '/': 5,
+	'm': 10.7, 'w': 9, 'M': 9.9, 'W': 11, '%': 12, '#': 9.2, '@': 11,
+}
+
+// textWidth estimates the width of s in pixels.
+func textWidth(s string) float64 {
+	var w float64
+	for _, r := range s {
+		if cw, ok := charWidths[r]; ok {
+			w += cw
+		} else {
+			w += 7
+		}
+	}
+	return w
+}
+
+// SVG renders the badge.
+func (b Badge) SVG() []byte {
+	const padding = 10
+	lw := int(textWidth(b.Label)+0.5) + padding
+	mw := int(textWidth(b.Message)+0.5) + padding
+	width := lw + mw
+	color := b.Color
+	if color == "" {
+		color = ColorLightGrey
+	}
+	label, message := html.EscapeString(b.Label), html.EscapeString(b.Message)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s: %s">`, width, label, message)
+	fmt.Fprintf(&buf, `<title>%s: %s</title>`, label, message)
+	buf.WriteString(`<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`)
+	fmt.Fprintf(&buf, `<clipPath id="r"><rect width="%d" height="20" rx="3" fill="#fff"/></clipPath>`, width)
+	fmt.Fprintf(&buf, `<g clip-path="url(#r)"><rect width="%d" height="20" fill="%s"/><rect x="%d" width="%d" height="20" fill="%s"/><rect width="%d" height="20" fill="url(#s)"/></g>`,
+		lw, labelColor, lw, mw, html.EscapeString(color), width)
+	buf.WriteString(`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`)
+	for _, t := range []struct {
+		x    float64
+		text string
+	}{{float64(lw) / 2, label}, {float64(lw) + float64(mw)/2, message}} {
+		fmt.Fprintf(&buf, `<text x="%.1f" y="15" fill="#010101" fill-opacity=".3">%s</text><text x="%.1f" y="14">%s</text>`, t.x, t.text, t.x, t.text)
+	}
+	buf.WriteString(`</g></svg>`)
+	return buf.Bytes()
+}
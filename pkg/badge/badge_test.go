@@ -0,0 +1,39 @@
+package badge
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSVG(t *testing.T) {
+	b := Badge{Label: "criticality", Message: "0.72 | #12 <top 1%>", Color: ColorRed}
+	svg := string(b.SVG())
+
+	// the svg is well-formed xml with the text escaped
+	dec := xml.NewDecoder(strings.NewReader(svg))
+	for {
+		if _, err := dec.Token(); err != nil {
+			if err != io.EOF {
+				t.Fatalf("invalid svg: %v\n%s", err, svg)
+			}
+			break
+		}
+	}
+	for _, want := range []string{`<title>criticality: 0.72 | #12 &lt;top 1%&gt;</title>`, `fill="` + ColorRed + `"`} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("svg does not contain %s:\n%s", want, svg)
+		}
+	}
+
+	// longer messages are wider
+	short := Badge{Label: "criticality", Message: "1"}.SVG()
+	long := Badge{Label: "criticality", Message: "not ranked"}.SVG()
+	if !strings.Contains(string(short), `width="`) || textWidth("not ranked") <= textWidth("1") {
+		t.Errorf("widths of %q and %q", short, long)
+	}
+	if !strings.Contains(string(short), ColorLightGrey) {
+		t.Errorf("badge without color is not grey: %s", short)
+	}
+}
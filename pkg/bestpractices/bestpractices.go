@@ -0,0 +1,140 @@
+// Package bestpractices looks up the OpenSSF Best Practices badges (formerly
+// CII Best Practices) of repos at bestpractices.dev, another hygiene signal
+// next to the supply-chain signals.
+//
+// A project registers its homepage and repo url, and earns the passing,
+// silver or gold badge by answering the criteria of the level. Projects
+// which have not met the passing criteria yet are in progress.
+package bestpractices
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/gitutil/normalize"
+)
+
+const DefaultBaseURL = "https://www.bestpractices.dev"
+
+// Level is the badge level of a project.
+type Level string
+
+const (
+	// LevelNone is the level of repos without a project
+	LevelNone       Level = "none"
+	LevelInProgress Level = "in_progress"
+	LevelPassing    Level = "passing"
+	LevelSilver     Level = "silver"
+	LevelGold       Level = "gold"
+)
+
+// levels are the levels in ascending order.
+var levels = []Level{LevelNone, LevelInProgress, LevelPassing, LevelSilver, LevelGold}
+
+// Rank returns the rank of the level, 0 for LevelNone and unknown levels up
+// to 4 for LevelGold.
+func (l Level) Rank() int {
+	for i, level := range levels {
+		if level == l {
+			return i
+		}
+	}
+	return 0
+}
+
+// Project is a project registered at bestpractices.dev.
+type Project struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	HomepageURL string `json:"homepage_url"`
+	RepoURL     string `json:"repo_url"`
+	BadgeLevel  Level  `json:"badge_level"`
+}
+
+// Client looks up the badges by the API of bestpractices.dev.
+type Client struct {
+	client  *http.Client
+	baseURL string
+}
+
+// New returns a client sending requests by client, http.DefaultClient if
+// nil, to baseURL, DefaultBaseURL if empty.
+func New(client *http.Client, baseURL string) *Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{client: client, baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// Lookup returns the project of the repo of link, nil if the repo has no
+// project.
+//
+// The url search of the API matches the homepage and repo urls by prefix,
+// so the results are narrowed down to the project whose repo url is the
+// same repo as link, see normalize.Key. If several projects claim the repo,
+// the one with the highest badge wins.
+func (c *Client) Lookup(ctx context.Context, link string) (*Project, error) {
+	key, err := normalize.Key(link)
+	if err != nil {
+		return nil, err
+	}
+	repoURL, err := normalize.URL(link)
+	if err != nil {
+		return nil, err
+	}
+
+	u := c.baseURL + "/projects.json?" + url.Values{"url": {repoURL}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", u, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", u, resp.Status)
+	}
+	var projects []Project
+	if err := json.Unmarshal(body, &projects); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", u, err)
+	}
+
+	var ret *Project
+	for i, p := range projects {
+		if k, err := normalize.Key(p.RepoURL); err != nil || k != key {
+			continue
+		}
+		if ret == nil || p.BadgeLevel.Rank() > ret.BadgeLevel.Rank() {
+			ret = &projects[i]
+		}
+	}
+	return ret, nil
+}
+
+// Badge returns the badge level of the repo of link, LevelNone if it has no
+// project.
+func (c *Client) Badge(ctx context.Context, link string) (Level, error) {
+	p, err := c.Lookup(ctx, link)
+	if err != nil {
+		return "", err
+	}
+	if p == nil {
+		return LevelNone, nil
+	}
+	return p.BadgeLevel, nil
+}
@@ -0,0 +1,70 @@
+package bestpractices
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestClient(t *testing.T) *Client {
+	t.Helper()
+	projects, err := os.ReadFile(filepath.Join("testdata", "projects.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("url") {
+		case "https://github.com/curl/curl":
+			w.Write(projects)
+		case "https://github.com/broken/broken":
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		default:
+			w.Write([]byte("[]"))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return New(server.Client(), server.URL)
+}
+
+func TestBadge(t *testing.T) {
+	c := newTestClient(t)
+	tests := []struct {
+		link string
+		want Level
+	}{
+		// the prefix match of curl-fork is left out, and gold beats the
+		// in progress duplicate
+		{"git@github.com:curl/curl.git", LevelGold},
+		{"https://github.com/foo/bar", LevelNone},
+	}
+	for _, tt := range tests {
+		if got, err := c.Badge(context.Background(), tt.link); err != nil || got != tt.want {
+			t.Errorf("Badge(%q) = %q, %v, want %q", tt.link, got, err, tt.want)
+		}
+	}
+
+	p, err := c.Lookup(context.Background(), "https://github.com/curl/curl")
+	if err != nil || p == nil || p.ID != 63 {
+		t.Errorf("Lookup() = %+v, %v, want project 63", p, err)
+	}
+
+	if _, err := c.Badge(context.Background(), "https://github.com/broken/broken"); err == nil {
+		t.Error("Badge() of a server error succeeded")
+	}
+	if _, err := c.Badge(context.Background(), "not a url"); err == nil {
+		t.Error("Badge() of an invalid link succeeded")
+	}
+}
+
+func TestLevelRank(t *testing.T) {
+	if !(LevelNone.Rank() < LevelInProgress.Rank() && LevelInProgress.Rank() < LevelPassing.Rank() &&
+		LevelPassing.Rank() < LevelSilver.Rank() && LevelSilver.Rank() < LevelGold.Rank()) {
+		t.Error("levels are not ranked in ascending order")
+	}
+	if Level("platinum").Rank() != 0 {
+		t.Error("unknown level is ranked")
+	}
+}
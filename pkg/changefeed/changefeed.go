@@ -0,0 +1,102 @@
+// Package changefeed follows the change feeds of package registries, e.g.
+// the _changes feed of npm, the commits of the crates.io index and the Go
+// module index, so the dependents of changed packages can be refreshed in
+// near real time instead of by periodic full passes.
+//
+// Every feed is read from a cursor, which is returned by the previous poll
+// and stored by the caller, so a restarted service resumes where it
+// stopped. An empty cursor starts from now.
+package changefeed
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Change is a changed package, by the system and the name of deps.dev.
+type Change struct {
+	// System is the deps.dev system, e.g. NPM
+	System string
+	Name   string
+}
+
+// Feed is the change feed of a registry.
+type Feed interface {
+	// Name identifies the feed, e.g. npm, the key of its cursor
+	Name() string
+	// Poll returns the changes after cursor and the cursor of the next
+	// poll, which is cursor if nothing changed.
+	Poll(ctx context.Context, cursor string) ([]Change, string, error)
+}
+
+// Names are the names of the feeds in New.
+var Names = []string{NpmName, CratesName, GoName}
+
+// New returns the feed of name sending requests by client,
+// http.DefaultClient if nil. token authenticates the requests to GitHub, it
+// is only used by the crates feed.
+func New(name string, client *http.Client, token string) (Feed, error) {
+	switch name {
+	case NpmName:
+		return NewNpm(client, ""), nil
+	case CratesName:
+		return NewCrates(client, "", token), nil
+	case GoName:
+		return NewGo(client, ""), nil
+	}
+	return nil, fmt.Errorf("unknown change feed %q, expected one of %s", name, strings.Join(Names, ", "))
+}
+
+// Dedup returns the changes without duplicates, in the order of their
+// first change.
+func Dedup(changes []Change) []Change {
+	seen := make(map[Change]bool, len(changes))
+	ret := make([]Change, 0, len(changes))
+	for _, c := range changes {
+		if !seen[c] {
+			seen[c] = true
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
+// get sends a GET request and returns the body of a 200 response.
+func get(ctx context.Context, client *http.Client, u string, header http.Header) ([]byte, error) {
+	body, _, err := getWithHeader(ctx, client, u, header)
+	return body, err
+}
+
+// getWithHeader is get returning the header of the response as well.
+func getWithHeader(ctx context.Context, client *http.Client, u string, header http.Header) ([]byte, http.Header, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("GET %s: %s", u, resp.Status)
+	}
+	return body, resp.Header, nil
+}
+
+func defaultClient(client *http.Client) *http.Client {
+	if client == nil {
+		return http.DefaultClient
+	}
+	return client
+}
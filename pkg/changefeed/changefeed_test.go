@@ -0,0 +1,122 @@
+package changefeed
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func newTestServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestNpm(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_changes" || r.URL.Query().Get("since") != "now" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Write([]byte(`{"results":[{"seq":41,"id":"react"},{"seq":42,"id":"_design/app"},{"seq":43,"id":"@babel/core"}],"last_seq":43}`))
+	})
+	changes, next, err := NewNpm(server.Client(), server.URL).Poll(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Change{{"NPM", "react"}, {"NPM", "@babel/core"}}
+	if !reflect.DeepEqual(changes, want) || next != "43" {
+		t.Errorf("Poll() = %v, %q, want %v, 43", changes, next, want)
+	}
+}
+
+func TestGo(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("since") != "2025-01-30T00:00:00Z" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Write([]byte(`{"Path":"golang.org/x/net","Version":"v0.34.0","Timestamp":"2025-01-30T00:00:01.5Z"}
+{"Path":"github.com/spf13/pflag","Version":"v1.0.6","Timestamp":"2025-01-30T00:00:02Z"}
+`))
+	})
+	changes, next, err := NewGo(server.Client(), server.URL).Poll(context.Background(), "2025-01-30T00:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Change{{"GO", "golang.org/x/net"}, {"GO", "github.com/spf13/pflag"}}
+	if !reflect.DeepEqual(changes, want) || next != "2025-01-30T00:00:02Z" {
+		t.Errorf("Poll() = %v, %q, want %v", changes, next, want)
+	}
+}
+
+func TestCrates(t *testing.T) {
+	var server *httptest.Server
+	server = newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		// since is inclusive, the commit of the last poll is listed again
+		if r.URL.Path != "/repos/rust-lang/crates.io-index/commits" || r.URL.Query().Get("since") != "2025-01-30T00:00:00Z" ||
+			r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		if r.URL.Query().Get("page") == "2" {
+			// merge is listed again as a commit was pushed between the pages
+			w.Write([]byte(`[
+				{"sha":"merge","commit":{"message":"Merge branch","committer":{"date":"2025-01-30T00:01:30Z"}}},
+				{"sha":"serde","commit":{"message":"Update crate ` + "`serde#1.0.197`" + `","committer":{"date":"2025-01-30T00:01:00Z"}}},
+				{"sha":"last","commit":{"message":"Update crate ` + "`rand#0.9.0`" + `","committer":{"date":"2025-01-30T00:00:00Z"}}}
+			]`))
+			return
+		}
+		w.Header().Set("Link", `<`+server.URL+r.URL.Path+"?"+r.URL.RawQuery+`&page=2>; rel="next", <`+server.URL+`/last>; rel="last"`)
+		w.Write([]byte(`[
+			{"sha":"tokio","commit":{"message":"Yank crate ` + "`tokio#1.0.0`" + `","committer":{"date":"2025-01-30T00:02:00Z"}}},
+			{"sha":"log","commit":{"message":"Update crate ` + "`log#0.4.25`" + `","committer":{"date":"2025-01-30T00:02:00Z"}}},
+			{"sha":"merge","commit":{"message":"Merge branch","committer":{"date":"2025-01-30T00:01:30Z"}}}
+		]`))
+	})
+	feed := NewCrates(server.Client(), server.URL, "token")
+	changes, next, err := feed.Poll(context.Background(), "2025-01-30T00:00:00Z last")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Change{{"CARGO", "serde"}, {"CARGO", "log"}, {"CARGO", "tokio"}}
+	if !reflect.DeepEqual(changes, want) || next != "2025-01-30T00:02:00Z log,tokio" {
+		t.Errorf("Poll() = %v, %q, want %v", changes, next, want)
+	}
+
+	// a cursor without shas, e.g. stored before they were added
+	changes, _, err = feed.Poll(context.Background(), "2025-01-30T00:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Change{{"CARGO", "rand"}, {"CARGO", "serde"}, {"CARGO", "log"}, {"CARGO", "tokio"}}; !reflect.DeepEqual(changes, want) {
+		t.Errorf("Poll() = %v, want %v", changes, want)
+	}
+}
+
+func TestNextLink(t *testing.T) {
+	tests := map[string]string{
+		"": "",
+		`<https://api.github.com/commits?page=2>; rel="next", <https://api.github.com/commits?page=5>; rel="last"`:  "https://api.github.com/commits?page=2",
+		`<https://api.github.com/commits?page=1>; rel="first", <https://api.github.com/commits?page=1>; rel="prev"`: "",
+	}
+	for header, want := range tests {
+		if got := nextLink(header); got != want {
+			t.Errorf("nextLink(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestPollFailure(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})
+	// the cursor is kept, so the changes are polled again
+	if _, next, err := NewNpm(server.Client(), server.URL).Poll(context.Background(), "42"); err == nil || next != "42" {
+		t.Errorf("Poll() = %q, %v, want the cursor and an error", next, err)
+	}
+	if got := Dedup([]Change{{"NPM", "a"}, {"GO", "a"}, {"NPM", "a"}}); len(got) != 2 {
+		t.Errorf("Dedup() = %v", got)
+	}
+}
@@ -0,0 +1,148 @@
+package changefeed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	CratesName = "crates"
+	// DefaultCratesBaseURL is the GitHub API serving the commits of the
+	// crates.io index
+	DefaultCratesBaseURL = "https://api.github.com"
+	cratesIndexRepo      = "rust-lang/crates.io-index"
+	// cratesPerPage is the max number of commits of a poll
+	cratesPerPage = 100
+)
+
+// crateCommitPattern matches the crate of an index commit, e.g.
+// Update crate `serde#1.0.197`.
+var crateCommitPattern = regexp.MustCompile("crate `([A-Za-z0-9_-]+)(#|`)")
+
+// Crates follows the commits of the crates.io index, every publish or yank
+// of a crate is a commit. The cursor is the commit time of the last commit
+// in RFC 3339, followed by the shas of the commits at that time, e.g.
+// 2025-01-30T00:02:00Z 1a2b3c,4d5e6f. The since of GitHub is inclusive, so
+// they are polled again and skipped by their shas.
+type Crates struct {
+	client  *http.Client
+	baseURL string
+	token   string
+}
+
+// NewCrates returns the crates feed reading commits from the GitHub API at
+// baseURL, DefaultCratesBaseURL if empty, authenticated by token if not
+// empty.
+func NewCrates(client *http.Client, baseURL, token string) *Crates {
+	if baseURL == "" {
+		baseURL = DefaultCratesBaseURL
+	}
+	return &Crates{client: defaultClient(client), baseURL: strings.TrimSuffix(baseURL, "/"), token: token}
+}
+
+func (f *Crates) Name() string {
+	return CratesName
+}
+
+type crateCommit struct {
+	SHA    string `json:"sha"`
+	Commit struct {
+		Message   string `json:"message"`
+		Committer struct {
+			Date time.Time `json:"date"`
+		} `json:"committer"`
+	} `json:"commit"`
+}
+
+// Poll implements Feed.
+func (f *Crates) Poll(ctx context.Context, cursor string) ([]Change, string, error) {
+	if cursor == "" {
+		cursor = time.Now().UTC().Format(time.RFC3339)
+	}
+	at, shas, _ := strings.Cut(cursor, " ")
+	since, err := time.Parse(time.RFC3339, at)
+	if err != nil {
+		return nil, cursor, fmt.Errorf("invalid crates cursor %q: %w", cursor, err)
+	}
+	seen := make(map[string]bool)
+	for _, sha := range strings.Split(shas, ",") {
+		if sha != "" {
+			seen[sha] = true
+		}
+	}
+
+	q := url.Values{"since": {since.Format(time.RFC3339)}, "per_page": {fmt.Sprint(cratesPerPage)}}
+	header := http.Header{"Accept": {"application/vnd.github+json"}}
+	if f.token != "" {
+		header.Set("Authorization", "Bearer "+f.token)
+	}
+	// commits are listed latest first, the pages are followed until the
+	// cursor
+	var commits []crateCommit
+	for u := f.baseURL + "/repos/" + cratesIndexRepo + "/commits?" + q.Encode(); u != ""; {
+		body, respHeader, err := getWithHeader(ctx, f.client, u, header)
+		if err != nil {
+			return nil, cursor, err
+		}
+		var page []crateCommit
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, cursor, fmt.Errorf("parsing crates.io index commits: %w", err)
+		}
+		commits = append(commits, page...)
+		if len(page) == 0 || !page[len(page)-1].Commit.Committer.Date.After(since) {
+			break
+		}
+		u = nextLink(respHeader.Get("Link"))
+	}
+	if len(commits) == 0 {
+		return nil, cursor, nil
+	}
+
+	var changes []Change
+	next := since
+	for i := len(commits) - 1; i >= 0; i-- {
+		c := commits[i]
+		if c.Commit.Committer.Date.After(next) {
+			next = c.Commit.Committer.Date
+		}
+		// commits of the last poll, or listed on two pages as commits were
+		// pushed between the pages
+		if seen[c.SHA] {
+			continue
+		}
+		seen[c.SHA] = true
+		if m := crateCommitPattern.FindStringSubmatch(c.Commit.Message); m != nil {
+			changes = append(changes, Change{System: "CARGO", Name: m[1]})
+		}
+	}
+	// the commits at next are polled again by the next poll
+	var nextSHAs []string
+	for _, c := range commits {
+		if c.Commit.Committer.Date.Equal(next) && !slices.Contains(nextSHAs, c.SHA) {
+			nextSHAs = append(nextSHAs, c.SHA)
+		}
+	}
+	sort.Strings(nextSHAs)
+	return changes, next.UTC().Format(time.RFC3339) + " " + strings.Join(nextSHAs, ","), nil
+}
+
+// nextLink returns the url of rel="next" of a Link header, empty if there
+// is no next page.
+func nextLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		u, params, ok := strings.Cut(link, ";")
+		if !ok || !strings.Contains(params, `rel="next"`) {
+			continue
+		}
+		return strings.Trim(strings.TrimSpace(u), "<>")
+	}
+	return ""
+}
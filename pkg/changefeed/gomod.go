@@ -0,0 +1,73 @@
+package changefeed
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	GoName           = "go"
+	DefaultGoBaseURL = "https://index.golang.org"
+	// goLimit is the max number of module versions of a poll
+	goLimit = 2000
+)
+
+// Go follows the Go module index, the cursor is the timestamp of the last
+// module version in RFC 3339.
+type Go struct {
+	client  *http.Client
+	baseURL string
+}
+
+// NewGo returns the feed of the Go module index at baseURL,
+// DefaultGoBaseURL if empty.
+func NewGo(client *http.Client, baseURL string) *Go {
+	if baseURL == "" {
+		baseURL = DefaultGoBaseURL
+	}
+	return &Go{client: defaultClient(client), baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+func (f *Go) Name() string {
+	return GoName
+}
+
+// Poll implements Feed.
+func (f *Go) Poll(ctx context.Context, cursor string) ([]Change, string, error) {
+	if cursor == "" {
+		cursor = time.Now().UTC().Format(time.RFC3339Nano)
+	}
+	q := url.Values{"since": {cursor}, "limit": {fmt.Sprint(goLimit)}}
+	body, err := get(ctx, f.client, f.baseURL+"/index?"+q.Encode(), nil)
+	if err != nil {
+		return nil, cursor, err
+	}
+	// the index is a json object of a module version per line
+	var changes []Change
+	next := cursor
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var v struct {
+			Path      string
+			Version   string
+			Timestamp string
+		}
+		if err := json.Unmarshal(line, &v); err != nil {
+			return nil, cursor, fmt.Errorf("parsing go module index: %w", err)
+		}
+		changes = append(changes, Change{System: "GO", Name: v.Path})
+		next = v.Timestamp
+	}
+	return changes, next, scanner.Err()
+}
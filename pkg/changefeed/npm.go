@@ -0,0 +1,73 @@
+package changefeed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	NpmName           = "npm"
+	DefaultNpmBaseURL = "https://replicate.npmjs.com"
+	// npmLimit is the max number of changes of a poll
+	npmLimit = 1000
+)
+
+// Npm follows the CouchDB _changes feed of the npm registry, the cursor is
+// the sequence of the last change.
+type Npm struct {
+	client  *http.Client
+	baseURL string
+}
+
+// NewNpm returns the npm feed at baseURL, DefaultNpmBaseURL if empty.
+func NewNpm(client *http.Client, baseURL string) *Npm {
+	if baseURL == "" {
+		baseURL = DefaultNpmBaseURL
+	}
+	return &Npm{client: defaultClient(client), baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+func (f *Npm) Name() string {
+	return NpmName
+}
+
+// Poll implements Feed.
+func (f *Npm) Poll(ctx context.Context, cursor string) ([]Change, string, error) {
+	since := cursor
+	if since == "" {
+		since = "now"
+	}
+	q := url.Values{"since": {since}, "limit": {fmt.Sprint(npmLimit)}}
+	body, err := get(ctx, f.client, f.baseURL+"/_changes?"+q.Encode(), nil)
+	if err != nil {
+		return nil, cursor, err
+	}
+	var resp struct {
+		Results []struct {
+			ID string `json:"id"`
+		} `json:"results"`
+		// sequences are numbers or strings, by the version of CouchDB
+		LastSeq json.RawMessage `json:"last_seq"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, cursor, fmt.Errorf("parsing npm changes: %w", err)
+	}
+	var changes []Change
+	for _, r := range resp.Results {
+		// design documents are not packages
+		if r.ID == "" || strings.HasPrefix(r.ID, "_design/") {
+			continue
+		}
+		changes = append(changes, Change{System: "NPM", Name: r.ID})
+	}
+	next := string(bytes.Trim(resp.LastSeq, `"`))
+	if next == "" || next == "null" {
+		next = cursor
+	}
+	return changes, next, nil
+}
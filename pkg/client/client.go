@@ -0,0 +1,309 @@
+// Package client is a client of the REST API served by apiserver, so Go
+// services can consume the criticality data without raw HTTP, e.g.
+//
+//	c := client.New(nil, &client.Config{BaseURL: "https://cs.example.org"})
+//	for p, err := range c.AllProjects(ctx, "", 0) {
+//		...
+//	}
+//
+// Errors of requests wrap ErrNotFound, ErrBadRequest, ErrRateLimited,
+// ErrServer or ErrRequest. Rate limited, server and network errors are
+// retried with a backoff, honoring Retry-After.
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/errors"
+	"github.com/HUSTSecLab/criticality_score/pkg/internal/jsonhttp"
+)
+
+const (
+	DefaultBaseURL = "http://localhost:8080"
+	// APIVersion is the version of the API, the path prefix of all routes
+	APIVersion        = "v1-alpha"
+	DefaultMaxRetries = 3
+	// DefaultPageSize is the page size of AllProjects
+	DefaultPageSize = 1000
+	// MaxTake is the largest number of projects of a request
+	MaxTake = 10000
+
+	// initialBackoff is the delay before the first retry, doubled by every
+	// retry
+	initialBackoff = time.Second
+)
+
+var (
+	// ErrNotFound is wrapped by errors of unknown distributions, leagues
+	// or dependency paths.
+	ErrNotFound = jsonhttp.ErrNotFound
+	// ErrBadRequest is wrapped by errors of invalid parameters.
+	ErrBadRequest = errors.New("bad request")
+	// ErrRateLimited is wrapped by errors of requests still rate limited
+	// after all retries.
+	ErrRateLimited = jsonhttp.ErrRateLimited
+	// ErrServer is wrapped by 5xx errors still failing after all retries.
+	ErrServer = jsonhttp.ErrServer
+	// ErrRequest is wrapped by errors of other status codes, network
+	// errors and malformed responses.
+	ErrRequest = jsonhttp.ErrRequest
+)
+
+type Config struct {
+	// BaseURL is the url of the API server without the version,
+	// DefaultBaseURL if empty
+	BaseURL string
+	// MaxRetries is the number of retries of rate limited, server and
+	// network errors
+	MaxRetries int
+}
+
+// StatusError is an error response of the API, its Message is the body of
+// the response, the API answers errors in plain text.
+type StatusError = jsonhttp.StatusError
+
+// Client is a client of the API.
+type Client struct {
+	http    *jsonhttp.Client
+	baseURL string
+}
+
+// New returns a client sending requests by client, http.DefaultClient if
+// nil.
+func New(client *http.Client, config *Config) *Client {
+	c := &Client{
+		http: jsonhttp.New(client, jsonhttp.Config{
+			MaxRetries:     config.MaxRetries,
+			InitialBackoff: initialBackoff,
+			Check:          check,
+		}),
+		baseURL: strings.TrimSuffix(config.BaseURL, "/"),
+	}
+	if c.baseURL == "" {
+		c.baseURL = DefaultBaseURL
+	}
+	return c
+}
+
+// URL returns the url of path under the API version with the query.
+func (c *Client) URL(path string, query url.Values) string {
+	ret := c.baseURL + "/" + APIVersion + "/" + strings.TrimPrefix(path, "/")
+	if len(query) > 0 {
+		ret += "?" + query.Encode()
+	}
+	return ret
+}
+
+// ProjectsOptions selects a page of Projects.
+type ProjectsOptions struct {
+	// Start is the offset of the first project
+	Start int
+	// Take is the number of projects, 100 if 0, at most MaxTake
+	Take int
+	// Cursor is the Next of the previous page, it can not be combined
+	// with Start
+	Cursor string
+	// Sort is the sort field, descending with a "-" prefix, "-score" if
+	// empty
+	Sort string
+	// Tag selects the projects with the tag, all projects if empty
+	Tag string
+}
+
+// Projects returns a page of the scored projects, by descending score by
+// default.
+func (c *Client) Projects(ctx context.Context, opts ProjectsOptions) (*ProjectPage, error) {
+	query := url.Values{}
+	if opts.Start > 0 {
+		query.Set("start", strconv.Itoa(opts.Start))
+	}
+	if opts.Take > 0 {
+		query.Set("take", strconv.Itoa(opts.Take))
+	}
+	if opts.Cursor != "" {
+		query.Set("cursor", opts.Cursor)
+	}
+	if opts.Sort != "" {
+		query.Set("sort", opts.Sort)
+	}
+	if opts.Tag != "" {
+		query.Set("tag", opts.Tag)
+	}
+	var ret ProjectPage
+	return &ret, c.get(ctx, "metrics", query, &ret)
+}
+
+// AllProjects iterates over all scored projects by descending score,
+// fetching pages of pageSize projects, DefaultPageSize if 0. The iteration
+// stops after the first error.
+//
+// The pages follow the cursors of the server, which continue after the
+// last project of the previous page, so the pages do not shift if
+// projects are added meanwhile. Servers without cursors are paged by
+// offset.
+func (c *Client) AllProjects(ctx context.Context, tag string, pageSize int) iter.Seq2[*Project, error] {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	pageSize = min(pageSize, MaxTake)
+	return func(yield func(*Project, error) bool) {
+		opts := ProjectsOptions{Take: pageSize, Tag: tag}
+		for start := 0; ; {
+			page, err := c.Projects(ctx, opts)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			for i := range page.Data {
+				if !yield(&page.Data[i], nil) {
+					return
+				}
+			}
+			start += len(page.Data)
+			if page.Next != "" {
+				opts.Cursor = page.Next
+				continue
+			}
+			if len(page.Data) < pageSize || start >= page.Total {
+				return
+			}
+			opts.Start = start
+		}
+	}
+}
+
+// EcosystemTop returns the top projects of an ecosystem, e.g. npm, take is
+// 100 if 0.
+func (c *Client) EcosystemTop(ctx context.Context, ecosystem string, take int) ([]TopProject, error) {
+	var ret []TopProject
+	return ret, c.get(ctx, "ecosystems/"+url.PathEscape(ecosystem)+"/top", takeQuery(take), &ret)
+}
+
+// Orgs returns the top organizations by the sum of the scores of their
+// projects, take is 100 if 0.
+func (c *Client) Orgs(ctx context.Context, take int) ([]OrgScore, error) {
+	var ret []OrgScore
+	return ret, c.get(ctx, "orgs", takeQuery(take), &ret)
+}
+
+// DistroSummaries returns the summaries of all distributions.
+func (c *Client) DistroSummaries(ctx context.Context) ([]DistroSummary, error) {
+	var ret []DistroSummary
+	return ret, c.get(ctx, "dist/summaries", nil, &ret)
+}
+
+// Coverage returns the coverage of all distributions and language
+// ecosystems.
+func (c *Client) Coverage(ctx context.Context) ([]CoverageStat, error) {
+	var ret []CoverageStat
+	return ret, c.get(ctx, "coverage", nil, &ret)
+}
+
+// Channels returns the channels distributing the project of link. The
+// error wraps ErrNotFound if no channel distributes it.
+func (c *Client) Channels(ctx context.Context, link string) (*DistributionChannels, error) {
+	var ret DistributionChannels
+	return &ret, c.get(ctx, "channels", url.Values{"link": {link}}, &ret)
+}
+
+// Popularity returns the time series of the stars and forks of the project
+// of link. The error wraps ErrNotFound if it has no snapshot.
+func (c *Client) Popularity(ctx context.Context, link string) (*Popularity, error) {
+	var ret Popularity
+	return &ret, c.get(ctx, "popularity", url.Values{"link": {link}}, &ret)
+}
+
+// Labels returns the current country and affiliation labels of the
+// project of link. The error wraps ErrNotFound if it has no label.
+func (c *Client) Labels(ctx context.Context, link string) (*ProjectLabels, error) {
+	var ret ProjectLabels
+	return &ret, c.get(ctx, "labels", url.Values{"link": {link}}, &ret)
+}
+
+// Signals returns the signals of the project of link collected by the
+// pluggable signal collectors. The error wraps ErrNotFound if it has none.
+func (c *Client) Signals(ctx context.Context, link string) (*SignalValues, error) {
+	var ret SignalValues
+	return &ret, c.get(ctx, "signals", url.Values{"link": {link}}, &ret)
+}
+
+// League returns the top projects of a league, kind is language, distro,
+// distro-only or tag, take is 100 if 0.
+func (c *Client) League(ctx context.Context, kind, name string, take int) (*League, error) {
+	var ret League
+	path := "leagues/" + url.PathEscape(kind) + "/" + url.PathEscape(name)
+	return &ret, c.get(ctx, path, takeQuery(take), &ret)
+}
+
+// Tags returns all tags with the number of their projects.
+func (c *Client) Tags(ctx context.Context) ([]Tag, error) {
+	var ret []Tag
+	return ret, c.get(ctx, "tags", nil, &ret)
+}
+
+// Dependents returns the transitive reverse dependencies of a package of a
+// distribution, e.g. debian, maxDepth is 16 if 0.
+func (c *Client) Dependents(ctx context.Context, dist, pkg string, maxDepth int) (*Dependents, error) {
+	query := url.Values{"package": {pkg}}
+	if maxDepth > 0 {
+		query.Set("maxDepth", strconv.Itoa(maxDepth))
+	}
+	var ret Dependents
+	return &ret, c.get(ctx, "dist/"+url.PathEscape(dist)+"/dependents", query, &ret)
+}
+
+// DependencyPath returns the shortest dependency path from a package to
+// another of a distribution, maxDepth is 16 if 0. The error wraps
+// ErrNotFound if there is no path.
+func (c *Client) DependencyPath(ctx context.Context, dist, from, to string, maxDepth int) ([]string, error) {
+	query := url.Values{"from": {from}, "to": {to}}
+	if maxDepth > 0 {
+		query.Set("maxDepth", strconv.Itoa(maxDepth))
+	}
+	var ret DependencyPath
+	return ret.Path, c.get(ctx, "dist/"+url.PathEscape(dist)+"/path", query, &ret)
+}
+
+// Package returns a package of a distribution, with its description
+// translated to lang, e.g. zh, or the language of the server if empty. The
+// error wraps ErrNotFound if the package is unknown.
+func (c *Client) Package(ctx context.Context, dist, pkg, lang string) (*Package, error) {
+	query := url.Values{"package": {pkg}}
+	if lang != "" {
+		query.Set("lang", lang)
+	}
+	var ret Package
+	return &ret, c.get(ctx, "dist/"+url.PathEscape(dist)+"/package", query, &ret)
+}
+
+func takeQuery(take int) url.Values {
+	if take <= 0 {
+		return nil
+	}
+	return url.Values{"take": {strconv.Itoa(take)}}
+}
+
+// get decodes the response of path into v.
+func (c *Client) get(ctx context.Context, path string, query url.Values, v any) error {
+	return c.http.GetJSON(ctx, c.URL(path, query), v)
+}
+
+// check returns the error of a response which is not 200.
+func check(u string, resp *http.Response, body []byte) error {
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	err := jsonhttp.StatusErr(resp.StatusCode)
+	if resp.StatusCode == http.StatusBadRequest {
+		err = ErrBadRequest
+	}
+	se := jsonhttp.NewStatusError(u, resp, err)
+	se.Message = strings.TrimSpace(string(body))
+	return se
+}
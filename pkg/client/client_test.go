@@ -0,0 +1,158 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() + "?" + r.URL.RawQuery {
+		case "/v1-alpha/ecosystems/npm/top?take=2":
+			fmt.Fprint(w, `[{"rank":1,"link":"https://github.com/facebook/react","score":0.9},{"rank":2,"link":"https://github.com/lodash/lodash","score":null}]`)
+		case "/v1-alpha/leagues/tag/c++?":
+			fmt.Fprint(w, `{"kind":"tag","name":"c++","title":"C++","projects":[{"rank":1,"link":"https://github.com/gcc-mirror/gcc","score":0.8,"percentile":100,"overallPercentile":99.5}]}`)
+		case "/v1-alpha/dist/debian/dependents?maxDepth=2&package=libc6":
+			fmt.Fprint(w, `{"total":2,"data":[{"package":"bash","depth":1},{"package":"vim","depth":2}]}`)
+		case "/v1-alpha/dist/debian/path?from=vim&to=libc6":
+			fmt.Fprint(w, `{"path":["vim","libc6"]}`)
+		case "/v1-alpha/dist/debian/package?lang=zh&package=nginx":
+			fmt.Fprint(w, `{"package":"nginx","description":"small, powerful, scalable web/proxy server","descriptionLanguage":"en","translatedDescription":"小巧、强大、可扩展的 Web/代理服务器","translatedLanguage":"zh"}`)
+		case "/v1-alpha/dist/debian/path?from=libc6&to=vim":
+			http.Error(w, "no path found", http.StatusNotFound)
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	c := New(srv.Client(), &Config{BaseURL: srv.URL + "/"})
+	ctx := context.Background()
+
+	top, err := c.EcosystemTop(ctx, "npm", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(top) != 2 || top[0].GitLink != "https://github.com/facebook/react" || *top[0].Score != 0.9 || top[1].Score != nil {
+		t.Errorf("EcosystemTop() = %+v", top)
+	}
+
+	league, err := c.League(ctx, "tag", "c++", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if league.Title != "C++" || len(league.Projects) != 1 || *league.Projects[0].OverallPercentile != 99.5 {
+		t.Errorf("League() = %+v", league)
+	}
+
+	dependents, err := c.Dependents(ctx, "debian", "libc6", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dependents.Total != 2 || dependents.Data[1] != (Dependent{Package: "vim", Depth: 2}) {
+		t.Errorf("Dependents() = %+v", dependents)
+	}
+
+	path, err := c.DependencyPath(ctx, "debian", "vim", "libc6", 0)
+	if err != nil || !reflect.DeepEqual(path, []string{"vim", "libc6"}) {
+		t.Errorf("DependencyPath() = %v, %v", path, err)
+	}
+
+	pkg, err := c.Package(ctx, "debian", "nginx", "zh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *pkg.DescriptionLanguage != "en" || *pkg.TranslatedLanguage != "zh" || pkg.Version != nil {
+		t.Errorf("Package() = %+v", pkg)
+	}
+
+	_, err = c.DependencyPath(ctx, "debian", "libc6", "vim", 0)
+	var se *StatusError
+	if !errors.Is(err, ErrNotFound) || !errors.As(err, &se) || se.Message != "no path found" {
+		t.Errorf("DependencyPath() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestAllProjects(t *testing.T) {
+	const total = 5
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if tag := r.URL.Query().Get("tag"); tag != "web" {
+			t.Errorf("tag = %q, want web", tag)
+		}
+		// the cursor is the start of the next page, after the first page
+		start, _ := strconv.Atoi(r.URL.Query().Get("cursor"))
+		if s := r.URL.Query().Get("start"); s != "" {
+			t.Errorf("start = %s with cursors", s)
+		}
+		take, _ := strconv.Atoi(r.URL.Query().Get("take"))
+		fmt.Fprintf(w, `{"total":%d,`, total)
+		if start+take < total {
+			fmt.Fprintf(w, `"next":"%d",`, start+take)
+		}
+		fmt.Fprint(w, `"data":[`)
+		for i := start; i < min(start+take, total); i++ {
+			if i > start {
+				fmt.Fprint(w, ",")
+			}
+			fmt.Fprintf(w, `{"link":"https://github.com/foo/%d"}`, i)
+		}
+		fmt.Fprint(w, "]}")
+	}))
+	t.Cleanup(srv.Close)
+	c := New(srv.Client(), &Config{BaseURL: srv.URL + "/"})
+
+	var links []string
+	for p, err := range c.AllProjects(context.Background(), "web", 2) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		links = append(links, p.GitLink)
+	}
+	want := []string{
+		"https://github.com/foo/0", "https://github.com/foo/1", "https://github.com/foo/2",
+		"https://github.com/foo/3", "https://github.com/foo/4",
+	}
+	if !reflect.DeepEqual(links, want) {
+		t.Errorf("AllProjects() = %v, want %v", links, want)
+	}
+
+	// breaking the loop stops fetching
+	n := 0
+	for range c.AllProjects(context.Background(), "web", 2) {
+		if n++; n == 3 {
+			break
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{http.StatusOK, nil},
+		{http.StatusBadRequest, ErrBadRequest},
+		{http.StatusNotFound, ErrNotFound},
+		{http.StatusForbidden, ErrRequest},
+		{http.StatusTooManyRequests, ErrRateLimited},
+		{http.StatusInternalServerError, ErrServer},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		rec.WriteHeader(tt.status)
+		err := check("/v1-alpha/coverage", rec.Result(), []byte(" database is down\n"))
+		if !errors.Is(err, tt.want) {
+			t.Errorf("status %d: error = %v, want %v", tt.status, err, tt.want)
+		}
+		var se *StatusError
+		if err != nil && (!errors.As(err, &se) || se.Message != "database is down") {
+			t.Errorf("status %d: error = %v, want the message of the body", tt.status, err)
+		}
+	}
+}
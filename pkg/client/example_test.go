@@ -0,0 +1,40 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/client"
+)
+
+// Example lists the 100 most critical projects tagged web and the
+// dependents of libc6 in Debian.
+func Example() {
+	c := client.New(nil, &client.Config{
+		BaseURL:    "http://localhost:8080",
+		MaxRetries: client.DefaultMaxRetries,
+	})
+	ctx := context.Background()
+
+	n := 0
+	for p, err := range c.AllProjects(ctx, "web", 0) {
+		if err != nil {
+			log.Fatal(err)
+		}
+		score := 0.0
+		if p.Score != nil {
+			score = *p.Score
+		}
+		fmt.Printf("%.4f %s\n", score, p.GitLink)
+		if n++; n == 100 {
+			break
+		}
+	}
+
+	dependents, err := c.Dependents(ctx, "debian", "libc6", 2)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%d packages depend on libc6\n", dependents.Total)
+}
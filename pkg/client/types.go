@@ -0,0 +1,199 @@
+package client
+
+import "time"
+
+// Project is a scored project of Projects.
+type Project struct {
+	GitLink          string     `json:"link"`
+	Ecosystems       *string    `json:"ecosystems"`
+	CreatedSince     *time.Time `json:"createdSince"`
+	UpdatedSince     *time.Time `json:"updatedSince"`
+	ContributorCount *int       `json:"contributorCount"`
+	OrgCount         *int       `json:"orgCount"`
+	CommitFrequency  *float64   `json:"commitFrequency"`
+	DepsDevCount     *int       `json:"depsDevCount"`
+	DepsDistroScore  *float64   `json:"depsDistroScore"`
+	License          *string    `json:"license"`
+	Language         *string    `json:"language"`
+	Industry         *string    `json:"industry"`
+	Domestic         *bool      `json:"domestic"`
+	Score            *float64   `json:"score"`
+	MaintenanceRisk  *string    `json:"maintenanceRisk"`
+}
+
+// ProjectPage is a page of the projects by descending score.
+type ProjectPage struct {
+	// Total is the number of projects of all pages
+	Total int `json:"total"`
+	// Next is the cursor of the next page, empty on the last page
+	Next string    `json:"next,omitempty"`
+	Data []Project `json:"data"`
+}
+
+// TopProject is a top project of an ecosystem.
+type TopProject struct {
+	Rank         int64      `json:"rank"`
+	GitLink      string     `json:"link"`
+	Score        *float64   `json:"score"`
+	DistScore    *float64   `json:"distScore"`
+	LangEcoScore *float64   `json:"langEcoScore"`
+	GitScore     *float64   `json:"gitScore"`
+	UpdateTime   *time.Time `json:"updateTime"`
+}
+
+// DistroSummary summarizes the projects of a distribution.
+type DistroSummary struct {
+	Dist         string     `json:"dist"`
+	Projects     int64      `json:"projects"`
+	Dependents   int64      `json:"dependents"`
+	AvgPageRank  *float64   `json:"avgPageRank"`
+	AvgDistScore *float64   `json:"avgDistScore"`
+	UpdateTime   *time.Time `json:"updateTime"`
+}
+
+// DistributionChannels are the language ecosystems and the distributions
+// distributing a project, and release if its releases have binary assets.
+type DistributionChannels struct {
+	GitLink    string     `json:"link"`
+	Channels   []string   `json:"channels"`
+	Breadth    int        `json:"breadth"`
+	UpdateTime *time.Time `json:"updateTime"`
+}
+
+// PopularityPoint is a snapshot of the stars and forks of a project.
+type PopularityPoint struct {
+	Time  time.Time `json:"time"`
+	Stars *int      `json:"stars"`
+	Forks *int      `json:"forks"`
+}
+
+// Popularity is the time series of the stars and forks of a project, the
+// growth rates are relative per month.
+type Popularity struct {
+	GitLink     string            `json:"link"`
+	Points      []PopularityPoint `json:"points"`
+	StarsGrowth *float64          `json:"starsGrowth"`
+	ForksGrowth *float64          `json:"forksGrowth"`
+}
+
+// ProjectLabel labels a project with a country, an ISO 3166-1 alpha-2
+// code, or an affiliation, the name of an organization.
+type ProjectLabel struct {
+	Kind       string  `json:"kind"`
+	Value      string  `json:"value"`
+	Confidence float64 `json:"confidence"`
+	// Source and Version are the import the label comes from
+	Source    string     `json:"source"`
+	Version   int        `json:"version"`
+	CreatedAt *time.Time `json:"createdAt"`
+}
+
+// ProjectLabels are the current labels of a project.
+type ProjectLabels struct {
+	GitLink string         `json:"link"`
+	Labels  []ProjectLabel `json:"labels"`
+}
+
+// SignalValue is a signal of a project collected by a pluggable signal
+// collector, e.g. the checks.maintained of scorecard.
+type SignalValue struct {
+	Collector   string     `json:"collector"`
+	Name        string     `json:"name"`
+	Value       float64    `json:"value"`
+	CollectedAt *time.Time `json:"collectedAt"`
+}
+
+// SignalValues are the signals of a project, sorted by collector and name.
+type SignalValues struct {
+	GitLink string        `json:"link"`
+	Signals []SignalValue `json:"signals"`
+}
+
+// OrgScore is the scores of the projects of an organization or a user,
+// e.g. github.com/curl, in the latest scoring run.
+type OrgScore struct {
+	Rank     int      `json:"rank"`
+	Owner    string   `json:"owner"`
+	Projects int      `json:"projects"`
+	ScoreSum *float64 `json:"scoreSum"`
+	ScoreMax *float64 `json:"scoreMax"`
+	// TopLink is the highest scored project, TopProjects the number of
+	// projects among the top projects of all organizations
+	TopLink     *string    `json:"topLink"`
+	TopProjects *int       `json:"topProjects"`
+	UpdateTime  *time.Time `json:"updateTime"`
+}
+
+// CoverageStat is the coverage of the packages of an ecosystem.
+type CoverageStat struct {
+	// Source is distribution or language
+	Source          string     `json:"source"`
+	Ecosystem       string     `json:"ecosystem"`
+	Packages        int64      `json:"packages"`
+	WithGitLink     int64      `json:"withGitLink"`
+	WithGitMetrics  int64      `json:"withGitMetrics"`
+	WithDependents  int64      `json:"withDependents"`
+	GitLinkShare    *float64   `json:"gitLinkShare"`
+	GitMetricsShare *float64   `json:"gitMetricsShare"`
+	DependentsShare *float64   `json:"dependentsShare"`
+	UpdateTime      *time.Time `json:"updateTime"`
+}
+
+// LeagueEntry is a project of a League.
+type LeagueEntry struct {
+	Rank    int     `json:"rank"`
+	GitLink string  `json:"link"`
+	Score   float64 `json:"score"`
+	// Percentile is the percentile rank within the league, and
+	// OverallPercentile among all projects
+	Percentile        float64  `json:"percentile"`
+	OverallPercentile *float64 `json:"overallPercentile"`
+}
+
+// League is the top projects of a language, a distribution or a tag.
+type League struct {
+	// Kind is language, distro, distro-only or tag
+	Kind     string        `json:"kind"`
+	Name     string        `json:"name"`
+	Title    string        `json:"title"`
+	Projects []LeagueEntry `json:"projects"`
+}
+
+// Tag is a project tag with the number of its projects.
+type Tag struct {
+	Tag      string `json:"tag"`
+	Projects int    `json:"projects"`
+}
+
+// Dependent is a transitive reverse dependency of a package.
+type Dependent struct {
+	Package string `json:"package"`
+	Depth   int    `json:"depth"`
+}
+
+// Dependents are the transitive reverse dependencies of a package.
+type Dependents struct {
+	Total int         `json:"total"`
+	Data  []Dependent `json:"data"`
+}
+
+// DependencyPath is the shortest dependency path between two packages.
+type DependencyPath struct {
+	Path []string `json:"path"`
+}
+
+// Package is a package of a distribution.
+type Package struct {
+	Package     string  `json:"package"`
+	Version     *string `json:"version"`
+	HomePage    *string `json:"homepage"`
+	GitLink     *string `json:"link"`
+	Description *string `json:"description"`
+	// DescriptionLanguage is the detected language of the description,
+	// e.g. en
+	DescriptionLanguage *string `json:"descriptionLanguage"`
+	// TranslatedDescription is the description in TranslatedLanguage, nil
+	// if it is already in that language or the server does not translate
+	TranslatedDescription *string `json:"translatedDescription"`
+	TranslatedLanguage    *string `json:"translatedLanguage"`
+}
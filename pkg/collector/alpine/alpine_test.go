@@ -0,0 +1,73 @@
+package alpine
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const index = `C:Q1abc=
+P:musl
+V:1.2.5-r8
+T:the musl c library (libc) implementation
+U:https://musl.libc.org/
+
+P:busybox
+V:1.37.0-r8
+T:Size optimized toolbox of many common UNIX utilities
+U:https://busybox.net/
+D:so:libc.musl-x86_64.so.1 musl=1.2.5-r8
+`
+
+func TestParseIndex(t *testing.T) {
+	pkgs := ParseIndex(index)
+	if len(pkgs) != 2 {
+		t.Fatalf("ParseIndex() = %d packages, want 2", len(pkgs))
+	}
+	busybox := pkgs[1]
+	if busybox.Name != "busybox" || busybox.Version != "1.37.0-r8" || busybox.Homepage != "https://busybox.net/" {
+		t.Errorf("ParseIndex() busybox = %+v", busybox)
+	}
+	if len(busybox.Depends) != 2 || busybox.Depends[0] != "libc.musl-x86_64.so.1" || busybox.Depends[1] != "musl" {
+		t.Errorf("ParseIndex() depends = %q", busybox.Depends)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write([]byte(index))
+	w.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/x86_64/APKINDEX.tar.gz" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	pkgs, err := Collect(context.Background(), Options{
+		URL:    server.URL + "/%s/APKINDEX.tar.gz",
+		Arches: []string{"x86_64"},
+		Client: server.Client(),
+	})
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	counts := make(map[string]int)
+	for _, pkg := range pkgs {
+		counts[pkg.Name] = pkg.DependsCount
+	}
+	if counts["musl"] != 2 || counts["busybox"] != 1 {
+		t.Errorf("Collect() depends counts = %v", counts)
+	}
+
+	if _, err := Collect(context.Background(), Options{URL: server.URL + "/%s/APKINDEX.tar.gz", Arches: []string{"armv7"}, Client: server.Client()}); err == nil {
+		t.Error("Collect() of a missing arch error = nil")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"archive/tar"
 	"bufio"
 	"compress/gzip"
+	"context"
 	"fmt"
 	"io"
 	"log"
@@ -13,8 +14,12 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/HUSTSecLab/criticality_score/pkg/collector"
+	"github.com/HUSTSecLab/criticality_score/pkg/failure"
+	"github.com/HUSTSecLab/criticality_score/pkg/graph"
+	"github.com/HUSTSecLab/criticality_score/pkg/progress"
+	"github.com/HUSTSecLab/criticality_score/pkg/sampling"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
-	"github.com/lib/pq"
 )
 
 type DepInfo struct {
@@ -46,30 +51,19 @@ func (al *ArchLinux) updateOrInsertDatabase(pkgInfoMap map[string]DepInfo) error
 	if err != nil {
 		return err
 	}
-	defer db.Close()
 
+	pkgs := make([]collector.Package, 0, len(pkgInfoMap))
 	for pkgName, pkgInfo := range pkgInfoMap {
-		var exists bool
-		err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM arch_packages WHERE package = $1)", pkgName).Scan(&exists)
-		if err != nil {
-			return err
-		}
-
-		if !exists {
-			_, err := db.Exec("INSERT INTO arch_packages (package, depends_count, description, homepage, version, page_rank) VALUES ($1, $2, $3, $4, $5, $6)",
-				pkgName, pkgInfo.DependsCount, pkgInfo.Description, pkgInfo.Homepage, pkgInfo.Version, pkgInfo.PageRank)
-			if err != nil {
-				return err
-			}
-		} else {
-			_, err := db.Exec("UPDATE arch_packages SET depends_count = $1, description = $2, homepage = $3, version = $4, page_rank = $5 WHERE package = $6",
-				pkgInfo.DependsCount, pkgInfo.Description, pkgInfo.Homepage, pkgInfo.Version, pkgInfo.PageRank, pkgName)
-			if err != nil {
-				return err
-			}
-		}
-	}
-	return nil
+		pkgs = append(pkgs, collector.Package{
+			Name:         pkgName,
+			Version:      pkgInfo.Version,
+			Description:  pkgInfo.Description,
+			Homepage:     pkgInfo.Homepage,
+			DependsCount: pkgInfo.DependsCount,
+			PageRank:     pkgInfo.PageRank,
+		})
+	}
+	return collector.UpsertPackages(context.Background(), db, "arch_packages", pkgs, true, 0)
 }
 
 func (al *ArchLinux) storeDependenciesInDatabase(pkgName string, dependencies []DepInfo) error {
@@ -77,15 +71,14 @@ func (al *ArchLinux) storeDependenciesInDatabase(pkgName string, dependencies []
 	if err != nil {
 		return err
 	}
-	defer db.Close()
 
+	names := make([]string, 0, len(dependencies))
 	for _, dep := range dependencies {
-		_, err := db.Exec("INSERT INTO arch_relationships (frompackage, topackage) VALUES ($1, $2)", pkgName, dep.Name)
-		if err != nil {
-			return err
+		if dep.Name != "" {
+			names = append(names, dep.Name)
 		}
 	}
-	return nil
+	return collector.ReplaceDependencies(context.Background(), db, "arch_relationships", pkgName, names)
 }
 
 func (al *ArchLinux) toDep(dep string, rawContent string) DepInfo {
@@ -234,110 +227,37 @@ func (al *ArchLinux) readDescFile(descPath string) (DepInfo, []DepInfo, error) {
 	return pkgInfo, dependencies, nil
 }
 
-func (al *ArchLinux) generateDependencyGraph(outputPath string) error {
-	file, err := os.Create(outputPath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	writer := bufio.NewWriter(file)
-	writer.WriteString("digraph {\n")
-
-	packageIndices := make(map[string]int)
-	index := 0
-
+func (al *ArchLinux) generateDependencyGraph(pkgInfoMap map[string]DepInfo, outputPath string) error {
+	export := graph.NewExport()
 	for pkgName, pkgInfo := range al.packages {
-		packageIndices[pkgName] = index
-		label := fmt.Sprintf("%s@%s", pkgName, pkgInfo["Info"].(DepInfo).Version)
-		writer.WriteString(fmt.Sprintf("  %d [label=\"%s\"];\n", index, label))
-		index++
-	}
-
-	for pkgName, pkgInfo := range al.packages {
-		pkgIndex := packageIndices[pkgName]
+		export.AddNode(graph.ExportNode{
+			Name:         pkgName,
+			Label:        fmt.Sprintf("%s@%s", pkgName, pkgInfo["Info"].(DepInfo).Version),
+			Version:      pkgInfo["Info"].(DepInfo).Version,
+			PageRank:     pkgInfoMap[pkgName].PageRank,
+			DependsCount: pkgInfoMap[pkgName].DependsCount,
+		})
 		if depends, ok := pkgInfo["Depends"].([]DepInfo); ok {
 			for _, dep := range depends {
-				if depIndex, ok := packageIndices[dep.Name]; ok {
-					writer.WriteString(fmt.Sprintf("  %d -> %d [label=\"%s\"];\n", pkgIndex, depIndex, dep.Version))
-				}
+				export.AddEdge(pkgName, dep.Name, dep.Version)
 			}
 		}
 	}
-
-	writer.WriteString("}\n")
-	writer.Flush()
-	return nil
+	return export.WriteFile(outputPath)
 }
 
-func (al *ArchLinux) getAllDep(pkgName string, deps []string) []string {
-	deps = append(deps, pkgName)
-	if pkg, ok := al.packages[pkgName]; ok {
-		if depends, ok := pkg["Depends"].([]DepInfo); ok {
-			for _, dep := range depends {
-				pkgname := dep.Name
-				if !contains(deps, pkgname) {
-					deps = al.getAllDep(pkgname, deps)
-				}
-			}
+// directDeps returns the names of the direct dependencies of the package.
+func (al *ArchLinux) directDeps(pkgName string) []string {
+	var deps []string
+	if depends, ok := al.packages[pkgName]["Depends"].([]DepInfo); ok {
+		for _, dep := range depends {
+			deps = append(deps, dep.Name)
 		}
 	}
 	return deps
 }
 
-func (al *ArchLinux) calculatePageRank(iterations int, dampingFactor float64) map[string]float64 {
-	pageRank := make(map[string]float64)
-	outgoingLinks := make(map[string]int)
-
-	for pkgName, pkgInfo := range al.packages {
-		pageRank[pkgName] = 1.0 / float64(len(al.packages))
-		if depends, ok := pkgInfo["Depends"].([]DepInfo); ok {
-			outgoingLinks[pkgName] = len(depends)
-		} else {
-			outgoingLinks[pkgName] = 0
-		}
-	}
-
-	for i := 0; i < iterations; i++ {
-		newPageRank := make(map[string]float64)
-		for pkgName := range al.packages {
-			newPageRank[pkgName] = (1 - dampingFactor) / float64(len(al.packages))
-		}
-
-		for pkgName, pkgInfo := range al.packages {
-			var depNum int
-			if depends, ok := pkgInfo["Depends"].([]DepInfo); ok {
-				for _, dep := range depends {
-					if outgoingLinks[dep.Name] > 0 {
-						depNum++
-					}
-				}
-			}
-			if depends, ok := pkgInfo["Depends"].([]DepInfo); ok {
-				for _, dep := range depends {
-					if outgoingLinks[dep.Name] > 0 {
-						newPageRank[dep.Name] += dampingFactor * pageRank[pkgName] / float64(depNum)
-					}
-				}
-			}
-		}
-
-		pageRank = newPageRank
-	}
-
-	return pageRank
-}
-
-func contains(slice []string, item string) bool {
-	for _, s := range slice {
-		if s == item {
-			return true
-		}
-	}
-	return false
-}
-
-func (al *ArchLinux) Collect(outputPath string) {
+func (al *ArchLinux) Collect(tracker *failure.Tracker, outputPath string) error {
 	// if _, err := os.Stat(al.downloadDir); os.IsNotExist(err) {
 	// 	log.Println("Download directory not found, starting download...")
 	DownloadFiles()
@@ -350,8 +270,7 @@ func (al *ArchLinux) Collect(outputPath string) {
 	if _, err := os.Stat(al.extractDir); os.IsNotExist(err) {
 		err := os.Mkdir(al.extractDir, 0o755)
 		if err != nil {
-			log.Printf("Error creating extract directory: %v\n", err)
-			return
+			return fmt.Errorf("error creating extract directory: %w", err)
 		}
 	}
 
@@ -377,8 +296,7 @@ func (al *ArchLinux) Collect(outputPath string) {
 		return nil
 	})
 	if err != nil {
-		log.Printf("Error walking through download directory: %v\n", err)
-		return
+		return fmt.Errorf("error walking through download directory: %w", err)
 	}
 
 	err = filepath.Walk(al.extractDir, func(path string, info os.FileInfo, err error) error {
@@ -402,19 +320,11 @@ func (al *ArchLinux) Collect(outputPath string) {
 		return nil
 	})
 	if err != nil {
-		log.Printf("Error walking through extracted directory: %v\n", err)
-		return
+		return fmt.Errorf("error walking through extracted directory: %w", err)
 	}
+	al.packages = sampling.Map(al.packages)
 	log.Printf("Done, total: %d packages.\n", len(al.packages))
 
-	if outputPath != "" {
-		err := al.generateDependencyGraph(outputPath)
-		if err != nil {
-			log.Printf("Error generating dependency graph: %v\n", err)
-			return
-		}
-		log.Println("Dependency graph generated successfully.")
-	}
 	log.Println("Building dependencies graph...")
 	keys := make([]string, 0, len(al.packages))
 	for k := range al.packages {
@@ -422,20 +332,14 @@ func (al *ArchLinux) Collect(outputPath string) {
 	}
 	sort.Strings(keys)
 
-	depMap := make(map[string][]string)
+	depMap := make(map[string][]string, len(keys))
 	for _, pkgName := range keys {
-		deps := al.getAllDep(pkgName, []string{})
-		depMap[pkgName] = deps
+		depMap[pkgName] = al.directDeps(pkgName)
 	}
 
-	pagerank := al.calculatePageRank(20, 0.85)
+	pagerank := collector.RankDependencies("archlinux", depMap)
 	log.Println("Calculating dependencies count...")
-	countMap := make(map[string]int)
-	for _, deps := range depMap {
-		for _, dep := range deps {
-			countMap[dep]++
-		}
-	}
+	countMap := collector.DependsCounts(depMap)
 
 	pkgInfoMap := make(map[string]DepInfo)
 
@@ -464,22 +368,33 @@ func (al *ArchLinux) Collect(outputPath string) {
 		}
 	}
 
+	if outputPath != "" {
+		err := al.generateDependencyGraph(pkgInfoMap, outputPath)
+		if err != nil {
+			log.Printf("Error generating dependency graph: %v\n", err)
+			return nil
+		}
+		log.Println("Dependency graph generated successfully.")
+	}
+
+	release := collector.AcquireWrite()
+	defer release()
 	err = al.updateOrInsertDatabase(pkgInfoMap)
 	if err != nil {
-		log.Printf("Error updating database: %v\n", err)
-		return
+		return fmt.Errorf("error updating database: %w", err)
 	}
+	relPhase := progress.Default().Phase("relationships", len(al.packages))
 	for _, pkgInfo := range al.packages {
+		relPhase.Add(1)
 		if packageInfo, ok := pkgInfo["Info"].(DepInfo); ok {
 			packageName := packageInfo.Name
 			if depends, ok := pkgInfo["Depends"].([]DepInfo); ok {
 				if err := al.storeDependenciesInDatabase(packageName, depends); err != nil {
-					if isUniqueViolation(err) {
-						continue
-					}
 					log.Printf("Error storing dependencies for package %s: %v\n", packageName, err)
-					return
+					tracker.Fail(packageName, err)
+					continue
 				}
+				tracker.Success()
 			} else {
 				log.Printf("No valid dependencies found for package %s\n", packageName)
 			}
@@ -487,12 +402,8 @@ func (al *ArchLinux) Collect(outputPath string) {
 			log.Printf("Invalid package name for pkgInfo: %v\n", pkgInfo)
 		}
 	}
+	relPhase.Done()
+	release()
 	log.Println("Database updated successfully.")
-}
-
-func isUniqueViolation(err error) bool {
-	if pqErr, ok := err.(*pq.Error); ok {
-		return pqErr.Code == "23505"
-	}
-	return false
+	return nil
 }
@@ -6,7 +6,8 @@ import (
 	"net/http"
 	"os"
 	"strings"
-	
+
+	"github.com/HUSTSecLab/criticality_score/pkg/collector/mirror"
 	"golang.org/x/net/html"
 )
 
@@ -74,7 +75,7 @@ func downloadFile(url, filepath string) error {
 }
 
 func DownloadFiles() {
-	baseURL := "https://mirrors.hust.edu.cn/archlinux/"
+	baseURL := mirror.Default().Base("archlinux")
 	downloadDir := "./download"
 
 	// Create the download directory if it doesn't exist
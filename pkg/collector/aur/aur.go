@@ -1,252 +1,126 @@
 package aur
 
 import (
-	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"log"
 	"net/http"
-	"os"
-
-	"github.com/lib/pq"
 
+	"github.com/HUSTSecLab/criticality_score/pkg/collector"
+	"github.com/HUSTSecLab/criticality_score/pkg/failure"
+	"github.com/HUSTSecLab/criticality_score/pkg/graph"
+	"github.com/HUSTSecLab/criticality_score/pkg/progress"
+	"github.com/HUSTSecLab/criticality_score/pkg/sampling"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
 )
 
 var URL = "https://aur.archlinux.org/packages-meta-ext-v1.json.gz"
 
-type PackageInfo struct {
-	Depends      []string
-	DependsCount int
-	Description  string
-	GitRepo      string
-	Homepage     string
-	Name         string
-	PageRank     float64
-	URL          string
-	Version      string
+// Options are the options of Collect.
+type Options struct {
+	// URL is the url of the metadata dump of all packages
+	URL string
+	// Client fetches the dump, http.DefaultClient if nil
+	Client *http.Client
+	// Sampler samples the packages before ranking, nil keeps all packages
+	Sampler *sampling.Sampler
+	// PageRank are the parameters of PageRank, unset ones are those of
+	// graph.DefaultPageRankParams
+	PageRank graph.PageRankParams
 }
 
-type AurCollector struct {
-	PkgInfoMap map[string]PackageInfo
-	DepMap     map[string][]string
-	CountMap   map[string]int
-	PageRank   map[string]float64
+func DefaultOptions() Options {
+	return Options{URL: URL}
 }
 
-func NewAurCollector() *AurCollector {
-	return &AurCollector{
-		PkgInfoMap: make(map[string]PackageInfo),
-		DepMap:     make(map[string][]string),
-		CountMap:   make(map[string]int),
-		PageRank:   make(map[string]float64),
-	}
+// metadata is a package of the metadata dump.
+type metadata struct {
+	Name        string
+	Version     string
+	Description string
+	URL         string
+	Depends     []string
 }
 
-func (ac *AurCollector) Collect(outputPath string) {
-	err := ac.getDependencies()
+// Collect returns the ranked packages of the metadata dump.
+func Collect(ctx context.Context, opts Options) ([]collector.Package, error) {
+	phase := progress.Default().Phase("download", 1)
+	body, err := collector.Get(ctx, opts.Client, opts.URL)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-
-	ac.calculateDependencies()
-	ac.calculatePageRank(20, 0.85)
-	err = ac.updateOrInsertDatabase()
+	phase.Add(1)
+	phase.Done()
+	pkgs, err := ParseMetadata(body)
 	if err != nil {
-		fmt.Printf("Error updating database: %v\n", err)
-		return
+		return nil, err
 	}
-	ac.storeDependenciesInDatabase()
-	fmt.Println("Database updated successfully.")
 
-	if outputPath != "" {
-		err := ac.generateDependencyGraph(outputPath)
-		if err != nil {
-			fmt.Printf("Error generating dependency graph: %v\n", err)
-			return
-		}
-		fmt.Println("Dependency graph generated successfully.")
+	pkgMap := make(map[string]collector.Package, len(pkgs))
+	for _, pkg := range pkgs {
+		pkgMap[pkg.Name] = pkg
 	}
-}
+	pkgMap = sampling.MapFunc(opts.Sampler, pkgMap, func(s string) string { return s })
 
-func (ac *AurCollector) getDependencies() error {
-	resp, err := http.Get(URL)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return err
-	}
-	var packages []PackageInfo
-	err = json.Unmarshal([]byte(body), &packages)
-	if err != nil {
-		return err
-	}
-
-	for _, pkg := range packages {
-		ac.PkgInfoMap[pkg.Name] = pkg
-	}
-	return nil
-}
-
-func (ac *AurCollector) calculateDependencies() {
-	for pkgName := range ac.PkgInfoMap {
-		visited := make(map[string]bool)
-		deps := ac.getAllDep(pkgName, visited, []string{})
-		ac.DepMap[pkgName] = deps
-	}
-
-	for _, deps := range ac.DepMap {
-		for _, dep := range deps {
-			ac.CountMap[dep]++
-		}
+	ret := make([]collector.Package, 0, len(pkgMap))
+	for _, pkg := range pkgMap {
+		ret = append(ret, pkg)
 	}
+	collector.Rank(ret, opts.PageRank)
+	return ret, nil
 }
 
-func (ac *AurCollector) getAllDep(pkgName string, visited map[string]bool, deps []string) []string {
-	if visited[pkgName] {
-		return deps
-	}
-
-	visited[pkgName] = true
-	deps = append(deps, pkgName)
-
-	if pkg, ok := ac.PkgInfoMap[pkgName]; ok {
-		for _, depName := range pkg.Depends {
-			deps = ac.getAllDep(depName, visited, deps)
-		}
-	}
-	return deps
-}
-
-func (ac *AurCollector) calculatePageRank(iterations int, dampingFactor float64) {
-	numPackages := len(ac.PkgInfoMap)
-
-	for pkgName := range ac.PkgInfoMap {
-		ac.PageRank[pkgName] = 1.0 / float64(numPackages)
-	}
-
-	for i := 0; i < iterations; i++ {
-		newPageRank := make(map[string]float64)
-
-		for pkgName := range ac.PkgInfoMap {
-			newPageRank[pkgName] = (1 - dampingFactor) / float64(numPackages)
-		}
-
-		for pkgName, pkgInfo := range ac.PkgInfoMap {
-			var depNum int
-			for _, depName := range pkgInfo.Depends {
-				if _, exists := ac.PkgInfoMap[depName]; exists {
-					depNum++
-				}
-			}
-			for _, depName := range pkgInfo.Depends {
-				if _, exists := ac.PkgInfoMap[depName]; exists {
-					newPageRank[depName] += dampingFactor * (ac.PageRank[pkgName] / float64(depNum))
-				}
-			}
-		}
-		ac.PageRank = newPageRank
-	}
-
-	for pkgName, pkgInfo := range ac.PkgInfoMap {
-		pkgInfo.PageRank = ac.PageRank[pkgName]
-		pkgInfo.DependsCount = ac.CountMap[pkgName]
-		ac.PkgInfoMap[pkgName] = pkgInfo
+// ParseMetadata returns the packages of the json metadata dump.
+func ParseMetadata(data []byte) ([]collector.Package, error) {
+	var packages []metadata
+	if err := json.Unmarshal(data, &packages); err != nil {
+		return nil, err
 	}
+	ret := make([]collector.Package, 0, len(packages))
+	for _, pkg := range packages {
+		ret = append(ret, collector.Package{
+			Name:        pkg.Name,
+			Version:     pkg.Version,
+			Description: pkg.Description,
+			Homepage:    pkg.URL,
+			Depends:     pkg.Depends,
+		})
+	}
+	return ret, nil
 }
 
-func (ac *AurCollector) updateOrInsertDatabase() error {
-	db, err := storage.GetDefaultAppDatabaseContext().GetDatabaseConnection()
-	if err != nil {
-		return err
-	}
-	defer db.Close()
-
-	for pkgName, pkgInfo := range ac.PkgInfoMap {
-		var exists bool
-		err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM aur_packages WHERE package = $1)", pkgName).Scan(&exists)
-		if err != nil {
-			return err
-		}
-		if !exists {
-			_, err := db.Exec("INSERT INTO aur_packages (package, depends_count, description, homepage, page_rank, version) VALUES ($1, $2, $3, $4, $5, $6)",
-				pkgName, pkgInfo.DependsCount, pkgInfo.Description, pkgInfo.URL, pkgInfo.PageRank, pkgInfo.Version)
-			if err != nil {
-				return err
-			}
-		} else {
-			_, err := db.Exec("UPDATE aur_packages SET depends_count = $1, description = $2, homepage = $3, page_rank = $4, version = $5 WHERE package = $6",
-				pkgInfo.DependsCount, pkgInfo.Description, pkgInfo.URL, pkgInfo.PageRank, pkgInfo.Version, pkgName)
-			if err != nil {
-				return err
-			}
-		}
-	}
-	return nil
+type AurCollector struct {
+	URL string
 }
 
-func (ac *AurCollector) storeDependenciesInDatabase() {
-	db, err := storage.GetDefaultAppDatabaseContext().GetDatabaseConnection()
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer db.Close()
-
-	for pkgName, pkgInfo := range ac.PkgInfoMap {
-		for _, dep := range pkgInfo.Depends {
-			_, err := db.Exec("INSERT INTO aur_relationships (frompackage, topackage) VALUES ($1, $2)", pkgName, dep)
-			if err != nil {
-				if isUniqueViolation(err) {
-					continue
-				}
-				fmt.Printf("Error storing dependencies for package %s: %v\n", pkgName, err)
-			}
-		}
-	}
+func NewAurCollector() *AurCollector {
+	return &AurCollector{URL: URL}
 }
 
-func (ac *AurCollector) generateDependencyGraph(outputPath string) error {
-	file, err := os.Create(outputPath)
+// Collect saves the packages to the database, and writes the dependency
+// graph to outputPath if it is not empty. Packages whose dependencies
+// cannot be saved are failed in tracker, other errors are returned.
+func (ac *AurCollector) Collect(tracker *failure.Tracker, outputPath string) error {
+	ctx := context.Background()
+	pageRank := collector.PageRankParams("aur")
+	pkgs, err := Collect(ctx, Options{URL: ac.URL, Sampler: sampling.Default(), PageRank: pageRank})
 	if err != nil {
 		return err
 	}
-	defer file.Close()
-
-	writer := bufio.NewWriter(file)
-	writer.WriteString("digraph {\n")
-
-	packageIndices := make(map[string]int)
-	index := 0
-
-	for pkgName, pkgInfo := range ac.PkgInfoMap {
-		packageIndices[pkgName] = index
-		label := fmt.Sprintf("%s@%s", pkgName, pkgInfo.Description)
-		writer.WriteString(fmt.Sprintf("  %d [label=\"%s\"];\n", index, label))
-		index++
+	store := collector.NewDBStore(storage.GetDefaultAppDatabaseContext(), repository.DistLinkTablePrefixAur, tracker)
+	if err := store.Save(ctx, pkgs); err != nil {
+		return fmt.Errorf("error updating database: %w", err)
 	}
+	collector.RecordRank(storage.GetDefaultAppDatabaseContext(), "aur", pageRank, len(pkgs))
+	fmt.Println("Database updated successfully.")
 
-	for pkgName, pkgInfo := range ac.PkgInfoMap {
-		pkgIndex := packageIndices[pkgName]
-		for _, depName := range pkgInfo.Depends {
-			if depIndex, ok := packageIndices[depName]; ok {
-				writer.WriteString(fmt.Sprintf("  %d -> %d;\n", pkgIndex, depIndex))
-			}
+	if outputPath != "" {
+		if err := collector.WriteGraph(pkgs, outputPath); err != nil {
+			fmt.Printf("Error generating dependency graph: %v\n", err)
+			return nil
 		}
+		fmt.Println("Dependency graph generated successfully.")
 	}
-
-	writer.WriteString("}\n")
-	writer.Flush()
 	return nil
 }
-
-func isUniqueViolation(err error) bool {
-	if pqErr, ok := err.(*pq.Error); ok {
-		return pqErr.Code == "23505"
-	}
-	return false
-}
@@ -0,0 +1,38 @@
+package collector
+
+import "sync"
+
+var (
+	writeMu sync.Mutex
+	// writeSlots has a slot per collector allowed to write at the same
+	// time, nil is unlimited
+	writeSlots chan struct{}
+)
+
+// SetWriteBudget limits the number of collectors writing their packages to
+// the database at the same time, so collectors run in parallel interleave
+// their download and parse phases with the writes of the others instead of
+// all hammering the database at once. 0 is unlimited, the default.
+func SetWriteBudget(n int) {
+	writeMu.Lock()
+	defer writeMu.Unlock()
+	if n <= 0 {
+		writeSlots = nil
+		return
+	}
+	writeSlots = make(chan struct{}, n)
+}
+
+// AcquireWrite blocks until the collector may write to the database, the
+// returned function releases the slot and must be called once.
+func AcquireWrite() func() {
+	writeMu.Lock()
+	slots := writeSlots
+	writeMu.Unlock()
+	if slots == nil {
+		return func() {}
+	}
+	slots <- struct{}{}
+	var once sync.Once
+	return func() { once.Do(func() { <-slots }) }
+}
@@ -0,0 +1,49 @@
+package collector
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWriteBudget(t *testing.T) {
+	SetWriteBudget(2)
+	defer SetWriteBudget(0)
+
+	var writing, most atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release := AcquireWrite()
+			defer release()
+			n := writing.Add(1)
+			for {
+				m := most.Load()
+				if n <= m || most.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			writing.Add(-1)
+			// releasing twice does not free another slot
+			release()
+		}()
+	}
+	wg.Wait()
+	if got := most.Load(); got > 2 {
+		t.Errorf("%d collectors wrote at the same time, want at most 2", got)
+	}
+
+	// unlimited by default
+	SetWriteBudget(0)
+	releases := make([]func(), 10)
+	for i := range releases {
+		releases[i] = AcquireWrite()
+	}
+	for _, release := range releases {
+		release()
+	}
+}
@@ -0,0 +1,358 @@
+// Package collector is the Go API shared by the collectors of distribution
+// packages in its sub packages. A collector only fetches and ranks the
+// packages of a distribution, e.g.
+//
+//	pkgs, err := alpine.Collect(ctx, alpine.DefaultOptions())
+//
+// and leaves where they are saved to a Store, so the collectors can be used
+// as a library without a database.
+package collector
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/failure"
+	"github.com/HUSTSecLab/criticality_score/pkg/graph"
+	"github.com/HUSTSecLab/criticality_score/pkg/maintainer"
+	"github.com/HUSTSecLab/criticality_score/pkg/progress"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+)
+
+// Package is a package of a distribution.
+type Package struct {
+	Name        string
+	Version     string
+	Description string
+	Homepage    string
+	// Depends are the names of the direct dependencies, they may not be
+	// packages of the distribution
+	Depends []string
+	// DependsCount is the number of packages depending on the package
+	// directly or indirectly, including itself
+	DependsCount int
+	PageRank     float64
+	// Maintainers are the contacts of the package in the index, e.g. the
+	// packager of an RPM, nil if the index has none
+	Maintainers []maintainer.Contact
+}
+
+// Store saves the packages of a distribution.
+type Store interface {
+	// Save saves the packages and their dependencies
+	Save(ctx context.Context, pkgs []Package) error
+}
+
+// DependsCounts returns the number of packages depending on every package
+// of deps directly or indirectly, including itself. deps maps the packages
+// to their direct dependencies, dependencies which are not packages of deps
+// are ignored. The counts are taken from the closure table of
+// graph.Ancestors instead of walking the dependencies of every package.
+func DependsCounts(deps map[string][]string) map[string]int {
+	g := graph.New()
+	for name := range deps {
+		g.AddNode(name)
+	}
+	for name, ds := range deps {
+		for _, dep := range ds {
+			if g.HasNode(dep) {
+				g.AddEdge(name, dep)
+			}
+		}
+	}
+	counts := graph.NewAncestors(g).Counts(false)
+	for name := range counts {
+		counts[name]++
+	}
+	return counts
+}
+
+// Rank sets DependsCount and PageRank of pkgs, PageRank is computed by p,
+// see graph.RankDependencies. Only dependencies which are packages of pkgs
+// are ranked. The number of iterations run is returned.
+func Rank(pkgs []Package, p graph.PageRankParams) int {
+	deps := make(map[string][]string, len(pkgs))
+	for _, pkg := range pkgs {
+		deps[pkg.Name] = pkg.Depends
+	}
+	counts := DependsCounts(deps)
+
+	phase := progress.Default().Phase("rank", 1)
+	defer phase.Done()
+	rank, n := graph.RankDependencies(deps, nil, p)
+	for i := range pkgs {
+		pkgs[i].DependsCount = counts[pkgs[i].Name]
+		pkgs[i].PageRank = rank[pkgs[i].Name]
+	}
+	phase.Add(1)
+	return n
+}
+
+// PageRankParams returns the configured PageRank parameters of the
+// collector of the distribution name, see graph.PageRankParamsOf.
+func PageRankParams(name string) graph.PageRankParams {
+	return graph.PageRankParamsOf(name, graph.DefaultPageRankParams)
+}
+
+// RankDependencies returns the PageRank of deps by the parameters of the
+// collector name, and records them with the run by RecordRank.
+func RankDependencies(name string, deps map[string][]string) map[string]float64 {
+	p := PageRankParams(name)
+	rank, n := graph.RankDependencies(deps, nil, p)
+	log.Printf("PageRank of %d packages ran %d iterations, %s", len(deps), n, p)
+	RecordRank(storage.GetDefaultAppDatabaseContext(), name, p, len(deps))
+	return rank
+}
+
+// RecordRank records the PageRank parameters of a run of the collector
+// name in the page_rank_runs table, so the ranks can be reproduced. A
+// failure is only logged, the ranks are saved anyway.
+func RecordRank(appDb storage.AppDatabaseContext, name string, p graph.PageRankParams, packages int) {
+	weighting := string(p.Weighting)
+	now := time.Now()
+	err := repository.NewPageRankRunRepository(appDb).Insert(&repository.PageRankRun{
+		Collector:  &name,
+		Iterations: &p.Iterations,
+		Damping:    &p.Damping,
+		Epsilon:    &p.Epsilon,
+		Weighting:  &weighting,
+		Packages:   &packages,
+		RunTime:    &now,
+	})
+	if err != nil {
+		log.Printf("Failed to record PageRank parameters of %s: %v", name, err)
+	}
+}
+
+// WriteGraph writes the dependency graph of pkgs to path, in GraphML format
+// if path ends with .graphml, otherwise DOT.
+func WriteGraph(pkgs []Package, path string) error {
+	export := graph.NewExport()
+	for _, pkg := range pkgs {
+		export.AddNode(graph.ExportNode{
+			Name:         pkg.Name,
+			Label:        fmt.Sprintf("%s@%s", pkg.Name, pkg.Description),
+			Version:      pkg.Version,
+			PageRank:     pkg.PageRank,
+			DependsCount: pkg.DependsCount,
+		})
+		for _, dep := range pkg.Depends {
+			export.AddEdge(pkg.Name, dep, "")
+		}
+	}
+	return export.WriteFile(path)
+}
+
+// Get fetches url with client, http.DefaultClient if client is nil. Status
+// codes other than 200 are errors.
+func Get(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// DBStore saves packages to the <prefix>_packages and
+// <prefix>_relationships tables.
+type DBStore struct {
+	appDb   storage.AppDatabaseContext
+	prefix  repository.DistPackageTablePrefix
+	tracker *failure.Tracker
+}
+
+// NewDBStore returns a store recording the packages whose dependencies are
+// saved or not in tracker.
+func NewDBStore(appDb storage.AppDatabaseContext, prefix repository.DistPackageTablePrefix, tracker *failure.Tracker) *DBStore {
+	return &DBStore{appDb: appDb, prefix: prefix, tracker: tracker}
+}
+
+// Save implements Store, it waits for a slot of the write budget first, see
+// SetWriteBudget. Packages are written by UpsertPackages, and the
+// dependencies of every package are replaced by ReplaceDependencies, so
+// dependencies dropped by the index are removed.
+// The maintainers of the packages are replaced as well if the index has
+// any.
+func (s *DBStore) Save(ctx context.Context, pkgs []Package) error {
+	release := AcquireWrite()
+	defer release()
+	db, err := s.appDb.GetDatabaseConnection()
+	if err != nil {
+		return err
+	}
+	relationships := repository.DistRelationshipTableName(s.prefix)
+
+	if err := UpsertPackages(ctx, db, repository.DistPackageTableName(s.prefix), pkgs, true, 0); err != nil {
+		return err
+	}
+
+	if err := s.saveMaintainers(pkgs); err != nil {
+		return fmt.Errorf("save maintainers: %w", err)
+	}
+
+	relPhase := progress.Default().Phase("relationships", len(pkgs))
+	defer relPhase.Done()
+
+	// a failed package does not stop the others, the tracker decides
+	// whether the run failed
+	for _, pkg := range pkgs {
+		var failed error
+		if err := ReplaceDependencies(ctx, db, relationships, pkg.Name, pkg.Depends); err != nil {
+			failed = fmt.Errorf("save dependencies of %s: %w", pkg.Name, err)
+		}
+		if failed != nil {
+			progress.Default().Error(failed)
+			s.tracker.Fail(pkg.Name, failed)
+		} else {
+			s.tracker.Success()
+		}
+		relPhase.Add(1)
+	}
+	return nil
+}
+
+// saveMaintainers replaces the maintainers of pkgs, if the index has any.
+func (s *DBStore) saveMaintainers(pkgs []Package) error {
+	contacts := make(map[string][]maintainer.Contact, len(pkgs))
+	found := false
+	for _, pkg := range pkgs {
+		contacts[pkg.Name] = pkg.Maintainers
+		found = found || len(pkg.Maintainers) > 0
+	}
+	if !found {
+		return nil
+	}
+	return maintainer.Save(repository.NewMaintainerRepository(s.appDb), string(s.prefix), contacts, time.Now())
+}
+
+// UpsertBatchSize is the default number of packages written by one
+// statement of UpsertPackages.
+const UpsertBatchSize = 1000
+
+// UpsertPackages inserts pkgs into the packages table, or updates them if
+// they exist, by a multi-row statement per batchSize packages, all in a
+// single transaction, UpsertBatchSize if batchSize is not positive.
+// Descriptions are normalized by Description, and the version is only
+// written if version is true, e.g. homebrew_packages has no version.
+// Depends and Maintainers are not saved.
+func UpsertPackages(ctx context.Context, db *sql.DB, packages string, pkgs []Package, version bool, batchSize int) error {
+	table, err := sqlutil.Table(packages)
+	if err != nil {
+		return err
+	}
+	// a statement can not update a row twice, the last package of a name
+	// wins as if they were written one by one
+	index := make(map[string]int, len(pkgs))
+	unique := make([]Package, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		if i, ok := index[pkg.Name]; ok {
+			unique[i] = pkg
+			continue
+		}
+		index[pkg.Name] = len(unique)
+		unique = append(unique, pkg)
+	}
+
+	query := `INSERT INTO ` + table + ` (package, depends_count, description, homepage, page_rank)
+		SELECT * FROM UNNEST($1::text[], $2::bigint[], $3::text[], $4::text[], $5::double precision[])
+		ON CONFLICT (package) DO UPDATE
+		SET depends_count = EXCLUDED.depends_count,
+			description = EXCLUDED.description,
+			homepage = EXCLUDED.homepage,
+			page_rank = EXCLUDED.page_rank`
+	if version {
+		query = `INSERT INTO ` + table + ` (package, depends_count, description, homepage, page_rank, version)
+		SELECT * FROM UNNEST($1::text[], $2::bigint[], $3::text[], $4::text[], $5::double precision[], $6::text[])
+		ON CONFLICT (package) DO UPDATE
+		SET depends_count = EXCLUDED.depends_count,
+			description = EXCLUDED.description,
+			homepage = EXCLUDED.homepage,
+			page_rank = EXCLUDED.page_rank,
+			version = EXCLUDED.version`
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	phase := progress.Default().Phase("save", len(unique))
+	defer phase.Done()
+	if batchSize <= 0 {
+		batchSize = UpsertBatchSize
+	}
+	for start := 0; start < len(unique); start += batchSize {
+		chunk := unique[start:min(start+batchSize, len(unique))]
+		names := make([]string, len(chunk))
+		counts := make([]int64, len(chunk))
+		descriptions := make([]string, len(chunk))
+		homepages := make([]string, len(chunk))
+		ranks := make([]float64, len(chunk))
+		versions := make([]string, len(chunk))
+		for i, pkg := range chunk {
+			names[i], counts[i], ranks[i] = pkg.Name, int64(pkg.DependsCount), pkg.PageRank
+			descriptions[i], homepages[i], versions[i] = Description(pkg.Description), pkg.Homepage, pkg.Version
+		}
+		args := []any{pq.Array(names), pq.Array(counts), pq.Array(descriptions), pq.Array(homepages), pq.Array(ranks)}
+		if version {
+			args = append(args, pq.Array(versions))
+		}
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("save packages of %s: %w", table, err)
+		}
+		phase.Add(len(chunk))
+	}
+	return tx.Commit()
+}
+
+// ReplaceDependencies sets the dependencies of pkg in the relationships
+// table to deps in one transaction. Dependencies not in deps are deleted
+// and the others are inserted, unchanged ones are left as they are.
+func ReplaceDependencies(ctx context.Context, db *sql.DB, relationships, pkg string, deps []string) error {
+	table, err := sqlutil.Table(relationships)
+	if err != nil {
+		return err
+	}
+	if deps == nil {
+		deps = []string{}
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE frompackage = $1 AND topackage <> ALL($2)`,
+		pkg, pq.Array(deps))
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO `+table+` (frompackage, topackage)
+		SELECT $1, unnest($2::varchar[])
+		ON CONFLICT DO NOTHING`, pkg, pq.Array(deps))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,117 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/graph"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+)
+
+func TestRank(t *testing.T) {
+	// a <- b <- c, and a dependency which is not a package
+	pkgs := []Package{
+		{Name: "a"},
+		{Name: "b", Depends: []string{"a", "missing"}},
+		{Name: "c", Depends: []string{"b"}},
+	}
+	Rank(pkgs, graph.DefaultPageRankParams)
+
+	wantCounts := map[string]int{"a": 3, "b": 2, "c": 1}
+	var sum float64
+	for _, pkg := range pkgs {
+		if pkg.DependsCount != wantCounts[pkg.Name] {
+			t.Errorf("DependsCount of %s = %d, want %d", pkg.Name, pkg.DependsCount, wantCounts[pkg.Name])
+		}
+		sum += pkg.PageRank
+	}
+	if !(pkgs[0].PageRank > pkgs[1].PageRank && pkgs[1].PageRank > pkgs[2].PageRank) {
+		t.Errorf("PageRank = %v, %v, %v, want descending", pkgs[0].PageRank, pkgs[1].PageRank, pkgs[2].PageRank)
+	}
+	if sum > 1+1e-9 || math.IsNaN(sum) {
+		t.Errorf("sum of PageRank = %v", sum)
+	}
+
+	// ranking again does not accumulate the counts
+	Rank(pkgs, graph.DefaultPageRankParams)
+	if pkgs[0].DependsCount != 3 {
+		t.Errorf("DependsCount after ranking twice = %d, want 3", pkgs[0].DependsCount)
+	}
+}
+
+func TestDependsCounts(t *testing.T) {
+	// x and y depend on each other, z depends on x
+	got := DependsCounts(map[string][]string{
+		"x": {"y"},
+		"y": {"x", "missing"},
+		"z": {"x", "z"},
+	})
+	want := map[string]int{"x": 3, "y": 3, "z": 1}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DependsCounts() = %v, want %v", got, want)
+	}
+}
+
+func TestGet(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/index" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	body, err := Get(context.Background(), server.Client(), server.URL+"/index")
+	if err != nil || string(body) != "ok" {
+		t.Errorf("Get() = %q, %v", body, err)
+	}
+	if _, err := Get(context.Background(), server.Client(), server.URL+"/missing"); err == nil {
+		t.Error("Get() of a missing page error = nil")
+	}
+}
+
+func TestReplaceDependencies(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "debian_relationships" WHERE frompackage = \$1 AND topackage <> ALL`).
+		WithArgs("curl", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO "debian_relationships" \(frompackage, topackage\)`).
+		WithArgs("curl", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	if err := ReplaceDependencies(context.Background(), db, "debian_relationships", "curl", []string{"libc6", "zlib1g"}); err != nil {
+		t.Errorf("ReplaceDependencies() = %v", err)
+	}
+
+	// a failed insert keeps the old dependencies
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "debian_relationships"`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO "debian_relationships"`).WillReturnError(errors.New("boom"))
+	mock.ExpectRollback()
+
+	if err := ReplaceDependencies(context.Background(), db, "debian_relationships", "curl", nil); err == nil {
+		t.Error("ReplaceDependencies() of a failed insert = nil")
+	}
+
+	if err := ReplaceDependencies(context.Background(), db, "unknown; DROP TABLE scores", "curl", nil); !errors.Is(err, sqlutil.ErrUnknownTable) {
+		t.Errorf("ReplaceDependencies() of an unknown table = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
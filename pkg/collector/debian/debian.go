@@ -1,18 +1,25 @@
 package debian
 
 import (
-	"bufio"
 	"compress/gzip"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
-	"os"
 	"regexp"
 	"sort"
 	"strings"
-
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/collector"
+	"github.com/HUSTSecLab/criticality_score/pkg/collector/mirror"
+	"github.com/HUSTSecLab/criticality_score/pkg/failure"
+	"github.com/HUSTSecLab/criticality_score/pkg/graph"
+	"github.com/HUSTSecLab/criticality_score/pkg/maintainer"
+	"github.com/HUSTSecLab/criticality_score/pkg/progress"
+	"github.com/HUSTSecLab/criticality_score/pkg/sampling"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
-	"github.com/lib/pq"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
 )
 
 var cacheDir = "/tmp/cloc-debian-cache"
@@ -49,29 +56,19 @@ func (dc *DebianCollector) updateOrInsertDatabase(pkgInfoMap map[string]PackageI
 	if err != nil {
 		return err
 	}
-	defer db.Close()
 
+	pkgs := make([]collector.Package, 0, len(pkgInfoMap))
 	for pkgName, pkgInfo := range pkgInfoMap {
-		var exists bool
-		err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM debian_packages WHERE package = $1)", pkgName).Scan(&exists)
-		if err != nil {
-			return err
-		}
-		if !exists {
-			_, err := db.Exec("INSERT INTO debian_packages (package, depends_count, description, homepage, version, page_rank) VALUES ($1, $2, $3, $4, $5, $6)",
-				pkgName, pkgInfo.DependsCount, pkgInfo.Description, pkgInfo.Homepage, pkgInfo.Version, pkgInfo.PageRank)
-			if err != nil {
-				return err
-			}
-		} else {
-			_, err := db.Exec("UPDATE debian_packages SET depends_count = $1, description = $2, homepage = $3, version = $4, page_rank = $5 WHERE package = $6",
-				pkgInfo.DependsCount, pkgInfo.Description, pkgInfo.Homepage, pkgInfo.Version, pkgInfo.PageRank, pkgName)
-			if err != nil {
-				return err
-			}
-		}
+		pkgs = append(pkgs, collector.Package{
+			Name:         pkgName,
+			Version:      pkgInfo.Version,
+			Description:  pkgInfo.Description,
+			Homepage:     pkgInfo.Homepage,
+			DependsCount: pkgInfo.DependsCount,
+			PageRank:     pkgInfo.PageRank,
+		})
 	}
-	return nil
+	return collector.UpsertPackages(context.Background(), db, "debian_packages", pkgs, true, 0)
 }
 
 func (dc *DebianCollector) storeDependenciesInDatabase(pkgName string, dependencies []DepInfo) error {
@@ -79,40 +76,56 @@ func (dc *DebianCollector) storeDependenciesInDatabase(pkgName string, dependenc
 	if err != nil {
 		return err
 	}
-	defer db.Close()
 
+	names := make([]string, 0, len(dependencies))
 	for _, dep := range dependencies {
-		_, err := db.Exec("INSERT INTO debian_relationships (frompackage, topackage) VALUES ($1, $2)", pkgName, dep.Name)
-		if err != nil {
-			return err
+		if dep.Name != "" {
+			names = append(names, dep.Name)
 		}
 	}
-	return nil
+	return collector.ReplaceDependencies(context.Background(), db, "debian_relationships", pkgName, names)
 }
 
-func (dc *DebianCollector) getMirrorFile(path string) []byte {
-	resp, _ := http.Get("https://mirrors.hust.edu.cn/debian/" + path)
+func (dc *DebianCollector) getMirrorFile(path string) ([]byte, error) {
+	resp, err := http.Get(mirror.Default().URL("debian", path))
+	if err != nil {
+		return nil, err
+	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get %s: %s", path, resp.Status)
+	}
 
-	body, _ := ioutil.ReadAll(resp.Body)
-	return body
+	return ioutil.ReadAll(resp.Body)
 }
 
-func (dc *DebianCollector) getDecompressedFile(path string) string {
-	file := dc.getMirrorFile(path)
-	reader, _ := gzip.NewReader(strings.NewReader(string(file)))
+func (dc *DebianCollector) getDecompressedFile(path string) (string, error) {
+	file, err := dc.getMirrorFile(path)
+	if err != nil {
+		return "", err
+	}
+	reader, err := gzip.NewReader(strings.NewReader(string(file)))
+	if err != nil {
+		return "", fmt.Errorf("decompress %s: %w", path, err)
+	}
 	defer reader.Close()
 
-	decompressed, _ := ioutil.ReadAll(reader)
-	return string(decompressed)
+	decompressed, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return "", fmt.Errorf("decompress %s: %w", path, err)
+	}
+	return string(decompressed), nil
 }
 
-func (dc *DebianCollector) getPackageList() string {
+func (dc *DebianCollector) getPackageList() (string, error) {
 	return dc.getDecompressedFile("dists/stable/main/binary-amd64/Packages.gz")
 }
 
-func (dc *DebianCollector) parseList() {
-	content := dc.getPackageList()
+func (dc *DebianCollector) parseList() error {
+	content, err := dc.getPackageList()
+	if err != nil {
+		return err
+	}
 	lists := strings.Split(content, "\n\n")
 
 	for _, packageStr := range lists {
@@ -155,6 +168,7 @@ func (dc *DebianCollector) parseList() {
 			dc.packages[packageName] = pkg
 		}
 	}
+	return nil
 }
 
 func (dc *DebianCollector) toDep(dep string, rawContent string) DepInfo {
@@ -187,111 +201,62 @@ func (dc *DebianCollector) toDep(dep string, rawContent string) DepInfo {
 	return depInfo
 }
 
-func (dc *DebianCollector) generateDependencyGraph(outputPath string) error {
-	file, err := os.Create(outputPath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	writer := bufio.NewWriter(file)
-	writer.WriteString("digraph {\n")
-
-	packageIndices := make(map[string]int)
-	index := 0
-
-	for pkgName, pkgInfo := range dc.packages {
-		packageIndices[pkgName] = index
-		label := fmt.Sprintf("%s@%s", pkgName, pkgInfo["Version"].(string))
-		writer.WriteString(fmt.Sprintf("  %d [label=\"%s\"];\n", index, label))
-		index++
-	}
-
+func (dc *DebianCollector) generateDependencyGraph(pkgInfoMap map[string]PackageInfo, outputPath string) error {
+	export := graph.NewExport()
 	for pkgName, pkgInfo := range dc.packages {
-		pkgIndex := packageIndices[pkgName]
+		export.AddNode(graph.ExportNode{
+			Name:         pkgName,
+			Label:        fmt.Sprintf("%s@%s", pkgName, pkgInfo["Version"].(string)),
+			Version:      pkgInfoMap[pkgName].Version,
+			PageRank:     pkgInfoMap[pkgName].PageRank,
+			DependsCount: pkgInfoMap[pkgName].DependsCount,
+		})
 		if depends, ok := pkgInfo["Depends"].([]interface{}); ok {
 			for _, depInterface := range depends {
 				if depInfo, ok := depInterface.(DepInfo); ok {
-					if depIndex, ok := packageIndices[depInfo.Name]; ok {
-						writer.WriteString(fmt.Sprintf("  %d -> %d [label=\"%s\"];\n", pkgIndex, depIndex, depInfo.Version))
-					}
+					export.AddEdge(pkgName, depInfo.Name, depInfo.Version)
 				}
 			}
 		}
 	}
-
-	writer.WriteString("}\n")
-	writer.Flush()
-	return nil
+	return export.WriteFile(outputPath)
 }
 
-func (dc *DebianCollector) getAllDep(pkgName string, deps []string) []string {
-	deps = append(deps, pkgName)
-	if pkg, ok := dc.packages[pkgName]; ok {
-		if depends, ok := pkg["Depends"].([]interface{}); ok {
-			for _, depInterface := range depends {
-				if depMap, ok := depInterface.(DepInfo); ok {
-					pkgname := depMap.Name
-					if !contains(deps, pkgname) {
-						deps = dc.getAllDep(pkgname, deps)
-					}
-				}
+// directDeps returns the names of the direct dependencies of the package.
+func (dc *DebianCollector) directDeps(pkgName string) []string {
+	var deps []string
+	if depends, ok := dc.packages[pkgName]["Depends"].([]interface{}); ok {
+		for _, depInterface := range depends {
+			if depInfo, ok := depInterface.(DepInfo); ok {
+				deps = append(deps, depInfo.Name)
 			}
 		}
 	}
 	return deps
 }
 
-func (dc *DebianCollector) rankPage(maxIterations int, dampingFactor float64) map[string]float64 {
-	rank := make(map[string]float64)
-	N := len(dc.packages)
-	for pkgName := range dc.packages {
-		rank[pkgName] = 1.0 / float64(N)
-	}
-
-	for i := 0; i < maxIterations; i++ {
-		newRank := make(map[string]float64)
-		for pkgName := range dc.packages {
-			newRank[pkgName] = (1 - dampingFactor) / float64(N)
+// maintainers returns the Maintainer and Uploaders of the packages.
+func (dc *DebianCollector) maintainers() map[string][]maintainer.Contact {
+	ret := make(map[string][]maintainer.Contact, len(dc.packages))
+	for pkgName, pkg := range dc.packages {
+		var contacts []maintainer.Contact
+		if s, ok := pkg["Maintainer"].(string); ok {
+			contacts = append(contacts, maintainer.ParseAddresses(s, maintainer.RoleMaintainer)...)
 		}
-
-		for pkgName, pkgInfo := range dc.packages {
-			if depends, ok := pkgInfo["Depends"].([]interface{}); ok {
-				var depNum int
-				for _, depInterface := range depends {
-					if depInfo, ok := depInterface.(DepInfo); ok {
-						if _, ok := dc.packages[depInfo.Name]; ok {
-							depNum++
-						}
-					}
-				}
-				for _, depInterface := range depends {
-					if depInfo, ok := depInterface.(DepInfo); ok {
-						if _, ok := dc.packages[depInfo.Name]; ok {
-							newRank[depInfo.Name] += dampingFactor * rank[pkgName] / float64(depNum)
-						}
-					}
-				}
-			}
-		}
-
-		rank = newRank
-	}
-	return rank
-}
-
-func contains(slice []string, item string) bool {
-	for _, a := range slice {
-		if a == item {
-			return true
+		if s, ok := pkg["Uploaders"].(string); ok {
+			contacts = append(contacts, maintainer.ParseAddresses(s, maintainer.RoleUploader)...)
 		}
+		ret[pkgName] = contacts
 	}
-	return false
+	return ret
 }
 
-func (dc *DebianCollector) Collect(outputPath string) {
+func (dc *DebianCollector) Collect(tracker *failure.Tracker, outputPath string) error {
 	fmt.Println("Getting package list...")
-	dc.parseList()
+	if err := dc.parseList(); err != nil {
+		return fmt.Errorf("error getting package list: %w", err)
+	}
+	dc.packages = sampling.Map(dc.packages)
 	fmt.Printf("Done, total: %d packages.\n", len(dc.packages))
 	fmt.Println("Building dependencies graph...")
 
@@ -301,20 +266,14 @@ func (dc *DebianCollector) Collect(outputPath string) {
 	}
 	sort.Strings(keys)
 
-	depMap := make(map[string][]string)
+	depMap := make(map[string][]string, len(keys))
 	for _, pkgName := range keys {
-		deps := dc.getAllDep(pkgName, []string{})
-		depMap[pkgName] = deps
+		depMap[pkgName] = dc.directDeps(pkgName)
 	}
 	fmt.Println("Calculating dependencies count...")
-	countMap := make(map[string]int)
-	for _, deps := range depMap {
-		for _, dep := range deps {
-			countMap[dep]++
-		}
-	}
+	countMap := collector.DependsCounts(depMap)
 
-	pagerank := dc.rankPage(20, 0.85)
+	pagerank := collector.RankDependencies("debian", depMap)
 
 	pkgInfoMap := make(map[string]PackageInfo)
 
@@ -347,12 +306,19 @@ func (dc *DebianCollector) Collect(outputPath string) {
 		}
 	}
 
+	release := collector.AcquireWrite()
+	defer release()
 	err := dc.updateOrInsertDatabase(pkgInfoMap)
 	if err != nil {
-		fmt.Printf("Error updating database: %v\n", err)
-		return
+		return fmt.Errorf("error updating database: %w", err)
+	}
+	maintainerRepo := repository.NewMaintainerRepository(storage.GetDefaultAppDatabaseContext())
+	if err := maintainer.Save(maintainerRepo, repository.DistLinkTablePrefixDebian, dc.maintainers(), time.Now()); err != nil {
+		return fmt.Errorf("error updating maintainers: %w", err)
 	}
+	relPhase := progress.Default().Phase("relationships", len(dc.packages))
 	for _, pkgInfo := range dc.packages {
+		relPhase.Add(1)
 		if packageName, ok := pkgInfo["Package"].(string); ok {
 			if depends, ok := pkgInfo["Depends"].([]interface{}); ok {
 				dependencies := make([]DepInfo, len(depends))
@@ -362,30 +328,25 @@ func (dc *DebianCollector) Collect(outputPath string) {
 					}
 				}
 				if err := dc.storeDependenciesInDatabase(packageName, dependencies); err != nil {
-					if isUniqueViolation(err) {
-						continue
-					}
 					fmt.Printf("Error storing dependencies for package %s: %v\n", packageName, err)
-					return
+					tracker.Fail(packageName, err)
+					continue
 				}
+				tracker.Success()
 			}
 		}
 	}
+	relPhase.Done()
+	release()
 	fmt.Println("Database updated successfully.")
 
 	if outputPath != "" {
-		err := dc.generateDependencyGraph(outputPath)
+		err := dc.generateDependencyGraph(pkgInfoMap, outputPath)
 		if err != nil {
 			fmt.Printf("Error generating dependency graph: %v\n", err)
-			return
+			return nil
 		}
 		fmt.Println("Dependency graph generated successfully.")
 	}
-}
-
-func isUniqueViolation(err error) bool {
-	if pqErr, ok := err.(*pq.Error); ok {
-		return pqErr.Code == "23505"
-	}
-	return false
+	return nil
 }
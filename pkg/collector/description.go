@@ -0,0 +1,40 @@
+package collector
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxDescriptionBytes is the max size of a description saved by collectors,
+// longer ones are truncated at a rune boundary.
+const MaxDescriptionBytes = 1024
+
+// Description returns the description s as it is saved: invalid UTF-8 is
+// replaced by U+FFFD, NUL bytes, which postgres does not store, are
+// dropped, it is in Unicode normalization form C and truncated to
+// MaxDescriptionBytes without splitting runes, so CJK descriptions are
+// kept intact.
+func Description(s string) string {
+	s = strings.ToValidUTF8(s, "\uFFFD")
+	s = strings.ReplaceAll(s, "\x00", "")
+	s = norm.NFC.String(strings.TrimSpace(s))
+	return Truncate(s, MaxDescriptionBytes)
+}
+
+// Truncate returns the longest prefix of s of at most n bytes which does
+// not split a rune.
+func Truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	if n <= 0 {
+		return ""
+	}
+	// back off to the start of the rune at n
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,41 @@
+package collector
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncate(t *testing.T) {
+	s := "压缩库 zlib"
+	for n := 0; n <= len(s)+1; n++ {
+		got := Truncate(s, n)
+		if len(got) > n || !utf8.ValidString(got) || !strings.HasPrefix(s, got) {
+			t.Errorf("Truncate(%q, %d) = %q", s, n, got)
+		}
+	}
+	if got := Truncate(s, 7); got != "压缩" {
+		t.Errorf("Truncate(%q, 7) = %q, want %q", s, got, "压缩")
+	}
+}
+
+func TestDescription(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"  日本語の説明 ", "日本語の説明"},
+		// decomposed e and combining acute accent are composed
+		{"cafe\u0301", "caf\u00e9"},
+		{"bad \xff byte\x00", "bad \ufffd byte"},
+	}
+	for _, tt := range tests {
+		if got := Description(tt.in); got != tt.want {
+			t.Errorf("Description(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	long := strings.Repeat("中", MaxDescriptionBytes)
+	if got := Description(long); len(got) > MaxDescriptionBytes || !utf8.ValidString(got) {
+		t.Errorf("Description() of a long description is %d bytes, valid %v", len(got), utf8.ValidString(got))
+	}
+}
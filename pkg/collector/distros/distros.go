@@ -0,0 +1,89 @@
+// Package distros runs the collector of a distribution by its name, it is
+// shared by dist-packages-collector, which runs one collector, and the
+// collect-all orchestrator, which runs many of them in parallel.
+package distros
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/collector/alpine"
+	"github.com/HUSTSecLab/criticality_score/pkg/collector/archlinux"
+	"github.com/HUSTSecLab/criticality_score/pkg/collector/aur"
+	"github.com/HUSTSecLab/criticality_score/pkg/collector/centos"
+	"github.com/HUSTSecLab/criticality_score/pkg/collector/debian"
+	"github.com/HUSTSecLab/criticality_score/pkg/collector/deepin"
+	"github.com/HUSTSecLab/criticality_score/pkg/collector/fedora"
+	"github.com/HUSTSecLab/criticality_score/pkg/collector/gentoo"
+	"github.com/HUSTSecLab/criticality_score/pkg/collector/homebrew"
+	"github.com/HUSTSecLab/criticality_score/pkg/collector/nix"
+	"github.com/HUSTSecLab/criticality_score/pkg/collector/ubuntu"
+	"github.com/HUSTSecLab/criticality_score/pkg/failure"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+)
+
+// ErrUnknown is returned for names which are not in Names.
+var ErrUnknown = errors.New("unknown distribution")
+
+// Names are the names of the distributions which can be collected.
+var Names = []string{
+	"alpine", "archlinux", "aur", "centos", "debian", "deepin",
+	"fedora", "gentoo", "homebrew", "nix", "ubuntu",
+}
+
+// Prefix returns the table prefix of the packages of the distribution name.
+func Prefix(name string) (repository.DistPackageTablePrefix, bool) {
+	if name == "archlinux" {
+		name = "arch"
+	}
+	return repository.ParseDistPackageTablePrefix(name)
+}
+
+// Options are the options of the collectors, not every collector uses all
+// of them.
+type Options struct {
+	// GenDot is the output graph file, in GraphML format if it ends with
+	// .graphml, otherwise DOT, no graph is written if empty
+	GenDot string
+	// DownloadDir and ExtractDir are the working directories of archlinux
+	DownloadDir string
+	ExtractDir  string
+	// Workers and BatchSize are used by nix
+	Workers   int
+	BatchSize int
+}
+
+// Collect collects the packages of the distribution name. Packages which
+// fail are recorded in tracker, a collector which cannot go on returns its
+// error, as do invalid names and options.
+func Collect(name string, tracker *failure.Tracker, opts Options) error {
+	switch name {
+	case "archlinux":
+		return archlinux.NewArchLinux(opts.DownloadDir, opts.ExtractDir).Collect(tracker, opts.GenDot)
+	case "debian":
+		return debian.NewDebianCollector().Collect(tracker, opts.GenDot)
+	case "deepin":
+		return deepin.NewDeepinCollector().Collect(tracker, opts.GenDot)
+	case "ubuntu":
+		return ubuntu.NewUbuntuCollector().Collect(tracker, opts.GenDot)
+	case "nix":
+		if opts.GenDot != "" {
+			return errors.New("nix does not support gendot")
+		}
+		return nix.NewNixCollector().Collect(tracker, opts.Workers, opts.BatchSize)
+	case "homebrew":
+		return homebrew.NewHomebrewCollector().Collect(tracker, opts.GenDot)
+	case "gentoo":
+		return gentoo.NewGentooCollector().Collect(tracker, opts.GenDot)
+	case "fedora":
+		return fedora.NewFedoraCollector().Collect(tracker, opts.GenDot)
+	case "centos":
+		return centos.NewCentosCollector().Collect(tracker, opts.GenDot)
+	case "alpine":
+		return alpine.NewAlpineCollector().Collect(tracker, opts.GenDot)
+	case "aur":
+		return aur.NewAurCollector().Collect(tracker, opts.GenDot)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknown, name)
+	}
+}
@@ -1,115 +1,75 @@
 package fedora
 
 import (
-	"bufio"
 	"bytes"
 	"compress/gzip"
+	"context"
 	"encoding/xml"
 	"fmt"
 	"io"
-	"io/ioutil"
-	"log"
 	"net/http"
-	"os"
 	"strings"
 
-	"github.com/lib/pq"
-
+	"github.com/HUSTSecLab/criticality_score/pkg/collector"
+	"github.com/HUSTSecLab/criticality_score/pkg/collector/mirror"
+	"github.com/HUSTSecLab/criticality_score/pkg/failure"
+	"github.com/HUSTSecLab/criticality_score/pkg/graph"
+	"github.com/HUSTSecLab/criticality_score/pkg/maintainer"
+	"github.com/HUSTSecLab/criticality_score/pkg/progress"
+	"github.com/HUSTSecLab/criticality_score/pkg/sampling"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
 )
 
-type PackageInfo struct {
-	Depends      []string
-	DependsCount int
-	Description  string
-	GitRepo      string
-	Homepage     string
-	Name         string
-	PageRank     float64
-	URL          string
-	Version      string
-}
-
-type FedoraCollector struct {
-	URL        string
-	PkgInfoMap map[string]PackageInfo
+// Options are the options of Collect.
+type Options struct {
+	// URL is the url of the gzipped primary.xml of the repository
+	URL string
+	// Client fetches the primary.xml, http.DefaultClient if nil
+	Client *http.Client
+	// Sampler samples the packages before ranking, nil keeps all packages
+	Sampler *sampling.Sampler
+	// PageRank are the parameters of PageRank, unset ones are those of
+	// graph.DefaultPageRankParams
+	PageRank graph.PageRankParams
 }
 
-func NewFedoraCollector() *FedoraCollector {
-	return &FedoraCollector{
-		URL:        "https://mirrors.aliyun.com/fedora/releases/41/Everything/source/tree/repodata/df7750a80c5a4e4ff04ff5a1a499d32b6379dd50680b29140638e6edb1d71d68-primary.xml.gz",
-		PkgInfoMap: make(map[string]PackageInfo),
+func DefaultOptions() Options {
+	return Options{
+		URL: mirror.Default().URL("fedora", "releases/41/Everything/source/tree/repodata/df7750a80c5a4e4ff04ff5a1a499d32b6379dd50680b29140638e6edb1d71d68-primary.xml.gz"),
 	}
 }
 
-func (fc *FedoraCollector) Collect(outputPath string) {
-	err := fc.getDependencies()
+// Collect returns the ranked packages of the primary.xml of the repository.
+func Collect(ctx context.Context, opts Options) ([]collector.Package, error) {
+	phase := progress.Default().Phase("download", 1)
+	body, err := collector.Get(ctx, opts.Client, opts.URL)
 	if err != nil {
-		log.Fatal(err)
-	}
-
-	depMap := make(map[string][]string)
-	for pkgName := range fc.PkgInfoMap {
-		visited := make(map[string]bool)
-		deps := fc.getAllDep(pkgName, visited, []string{})
-		depMap[pkgName] = deps
-	}
-
-	countMap := make(map[string]int)
-	for _, deps := range depMap {
-		for _, dep := range deps {
-			countMap[dep]++
-		}
-	}
-
-	pagerank := fc.calculatePageRank(20, 0.85)
-
-	for pkgName, pkgInfo := range fc.PkgInfoMap {
-		pagerankVal := pagerank[pkgName]
-		depCount := countMap[pkgName]
-		pkgInfo.PageRank = pagerankVal
-		pkgInfo.DependsCount = depCount
-		fc.PkgInfoMap[pkgName] = pkgInfo
+		return nil, err
 	}
-	err = fc.updateOrInsertDatabase()
+	phase.Add(1)
+	phase.Done()
+	data, err := decompressGzip(body)
 	if err != nil {
-		fmt.Printf("Error updating database: %v\n", err)
-		return
+		return nil, err
 	}
-	for pkgName, pkgInfo := range fc.PkgInfoMap {
-		if err := fc.storeDependenciesInDatabase(pkgName, pkgInfo.Depends); err != nil {
-			if isUniqueViolation(err) {
-				continue
-			}
-			fmt.Printf("Error storing dependencies for package %s: %v\n", pkgName, err)
-		}
+	pkgs, err := ParsePrimary(data)
+	if err != nil {
+		return nil, err
 	}
-	fmt.Println("Database updated successfully.")
 
-	if outputPath != "" {
-		err := fc.generateDependencyGraph(outputPath)
-		if err != nil {
-			fmt.Printf("Error generating dependency graph: %v\n", err)
-			return
-		}
-		fmt.Println("Dependency graph generated successfully.")
+	pkgMap := make(map[string]collector.Package, len(pkgs))
+	for _, pkg := range pkgs {
+		pkgMap[pkg.Name] = pkg
 	}
-}
+	pkgMap = sampling.MapFunc(opts.Sampler, pkgMap, func(s string) string { return s })
 
-func (fc *FedoraCollector) getAllDep(pkgName string, visited map[string]bool, deps []string) []string {
-	if visited[pkgName] {
-		return deps
+	ret := make([]collector.Package, 0, len(pkgMap))
+	for _, pkg := range pkgMap {
+		ret = append(ret, pkg)
 	}
-
-	visited[pkgName] = true
-	deps = append(deps, pkgName)
-
-	if pkg, ok := fc.PkgInfoMap[pkgName]; ok {
-		for _, depName := range pkg.Depends {
-			deps = fc.getAllDep(depName, visited, deps)
-		}
-	}
-	return deps
+	collector.Rank(ret, opts.PageRank)
+	return ret, nil
 }
 
 func decompressGzip(data []byte) (string, error) {
@@ -119,7 +79,7 @@ func decompressGzip(data []byte) (string, error) {
 	}
 	defer reader.Close()
 
-	uncompressedData, err := ioutil.ReadAll(reader)
+	uncompressedData, err := io.ReadAll(reader)
 	if err != nil {
 		return "", err
 	}
@@ -127,21 +87,12 @@ func decompressGzip(data []byte) (string, error) {
 	return string(uncompressedData), nil
 }
 
-func (fc *FedoraCollector) getDependencies() error {
-	resp, err := http.Get(fc.URL)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
+// ParsePrimary returns the rpm packages of an uncompressed primary.xml, the
+// first package of a name is kept.
+func ParsePrimary(data string) ([]collector.Package, error) {
+	var ret []collector.Package
+	seen := make(map[string]bool)
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return err
-	}
-	data, err := decompressGzip(body)
-	if err != nil {
-		return err
-	}
 	data = strings.Replace(data, "\x00", "", -1)
 	decoder := xml.NewDecoder(strings.NewReader(data))
 	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
@@ -156,51 +107,47 @@ func (fc *FedoraCollector) getDependencies() error {
 			if err == io.EOF {
 				break
 			}
-			return err
+			return nil, err
 		}
 
-		switch se := tok.(type) {
-		case xml.StartElement:
-			if se.Name.Local == "package" {
-				var pkgData struct {
-					Type string `xml:"type,attr"`
-					XML  string `xml:",innerxml"`
-				}
-				err := decoder.DecodeElement(&pkgData, &se)
-				if err != nil {
-					return err
-				}
-
-				if pkgData.Type == "rpm" {
-					lines := strings.Split(pkgData.XML, "\n")
-					for i, line := range lines {
-						if len(line) > 2 {
-							lines[i] = line[2:]
-						}
-					}
-					trimmedXML := strings.Join(lines, "\n")
-					pkgInfo, err := parsePackageXML(trimmedXML[1:])
-					if err != nil {
-						return err
-					}
+		se, ok := tok.(xml.StartElement)
+		if !ok || se.Name.Local != "package" {
+			continue
+		}
+		var pkgData struct {
+			Type string `xml:"type,attr"`
+			XML  string `xml:",innerxml"`
+		}
+		if err := decoder.DecodeElement(&pkgData, &se); err != nil {
+			return nil, err
+		}
+		if pkgData.Type != "rpm" {
+			continue
+		}
 
-					if _, exists := fc.PkgInfoMap[pkgInfo.Name]; !exists {
-						fc.PkgInfoMap[pkgInfo.Name] = pkgInfo
-					}
-				}
+		lines := strings.Split(pkgData.XML, "\n")
+		for i, line := range lines {
+			if len(line) > 2 {
+				lines[i] = line[2:]
 			}
 		}
+		trimmedXML := strings.Join(lines, "\n")
+		pkgInfo, err := parsePackageXML(trimmedXML[1:])
+		if err != nil {
+			return nil, err
+		}
+		if !seen[pkgInfo.Name] {
+			seen[pkgInfo.Name] = true
+			ret = append(ret, pkgInfo)
+		}
 	}
-	return nil
+	return ret, nil
 }
 
-func parsePackageXML(data string) (PackageInfo, error) {
-	data = strings.Map(func(r rune) rune {
-		if r == '\x00' || r > 127 {
-			return -1
-		}
-		return r
-	}, data)
+func parsePackageXML(data string) (collector.Package, error) {
+	// NUL is not valid in XML, other runes are kept, descriptions are
+	// normalized when saved
+	data = strings.ReplaceAll(data, "\x00", "")
 	decoder := xml.NewDecoder(strings.NewReader(data))
 	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
 		if charset == "utf-8" {
@@ -208,7 +155,7 @@ func parsePackageXML(data string) (PackageInfo, error) {
 		}
 		return nil, fmt.Errorf("unsupported charset: %s", charset)
 	}
-	var pkgInfo PackageInfo
+	var pkgInfo collector.Package
 	var depends []string
 
 	for {
@@ -217,7 +164,7 @@ func parsePackageXML(data string) (PackageInfo, error) {
 			if err == io.EOF {
 				break
 			}
-			return PackageInfo{}, err
+			return collector.Package{}, err
 		}
 
 		switch se := tok.(type) {
@@ -226,24 +173,27 @@ func parsePackageXML(data string) (PackageInfo, error) {
 			case "name":
 				var name string
 				if err := decoder.DecodeElement(&name, &se); err != nil {
-					return PackageInfo{}, err
+					return collector.Package{}, err
 				}
 				pkgInfo.Name = name
 			case "description":
 				var description string
 				if err := decoder.DecodeElement(&description, &se); err != nil {
-					return PackageInfo{}, err
-				}
-				if len(description) > 255 {
-					description = description[:255]
+					return collector.Package{}, err
 				}
 				pkgInfo.Description = description
+			case "packager":
+				var packager string
+				if err := decoder.DecodeElement(&packager, &se); err != nil {
+					return collector.Package{}, err
+				}
+				pkgInfo.Maintainers = maintainer.ParseAddresses(packager, maintainer.RolePackager)
 			case "url":
 				var url string
 				if err := decoder.DecodeElement(&url, &se); err != nil {
-					return PackageInfo{}, err
+					return collector.Package{}, err
 				}
-				pkgInfo.URL = url
+				pkgInfo.Homepage = url
 			case "version":
 				var version struct {
 					Epoch string `xml:"epoch,attr"`
@@ -251,7 +201,7 @@ func parsePackageXML(data string) (PackageInfo, error) {
 					Rel   string `xml:"rel,attr"`
 				}
 				if err := decoder.DecodeElement(&version, &se); err != nil {
-					return PackageInfo{}, err
+					return collector.Package{}, err
 				}
 				pkgInfo.Version = fmt.Sprintf("%s:%s-%s", version.Epoch, version.Ver, version.Rel)
 			case "entry":
@@ -259,7 +209,7 @@ func parsePackageXML(data string) (PackageInfo, error) {
 					Name string `xml:"name,attr"`
 				}
 				if err := decoder.DecodeElement(&entry, &se); err != nil {
-					return PackageInfo{}, err
+					return collector.Package{}, err
 				}
 				depends = append(depends, entry.Name)
 			}
@@ -270,122 +220,37 @@ func parsePackageXML(data string) (PackageInfo, error) {
 	return pkgInfo, nil
 }
 
-func (fc *FedoraCollector) calculatePageRank(iterations int, dampingFactor float64) map[string]float64 {
-	pageRank := make(map[string]float64)
-	numPackages := len(fc.PkgInfoMap)
-
-	for pkgName := range fc.PkgInfoMap {
-		pageRank[pkgName] = 1.0 / float64(numPackages)
-	}
-
-	for i := 0; i < iterations; i++ {
-		newPageRank := make(map[string]float64)
-
-		for pkgName := range fc.PkgInfoMap {
-			newPageRank[pkgName] = (1 - dampingFactor) / float64(numPackages)
-		}
-
-		for pkgName, pkgInfo := range fc.PkgInfoMap {
-			var depNum int
-			for _, depName := range pkgInfo.Depends {
-				if _, exists := fc.PkgInfoMap[depName]; exists {
-					depNum++
-				}
-			}
-			for _, depName := range pkgInfo.Depends {
-				if _, exists := fc.PkgInfoMap[depName]; exists {
-					newPageRank[depName] += dampingFactor * (pageRank[pkgName] / float64(depNum))
-				}
-			}
-		}
-		pageRank = newPageRank
-	}
-	return pageRank
-}
-
-func isUniqueViolation(err error) bool {
-	if pqErr, ok := err.(*pq.Error); ok {
-		return pqErr.Code == "23505"
-	}
-	return false
-}
-
-func (fc *FedoraCollector) updateOrInsertDatabase() error {
-	db, err := storage.GetDefaultAppDatabaseContext().GetDatabaseConnection()
-	if err != nil {
-		return err
-	}
-	defer db.Close()
-
-	for pkgName, pkgInfo := range fc.PkgInfoMap {
-		var exists bool
-		err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM fedora_packages WHERE package = $1)", pkgName).Scan(&exists)
-		if err != nil {
-			return err
-		}
-		if !exists {
-			_, err := db.Exec("INSERT INTO fedora_packages (package, depends_count, description, homepage, page_rank, version) VALUES ($1, $2, $3, $4, $5, $6)",
-				pkgName, pkgInfo.DependsCount, pkgInfo.Description, pkgInfo.URL, pkgInfo.PageRank, pkgInfo.Version)
-			if err != nil {
-				return err
-			}
-		} else {
-			_, err := db.Exec("UPDATE fedora_packages SET depends_count = $1, description = $2, homepage = $3, page_rank = $4, version = $5 WHERE package = $6",
-				pkgInfo.DependsCount, pkgInfo.Description, pkgInfo.URL, pkgInfo.PageRank, pkgInfo.Version, pkgName)
-			if err != nil {
-				return err
-			}
-		}
-	}
-	return nil
+type FedoraCollector struct {
+	URL string
 }
 
-func (fc *FedoraCollector) storeDependenciesInDatabase(pkgName string, dependencies []string) error {
-	db, err := storage.GetDefaultAppDatabaseContext().GetDatabaseConnection()
-	if err != nil {
-		return err
-	}
-	defer db.Close()
-
-	for _, dep := range dependencies {
-		_, err := db.Exec("INSERT INTO fedora_relationships (frompackage, topackage) VALUES ($1, $2)", pkgName, dep)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+func NewFedoraCollector() *FedoraCollector {
+	return &FedoraCollector{URL: DefaultOptions().URL}
 }
 
-func (fc *FedoraCollector) generateDependencyGraph(outputPath string) error {
-	file, err := os.Create(outputPath)
+// Collect saves the packages to the database, and writes the dependency
+// graph to outputPath if it is not empty. Packages whose dependencies
+// cannot be saved are failed in tracker, other errors are returned.
+func (fc *FedoraCollector) Collect(tracker *failure.Tracker, outputPath string) error {
+	ctx := context.Background()
+	pageRank := collector.PageRankParams("fedora")
+	pkgs, err := Collect(ctx, Options{URL: fc.URL, Sampler: sampling.Default(), PageRank: pageRank})
 	if err != nil {
 		return err
 	}
-	defer file.Close()
-
-	writer := bufio.NewWriter(file)
-	writer.WriteString("digraph {\n")
-
-	packageIndices := make(map[string]int)
-	index := 0
-
-	for pkgName, pkgInfo := range fc.PkgInfoMap {
-		packageIndices[pkgName] = index
-		label := fmt.Sprintf("%s@%s", pkgName, pkgInfo.Description)
-		writer.WriteString(fmt.Sprintf("  %d [label=\"%s\"];\n", index, label))
-		index++
+	store := collector.NewDBStore(storage.GetDefaultAppDatabaseContext(), repository.DistLinkTablePrefixFedora, tracker)
+	if err := store.Save(ctx, pkgs); err != nil {
+		return fmt.Errorf("error updating database: %w", err)
 	}
+	collector.RecordRank(storage.GetDefaultAppDatabaseContext(), "fedora", pageRank, len(pkgs))
+	fmt.Println("Database updated successfully.")
 
-	for pkgName, pkgInfo := range fc.PkgInfoMap {
-		pkgIndex := packageIndices[pkgName]
-		for _, depName := range pkgInfo.Depends {
-			if depIndex, ok := packageIndices[depName]; ok {
-				writer.WriteString(fmt.Sprintf("  %d -> %d;\n", pkgIndex, depIndex))
-			}
+	if outputPath != "" {
+		if err := collector.WriteGraph(pkgs, outputPath); err != nil {
+			fmt.Printf("Error generating dependency graph: %v\n", err)
+			return nil
 		}
+		fmt.Println("Dependency graph generated successfully.")
 	}
-
-	writer.WriteString("}\n")
-	writer.Flush()
 	return nil
 }
@@ -2,6 +2,7 @@ package gentoo
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -9,8 +10,13 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/HUSTSecLab/criticality_score/pkg/collector"
+	"github.com/HUSTSecLab/criticality_score/pkg/failure"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitutil/normalize"
+	"github.com/HUSTSecLab/criticality_score/pkg/graph"
+	"github.com/HUSTSecLab/criticality_score/pkg/progress"
+	"github.com/HUSTSecLab/criticality_score/pkg/sampling"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
-	"github.com/lib/pq"
 )
 
 type PackageInfo struct {
@@ -41,15 +47,8 @@ func (hc *GentooCollector) storeDependenciesInDatabase(pkgName string, dependenc
 	if err != nil {
 		return err
 	}
-	defer db.Close()
 
-	for _, dep := range dependencies {
-		_, err := db.Exec("INSERT INTO gentoo_relationships (frompackage, topackage) VALUES ($1, $2)", pkgName, dep)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+	return collector.ReplaceDependencies(context.Background(), db, "gentoo_relationships", pkgName, dependencies)
 }
 
 func extractNameAndVersion(fileName string) (string, string) {
@@ -104,18 +103,11 @@ func (hc *GentooCollector) ParseEbuild(filePath string) (PackageInfo, error) {
 		} else if reURL.MatchString(line) {
 			matches := reURL.FindStringSubmatch(line)
 			pkgInfo.URL = matches[1]
-			if strings.HasPrefix(pkgInfo.URL, "https://github.com/") {
-				parts := strings.Split(pkgInfo.URL, "/")
-				if len(parts) >= 5 {
-					orgName := parts[3]
-					repoName := parts[4]
-					if strings.Contains(orgName, "${PN}") {
-						orgName = strings.Replace(orgName, "${PN}", pkgInfo.Name, -1)
-					}
-					if strings.Contains(repoName, "${PN}") {
-						repoName = strings.Replace(repoName, "${PN}", pkgInfo.Name, -1)
-					}
-					pkgInfo.GitRepo = fmt.Sprintf("https://github.com/%s/%s.git", orgName, repoName)
+			// repos on forges, or forge-less repos, e.g. a cgit snapshot of
+			// git.kernel.org
+			if fields := strings.Fields(strings.ReplaceAll(pkgInfo.URL, "${PN}", pkgInfo.Name)); len(fields) > 0 {
+				if repo, ok := normalize.Repo(fields[0]); ok {
+					pkgInfo.GitRepo = repo
 				}
 			}
 		}
@@ -193,37 +185,26 @@ func (hc *GentooCollector) UpdateOrInsertDatabase() error {
 	if err != nil {
 		return err
 	}
-	defer db.Close()
 
+	pkgs := make([]collector.Package, 0, len(hc.PkgInfoMap))
 	for pkgName, pkgInfo := range hc.PkgInfoMap {
-		var exists bool
-		err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM gentoo_packages WHERE package = $1)", pkgName).Scan(&exists)
-		if err != nil {
-			return err
-		}
-		if !exists {
-			_, err := db.Exec("INSERT INTO gentoo_packages (package, version, depends_count, description, homepage, page_rank) VALUES ($1, $2, $3, $4, $5, $6)",
-				pkgName, pkgInfo.Version, pkgInfo.DependsCount, pkgInfo.Description, pkgInfo.Homepage, pkgInfo.PageRank)
-			if err != nil {
-				return err
-			}
-		} else {
-			_, err := db.Exec("UPDATE gentoo_packages SET version = $1, depends_count = $2, description = $3, homepage = $4, page_rank = $5 WHERE package = $6",
-				pkgInfo.Version, pkgInfo.DependsCount, pkgInfo.Description, pkgInfo.Homepage, pkgInfo.PageRank, pkgName)
-			if err != nil {
-				return err
-			}
-		}
-	}
-	return nil
+		pkgs = append(pkgs, collector.Package{
+			Name:         pkgName,
+			Version:      pkgInfo.Version,
+			Description:  pkgInfo.Description,
+			Homepage:     pkgInfo.Homepage,
+			DependsCount: pkgInfo.DependsCount,
+			PageRank:     pkgInfo.PageRank,
+		})
+	}
+	return collector.UpsertPackages(context.Background(), db, "gentoo_packages", pkgs, true, 0)
 }
 
-func (hc *GentooCollector) Collect(outputPath string) {
+func (hc *GentooCollector) Collect(tracker *failure.Tracker, outputPath string) error {
 	baseDirectory := "gentoo"
 	err := cloneGentooRepo(baseDirectory)
 	if err != nil {
-		fmt.Printf("Error cloning Gentoo repository: %v\n", err)
-		return
+		return fmt.Errorf("error cloning Gentoo repository: %w", err)
 	}
 
 	cmd := exec.Command("emerge", "--sync")
@@ -233,27 +214,19 @@ func (hc *GentooCollector) Collect(outputPath string) {
 
 	err = hc.FetchAndParseEbuildFiles(baseDirectory)
 	if err != nil {
-		fmt.Printf("Error fetching package info: %v\n", err)
-		return
+		return fmt.Errorf("error fetching package info: %w", err)
 	}
 
+	hc.PkgInfoMap = sampling.Map(hc.PkgInfoMap)
 	fmt.Println("Fetched and parsed ebuild files successfully.")
 
-	depMap := make(map[string][]string)
-	for pkgName := range hc.PkgInfoMap {
-		visited := make(map[string]bool)
-		deps := getAllDep(hc.PkgInfoMap, pkgName, visited, []string{})
-		depMap[pkgName] = deps
-	}
-
-	countMap := make(map[string]int)
-	for _, deps := range depMap {
-		for _, dep := range deps {
-			countMap[dep]++
-		}
+	depMap := make(map[string][]string, len(hc.PkgInfoMap))
+	for pkgName, pkgInfo := range hc.PkgInfoMap {
+		depMap[pkgName] = pkgInfo.Depends
 	}
+	countMap := collector.DependsCounts(depMap)
 
-	pageRankMap := pageRank(hc.PkgInfoMap, 0.85, 20)
+	pageRankMap := collector.RankDependencies("gentoo", depMap)
 
 	for pkgName, pkgInfo := range hc.PkgInfoMap {
 		pkgInfo.PageRank = pageRankMap[pkgName]
@@ -262,30 +235,37 @@ func (hc *GentooCollector) Collect(outputPath string) {
 		hc.PkgInfoMap[pkgName] = pkgInfo
 	}
 
+	release := collector.AcquireWrite()
+	defer release()
 	err = hc.UpdateOrInsertDatabase()
 	if err != nil {
-		fmt.Printf("Error updating database: %v\n", err)
-		return
+		return fmt.Errorf("error updating database: %w", err)
 	}
+	relPhase := progress.Default().Phase("relationships", len(hc.PkgInfoMap))
 	for pkgName, pkgInfo := range hc.PkgInfoMap {
+		relPhase.Add(1)
 		fmt.Println("Storing dependencies for package", pkgName, pkgInfo.Depends)
 		if err := hc.storeDependenciesInDatabase(pkgName, pkgInfo.Depends); err != nil {
-			if isUniqueViolation(err) {
-				continue
-			}
-			fmt.Printf("Error storing dependencies for package %s: %v\n", pkgName, err)
+			err = fmt.Errorf("error storing dependencies for package %s: %w", pkgName, err)
+			progress.Default().Error(err)
+			tracker.Fail(pkgName, err)
+			continue
 		}
+		tracker.Success()
 	}
+	relPhase.Done()
+	release()
 	fmt.Println("Database updated successfully.")
 
 	if outputPath != "" {
 		err := generateDependencyGraph(hc.PkgInfoMap, outputPath)
 		if err != nil {
 			fmt.Printf("Error generating dependency graph: %v\n", err)
-			return
+			return nil
 		}
 		fmt.Println("Dependency graph generated successfully.")
 	}
+	return nil
 }
 
 func cloneGentooRepo(baseDirectory string) error {
@@ -306,95 +286,19 @@ func cloneGentooRepo(baseDirectory string) error {
 	return nil
 }
 
-func getAllDep(packages map[string]PackageInfo, pkgName string, visited map[string]bool, deps []string) []string {
-	if visited[pkgName] {
-		return deps
-	}
-
-	visited[pkgName] = true
-	deps = append(deps, pkgName)
-
-	if pkg, ok := packages[pkgName]; ok {
-		for _, depName := range pkg.Depends {
-			deps = getAllDep(packages, depName, visited, deps)
-		}
-	}
-	return deps
-}
-
-func pageRank(pkgInfoMap map[string]PackageInfo, d float64, iterations int) map[string]float64 {
-	ranks := make(map[string]float64)
-	N := float64(len(pkgInfoMap))
-
-	// Initialize ranks
-	for pkgName := range pkgInfoMap {
-		ranks[pkgName] = 1.0 / N
-	}
-
-	for i := 0; i < iterations; i++ {
-		newRanks := make(map[string]float64)
-		for pkgName := range pkgInfoMap {
-			newRanks[pkgName] = (1 - d) / N
-		}
-
-		for pkgName, pkgInfo := range pkgInfoMap {
-			var depNum int
-			for _, depName := range pkgInfo.Depends {
-				if _, exists := pkgInfoMap[depName]; exists {
-					depNum++
-				}
-			}
-			share := ranks[pkgName] / float64(depNum)
-			for _, dep := range pkgInfo.Depends {
-				if _, exists := pkgInfoMap[dep]; exists {
-					newRanks[dep] += d * share
-				}
-			}
-		}
-
-		ranks = newRanks
-	}
-
-	return ranks
-}
-
 func generateDependencyGraph(pkgInfoMap map[string]PackageInfo, outputPath string) error {
-	file, err := os.Create(outputPath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	writer := bufio.NewWriter(file)
-	writer.WriteString("digraph {\n")
-
-	packageIndices := make(map[string]int)
-	index := 0
-
+	export := graph.NewExport()
 	for pkgName, pkgInfo := range pkgInfoMap {
-		packageIndices[pkgName] = index
-		label := fmt.Sprintf("%s@%s", pkgName, pkgInfo.Description)
-		writer.WriteString(fmt.Sprintf("  %d [label=\"%s\"];\n", index, label))
-		index++
-	}
-
-	for pkgName, pkgInfo := range pkgInfoMap {
-		pkgIndex := packageIndices[pkgName]
+		export.AddNode(graph.ExportNode{
+			Name:         pkgName,
+			Label:        fmt.Sprintf("%s@%s", pkgName, pkgInfo.Description),
+			Version:      pkgInfo.Version,
+			PageRank:     pkgInfo.PageRank,
+			DependsCount: pkgInfo.DependsCount,
+		})
 		for _, depName := range pkgInfo.Depends {
-			if depIndex, ok := packageIndices[depName]; ok {
-				writer.WriteString(fmt.Sprintf("  %d -> %d;\n", pkgIndex, depIndex))
-			}
+			export.AddEdge(pkgName, depName, "")
 		}
 	}
-
-	writer.WriteString("}\n")
-	writer.Flush()
-	return nil
-}
-
-func isUniqueViolation(err error) bool {
-	if pqErr, ok := err.(*pq.Error); ok {
-		return pqErr.Code == "23505"
-	}
-	return false
+	return export.WriteFile(outputPath)
 }
@@ -1,7 +1,7 @@
 package homebrew
 
 import (
-	"bufio"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -10,8 +10,13 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/HUSTSecLab/criticality_score/pkg/collector"
+	"github.com/HUSTSecLab/criticality_score/pkg/failure"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitutil/normalize"
+	"github.com/HUSTSecLab/criticality_score/pkg/graph"
+	"github.com/HUSTSecLab/criticality_score/pkg/progress"
+	"github.com/HUSTSecLab/criticality_score/pkg/sampling"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
-	"github.com/lib/pq"
 )
 
 type PackageInfo struct {
@@ -120,121 +125,30 @@ func (hc *HomebrewCollector) parseFormulaContent(content string, fileName string
 	if match := urlRe.FindStringSubmatch(content); len(match) > 1 {
 		url := match[1]
 		pkgInfo.URL = url
-		if strings.HasPrefix(url, "https://github.com/") {
-			parts := strings.Split(url, "/")
-			if len(parts) >= 5 {
-				orgName := parts[3]
-				repoName := parts[4]
-				pkgInfo.GitRepo = fmt.Sprintf("https://github.com/%s/%s.git", orgName, repoName)
-			}
-		} else if strings.HasPrefix(url, "https://gitlab.com/") {
-			parts := strings.Split(url, "/")
-			if len(parts) >= 5 {
-				orgName := parts[3]
-				repoName := parts[4]
-				pkgInfo.GitRepo = fmt.Sprintf("https://gitlab.com/%s/%s.git", orgName, repoName)
-			}
-		} else if strings.HasPrefix(url, "https://gitee.com/") {
-			parts := strings.Split(url, "/")
-			if len(parts) >= 5 {
-				orgName := parts[3]
-				repoName := parts[4]
-				pkgInfo.GitRepo = fmt.Sprintf("https://gitee.com/%s/%s.git", orgName, repoName)
-			}
-		} else if strings.HasPrefix(url, "https://bitbucket.org/") {
-			parts := strings.Split(url, "/")
-			if len(parts) >= 5 {
-				orgName := parts[3]
-				repoName := parts[4]
-				pkgInfo.GitRepo = fmt.Sprintf("https://bitbucket.org/%s/%s.git", orgName, repoName)
-			}
+		// repos on forges, or forge-less repos, e.g. a cgit snapshot of
+		// git.kernel.org
+		if repo, ok := normalize.Repo(url); ok {
+			pkgInfo.GitRepo = repo
 		}
 	}
 
 	return pkgInfo
 }
 
-func (hc *HomebrewCollector) getAllDep(pkgName string, visited map[string]bool, deps []string) []string {
-	if visited[pkgName] {
-		return deps
-	}
-
-	visited[pkgName] = true
-	deps = append(deps, pkgName)
-
-	if pkg, ok := hc.PkgInfoMap[pkgName]; ok {
-		for _, depName := range pkg.Depends {
-			deps = hc.getAllDep(depName, visited, deps)
-		}
-	}
-	return deps
-}
-
-func (hc *HomebrewCollector) calculatePageRank(iterations int, dampingFactor float64) map[string]float64 {
-	pageRank := make(map[string]float64)
-	numPackages := len(hc.PkgInfoMap)
-
-	for pkgName := range hc.PkgInfoMap {
-		pageRank[pkgName] = 1.0 / float64(numPackages)
-	}
-
-	for i := 0; i < iterations; i++ {
-		newPageRank := make(map[string]float64)
-
-		for pkgName := range hc.PkgInfoMap {
-			newPageRank[pkgName] = (1 - dampingFactor) / float64(numPackages)
-		}
-
-		for pkgName, pkgInfo := range hc.PkgInfoMap {
-			var depNum int
-			for _, depName := range pkgInfo.Depends {
-				if _, exists := hc.PkgInfoMap[depName]; exists {
-					depNum++
-				}
-			}
-			for _, depName := range pkgInfo.Depends {
-				if _, exists := hc.PkgInfoMap[depName]; exists {
-					newPageRank[depName] += dampingFactor * (pageRank[pkgName] / float64(depNum))
-				}
-			}
-		}
-		pageRank = newPageRank
-	}
-	return pageRank
-}
-
 func (hc *HomebrewCollector) generateDependencyGraph(outputPath string) error {
-	file, err := os.Create(outputPath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	writer := bufio.NewWriter(file)
-	writer.WriteString("digraph {\n")
-
-	packageIndices := make(map[string]int)
-	index := 0
-
+	export := graph.NewExport()
 	for pkgName, pkgInfo := range hc.PkgInfoMap {
-		packageIndices[pkgName] = index
-		label := fmt.Sprintf("%s@%s", pkgName, pkgInfo.Description)
-		writer.WriteString(fmt.Sprintf("  %d [label=\"%s\"];\n", index, label))
-		index++
-	}
-
-	for pkgName, pkgInfo := range hc.PkgInfoMap {
-		pkgIndex := packageIndices[pkgName]
+		export.AddNode(graph.ExportNode{
+			Name:         pkgName,
+			Label:        fmt.Sprintf("%s@%s", pkgName, pkgInfo.Description),
+			PageRank:     pkgInfo.PageRank,
+			DependsCount: pkgInfo.DependsCount,
+		})
 		for _, depName := range pkgInfo.Depends {
-			if depIndex, ok := packageIndices[depName]; ok {
-				writer.WriteString(fmt.Sprintf("  %d -> %d;\n", pkgIndex, depIndex))
-			}
+			export.AddEdge(pkgName, depName, "")
 		}
 	}
-
-	writer.WriteString("}\n")
-	writer.Flush()
-	return nil
+	return export.WriteFile(outputPath)
 }
 
 func (hc *HomebrewCollector) storeDependenciesInDatabase(pkgName string, dependencies []string) error {
@@ -242,15 +156,8 @@ func (hc *HomebrewCollector) storeDependenciesInDatabase(pkgName string, depende
 	if err != nil {
 		return err
 	}
-	defer db.Close()
 
-	for _, dep := range dependencies {
-		_, err := db.Exec("INSERT INTO homebrew_relationships (frompackage, topackage) VALUES ($1, $2)", pkgName, dep)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+	return collector.ReplaceDependencies(context.Background(), db, "homebrew_relationships", pkgName, dependencies)
 }
 
 func (hc *HomebrewCollector) updateOrInsertDatabase() error {
@@ -258,59 +165,33 @@ func (hc *HomebrewCollector) updateOrInsertDatabase() error {
 	if err != nil {
 		return err
 	}
-	defer db.Close()
 
+	pkgs := make([]collector.Package, 0, len(hc.PkgInfoMap))
 	for pkgName, pkgInfo := range hc.PkgInfoMap {
-		var exists bool
-		err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM homebrew_packages WHERE package = $1)", pkgName).Scan(&exists)
-		if err != nil {
-			return err
-		}
-		if !exists {
-			_, err := db.Exec("INSERT INTO homebrew_packages (package, depends_count, description, homepage, page_rank) VALUES ($1, $2, $3, $4, $5)",
-				pkgName, pkgInfo.DependsCount, pkgInfo.Description, pkgInfo.Homepage, pkgInfo.PageRank)
-			if err != nil {
-				return err
-			}
-		} else {
-			_, err := db.Exec("UPDATE homebrew_packages SET depends_count = $1, description = $2, homepage = $3, page_rank = $4 WHERE package = $5",
-				pkgInfo.DependsCount, pkgInfo.Description, pkgInfo.Homepage, pkgInfo.PageRank, pkgName)
-			if err != nil {
-				return err
-			}
-		}
-	}
-	return nil
-}
-
-func (hc *HomebrewCollector) isUniqueViolation(err error) bool {
-	if pqErr, ok := err.(*pq.Error); ok {
-		return pqErr.Code == "23505"
-	}
-	return false
+		pkgs = append(pkgs, collector.Package{
+			Name:         pkgName,
+			Description:  pkgInfo.Description,
+			Homepage:     pkgInfo.Homepage,
+			DependsCount: pkgInfo.DependsCount,
+			PageRank:     pkgInfo.PageRank,
+		})
+	}
+	return collector.UpsertPackages(context.Background(), db, "homebrew_packages", pkgs, false, 0)
 }
 
-func (hc *HomebrewCollector) Collect(outputPath string) {
+func (hc *HomebrewCollector) Collect(tracker *failure.Tracker, outputPath string) error {
 	if err := hc.FetchAndParseFormulaFiles(); err != nil {
-		fmt.Printf("Error fetching package info: %v\n", err)
-		return
-	}
-
-	depMap := make(map[string][]string)
-	for pkgName := range hc.PkgInfoMap {
-		visited := make(map[string]bool)
-		deps := hc.getAllDep(pkgName, visited, []string{})
-		depMap[pkgName] = deps
+		return fmt.Errorf("error fetching package info: %w", err)
 	}
+	hc.PkgInfoMap = sampling.Map(hc.PkgInfoMap)
 
-	countMap := make(map[string]int)
-	for _, deps := range depMap {
-		for _, dep := range deps {
-			countMap[dep]++
-		}
+	depMap := make(map[string][]string, len(hc.PkgInfoMap))
+	for pkgName, pkgInfo := range hc.PkgInfoMap {
+		depMap[pkgName] = pkgInfo.Depends
 	}
+	countMap := collector.DependsCounts(depMap)
 
-	pagerank := hc.calculatePageRank(20, 0.85)
+	pagerank := collector.RankDependencies("homebrew", depMap)
 
 	for pkgName, pkgInfo := range hc.PkgInfoMap {
 		pagerankVal := pagerank[pkgName]
@@ -319,27 +200,34 @@ func (hc *HomebrewCollector) Collect(outputPath string) {
 		pkgInfo.DependsCount = depCount
 		hc.PkgInfoMap[pkgName] = pkgInfo
 	}
+	release := collector.AcquireWrite()
+	defer release()
 	err := hc.updateOrInsertDatabase()
 	if err != nil {
-		fmt.Printf("Error updating database: %v\n", err)
-		return
+		return fmt.Errorf("error updating database: %w", err)
 	}
+	relPhase := progress.Default().Phase("relationships", len(hc.PkgInfoMap))
 	for pkgName, pkgInfo := range hc.PkgInfoMap {
+		relPhase.Add(1)
 		if err := hc.storeDependenciesInDatabase(pkgName, pkgInfo.Depends); err != nil {
-			if hc.isUniqueViolation(err) {
-				continue
-			}
-			fmt.Printf("Error storing dependencies for package %s: %v\n", pkgName, err)
+			err = fmt.Errorf("error storing dependencies for package %s: %w", pkgName, err)
+			progress.Default().Error(err)
+			tracker.Fail(pkgName, err)
+			continue
 		}
+		tracker.Success()
 	}
+	relPhase.Done()
+	release()
 	fmt.Println("Database updated successfully.")
 
 	if outputPath != "" {
 		err := hc.generateDependencyGraph(outputPath)
 		if err != nil {
 			fmt.Printf("Error generating dependency graph: %v\n", err)
-			return
+			return nil
 		}
 		fmt.Println("Dependency graph generated successfully.")
 	}
+	return nil
 }
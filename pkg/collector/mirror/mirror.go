@@ -0,0 +1,393 @@
+// Package mirror selects the mirror the distribution collectors download
+// their package indexes from. The first use of a distribution benchmarks
+// its candidate mirrors, the latency to the first byte and the throughput
+// of a small file of the repository, and picks the fastest one, so
+// deployments outside China do not download from the mirrors in China.
+//
+// The selection is cached in a file for a TTL, so the mirrors are only
+// benchmarked once a day by default, a region hint prefers the mirrors of
+// a region and pinned mirrors skip the benchmark. The default selector is
+// initialized by config.ParseFlags when config.RegistMirrorFlags is called,
+// if it is not, the first candidate is used without a benchmark:
+//
+//	url := mirror.Default().URL("debian", "dists/stable/main/binary-amd64/Packages.gz")
+package mirror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultCacheTTL is the default age of the cached selections, the
+	// mirrors are benchmarked again once it is exceeded.
+	DefaultCacheTTL = 24 * time.Hour
+	// DefaultTimeout is the default timeout of the benchmark of a mirror.
+	DefaultTimeout = 10 * time.Second
+
+	// maxProbeBytes is the most bytes of the probe file read to measure
+	// the throughput, and the size the cost of a mirror is estimated for.
+	maxProbeBytes = 1 << 20
+)
+
+// Mirror is a mirror of a distribution, URL is the base of the paths of
+// the repository and ends in a slash.
+type Mirror struct {
+	URL    string `json:"url" mapstructure:"url"`
+	Region string `json:"region" mapstructure:"region"`
+}
+
+// Candidates are the default mirrors of the distributions, the first one
+// is the fallback if no mirror can be reached. Regions are cn, asia, eu,
+// us, and global for the mirrors redirecting to a mirror close to the
+// client.
+var Candidates = map[string][]Mirror{
+	"debian": {
+		{"https://mirrors.hust.edu.cn/debian/", "cn"},
+		{"https://deb.debian.org/debian/", "global"},
+		{"https://ftp.jp.debian.org/debian/", "asia"},
+		{"https://ftp.de.debian.org/debian/", "eu"},
+		{"https://mirrors.kernel.org/debian/", "us"},
+	},
+	"ubuntu": {
+		{"https://mirrors.hust.edu.cn/ubuntu/", "cn"},
+		{"https://archive.ubuntu.com/ubuntu/", "global"},
+		{"https://jp.archive.ubuntu.com/ubuntu/", "asia"},
+		{"https://de.archive.ubuntu.com/ubuntu/", "eu"},
+		{"https://us.archive.ubuntu.com/ubuntu/", "us"},
+	},
+	"deepin": {
+		{"https://mirrors.hust.edu.cn/deepin/", "cn"},
+		{"https://community-packages.deepin.com/", "global"},
+	},
+	"archlinux": {
+		{"https://mirrors.hust.edu.cn/archlinux/", "cn"},
+		{"https://geo.mirror.pkgbuild.com/", "global"},
+		{"https://mirror.netcologne.de/archlinux/", "eu"},
+		{"https://mirrors.kernel.org/archlinux/", "us"},
+	},
+	"alpine": {
+		{"https://mirrors.aliyun.com/alpine/", "cn"},
+		{"https://dl-cdn.alpinelinux.org/alpine/", "global"},
+	},
+	"centos": {
+		{"https://mirrors.aliyun.com/centos/", "cn"},
+	},
+	"fedora": {
+		{"https://mirrors.aliyun.com/fedora/", "cn"},
+		{"https://dl.fedoraproject.org/pub/fedora/linux/", "global"},
+		{"https://mirrors.kernel.org/fedora/", "us"},
+	},
+}
+
+// ProbePaths are the files of the distributions downloaded to benchmark
+// their mirrors, small files every mirror of the collected release has.
+var ProbePaths = map[string]string{
+	"debian":    "dists/stable/Release",
+	"ubuntu":    "dists/jammy/Release",
+	"deepin":    "beige/dists/beige/Release",
+	"archlinux": "core/os/x86_64/core.db",
+	"alpine":    "v3.21/main/x86_64/APKINDEX.tar.gz",
+	"centos":    "7/os/x86_64/repodata/repomd.xml",
+	"fedora":    "releases/41/Everything/source/tree/repodata/repomd.xml",
+}
+
+type Config struct {
+	// Region is preferred if one of its mirrors can be reached, empty
+	// selects the fastest mirror of all regions
+	Region string
+	// Mirrors are the pinned mirrors of distributions, they are used
+	// without a benchmark
+	Mirrors map[string]string
+	// Candidates replace the default candidates of distributions
+	Candidates map[string][]Mirror
+	// CacheFile keeps the selections between runs, empty disables the
+	// cache
+	CacheFile string
+	// CacheTTL is the age of a cached selection it is used for
+	CacheTTL time.Duration
+	// Timeout of the benchmark of a mirror
+	Timeout time.Duration
+}
+
+// Result is the benchmark of a mirror, Err is the error of an unreachable
+// mirror.
+type Result struct {
+	Mirror
+	Latency    time.Duration `json:"latency"`
+	Throughput float64       `json:"throughput"`
+	Err        string        `json:"err,omitempty"`
+}
+
+// OK reports whether the mirror could be reached.
+func (r Result) OK() bool {
+	return r.Err == ""
+}
+
+// Cost is the estimated time to download 1MiB from the mirror, the mirror
+// with the lowest cost is selected.
+func (r Result) Cost() time.Duration {
+	if r.Throughput <= 0 {
+		return r.Latency
+	}
+	return r.Latency + time.Duration(maxProbeBytes/r.Throughput*float64(time.Second))
+}
+
+func (r Result) String() string {
+	if !r.OK() {
+		return fmt.Sprintf("%s (%s): %s", r.URL, r.Region, r.Err)
+	}
+	return fmt.Sprintf("%s (%s): latency %s, %.1f KiB/s", r.URL, r.Region, r.Latency.Round(time.Millisecond), r.Throughput/1024)
+}
+
+func probe(ctx context.Context, client *http.Client, m Mirror, path string) Result {
+	r := Result{Mirror: m}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.URL+path, nil)
+	if err != nil {
+		r.Err = err.Error()
+		return r
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		r.Err = err.Error()
+		return r
+	}
+	defer resp.Body.Close()
+	r.Latency = time.Since(start)
+	if resp.StatusCode != http.StatusOK {
+		r.Err = resp.Status
+		return r
+	}
+	start = time.Now()
+	n, err := io.Copy(io.Discard, io.LimitReader(resp.Body, maxProbeBytes))
+	if err != nil {
+		r.Err = err.Error()
+		return r
+	}
+	r.Throughput = float64(n) / max(time.Since(start), time.Millisecond).Seconds()
+	return r
+}
+
+// Benchmark downloads the file path of every mirror concurrently, each
+// within timeout, and returns the results in the order of the mirrors.
+func Benchmark(ctx context.Context, client *http.Client, mirrors []Mirror, path string, timeout time.Duration) []Result {
+	results := make([]Result, len(mirrors))
+	var wg sync.WaitGroup
+	for i, m := range mirrors {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			results[i] = probe(ctx, client, m, path)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// Select returns the reachable mirror of the lowest cost, of region if one
+// of its mirrors is reachable, and false if no mirror is reachable.
+func Select(results []Result, region string) (Result, bool) {
+	var ok, inRegion []Result
+	for _, r := range results {
+		if !r.OK() {
+			continue
+		}
+		ok = append(ok, r)
+		if region != "" && r.Region == region {
+			inRegion = append(inRegion, r)
+		}
+	}
+	if len(inRegion) > 0 {
+		ok = inRegion
+	}
+	if len(ok) == 0 {
+		return Result{}, false
+	}
+	sort.SliceStable(ok, func(i, j int) bool { return ok[i].Cost() < ok[j].Cost() })
+	return ok[0], true
+}
+
+// cacheEntry is the selection of a distribution kept in the cache file.
+type cacheEntry struct {
+	Region     string    `json:"region"`
+	URL        string    `json:"url"`
+	SelectedAt time.Time `json:"selected_at"`
+	Results    []Result  `json:"results"`
+}
+
+func readCache(path string) (map[string]cacheEntry, error) {
+	cache := map[string]cacheEntry{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return cache, nil
+}
+
+// writeCache replaces the file by a rename, so concurrent collectors never
+// read a partial file.
+func writeCache(path string, cache map[string]cacheEntry) error {
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Selector selects the mirrors of the distributions, the mirror of a
+// distribution is selected once and kept for the run.
+type Selector struct {
+	config Config
+	client *http.Client
+	// benchmark is false for the uninitialized default selector
+	benchmark bool
+
+	mu       sync.Mutex
+	selected map[string]string
+}
+
+// NewSelector returns a selector of the config, nil uses the first
+// candidates without a benchmark.
+func NewSelector(config *Config) *Selector {
+	s := &Selector{client: &http.Client{}, selected: map[string]string{}}
+	if config != nil {
+		s.config = *config
+		s.benchmark = true
+	}
+	if s.config.Timeout <= 0 {
+		s.config.Timeout = DefaultTimeout
+	}
+	return s
+}
+
+func (s *Selector) candidates(distro string) []Mirror {
+	if c, ok := s.config.Candidates[distro]; ok {
+		return c
+	}
+	return Candidates[distro]
+}
+
+// Base returns the base URL of the mirror of distro, ending in a slash.
+// It panics if distro has no pinned mirror and no candidates.
+func (s *Selector) Base(distro string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if url, ok := s.selected[distro]; ok {
+		return url
+	}
+	url := s.selectMirror(distro)
+	if !strings.HasSuffix(url, "/") {
+		url += "/"
+	}
+	s.selected[distro] = url
+	return url
+}
+
+// URL returns the url of path on the mirror of distro.
+func (s *Selector) URL(distro, path string) string {
+	return s.Base(distro) + strings.TrimPrefix(path, "/")
+}
+
+func (s *Selector) selectMirror(distro string) string {
+	if url, ok := s.config.Mirrors[distro]; ok {
+		return url
+	}
+	candidates := s.candidates(distro)
+	if len(candidates) == 0 {
+		panic("mirror: no candidates of " + distro)
+	}
+	fallback := candidates[0].URL
+	if !s.benchmark || len(candidates) == 1 {
+		return fallback
+	}
+
+	cache := map[string]cacheEntry{}
+	if s.config.CacheFile != "" {
+		var err error
+		if cache, err = readCache(s.config.CacheFile); err != nil {
+			log.Printf("Failed to read the mirror cache, the mirrors are benchmarked again: %v", err)
+			cache = map[string]cacheEntry{}
+		}
+		if e, ok := cache[distro]; ok && e.Region == s.config.Region &&
+			time.Since(e.SelectedAt) < s.config.CacheTTL && contains(candidates, e.URL) {
+			return e.URL
+		}
+	}
+
+	results := Benchmark(context.Background(), s.client, candidates, ProbePaths[distro], s.config.Timeout)
+	for _, r := range results {
+		log.Printf("Mirror of %s %s", distro, r)
+	}
+	best, ok := Select(results, s.config.Region)
+	if !ok {
+		// not cached, the mirrors are benchmarked again by the next run
+		log.Printf("No mirror of %s can be reached, using %s", distro, fallback)
+		return fallback
+	}
+	log.Printf("Selected the mirror %s of %s", best.URL, distro)
+
+	if s.config.CacheFile != "" {
+		cache[distro] = cacheEntry{Region: s.config.Region, URL: best.URL, SelectedAt: time.Now(), Results: results}
+		if err := writeCache(s.config.CacheFile, cache); err != nil {
+			log.Printf("Failed to write the mirror cache: %v", err)
+		}
+	}
+	return best.URL
+}
+
+func contains(mirrors []Mirror, url string) bool {
+	for _, m := range mirrors {
+		if m.URL == url {
+			return true
+		}
+	}
+	return false
+}
+
+var defaultSelector = NewSelector(nil)
+
+// InitDefault initializes the default selector.
+func InitDefault(config *Config) {
+	defaultSelector = NewSelector(config)
+}
+
+// Default returns the default selector, if it is not initialized, the
+// first candidates are used without a benchmark.
+func Default() *Selector {
+	return defaultSelector
+}
@@ -0,0 +1,100 @@
+package mirror
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newServer(t *testing.T, delay time.Duration, status int) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		if r.URL.Path != "/dists/stable/Release" {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(strings.Repeat("x", 4096)))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSelect(t *testing.T) {
+	results := []Result{
+		{Mirror: Mirror{"a/", "cn"}, Latency: 300 * time.Millisecond, Throughput: 1 << 20},
+		{Mirror: Mirror{"b/", "eu"}, Latency: 50 * time.Millisecond, Throughput: 1 << 20},
+		{Mirror: Mirror{"c/", "eu"}, Latency: 10 * time.Millisecond, Throughput: 1 << 10},
+		{Mirror: Mirror{"d/", "us"}, Err: "timeout"},
+	}
+	tests := []struct {
+		region string
+		want   string
+	}{
+		{"", "b/"},
+		{"cn", "a/"},
+		// no reachable mirror in the region
+		{"us", "b/"},
+	}
+	for _, tt := range tests {
+		if got, ok := Select(results, tt.region); !ok || got.URL != tt.want {
+			t.Errorf("Select(%q) = %s, %v, want %s", tt.region, got.URL, ok, tt.want)
+		}
+	}
+	if _, ok := Select(results[3:], ""); ok {
+		t.Error("Select() of unreachable mirrors is ok")
+	}
+}
+
+func TestBenchmark(t *testing.T) {
+	slow := newServer(t, 200*time.Millisecond, http.StatusOK)
+	fast := newServer(t, 0, http.StatusOK)
+	broken := newServer(t, 0, http.StatusInternalServerError)
+	mirrors := []Mirror{{slow.URL + "/", "cn"}, {fast.URL + "/", "eu"}, {broken.URL + "/", "us"}}
+
+	results := Benchmark(context.Background(), http.DefaultClient, mirrors, "dists/stable/Release", 100*time.Millisecond)
+	if results[0].OK() || !results[1].OK() || results[2].OK() {
+		t.Fatalf("Benchmark() = %v", results)
+	}
+	if results[1].Throughput <= 0 {
+		t.Errorf("throughput = %v", results[1].Throughput)
+	}
+}
+
+func TestSelector(t *testing.T) {
+	fast := newServer(t, 0, http.StatusOK)
+	broken := newServer(t, 0, http.StatusNotFound)
+	cache := filepath.Join(t.TempDir(), "mirrors.json")
+	config := &Config{
+		Candidates: map[string][]Mirror{"debian": {{broken.URL, "cn"}, {fast.URL + "/", "eu"}}},
+		Mirrors:    map[string]string{"ubuntu": "https://pinned.example/ubuntu"},
+		CacheFile:  cache,
+		CacheTTL:   time.Hour,
+	}
+	s := NewSelector(config)
+	if got, want := s.URL("debian", "/dists/x"), fast.URL+"/dists/x"; got != want {
+		t.Errorf("URL() = %s, want %s", got, want)
+	}
+	if got := s.Base("ubuntu"); got != "https://pinned.example/ubuntu/" {
+		t.Errorf("Base() of a pinned mirror = %s", got)
+	}
+
+	// the next run uses the cached selection, even if the mirror is down
+	fast.Close()
+	if got := NewSelector(config).Base("debian"); got != fast.URL+"/" {
+		t.Errorf("Base() of the cache = %s", got)
+	}
+	// the cache is of a region
+	config.Region = "cn"
+	if got := NewSelector(config).Base("debian"); got != broken.URL+"/" {
+		t.Errorf("Base() of unreachable mirrors = %s, want the first candidate", got)
+	}
+
+	if got := NewSelector(nil).Base("debian"); got != Candidates["debian"][0].URL {
+		t.Errorf("Base() without benchmark = %s", got)
+	}
+}
@@ -2,11 +2,10 @@ package nix
 
 import (
 	"bytes"
-	"database/sql"
+	"context"
 	"encoding/gob"
 	"encoding/json"
 	"fmt"
-	"net/url"
 	"os"
 	"os/exec"
 	"regexp"
@@ -14,8 +13,12 @@ import (
 	"sync"
 	"unicode"
 
+	"github.com/HUSTSecLab/criticality_score/pkg/collector"
+	"github.com/HUSTSecLab/criticality_score/pkg/failure"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitutil/normalize"
+	"github.com/HUSTSecLab/criticality_score/pkg/progress"
+	"github.com/HUSTSecLab/criticality_score/pkg/sampling"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
-	"github.com/lib/pq"
 )
 
 // DepInfo struct to store package information
@@ -44,15 +47,14 @@ func (NixCollector *NixCollector) storeDependenciesInDatabase(pkgName string, de
 	if err != nil {
 		return err
 	}
-	defer db.Close()
 
+	names := make([]string, 0, len(dependencies))
 	for _, dep := range dependencies {
-		_, err := db.Exec("INSERT INTO nix_relationships (frompackage, topackage) VALUES ($1, $2)", pkgName, dep.Name)
-		if err != nil {
-			return err
+		if dep.Name != "" {
+			names = append(names, dep.Name)
 		}
 	}
-	return nil
+	return collector.ReplaceDependencies(context.Background(), db, "nix_relationships", pkgName, names)
 }
 
 func isValidNixIdentifier(s string) bool {
@@ -286,30 +288,11 @@ func (NixCollector *NixCollector) nixEval(expr string) (map[string][]DepInfo, er
 	return depsMap, nil
 }
 
-// processGitLink processes the gitLink to ensure it points to a git repository
+// processGitLink returns the clone url of the repo of gitLink, empty if it
+// does not point to a git repository
 func (NixCollector *NixCollector) processGitLink(gitLink string) string {
-	if gitLink == "" {
-		return ""
-	}
-
-	parsedURL, err := url.Parse(gitLink)
-	if err != nil {
-		return ""
-	}
-
-	codeHostingDomains := []string{
-		"github.com",
-		"gitlab.com",
-		"bitbucket.org",
-	}
-
-	for _, domain := range codeHostingDomains {
-		if strings.Contains(parsedURL.Host, domain) {
-			return gitLink
-		}
-	}
-
-	return ""
+	repo, _ := normalize.Repo(gitLink)
+	return repo
 }
 
 func (NixCollector *NixCollector) mergeDependencies(packages map[DepInfo][]DepInfo) map[DepInfo][]DepInfo {
@@ -372,39 +355,20 @@ func getAllDep(packages map[string][]string, pkgName string, visited map[string]
 	return deps
 }
 
-func (NixCollector *NixCollector) calculatePageRank(packages map[DepInfo][]DepInfo, iterations int, dampingFactor float64) map[string]float64 {
-	ranks := make(map[string]float64)
-	numPackages := float64(len(packages))
-
-	for pkg := range packages {
-		ranks[pkg.Name] = 1.0 / numPackages
-	}
-
-	for i := 0; i < iterations; i++ {
-		newRanks := make(map[string]float64)
-		for pkg := range packages {
-			newRanks[pkg.Name] = (1.0 - dampingFactor) / numPackages
-		}
-
-		for pkg, deps := range packages {
-			depCount := 0
-			for _, dep := range deps {
-				if _, exists := packages[dep]; exists {
-					depCount++
-				}
-			}
-			contribution := (dampingFactor * ranks[pkg.Name]) / float64(depCount)
-			for _, dep := range deps {
-				if _, exists := packages[dep]; exists {
-					newRanks[dep.Name] += contribution
-				}
+// rankPackages returns the PageRank of packages, keyed by the names of the
+// packages.
+func (NixCollector *NixCollector) rankPackages(packages map[DepInfo][]DepInfo) map[string]float64 {
+	depMap := make(map[string][]string, len(packages))
+	for pkg, deps := range packages {
+		names := make([]string, 0, len(deps))
+		for _, dep := range deps {
+			if _, exists := packages[dep]; exists {
+				names = append(names, dep.Name)
 			}
 		}
-
-		ranks = newRanks
+		depMap[pkg.Name] = names
 	}
-
-	return ranks
+	return collector.RankDependencies("nix", depMap)
 }
 
 func contains(slice []string, item string) bool {
@@ -490,17 +454,17 @@ func reverseDependencies(deps map[DepInfo][]DepInfo) map[DepInfo][]DepInfo {
 	return reversed
 }
 
-func (NixCollector *NixCollector) Collect(workerCount int, batchSize int) {
+func (NixCollector *NixCollector) Collect(tracker *failure.Tracker, workerCount int, batchSize int) error {
 	packages, err := NixCollector.GetAllNixPackages(workerCount)
 	if err != nil {
-		fmt.Printf("Error retrieving Nix packages: %v\n", err)
-		return
+		return fmt.Errorf("error retrieving Nix packages: %w", err)
 	}
+	packages = sampling.MapFunc(sampling.Default(), packages, func(d DepInfo) string { return d.Name })
 
 	fmt.Println("Nix package information retrieved successfully")
 	NixCollector.countDependencies(packages)
 
-	pageRanks := NixCollector.calculatePageRank(packages, 20, 0.85)
+	pageRanks := NixCollector.rankPackages(packages)
 
 	for pkgInfo := range packages {
 		pkgInfo.PageRank = pageRanks[pkgInfo.Name]
@@ -509,21 +473,28 @@ func (NixCollector *NixCollector) Collect(workerCount int, batchSize int) {
 
 	fmt.Println("Nix package information updated successfully")
 
+	release := collector.AcquireWrite()
+	defer release()
 	if err := NixCollector.batchupdateOrInsertNixPackages(packages, batchSize); err != nil {
-		fmt.Printf("Error updating or inserting Nix packages into database: %v\n", err)
-		return
+		return fmt.Errorf("error updating or inserting Nix packages into database: %w", err)
 	}
 
+	relPhase := progress.Default().Phase("relationships", len(packages))
 	for pkg, pkgInfo := range packages {
+		relPhase.Add(1)
 		if err := NixCollector.storeDependenciesInDatabase(pkg.Name, pkgInfo); err != nil {
-			if isUniqueViolation(err) {
-				continue
-			}
-			fmt.Printf("Error storing dependencies for package %s: %v\n", pkg.Name, err)
+			err = fmt.Errorf("error storing dependencies for package %s: %w", pkg.Name, err)
+			progress.Default().Error(err)
+			tracker.Fail(pkg.Name, err)
+			continue
 		}
+		tracker.Success()
 	}
+	relPhase.Done()
 
+	release()
 	fmt.Println("Successfully updated package information in the database")
+	return nil
 }
 
 func (NixCollector *NixCollector) batchupdateOrInsertNixPackages(packages map[DepInfo][]DepInfo, batchSize int) error {
@@ -531,59 +502,24 @@ func (NixCollector *NixCollector) batchupdateOrInsertNixPackages(packages map[De
 	if err != nil {
 		return fmt.Errorf("error connecting to database: %w", err)
 	}
-	defer db.Close()
 
-	var packageList []DepInfo
+	var packageList []collector.Package
 	seen := make(map[string]bool)
 
 	for pkg := range packages {
 		if !seen[pkg.Name] {
-			packageList = append(packageList, pkg)
+			packageList = append(packageList, collector.Package{
+				Name:         pkg.Name,
+				Version:      pkg.Version,
+				Description:  pkg.Description,
+				Homepage:     pkg.Homepage,
+				DependsCount: pkg.DepCount,
+				PageRank:     pkg.PageRank,
+			})
 			seen[pkg.Name] = true
 		}
 	}
-
-	for i := 0; i < len(packageList); i += batchSize {
-		end := i + batchSize
-		if end > len(packageList) {
-			end = len(packageList)
-		}
-		batch := packageList[i:end]
-
-		if err := updateOrInsertBatch(db, batch); err != nil {
-			return fmt.Errorf("error processing batch: %w", err)
-		}
-	}
-
-	return nil
-}
-
-func updateOrInsertBatch(db *sql.DB, batch []DepInfo) error {
-	tx, err := db.Begin()
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback()
-
-	query := `
-		INSERT INTO nix_packages (package, version, homepage, description, depends_count, page_rank)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		ON CONFLICT (package) DO UPDATE
-		SET version = EXCLUDED.version,
-			homepage = EXCLUDED.homepage,
-			description = EXCLUDED.description,
-			depends_count = EXCLUDED.depends_count,
-			page_rank = EXCLUDED.page_rank
-	`
-
-	for _, pkg := range batch {
-		_, err := tx.Exec(query, pkg.Name, pkg.Version, pkg.Homepage, pkg.Description, pkg.DepCount, pkg.PageRank)
-		if err != nil {
-			return fmt.Errorf("error inserting or updating package %s: %w", pkg.Name, err)
-		}
-	}
-
-	return tx.Commit()
+	return collector.UpsertPackages(context.Background(), db, "nix_packages", packageList, true, batchSize)
 }
 
 func findDepInfoByName(packages map[DepInfo][]DepInfo, name string) (DepInfo, bool) {
@@ -595,74 +531,6 @@ func findDepInfoByName(packages map[DepInfo][]DepInfo, name string) (DepInfo, bo
 	return DepInfo{}, false
 }
 
-func normalizeGitLink(link string) string {
-	var orgName, repoName string
-
-	if strings.HasSuffix(link, ".git") {
-		link = link[:len(link)-4]
-	}
-
-	if strings.HasPrefix(link, "https://github.com/") {
-		parts := strings.Split(link, "/")
-		if len(parts) >= 5 {
-			orgName = parts[3]
-			repoName = parts[4]
-			return fmt.Sprintf("https://github.com/%s/%s.git", orgName, repoName)
-		}
-	} else if strings.HasPrefix(link, "http://github.com/") {
-		parts := strings.Split(link, "/")
-		if len(parts) >= 5 {
-			orgName = parts[3]
-			repoName = parts[4]
-			return fmt.Sprintf("http://github.com/%s/%s.git", orgName, repoName)
-		}
-	} else if strings.HasPrefix(link, "https://gitlab.com/") {
-		parts := strings.Split(link, "/")
-		if len(parts) >= 5 {
-			orgName = parts[3]
-			repoName = parts[4]
-			return fmt.Sprintf("https://gitlab.com/%s/%s.git", orgName, repoName)
-		}
-	} else if strings.HasPrefix(link, "http://gitlab.com/") {
-		parts := strings.Split(link, "/")
-		if len(parts) >= 5 {
-			orgName = parts[3]
-			repoName = parts[4]
-			return fmt.Sprintf("http://gitlab.com/%s/%s.git", orgName, repoName)
-		}
-	} else if strings.HasPrefix(link, "https://gitee.com/") {
-		parts := strings.Split(link, "/")
-		if len(parts) >= 5 {
-			orgName = parts[3]
-			repoName = parts[4]
-			return fmt.Sprintf("https://gitee.com/%s/%s.git", orgName, repoName)
-		}
-	} else if strings.HasPrefix(link, "http://gitee.com/") {
-		parts := strings.Split(link, "/")
-		if len(parts) >= 5 {
-			orgName = parts[3]
-			repoName = parts[4]
-			return fmt.Sprintf("http://gitee.com/%s/%s.git", orgName, repoName)
-		}
-	} else if strings.HasPrefix(link, "https://bitbucket.org/") {
-		parts := strings.Split(link, "/")
-		if len(parts) >= 5 {
-			orgName = parts[3]
-			repoName = parts[4]
-			return fmt.Sprintf("https://bitbucket.org/%s/%s", orgName, repoName)
-		}
-	} else if strings.HasPrefix(link, "http://bitbucket.org/") {
-		parts := strings.Split(link, "/")
-		if len(parts) >= 5 {
-			orgName = parts[3]
-			repoName = parts[4]
-			return fmt.Sprintf("http://bitbucket.org/%s/%s", orgName, repoName)
-		}
-	}
-
-	return "" // 如果不符合任何协议，返回空字符串
-}
-
 func (NixCollector *NixCollector) countDependencies(packages map[DepInfo][]DepInfo) {
 	countMap := make(map[string]int)
 	depMap := make(map[string][]string)
@@ -694,13 +562,6 @@ func (NixCollector *NixCollector) countDependencies(packages map[DepInfo][]DepIn
 	}
 }
 
-func isUniqueViolation(err error) bool {
-	if pqErr, ok := err.(*pq.Error); ok {
-		return pqErr.Code == "23505"
-	}
-	return false
-}
-
 type WorkerFunc func(worker int)
 
 func WorkerPool(n int, w WorkerFunc) (waitFunc func()) {
@@ -2,18 +2,70 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
+	"time"
 	"unsafe"
 
+	"github.com/HUSTSecLab/criticality_score/pkg/collector/mirror"
+	"github.com/HUSTSecLab/criticality_score/pkg/depsdevclient"
+	"github.com/HUSTSecLab/criticality_score/pkg/failure"
+	"github.com/HUSTSecLab/criticality_score/pkg/freshness"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/bundle"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/clonestore"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/pathmap"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/probe"
+	gitUtil "github.com/HUSTSecLab/criticality_score/pkg/gitfile/util"
+	"github.com/HUSTSecLab/criticality_score/pkg/graph"
+	"github.com/HUSTSecLab/criticality_score/pkg/httpcache"
+	"github.com/HUSTSecLab/criticality_score/pkg/librariesio"
 	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/priority"
+	"github.com/HUSTSecLab/criticality_score/pkg/progress"
+	"github.com/HUSTSecLab/criticality_score/pkg/ratelimit"
+	"github.com/HUSTSecLab/criticality_score/pkg/rawresponse"
+	"github.com/HUSTSecLab/criticality_score/pkg/respcache"
+	"github.com/HUSTSecLab/criticality_score/pkg/sampling"
+	"github.com/HUSTSecLab/criticality_score/pkg/scope"
+	"github.com/HUSTSecLab/criticality_score/pkg/stackexchange"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/spool"
+	"github.com/HUSTSecLab/criticality_score/pkg/tagging"
+	"github.com/HUSTSecLab/criticality_score/pkg/translate"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
 var (
-	databaseRegisted = false
-	logRegisted      = false
+	databaseRegisted      = false
+	logRegisted           = false
+	sampleRegisted        = false
+	httpCacheRegisted     = false
+	archiveRegisted       = false
+	outputRegisted        = false
+	bundleRegisted        = false
+	priorityRegisted      = false
+	tagRegisted           = false
+	scopeRegisted         = false
+	scoreRegisted         = false
+	progressRegisted      = false
+	failureRegisted       = false
+	spoolRegisted         = false
+	mirrorRegisted        = false
+	depsDevRegisted       = false
+	librariesIORegisted   = false
+	stackExchangeRegisted = false
+
+	githubTokenRegisted = false
+	freshnessRegisted   = false
+	gitStorageRegisted  = false
+	probeRegisted       = false
+	graphRegisted       = false
+	pageRankRegisted    = false
+	slaRegisted         = false
+	apiServerRegisted   = false
+	translationRegisted = false
 )
 
 func RegistConfigFileFlags(flag *pflag.FlagSet) {
@@ -44,6 +96,23 @@ func RegistDatabaseFlags(flag *pflag.FlagSet) {
 	viper.BindEnv("db.password", "DB_PASSWORD")
 	viper.BindEnv("db.password-file", "DB_PASSWORD_FILE")
 	viper.BindEnv("db.use-ssl", "DB_USE_SSL")
+
+	// read-only components, e.g. the api server, connect with the read-only
+	// user if it is set
+	flag.String("db-readonly-host", "", "database host of read-only components, e.g. a replica, default is db-host,\ncan set by environment DB_READONLY_HOST")
+	flag.String("db-readonly-user", "", "database user of read-only components, empty uses db-user,\ncan set by environment DB_READONLY_USER")
+	flag.String("db-readonly-password", "", "database password of db-readonly-user,\ncan set by environment DB_READONLY_PASSWORD")
+	flag.String("db-readonly-password-file", "", "database password file of db-readonly-user, if db-readonly-password is set, this will be ignored,\ncan set by environment DB_READONLY_PASSWORD_FILE")
+
+	viper.BindPFlag("db.readonly.host", flag.Lookup("db-readonly-host"))
+	viper.BindPFlag("db.readonly.user", flag.Lookup("db-readonly-user"))
+	viper.BindPFlag("db.readonly.password", flag.Lookup("db-readonly-password"))
+	viper.BindPFlag("db.readonly.password-file", flag.Lookup("db-readonly-password-file"))
+
+	viper.BindEnv("db.readonly.host", "DB_READONLY_HOST")
+	viper.BindEnv("db.readonly.user", "DB_READONLY_USER")
+	viper.BindEnv("db.readonly.password", "DB_READONLY_PASSWORD")
+	viper.BindEnv("db.readonly.password-file", "DB_READONLY_PASSWORD_FILE")
 }
 
 func RegistLogFlags(flag *pflag.FlagSet) {
@@ -68,17 +137,448 @@ func RegistLogFlags(flag *pflag.FlagSet) {
 }
 
 func RegistGitStorageFlags(flag *pflag.FlagSet) {
+	gitStorageRegisted = true
 	flag.StringP("git-storage", "s", "", "path to git storage location")
+	flag.String("git-storage-layout", "", "layout of clones in git storage: plain or hashed, default is plain on linux and hashed on others,\ncan set by environment GIT_STORAGE_LAYOUT")
+	flag.Int64("git-storage-max-size", 0, "max bytes of clones in git storage, the least recently analyzed clones are removed, 0 means unlimited,\ncan set by environment GIT_STORAGE_MAX_SIZE")
+
 	viper.BindPFlag("git.storage", flag.Lookup("git-storage"))
+	viper.BindPFlag("git.storage-layout", flag.Lookup("git-storage-layout"))
+	viper.BindPFlag("git.storage-max-size", flag.Lookup("git-storage-max-size"))
+
 	viper.BindEnv("git.storage", "GIT_STORAGE_PATH")
+	viper.BindEnv("git.storage-layout", "GIT_STORAGE_LAYOUT")
+	viper.BindEnv("git.storage-max-size", "GIT_STORAGE_MAX_SIZE")
 }
 
 func RegistGithubTokenFlags(flag *pflag.FlagSet) {
+	githubTokenRegisted = true
 	flag.String("github-token", "", "github token")
 	viper.BindPFlag("token.github", flag.Lookup("github-token"))
 	viper.BindEnv("token.github", "GITHUB")
 }
 
+// libraries.io flags are used by the libraries.io collector
+func RegistLibrariesIOFlags(flag *pflag.FlagSet) {
+	librariesIORegisted = true
+	flag.String("librariesio-api-key", "", "api key of libraries.io,\ncan set by environment LIBRARIES_IO_API_KEY")
+	flag.String("librariesio-url", librariesio.DefaultBaseURL, "url of the libraries.io api,\ncan set by environment LIBRARIES_IO_URL")
+	flag.Int("librariesio-retries", librariesio.DefaultMaxRetries, "number of retries of rate limited and server errors of the libraries.io api,\ncan set by environment LIBRARIES_IO_MAX_RETRIES")
+	flag.Duration("librariesio-interval", librariesio.DefaultInterval, "min delay between requests to the libraries.io api, which allows 60 requests per minute,\ncan set by environment LIBRARIES_IO_INTERVAL")
+
+	viper.BindPFlag("token.librariesio", flag.Lookup("librariesio-api-key"))
+	viper.BindPFlag("librariesio.url", flag.Lookup("librariesio-url"))
+	viper.BindPFlag("librariesio.max-retries", flag.Lookup("librariesio-retries"))
+	viper.BindPFlag("librariesio.interval", flag.Lookup("librariesio-interval"))
+
+	viper.BindEnv("token.librariesio", "LIBRARIES_IO_API_KEY")
+	viper.BindEnv("librariesio.url", "LIBRARIES_IO_URL")
+	viper.BindEnv("librariesio.max-retries", "LIBRARIES_IO_MAX_RETRIES")
+	viper.BindEnv("librariesio.interval", "LIBRARIES_IO_INTERVAL")
+}
+
+func RegistStackExchangeFlags(flag *pflag.FlagSet) {
+	stackExchangeRegisted = true
+	flag.String("stackexchange-key", "", "app key of the stack exchange api, which raises the daily quota,\ncan set by environment STACKEXCHANGE_KEY")
+	flag.String("stackexchange-url", stackexchange.DefaultBaseURL, "url of the stack exchange api,\ncan set by environment STACKEXCHANGE_URL")
+	flag.String("stackexchange-site", stackexchange.DefaultSite, "stack exchange site of the questions,\ncan set by environment STACKEXCHANGE_SITE")
+	flag.Int("stackexchange-retries", stackexchange.DefaultMaxRetries, "number of retries of throttled and server errors of the stack exchange api,\ncan set by environment STACKEXCHANGE_MAX_RETRIES")
+
+	viper.BindPFlag("token.stackexchange", flag.Lookup("stackexchange-key"))
+	viper.BindPFlag("stackexchange.url", flag.Lookup("stackexchange-url"))
+	viper.BindPFlag("stackexchange.site", flag.Lookup("stackexchange-site"))
+	viper.BindPFlag("stackexchange.max-retries", flag.Lookup("stackexchange-retries"))
+
+	viper.BindEnv("token.stackexchange", "STACKEXCHANGE_KEY")
+	viper.BindEnv("stackexchange.url", "STACKEXCHANGE_URL")
+	viper.BindEnv("stackexchange.site", "STACKEXCHANGE_SITE")
+	viper.BindEnv("stackexchange.max-retries", "STACKEXCHANGE_MAX_RETRIES")
+}
+
+// sample flags are used to run collectors against a small subset of packages/repos
+func RegistSampleFlags(flag *pflag.FlagSet) {
+	sampleRegisted = true
+	flag.Int("sample", 0, "only handle N random packages/repos, 0 means all,\ncan set by environment SAMPLE_SIZE")
+	flag.String("filter", "", "only handle packages/repos matching the regex,\ncan set by environment SAMPLE_FILTER")
+	flag.Int64("sample-seed", 0, "random seed used by --sample, 0 means random")
+
+	viper.BindPFlag("sample.size", flag.Lookup("sample"))
+	viper.BindPFlag("sample.filter", flag.Lookup("filter"))
+	viper.BindPFlag("sample.seed", flag.Lookup("sample-seed"))
+
+	viper.BindEnv("sample.size", "SAMPLE_SIZE")
+	viper.BindEnv("sample.filter", "SAMPLE_FILTER")
+}
+
+// http cache flags are used by collectors querying deps.dev and registries
+func RegistHTTPCacheFlags(flag *pflag.FlagSet) {
+	httpCacheRegisted = true
+	flag.Duration("cache-ttl", 24*time.Hour, "ttl of cached api responses, 0 disables the cache,\ncan set by environment HTTP_CACHE_TTL")
+	flag.Duration("cache-error-ttl", time.Hour, "ttl of cached api error responses, 0 disables caching errors,\ncan set by environment HTTP_CACHE_ERROR_TTL")
+	flag.Bool("cache-offline", false, "only serve api responses from the cache, expired ones included, to recompute metrics without hitting the network,\ncan set by environment HTTP_CACHE_OFFLINE")
+
+	viper.BindPFlag("http-cache.ttl", flag.Lookup("cache-ttl"))
+	viper.BindPFlag("http-cache.error-ttl", flag.Lookup("cache-error-ttl"))
+	viper.BindPFlag("http-cache.offline", flag.Lookup("cache-offline"))
+
+	viper.BindEnv("http-cache.ttl", "HTTP_CACHE_TTL")
+	viper.BindEnv("http-cache.error-ttl", "HTTP_CACHE_ERROR_TTL")
+	viper.BindEnv("http-cache.offline", "HTTP_CACHE_OFFLINE")
+}
+
+// deps.dev flags are used by collectors querying the deps.dev api
+func RegistDepsDevFlags(flag *pflag.FlagSet) {
+	depsDevRegisted = true
+	flag.String("depsdev-api-version", depsdevclient.DefaultAPIVersion, "version of the deps.dev api, e.g. v3 once v3alpha is deprecated,\ncan set by environment DEPSDEV_API_VERSION")
+	flag.String("depsdev-url", depsdevclient.DefaultBaseURL, "url of the deps.dev api without the version,\ncan set by environment DEPSDEV_URL")
+	flag.Int("depsdev-retries", depsdevclient.DefaultMaxRetries, "number of retries of rate limited and server errors of the deps.dev api,\ncan set by environment DEPSDEV_MAX_RETRIES")
+
+	viper.BindPFlag("depsdev.api-version", flag.Lookup("depsdev-api-version"))
+	viper.BindPFlag("depsdev.url", flag.Lookup("depsdev-url"))
+	viper.BindPFlag("depsdev.max-retries", flag.Lookup("depsdev-retries"))
+
+	viper.BindEnv("depsdev.api-version", "DEPSDEV_API_VERSION")
+	viper.BindEnv("depsdev.url", "DEPSDEV_URL")
+	viper.BindEnv("depsdev.max-retries", "DEPSDEV_MAX_RETRIES")
+}
+
+// response archive flags are used by collectors querying apis, to keep the
+// raw responses of a run for audits and reprocessing
+func RegistResponseArchiveFlags(flag *pflag.FlagSet) {
+	archiveRegisted = true
+	flag.Bool("archive-responses", false, "archive the raw json api responses of the run, compressed,\ncan set by environment ARCHIVE_RESPONSES")
+	flag.String("run-id", "", "id of the run keying the archived responses, the start time by default,\ncan set by environment RUN_ID")
+
+	viper.BindPFlag("response-archive.enabled", flag.Lookup("archive-responses"))
+	viper.BindPFlag("response-archive.run-id", flag.Lookup("run-id"))
+
+	viper.BindEnv("response-archive.enabled", "ARCHIVE_RESPONSES")
+	viper.BindEnv("response-archive.run-id", "RUN_ID")
+}
+
+// output flags are used by collectors which can run without a database,
+// writing their rows to files instead
+func RegistOutputFlags(flag *pflag.FlagSet) {
+	outputRegisted = true
+	flag.String("output", "postgres", "where to write the collected rows: postgres, jsonl, csv or stdout,\ncan set by environment OUTPUT_FORMAT")
+	flag.String("output-path", "", "file of jsonl output, stdout if empty, or directory of csv output,\ncan set by environment OUTPUT_PATH")
+	flag.String("output-mode", string(gitUtil.ModeTruncate), "how existing output files are written: truncate, append, or rotate, which renames them by their modification time,\ncan set by environment OUTPUT_MODE")
+	flag.String("input", "", "csv file of the git links to collect, - for stdin, read instead of the database if the output is not postgres,\ncan set by environment INPUT_FILE")
+	flag.String("url-column", "", "column of the git links in --input, a header name or an index from 1, default is a url column of the header or the first column,\ncan set by environment INPUT_URL_COLUMN")
+	flag.Int("write-batch", 100, "max number of rows written to the database at once by the writer of a table,\ncan set by environment OUTPUT_WRITE_BATCH")
+	flag.Int("write-queue", 1000, "max number of rows waiting for the writer of a table, workers wait when it is full,\ncan set by environment OUTPUT_WRITE_QUEUE")
+
+	viper.BindPFlag("output.format", flag.Lookup("output"))
+	viper.BindPFlag("output.path", flag.Lookup("output-path"))
+	viper.BindPFlag("output.mode", flag.Lookup("output-mode"))
+	viper.BindPFlag("output.input", flag.Lookup("input"))
+	viper.BindPFlag("output.url-column", flag.Lookup("url-column"))
+	viper.BindPFlag("output.write-batch", flag.Lookup("write-batch"))
+	viper.BindPFlag("output.write-queue", flag.Lookup("write-queue"))
+
+	viper.BindEnv("output.format", "OUTPUT_FORMAT")
+	viper.BindEnv("output.path", "OUTPUT_PATH")
+	viper.BindEnv("output.mode", "OUTPUT_MODE")
+	viper.BindEnv("output.input", "INPUT_FILE")
+	viper.BindEnv("output.url-column", "INPUT_URL_COLUMN")
+	viper.BindEnv("output.write-batch", "OUTPUT_WRITE_BATCH")
+	viper.BindEnv("output.write-queue", "OUTPUT_WRITE_QUEUE")
+}
+
+// freshness flags are used by collectors to skip repos collected recently,
+// so accidental double runs do not repeat expensive api work
+func RegistFreshnessFlags(flag *pflag.FlagSet) {
+	freshnessRegisted = true
+	flag.Duration("freshness", 12*time.Hour, "skip repos collected within the duration, 0 collects all repos,\ncan set by environment FRESHNESS_WINDOW")
+	viper.BindPFlag("freshness", flag.Lookup("freshness"))
+	viper.BindEnv("freshness", "FRESHNESS_WINDOW")
+}
+
+// tag flags are used by commands to only handle projects with some tags
+func RegistTagFlags(flag *pflag.FlagSet) {
+	tagRegisted = true
+	flag.StringSlice("tag", nil, "only handle projects with any of the tags, can be repeated,\ncan set by environment PROJECT_TAGS, separated by commas")
+	viper.BindPFlag("tag", flag.Lookup("tag"))
+	viper.BindEnv("tag", "PROJECT_TAGS")
+}
+
+// scope flags are used by enumerators, collectors and the scorer to skip
+// ecosystems, distributions and languages out of the scope of a deployment
+func RegistScopeFlags(flag *pflag.FlagSet) {
+	scopeRegisted = true
+	flag.StringSlice("ecosystems", nil, "only handle projects of the language ecosystems, e.g. npm,pypi,\ncan set by environment SCOPE_ECOSYSTEMS")
+	flag.StringSlice("exclude-ecosystems", nil, "skip projects of the language ecosystems,\ncan set by environment SCOPE_EXCLUDE_ECOSYSTEMS")
+	flag.StringSlice("distros", nil, "only handle projects of the distributions, e.g. debian,archlinux,\ncan set by environment SCOPE_DISTROS")
+	flag.StringSlice("exclude-distros", nil, "skip projects of the distributions,\ncan set by environment SCOPE_EXCLUDE_DISTROS")
+	flag.StringSlice("languages", nil, "only handle repos of the languages, e.g. python,c,\ncan set by environment SCOPE_LANGUAGES")
+	flag.StringSlice("exclude-languages", nil, "skip repos of the languages,\ncan set by environment SCOPE_EXCLUDE_LANGUAGES")
+	flag.String("include", "", "only handle names or links matching the regex,\ncan set by environment SCOPE_INCLUDE")
+	flag.String("exclude", "", "skip names or links matching the regex,\ncan set by environment SCOPE_EXCLUDE")
+
+	for _, key := range []string{"ecosystems", "exclude-ecosystems", "distros", "exclude-distros", "languages", "exclude-languages", "include", "exclude"} {
+		viper.BindPFlag("scope."+key, flag.Lookup(key))
+		viper.BindEnv("scope."+key, "SCOPE_"+strings.ToUpper(strings.ReplaceAll(key, "-", "_")))
+	}
+}
+
+// priority flags are used by collectors to refresh the most critical repos
+// first, so they have recent metrics even if the quota is tight
+func RegistPriorityFlags(flag *pflag.FlagSet) {
+	priorityRegisted = true
+	flag.Duration("priority-half-life", 7*24*time.Hour, "collect repos ordered by score and age of metrics, metrics of the age are half fresh, 0 keeps the order,\ncan set by environment PRIORITY_HALF_LIFE")
+	flag.Int("limit", 0, "max number of repos to collect in priority order, 0 means no limit,\ncan set by environment COLLECT_LIMIT")
+
+	viper.BindPFlag("priority.half-life", flag.Lookup("priority-half-life"))
+	viper.BindPFlag("priority.limit", flag.Lookup("limit"))
+
+	viper.BindEnv("priority.half-life", "PRIORITY_HALF_LIFE")
+	viper.BindEnv("priority.limit", "COLLECT_LIMIT")
+}
+
+// bundle flags are used by git collectors to store cloned repos as git
+// bundles in S3-compatible storage, bundles are disabled without a bucket
+func RegistBundleFlags(flag *pflag.FlagSet) {
+	bundleRegisted = true
+	flag.String("s3-endpoint", "", "endpoint of S3-compatible storage for git bundles, e.g. http://minio:9000,\ncan set by environment S3_ENDPOINT")
+	flag.String("s3-bucket", "", "bucket of git bundles, empty disables bundles,\ncan set by environment S3_BUCKET")
+	flag.String("s3-region", "us-east-1", "region of the bucket,\ncan set by environment S3_REGION")
+	flag.String("s3-access-key", "", "access key of the bucket,\ncan set by environment S3_ACCESS_KEY")
+	flag.String("s3-secret-key", "", "secret key of the bucket,\ncan set by environment S3_SECRET_KEY")
+	flag.String("bundle-cache-dir", "./bundles", "local cache directory of git bundles,\ncan set by environment BUNDLE_CACHE_DIR")
+	flag.Int64("bundle-cache-size", 50<<30, "max bytes of cached git bundles, 0 means unlimited,\ncan set by environment BUNDLE_CACHE_SIZE")
+
+	viper.BindPFlag("bundle.s3-endpoint", flag.Lookup("s3-endpoint"))
+	viper.BindPFlag("bundle.s3-bucket", flag.Lookup("s3-bucket"))
+	viper.BindPFlag("bundle.s3-region", flag.Lookup("s3-region"))
+	viper.BindPFlag("bundle.s3-access-key", flag.Lookup("s3-access-key"))
+	viper.BindPFlag("bundle.s3-secret-key", flag.Lookup("s3-secret-key"))
+	viper.BindPFlag("bundle.cache-dir", flag.Lookup("bundle-cache-dir"))
+	viper.BindPFlag("bundle.cache-size", flag.Lookup("bundle-cache-size"))
+
+	viper.BindEnv("bundle.s3-endpoint", "S3_ENDPOINT")
+	viper.BindEnv("bundle.s3-bucket", "S3_BUCKET")
+	viper.BindEnv("bundle.s3-region", "S3_REGION")
+	viper.BindEnv("bundle.s3-access-key", "S3_ACCESS_KEY")
+	viper.BindEnv("bundle.s3-secret-key", "S3_SECRET_KEY")
+	viper.BindEnv("bundle.cache-dir", "BUNDLE_CACHE_DIR")
+	viper.BindEnv("bundle.cache-size", "BUNDLE_CACHE_SIZE")
+}
+
+// probe flags are used by git collectors to collect very large repos from
+// their HEAD instead of cloning the whole history
+func RegistProbeFlags(flag *pflag.FlagSet) {
+	probeRegisted = true
+	flag.Int64("probe-threshold", 0, "collect repos larger than the bytes reported by github from HEAD only, without history, 0 disables,\ncan set by environment PROBE_SIZE_THRESHOLD")
+	viper.BindPFlag("probe.threshold", flag.Lookup("probe-threshold"))
+	viper.BindEnv("probe.threshold", "PROBE_SIZE_THRESHOLD")
+}
+
+// graph flags are used by commands querying dependency graphs, to share
+// them in memory and in memory-mapped files instead of loading them from
+// the database every time
+func RegistGraphFlags(flag *pflag.FlagSet) {
+	graphRegisted = true
+	flag.Bool("graph-service", false, "keep dependency graphs in memory, shared by PageRank, closures and reachability queries,\ncan set by environment GRAPH_SERVICE")
+	flag.String("graph-dir", "", "directory of the graph files shared by processes, graphs are only kept in memory if empty,\ncan set by environment GRAPH_DIR")
+	flag.Duration("graph-refresh", time.Hour, "interval long-running services reload the graphs from the database, 0 disables reloading,\ncan set by environment GRAPH_REFRESH")
+
+	viper.BindPFlag("graph.enabled", flag.Lookup("graph-service"))
+	viper.BindPFlag("graph.dir", flag.Lookup("graph-dir"))
+	viper.BindPFlag("graph.refresh", flag.Lookup("graph-refresh"))
+
+	viper.BindEnv("graph.enabled", "GRAPH_SERVICE")
+	viper.BindEnv("graph.dir", "GRAPH_DIR")
+	viper.BindEnv("graph.refresh", "GRAPH_REFRESH")
+}
+
+// pagerank flags are used by the collectors ranking dependency graphs, the
+// parameters of single collectors are set in the config file, e.g.
+//
+//	pagerank:
+//	  collectors:
+//	    debian: {iterations: 50, weighting: dependents}
+func RegistPageRankFlags(flag *pflag.FlagSet) {
+	pageRankRegisted = true
+	flag.Int("pagerank-iterations", 0, "max iterations of PageRank, 0 is the default of the collector, 20 for distributions,\ncan set by environment PAGERANK_ITERATIONS")
+	flag.Float64("pagerank-damping", 0, "damping factor of PageRank, 0 is the default of the collector, 0.85,\ncan set by environment PAGERANK_DAMPING")
+	flag.Float64("pagerank-epsilon", 0, "stop PageRank once the ranks change less, in sum of absolute changes, 0 is the default of the collector,\ncan set by environment PAGERANK_EPSILON")
+	flag.String("pagerank-weighting", "", "how packages split their rank among dependencies, uniform or dependents, empty is the default of the collector, uniform,\ncan set by environment PAGERANK_WEIGHTING")
+
+	viper.BindPFlag("pagerank.iterations", flag.Lookup("pagerank-iterations"))
+	viper.BindPFlag("pagerank.damping", flag.Lookup("pagerank-damping"))
+	viper.BindPFlag("pagerank.epsilon", flag.Lookup("pagerank-epsilon"))
+	viper.BindPFlag("pagerank.weighting", flag.Lookup("pagerank-weighting"))
+
+	viper.BindEnv("pagerank.iterations", "PAGERANK_ITERATIONS")
+	viper.BindEnv("pagerank.damping", "PAGERANK_DAMPING")
+	viper.BindEnv("pagerank.epsilon", "PAGERANK_EPSILON")
+	viper.BindEnv("pagerank.weighting", "PAGERANK_WEIGHTING")
+}
+
+// sla flags are used by the freshness monitor, the max ages of single
+// collectors or signals are set in the config file, e.g.
+//
+//	sla:
+//	  max-ages:
+//	    nix: 720h
+//	    wikidata: 2160h
+func RegistSLAFlags(flag *pflag.FlagSet) {
+	slaRegisted = true
+	flag.Duration("sla-max-age", freshness.DefaultMaxAge, "max age of the last successful run of collectors and of the signals of projects,\ncan set by environment SLA_MAX_AGE")
+	flag.Float64("sla-max-stale-rate", freshness.DefaultMaxStaleRate, "max rate of projects whose signal is older than its max age,\ncan set by environment SLA_MAX_STALE_RATE")
+
+	viper.BindPFlag("sla.max-age", flag.Lookup("sla-max-age"))
+	viper.BindPFlag("sla.max-stale-rate", flag.Lookup("sla-max-stale-rate"))
+
+	viper.BindEnv("sla.max-age", "SLA_MAX_AGE")
+	viper.BindEnv("sla.max-stale-rate", "SLA_MAX_STALE_RATE")
+}
+
+// api server flags are used by the public api server, to limit clients and
+// cache responses in front of the database
+func RegistAPIServerFlags(flag *pflag.FlagSet) {
+	apiServerRegisted = true
+	flag.Float64("rate-limit", 10, "requests per second of every client IP, 0 disables the limit,\ncan set by environment API_RATE_LIMIT")
+	flag.Int("rate-burst", 40, "requests a client IP can send at once,\ncan set by environment API_RATE_BURST")
+	flag.Bool("trust-proxy", false, "take client IPs from X-Forwarded-For, only behind a CDN or a reverse proxy,\ncan set by environment API_TRUST_PROXY")
+	flag.Int("proxy-hops", 1, "number of proxies in front of the server with --trust-proxy, the client IP is the address\nthe outermost of them got the request from, can set by environment API_PROXY_HOPS")
+	flag.Duration("response-cache-ttl", 5*time.Minute, "ttl of cached responses, also the max-age of Cache-Control, 0 disables the cache,\ncan set by environment API_RESPONSE_CACHE_TTL")
+	flag.Int("response-cache-size", 10000, "max number of cached responses,\ncan set by environment API_RESPONSE_CACHE_SIZE")
+
+	viper.BindPFlag("api.rate-limit", flag.Lookup("rate-limit"))
+	viper.BindPFlag("api.rate-burst", flag.Lookup("rate-burst"))
+	viper.BindPFlag("api.trust-proxy", flag.Lookup("trust-proxy"))
+	viper.BindPFlag("api.proxy-hops", flag.Lookup("proxy-hops"))
+	viper.BindPFlag("api.response-cache-ttl", flag.Lookup("response-cache-ttl"))
+	viper.BindPFlag("api.response-cache-size", flag.Lookup("response-cache-size"))
+
+	viper.BindEnv("api.rate-limit", "API_RATE_LIMIT")
+	viper.BindEnv("api.rate-burst", "API_RATE_BURST")
+	viper.BindEnv("api.trust-proxy", "API_TRUST_PROXY")
+	viper.BindEnv("api.proxy-hops", "API_PROXY_HOPS")
+	viper.BindEnv("api.response-cache-ttl", "API_RESPONSE_CACHE_TTL")
+	viper.BindEnv("api.response-cache-size", "API_RESPONSE_CACHE_SIZE")
+}
+
+// translation flags are used by the api server, to serve the descriptions
+// of packages in the language of its users
+func RegistTranslationFlags(flag *pflag.FlagSet) {
+	translationRegisted = true
+	flag.String("translate-to", "", "language descriptions are translated to, e.g. zh, requests may ask for another one by ?lang=, none if empty,\ncan set by environment TRANSLATION_LANGUAGE")
+	flag.String("translation-provider", "", "provider translating descriptions, libretranslate or llm, translation is disabled if empty,\ncan set by environment TRANSLATION_PROVIDER")
+	flag.String("translation-url", "", "base url of the translation provider, e.g. http://localhost:5000 for libretranslate or http://localhost:11434 for ollama,\ncan set by environment TRANSLATION_URL")
+	flag.String("translation-api-key", "", "api key of the translation provider, if it requires one,\ncan set by environment TRANSLATION_API_KEY")
+	flag.String("translation-model", "", "model translating descriptions, only used by the llm provider,\ncan set by environment TRANSLATION_MODEL")
+	flag.Duration("translation-timeout", translate.DefaultTimeout, "timeout of a translation,\ncan set by environment TRANSLATION_TIMEOUT")
+	flag.Int("translation-cache-size", 10000, "number of translations kept in memory,\ncan set by environment TRANSLATION_CACHE_SIZE")
+
+	viper.BindPFlag("translation.language", flag.Lookup("translate-to"))
+	viper.BindPFlag("translation.provider", flag.Lookup("translation-provider"))
+	viper.BindPFlag("translation.url", flag.Lookup("translation-url"))
+	viper.BindPFlag("translation.api-key", flag.Lookup("translation-api-key"))
+	viper.BindPFlag("translation.model", flag.Lookup("translation-model"))
+	viper.BindPFlag("translation.timeout", flag.Lookup("translation-timeout"))
+	viper.BindPFlag("translation.cache-size", flag.Lookup("translation-cache-size"))
+
+	viper.BindEnv("translation.language", "TRANSLATION_LANGUAGE")
+	viper.BindEnv("translation.provider", "TRANSLATION_PROVIDER")
+	viper.BindEnv("translation.url", "TRANSLATION_URL")
+	viper.BindEnv("translation.api-key", "TRANSLATION_API_KEY")
+	viper.BindEnv("translation.model", "TRANSLATION_MODEL")
+	viper.BindEnv("translation.timeout", "TRANSLATION_TIMEOUT")
+	viper.BindEnv("translation.cache-size", "TRANSLATION_CACHE_SIZE")
+}
+
+// score flags are used by the score calculator to weight the projects of
+// some ecosystems differently
+func RegistScoreFlags(flag *pflag.FlagSet) {
+	scoreRegisted = true
+	flag.String("score-profiles", "", "json or yaml file of the scoring profiles, which override weights for projects of some ecosystems,\ncan set by environment SCORE_PROFILES_FILE")
+	flag.String("score-formulas", "", "json or yaml file of named scoring formulas, which are computed side by side with the stored one,\ncan set by environment SCORE_FORMULAS_FILE")
+	flag.String("score-formula", "default", "formula of the stored scores, default is the default weights,\ncan set by environment SCORE_FORMULA")
+	flag.Duration("score-decay-grace", freshness.DefaultGrace, "age of signals until which they are not discounted,\ncan set by environment SCORE_DECAY_GRACE")
+	flag.Duration("score-decay-half-life", freshness.DefaultHalfLife, "signals older than the grace are halved every half-life, 0 only discounts signals by their confidence,\ncan set by environment SCORE_DECAY_HALF_LIFE")
+
+	viper.BindPFlag("score.profiles-file", flag.Lookup("score-profiles"))
+	viper.BindPFlag("score.formulas-file", flag.Lookup("score-formulas"))
+	viper.BindPFlag("score.formula", flag.Lookup("score-formula"))
+	viper.BindPFlag("score.decay-grace", flag.Lookup("score-decay-grace"))
+	viper.BindPFlag("score.decay-half-life", flag.Lookup("score-decay-half-life"))
+
+	viper.BindEnv("score.profiles-file", "SCORE_PROFILES_FILE")
+	viper.BindEnv("score.formulas-file", "SCORE_FORMULAS_FILE")
+	viper.BindEnv("score.formula", "SCORE_FORMULA")
+	viper.BindEnv("score.decay-grace", "SCORE_DECAY_GRACE")
+	viper.BindEnv("score.decay-half-life", "SCORE_DECAY_HALF_LIFE")
+}
+
+// progress flags are used by long collector runs to show their progress
+func RegistProgressFlags(flag *pflag.FlagSet) {
+	progressRegisted = true
+	flag.Bool("tui", false, "show progress bars, throughput, ETA and recent errors on terminals, plain logs otherwise,\ncan set by environment PROGRESS_TUI")
+	flag.Duration("progress-interval", progress.DefaultInterval, "interval of progress logs without the TUI, 0 only logs the start and the end of phases,\ncan set by environment PROGRESS_INTERVAL")
+
+	viper.BindPFlag("progress.tui", flag.Lookup("tui"))
+	viper.BindPFlag("progress.interval", flag.Lookup("progress-interval"))
+
+	viper.BindEnv("progress.tui", "PROGRESS_TUI")
+	viper.BindEnv("progress.interval", "PROGRESS_INTERVAL")
+}
+
+// failure flags are used by collectors tolerating failures of single items
+func RegistFailureFlags(flag *pflag.FlagSet) {
+	failureRegisted = true
+	flag.Float64("max-failure-rate", failure.DefaultMaxRate, "max rate of failed items, from 0 to 1, a run with more failures exits with 1 instead of 2,\ncan set by environment MAX_FAILURE_RATE")
+	flag.String("failure-summary", "", "file of the json summary of failed items, stderr if empty,\ncan set by environment FAILURE_SUMMARY_FILE")
+
+	viper.BindPFlag("failure.max-rate", flag.Lookup("max-failure-rate"))
+	viper.BindPFlag("failure.summary-file", flag.Lookup("failure-summary"))
+
+	viper.BindEnv("failure.max-rate", "MAX_FAILURE_RATE")
+	viper.BindEnv("failure.summary-file", "FAILURE_SUMMARY_FILE")
+}
+
+// spool flags are used by collectors to keep their writes on local disk
+// while the database is unreachable, see pkg/storage/spool
+func RegistSpoolFlags(flag *pflag.FlagSet) {
+	spoolRegisted = true
+	flag.String("spool-dir", "", "directory spooling the writes failed as the database is unreachable, empty disables the spool,\ncan set by environment SPOOL_DIR")
+
+	viper.BindPFlag("spool.dir", flag.Lookup("spool-dir"))
+
+	viper.BindEnv("spool.dir", "SPOOL_DIR")
+}
+
+// mirror flags are used by the distribution collectors, to benchmark the
+// mirrors and download from the fastest one, the candidates of
+// distributions can be replaced in the config file, e.g.
+//
+//	mirror:
+//	  candidates:
+//	    debian:
+//	      - url: https://ftp.fr.debian.org/debian/
+//	        region: eu
+func RegistMirrorFlags(flag *pflag.FlagSet) {
+	mirrorRegisted = true
+	flag.String("mirror-region", "", "prefer the mirrors of the region, cn, asia, eu, us or global, empty selects the fastest of all,\ncan set by environment MIRROR_REGION")
+	flag.StringSlice("mirror", nil, "pin the mirror of a distribution as distro=url, skipping the benchmark, can be repeated,\ncan set by environment MIRRORS, separated by commas")
+	flag.String("mirror-cache", "", "file caching the selected mirrors between runs, empty benchmarks the mirrors every run,\ncan set by environment MIRROR_CACHE")
+	flag.Duration("mirror-cache-ttl", mirror.DefaultCacheTTL, "age of the cached mirrors after which they are benchmarked again,\ncan set by environment MIRROR_CACHE_TTL")
+	flag.Duration("mirror-timeout", mirror.DefaultTimeout, "timeout of the benchmark of a mirror,\ncan set by environment MIRROR_TIMEOUT")
+
+	viper.BindPFlag("mirror.region", flag.Lookup("mirror-region"))
+	viper.BindPFlag("mirror.mirrors", flag.Lookup("mirror"))
+	viper.BindPFlag("mirror.cache", flag.Lookup("mirror-cache"))
+	viper.BindPFlag("mirror.cache-ttl", flag.Lookup("mirror-cache-ttl"))
+	viper.BindPFlag("mirror.timeout", flag.Lookup("mirror-timeout"))
+
+	viper.BindEnv("mirror.region", "MIRROR_REGION")
+	viper.BindEnv("mirror.mirrors", "MIRRORS")
+	viper.BindEnv("mirror.cache", "MIRROR_CACHE")
+	viper.BindEnv("mirror.cache-ttl", "MIRROR_CACHE_TTL")
+	viper.BindEnv("mirror.timeout", "MIRROR_TIMEOUT")
+}
+
 // include config file, database, log
 func RegistCommonFlags(flag *pflag.FlagSet) {
 	RegistConfigFileFlags(flag)
@@ -86,6 +586,16 @@ func RegistCommonFlags(flag *pflag.FlagSet) {
 	RegistLogFlags(flag)
 }
 
+// ReadConfigFile reads the config file set by --config, if any.
+func ReadConfigFile() error {
+	configFile := viper.GetString("config-file")
+	if configFile == "" {
+		return nil
+	}
+	viper.SetConfigFile(configFile)
+	return viper.ReadInConfig()
+}
+
 // parse flags and set config
 func ParseFlags(flag *pflag.FlagSet) {
 	// set flag error handling to continue
@@ -103,18 +613,122 @@ func ParseFlags(flag *pflag.FlagSet) {
 		}
 	}
 
-	configFile := viper.GetString("config-file")
-	if configFile != "" {
-		viper.SetConfigFile(configFile)
-		viper.ReadInConfig()
+	if err := ReadConfigFile(); err != nil {
+		logger.Fatalf("Failed to read config file: %v", err)
+	}
+	if err := Validate(); err != nil {
+		logger.Fatalf("Invalid config, run `config check` for details:\n%v", err)
 	}
 
 	if databaseRegisted {
 		storage.InitDefaultDatabaseContext(GetDatabaseConfig())
+		storage.InitDefaultReadOnlyDatabaseContext(GetReadOnlyDatabaseConfig())
+	}
+
+	if spoolRegisted && databaseRegisted {
+		if dir := GetSpoolDir(); dir != "" {
+			s := spool.New(dir, filepath.Base(os.Args[0]))
+			storage.WrapDefaultDatabaseContext(func(ac storage.AppDatabaseContext) storage.AppDatabaseContext {
+				return spool.Wrap(ac, s)
+			})
+		}
 	}
 
 	if logRegisted {
 		logger.Config(GetLogConfig())
 	}
 
+	if sampleRegisted {
+		if err := sampling.InitDefault(GetSampleConfig()); err != nil {
+			logger.Fatalf("Invalid sample config: %v", err)
+		}
+	}
+
+	if archiveRegisted {
+		rawresponse.InitDefault(GetResponseArchiveConfig())
+	}
+
+	// the default client also archives responses
+	if httpCacheRegisted || archiveRegisted {
+		httpcache.InitDefault(GetHTTPCacheConfig())
+	}
+
+	// the deps.dev client sends requests by the default http cache client
+	if depsDevRegisted {
+		depsdevclient.InitDefault(GetDepsDevConfig())
+	}
+
+	if tagRegisted {
+		if err := tagging.InitDefault(storage.GetDefaultAppDatabaseContext(), GetTagConfig()); err != nil {
+			logger.Fatalf("Failed to load tags: %v", err)
+		}
+	}
+
+	if scopeRegisted {
+		// languages of repos are stored in the database, names are matched
+		// without it
+		var ac storage.AppDatabaseContext
+		if UsesDatabase() {
+			ac = storage.GetDefaultAppDatabaseContext()
+		}
+		if err := scope.InitDefault(ac, GetScopeConfig()); err != nil {
+			logger.Fatalf("Failed to init scope: %v", err)
+		}
+	}
+
+	if priorityRegisted {
+		priority.InitDefault(GetPriorityConfig())
+	}
+
+	if bundleRegisted {
+		bundle.InitDefault(GetBundleConfig())
+	}
+
+	if gitStorageRegisted {
+		// validated, so it does not fail
+		pathmap.InitDefault(GetGitStorageLayout())
+		clonestore.InitDefault(GetCloneStoreConfig())
+	}
+
+	if probeRegisted {
+		probe.InitDefault(GetProbeConfig())
+	}
+
+	if graphRegisted {
+		graph.InitDefault(storage.GetDefaultAppDatabaseContext(), GetGraphServiceConfig())
+	}
+
+	if mirrorRegisted {
+		// validated, so it does not fail
+		cfg, _ := GetMirrorConfig()
+		mirror.InitDefault(cfg)
+	}
+
+	if pageRankRegisted {
+		// validated, so it does not fail
+		cfg, _ := GetPageRankConfig()
+		graph.InitPageRank(cfg)
+	}
+
+	if apiServerRegisted {
+		ratelimit.InitDefault(GetRateLimitConfig())
+		respcache.InitDefault(GetResponseCacheConfig())
+	}
+
+	if translationRegisted {
+		if err := translate.InitDefault(GetTranslationConfig()); err != nil {
+			logger.Fatalf("Failed to init translation: %v", err)
+		}
+	}
+
+	// the caller stops it with progress.Default().Stop()
+	if progressRegisted {
+		progress.InitDefault(GetProgressConfig())
+	}
+
+	// the caller exits with failure.Default().Exit()
+	if failureRegisted {
+		failure.InitDefault(GetFailureConfig())
+	}
+
 }
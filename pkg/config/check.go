@@ -0,0 +1,96 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/objectstore"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/spf13/viper"
+)
+
+// GithubAPIURL is the url used to check the github token.
+var GithubAPIURL = "https://api.github.com/user"
+
+// CheckTimeout is the timeout of each connectivity check.
+const CheckTimeout = 10 * time.Second
+
+// CheckResult is the result of a connectivity check, Err is nil if the
+// check passed.
+type CheckResult struct {
+	Name string
+	Err  error
+}
+
+func checkDatabase(ctx context.Context, cfg *storage.Config) error {
+	db, err := storage.NewAppDatabase(cfg).GetDatabaseConnection()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.PingContext(ctx)
+}
+
+func checkGithubToken(ctx context.Context, token string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, GithubAPIURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("github returned %s", resp.Status)
+	}
+	return nil
+}
+
+func checkBucket(cfg objectstore.Config) error {
+	// any key works, a missing object means the bucket is reachable and
+	// the keys are accepted
+	_, err := objectstore.NewS3Store(cfg).Get("config-check")
+	if errors.Is(err, objectstore.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// Check tests the connections configured by the registered flags, i.e. the
+// database, the github token and the bucket of git bundles. Validate should
+// pass before Check is called.
+func Check() []CheckResult {
+	ret := make([]CheckResult, 0)
+	run := func(name string, fn func(ctx context.Context) error) {
+		ctx, cancel := context.WithTimeout(context.Background(), CheckTimeout)
+		defer cancel()
+		ret = append(ret, CheckResult{Name: name, Err: fn(ctx)})
+	}
+
+	if databaseRegisted {
+		run("database", func(ctx context.Context) error {
+			return checkDatabase(ctx, GetDatabaseConfig())
+		})
+		if cfg := GetReadOnlyDatabaseConfig(); cfg != nil {
+			run("read-only database", func(ctx context.Context) error {
+				return checkDatabase(ctx, cfg)
+			})
+		}
+	}
+	if githubTokenRegisted && GetGithubToken() != "" {
+		run("github token", func(ctx context.Context) error {
+			return checkGithubToken(ctx, GetGithubToken())
+		})
+	}
+	if bundleRegisted && viper.GetString("bundle.s3-bucket") != "" {
+		run("bundle bucket", func(ctx context.Context) error {
+			return checkBucket(GetBundleConfig().Store)
+		})
+	}
+	return ret
+}
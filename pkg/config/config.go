@@ -1,10 +1,38 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/HUSTSecLab/criticality_score/pkg/collector/mirror"
+	"github.com/HUSTSecLab/criticality_score/pkg/depsdevclient"
+	"github.com/HUSTSecLab/criticality_score/pkg/failure"
+	"github.com/HUSTSecLab/criticality_score/pkg/freshness"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/bundle"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/clonestore"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/pathmap"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/probe"
+	gitUtil "github.com/HUSTSecLab/criticality_score/pkg/gitfile/util"
+	"github.com/HUSTSecLab/criticality_score/pkg/graph"
+	"github.com/HUSTSecLab/criticality_score/pkg/httpcache"
+	"github.com/HUSTSecLab/criticality_score/pkg/librariesio"
 	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/objectstore"
+	"github.com/HUSTSecLab/criticality_score/pkg/output"
+	"github.com/HUSTSecLab/criticality_score/pkg/priority"
+	"github.com/HUSTSecLab/criticality_score/pkg/progress"
+	"github.com/HUSTSecLab/criticality_score/pkg/ratelimit"
+	"github.com/HUSTSecLab/criticality_score/pkg/rawresponse"
+	"github.com/HUSTSecLab/criticality_score/pkg/respcache"
+	"github.com/HUSTSecLab/criticality_score/pkg/sampling"
+	"github.com/HUSTSecLab/criticality_score/pkg/scope"
+	"github.com/HUSTSecLab/criticality_score/pkg/score"
+	"github.com/HUSTSecLab/criticality_score/pkg/stackexchange"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/tagging"
+	"github.com/HUSTSecLab/criticality_score/pkg/translate"
 	"github.com/spf13/viper"
 )
 
@@ -31,6 +59,29 @@ func GetDatabaseConfig() *storage.Config {
 	}
 }
 
+// GetReadOnlyDatabaseConfig returns the config of read-only components, or
+// nil if neither a read-only host nor a read-only user is set.
+func GetReadOnlyDatabaseConfig() *storage.Config {
+	host, user := viper.GetString("db.readonly.host"), viper.GetString("db.readonly.user")
+	if host == "" && user == "" {
+		return nil
+	}
+
+	cfg := GetDatabaseConfig()
+	if host != "" {
+		cfg.Host = host
+	}
+	if user != "" {
+		if viper.GetString("db.readonly.password") == "" && viper.GetString("db.readonly.password-file") != "" {
+			viper.Set("db.readonly.password", readPasswordFromFile(viper.GetString("db.readonly.password-file")))
+		}
+		cfg.User = user
+		cfg.Password = viper.GetString("db.readonly.password")
+	}
+	cfg.ReadOnly = true
+	return cfg
+}
+
 func GetLogConfig() *logger.AppLoggerConfig {
 	var level logger.LoggerLevel
 	var format logger.LoggerFormatType
@@ -86,3 +137,334 @@ func GetGithubToken() string {
 func GetGitStoragePath() string {
 	return viper.GetString("git.storage")
 }
+
+// GetGitStorageLayout returns the layout of clones in git storage, empty
+// means the default layout of the platform.
+func GetGitStorageLayout() pathmap.Layout {
+	return pathmap.Layout(viper.GetString("git.storage-layout"))
+}
+
+func GetCloneStoreConfig() *clonestore.Config {
+	return &clonestore.Config{
+		MaxBytes: viper.GetInt64("git.storage-max-size"),
+	}
+}
+
+func GetProbeConfig() *probe.Config {
+	return &probe.Config{
+		Threshold: viper.GetInt64("probe.threshold"),
+		Token:     GetGithubToken(),
+	}
+}
+
+func GetGraphServiceConfig() *graph.ServiceConfig {
+	return &graph.ServiceConfig{
+		Enabled: viper.GetBool("graph.enabled"),
+		Dir:     viper.GetString("graph.dir"),
+		Refresh: viper.GetDuration("graph.refresh"),
+	}
+}
+
+// GetPageRankConfig returns the PageRank parameters of the flags and of
+// the collectors set in the config file under pagerank.collectors.
+func GetPageRankConfig() (*graph.PageRankConfig, error) {
+	cfg := &graph.PageRankConfig{
+		Default: graph.PageRankParams{
+			Iterations: viper.GetInt("pagerank.iterations"),
+			Damping:    viper.GetFloat64("pagerank.damping"),
+			Epsilon:    viper.GetFloat64("pagerank.epsilon"),
+			Weighting:  graph.Weighting(viper.GetString("pagerank.weighting")),
+		},
+	}
+	if err := viper.UnmarshalKey("pagerank.collectors", &cfg.Collectors); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// GetSLAConfig returns the freshness SLA of the flags and of the
+// collectors and signals set in the config file under sla.max-ages.
+func GetSLAConfig() (*freshness.SLA, error) {
+	cfg := &freshness.SLA{
+		MaxAge:       viper.GetDuration("sla.max-age"),
+		MaxStaleRate: viper.GetFloat64("sla.max-stale-rate"),
+	}
+	if err := viper.UnmarshalKey("sla.max-ages", &cfg.MaxAges); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func GetRateLimitConfig() *ratelimit.Config {
+	return &ratelimit.Config{
+		Rate:       viper.GetFloat64("api.rate-limit"),
+		Burst:      viper.GetInt("api.rate-burst"),
+		TrustProxy: viper.GetBool("api.trust-proxy"),
+		ProxyHops:  viper.GetInt("api.proxy-hops"),
+	}
+}
+
+func GetResponseCacheConfig() *respcache.Config {
+	return &respcache.Config{
+		TTL:        viper.GetDuration("api.response-cache-ttl"),
+		MaxEntries: viper.GetInt("api.response-cache-size"),
+	}
+}
+
+func GetTranslationConfig() *translate.Config {
+	return &translate.Config{
+		Language:  viper.GetString("translation.language"),
+		Provider:  viper.GetString("translation.provider"),
+		URL:       viper.GetString("translation.url"),
+		APIKey:    viper.GetString("translation.api-key"),
+		Model:     viper.GetString("translation.model"),
+		Timeout:   viper.GetDuration("translation.timeout"),
+		CacheSize: viper.GetInt("translation.cache-size"),
+	}
+}
+
+func GetSampleConfig() *sampling.Config {
+	return &sampling.Config{
+		Size:   viper.GetInt("sample.size"),
+		Filter: viper.GetString("sample.filter"),
+		Seed:   viper.GetInt64("sample.seed"),
+	}
+}
+
+func GetHTTPCacheConfig() *httpcache.Config {
+	return &httpcache.Config{
+		TTL:      viper.GetDuration("http-cache.ttl"),
+		ErrorTTL: viper.GetDuration("http-cache.error-ttl"),
+		Offline:  viper.GetBool("http-cache.offline"),
+	}
+}
+
+func GetDepsDevConfig() *depsdevclient.Config {
+	return &depsdevclient.Config{
+		BaseURL:    viper.GetString("depsdev.url"),
+		APIVersion: viper.GetString("depsdev.api-version"),
+		MaxRetries: viper.GetInt("depsdev.max-retries"),
+	}
+}
+
+func GetLibrariesIOConfig() *librariesio.Config {
+	return &librariesio.Config{
+		BaseURL:    viper.GetString("librariesio.url"),
+		APIKey:     viper.GetString("token.librariesio"),
+		MaxRetries: viper.GetInt("librariesio.max-retries"),
+		Interval:   viper.GetDuration("librariesio.interval"),
+	}
+}
+
+func GetStackExchangeConfig() *stackexchange.Config {
+	return &stackexchange.Config{
+		BaseURL:    viper.GetString("stackexchange.url"),
+		Key:        viper.GetString("token.stackexchange"),
+		Site:       viper.GetString("stackexchange.site"),
+		MaxRetries: viper.GetInt("stackexchange.max-retries"),
+	}
+}
+
+func GetResponseArchiveConfig() *rawresponse.Config {
+	return &rawresponse.Config{
+		Enabled: viper.GetBool("response-archive.enabled"),
+		RunID:   viper.GetString("response-archive.run-id"),
+	}
+}
+
+func GetOutputConfig() *output.Config {
+	return &output.Config{
+		Format: output.Format(viper.GetString("output.format")),
+		Path:   viper.GetString("output.path"),
+		Mode:   gitUtil.WriteMode(viper.GetString("output.mode")),
+	}
+}
+
+// GetQueueConfig returns the config of the writers of the database sink.
+func GetQueueConfig() *output.QueueConfig {
+	return &output.QueueConfig{
+		BatchSize: viper.GetInt("output.write-batch"),
+		QueueSize: viper.GetInt("output.write-queue"),
+	}
+}
+
+// UsesDatabase returns false if the collector writes its rows to files, so
+// it must neither read nor write the database.
+func UsesDatabase() bool {
+	if !outputRegisted {
+		return true
+	}
+	format := output.Format(viper.GetString("output.format"))
+	return format == "" || format == output.FormatPostgres
+}
+
+// GetInputPath returns the csv file of the links to collect without a
+// database.
+func GetInputPath() string {
+	return viper.GetString("output.input")
+}
+
+// GetInputURLColumn returns the column of the links in the input file, see
+// util.ParseURLs.
+func GetInputURLColumn() string {
+	return viper.GetString("output.url-column")
+}
+
+// GetFreshnessWindow returns the duration in which collected repos are not
+// collected again, 0 means always collect.
+func GetFreshnessWindow() time.Duration {
+	return viper.GetDuration("freshness")
+}
+
+func GetTagConfig() *tagging.Config {
+	return &tagging.Config{Tags: splitList("tag")}
+}
+
+// splitList returns the comma separated values of a string slice key,
+// environment variables are not split by viper.
+func splitList(key string) []string {
+	var ret []string
+	for _, v := range viper.GetStringSlice(key) {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				ret = append(ret, item)
+			}
+		}
+	}
+	return ret
+}
+
+func GetScopeConfig() *scope.Config {
+	return &scope.Config{
+		Ecosystems:        splitList("scope.ecosystems"),
+		ExcludeEcosystems: splitList("scope.exclude-ecosystems"),
+		Distros:           splitList("scope.distros"),
+		ExcludeDistros:    splitList("scope.exclude-distros"),
+		Languages:         splitList("scope.languages"),
+		ExcludeLanguages:  splitList("scope.exclude-languages"),
+		Include:           viper.GetString("scope.include"),
+		Exclude:           viper.GetString("scope.exclude"),
+	}
+}
+
+// readScoreFile reads a json or yaml file of scoring profiles or formulas
+// into out, nothing if path is empty.
+func readScoreFile(path string, out any) error {
+	if path == "" {
+		return nil
+	}
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return err
+	}
+	return v.Unmarshal(out)
+}
+
+// GetScoreProfiles returns the scoring profiles of the file set by
+// --score-profiles, only the default profile if it is not set. The file
+// maps the names of profiles to their ecosystems and weights.
+func GetScoreProfiles() (*score.Profiles, error) {
+	var profiles map[string]score.Profile
+	if err := readScoreFile(viper.GetString("score.profiles-file"), &profiles); err != nil {
+		return nil, err
+	}
+	return score.NewProfiles(profiles)
+}
+
+// GetScoreFormulas returns the profiles of every formula of the file set by
+// --score-formulas and of the default formula. The file maps the names of
+// formulas to their weights.
+func GetScoreFormulas() (map[string]*score.Profiles, error) {
+	var profiles map[string]score.Profile
+	if err := readScoreFile(viper.GetString("score.profiles-file"), &profiles); err != nil {
+		return nil, err
+	}
+	var formulas map[string]score.Formula
+	if err := readScoreFile(viper.GetString("score.formulas-file"), &formulas); err != nil {
+		return nil, err
+	}
+	return score.NewFormulas(formulas, profiles)
+}
+
+// GetScoreFormula returns the formula of the stored scores.
+func GetScoreFormula() string {
+	if f := viper.GetString("score.formula"); f != "" {
+		return f
+	}
+	return score.DefaultFormula
+}
+
+// GetDecayPolicy returns the policy discounting the signals of scores by
+// their age.
+func GetDecayPolicy() freshness.Policy {
+	return freshness.Policy{
+		Grace:    viper.GetDuration("score.decay-grace"),
+		HalfLife: viper.GetDuration("score.decay-half-life"),
+	}
+}
+
+func GetPriorityConfig() *priority.Config {
+	return &priority.Config{
+		HalfLife: viper.GetDuration("priority.half-life"),
+		Limit:    viper.GetInt("priority.limit"),
+	}
+}
+
+func GetProgressConfig() *progress.Config {
+	return &progress.Config{
+		TUI:      viper.GetBool("progress.tui"),
+		Interval: viper.GetDuration("progress.interval"),
+	}
+}
+
+func GetFailureConfig() *failure.Config {
+	return &failure.Config{
+		MaxRate:     viper.GetFloat64("failure.max-rate"),
+		SummaryFile: viper.GetString("failure.summary-file"),
+	}
+}
+
+// GetSpoolDir returns the directory of the spool, empty if the spool is
+// disabled.
+func GetSpoolDir() string {
+	return viper.GetString("spool.dir")
+}
+
+// GetMirrorConfig returns the mirror config of the flags, the pinned
+// mirrors are distro=url pairs, and of the candidates set in the config
+// file under mirror.candidates.
+func GetMirrorConfig() (*mirror.Config, error) {
+	cfg := &mirror.Config{
+		Region:    viper.GetString("mirror.region"),
+		Mirrors:   map[string]string{},
+		CacheFile: viper.GetString("mirror.cache"),
+		CacheTTL:  viper.GetDuration("mirror.cache-ttl"),
+		Timeout:   viper.GetDuration("mirror.timeout"),
+	}
+	for _, pin := range splitList("mirror.mirrors") {
+		distro, url, ok := strings.Cut(pin, "=")
+		if !ok || distro == "" || url == "" {
+			return nil, fmt.Errorf("%q is not distro=url", pin)
+		}
+		cfg.Mirrors[distro] = url
+	}
+	if err := viper.UnmarshalKey("mirror.candidates", &cfg.Candidates); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func GetBundleConfig() *bundle.Config {
+	return &bundle.Config{
+		Store: objectstore.Config{
+			Endpoint:  viper.GetString("bundle.s3-endpoint"),
+			Bucket:    viper.GetString("bundle.s3-bucket"),
+			Region:    viper.GetString("bundle.s3-region"),
+			AccessKey: viper.GetString("bundle.s3-access-key"),
+			SecretKey: viper.GetString("bundle.s3-secret-key"),
+		},
+		CacheDir:  viper.GetString("bundle.cache-dir"),
+		CacheSize: viper.GetInt64("bundle.cache-size"),
+	}
+}
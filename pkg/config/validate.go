@@ -0,0 +1,423 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/collector/mirror"
+	"github.com/HUSTSecLab/criticality_score/pkg/depsdevclient"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/pathmap"
+	gitUtil "github.com/HUSTSecLab/criticality_score/pkg/gitfile/util"
+	"github.com/HUSTSecLab/criticality_score/pkg/graph"
+	"github.com/HUSTSecLab/criticality_score/pkg/output"
+	"github.com/HUSTSecLab/criticality_score/pkg/translate"
+	"github.com/spf13/viper"
+	"golang.org/x/text/language"
+)
+
+// FieldError is a config value which is missing or malformed.
+type FieldError struct {
+	// Key is the viper key, e.g. db.host
+	Key     string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Key, e.Message)
+}
+
+type validator struct {
+	errs []error
+}
+
+func (v *validator) fail(key string, format string, args ...interface{}) {
+	v.errs = append(v.errs, &FieldError{Key: key, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) required(key string) bool {
+	if viper.GetString(key) == "" {
+		v.fail(key, "is required")
+		return false
+	}
+	return true
+}
+
+func (v *validator) oneOf(key string, values ...string) {
+	s := viper.GetString(key)
+	for _, value := range values {
+		if s == value {
+			return
+		}
+	}
+	v.fail(key, "%q is not one of %v", s, values)
+}
+
+// url checks the value is an absolute http(s) url, empty is allowed.
+func (v *validator) url(key string) {
+	s := viper.GetString(key)
+	if s == "" {
+		return
+	}
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.fail(key, "%q is not a http(s) url", s)
+	}
+}
+
+func (v *validator) nonNegative(key string) {
+	s := viper.GetString(key)
+	if s == "" {
+		return
+	}
+	// durations are checked as well, their string form starts with "-"
+	// if negative
+	if s[0] == '-' {
+		v.fail(key, "%q must not be negative", s)
+	}
+}
+
+func (v *validator) file(key string) {
+	s := viper.GetString(key)
+	if s == "" {
+		return
+	}
+	if _, err := os.Stat(s); err != nil {
+		v.fail(key, "%v", err)
+	}
+}
+
+var githubTokenPattern = regexp.MustCompile(`^([0-9a-f]{40}|(ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})$`)
+
+func (v *validator) githubToken(key string) {
+	s := viper.GetString(key)
+	if s != "" && !githubTokenPattern.MatchString(s) {
+		v.fail(key, "is not a github token")
+	}
+}
+
+// librariesIOAPIKeyPattern matches the api keys of libraries.io, 32 hex
+// digits.
+var librariesIOAPIKeyPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+func (v *validator) librariesIOAPIKey(key string) {
+	s := viper.GetString(key)
+	if s != "" && !librariesIOAPIKeyPattern.MatchString(s) {
+		v.fail(key, "is not a libraries.io api key")
+	}
+}
+
+// stackExchangeSitePattern matches the api names of stack exchange sites,
+// e.g. stackoverflow or ru.stackoverflow.
+var stackExchangeSitePattern = regexp.MustCompile(`^[a-z]+(\.[a-z]+)*$`)
+
+func (v *validator) validateDatabase() {
+	v.required("db.host")
+	// lib/pq can not parse an empty port of the connection string
+	if v.required("db.port") {
+		port := viper.GetString("db.port")
+		if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+			v.fail("db.port", "%q is not a port", port)
+		}
+	}
+	v.required("db.user")
+	if viper.GetString("db.password") == "" {
+		v.file("db.password-file")
+	}
+	if viper.GetString("db.readonly.user") != "" && viper.GetString("db.readonly.password") == "" {
+		v.file("db.readonly.password-file")
+	}
+}
+
+func (v *validator) validateLog() {
+	v.oneOf("log.level", "trace", "debug", "info", "warn", "error", "fatal", "panic")
+	v.oneOf("log.type", "console", "file", "es")
+	switch viper.GetString("log.type") {
+	case "file":
+		v.required("log.path")
+	case "es":
+		if v.required("log.es-url") {
+			v.url("log.es-url")
+		}
+		v.required("log.es-index")
+	}
+}
+
+func (v *validator) validateSample() {
+	v.nonNegative("sample.size")
+	if filter := viper.GetString("sample.filter"); filter != "" {
+		if _, err := regexp.Compile(filter); err != nil {
+			v.fail("sample.filter", "%v", err)
+		}
+	}
+}
+
+func (v *validator) validateBundle() {
+	if viper.GetString("bundle.s3-bucket") == "" {
+		return
+	}
+	if v.required("bundle.s3-endpoint") {
+		v.url("bundle.s3-endpoint")
+	}
+	// anonymous access needs neither key
+	if (viper.GetString("bundle.s3-access-key") == "") != (viper.GetString("bundle.s3-secret-key") == "") {
+		v.fail("bundle.s3-secret-key", "access key and secret key must be set together")
+	}
+	v.nonNegative("bundle.cache-size")
+}
+
+func (v *validator) validateOutput() {
+	formats := make([]string, len(output.Formats))
+	for i, f := range output.Formats {
+		formats[i] = string(f)
+	}
+	if viper.GetString("output.format") != "" {
+		v.oneOf("output.format", formats...)
+	}
+	v.nonNegative("output.write-batch")
+	v.nonNegative("output.write-queue")
+	if viper.GetString("output.mode") != "" {
+		modes := make([]string, len(gitUtil.WriteModes))
+		for i, m := range gitUtil.WriteModes {
+			modes[i] = string(m)
+		}
+		v.oneOf("output.mode", modes...)
+	}
+	if UsesDatabase() {
+		return
+	}
+	if viper.GetString("output.format") == string(output.FormatCSV) {
+		v.required("output.path")
+	}
+	if viper.GetString("output.input") != gitUtil.Stdin {
+		v.file("output.input")
+	}
+	if len(viper.GetStringSlice("tag")) > 0 {
+		v.fail("tag", "tags are stored in the database, which is not used by %s output", viper.GetString("output.format"))
+	}
+}
+
+func (v *validator) validateTranslation() {
+	if lang := viper.GetString("translation.language"); lang != "" {
+		if _, err := language.Parse(lang); err != nil {
+			v.fail("translation.language", "%q is not a language tag, e.g. zh or en", lang)
+		}
+	}
+	v.nonNegative("translation.timeout")
+	v.nonNegative("translation.cache-size")
+	if viper.GetString("translation.provider") == "" {
+		return
+	}
+	v.oneOf("translation.provider", translate.Providers...)
+	if v.required("translation.url") {
+		v.url("translation.url")
+	}
+	if viper.GetString("translation.provider") == translate.ProviderLLM {
+		v.required("translation.model")
+	}
+}
+
+func (v *validator) validatePageRank() {
+	cfg, err := GetPageRankConfig()
+	if err != nil {
+		v.fail("pagerank.collectors", "%v", err)
+		return
+	}
+	v.nonNegative("pagerank.iterations")
+	v.nonNegative("pagerank.epsilon")
+	if d := cfg.Default.Damping; d < 0 || d >= 1 {
+		v.fail("pagerank.damping", "%v is not in [0, 1)", d)
+	}
+	if cfg.Default.Weighting != "" {
+		v.oneOf("pagerank.weighting", string(graph.WeightUniform), string(graph.WeightDependents))
+	}
+	for name, p := range cfg.Collectors {
+		if err := p.Validate(); err != nil {
+			v.fail("pagerank.collectors."+name, "%v", err)
+		}
+	}
+}
+
+func (v *validator) validateMirror() {
+	cfg, err := GetMirrorConfig()
+	if err != nil {
+		v.fail("mirror", "%v", err)
+		return
+	}
+	v.nonNegative("mirror.cache-ttl")
+	v.nonNegative("mirror.timeout")
+	for distro, pinned := range cfg.Mirrors {
+		if u, err := url.Parse(pinned); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.fail("mirror.mirrors", "%q of %s is not a http(s) url", pinned, distro)
+		}
+	}
+	for distro, candidates := range cfg.Candidates {
+		if len(candidates) == 0 {
+			v.fail("mirror.candidates."+distro, "no candidates")
+		}
+		if _, ok := mirror.ProbePaths[distro]; !ok {
+			v.fail("mirror.candidates."+distro, "unknown distribution")
+		}
+	}
+}
+
+func (v *validator) validateSLA() {
+	cfg, err := GetSLAConfig()
+	if err != nil {
+		v.fail("sla.max-ages", "%v", err)
+		return
+	}
+	v.nonNegative("sla.max-age")
+	if r := cfg.MaxStaleRate; r < 0 || r > 1 {
+		v.fail("sla.max-stale-rate", "%v is not in [0, 1]", r)
+	}
+	for name, d := range cfg.MaxAges {
+		if d < 0 {
+			v.fail("sla.max-ages."+name, "%q must not be negative", d)
+		}
+	}
+}
+
+func (v *validator) validateScore() {
+	v.file("score.profiles-file")
+	v.file("score.formulas-file")
+	v.nonNegative("score.decay-grace")
+	v.nonNegative("score.decay-half-life")
+	if _, err := GetScoreProfiles(); err != nil {
+		v.fail("score.profiles-file", "%v", err)
+		return
+	}
+	formulas, err := GetScoreFormulas()
+	if err != nil {
+		v.fail("score.formulas-file", "%v", err)
+		return
+	}
+	if _, ok := formulas[GetScoreFormula()]; !ok {
+		v.fail("score.formula", "%q is not a formula of %s", GetScoreFormula(), viper.GetString("score.formulas-file"))
+	}
+}
+
+var runIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+var requiredKeys []string
+
+// MarkRequired marks config keys, e.g. git.storage, which must be set for
+// the command, it is called after the flags of the keys are registered.
+func MarkRequired(keys ...string) {
+	requiredKeys = append(requiredKeys, keys...)
+}
+
+// Validate checks the config of the registered flags, so that a missing
+// or malformed value is reported before a command starts. The returned
+// error joins one *FieldError per invalid value.
+func Validate() error {
+	v := &validator{}
+	if databaseRegisted && UsesDatabase() {
+		v.validateDatabase()
+	}
+	if outputRegisted {
+		v.validateOutput()
+	}
+	if logRegisted {
+		v.validateLog()
+	}
+	if gitStorageRegisted {
+		if viper.GetString("git.storage-layout") != "" {
+			v.oneOf("git.storage-layout", string(pathmap.LayoutPlain), string(pathmap.LayoutHashed))
+		}
+		v.nonNegative("git.storage-max-size")
+	}
+	if probeRegisted {
+		v.nonNegative("probe.threshold")
+	}
+	if graphRegisted {
+		v.nonNegative("graph.refresh")
+	}
+	if mirrorRegisted {
+		v.validateMirror()
+	}
+	if pageRankRegisted {
+		v.validatePageRank()
+	}
+	if slaRegisted {
+		v.validateSLA()
+	}
+	if apiServerRegisted {
+		v.nonNegative("api.rate-limit")
+		v.nonNegative("api.response-cache-ttl")
+		v.nonNegative("api.response-cache-size")
+	}
+	if translationRegisted {
+		v.validateTranslation()
+	}
+	for _, key := range requiredKeys {
+		v.required(key)
+	}
+	if githubTokenRegisted {
+		v.githubToken("token.github")
+	}
+	if sampleRegisted {
+		v.validateSample()
+	}
+	if scopeRegisted {
+		for _, key := range []string{"scope.include", "scope.exclude"} {
+			if _, err := regexp.Compile(viper.GetString(key)); err != nil {
+				v.fail(key, "%v", err)
+			}
+		}
+	}
+	if httpCacheRegisted {
+		v.nonNegative("http-cache.ttl")
+		v.nonNegative("http-cache.error-ttl")
+	}
+	if depsDevRegisted {
+		if version := viper.GetString("depsdev.api-version"); version != "" && !depsdevclient.APIVersionPattern.MatchString(version) {
+			v.fail("depsdev.api-version", "%q is not an api version, e.g. v3 or v3alpha", version)
+		}
+		v.url("depsdev.url")
+		v.nonNegative("depsdev.max-retries")
+	}
+	if librariesIORegisted {
+		v.librariesIOAPIKey("token.librariesio")
+		v.url("librariesio.url")
+		v.nonNegative("librariesio.max-retries")
+		v.nonNegative("librariesio.interval")
+	}
+	if stackExchangeRegisted {
+		v.url("stackexchange.url")
+		if site := viper.GetString("stackexchange.site"); site != "" && !stackExchangeSitePattern.MatchString(site) {
+			v.fail("stackexchange.site", "%q is not a stack exchange site, e.g. stackoverflow or superuser", site)
+		}
+		v.nonNegative("stackexchange.max-retries")
+	}
+	if archiveRegisted {
+		if id := viper.GetString("response-archive.run-id"); id != "" && !runIDPattern.MatchString(id) {
+			v.fail("response-archive.run-id", "%q is not 1 to 64 letters, digits, '.', '_' or '-'", id)
+		}
+	}
+	if progressRegisted {
+		v.nonNegative("progress.interval")
+	}
+	if failureRegisted {
+		if rate := viper.GetFloat64("failure.max-rate"); rate < 0 || rate > 1 {
+			v.fail("failure.max-rate", "%v is not between 0 and 1", rate)
+		}
+	}
+	if freshnessRegisted {
+		v.nonNegative("freshness")
+	}
+	if priorityRegisted {
+		v.nonNegative("priority.half-life")
+		v.nonNegative("priority.limit")
+	}
+	if bundleRegisted {
+		v.validateBundle()
+	}
+	if scoreRegisted {
+		v.validateScore()
+	}
+	return errors.Join(v.errs...)
+}
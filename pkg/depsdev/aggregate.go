@@ -0,0 +1,45 @@
+package depsdev
+
+import "fmt"
+
+// AggregatePolicy decides how the dependents of multiple packages published
+// from one repo are aggregated into the dependents of the repo.
+type AggregatePolicy string
+
+const (
+	// AggregateSum adds up the dependents of all packages, a project
+	// depending on several packages of the repo is counted several times
+	AggregateSum AggregatePolicy = "sum"
+	// AggregateMax takes the dependents of the most depended package
+	AggregateMax AggregatePolicy = "max"
+	// AggregateList only stores the per-package breakdown, the repo
+	// dependents are not written
+	AggregateList AggregatePolicy = "list"
+)
+
+func ParseAggregatePolicy(s string) (AggregatePolicy, error) {
+	switch p := AggregatePolicy(s); p {
+	case AggregateSum, AggregateMax, AggregateList:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown aggregate policy %q, allow sum, max, list", s)
+}
+
+// Aggregate returns the repo dependents of the package dependents, and
+// false if the policy doesn't write repo dependents.
+func (p AggregatePolicy) Aggregate(counts []int) (int, bool) {
+	ret := 0
+	switch p {
+	case AggregateSum:
+		for _, c := range counts {
+			ret += c
+		}
+	case AggregateMax:
+		for _, c := range counts {
+			ret = max(ret, c)
+		}
+	default:
+		return 0, false
+	}
+	return ret, true
+}
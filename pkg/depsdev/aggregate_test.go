@@ -0,0 +1,26 @@
+package depsdev
+
+import "testing"
+
+func TestAggregate(t *testing.T) {
+	counts := []int{3, 10, 0, 7}
+	tests := []struct {
+		policy AggregatePolicy
+		want   int
+		wantOk bool
+	}{
+		{AggregateSum, 20, true},
+		{AggregateMax, 10, true},
+		{AggregateList, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := tt.policy.Aggregate(counts)
+		if got != tt.want || ok != tt.wantOk {
+			t.Errorf("%s: Aggregate() = %d, %v, want %d, %v", tt.policy, got, ok, tt.want, tt.wantOk)
+		}
+	}
+
+	if _, err := ParseAggregatePolicy("avg"); err == nil {
+		t.Errorf("ParseAggregatePolicy(avg) should fail")
+	}
+}
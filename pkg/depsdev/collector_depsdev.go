@@ -3,75 +3,36 @@ package depsdev
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"regexp"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/HUSTSecLab/criticality_score/pkg/collector"
+	"github.com/HUSTSecLab/criticality_score/pkg/depsdevclient"
+	"github.com/HUSTSecLab/criticality_score/pkg/freshness"
+	"github.com/HUSTSecLab/criticality_score/pkg/priority"
+	"github.com/HUSTSecLab/criticality_score/pkg/sampling"
+	"github.com/HUSTSecLab/criticality_score/pkg/scope"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/HUSTSecLab/criticality_score/pkg/tagging"
 	"github.com/go-redis/redis/v8"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/samber/lo"
 )
 
-type DependentInfo struct {
-	DependentCount         int `json:"dependentCount"`
-	DirectDependentCount   int `json:"directDependentCount"`
-	IndirectDependentCount int `json:"indirectDependentCount"`
-}
-
-type VersionInfo struct {
-	VersionKey struct {
-		Version string `json:"version"`
-	} `json:"versionKey"`
-	PublishedAt time.Time `json:"publishedAt"`
-}
-
-type PackageInfo struct {
-	Versions []VersionInfo `json:"versions"`
-}
-
-type Node struct {
-	VersionKey Version  `json:"versionKey"`
-	Bundled    bool     `json:"bundled"`
-	Relation   string   `json:"relation"`
-	Errors     []string `json:"errors"`
-}
-
-type Edge struct {
-	FromNode    int    `json:"fromNode"`
-	ToNode      int    `json:"toNode"`
-	Requirement string `json:"requirement"`
-}
-
-type Dependencies struct {
-	Nodes []Node `json:"nodes"`
-	Edges []Edge `json:"edges"`
-	Error string `json:"error"`
-}
-
-type Version struct {
-	System  string `json:"system"`
-	Name    string `json:"name"`
-	Version string `json:"version"`
-}
-
-type PkgInfo struct {
-	VersionKey         Version
-	RelationType       string   `json:"relationType"`
-	RelationProvenance string   `json:"relationProvenance"`
-	SlsaProvenances    []string `json:"slsaProvenances"`
-	Attestations       []string `json:"attestations"`
-}
+var (
+	// ErrDepsDev is wrapped by errors of querying the deps.dev API.
+	ErrDepsDev = errors.New("deps.dev query failed")
+	// ErrStorage is wrapped by errors of reading or writing the database
+	// and redis.
+	ErrStorage = errors.New("storage failed")
+)
 
-type DepsDevInfo struct {
-	Versions []PkgInfo `json:"versions"`
-}
+// Version is a package version.
+type Version = depsdevclient.VersionKey
 
 type EcoSystemRatio struct {
 	NpmRatio   float64
@@ -82,23 +43,16 @@ type EcoSystemRatio struct {
 	CargoRatio float64
 }
 
-func getLatestVersion(repo, projectType string) string {
-	ctx := context.Background()
-
-	url := fmt.Sprintf("https://api.deps.dev/v3alpha/systems/%s/packages/%s", projectType, repo)
-
-	req, _ := http.NewRequest("GET", url, nil)
-	client := &http.Client{}
-	resp, err := client.Do(req.WithContext(ctx))
+// getLatestVersion returns the most recently published version of a
+// package, or an empty string if deps.dev does not know the package.
+func getLatestVersion(repo, projectType string) (string, error) {
+	result, err := depsdevclient.Default().GetPackage(context.Background(), projectType, repo)
+	if errors.Is(err, depsdevclient.ErrNotFound) {
+		return "", nil
+	}
 	if err != nil {
-		fmt.Println("Error fetching package information:", err)
-		return ""
+		return "", fmt.Errorf("%w: %w", ErrDepsDev, err)
 	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-	var result PackageInfo
-	json.Unmarshal(body, &result)
 
 	var latestVersion string
 	var latestDate time.Time
@@ -109,105 +63,148 @@ func getLatestVersion(repo, projectType string) string {
 		}
 	}
 
-	return latestVersion
+	return latestVersion, nil
 }
 
-func queryDepsDev(projectType, projectName, version string) int {
-	url := fmt.Sprintf("https://api.deps.dev/v3alpha/systems/%s/packages/%s/versions/%s:dependents", projectType, projectName, version)
-	resp, err := http.Get(url)
-	if err != nil {
-		version = getLatestVersion(projectName, projectType)
-		url = fmt.Sprintf("https://api.deps.dev/v3alpha/systems/%s/packages/%s/versions/%s:dependents", projectType, projectName, version)
-		resp, err = http.Get(url)
-		if err != nil {
-			fmt.Println("Error fetching package information:", err)
-			return 0
+// queryDepsDev returns the number of dependents of a package version, the
+// latest version is used if the version is not known by deps.dev.
+func queryDepsDev(projectType, projectName, version string) (int, error) {
+	info, err := depsdevclient.Default().GetDependents(context.Background(), projectType, projectName, version)
+	if errors.Is(err, depsdevclient.ErrNotFound) {
+		latest, err := getLatestVersion(projectName, projectType)
+		if err != nil || latest == "" || latest == version {
+			return 0, err
 		}
-		defer resp.Body.Close()
+		return queryDepsDev(projectType, projectName, latest)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return 0
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrDepsDev, err)
 	}
+	return info.DependentCount, nil
+}
 
-	var info DependentInfo
-	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
-		fmt.Println("Error decoding response:", err)
-		return 0
+// queryVersion returns the licenses and advisories of a package version,
+// nil if the version is not known by deps.dev.
+func queryVersion(projectType, projectName, version string) (*depsdevclient.Version, error) {
+	details, err := depsdevclient.Default().GetVersion(context.Background(), projectType, projectName, version)
+	if errors.Is(err, depsdevclient.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDepsDev, err)
 	}
-	return info.DependentCount
+	return details, nil
 }
 
-func getGitlink(db *sql.DB) []string {
+func getGitlink(db *sql.DB) ([]string, error) {
 	rows, err := db.Query("SELECT git_link FROM git_metrics")
 	if err != nil {
-		fmt.Println("Error querying git_metrics:", err)
-		return nil
+		return nil, fmt.Errorf("%w: querying git_metrics: %w", ErrStorage, err)
 	}
 	defer rows.Close()
 	var gitLinks []string
 	for rows.Next() {
 		var gitLink string
 		if err := rows.Scan(&gitLink); err != nil {
-			fmt.Println("Error scanning git_link:", err)
-			return nil
+			return nil, fmt.Errorf("%w: scanning git_link: %w", ErrStorage, err)
 		}
 		gitLinks = append(gitLinks, gitLink)
 	}
-	return gitLinks
+	return gitLinks, rows.Err()
 }
 
-func queryDepsName(gitlink string, rdb *redis.Client) map[string]Version {
+// queryDepsName returns the latest versions of the packages published from
+// gitlink, keyed by packageKey. If the repo is already cloned, the packages
+// declared by its manifests are used, so no remote call is spent on finding
+// them; the deps.dev projects API is asked if the repo is not cloned or its
+// manifests declare nothing known by deps.dev. The git link of each package
+// is saved in redis.
+//
+// Packages which fail to be resolved are skipped, and their errors are
+// joined into the returned error.
+func queryDepsName(gitlink string, rdb *redis.Client) (map[string]Version, error) {
 	depMap := make(map[string]Version)
-	if strings.Contains(gitlink, ".git") {
-		gitlink = strings.TrimSuffix(gitlink, ".git")
-	}
-	var repo, name string
-	if len(strings.Split(gitlink, "/")) == 5 {
-		repo = strings.Split(gitlink, "/")[3]
-		name = strings.Split(gitlink, "/")[4]
-	}
-	url := fmt.Sprintf("https://api.deps.dev/v3alpha/projects/github.com%%2f%s%%2f%s:packageversions", repo, name)
-	resp, err := http.Get(url)
-	if err != nil {
-		fmt.Println("Error querying deps.dev:", err)
-		return depMap
+	gitlink = strings.TrimSuffix(gitlink, ".git")
+	var errs []error
+
+	for _, p := range manifestPackages(gitlink) {
+		if !scope.Default().Ecosystem(p.System) {
+			continue
+		}
+		name := normalizeName(p.System, p.Name)
+		version, err := getLatestVersion(name, p.System)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if version == "" {
+			continue
+		}
+		depMap[packageKey(p.System, name)] = Version{System: p.System, Name: name, Version: version}
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return depMap
+
+	if len(depMap) == 0 {
+		if key, ok := projectKey(gitlink); ok {
+			versions, err := queryProjectPackages(key)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			for _, v := range versions {
+				if !scope.Default().Ecosystem(v.System) {
+					continue
+				}
+				k := packageKey(v.System, v.Name)
+				if current, exists := depMap[k]; !exists || v.Version > current.Version {
+					depMap[k] = v
+				}
+			}
+		}
 	}
-	var result DepsDevInfo
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		fmt.Println("Error decoding response:", err)
-		return depMap
+	for k := range depMap {
+		if err := storage.SetKeyValue(rdb, k, gitlink); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %w", ErrStorage, err))
+		}
 	}
+	return depMap, errors.Join(errs...)
+}
 
-	latestVersions := make(map[string]map[string]string)
+// queryProjectPackages returns all package versions published from a
+// deps.dev project.
+func queryProjectPackages(key string) ([]Version, error) {
+	result, err := depsdevclient.Default().GetProjectPackageVersions(context.Background(), key)
+	if errors.Is(err, depsdevclient.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDepsDev, err)
+	}
+	ret := make([]Version, 0, len(result.Versions))
 	for _, item := range result.Versions {
-		name := item.VersionKey.Name
-		version := item.VersionKey.Version
-		system := item.VersionKey.System
-		if strings.Contains(name, "\u003E") {
-			split := strings.Split(name, "\u003E")
-			name = split[len(split)-1]
-		}
-		if strings.Contains(name, "/") {
-			split := strings.Split(name, "/")
-			name = split[len(split)-1]
-		}
-		if _, exists := latestVersions[name]; !exists {
-			latestVersions[name] = make(map[string]string)
-		}
-		if currentVersion, exists := latestVersions[name][system]; !exists || version > currentVersion {
-			latestVersions[name][system] = version
-			depMap[name] = Version{Name: name, System: system, Version: version}
-			storage.SetKeyValue(rdb, name, gitlink)
-		}
+		ret = append(ret, item.VersionKey)
 	}
-	return depMap
+	return ret, nil
+}
+
+// langEcoType returns the type of a deps.dev system, systems are matched
+// case insensitively.
+func langEcoType(system string) repository.LangEcosystemType {
+	var ltype repository.LangEcosystemType
+	switch strings.ToLower(system) {
+	case "cargo":
+		ltype = repository.Cargo
+	case "go":
+		ltype = repository.Go
+	case "maven":
+		ltype = repository.Maven
+	case "npm":
+		ltype = repository.Npm
+	case "nuget":
+		ltype = repository.NuGet
+	case "pypi":
+		ltype = repository.Pypi
+	}
+	return ltype
 }
 
 type GitMetrics struct {
@@ -215,33 +212,95 @@ type GitMetrics struct {
 	LangEcoPageRank float64
 }
 
-func Depsdev(batchSize int, workerPoolSize int, calculatePageRankFlag bool) {
+// Depsdev collects the dependents of the packages published from every
+// repo. Repos collected within window are skipped, 0 collects all repos.
+// Failures of single packages do not stop the collection, all errors are
+// joined into the returned error, which wraps ErrDepsDev or ErrStorage.
+func Depsdev(batchSize int, workerPoolSize int, calculatePageRankFlag bool, policy AggregatePolicy, window time.Duration) error {
 	db := storage.GetDefaultAppDatabaseContext()
 	repo := repository.NewLangEcoLinkRepository(db)
-	rdb, _ := storage.InitRedis()
+	pkgRepo := repository.NewLangEcosystemPackageRepository(db)
+	metricRepo := repository.NewGitMetricsRepository(db)
+	tsRepo := repository.NewCollectionTimestampRepository(db)
+	rdb, err := storage.InitRedis()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrStorage, err)
+	}
+	var errs []error
 	// gitLinks := getGitlink(db)
 	gitLinks := []string{"https://github.com/facebook/react.git"}
+	gitLinks = sampling.Slice(scope.Slice(tagging.Slice(gitLinks)))
+	if window > 0 {
+		stale, err := tsRepo.FilterStale(repository.SignalLangEcosystem, gitLinks, time.Now().Add(-window))
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrStorage, err)
+		}
+		if skipped := len(gitLinks) - len(stale); skipped > 0 {
+			fmt.Printf("Skipping %d repos collected within %s\n", skipped, window)
+		}
+		gitLinks = stale
+	}
+	gitLinks, err = priority.Schedule(db, repository.SignalLangEcosystem, gitLinks)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrStorage, err)
+	}
+	// repos whose packages are all collected without errors
+	collected := make([]string, 0, len(gitLinks))
 	pkgMap := make(map[string][]Version)
 	pkgDepMap := make(map[string]map[string]int)
+	pkgVersions := make(map[string]Version)
+	pkgDetails := make(map[string]*depsdevclient.Version)
 	for _, gitlink := range gitLinks {
-		depMap := queryDepsName(gitlink, rdb)
-		for pkgName, pkgInfo := range depMap {
+		ok := true
+		depMap, err := queryDepsName(gitlink, rdb)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", gitlink, err))
+			ok = false
+		}
+		for pkgKey, pkgInfo := range depMap {
+			count, err := queryDepsDev(pkgInfo.System, pkgInfo.Name, pkgInfo.Version)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", pkgKey, err))
+				ok = false
+				continue
+			}
+			details, err := queryVersion(pkgInfo.System, pkgInfo.Name, pkgInfo.Version)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", pkgKey, err))
+				ok = false
+				continue
+			}
+			pkgVersions[pkgKey] = pkgInfo
+			pkgDetails[pkgKey] = details
 			if _, exists := pkgDepMap[pkgInfo.System]; !exists {
 				pkgDepMap[pkgInfo.System] = make(map[string]int)
 			}
-			pkgDepMap[pkgInfo.System][pkgName] = queryDepsDev(pkgInfo.System, pkgInfo.Name, pkgInfo.Version)
+			pkgDepMap[pkgInfo.System][pkgKey] = count
 		}
 		if calculatePageRankFlag {
-			pkgdepMap := fetchDep(depMap, workerPoolSize)
+			pkgdepMap, err := fetchDep(depMap, workerPoolSize)
+			if err != nil {
+				errs = append(errs, err)
+			}
 			for pkgName, pkgInfo := range pkgdepMap {
 				pkgMap[pkgName] = pkgInfo
 			}
-			storage.PersistData(rdb)
+			if err := storage.PersistData(rdb); err != nil {
+				errs = append(errs, fmt.Errorf("%w: %w", ErrStorage, err))
+			}
+		}
+		if ok {
+			collected = append(collected, gitlink)
 		}
 	}
+	// errors after here are not bound to a repo
+	repoErrs := len(errs)
 	var pageRank map[string]float64
 	if calculatePageRankFlag {
-		pageRank = calculatePageRank(pkgMap, 100, 0.85)
+		pageRank, err = updatePageRank(rdb, pkgMap)
+		if err != nil {
+			errs = append(errs, err)
+		}
 	} else {
 		pageRank = make(map[string]float64)
 		for _, pkgMap := range pkgDepMap {
@@ -258,162 +317,150 @@ func Depsdev(batchSize int, workerPoolSize int, calculatePageRankFlag bool) {
 		ltype   repository.LangEcosystemType
 	}
 
-	langEco := make(map[langEcoKey]int)
+	// per-package dependents of each repo and type
+	langEco := make(map[langEcoKey][]*repository.LangEcosystemPackage)
 
 	for system, pkgMap := range pkgDepMap {
-		for pkgName := range pkgMap {
+		for pkgKey := range pkgMap {
 			wg.Add(1)
 			semaphore <- struct{}{}
-			go func(system, pkgName string) {
+			go func(system, pkgKey string) {
 				defer wg.Done()
 				defer func() { <-semaphore }()
-				_, err := storage.GetKeyValue(rdb, pkgName)
+				gitLink, err := storage.GetKeyValue(rdb, pkgKey)
 				if err != nil {
-					fmt.Println("Error getting key:", err)
+					mu.Lock()
+					errs = append(errs, fmt.Errorf("%w: %w", ErrStorage, err))
+					mu.Unlock()
 					return
 				}
 
-				var ltype repository.LangEcosystemType
-				switch strings.ToLower(system) {
-				case "cargo":
-					ltype = repository.Cargo
-				case "go":
-					ltype = repository.Go
-				case "maven":
-					ltype = repository.Maven
-				case "npm":
-					ltype = repository.Npm
-				case "nuget":
-					ltype = repository.NuGet
-				case "pypi":
-					ltype = repository.Pypi
-				}
+				ltype := langEcoType(system)
 
 				key := langEcoKey{
-					gitLink: pkgName,
+					gitLink: gitLink,
 					ltype:   ltype,
 				}
 
-				mu.Lock()
-
-				if _, exists := langEco[key]; !exists {
-					langEco[key] = pkgDepMap[system][pkgName]
-				} else {
-					langEco[key] += pkgDepMap[system][pkgName]
+				pkg := &repository.LangEcosystemPackage{
+					GitLink:  lo.ToPtr(gitLink),
+					Type:     lo.ToPtr(ltype),
+					Package:  lo.ToPtr(pkgVersions[pkgKey].Name),
+					Version:  lo.ToPtr(pkgVersions[pkgKey].Version),
+					DepCount: lo.ToPtr(pkgDepMap[system][pkgKey]),
+				}
+				// versions unknown by deps.dev have no licenses or advisories
+				if details := pkgDetails[pkgKey]; details != nil {
+					pkg.Licenses = lo.ToPtr(pq.StringArray(details.Licenses))
+					pkg.AdvisoryCount = lo.ToPtr(len(details.AdvisoryKeys))
 				}
 
+				mu.Lock()
+				langEco[key] = append(langEco[key], pkg)
 				mu.Unlock()
-			}(system, pkgName)
+			}(system, pkgKey)
 		}
 	}
 	wg.Wait()
 	var toUpdateList []*repository.LangEcosystem
-	for key, info := range langEco {
-		toUpdateList = append(toUpdateList, lo.ToPtr(repository.LangEcosystem{
-			GitLink:  lo.ToPtr(key.gitLink),
-			Type:     lo.ToPtr(key.ltype),
-			DepCount: lo.ToPtr(info),
-		}))
-	}
-	err := repo.BatchInsertOrUpdate(toUpdateList)
-	if err != nil {
-		fmt.Printf("Error updating database: %v\n", err)
+	var breakdown []*repository.LangEcosystemPackage
+	// advisories of the packages of each repo, of all types
+	advisories := make(map[string]int)
+	for key, pkgs := range langEco {
+		breakdown = append(breakdown, pkgs...)
+		for _, p := range pkgs {
+			if p.AdvisoryCount != nil {
+				advisories[key.gitLink] += *p.AdvisoryCount
+			}
+		}
+		counts := lo.Map(pkgs, func(p *repository.LangEcosystemPackage, _ int) int { return *p.DepCount })
+		if depCount, ok := policy.Aggregate(counts); ok {
+			toUpdateList = append(toUpdateList, lo.ToPtr(repository.LangEcosystem{
+				GitLink:  lo.ToPtr(key.gitLink),
+				Type:     lo.ToPtr(key.ltype),
+				DepCount: lo.ToPtr(depCount),
+			}))
+		}
+	}
+	// the writes share the write budget with the distribution collectors
+	// run in parallel, see collector.SetWriteBudget
+	release := collector.AcquireWrite()
+	// every chunk is written in a single transaction
+	for _, chunk := range lo.Chunk(breakdown, max(batchSize, 1)) {
+		if err := pkgRepo.BatchInsert(chunk); err != nil {
+			errs = append(errs, fmt.Errorf("%w: updating package breakdown: %w", ErrStorage, err))
+		}
+	}
+	for _, chunk := range lo.Chunk(toUpdateList, max(batchSize, 1)) {
+		if err := repo.BatchInsertOrUpdate(chunk); err != nil {
+			errs = append(errs, fmt.Errorf("%w: updating lang_ecosystems: %w", ErrStorage, err))
+		}
 	}
+	advisoryCounts := make([]*repository.GitMetric, 0, len(advisories))
+	for gitLink, count := range advisories {
+		advisoryCounts = append(advisoryCounts, &repository.GitMetric{GitLink: lo.ToPtr(gitLink), AdvisoryCount: lo.ToPtr(count)})
+	}
+	for _, chunk := range lo.Chunk(advisoryCounts, max(batchSize, 1)) {
+		if err := metricRepo.BatchUpdateColumns(chunk, "advisory_count"); err != nil {
+			errs = append(errs, fmt.Errorf("%w: updating advisory counts: %w", ErrStorage, err))
+		}
+	}
+	release()
+	memoized, sent := depsdevclient.Default().Stats()
+	fmt.Printf("Sent %d deps.dev requests, %d repeated lookups served from memory\n", sent, memoized)
+	// only mark repos when everything is written, so failed repos are
+	// collected again by the next run
+	if len(errs) == repoErrs {
+		if err := freshness.MarkCollected(db, repository.SignalLangEcosystem, freshness.SourceDepsDev, collected, time.Now()); err != nil {
+			errs = append(errs, fmt.Errorf("%w: marking collected repos: %w", ErrStorage, err))
+		}
+	}
+	return errors.Join(errs...)
 }
 
-func fetchDep(depMap map[string]Version, threadnum int) map[string][]Version {
+func fetchDep(depMap map[string]Version, threadnum int) (map[string][]Version, error) {
 	depMapNew := make(map[string][]Version)
 	var wg sync.WaitGroup
 	semaphore := make(chan struct{}, threadnum)
 	var mu sync.Mutex
-	for depName, depInfo := range depMap {
+	var errs []error
+	for depKey, depInfo := range depMap {
 		wg.Add(1)
 		semaphore <- struct{}{}
-		go func(depName string, depInfo Version) {
+		go func(depKey string, depInfo Version) {
 			defer wg.Done()
 			defer func() { <-semaphore }()
-			var system, name, version string
-			system = depInfo.System
-			name = depName
-			version = depInfo.Version
-			result := getAndProcessDependencies(system, name, version)
+			result, err := getAndProcessDependencies(depInfo.System, depInfo.Name, depInfo.Version)
 			mu.Lock()
-			depMapNew[name] = []Version{}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", depKey, err))
+			}
+			depMapNew[depKey] = []Version{}
 			for _, node := range result.Nodes {
 				if node.Relation == "DIRECT" {
-					depMapNew[name] = append(depMapNew[name], node.VersionKey)
+					depMapNew[depKey] = append(depMapNew[depKey], node.VersionKey)
 				}
 			}
 			mu.Unlock()
-		}(depName, depInfo)
+		}(depKey, depInfo)
 	}
 	wg.Wait()
-	return depMapNew
+	return depMapNew, errors.Join(errs...)
 }
 
-func calculatePageRank(pkgInfoMap map[string][]Version, iterations int, dampingFactor float64) map[string]float64 {
-	pageRank := make(map[string]float64)
-	numPackages := len(pkgInfoMap)
-
-	for pkgName := range pkgInfoMap {
-		pageRank[pkgName] = 1.0 / float64(numPackages)
-	}
-
-	for i := 0; i < iterations; i++ {
-		newPageRank := make(map[string]float64)
-
-		for pkgName := range pkgInfoMap {
-			newPageRank[pkgName] = (1 - dampingFactor) / float64(numPackages)
-		}
-
-		for pkgName, deps := range pkgInfoMap {
-			depNum := len(deps)
-			for _, depName := range deps {
-				if _, exists := pkgInfoMap[depName.Name]; exists {
-					newPageRank[depName.Name] += dampingFactor * (pageRank[pkgName] / float64(depNum))
-				}
-			}
+// getAndProcessDependencies returns the dependency graph of a package
+// version, of the latest version if the version is not known by deps.dev.
+func getAndProcessDependencies(system, name, version string) (*depsdevclient.Dependencies, error) {
+	result, err := depsdevclient.Default().GetDependencies(context.Background(), system, name, version)
+	if errors.Is(err, depsdevclient.ErrNotFound) {
+		latest, err := getLatestVersion(name, system)
+		if err != nil || latest == "" || latest == version {
+			return &depsdevclient.Dependencies{}, err
 		}
-		pageRank = newPageRank
+		return getAndProcessDependencies(system, name, latest)
 	}
-	return pageRank
-}
-
-func getAndProcessDependencies(system, name, version string) Dependencies {
-	var result Dependencies
-	url := fmt.Sprintf("https://api.deps.dev/v3alpha/systems/%s/packages/%s/versions/%s:dependencies", system, name, version)
-	resp, err := http.Get(url)
 	if err != nil {
-		fmt.Println("Error querying deps.dev:", err)
-		return result
+		return &depsdevclient.Dependencies{}, fmt.Errorf("%w: %w", ErrDepsDev, err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		version = getLatestVersion(name, system)
-		url = fmt.Sprintf("https://api.deps.dev/v3alpha/systems/%s/packages/%s/versions/%s:dependencies", system, name, version)
-		resp, err = http.Get(url)
-		if err != nil {
-			fmt.Println("Error querying deps.dev:", err)
-			return result
-		}
-		defer resp.Body.Close()
-	}
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Println("Error reading response body:", err)
-		return result
-	}
-	cleanedBody := removeInvisibleChars(string(body))
-	err = json.Unmarshal([]byte(cleanedBody), &result)
-	if err != nil {
-		return result
-	}
-
-	return result
-}
-
-func removeInvisibleChars(input string) string {
-	re := regexp.MustCompile(`[[:cntrl:]]+`)
-	return re.ReplaceAllString(input, "")
+	return result, nil
 }
@@ -0,0 +1,192 @@
+package depsdev
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/collector"
+	"github.com/HUSTSecLab/criticality_score/pkg/graph"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// pageRankKey is the redis key of the state of the last PageRank run
+	pageRankKey = "depsdev:pagerank"
+	// pageRankMaxDelta is the maximum rate of changed edges for which the
+	// ranks of the last run are used as the starting point
+	pageRankMaxDelta = 0.05
+)
+
+// defaultPageRankParams are the PageRank parameters of the collector unless
+// configured otherwise, it iterates until the ranks converge, so runs
+// starting from the last ranks are shorter.
+var defaultPageRankParams = graph.PageRankParams{
+	Iterations: 100,
+	Damping:    0.85,
+	Epsilon:    1e-9,
+}
+
+// pageRankState is the state of a PageRank run, saved to skip or shorten
+// the next run.
+type pageRankState struct {
+	// Fingerprint is the hash of Edges
+	Fingerprint string             `json:"fingerprint"`
+	Edges       []string           `json:"edges"`
+	Ranks       map[string]float64 `json:"ranks"`
+	// Params are the parameters of the run, the ranks are only reused by
+	// runs of the same parameters
+	Params graph.PageRankParams `json:"params"`
+}
+
+// graphEdges returns the sorted edges of the dependency graph, keyed by
+// packageKey, as "from\tto" lines. Packages without dependencies are
+// "from\t", so adding or removing them changes the edges too.
+func graphEdges(graph map[string][]Version) []string {
+	var ret []string
+	for from, deps := range graph {
+		if len(deps) == 0 {
+			ret = append(ret, from+"\t")
+		}
+		for _, dep := range deps {
+			ret = append(ret, from+"\t"+packageKey(dep.System, dep.Name))
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// fingerprint returns the hash of sorted edges.
+func fingerprint(edges []string) string {
+	h := sha256.New()
+	for _, e := range edges {
+		h.Write([]byte(e))
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// edgeDelta returns the rate of edges added or removed between the sorted
+// edges a and b, 1 if both are empty.
+func edgeDelta(a, b []string) float64 {
+	total := max(len(a), len(b))
+	if total == 0 {
+		return 1
+	}
+	changed := 0
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch strings.Compare(a[i], b[j]) {
+		case 0:
+			i++
+			j++
+		case -1:
+			changed++
+			i++
+		default:
+			changed++
+			j++
+		}
+	}
+	changed += len(a) - i + len(b) - j
+	return float64(changed) / float64(total)
+}
+
+// dependencies returns the dependencies of the packages of g keyed by
+// packageKey, for graph.RankDependencies.
+func dependencies(g map[string][]Version) map[string][]string {
+	ret := make(map[string][]string, len(g))
+	for from, deps := range g {
+		keys := make([]string, 0, len(deps))
+		for _, dep := range deps {
+			keys = append(keys, packageKey(dep.System, dep.Name))
+		}
+		ret[from] = keys
+	}
+	return ret
+}
+
+// calculatePageRank returns the PageRank of the packages of g by p,
+// starting from the same rank for every package.
+func calculatePageRank(g map[string][]Version, p graph.PageRankParams) map[string]float64 {
+	ranks, _ := graph.RankDependencies(dependencies(g), nil, p)
+	return ranks
+}
+
+// nextPageRank returns the PageRank of g by p and its state, given the
+// state of the last run, which may be nil. The last ranks are returned as
+// they are if neither the graph nor the parameters changed, and used as the
+// starting point if few edges changed.
+//
+// The iteration converges to the same ranks whatever the start, so the
+// ranks of a slightly different graph only shorten it.
+func nextPageRank(g map[string][]Version, last *pageRankState, p graph.PageRankParams) (map[string]float64, *pageRankState) {
+	edges := graphEdges(g)
+	state := &pageRankState{Fingerprint: fingerprint(edges), Edges: edges, Params: p}
+	if last != nil && last.Params != p {
+		log.Printf("PageRank parameters changed from %s, starting over", last.Params)
+		last = nil
+	}
+	if last != nil && last.Fingerprint == state.Fingerprint {
+		log.Printf("Dependency graph unchanged, skipping PageRank")
+		state.Ranks = last.Ranks
+		return state.Ranks, state
+	}
+
+	var start map[string]float64
+	if last != nil {
+		if delta := edgeDelta(last.Edges, edges); delta <= pageRankMaxDelta {
+			log.Printf("%.2f%% of the dependency graph changed, updating PageRank", 100*delta)
+			start = last.Ranks
+		}
+	}
+	ranks, n := graph.RankDependencies(dependencies(g), start, p)
+	log.Printf("PageRank of %d packages converged in %d iterations, %s", len(g), n, p)
+	state.Ranks = ranks
+	return ranks, state
+}
+
+// updatePageRank returns the PageRank of g by the parameters configured for
+// depsdev, see graph.PageRankParamsOf, skipping or shortening the
+// computation by the state of the last run saved in redis. The parameters
+// are recorded with the run. The errors wrap ErrStorage, the ranks are
+// returned even if the state is not saved.
+func updatePageRank(rdb *redis.Client, g map[string][]Version) (map[string]float64, error) {
+	ctx := context.Background()
+	p := graph.PageRankParamsOf("depsdev", defaultPageRankParams)
+	collector.RecordRank(storage.GetDefaultAppDatabaseContext(), "depsdev", p, len(g))
+	var last *pageRankState
+	data, err := rdb.Get(ctx, pageRankKey).Bytes()
+	switch {
+	case errors.Is(err, redis.Nil):
+	case err != nil:
+		return calculatePageRank(g, p),
+			fmt.Errorf("%w: reading PageRank state: %w", ErrStorage, err)
+	default:
+		last = new(pageRankState)
+		if err := json.Unmarshal(data, last); err != nil {
+			log.Printf("Ignoring invalid PageRank state: %v", err)
+			last = nil
+		}
+	}
+
+	ranks, state := nextPageRank(g, last, p)
+	if last != nil && last.Fingerprint == state.Fingerprint {
+		return ranks, nil
+	}
+	data, err = json.Marshal(state)
+	if err != nil {
+		return ranks, fmt.Errorf("%w: encoding PageRank state: %w", ErrStorage, err)
+	}
+	if err := rdb.Set(ctx, pageRankKey, data, 0).Err(); err != nil {
+		return ranks, fmt.Errorf("%w: saving PageRank state: %w", ErrStorage, err)
+	}
+	return ranks, nil
+}
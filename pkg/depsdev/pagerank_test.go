@@ -0,0 +1,92 @@
+package depsdev
+
+import (
+	"math"
+	"testing"
+)
+
+func testGraph() map[string][]Version {
+	dep := func(name string) Version { return Version{System: "NPM", Name: name} }
+	return map[string][]Version{
+		"npm/a": {dep("b"), dep("c")},
+		"npm/b": {dep("c")},
+		"npm/c": {dep("a")},
+		"npm/d": {dep("c"), dep("external")},
+		"npm/e": {},
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	g := testGraph()
+	fp := fingerprint(graphEdges(g))
+	if got := fingerprint(graphEdges(testGraph())); got != fp {
+		t.Errorf("fingerprint of the same graph changed: %s != %s", got, fp)
+	}
+
+	// the order of dependencies does not matter
+	g["npm/a"] = []Version{g["npm/a"][1], g["npm/a"][0]}
+	if got := fingerprint(graphEdges(g)); got != fp {
+		t.Errorf("fingerprint changed by the order of dependencies")
+	}
+
+	delete(g, "npm/e")
+	if got := fingerprint(graphEdges(g)); got == fp {
+		t.Errorf("fingerprint unchanged by removing a package")
+	}
+}
+
+func TestEdgeDelta(t *testing.T) {
+	tests := []struct {
+		a, b []string
+		want float64
+	}{
+		{nil, nil, 1},
+		{[]string{"a\tb", "b\tc"}, []string{"a\tb", "b\tc"}, 0},
+		{[]string{"a\tb", "b\tc"}, []string{"a\tb", "b\td"}, 1},
+		{[]string{"a\tb", "b\tc", "c\td", "d\te"}, []string{"a\tb", "b\tc", "c\td"}, 0.25},
+	}
+	for _, tt := range tests {
+		if got := edgeDelta(tt.a, tt.b); got != tt.want {
+			t.Errorf("edgeDelta(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestNextPageRank(t *testing.T) {
+	g := testGraph()
+	full := calculatePageRank(g, defaultPageRankParams)
+
+	ranks, state := nextPageRank(g, nil, defaultPageRankParams)
+	for pkg, want := range full {
+		if math.Abs(ranks[pkg]-want) > 1e-6 {
+			t.Errorf("rank of %s = %v, want %v", pkg, ranks[pkg], want)
+		}
+	}
+
+	// unchanged graphs reuse the ranks
+	last := &pageRankState{Fingerprint: state.Fingerprint, Edges: state.Edges, Params: state.Params, Ranks: map[string]float64{"npm/a": 42}}
+	if ranks, _ := nextPageRank(g, last, defaultPageRankParams); ranks["npm/a"] != 42 {
+		t.Errorf("ranks of an unchanged graph were recomputed")
+	}
+	// unless the parameters changed
+	damped := defaultPageRankParams
+	damped.Damping = 0.5
+	if ranks, _ := nextPageRank(g, last, damped); ranks["npm/a"] == 42 {
+		t.Errorf("ranks of other parameters were reused")
+	}
+
+	// small changes start from the last ranks, and converge to the same
+	// ranks as a full run
+	for i := 0; i < 40; i++ {
+		g[packageKey("npm", string(rune('f'+i)))] = []Version{{System: "npm", Name: "a"}}
+	}
+	_, base := nextPageRank(g, nil, defaultPageRankParams)
+	g["npm/e"] = []Version{{System: "npm", Name: "c"}}
+	full = calculatePageRank(g, defaultPageRankParams)
+	ranks, _ = nextPageRank(g, base, defaultPageRankParams)
+	for pkg, want := range full {
+		if math.Abs(ranks[pkg]-want) > 1e-6 {
+			t.Errorf("updated rank of %s = %v, want %v", pkg, ranks[pkg], want)
+		}
+	}
+}
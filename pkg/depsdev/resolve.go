@@ -0,0 +1,60 @@
+package depsdev
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	giturl "github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser/url"
+	gitUtil "github.com/HUSTSecLab/criticality_score/pkg/gitfile/util"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitutil/normalize"
+	"github.com/HUSTSecLab/criticality_score/pkg/manifest"
+)
+
+var pypiNameSeparators = regexp.MustCompile(`[-_.]+`)
+
+// packageKey identifies a package across systems, e.g. npm/@babel/core or
+// maven/com.google.guava:guava.
+func packageKey(system, name string) string {
+	return strings.ToLower(system) + "/" + name
+}
+
+// normalizeName returns the canonical name of a package in its system, so
+// that names read from manifests match names known by deps.dev.
+func normalizeName(system, name string) string {
+	name = strings.TrimSpace(name)
+	switch strings.ToUpper(system) {
+	case manifest.SystemPyPI:
+		// PEP 503
+		return pypiNameSeparators.ReplaceAllString(strings.ToLower(name), "-")
+	case manifest.SystemNpm:
+		return strings.ToLower(name)
+	}
+	return name
+}
+
+// projectKey returns the deps.dev project key of a git link, e.g.
+// github.com/facebook/react for git@github.com:facebook/react.git.
+func projectKey(gitlink string) (string, bool) {
+	link, err := normalize.URL(gitlink)
+	if err != nil {
+		return "", false
+	}
+	key, _ := strings.CutPrefix(link, "https://")
+	if strings.Count(key, "/") != 2 {
+		return "", false
+	}
+	return key, true
+}
+
+// manifestPackages reads the packages declared by the manifests of the local
+// clone of gitlink, it returns nothing if the git storage is not configured
+// or the repo is not cloned.
+func manifestPackages(gitlink string) []manifest.Package {
+	storagePath := config.GetGitStoragePath()
+	if storagePath == "" {
+		return nil
+	}
+	u := giturl.ParseURL(gitlink)
+	return manifest.Packages(gitUtil.GetGitRepositoryPath(storagePath, &u), manifest.DefaultMaxDepth)
+}
@@ -0,0 +1,42 @@
+package depsdev
+
+import "testing"
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct {
+		system, name, want string
+	}{
+		{"PYPI", "Flask_SQLAlchemy", "flask-sqlalchemy"},
+		{"pypi", "zope.interface", "zope-interface"},
+		{"NPM", "@Babel/Core", "@babel/core"},
+		{"MAVEN", "com.google.guava:guava", "com.google.guava:guava"},
+	}
+	for _, tt := range tests {
+		if got := normalizeName(tt.system, tt.name); got != tt.want {
+			t.Errorf("normalizeName(%q, %q) = %q, want %q", tt.system, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestProjectKey(t *testing.T) {
+	tests := []struct {
+		link string
+		want string
+		ok   bool
+	}{
+		{"https://github.com/facebook/react.git", "github.com/facebook/react", true},
+		{"https://GitLab.com/gitlab-org/gitlab/", "gitlab.com/gitlab-org/gitlab", true},
+		{"git@github.com:facebook/react.git", "github.com/facebook/react", true},
+		{"https://github.com/facebook/react/tree/main", "github.com/facebook/react", true},
+		{"https://gitlab.com/gitlab-org/security-products/analyzers", "", false},
+		{"git://git.kernel.org/pub/scm/git/git.git", "", false},
+		{"https://github.com/facebook", "", false},
+		{"not a url", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := projectKey(tt.link)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("projectKey(%q) = %q, %v, want %q, %v", tt.link, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package depsdev
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/changefeed"
+	"github.com/HUSTSecLab/criticality_score/pkg/freshness"
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/scope"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/HUSTSecLab/criticality_score/pkg/tagging"
+	"github.com/lib/pq"
+	"github.com/samber/lo"
+)
+
+// trackedReload is how often the tracked packages are reloaded, so the
+// packages found by full passes are followed as well.
+const trackedReload = time.Hour
+
+// tracker is the latest breakdown of the packages of the repos, the
+// packages are shared by both maps.
+type tracker struct {
+	// packages are keyed by packageKey, a package may be published from
+	// several repos
+	packages map[string][]*repository.LangEcosystemPackage
+	links    map[string][]*repository.LangEcosystemPackage
+}
+
+func newTracker(pkgs []*repository.LangEcosystemPackage) *tracker {
+	t := &tracker{
+		packages: make(map[string][]*repository.LangEcosystemPackage),
+		links:    make(map[string][]*repository.LangEcosystemPackage),
+	}
+	for _, p := range pkgs {
+		key := packageKey(p.Type.String(), *p.Package)
+		t.packages[key] = append(t.packages[key], p)
+		t.links[*p.GitLink] = append(t.links[*p.GitLink], p)
+	}
+	return t
+}
+
+// changed returns the keys of the tracked packages in changes.
+func (t *tracker) changed(changes []changefeed.Change) []string {
+	var keys []string
+	for _, c := range changefeed.Dedup(changes) {
+		if !scope.Default().Ecosystem(c.System) {
+			continue
+		}
+		key := packageKey(c.System, normalizeName(c.System, c.Name))
+		if _, ok := t.packages[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// aggregate returns the dependents of every type of the packages of link
+// by policy, and the advisories of all of them.
+func (t *tracker) aggregate(link string, policy AggregatePolicy) ([]*repository.LangEcosystem, int) {
+	counts := make(map[repository.LangEcosystemType][]int)
+	advisories := 0
+	for _, p := range t.links[link] {
+		if p.DepCount != nil {
+			counts[*p.Type] = append(counts[*p.Type], *p.DepCount)
+		}
+		if p.AdvisoryCount != nil {
+			advisories += *p.AdvisoryCount
+		}
+	}
+	var ret []*repository.LangEcosystem
+	for _, ltype := range lo.Keys(counts) {
+		if depCount, ok := policy.Aggregate(counts[ltype]); ok {
+			ret = append(ret, &repository.LangEcosystem{
+				GitLink:  lo.ToPtr(link),
+				Type:     lo.ToPtr(ltype),
+				DepCount: lo.ToPtr(depCount),
+			})
+		}
+	}
+	return ret, advisories
+}
+
+// refresh updates the tracked package of key to its latest version, and
+// returns its new records, nothing if deps.dev does not know it.
+func (t *tracker) refresh(key string) ([]*repository.LangEcosystemPackage, error) {
+	pkgs := t.packages[key]
+	system, name := strings.ToUpper(pkgs[0].Type.String()), *pkgs[0].Package
+	version, err := getLatestVersion(name, system)
+	if err != nil || version == "" {
+		return nil, err
+	}
+	count, err := queryDepsDev(system, name, version)
+	if err != nil {
+		return nil, err
+	}
+	details, err := queryVersion(system, name, version)
+	if err != nil {
+		return nil, err
+	}
+	var ret []*repository.LangEcosystemPackage
+	for _, p := range pkgs {
+		p.Version = lo.ToPtr(version)
+		p.DepCount = lo.ToPtr(count)
+		if details != nil {
+			p.Licenses = lo.ToPtr(pq.StringArray(details.Licenses))
+			p.AdvisoryCount = lo.ToPtr(len(details.AdvisoryKeys))
+		}
+		record := *p
+		record.ID = nil
+		ret = append(ret, &record)
+	}
+	return ret, nil
+}
+
+// watcher refreshes the packages changed in the feeds.
+type watcher struct {
+	ac      storage.AppDatabaseContext
+	policy  AggregatePolicy
+	tracked *tracker
+	loaded  time.Time
+}
+
+// load reloads the tracked packages of the repos in scope.
+func (w *watcher) load() error {
+	pkgs, err := repository.NewLangEcosystemPackageRepository(w.ac).QueryLatest()
+	if err != nil {
+		return fmt.Errorf("%w: loading tracked packages: %w", ErrStorage, err)
+	}
+	var list []*repository.LangEcosystemPackage
+	for p := range pkgs {
+		if p.GitLink != nil && p.Type != nil && p.Package != nil {
+			list = append(list, p)
+		}
+	}
+	link := func(p *repository.LangEcosystemPackage) string { return *p.GitLink }
+	w.tracked = newTracker(scope.SliceFunc(scope.Default(), tagging.SliceFunc(tagging.Default(), list, link), link))
+	w.loaded = time.Now()
+	logger.Infof("Following %d packages of %d repos", len(w.tracked.packages), len(w.tracked.links))
+	return nil
+}
+
+// poll refreshes the packages changed in feed since its stored cursor,
+// then stores the next cursor. Packages which fail to be refreshed are
+// skipped, their repos are not marked collected, so the next full pass
+// collects them.
+func (w *watcher) poll(ctx context.Context, feed changefeed.Feed) error {
+	cursorRepo := repository.NewChangeFeedCursorRepository(w.ac)
+	var cursor string
+	stored, err := cursorRepo.Get(feed.Name())
+	if err != nil {
+		return fmt.Errorf("%w: reading cursor of %s: %w", ErrStorage, feed.Name(), err)
+	}
+	if stored != nil && stored.Cursor != nil {
+		cursor = *stored.Cursor
+	}
+	changes, next, err := feed.Poll(ctx, cursor)
+	if err != nil {
+		return fmt.Errorf("polling %s: %w", feed.Name(), err)
+	}
+
+	var errs []error
+	var records []*repository.LangEcosystemPackage
+	links := make(map[string]bool)
+	failed := make(map[string]bool)
+	keys := w.tracked.changed(changes)
+	for _, key := range keys {
+		refreshed, err := w.tracked.refresh(key)
+		for _, p := range w.tracked.packages[key] {
+			links[*p.GitLink] = true
+			if err != nil {
+				failed[*p.GitLink] = true
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+			continue
+		}
+		records = append(records, refreshed...)
+	}
+
+	pkgRepo := repository.NewLangEcosystemPackageRepository(w.ac)
+	langEcoRepo := repository.NewLangEcoLinkRepository(w.ac)
+	metricRepo := repository.NewGitMetricsRepository(w.ac)
+	if len(records) > 0 {
+		if err := pkgRepo.BatchInsert(records); err != nil {
+			return fmt.Errorf("%w: updating package breakdown: %w", ErrStorage, err)
+		}
+	}
+	var collected []string
+	for link := range links {
+		aggregated, advisories := w.tracked.aggregate(link, w.policy)
+		if err := langEcoRepo.BatchInsertOrUpdate(aggregated); err != nil {
+			return fmt.Errorf("%w: updating lang_ecosystems: %w", ErrStorage, err)
+		}
+		if err := metricRepo.UpdateAdvisoryCount(link, advisories); err != nil {
+			errs = append(errs, fmt.Errorf("%w: updating advisory count of %s: %w", ErrStorage, link, err))
+		}
+		if !failed[link] {
+			collected = append(collected, link)
+		}
+	}
+	if len(collected) > 0 {
+		now := time.Now()
+		if err := freshness.Record(repository.NewSignalProvenanceRepository(w.ac), repository.SignalLangEcosystem, collected, freshness.SourceDepsDev, now); err != nil {
+			errs = append(errs, fmt.Errorf("%w: recording the provenance of collected repos: %w", ErrStorage, err))
+		}
+		if err := repository.NewCollectionTimestampRepository(w.ac).MarkCollected(repository.SignalLangEcosystem, collected, now); err != nil {
+			errs = append(errs, fmt.Errorf("%w: marking collected repos: %w", ErrStorage, err))
+		}
+	}
+	// failed packages are not polled again, they are left to full passes
+	if next != "" && next != cursor {
+		if err := cursorRepo.Set(feed.Name(), next); err != nil {
+			errs = append(errs, fmt.Errorf("%w: storing cursor of %s: %w", ErrStorage, feed.Name(), err))
+		}
+	}
+	logger.Infof("%s: %d changes, %d tracked packages of %d repos refreshed", feed.Name(), len(changes), len(keys), len(links))
+	return errors.Join(errs...)
+}
+
+// Watch follows the change feeds of the registries, and refreshes the
+// dependents of the changed packages every interval until ctx is done.
+//
+// Only packages already in the breakdown of a repo, i.e. found by a full
+// pass of Depsdev, are followed, so the service does not discover new
+// packages. Failures are logged and retried by the next poll or full pass.
+func Watch(ctx context.Context, feeds []changefeed.Feed, interval time.Duration, policy AggregatePolicy) error {
+	w := &watcher{ac: storage.GetDefaultAppDatabaseContext(), policy: policy}
+	if err := w.load(); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if time.Since(w.loaded) > trackedReload {
+			if err := w.load(); err != nil {
+				logger.Warnf("Failed to reload tracked packages, keeping the previous ones: %v", err)
+			}
+		}
+		for _, feed := range feeds {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err := w.poll(ctx, feed); err != nil {
+				logger.Warnf("Failed to refresh changes of %s: %v", feed.Name(), err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package depsdev
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/changefeed"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/samber/lo"
+)
+
+func TestTracker(t *testing.T) {
+	pkg := func(link string, ltype repository.LangEcosystemType, name string, deps, advisories int) *repository.LangEcosystemPackage {
+		return &repository.LangEcosystemPackage{
+			GitLink: lo.ToPtr(link), Type: lo.ToPtr(ltype), Package: lo.ToPtr(name),
+			DepCount: lo.ToPtr(deps), AdvisoryCount: lo.ToPtr(advisories),
+		}
+	}
+	tr := newTracker([]*repository.LangEcosystemPackage{
+		pkg("https://github.com/babel/babel", repository.Npm, "@babel/core", 100, 1),
+		pkg("https://github.com/babel/babel", repository.Npm, "@babel/parser", 50, 2),
+		pkg("https://github.com/psf/requests", repository.Pypi, "requests", 70, 0),
+	})
+
+	// names are normalized, untracked packages are skipped
+	got := tr.changed([]changefeed.Change{
+		{System: "NPM", Name: "@babel/core"},
+		{System: "NPM", Name: "left-pad"},
+		{System: "PYPI", Name: "Requests"},
+		{System: "NPM", Name: "@babel/core"},
+	})
+	want := []string{"npm/@babel/core", "pypi/requests"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("changed() = %v, want %v", got, want)
+	}
+
+	for policy, deps := range map[AggregatePolicy]int{AggregateSum: 150, AggregateMax: 100} {
+		aggregated, advisories := tr.aggregate("https://github.com/babel/babel", policy)
+		if len(aggregated) != 1 || *aggregated[0].Type != repository.Npm || *aggregated[0].DepCount != deps || advisories != 3 {
+			t.Errorf("aggregate(%s) = %+v, %d, want %d dependents and 3 advisories", policy, aggregated, advisories, deps)
+		}
+	}
+	if aggregated, _ := tr.aggregate("https://github.com/babel/babel", AggregateList); len(aggregated) != 0 {
+		t.Errorf("aggregate(list) = %+v, want nothing", aggregated)
+	}
+}
@@ -0,0 +1,265 @@
+// Package depsdevclient is a client of the deps.dev API. The API version is
+// configurable, as Google deprecates the alpha versions periodically, e.g.
+// v3alpha in favor of v3.
+//
+// Errors of requests wrap ErrNotFound, ErrRateLimited, ErrServer,
+// ErrUnsupportedAPIVersion or ErrRequest, so callers can tell a package
+// unknown by deps.dev from an API which is gone. Rate limited, server and
+// network errors are retried, honoring Retry-After. A deprecation announced
+// by the Deprecation or Sunset headers is logged once.
+//
+// A client requests every url once per run, as many distro packages and
+// repos lead to the same packages. Across runs, responses are cached in the
+// database by the default client of httpcache.
+package depsdevclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/errors"
+	"github.com/HUSTSecLab/criticality_score/pkg/httpcache"
+	"github.com/HUSTSecLab/criticality_score/pkg/internal/jsonhttp"
+)
+
+const (
+	DefaultBaseURL    = "https://api.deps.dev"
+	DefaultAPIVersion = "v3alpha"
+	DefaultMaxRetries = 3
+
+	// initialBackoff is the delay before the first retry without
+	// Retry-After, doubled by every retry
+	initialBackoff = time.Second
+)
+
+var (
+	// ErrNotFound is wrapped by errors of unknown packages, versions or
+	// projects.
+	ErrNotFound = jsonhttp.ErrNotFound
+	// ErrRateLimited is wrapped by errors of requests still rate limited
+	// after all retries.
+	ErrRateLimited = jsonhttp.ErrRateLimited
+	// ErrServer is wrapped by 5xx errors still failing after all retries.
+	ErrServer = jsonhttp.ErrServer
+	// ErrUnsupportedAPIVersion is wrapped by errors of an API version which
+	// is gone, the API version has to be changed.
+	ErrUnsupportedAPIVersion = errors.New("unsupported api version")
+	// ErrRequest is wrapped by errors of other status codes, network
+	// errors and malformed responses.
+	ErrRequest = jsonhttp.ErrRequest
+)
+
+// APIVersionPattern matches API versions, e.g. v3 or v3alpha.
+var APIVersionPattern = regexp.MustCompile(`^v[0-9]+((alpha|beta)[0-9]*)?$`)
+
+type Config struct {
+	// BaseURL is the url of the API without the version, DefaultBaseURL if
+	// empty
+	BaseURL string
+	// APIVersion is the version of the API, DefaultAPIVersion if empty
+	APIVersion string
+	// MaxRetries is the number of retries of rate limited, server and
+	// network errors
+	MaxRetries int
+}
+
+// StatusError is an error response of the API, its Message is the
+// message of the error body, if any.
+type StatusError = jsonhttp.StatusError
+
+// Client is a client of one version of the API.
+type Client struct {
+	http    *jsonhttp.Client
+	baseURL string
+	version string
+
+	memo *memo
+	// warnOnce logs the deprecation of the API version once
+	warnOnce sync.Once
+}
+
+// New returns a client sending requests by client, http.DefaultClient if
+// nil.
+func New(client *http.Client, config *Config) *Client {
+	c := &Client{
+		baseURL: strings.TrimSuffix(config.BaseURL, "/"),
+		version: config.APIVersion,
+		memo:    newMemo(),
+	}
+	c.http = jsonhttp.New(client, jsonhttp.Config{
+		MaxRetries:     config.MaxRetries,
+		InitialBackoff: initialBackoff,
+		Check:          c.check,
+	})
+	if c.baseURL == "" {
+		c.baseURL = DefaultBaseURL
+	}
+	if c.version == "" {
+		c.version = DefaultAPIVersion
+	}
+	return c
+}
+
+// APIVersion returns the API version of the client.
+func (c *Client) APIVersion() string {
+	return c.version
+}
+
+// EscapePathSegment escapes a package name or version to be used as a
+// single path segment, e.g. @babel/core becomes %40babel%2Fcore and
+// com.google.guava:guava becomes com.google.guava%3Aguava.
+func EscapePathSegment(s string) string {
+	s = url.PathEscape(s)
+	s = strings.ReplaceAll(s, "@", "%40")
+	s = strings.ReplaceAll(s, ":", "%3A")
+	return s
+}
+
+// URL returns the url of path under the API version, path segments must be
+// escaped by EscapePathSegment.
+func (c *Client) URL(path string) string {
+	return c.baseURL + "/" + c.version + "/" + strings.TrimPrefix(path, "/")
+}
+
+func versionPath(system, name, version string) string {
+	return fmt.Sprintf("systems/%s/packages/%s/versions/%s",
+		strings.ToLower(system), EscapePathSegment(name), EscapePathSegment(version))
+}
+
+// GetPackage returns the versions of a package.
+func (c *Client) GetPackage(ctx context.Context, system, name string) (*Package, error) {
+	var ret Package
+	path := fmt.Sprintf("systems/%s/packages/%s", strings.ToLower(system), EscapePathSegment(name))
+	return &ret, c.get(ctx, path, &ret)
+}
+
+// GetVersion returns a package version, with its licenses and advisories.
+func (c *Client) GetVersion(ctx context.Context, system, name, version string) (*Version, error) {
+	var ret Version
+	return &ret, c.get(ctx, versionPath(system, name, version), &ret)
+}
+
+// GetDependents returns the number of dependents of a package version.
+func (c *Client) GetDependents(ctx context.Context, system, name, version string) (*Dependents, error) {
+	var ret Dependents
+	return &ret, c.get(ctx, versionPath(system, name, version)+":dependents", &ret)
+}
+
+// GetDependencies returns the resolved dependency graph of a package
+// version.
+func (c *Client) GetDependencies(ctx context.Context, system, name, version string) (*Dependencies, error) {
+	var ret Dependencies
+	return &ret, c.get(ctx, versionPath(system, name, version)+":dependencies", &ret)
+}
+
+// GetProjectPackageVersions returns the package versions published from a
+// project, e.g. github.com/facebook/react.
+func (c *Client) GetProjectPackageVersions(ctx context.Context, projectKey string) (*ProjectPackageVersions, error) {
+	var ret ProjectPackageVersions
+	return &ret, c.get(ctx, "projects/"+EscapePathSegment(projectKey)+":packageversions", &ret)
+}
+
+// controlChars are stripped from responses, some package metadata has
+// control characters which are not escaped
+var controlChars = regexp.MustCompile(`[[:cntrl:]]+`)
+
+// get decodes the response of path into v. Responses are memoized for the
+// run, see memo.
+func (c *Client) get(ctx context.Context, path string, v any) error {
+	u := c.URL(path)
+	body, err := c.memo.do(u, func() ([]byte, error) { return c.fetch(ctx, u) })
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("%w: decoding %s: %w", ErrRequest, u, err)
+	}
+	return nil
+}
+
+// fetch returns the body of the response of u without control characters.
+func (c *Client) fetch(ctx context.Context, u string) ([]byte, error) {
+	body, err := c.http.Get(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	return controlChars.ReplaceAll(body, nil), nil
+}
+
+// errorBody is the body of error responses of the API.
+type errorBody struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// check returns the error of a response which is not 200.
+//
+// The API answers unknown packages with 404 and a json error body, a 404
+// without it, e.g. an html page, or a 410 means the API version itself is
+// gone.
+func (c *Client) check(u string, resp *http.Response, body []byte) error {
+	c.checkDeprecation(resp)
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	var eb errorBody
+	isAPIError := json.Unmarshal(bytes.TrimSpace(body), &eb) == nil && eb.Message != ""
+	err := jsonhttp.StatusErr(resp.StatusCode)
+	if code := resp.StatusCode; (code == http.StatusNotFound && !isAPIError) || code == http.StatusGone {
+		err = fmt.Errorf("%w %s", ErrUnsupportedAPIVersion, c.version)
+	}
+	se := jsonhttp.NewStatusError(u, resp, err)
+	if isAPIError {
+		se.Message = eb.Message
+	}
+	return se
+}
+
+// checkDeprecation logs once if the response announces the deprecation of
+// the API version.
+func (c *Client) checkDeprecation(resp *http.Response) {
+	deprecation, sunset := resp.Header.Get("Deprecation"), resp.Header.Get("Sunset")
+	if deprecation == "" && sunset == "" {
+		return
+	}
+	c.warnOnce.Do(func() {
+		msg := fmt.Sprintf("deps.dev API %s is deprecated", c.version)
+		if sunset != "" {
+			msg += ", sunset " + sunset
+		}
+		log.Printf("%s, set --depsdev-api-version to a newer version", msg)
+	})
+}
+
+var (
+	defaultMu     sync.Mutex
+	defaultClient *Client
+)
+
+// InitDefault initializes the default client, sending requests by the
+// default client of httpcache, which must be initialized first.
+func InitDefault(config *Config) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultClient = New(httpcache.Client(), config)
+}
+
+// Default returns the default client, if it is not initialized, it is
+// initialized with DefaultAPIVersion. Its memo lasts for the run.
+func Default() *Client {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	if defaultClient == nil {
+		defaultClient = New(httpcache.Client(), &Config{MaxRetries: DefaultMaxRetries})
+	}
+	return defaultClient
+}
@@ -0,0 +1,146 @@
+package depsdevclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// fixtureServer serves the recorded responses in testdata by escaped path.
+func fixtureServer(t *testing.T, fixtures map[string]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, ok := fixtures[r.URL.EscapedPath()]
+		if !ok {
+			name = "not_found.json"
+			w.WriteHeader(http.StatusNotFound)
+		}
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Error(err)
+		}
+		w.Write(data)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClient(t *testing.T) {
+	srv := fixtureServer(t, map[string]string{
+		"/v3/systems/npm/packages/%40babel%2Fcore":                    "package.json",
+		"/v3/systems/npm/packages/lodash/versions/4.17.20":            "version.json",
+		"/v3/systems/npm/packages/lodash/versions/4.17.20:dependents": "dependents.json",
+		"/v3/systems/npm/packages/react/versions/18.3.1:dependencies": "dependencies.json",
+		"/v3/projects/github.com%2Ffacebook%2Freact:packageversions":  "project.json",
+	})
+	c := New(srv.Client(), &Config{BaseURL: srv.URL, APIVersion: "v3"})
+	ctx := context.Background()
+
+	pkg, err := c.GetPackage(ctx, "NPM", "@babel/core")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkg.Versions) != 2 || pkg.Versions[1].VersionKey.Version != "7.26.0" || !pkg.Versions[1].IsDefault {
+		t.Errorf("GetPackage() = %+v", pkg)
+	}
+
+	version, err := c.GetVersion(ctx, "npm", "lodash", "4.17.20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(version.Licenses, []string{"MIT"}) || len(version.AdvisoryKeys) != 2 ||
+		version.AdvisoryKeys[0].ID != "GHSA-29mw-wpgm-hmr9" {
+		t.Errorf("GetVersion() = %+v", version)
+	}
+
+	dependents, err := c.GetDependents(ctx, "npm", "lodash", "4.17.20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dependents.DependentCount != 171853 || dependents.DirectDependentCount != 71802 {
+		t.Errorf("GetDependents() = %+v", dependents)
+	}
+
+	// the fixture has a control character, which is stripped
+	deps, err := c.GetDependencies(ctx, "npm", "react", "18.3.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deps.Nodes) != 3 || deps.Nodes[1].Relation != "DIRECT" || deps.Edges[1].Requirement != "^3.0.0 || ^4.0.0" {
+		t.Errorf("GetDependencies() = %+v", deps)
+	}
+
+	project, err := c.GetProjectPackageVersions(ctx, "github.com/facebook/react")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(project.Versions) != 2 || len(project.Versions[1].SLSAProvenances) != 1 || !project.Versions[1].SLSAProvenances[0].Verified {
+		t.Errorf("GetProjectPackageVersions() = %+v", project)
+	}
+
+	_, err = c.GetVersion(ctx, "npm", "lodash", "0.0.0")
+	var se *StatusError
+	if !errors.Is(err, ErrNotFound) || !errors.As(err, &se) || se.Message != "package not found" {
+		t.Errorf("GetVersion() of an unknown version = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   error
+	}{
+		{"ok", 200, "dependents.json", nil},
+		{"not found", 404, "not_found.json", ErrNotFound},
+		{"api version gone", 404, "gone.html", ErrUnsupportedAPIVersion},
+		{"gone", 410, "not_found.json", ErrUnsupportedAPIVersion},
+		{"bad request", 400, "not_found.json", ErrRequest},
+		{"rate limited", 429, "", ErrRateLimited},
+		{"server error", 503, "", ErrServer},
+	}
+	c := New(nil, &Config{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body []byte
+			if tt.body != "" {
+				var err error
+				if body, err = os.ReadFile(filepath.Join("testdata", tt.body)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			rec := httptest.NewRecorder()
+			rec.WriteHeader(tt.status)
+			err := c.check("/v3/systems/npm/packages/lodash", rec.Result(), body)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("check() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestURL(t *testing.T) {
+	c := New(nil, &Config{})
+	if got, want := c.URL("systems/npm"), "https://api.deps.dev/v3alpha/systems/npm"; got != want {
+		t.Errorf("URL() = %q, want %q", got, want)
+	}
+
+	tests := map[string]string{
+		"react":                  "react",
+		"@babel/core":            "%40babel%2Fcore",
+		"com.google.guava:guava": "com.google.guava%3Aguava",
+		"github.com/spf13/pflag": "github.com%2Fspf13%2Fpflag",
+		"1.0.0+build":            "1.0.0+build",
+	}
+	for in, want := range tests {
+		if got := EscapePathSegment(in); got != want {
+			t.Errorf("EscapePathSegment(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
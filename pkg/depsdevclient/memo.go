@@ -0,0 +1,66 @@
+package depsdevclient
+
+import (
+	"errors"
+	"sync"
+)
+
+// memo memoizes the responses of a run by url, which is keyed by the API
+// version, the system, the package and the version. Concurrent requests of
+// the same url wait for the first one instead of querying again.
+//
+// Successful responses and errors of unknown packages are kept, transient
+// errors are not, so the url is requested again later.
+type memo struct {
+	mu     sync.Mutex
+	calls  map[string]*call
+	hits   int
+	misses int
+}
+
+type call struct {
+	done chan struct{}
+	body []byte
+	err  error
+}
+
+func newMemo() *memo {
+	return &memo{calls: make(map[string]*call)}
+}
+
+// permanent reports whether err is kept by the memo.
+func permanent(err error) bool {
+	return err == nil || errors.Is(err, ErrNotFound) || errors.Is(err, ErrUnsupportedAPIVersion)
+}
+
+// do returns the memoized response of u, calling fetch if there is none.
+func (m *memo) do(u string, fetch func() ([]byte, error)) ([]byte, error) {
+	m.mu.Lock()
+	if c, ok := m.calls[u]; ok {
+		m.hits++
+		m.mu.Unlock()
+		<-c.done
+		return c.body, c.err
+	}
+	m.misses++
+	c := &call{done: make(chan struct{})}
+	m.calls[u] = c
+	m.mu.Unlock()
+
+	c.body, c.err = fetch()
+	if !permanent(c.err) {
+		m.mu.Lock()
+		delete(m.calls, u)
+		m.mu.Unlock()
+	}
+	close(c.done)
+	return c.body, c.err
+}
+
+// Stats returns the number of requests served by the memo of the run, and
+// the number of requests sent.
+func (c *Client) Stats() (memoized, sent int) {
+	c.memo.mu.Lock()
+	defer c.memo.mu.Unlock()
+	return c.memo.hits, c.memo.misses
+}
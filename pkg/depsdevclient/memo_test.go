@@ -0,0 +1,63 @@
+package depsdevclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestMemo(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch r.URL.Path {
+		case "/v3alpha/systems/npm/packages/lodash/versions/4.17.20:dependents":
+			w.Write([]byte(`{"dependentCount": 3}`))
+		case "/v3alpha/systems/npm/packages/flaky/versions/1.0.0:dependents":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code": 5, "message": "package not found"}`))
+		}
+	}))
+	defer srv.Close()
+	c := New(srv.Client(), &Config{BaseURL: srv.URL})
+	ctx := context.Background()
+
+	// concurrent lookups of the same version are sent once
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d, err := c.GetDependents(ctx, "NPM", "lodash", "4.17.20")
+			if err != nil || d.DependentCount != 3 {
+				t.Errorf("GetDependents() = %+v, %v", d, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := requests.Load(); n != 1 {
+		t.Errorf("sent %d requests, want 1", n)
+	}
+
+	// unknown packages are memoized, transient errors are not
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetDependents(ctx, "npm", "unknown", "1.0.0"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("GetDependents(unknown) = %v, want %v", err, ErrNotFound)
+		}
+		if _, err := c.GetDependents(ctx, "npm", "flaky", "1.0.0"); !errors.Is(err, ErrServer) {
+			t.Errorf("GetDependents(flaky) = %v, want %v", err, ErrServer)
+		}
+	}
+	if n := requests.Load(); n != 4 {
+		t.Errorf("sent %d requests, want 4", n)
+	}
+	if memoized, sent := c.Stats(); memoized != 10 || sent != 4 {
+		t.Errorf("Stats() = %d, %d, want 10, 4", memoized, sent)
+	}
+}
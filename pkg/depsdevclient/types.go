@@ -0,0 +1,113 @@
+package depsdevclient
+
+import "time"
+
+// PackageKey identifies a package, System is upper case in responses, e.g.
+// NPM, and case insensitive in requests.
+type PackageKey struct {
+	System string `json:"system"`
+	Name   string `json:"name"`
+}
+
+// VersionKey identifies a package version.
+type VersionKey struct {
+	System  string `json:"system"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Package is the response of GetPackage.
+type Package struct {
+	PackageKey PackageKey       `json:"packageKey"`
+	Versions   []PackageVersion `json:"versions"`
+}
+
+// PackageVersion is a version of a Package.
+type PackageVersion struct {
+	VersionKey  VersionKey `json:"versionKey"`
+	PublishedAt time.Time  `json:"publishedAt"`
+	IsDefault   bool       `json:"isDefault"`
+}
+
+// AdvisoryKey identifies a security advisory, e.g. GHSA-29mw-wpgm-hmr9.
+type AdvisoryKey struct {
+	ID string `json:"id"`
+}
+
+// Link is a link of a version, e.g. its SOURCE_REPO.
+type Link struct {
+	Label string `json:"label"`
+	URL   string `json:"url"`
+}
+
+// Version is the response of GetVersion.
+type Version struct {
+	VersionKey   VersionKey    `json:"versionKey"`
+	PublishedAt  time.Time     `json:"publishedAt"`
+	IsDefault    bool          `json:"isDefault"`
+	Licenses     []string      `json:"licenses"`
+	AdvisoryKeys []AdvisoryKey `json:"advisoryKeys"`
+	Links        []Link        `json:"links"`
+}
+
+// Dependents is the response of GetDependents.
+type Dependents struct {
+	DependentCount         int `json:"dependentCount"`
+	DirectDependentCount   int `json:"directDependentCount"`
+	IndirectDependentCount int `json:"indirectDependentCount"`
+}
+
+// Node is a package version of a dependency graph.
+type Node struct {
+	VersionKey VersionKey `json:"versionKey"`
+	Bundled    bool       `json:"bundled"`
+	// Relation is SELF, DIRECT or INDIRECT
+	Relation string   `json:"relation"`
+	Errors   []string `json:"errors"`
+}
+
+// Edge is a dependency between the nodes of a dependency graph, by index.
+type Edge struct {
+	FromNode    int    `json:"fromNode"`
+	ToNode      int    `json:"toNode"`
+	Requirement string `json:"requirement"`
+}
+
+// Dependencies is the response of GetDependencies, the resolved dependency
+// graph of a version.
+type Dependencies struct {
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+	Error string `json:"error"`
+}
+
+// SLSAProvenance is a SLSA provenance of a package version.
+type SLSAProvenance struct {
+	SourceRepository string `json:"sourceRepository"`
+	Commit           string `json:"commit"`
+	URL              string `json:"url"`
+	Verified         bool   `json:"verified"`
+}
+
+// Attestation is an attestation of a package version.
+type Attestation struct {
+	Type             string `json:"type"`
+	URL              string `json:"url"`
+	Verified         bool   `json:"verified"`
+	SourceRepository string `json:"sourceRepository"`
+	Commit           string `json:"commit"`
+}
+
+// ProjectPackageVersion is a package version published from a project.
+type ProjectPackageVersion struct {
+	VersionKey         VersionKey       `json:"versionKey"`
+	RelationType       string           `json:"relationType"`
+	RelationProvenance string           `json:"relationProvenance"`
+	SLSAProvenances    []SLSAProvenance `json:"slsaProvenances"`
+	Attestations       []Attestation    `json:"attestations"`
+}
+
+// ProjectPackageVersions is the response of GetProjectPackageVersions.
+type ProjectPackageVersions struct {
+	Versions []ProjectPackageVersion `json:"versions"`
+}
@@ -0,0 +1,172 @@
+// Package errors classifies the errors of the collectors into categories,
+// so retry loops, failure summaries and logs agree about which errors are
+// worth retrying:
+//
+//   - Transient: the request may succeed later, e.g. a timeout, a 5xx or a
+//     deadlock of the database
+//   - RateLimited: the source asks to slow down, e.g. a 429
+//   - NotFound: the item is gone or never existed, e.g. an unknown package
+//   - ParseError: the response or the file is malformed, retrying returns
+//     the same
+//   - DataIntegrity: the data violates a constraint, e.g. a duplicate key
+//
+// Packages mark their sentinel errors with a category, and errors of the
+// standard library, net and pq are classified by Of without marks:
+//
+//	ErrNotFound = errors.Mark(errors.New("not found"), errors.NotFound)
+//
+//	if errors.Retryable(err) {
+//		// retry later
+//	}
+//
+// The package wraps the standard errors package, so it replaces its import.
+package errors
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	stderrors "errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/lib/pq"
+)
+
+// Category is the category of an error, it is an error itself, so
+// errors.Is(err, errors.NotFound) tells whether err is of a category.
+type Category string
+
+const (
+	// Unknown is the category of errors which are not classified, they are
+	// not retried
+	Unknown       Category = "unknown"
+	Transient     Category = "transient"
+	RateLimited   Category = "rate_limited"
+	NotFound      Category = "not_found"
+	ParseError    Category = "parse_error"
+	DataIntegrity Category = "data_integrity"
+)
+
+// Categories are the categories of classified errors.
+var Categories = []Category{Transient, RateLimited, NotFound, ParseError, DataIntegrity}
+
+func (c Category) Error() string {
+	return string(c)
+}
+
+// Retryable returns true if errors of the category may succeed if
+// retried, later for RateLimited.
+func (c Category) Retryable() bool {
+	return c == Transient || c == RateLimited
+}
+
+// marked is an error with a category.
+type marked struct {
+	err      error
+	category Category
+}
+
+func (m *marked) Error() string {
+	return m.err.Error()
+}
+
+// Unwrap returns the category first, so it takes precedence over the
+// categories of err.
+func (m *marked) Unwrap() []error {
+	return []error{m.category, m.err}
+}
+
+// Mark returns err of the category c, nil if err is nil. The mark takes
+// precedence over the categories err wraps.
+func Mark(err error, c Category) error {
+	if err == nil {
+		return nil
+	}
+	return &marked{err: err, category: c}
+}
+
+// Of returns the category of err, "" if err is nil and Unknown if it is
+// not classified. The first category marked along the chain of err is
+// returned, otherwise err is classified by the errors it wraps:
+//
+//   - timeouts, refused or reset connections, unexpected EOFs and
+//     context.DeadlineExceeded are Transient, context.Canceled is Unknown
+//     as the run is stopping
+//   - unknown hosts and sql.ErrNoRows are NotFound
+//   - json and pq syntax errors are ParseError
+//   - pq serialization failures, deadlocks and connection errors are
+//     Transient, and pq integrity violations DataIntegrity
+func Of(err error) Category {
+	if err == nil {
+		return ""
+	}
+	var c Category
+	if As(err, &c) {
+		return c
+	}
+
+	if Is(err, context.Canceled) {
+		return Unknown
+	}
+	var pqErr *pq.Error
+	if As(err, &pqErr) {
+		return pqCategory(pqErr)
+	}
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if As(err, &syntaxErr) || As(err, &typeErr) {
+		return ParseError
+	}
+	if Is(err, sql.ErrNoRows) {
+		return NotFound
+	}
+	var dnsErr *net.DNSError
+	if As(err, &dnsErr) && dnsErr.IsNotFound {
+		return NotFound
+	}
+	if Is(err, context.DeadlineExceeded) || Is(err, io.ErrUnexpectedEOF) ||
+		Is(err, syscall.ECONNREFUSED) || Is(err, syscall.ECONNRESET) || Is(err, syscall.EPIPE) {
+		return Transient
+	}
+	var netErr net.Error
+	if As(err, &netErr) && netErr.Timeout() || As(err, &dnsErr) && dnsErr.IsTemporary {
+		return Transient
+	}
+	return Unknown
+}
+
+// pqCategory returns the category of an error of postgres by the class of
+// its code, see https://www.postgresql.org/docs/current/errcodes-appendix.html.
+func pqCategory(err *pq.Error) Category {
+	code := string(err.Code)
+	switch {
+	case code == "40001" || code == "40P01":
+		// serialization failure and deadlock
+		return Transient
+	case strings.HasPrefix(code, "08"), code == "57P01", code == "57P03", code == "53300":
+		// connection exceptions, shutdowns and too many connections
+		return Transient
+	case strings.HasPrefix(code, "23"):
+		return DataIntegrity
+	case strings.HasPrefix(code, "22"):
+		// data exceptions, e.g. invalid text representation
+		return ParseError
+	}
+	return Unknown
+}
+
+// Retryable returns true if err may succeed if retried, see Of.
+func Retryable(err error) bool {
+	return Of(err).Retryable()
+}
+
+// The functions of the standard errors package.
+
+func New(text string) error         { return stderrors.New(text) }
+func Is(err, target error) bool     { return stderrors.Is(err, target) }
+func As(err error, target any) bool { return stderrors.As(err, target) }
+func Join(errs ...error) error      { return stderrors.Join(errs...) }
+func Unwrap(err error) error        { return stderrors.Unwrap(err) }
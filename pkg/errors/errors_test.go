@@ -0,0 +1,68 @@
+package errors
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestOf(t *testing.T) {
+	errNotFound := Mark(New("not found"), NotFound)
+	var syntaxErr error = &json.SyntaxError{}
+	jsonErr := json.Unmarshal([]byte("{"), &struct{}{})
+
+	tests := []struct {
+		name string
+		err  error
+		want Category
+	}{
+		{"nil", nil, ""},
+		{"unclassified", New("boom"), Unknown},
+		{"marked", errNotFound, NotFound},
+		{"wrapped mark", fmt.Errorf("package foo: %w", errNotFound), NotFound},
+		{"wrapped category", fmt.Errorf("%w: 503", Transient), Transient},
+		{"outer mark first", Mark(fmt.Errorf("%w", errNotFound), DataIntegrity), DataIntegrity},
+		{"joined", Join(New("boom"), Mark(New("slow down"), RateLimited)), RateLimited},
+		{"deadline", fmt.Errorf("fetch: %w", context.DeadlineExceeded), Transient},
+		{"canceled", context.Canceled, Unknown},
+		{"unexpected eof", io.ErrUnexpectedEOF, Transient},
+		{"refused", &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, Transient},
+		{"timeout", &net.DNSError{Err: "i/o timeout", IsTimeout: true}, Transient},
+		{"unknown host", &net.DNSError{Err: "no such host", IsNotFound: true}, NotFound},
+		{"no rows", fmt.Errorf("query: %w", sql.ErrNoRows), NotFound},
+		{"json syntax", jsonErr, ParseError},
+		{"json syntax type", syntaxErr, ParseError},
+		{"deadlock", &pq.Error{Code: "40P01"}, Transient},
+		{"connection", &pq.Error{Code: "08006"}, Transient},
+		{"duplicate key", &pq.Error{Code: "23505"}, DataIntegrity},
+		{"invalid text", &pq.Error{Code: "22P02"}, ParseError},
+		{"syntax error of a query", &pq.Error{Code: "42601"}, Unknown},
+	}
+	for _, tt := range tests {
+		if got := Of(tt.err); got != tt.want {
+			t.Errorf("%s: Of(%v) = %q, want %q", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestMark(t *testing.T) {
+	base := New("rate limited")
+	err := Mark(base, RateLimited)
+	if err.Error() != "rate limited" || !Is(err, base) || !Is(err, RateLimited) || Is(err, Transient) {
+		t.Errorf("Mark() = %v, does not wrap its error and category", err)
+	}
+	if !Retryable(fmt.Errorf("after 3 retries: %w", err)) || Retryable(Mark(base, NotFound)) {
+		t.Error("Retryable() does not follow the category")
+	}
+	if Mark(nil, Transient) != nil {
+		t.Error("Mark(nil) is not nil")
+	}
+}
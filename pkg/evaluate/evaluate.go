@@ -0,0 +1,181 @@
+// Package evaluate validates the scores against the dataset of the upstream
+// OpenSSF criticality_score project. Both rankings are joined by repo url
+// and compared by rank correlation, and the projects ranked most
+// differently are reported, as they point at bugs in collectors or in
+// scoring.
+package evaluate
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	url "github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser/url"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/pathmap"
+	"github.com/HUSTSecLab/criticality_score/pkg/score"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+)
+
+// DefaultURL is the csv dataset of all projects scored by the upstream
+// project.
+const DefaultURL = "https://www.googleapis.com/download/storage/v1/b/ossf-criticality-score/o/all.csv?alt=media"
+
+const (
+	// Upstream and Ours name the rankings in reports
+	Upstream = "upstream"
+	Ours     = "ours"
+)
+
+// columns of the repo url and the score, of the current and the legacy
+// datasets of the upstream project
+var (
+	urlColumns   = []string{"repo.url", "url"}
+	scoreColumns = []string{"default_score", "criticality_score"}
+)
+
+// Key returns the key joining projects by repo url, regardless of the
+// case, the scheme and the .git suffix of the url.
+func Key(link string) string {
+	u := url.ParseURL(link)
+	return pathmap.Normalize(&u)
+}
+
+func column(header []string, names []string) int {
+	for _, name := range names {
+		for i, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), name) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// Parse returns the scores of a csv dataset of the upstream project by
+// Key. Rows without a score are skipped.
+func Parse(r io.Reader) (map[string]float64, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	urlCol, scoreCol := column(header, urlColumns), column(header, scoreColumns)
+	if urlCol < 0 || scoreCol < 0 {
+		return nil, fmt.Errorf("dataset has no %s or %s column", strings.Join(urlColumns, "/"), strings.Join(scoreColumns, "/"))
+	}
+
+	ret := make(map[string]float64)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) <= urlCol || len(record) <= scoreCol || record[urlCol] == "" {
+			continue
+		}
+		s, err := strconv.ParseFloat(strings.TrimSpace(record[scoreCol]), 64)
+		if err != nil {
+			continue
+		}
+		ret[Key(record[urlCol])] = s
+	}
+	return ret, nil
+}
+
+// Open opens a dataset, source is a http(s) url or a local file. client is
+// http.DefaultClient if nil.
+func Open(ctx context.Context, client *http.Client, source string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return os.Open(source)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to download %s: %s", source, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// Load returns the scores of the dataset at source by Key.
+func Load(ctx context.Context, client *http.Client, source string) (map[string]float64, error) {
+	r, err := Open(ctx, client, source)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return Parse(r)
+}
+
+// LoadScores returns the latest scores of the database by Key. Links of
+// the same repo keep the highest score.
+func LoadScores(ac storage.AppDatabaseContext) (map[string]float64, error) {
+	scores, err := repository.NewScoreRepository(ac).Query()
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[string]float64)
+	for s := range scores {
+		if s.GitLink == nil || s.Score == nil {
+			continue
+		}
+		key := Key(*s.GitLink)
+		if old, ok := ret[key]; !ok || *s.Score > old {
+			ret[key] = *s.Score
+		}
+	}
+	return ret, nil
+}
+
+// Evaluation compares our ranking with the upstream one.
+type Evaluation struct {
+	*score.Comparison
+	// Ours and Upstream are the numbers of projects of both rankings, the
+	// joined projects are Comparison.Projects
+	Ours     int
+	Upstream int
+}
+
+// Evaluate compares ours with upstream, keeping the top projects we rank
+// higher and lower than upstream.
+func Evaluate(ours, upstream map[string]float64, top int) *Evaluation {
+	return &Evaluation{
+		Comparison: score.Compare(Upstream, upstream, Ours, ours, top),
+		Ours:       len(ours),
+		Upstream:   len(upstream),
+	}
+}
+
+// WriteReport writes the evaluation as markdown.
+func (e *Evaluation) WriteReport(w io.Writer) error {
+	coverage := 0.0
+	if e.Upstream > 0 {
+		coverage = float64(e.Projects) / float64(e.Upstream) * 100
+	}
+	if _, err := fmt.Fprintf(w, "# Evaluation against the upstream dataset\n\n- Our projects: %d\n- Upstream projects: %d\n- Joined projects: %d (%.1f%% of upstream)\n\n",
+		e.Ours, e.Upstream, e.Projects, coverage); err != nil {
+		return err
+	}
+	return e.Comparison.WriteReport(w)
+}
@@ -0,0 +1,68 @@
+package evaluate
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := map[string]string{
+		"current": "repo.name,repo.url,default_score\nbar,https://github.com/Foo/bar,0.5\nbaz,https://github.com/foo/baz,\n",
+		"legacy":  "name,url,language,criticality_score\nbar,https://github.com/foo/bar.git,Go,0.5\n",
+	}
+	for name, data := range tests {
+		got, err := Parse(strings.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s: Parse() error = %v", name, err)
+		}
+		if len(got) != 1 || got["github.com/foo/bar"] != 0.5 {
+			t.Errorf("%s: Parse() = %v", name, got)
+		}
+	}
+	if _, err := Parse(strings.NewReader("name,stars\nbar,1\n")); err == nil {
+		t.Error("Parse() without url column error = nil")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/all.csv" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("repo.url,default_score\ngit@github.com:foo/bar.git,0.5\n"))
+	}))
+	defer srv.Close()
+
+	got, err := Load(context.Background(), srv.Client(), srv.URL+"/all.csv")
+	if err != nil || got["github.com/foo/bar"] != 0.5 {
+		t.Errorf("Load() = %v, %v", got, err)
+	}
+	if _, err := Load(context.Background(), srv.Client(), srv.URL+"/missing.csv"); err == nil {
+		t.Error("Load() of missing dataset error = nil")
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	ours := map[string]float64{"github.com/a/a": 0.9, "github.com/b/b": 0.1, "github.com/c/c": 0.5, "github.com/d/d": 0.3}
+	upstream := map[string]float64{"github.com/a/a": 0.8, "github.com/b/b": 0.7, "github.com/c/c": 0.4, "github.com/e/e": 0.1}
+	e := Evaluate(ours, upstream, 10)
+	if e.Ours != 4 || e.Upstream != 4 || e.Projects != 3 {
+		t.Errorf("Evaluate() = %d ours, %d upstream, %d joined, want 4, 4, 3", e.Ours, e.Upstream, e.Projects)
+	}
+	if len(e.Drops) != 1 || e.Drops[0].GitLink != "github.com/b/b" {
+		t.Errorf("Evaluate() drops = %v", e.Drops)
+	}
+
+	var buf bytes.Buffer
+	if err := e.WriteReport(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "Joined projects: 3 (75.0% of upstream)") {
+		t.Errorf("WriteReport() = %s", buf.String())
+	}
+}
@@ -0,0 +1,151 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// BigQueryURL is the endpoint of the BigQuery REST API.
+var BigQueryURL = "https://bigquery.googleapis.com/bigquery/v2"
+
+// BigQueryLoader loads newline-delimited JSON files from GCS into BigQuery
+// tables.
+//
+// Every load replaces the table with the schema of the exported struct, so
+// columns added to the database are added to BigQuery by the next export
+// without any manual migration.
+type BigQueryLoader struct {
+	Project string
+	Dataset string
+	// Token is an OAuth2 access token, e.g. from
+	// `gcloud auth print-access-token`
+	Token  string
+	Client *http.Client
+	// PollInterval is the interval of checking whether a load job is done
+	PollInterval time.Duration
+}
+
+type bigQueryJob struct {
+	JobReference *struct {
+		ProjectID string `json:"projectId"`
+		JobID     string `json:"jobId"`
+		Location  string `json:"location"`
+	} `json:"jobReference,omitempty"`
+	Configuration struct {
+		Load *bigQueryLoadConfig `json:"load,omitempty"`
+	} `json:"configuration"`
+	Status *struct {
+		State       string `json:"state"`
+		ErrorResult *struct {
+			Message string `json:"message"`
+		} `json:"errorResult"`
+	} `json:"status,omitempty"`
+}
+
+type bigQueryLoadConfig struct {
+	SourceURIs       []string `json:"sourceUris"`
+	SourceFormat     string   `json:"sourceFormat"`
+	WriteDisposition string   `json:"writeDisposition"`
+	DestinationTable struct {
+		ProjectID string `json:"projectId"`
+		DatasetID string `json:"datasetId"`
+		TableID   string `json:"tableId"`
+	} `json:"destinationTable"`
+	Schema struct {
+		Fields []Field `json:"fields"`
+	} `json:"schema"`
+}
+
+func (b *BigQueryLoader) do(method, u string, body any) (*bigQueryJob, error) {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, u, r)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+b.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := b.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("bigquery returned %s: %s", resp.Status, data)
+	}
+
+	var job bigQueryJob
+	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Load replaces the table with the content of sourceURI, and waits for the
+// load job to finish.
+func (b *BigQueryLoader) Load(table string, schema []Field, sourceURI string) error {
+	if !strings.HasPrefix(sourceURI, "gs://") {
+		return fmt.Errorf("bigquery can only load files from gcs, got %s", sourceURI)
+	}
+
+	load := &bigQueryLoadConfig{
+		SourceURIs:       []string{sourceURI},
+		SourceFormat:     "NEWLINE_DELIMITED_JSON",
+		WriteDisposition: "WRITE_TRUNCATE",
+	}
+	load.DestinationTable.ProjectID = b.Project
+	load.DestinationTable.DatasetID = b.Dataset
+	load.DestinationTable.TableID = table
+	load.Schema.Fields = schema
+
+	var req bigQueryJob
+	req.Configuration.Load = load
+	job, err := b.do(http.MethodPost, fmt.Sprintf("%s/projects/%s/jobs", BigQueryURL, url.PathEscape(b.Project)), &req)
+	if err != nil {
+		return err
+	}
+
+	interval := b.PollInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	for {
+		if job.Status != nil && job.Status.State == "DONE" {
+			if job.Status.ErrorResult != nil {
+				return fmt.Errorf("load job failed: %s", job.Status.ErrorResult.Message)
+			}
+			return nil
+		}
+		if job.JobReference == nil {
+			return fmt.Errorf("load job has no reference")
+		}
+
+		time.Sleep(interval)
+		u := fmt.Sprintf("%s/projects/%s/jobs/%s", BigQueryURL,
+			url.PathEscape(job.JobReference.ProjectID), url.PathEscape(job.JobReference.JobID))
+		if job.JobReference.Location != "" {
+			u += "?location=" + url.QueryEscape(job.JobReference.Location)
+		}
+		if job, err = b.do(http.MethodGet, u, nil); err != nil {
+			return err
+		}
+	}
+}
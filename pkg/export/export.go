@@ -0,0 +1,170 @@
+// Package export pushes the scores and metrics tables to data warehouses,
+// as newline-delimited JSON files in a directory or a GCS bucket, which are
+// then loaded into BigQuery like the dataset of the upstream
+// criticality_score project.
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"reflect"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/schema"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+	"github.com/lib/pq"
+)
+
+// Field is a column of a BigQuery table schema.
+type Field = schema.Field
+
+// Dataset is the name of the exported dataset in its schema descriptor.
+const Dataset = "criticality_score"
+
+// SchemaVersion is the version of the exported tables, bump the minor
+// version when columns are added, and the major version when columns are
+// removed or changed, see package schema.
+const SchemaVersion = "1.14"
+
+// Table is a table to be exported.
+type Table struct {
+	Name   string
+	Schema []Field
+	// Write writes all rows as newline-delimited JSON and returns the number
+	// of rows
+	Write func(w io.Writer) (int, error)
+}
+
+// SchemaOf returns the BigQuery schema of a repository struct, the field
+// names are the database column names.
+func SchemaOf[T any]() ([]Field, error) {
+	return schema.ColumnsOf[T]()
+}
+
+// Descriptor returns the schema descriptor of the exported tables.
+func Descriptor(tables []Table) *schema.Descriptor {
+	d := &schema.Descriptor{Dataset: Dataset, Version: SchemaVersion}
+	for _, t := range tables {
+		d.Tables = append(d.Tables, schema.Table{Name: t.Name, Format: schema.FormatNDJSON, Fields: t.Schema})
+	}
+	return d
+}
+
+// rowOf returns a row as a map from column names to values, nil fields are
+// omitted.
+func rowOf(v any) map[string]any {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer {
+		rv = rv.Elem()
+	}
+	t := rv.Type()
+
+	ret := make(map[string]any, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() || f.Tag.Get("ignore") == "true" {
+			continue
+		}
+		fv := rv.Field(i)
+		if fv.Kind() == reflect.Pointer {
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		}
+		switch val := fv.Interface().(type) {
+		case time.Time:
+			ret[sqlutil.ColumnName(f)] = val.UTC().Format(time.RFC3339Nano)
+		case pq.StringArray:
+			ret[sqlutil.ColumnName(f)] = []string(val)
+		default:
+			ret[sqlutil.ColumnName(f)] = val
+		}
+	}
+	return ret
+}
+
+// WriteNDJSON writes rows as newline-delimited JSON.
+func WriteNDJSON[T any](w io.Writer, rows iter.Seq[*T]) (int, error) {
+	enc := json.NewEncoder(w)
+	n := 0
+	for row := range rows {
+		if err := enc.Encode(rowOf(row)); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+func newTable[T any](name string, query func() (iter.Seq[*T], error)) (Table, error) {
+	schema, err := SchemaOf[T]()
+	if err != nil {
+		return Table{}, err
+	}
+	return Table{
+		Name:   name,
+		Schema: schema,
+		Write: func(w io.Writer) (int, error) {
+			rows, err := query()
+			if err != nil {
+				return 0, err
+			}
+			return WriteNDJSON(w, rows)
+		},
+	}, nil
+}
+
+// Tables returns the exported tables, which are the latest scores and git
+// metrics of every repo.
+func Tables(ac storage.AppDatabaseContext) ([]Table, error) {
+	scores, err := newTable(repository.ScoreTableName, repository.NewScoreRepository(ac).Query)
+	if err != nil {
+		return nil, err
+	}
+	metrics, err := newTable(repository.GitMetricTableName, repository.NewGitMetricsRepository(ac).Query)
+	if err != nil {
+		return nil, err
+	}
+	return []Table{scores, metrics}, nil
+}
+
+// Export writes the schema descriptor of the tables into the sink as
+// <prefix>schema.json, then every table as <prefix><table>.json, and loads
+// it into BigQuery if loader is not nil.
+func Export(tables []Table, sink Sink, loader *BigQueryLoader, prefix string) error {
+	descriptor, err := Descriptor(tables).Marshal()
+	if err != nil {
+		return err
+	}
+	if _, err := sink.Put(prefix+schema.File, bytes.NewReader(descriptor)); err != nil {
+		return fmt.Errorf("failed to upload %s: %w", prefix+schema.File, err)
+	}
+
+	for _, table := range tables {
+		var buf bytes.Buffer
+		n, err := table.Write(&buf)
+		if err != nil {
+			return fmt.Errorf("failed to export %s: %w", table.Name, err)
+		}
+
+		object := prefix + table.Name + ".json"
+		uri, err := sink.Put(object, &buf)
+		if err != nil {
+			return fmt.Errorf("failed to upload %s: %w", object, err)
+		}
+
+		if loader != nil {
+			if err := loader.Load(table.Name, table.Schema, uri); err != nil {
+				return fmt.Errorf("failed to load %s into BigQuery: %w", table.Name, err)
+			}
+		}
+		fmt.Printf("Exported %d rows of %s to %s\n", n, table.Name, uri)
+	}
+	return nil
+}
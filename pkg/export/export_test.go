@@ -0,0 +1,160 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/schema"
+	"github.com/lib/pq"
+	"github.com/samber/lo"
+)
+
+type testRow struct {
+	ID        *int64 `pk:"true"`
+	GitLink   *string
+	Score     *float64
+	Language  *pq.StringArray
+	Valid     *bool
+	UpdatedAt *time.Time `column:"update_time"`
+}
+
+func TestSchemaOf(t *testing.T) {
+	schema, err := SchemaOf[testRow]()
+	if err != nil {
+		t.Fatalf("SchemaOf() error = %v", err)
+	}
+	want := []Field{
+		{Name: "id", Type: "INTEGER", Mode: "NULLABLE"},
+		{Name: "git_link", Type: "STRING", Mode: "NULLABLE"},
+		{Name: "score", Type: "FLOAT", Mode: "NULLABLE"},
+		{Name: "language", Type: "STRING", Mode: "REPEATED"},
+		{Name: "valid", Type: "BOOLEAN", Mode: "NULLABLE"},
+		{Name: "update_time", Type: "TIMESTAMP", Mode: "NULLABLE"},
+	}
+	if !reflect.DeepEqual(schema, want) {
+		t.Errorf("SchemaOf() = %v, want %v", schema, want)
+	}
+
+	if _, err := SchemaOf[struct{ M map[string]int }](); err == nil {
+		t.Errorf("SchemaOf() should fail for unsupported types")
+	}
+}
+
+func TestWriteNDJSON(t *testing.T) {
+	rows := []*testRow{
+		{
+			ID:        lo.ToPtr(int64(1)),
+			GitLink:   lo.ToPtr("https://github.com/a/b"),
+			Language:  &pq.StringArray{"Go", "C"},
+			UpdatedAt: lo.ToPtr(time.Date(2025, 1, 13, 8, 0, 0, 0, time.UTC)),
+		},
+		{ID: lo.ToPtr(int64(2))},
+	}
+
+	var buf bytes.Buffer
+	n, err := WriteNDJSON(&buf, slices.Values(rows))
+	if err != nil || n != 2 {
+		t.Fatalf("WriteNDJSON() = %d, %v", n, err)
+	}
+	want := `{"git_link":"https://github.com/a/b","id":1,"language":["Go","C"],"update_time":"2025-01-13T08:00:00Z"}
+{"id":2}
+`
+	if buf.String() != want {
+		t.Errorf("WriteNDJSON() wrote %q, want %q", buf.String(), want)
+	}
+}
+
+func TestGCSAndBigQuery(t *testing.T) {
+	var uploaded, loaded string
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/upload/b/bucket/o"):
+			body, _ := io.ReadAll(r.Body)
+			uploaded = r.URL.Query().Get("name") + ":" + string(body)
+			w.Write([]byte(`{}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/bq/projects/p/jobs":
+			var job bigQueryJob
+			json.NewDecoder(r.Body).Decode(&job)
+			loaded = job.Configuration.Load.SourceURIs[0] + " -> " +
+				job.Configuration.Load.DestinationTable.DatasetID + "." + job.Configuration.Load.DestinationTable.TableID
+			w.Write([]byte(`{"jobReference": {"projectId": "p", "jobId": "j1", "location": "US"}, "status": {"state": "RUNNING"}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/bq/projects/p/jobs/j1":
+			polls++
+			w.Write([]byte(`{"jobReference": {"projectId": "p", "jobId": "j1"}, "status": {"state": "DONE"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	oldGCS, oldBQ := GCSUploadURL, BigQueryURL
+	GCSUploadURL, BigQueryURL = server.URL+"/upload/b/%s/o", server.URL+"/bq"
+	defer func() { GCSUploadURL, BigQueryURL = oldGCS, oldBQ }()
+
+	table := Table{
+		Name:   "scores",
+		Schema: []Field{{Name: "id", Type: "INTEGER", Mode: "NULLABLE"}},
+		Write: func(w io.Writer) (int, error) {
+			w.Write([]byte("{\"id\":1}\n"))
+			return 1, nil
+		},
+	}
+	sink := &GCSSink{Bucket: "bucket", Token: "token"}
+	loader := &BigQueryLoader{Project: "p", Dataset: "d", Token: "token", PollInterval: time.Millisecond}
+
+	if err := Export([]Table{table}, sink, loader, "daily/"); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if uploaded != "daily/scores.json:{\"id\":1}\n" {
+		t.Errorf("uploaded %q", uploaded)
+	}
+	if loaded != "gs://bucket/daily/scores.json -> d.scores" || polls != 1 {
+		t.Errorf("loaded %q after %d polls", loaded, polls)
+	}
+
+	if err := loader.Load("scores", nil, "/tmp/scores.json"); err == nil {
+		t.Errorf("Load() should fail for local files")
+	}
+}
+
+var updateSchema = flag.Bool("update-schema", false, "update testdata/schema.json, the schema of the last release")
+
+// TestSchemaCompatible fails if the exported tables change without a new
+// SchemaVersion. After bumping it, update the released schema with
+//
+//	go test ./pkg/export -run TestSchemaCompatible -update-schema
+func TestSchemaCompatible(t *testing.T) {
+	tables, err := Tables(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current := Descriptor(tables)
+	path := filepath.Join("testdata", schema.File)
+	if *updateSchema {
+		if err := current.Write(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	released, err := schema.Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := schema.Check(released, current); err != nil {
+		t.Error(err)
+	}
+}
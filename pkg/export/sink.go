@@ -0,0 +1,79 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// Sink stores exported files.
+type Sink interface {
+	// Put stores the content as name, and returns the URI of the stored
+	// file
+	Put(name string, r io.Reader) (string, error)
+}
+
+// DirSink stores files in a local directory, which can be synced to any
+// object storage, e.g. with `aws s3 sync`.
+type DirSink struct {
+	Dir string
+}
+
+// Put implements Sink.
+func (d *DirSink) Put(name string, r io.Reader) (string, error) {
+	path := filepath.Join(d.Dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	if _, err := io.Copy(file, r); err != nil {
+		return "", err
+	}
+	return path, file.Close()
+}
+
+// GCSUploadURL is the endpoint of the Cloud Storage JSON API upload.
+var GCSUploadURL = "https://storage.googleapis.com/upload/storage/v1/b/%s/o"
+
+// GCSSink uploads files to a Google Cloud Storage bucket.
+type GCSSink struct {
+	Bucket string
+	// Token is an OAuth2 access token, e.g. from
+	// `gcloud auth print-access-token`
+	Token  string
+	Client *http.Client
+}
+
+// Put implements Sink.
+func (g *GCSSink) Put(name string, r io.Reader) (string, error) {
+	u := fmt.Sprintf(GCSUploadURL, url.PathEscape(g.Bucket)) +
+		"?uploadType=media&name=" + url.QueryEscape(name)
+	req, err := http.NewRequest(http.MethodPost, u, r)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+g.Token)
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	client := g.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("gcs returned %s: %s", resp.Status, body)
+	}
+	return fmt.Sprintf("gs://%s/%s", g.Bucket, name), nil
+}
@@ -0,0 +1,251 @@
+// Package failure tolerates failures of single items of a collector run, so
+// one unreachable package index or repository does not abort the run. A run
+// records the success or failure of every item, and exits with:
+//
+//   - ExitOK if every item succeeded
+//   - ExitPartial if some items failed, at most MaxRate of them
+//   - ExitFatal if more items failed, or the run could not go on at all
+//
+// A machine-readable summary of the run is written before exiting, so cron
+// jobs and pipelines can tell a flaky mirror from a broken run. Failures
+// are counted by their category, see package errors, so a pipeline can
+// rerun a run whose failures are all retryable.
+//
+// Collectors use the default tracker, which is initialized by
+// config.ParseFlags when config.RegistFailureFlags is called. A nil tracker
+// only logs fatal errors, like log.Fatal:
+//
+//	for _, link := range links {
+//		if err := collect(link); err != nil {
+//			failure.Default().Fail(link, err)
+//			continue
+//		}
+//		failure.Default().Success()
+//	}
+//	failure.Default().Exit()
+package failure
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/errors"
+)
+
+// Exit codes of a run.
+const (
+	ExitOK      = 0
+	ExitFatal   = 1
+	ExitPartial = 2
+)
+
+// Statuses of a run in the summary.
+const (
+	StatusSuccess = "success"
+	StatusPartial = "partial"
+	StatusFatal   = "fatal"
+)
+
+const (
+	// DefaultMaxRate is the default maximum failure rate of a partial run
+	DefaultMaxRate = 0.1
+	// MaxListed is the number of failed items listed by the summary
+	MaxListed = 1000
+)
+
+type Config struct {
+	// MaxRate is the maximum rate of failed items of a partial run, a run
+	// with more failures is fatal
+	MaxRate float64
+	// SummaryFile is the file of the summary, stderr if empty
+	SummaryFile string
+}
+
+// Failure is a failed item.
+type Failure struct {
+	Item  string `json:"item"`
+	Error string `json:"error"`
+	// Category is the category of the error, see errors.Of
+	Category  errors.Category `json:"category"`
+	Retryable bool            `json:"retryable"`
+}
+
+// Summary summarizes a run.
+type Summary struct {
+	Status         string  `json:"status"`
+	Items          int     `json:"items"`
+	Failed         int     `json:"failed"`
+	FailureRate    float64 `json:"failure_rate"`
+	MaxFailureRate float64 `json:"max_failure_rate"`
+	// Categories are the numbers of failed items of every error category,
+	// and Retryable the number of them which may succeed if retried
+	Categories map[errors.Category]int `json:"categories"`
+	Retryable  int                     `json:"retryable"`
+	// Fatal is the error which stopped the run, if any
+	Fatal string `json:"fatal,omitempty"`
+	// Failures are the first MaxListed failed items
+	Failures []Failure `json:"failures"`
+}
+
+// Tracker records the items of a run.
+type Tracker struct {
+	mu         sync.Mutex
+	config     Config
+	succeeded  int
+	failed     int
+	failures   []Failure
+	categories map[errors.Category]int
+	fatal      error
+
+	// exit is os.Exit, replaced by tests
+	exit func(code int)
+}
+
+// New returns a tracker of a run.
+func New(config *Config) *Tracker {
+	return &Tracker{config: *config, categories: make(map[errors.Category]int), exit: os.Exit}
+}
+
+// Success records a succeeded item.
+func (t *Tracker) Success() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.succeeded++
+}
+
+// Fail records a failed item, the caller logs the error.
+func (t *Tracker) Fail(item string, err error) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.failed++
+	c := errors.Of(err)
+	t.categories[c]++
+	if len(t.failures) < MaxListed {
+		t.failures = append(t.failures, Failure{Item: item, Error: err.Error(), Category: c, Retryable: c.Retryable()})
+	}
+}
+
+// Summary returns the summary of the run so far.
+func (t *Tracker) Summary() *Summary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	items := t.succeeded + t.failed
+	ret := &Summary{
+		Status:         StatusSuccess,
+		Items:          items,
+		Failed:         t.failed,
+		MaxFailureRate: t.config.MaxRate,
+		Failures:       append([]Failure{}, t.failures...),
+		Categories:     make(map[errors.Category]int, len(t.categories)),
+	}
+	for c, n := range t.categories {
+		ret.Categories[c] = n
+		if c.Retryable() {
+			ret.Retryable += n
+		}
+	}
+	if items > 0 {
+		ret.FailureRate = float64(t.failed) / float64(items)
+	}
+	switch {
+	case t.fatal != nil:
+		ret.Status = StatusFatal
+		ret.Fatal = t.fatal.Error()
+	case ret.FailureRate > t.config.MaxRate:
+		ret.Status = StatusFatal
+	case t.failed > 0:
+		ret.Status = StatusPartial
+	}
+	return ret
+}
+
+// ExitCode returns the exit code of a summary.
+func (s *Summary) ExitCode() int {
+	switch s.Status {
+	case StatusFatal:
+		return ExitFatal
+	case StatusPartial:
+		return ExitPartial
+	}
+	return ExitOK
+}
+
+// Write writes the summary as json to w.
+func (s *Summary) Write(w io.Writer) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// writeSummary writes the summary to the summary file or stderr.
+func (t *Tracker) writeSummary(s *Summary) {
+	if t.config.SummaryFile == "" {
+		if err := s.Write(os.Stderr); err != nil {
+			log.Printf("failed to write failure summary: %v", err)
+		}
+		return
+	}
+	f, err := os.Create(t.config.SummaryFile)
+	if err != nil {
+		log.Printf("failed to write failure summary: %v", err)
+		return
+	}
+	defer f.Close()
+	if err := s.Write(f); err != nil {
+		log.Printf("failed to write failure summary: %v", err)
+	}
+}
+
+// Exit writes the summary and exits with its exit code. It returns if the
+// run succeeded, so deferred calls still run.
+func (t *Tracker) Exit() {
+	if t == nil {
+		return
+	}
+	s := t.Summary()
+	t.writeSummary(s)
+	if code := s.ExitCode(); code != ExitOK {
+		log.Printf("%d of %d items failed, exiting with %d", s.Failed, s.Items, code)
+		t.exit(code)
+	}
+}
+
+// Fatal stops the run on an error which is not of a single item, e.g. the
+// database is unreachable, the summary is written and the run exits with
+// ExitFatal.
+func (t *Tracker) Fatal(err error) {
+	if t == nil {
+		log.Fatal(err)
+		return
+	}
+	log.Print(err)
+	t.mu.Lock()
+	t.fatal = err
+	t.mu.Unlock()
+	t.writeSummary(t.Summary())
+	t.exit(ExitFatal)
+}
+
+var defaultTracker *Tracker
+
+// InitDefault initializes the default tracker.
+func InitDefault(config *Config) {
+	defaultTracker = New(config)
+}
+
+// Default returns the default tracker, nil if it is not initialized.
+func Default() *Tracker {
+	return defaultTracker
+}
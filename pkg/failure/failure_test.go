@@ -0,0 +1,139 @@
+package failure
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/errors"
+)
+
+func TestSummary(t *testing.T) {
+	tests := []struct {
+		name      string
+		succeeded int
+		failed    int
+		fatal     error
+		want      string
+		code      int
+	}{
+		{"success", 10, 0, nil, StatusSuccess, ExitOK},
+		{"empty", 0, 0, nil, StatusSuccess, ExitOK},
+		{"partial", 9, 1, nil, StatusPartial, ExitPartial},
+		{"too many failures", 8, 2, nil, StatusFatal, ExitFatal},
+		{"fatal", 10, 0, errors.New("database is down"), StatusFatal, ExitFatal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := New(&Config{MaxRate: 0.1})
+			for i := 0; i < tt.succeeded; i++ {
+				tracker.Success()
+			}
+			for i := 0; i < tt.failed; i++ {
+				tracker.Fail("item", errors.New("timeout"))
+			}
+			tracker.fatal = tt.fatal
+
+			s := tracker.Summary()
+			if s.Status != tt.want || s.ExitCode() != tt.code {
+				t.Errorf("status = %s, exit code = %d, want %s, %d", s.Status, s.ExitCode(), tt.want, tt.code)
+			}
+			if s.Items != tt.succeeded+tt.failed || s.Failed != tt.failed || len(s.Failures) != tt.failed {
+				t.Errorf("summary = %+v", s)
+			}
+		})
+	}
+}
+
+func TestCategories(t *testing.T) {
+	tracker := New(&Config{MaxRate: 1})
+	tracker.Fail("a", errors.Mark(errors.New("503"), errors.Transient))
+	tracker.Fail("b", errors.Mark(errors.New("429"), errors.RateLimited))
+	tracker.Fail("c", errors.Mark(errors.New("404"), errors.NotFound))
+	tracker.Fail("d", errors.New("boom"))
+
+	s := tracker.Summary()
+	want := map[errors.Category]int{errors.Transient: 1, errors.RateLimited: 1, errors.NotFound: 1, errors.Unknown: 1}
+	if len(s.Categories) != len(want) || s.Retryable != 2 {
+		t.Errorf("categories = %v, retryable = %d", s.Categories, s.Retryable)
+	}
+	for c, n := range want {
+		if s.Categories[c] != n {
+			t.Errorf("categories[%s] = %d, want %d", c, s.Categories[c], n)
+		}
+	}
+	if f := s.Failures[2]; f.Category != errors.NotFound || f.Retryable {
+		t.Errorf("failure = %+v", f)
+	}
+}
+
+func TestMaxListed(t *testing.T) {
+	tracker := New(&Config{MaxRate: 1})
+	for i := 0; i < MaxListed+1; i++ {
+		tracker.Fail("item", errors.New("timeout"))
+	}
+	s := tracker.Summary()
+	if s.Failed != MaxListed+1 || len(s.Failures) != MaxListed {
+		t.Errorf("failed = %d, listed = %d", s.Failed, len(s.Failures))
+	}
+	if s.Status != StatusPartial {
+		t.Errorf("status = %s, want %s", s.Status, StatusPartial)
+	}
+}
+
+func TestExit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "summary.json")
+	tracker := New(&Config{MaxRate: 0.5, SummaryFile: path})
+	code := -1
+	tracker.exit = func(c int) { code = c }
+
+	tracker.Success()
+	tracker.Exit()
+	if code != -1 {
+		t.Errorf("successful run exited with %d", code)
+	}
+
+	tracker.Fail("https://github.com/foo/bar", errors.New("timeout"))
+	tracker.Exit()
+	if code != ExitPartial {
+		t.Errorf("exit code = %d, want %d", code, ExitPartial)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s Summary
+	if err := json.Unmarshal(data, &s); err != nil {
+		t.Fatal(err)
+	}
+	want := Failure{Item: "https://github.com/foo/bar", Error: "timeout", Category: errors.Unknown}
+	if s.Status != StatusPartial || s.FailureRate != 0.5 || len(s.Failures) != 1 || s.Failures[0] != want {
+		t.Errorf("summary = %+v", s)
+	}
+
+	tracker.Fatal(errors.New("database is down"))
+	if code != ExitFatal {
+		t.Errorf("exit code = %d, want %d", code, ExitFatal)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	var b bytes.Buffer
+	s := &Summary{Status: StatusSuccess, Failures: []Failure{}, Categories: map[errors.Category]int{}}
+	if err := s.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"status":"success","items":0,"failed":0,"failure_rate":0,"max_failure_rate":0,"categories":{},"retryable":0,"failures":[]}` + "\n"
+	if b.String() != want {
+		t.Errorf("Write() = %s, want %s", b.String(), want)
+	}
+}
+
+func TestNilTracker(t *testing.T) {
+	var tracker *Tracker
+	tracker.Success()
+	tracker.Fail("item", errors.New("timeout"))
+	tracker.Exit()
+}
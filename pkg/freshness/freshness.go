@@ -0,0 +1,136 @@
+// Package freshness discounts the signals of the score by their age and by
+// the confidence of their source, so a repo whose metrics were collected
+// years ago, or only probed from its HEAD, does not keep the score of a
+// fresh and full collection.
+//
+// Collectors record the provenance of every signal they collect, i.e. its
+// source, collection time and confidence, in signal_provenance.
+package freshness
+
+import (
+	"math"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/output"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+)
+
+// Sources of signals.
+const (
+	// SourceGitClone is a full clone of a git repo
+	SourceGitClone = "git-clone"
+	// SourceVCSLog is the log of a svn or hg repo, without its files
+	SourceVCSLog = "vcs-log"
+	// SourceProbe is the HEAD of a very large repo, the metrics which need
+	// the history are kept from a previous collection
+	SourceProbe = "probe"
+	// SourceDepsDev is the dependents of packages by deps.dev
+	SourceDepsDev = "deps.dev"
+	// SourceForgeAPI is the API of the forge of a repo, e.g. GitHub
+	SourceForgeAPI = "forge-api"
+	// SourceMailingList is the archives of mailing lists
+	SourceMailingList = "mailing-list"
+	// SourceBestPractices is bestpractices.dev
+	SourceBestPractices = "bestpractices.dev"
+	// SourceWikidata is the SPARQL endpoint of Wikidata
+	SourceWikidata = "wikidata"
+	// SourceStackExchange is the Stack Exchange API
+	SourceStackExchange = "stackexchange"
+)
+
+// confidences are the confidences of the sources which do not collect every
+// metric of their signal, the others have a confidence of 1.
+var confidences = map[string]float64{
+	// no license, language or ecosystem
+	SourceVCSLog: 0.9,
+	// contributors and commits are as old as the previous clone
+	SourceProbe: 0.6,
+}
+
+// Confidence returns the confidence of signals collected from source.
+func Confidence(source string) float64 {
+	if c, ok := confidences[source]; ok {
+		return c
+	}
+	return 1
+}
+
+const (
+	DefaultGrace    = 90 * 24 * time.Hour
+	DefaultHalfLife = 365 * 24 * time.Hour
+)
+
+// Policy discounts signals by their age.
+type Policy struct {
+	// Grace is the age until which signals are not discounted by age
+	Grace time.Duration
+	// HalfLife halves the discount of signals every HalfLife after Grace,
+	// signals are not discounted by age if 0
+	HalfLife time.Duration
+}
+
+// DefaultPolicy is the policy of DefaultGrace and DefaultHalfLife.
+var DefaultPolicy = Policy{Grace: DefaultGrace, HalfLife: DefaultHalfLife}
+
+// Decay returns the factor of a signal collected at, in (0, 1].
+func (p Policy) Decay(at, now time.Time) float64 {
+	age := now.Sub(at) - p.Grace
+	if p.HalfLife <= 0 || age <= 0 {
+		return 1
+	}
+	return math.Pow(0.5, float64(age)/float64(p.HalfLife))
+}
+
+// Discount returns the factor of a signal in [0, 1], its confidence times
+// its decay. Signals without provenance, e.g. collected before provenance
+// was recorded, are not discounted.
+func (p Policy) Discount(prov *repository.SignalProvenance, now time.Time) float64 {
+	if prov == nil || prov.CollectedAt == nil {
+		return 1
+	}
+	confidence := 1.0
+	if prov.Confidence != nil {
+		confidence = min(max(*prov.Confidence, 0), 1)
+	}
+	return confidence * p.Decay(*prov.CollectedAt, now)
+}
+
+// Record records the provenance of the signal of links collected from
+// source at, with the confidence of the source.
+func Record(repo repository.SignalProvenanceRepository, signal repository.Signal, links []string, source string, at time.Time) error {
+	return repo.Record(signal, links, source, Confidence(source), at)
+}
+
+// MarkCollected records the provenance of the signal of links collected
+// from source at, and marks them collected, in a single transaction.
+func MarkCollected(ac storage.AppDatabaseContext, signal repository.Signal, source string, links []string, at time.Time) error {
+	return storage.InBatch(ac, func(ac storage.AppDatabaseContext) error {
+		if err := Record(repository.NewSignalProvenanceRepository(ac), signal, links, source, at); err != nil {
+			return err
+		}
+		return repository.NewCollectionTimestampRepository(ac).MarkCollected(signal, links, at)
+	})
+}
+
+// MetricWriter returns the writer of an output.Queue of the rows of
+// git_metrics collected for signal from source, the rows are
+// *repository.GitMetric. The columns of the rows of a batch are updated by
+// one statement, and their links are marked collected by MarkCollected in
+// the same transaction.
+func MetricWriter(ac storage.AppDatabaseContext, signal repository.Signal, source string, columns ...string) output.BatchFunc {
+	return func(_ string, rows []any) error {
+		metrics := make([]*repository.GitMetric, len(rows))
+		links := make([]string, len(rows))
+		for i, row := range rows {
+			metrics[i] = row.(*repository.GitMetric)
+			links[i] = *metrics[i].GitLink
+		}
+		return storage.InBatch(ac, func(ac storage.AppDatabaseContext) error {
+			if err := repository.NewGitMetricsRepository(ac).BatchUpdateColumns(metrics, columns...); err != nil {
+				return err
+			}
+			return MarkCollected(ac, signal, source, links, time.Now())
+		})
+	}
+}
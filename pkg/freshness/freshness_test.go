@@ -0,0 +1,53 @@
+package freshness
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/samber/lo"
+)
+
+func TestDecay(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	p := Policy{Grace: 30 * 24 * time.Hour, HalfLife: 100 * 24 * time.Hour}
+	tests := []struct {
+		age  time.Duration
+		want float64
+	}{
+		{0, 1},
+		{30 * 24 * time.Hour, 1},
+		{130 * 24 * time.Hour, 0.5},
+		{230 * 24 * time.Hour, 0.25},
+		// collected in the future by a skewed clock
+		{-time.Hour, 1},
+	}
+	for _, tt := range tests {
+		if got := p.Decay(now.Add(-tt.age), now); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Decay(%s) = %v, want %v", tt.age, got, tt.want)
+		}
+	}
+	if got := (Policy{}).Decay(now.AddDate(-10, 0, 0), now); got != 1 {
+		t.Errorf("Decay() without a half-life = %v, want 1", got)
+	}
+}
+
+func TestDiscount(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	p := Policy{HalfLife: 100 * 24 * time.Hour}
+	probed := &repository.SignalProvenance{
+		Source:      lo.ToPtr(SourceProbe),
+		CollectedAt: lo.ToPtr(now.AddDate(0, 0, -100)),
+		Confidence:  lo.ToPtr(Confidence(SourceProbe)),
+	}
+	if got := p.Discount(probed, now); math.Abs(got-0.3) > 1e-9 {
+		t.Errorf("Discount() = %v, want 0.3", got)
+	}
+	if got := p.Discount(nil, now); got != 1 {
+		t.Errorf("Discount(nil) = %v, want 1", got)
+	}
+	if got := Confidence(SourceGitClone); got != 1 {
+		t.Errorf("Confidence(%s) = %v, want 1", SourceGitClone, got)
+	}
+}
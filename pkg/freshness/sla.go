@@ -0,0 +1,198 @@
+package freshness
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+)
+
+// CollectorLangEcosystem is the collector of the language ecosystems in
+// collector_runs, the distributions are recorded by their names.
+const CollectorLangEcosystem = "lang-ecosystem"
+
+const (
+	DefaultMaxAge       = 7 * 24 * time.Hour
+	DefaultMaxStaleRate = 0.05
+)
+
+// Kinds of checks.
+const (
+	// KindCollector checks the last successful run of a collector
+	KindCollector = "collector"
+	// KindSignal checks the signals of the projects
+	KindSignal = "signal"
+)
+
+// SLA is the freshness service level of the collected data: every
+// collector succeeded within its max age, and few projects have signals
+// older than the max age of the signal.
+type SLA struct {
+	// MaxAge is the max age of collectors and signals without their own
+	MaxAge time.Duration
+	// MaxAges are the max ages of single collectors or signals, e.g.
+	// debian or wikidata
+	MaxAges map[string]time.Duration
+	// MaxStaleRate is the max rate of projects with a stale signal, of the
+	// projects whose signal was ever collected
+	MaxStaleRate float64
+}
+
+// DefaultSLA is the SLA of DefaultMaxAge and DefaultMaxStaleRate.
+var DefaultSLA = SLA{MaxAge: DefaultMaxAge, MaxStaleRate: DefaultMaxStaleRate}
+
+// maxAge returns the max age of a collector or a signal.
+func (s *SLA) maxAge(name string) time.Duration {
+	if d, ok := s.MaxAges[name]; ok {
+		return d
+	}
+	return s.MaxAge
+}
+
+// Status is the result of a check.
+type Status struct {
+	Kind   string        `json:"kind"`
+	Name   string        `json:"name"`
+	MaxAge time.Duration `json:"maxAge"`
+	// LastSuccess is the last successful run of a collector, or the latest
+	// collection of a signal, nil if never
+	LastSuccess *time.Time `json:"lastSuccess"`
+	// LastError is the error of the last run of a collector, if it failed
+	LastError string `json:"lastError,omitempty"`
+	// Projects is the number of projects whose signal was ever collected,
+	// and Stale the number of them collected before the max age
+	Projects int `json:"projects,omitempty"`
+	Stale    int `json:"stale,omitempty"`
+	// Breached is true if the check fails, for the reason
+	Breached bool   `json:"breached"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// CheckCollector checks the last run of a collector, which is nil if it
+// never ran.
+func (s *SLA) CheckCollector(name string, run *repository.CollectorRun, now time.Time) Status {
+	ret := Status{Kind: KindCollector, Name: name, MaxAge: s.maxAge(name)}
+	if run != nil {
+		ret.LastSuccess = run.LastSuccessAt
+		if run.LastError != nil {
+			ret.LastError = *run.LastError
+		}
+	}
+	switch {
+	case ret.LastSuccess == nil:
+		ret.Breached, ret.Reason = true, "never collected successfully"
+	case now.Sub(*ret.LastSuccess) > ret.MaxAge:
+		ret.Breached = true
+		ret.Reason = fmt.Sprintf("last collected successfully %s ago", now.Sub(*ret.LastSuccess).Truncate(time.Hour))
+	}
+	return ret
+}
+
+// CheckSignal checks the freshness of a signal over all projects, queried
+// with the time of the max age of the signal. Signals which were never
+// collected are not breached, not every deployment collects every signal.
+func (s *SLA) CheckSignal(signal repository.Signal, f *repository.SignalFreshness) Status {
+	ret := Status{Kind: KindSignal, Name: string(signal), MaxAge: s.maxAge(string(signal))}
+	if f == nil || f.Collected == 0 {
+		return ret
+	}
+	ret.LastSuccess = f.Latest
+	ret.Projects, ret.Stale = f.Collected, f.Stale
+	if rate := float64(f.Stale) / float64(f.Collected); rate > s.MaxStaleRate {
+		ret.Breached = true
+		ret.Reason = fmt.Sprintf("%d of %d projects (%.1f%%) are stale", f.Stale, f.Collected, 100*rate)
+	}
+	return ret
+}
+
+// Check checks the collectors and all signals in the database at now.
+func (s *SLA) Check(ac storage.AppDatabaseContext, collectors []string, now time.Time) ([]Status, error) {
+	runs, err := repository.NewCollectorRunRepository(ac).Query()
+	if err != nil {
+		return nil, err
+	}
+	lastRuns := make(map[string]*repository.CollectorRun)
+	for run := range runs {
+		lastRuns[*run.Collector] = run
+	}
+	sorted := append([]string{}, collectors...)
+	sort.Strings(sorted)
+
+	ret := make([]Status, 0, len(sorted)+len(repository.Signals))
+	for _, name := range sorted {
+		ret = append(ret, s.CheckCollector(name, lastRuns[name], now))
+	}
+	repo := repository.NewCollectionTimestampRepository(ac)
+	for _, signal := range repository.Signals {
+		f, err := repo.QueryFreshness(signal, now.Add(-s.maxAge(string(signal))))
+		if err != nil {
+			return nil, fmt.Errorf("freshness of %s: %w", signal, err)
+		}
+		ret = append(ret, s.CheckSignal(signal, f))
+	}
+	return ret, nil
+}
+
+// Breached returns the breached statuses.
+func Breached(statuses []Status) []Status {
+	var ret []Status
+	for _, st := range statuses {
+		if st.Breached {
+			ret = append(ret, st)
+		}
+	}
+	return ret
+}
+
+// WritePrometheus writes the statuses in the text format of Prometheus,
+// see docs/tools/freshness_monitor.md for the alerting rules.
+func WritePrometheus(w io.Writer, statuses []Status) error {
+	metrics := []struct {
+		name, help string
+		value      func(st Status) (float64, bool)
+	}{
+		{"criticality_freshness_sla_breached", "1 if the collector or the signal breaches the freshness SLA.",
+			func(st Status) (float64, bool) {
+				if st.Breached {
+					return 1, true
+				}
+				return 0, true
+			}},
+		{"criticality_freshness_max_age_seconds", "Max age of the collector or the signal by the SLA.",
+			func(st Status) (float64, bool) { return st.MaxAge.Seconds(), true }},
+		{"criticality_freshness_last_success_timestamp_seconds", "Unix time of the last successful run of the collector, or of the latest collection of the signal.",
+			func(st Status) (float64, bool) {
+				if st.LastSuccess == nil {
+					return 0, true
+				}
+				return float64(st.LastSuccess.Unix()), true
+			}},
+		{"criticality_freshness_projects", "Projects whose signal was ever collected.",
+			func(st Status) (float64, bool) { return float64(st.Projects), st.Kind == KindSignal }},
+		{"criticality_freshness_stale_projects", "Projects whose signal is older than its max age.",
+			func(st Status) (float64, bool) { return float64(st.Stale), st.Kind == KindSignal }},
+	}
+	for _, m := range metrics {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", m.name, m.help, m.name); err != nil {
+			return err
+		}
+		for _, st := range statuses {
+			if v, ok := m.value(st); ok {
+				if _, err := fmt.Fprintf(w, "%s{kind=%q,name=%q} %g\n", m.name, st.Kind, st.Name, v); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// RecordRun records a run of a collector of a whole ecosystem, which
+// succeeded if err is nil. A failure is returned for the caller to log,
+// the run is not failed by it.
+func RecordRun(ac storage.AppDatabaseContext, collector string, err error) error {
+	return repository.NewCollectorRunRepository(ac).Record(collector, time.Now(), err)
+}
@@ -0,0 +1,87 @@
+package freshness
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+)
+
+func TestCheck(t *testing.T) {
+	now := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	ago := func(d time.Duration) *time.Time {
+		t := now.Add(-d)
+		return &t
+	}
+	failed := "connection refused"
+	sla := SLA{MaxAge: DefaultMaxAge, MaxAges: map[string]time.Duration{"nix": 30 * 24 * time.Hour}, MaxStaleRate: 0.1}
+
+	collectors := []struct {
+		name     string
+		run      *repository.CollectorRun
+		breached bool
+	}{
+		{"debian", &repository.CollectorRun{LastSuccessAt: ago(24 * time.Hour)}, false},
+		{"debian", &repository.CollectorRun{LastSuccessAt: ago(8 * 24 * time.Hour), LastError: &failed}, true},
+		{"nix", &repository.CollectorRun{LastSuccessAt: ago(8 * 24 * time.Hour)}, false},
+		{"gentoo", &repository.CollectorRun{LastError: &failed}, true},
+		{"alpine", nil, true},
+	}
+	for _, c := range collectors {
+		if st := sla.CheckCollector(c.name, c.run, now); st.Breached != c.breached {
+			t.Errorf("CheckCollector(%s, %+v) = %+v, want breached %v", c.name, c.run, st, c.breached)
+		}
+	}
+
+	signals := []struct {
+		f        *repository.SignalFreshness
+		breached bool
+	}{
+		{nil, false},
+		{&repository.SignalFreshness{}, false},
+		{&repository.SignalFreshness{Collected: 100, Stale: 10}, false},
+		{&repository.SignalFreshness{Collected: 100, Stale: 11}, true},
+	}
+	for _, s := range signals {
+		if st := sla.CheckSignal(repository.SignalGitMetadata, s.f); st.Breached != s.breached {
+			t.Errorf("CheckSignal(%+v) = %+v, want breached %v", s.f, st, s.breached)
+		}
+	}
+}
+
+type failWriter struct{}
+
+func (failWriter) Write([]byte) (int, error) { return 0, errors.New("closed") }
+
+func TestWritePrometheus(t *testing.T) {
+	last := time.Unix(1738368000, 0)
+	statuses := []Status{
+		{Kind: KindCollector, Name: "debian", MaxAge: DefaultMaxAge, LastSuccess: &last},
+		{Kind: KindSignal, Name: "git_metadata", MaxAge: DefaultMaxAge, Projects: 100, Stale: 20, Breached: true},
+	}
+	var b strings.Builder
+	if err := WritePrometheus(&b, statuses); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		"# TYPE criticality_freshness_sla_breached gauge\n",
+		`criticality_freshness_sla_breached{kind="collector",name="debian"} 0` + "\n",
+		`criticality_freshness_sla_breached{kind="signal",name="git_metadata"} 1` + "\n",
+		`criticality_freshness_max_age_seconds{kind="collector",name="debian"} 604800` + "\n",
+		`criticality_freshness_last_success_timestamp_seconds{kind="collector",name="debian"} 1.738368e+09` + "\n",
+		`criticality_freshness_stale_projects{kind="signal",name="git_metadata"} 20` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, `criticality_freshness_projects{kind="collector"`) {
+		t.Errorf("collectors have project counts:\n%s", out)
+	}
+	if err := WritePrometheus(failWriter{}, statuses); err == nil {
+		t.Error("write error is not returned")
+	}
+}
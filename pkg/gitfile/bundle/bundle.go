@@ -0,0 +1,271 @@
+// Package bundle stores cloned repositories as git bundles in object
+// storage, so collectors can restore a repo instead of cloning it again and
+// analysis workers do not need a persistent disk.
+package bundle
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser/url"
+	gitUtil "github.com/HUSTSecLab/criticality_score/pkg/gitfile/util"
+	"github.com/HUSTSecLab/criticality_score/pkg/objectstore"
+)
+
+// Extension is the file extension of bundles.
+const Extension = ".bundle"
+
+// Key returns the object key of the bundle of a repo, e.g.
+// github.com/owner/repo.bundle.
+func Key(u *url.RepoURL) string {
+	return strings.TrimPrefix(path.Join(u.Resource, strings.TrimSuffix(u.Pathname, ".git")), "/") + Extension
+}
+
+// Create writes all refs of the repo at repoPath into a bundle file.
+func Create(repoPath, dst string) error {
+	// dst is relative to the repo after -C
+	dst, err := filepath.Abs(dst)
+	if err != nil {
+		return err
+	}
+	out, err := exec.Command("git", "-C", repoPath, "bundle", "create", dst, "--all").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git bundle create: %w: %s", err, out)
+	}
+	return nil
+}
+
+// Restore clones a bundle into repoPath, and points the origin remote to
+// remoteURL so the repo can be updated from upstream.
+func Restore(bundlePath, repoPath, remoteURL string) error {
+	if err := os.MkdirAll(filepath.Dir(repoPath), 0755); err != nil {
+		return err
+	}
+	out, err := exec.Command("git", "clone", "--quiet", bundlePath, repoPath).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git clone bundle: %w: %s", err, out)
+	}
+	out, err = exec.Command("git", "-C", repoPath, "remote", "set-url", "origin", remoteURL).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git remote set-url: %w: %s", err, out)
+	}
+	return nil
+}
+
+// Cache keeps recently used bundles in a local directory in front of an
+// object store, the least recently used bundles are removed once the
+// directory grows over maxBytes.
+type Cache struct {
+	dir      string
+	maxBytes int64
+	store    objectstore.Store
+	mu       sync.Mutex
+}
+
+func NewCache(dir string, maxBytes int64, store objectstore.Store) *Cache {
+	return &Cache{dir: dir, maxBytes: maxBytes, store: store}
+}
+
+func (c *Cache) localPath(key string) string {
+	return filepath.Join(c.dir, filepath.FromSlash(key))
+}
+
+// touch marks a cached bundle as recently used.
+func touch(path string) {
+	now := time.Now()
+	os.Chtimes(path, now, now)
+}
+
+// Fetch returns the local path of a bundle, downloading it from the store
+// if it is not cached. It returns objectstore.ErrNotFound if the bundle
+// does not exist.
+func (c *Cache) Fetch(key string) (string, error) {
+	local := c.localPath(key)
+	if _, err := os.Stat(local); err == nil {
+		touch(local)
+		return local, nil
+	}
+
+	r, err := c.store.Get(key)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	if err := os.MkdirAll(filepath.Dir(local), 0755); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(local), ".download-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), local); err != nil {
+		return "", err
+	}
+
+	return local, c.Evict()
+}
+
+// Put uploads the file at src as key, and moves it into the cache.
+func (c *Cache) Put(key, src string) error {
+	file, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if err := c.store.Put(key, file, stat.Size()); err != nil {
+		return err
+	}
+	file.Close()
+
+	local := c.localPath(key)
+	if err := os.MkdirAll(filepath.Dir(local), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(src, local); err != nil {
+		return err
+	}
+	touch(local)
+	return c.Evict()
+}
+
+// Evict removes the least recently used bundles until the cache fits into
+// maxBytes, maxBytes <= 0 means unlimited.
+func (c *Cache) Evict() error {
+	if c.maxBytes <= 0 {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	type entry struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var entries []entry
+	var total int64
+	err := filepath.WalkDir(c.dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(p, Extension) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		entries = append(entries, entry{path: p, size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].modTime.Before(entries[j].modTime)
+	})
+	for _, e := range entries {
+		if total <= c.maxBytes {
+			break
+		}
+		if err := os.Remove(e.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		total -= e.size
+	}
+	return nil
+}
+
+// RestoreRepo restores the repo from its bundle if it is not cloned in
+// storagePath. It returns false if the repo exists or has no bundle.
+func (c *Cache) RestoreRepo(u *url.RepoURL, storagePath string) (bool, error) {
+	repoPath := gitUtil.GetGitRepositoryPath(storagePath, u)
+	if _, err := os.Stat(repoPath); err == nil {
+		return false, nil
+	}
+
+	local, err := c.Fetch(Key(u))
+	if errors.Is(err, objectstore.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := Restore(local, repoPath, u.URL); err != nil {
+		os.RemoveAll(repoPath)
+		return false, err
+	}
+	return true, nil
+}
+
+// UploadRepo bundles the repo cloned in storagePath and uploads the bundle.
+func (c *Cache) UploadRepo(u *url.RepoURL, storagePath string) error {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+	// the temp file has no bundle extension, so it is never evicted
+	tmp, err := os.CreateTemp(c.dir, ".create-*")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	// git refuses to write into an existing file
+	os.Remove(tmp.Name())
+	defer os.Remove(tmp.Name())
+
+	if err := Create(gitUtil.GetGitRepositoryPath(storagePath, u), tmp.Name()); err != nil {
+		return err
+	}
+	return c.Put(Key(u), tmp.Name())
+}
+
+// Config is the config of the default cache.
+type Config struct {
+	Store objectstore.Config
+	// CacheDir is the local directory of cached bundles
+	CacheDir string
+	// CacheSize is the max bytes of cached bundles, 0 means unlimited
+	CacheSize int64
+}
+
+var defaultCache *Cache
+
+// InitDefault initializes the default cache, bundles are disabled if no
+// bucket is configured.
+func InitDefault(config *Config) {
+	if config == nil || config.Store.Bucket == "" || config.Store.Endpoint == "" {
+		defaultCache = nil
+		return
+	}
+	defaultCache = NewCache(config.CacheDir, config.CacheSize, objectstore.NewS3Store(config.Store))
+}
+
+// Default returns the default cache, or nil if bundles are disabled.
+func Default() *Cache {
+	return defaultCache
+}
@@ -0,0 +1,127 @@
+package bundle
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser/url"
+	gitUtil "github.com/HUSTSecLab/criticality_score/pkg/gitfile/util"
+	"github.com/HUSTSecLab/criticality_score/pkg/objectstore"
+)
+
+type memoryStore map[string][]byte
+
+func (m memoryStore) Get(key string) (io.ReadCloser, error) {
+	data, ok := m[key]
+	if !ok {
+		return nil, objectstore.ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m memoryStore) Put(key string, r io.Reader, size int64) error {
+	data, err := io.ReadAll(r)
+	m[key] = data
+	return err
+}
+
+func (m memoryStore) URI(key string) string {
+	return "memory://" + key
+}
+
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v: %s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestUploadAndRestore(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	storagePath := t.TempDir()
+	u := url.ParseURL("https://github.com/owner/repo.git")
+	if got := Key(&u); got != "github.com/owner/repo.bundle" {
+		t.Fatalf("Key() = %q", got)
+	}
+
+	repoPath := gitUtil.GetGitRepositoryPath(storagePath, &u)
+	os.MkdirAll(repoPath, 0755)
+	git(t, repoPath, "init", "--quiet")
+	os.WriteFile(filepath.Join(repoPath, "README"), []byte("hello"), 0644)
+	git(t, repoPath, "add", "README")
+	git(t, repoPath, "commit", "--quiet", "-m", "init")
+	head := git(t, repoPath, "rev-parse", "HEAD")
+
+	store := memoryStore{}
+	cache := NewCache(t.TempDir(), 0, store)
+	if err := cache.UploadRepo(&u, storagePath); err != nil {
+		t.Fatalf("UploadRepo() error = %v", err)
+	}
+	if _, ok := store["github.com/owner/repo.bundle"]; !ok {
+		t.Fatalf("bundle is not uploaded: %v", store)
+	}
+
+	// a stateless worker with an empty cache and storage
+	worker := NewCache(t.TempDir(), 0, store)
+	workerStorage := t.TempDir()
+	ok, err := worker.RestoreRepo(&u, workerStorage)
+	if err != nil || !ok {
+		t.Fatalf("RestoreRepo() = %v, %v", ok, err)
+	}
+	restored := gitUtil.GetGitRepositoryPath(workerStorage, &u)
+	if got := git(t, restored, "rev-parse", "HEAD"); got != head {
+		t.Errorf("restored HEAD = %s, want %s", got, head)
+	}
+	if got := git(t, restored, "remote", "get-url", "origin"); got != u.URL {
+		t.Errorf("origin = %s, want %s", got, u.URL)
+	}
+
+	// restoring again is a no-op
+	if ok, err := worker.RestoreRepo(&u, workerStorage); ok || err != nil {
+		t.Errorf("RestoreRepo() of an existing repo = %v, %v", ok, err)
+	}
+	missing := url.ParseURL("https://github.com/owner/missing.git")
+	if ok, err := worker.RestoreRepo(&missing, workerStorage); ok || err != nil {
+		t.Errorf("RestoreRepo() without bundle = %v, %v", ok, err)
+	}
+}
+
+func TestEvict(t *testing.T) {
+	dir := t.TempDir()
+	cache := NewCache(dir, 10, memoryStore{})
+
+	base := time.Now().Add(-time.Hour)
+	for i, name := range []string{"a.bundle", "b.bundle", "c.bundle"} {
+		p := filepath.Join(dir, name)
+		os.WriteFile(p, []byte("12345"), 0644)
+		ts := base.Add(time.Duration(i) * time.Minute)
+		os.Chtimes(p, ts, ts)
+	}
+	// a is used recently
+	touch(filepath.Join(dir, "a.bundle"))
+
+	if err := cache.Evict(); err != nil {
+		t.Fatalf("Evict() error = %v", err)
+	}
+	for name, want := range map[string]bool{"a.bundle": true, "b.bundle": false, "c.bundle": true} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if (err == nil) != want {
+			t.Errorf("%s exists = %v, want %v", name, err == nil, want)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+// Package clonestore manages the clone storage of git collectors. It keeps
+// the storage within a disk budget, removing the clones analyzed least
+// recently first, and locks clones so concurrent workers do not clone or
+// fetch the same repo at the same time.
+package clonestore
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MarkerFile is touched in the .git directory of a clone whenever the clone
+// is analyzed, git ignores unknown files there.
+const MarkerFile = "cs-last-analyzed"
+
+// DefaultInterval is the min interval between two budget checks of
+// Enforce, walking a large storage is expensive.
+const DefaultInterval = 10 * time.Minute
+
+// Clone is a cloned repo in the storage.
+type Clone struct {
+	Path string
+	Size int64
+	// LastAnalyzed is the time the marker was touched, or the modification
+	// time of the .git directory if it was never touched
+	LastAnalyzed time.Time
+}
+
+// Touch marks the clone at repoPath as analyzed now.
+func Touch(repoPath string) error {
+	marker := filepath.Join(repoPath, ".git", MarkerFile)
+	now := time.Now()
+	if err := os.Chtimes(marker, now, now); err == nil {
+		return nil
+	}
+	f, err := os.Create(marker)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !d.IsDir() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// List returns the clones in storagePath, a clone is a directory with a
+// .git directory in it.
+func List(storagePath string) ([]Clone, error) {
+	clones := make([]Clone, 0)
+	err := filepath.WalkDir(storagePath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == storagePath {
+				return err
+			}
+			// removed by a concurrent collector
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		gitDir, err := os.Stat(filepath.Join(p, ".git"))
+		if err != nil || !gitDir.IsDir() {
+			return nil
+		}
+
+		c := Clone{Path: p, Size: dirSize(p), LastAnalyzed: gitDir.ModTime()}
+		if marker, err := os.Stat(filepath.Join(p, ".git", MarkerFile)); err == nil {
+			c.LastAnalyzed = marker.ModTime()
+		}
+		clones = append(clones, c)
+		return filepath.SkipDir
+	})
+	return clones, err
+}
+
+// removeEmptyParents removes the empty directories between dir and root,
+// e.g. the host and owner directories of a removed clone.
+func removeEmptyParents(root, dir string) {
+	root = filepath.Clean(root)
+	for dir = filepath.Clean(dir); dir != root && len(dir) > len(root); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			return
+		}
+	}
+}
+
+// GC removes the least recently analyzed clones until the storage fits
+// into maxBytes, clones in keep and locked clones are never removed. It
+// returns the removed clones, maxBytes <= 0 means unlimited.
+func GC(storagePath string, maxBytes int64, keep ...string) ([]Clone, error) {
+	removed := make([]Clone, 0)
+	if maxBytes <= 0 {
+		return removed, nil
+	}
+	clones, err := List(storagePath)
+	if err != nil {
+		return removed, err
+	}
+
+	kept := make(map[string]bool)
+	for _, k := range keep {
+		kept[filepath.Clean(k)] = true
+	}
+	var total int64
+	for _, c := range clones {
+		total += c.Size
+	}
+
+	sort.Slice(clones, func(i, j int) bool {
+		return clones[i].LastAnalyzed.Before(clones[j].LastAnalyzed)
+	})
+	var errs []error
+	for _, c := range clones {
+		if total <= maxBytes {
+			break
+		}
+		if kept[filepath.Clean(c.Path)] {
+			continue
+		}
+		// a locked clone is being cloned, fetched or analyzed, the lock is
+		// held while removing so no worker takes it in the meantime
+		lock, err := TryLock(c.Path)
+		if err != nil {
+			continue
+		}
+		err = os.RemoveAll(c.Path)
+		lock.Unlock()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		removeEmptyParents(storagePath, filepath.Dir(c.Path))
+		total -= c.Size
+		removed = append(removed, c)
+	}
+	return removed, errors.Join(errs...)
+}
+
+// Config is the disk budget of the clone storage.
+type Config struct {
+	// MaxBytes is the max bytes of all clones, 0 means unlimited
+	MaxBytes int64
+	// Interval is the min interval between two checks of Enforce, 0 means
+	// DefaultInterval
+	Interval time.Duration
+}
+
+// Quota enforces a disk budget on the clone storage while collecting.
+type Quota struct {
+	config  Config
+	mu      sync.Mutex
+	lastRun time.Time
+}
+
+func NewQuota(config Config) *Quota {
+	if config.Interval <= 0 {
+		config.Interval = DefaultInterval
+	}
+	return &Quota{config: config}
+}
+
+// Enforce runs GC on storagePath if it has not run within the interval,
+// the clone being collected is passed as keep.
+func (q *Quota) Enforce(storagePath string, keep ...string) ([]Clone, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if time.Since(q.lastRun) < q.config.Interval {
+		return nil, nil
+	}
+	q.lastRun = time.Now()
+	return GC(storagePath, q.config.MaxBytes, keep...)
+}
+
+var defaultQuota *Quota
+
+// InitDefault initializes the default quota, it is disabled if MaxBytes
+// is not positive.
+func InitDefault(config *Config) {
+	if config == nil || config.MaxBytes <= 0 {
+		defaultQuota = nil
+		return
+	}
+	defaultQuota = NewQuota(*config)
+}
+
+// Default returns the default quota, or nil if the storage is unlimited.
+func Default() *Quota {
+	return defaultQuota
+}
@@ -0,0 +1,83 @@
+package clonestore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func makeClone(t *testing.T, path string, size int, analyzed time.Time) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(path, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(path, "data"), make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Touch(path); err != nil {
+		t.Fatal(err)
+	}
+	marker := filepath.Join(path, ".git", MarkerFile)
+	if err := os.Chtimes(marker, analyzed, analyzed); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGC(t *testing.T) {
+	storage := t.TempDir()
+	now := time.Now()
+	oldest := filepath.Join(storage, "github.com", "a", "oldest.git")
+	old := filepath.Join(storage, "github.com", "b", "old.git")
+	recent := filepath.Join(storage, "gitlab.com", "c", "recent.git")
+	makeClone(t, oldest, 1000, now.Add(-3*time.Hour))
+	makeClone(t, old, 1000, now.Add(-2*time.Hour))
+	makeClone(t, recent, 1000, now.Add(-time.Hour))
+
+	clones, err := List(storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clones) != 3 {
+		t.Fatalf("List() = %d clones, want 3", len(clones))
+	}
+
+	// keep the oldest clone, so the old one is removed instead
+	removed, err := GC(storage, 2500, oldest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || removed[0].Path != old {
+		t.Fatalf("GC() removed %v, want %s", removed, old)
+	}
+	if _, err := os.Stat(filepath.Join(storage, "github.com", "b")); !os.IsNotExist(err) {
+		t.Errorf("empty owner directory is not removed: %v", err)
+	}
+
+	removed, err = GC(storage, 1500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || removed[0].Path != oldest {
+		t.Fatalf("GC() removed %v, want %s", removed, oldest)
+	}
+	if _, err := os.Stat(recent); err != nil {
+		t.Errorf("recent clone is removed: %v", err)
+	}
+}
+
+func TestQuotaInterval(t *testing.T) {
+	storage := t.TempDir()
+	makeClone(t, filepath.Join(storage, "a.git"), 1000, time.Now())
+
+	q := NewQuota(Config{MaxBytes: 1, Interval: time.Hour})
+	q.lastRun = time.Now()
+	if removed, _ := q.Enforce(storage); len(removed) != 0 {
+		t.Errorf("Enforce() ran within the interval")
+	}
+
+	q.lastRun = time.Time{}
+	if removed, _ := q.Enforce(storage); len(removed) != 1 {
+		t.Errorf("Enforce() removed %d clones, want 1", len(removed))
+	}
+}
@@ -0,0 +1,149 @@
+package clonestore
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/errors"
+)
+
+// ErrLocked is returned by TryLock if another worker holds the lock.
+var ErrLocked = errors.Mark(errors.New("clone is locked by another worker"), errors.Transient)
+
+// LockSuffix is appended to the path of a clone to get its lock file. The
+// lock file is a sibling of the clone, so it can be taken before the clone
+// exists and is not removed with it.
+const LockSuffix = ".cs-lock"
+
+var (
+	// StaleAfter is the age after which a lock not refreshed by its holder
+	// is taken over, e.g. if the worker was killed
+	StaleAfter = 10 * time.Minute
+	// PollInterval is the interval of Lock to retry a held lock
+	PollInterval = time.Second
+)
+
+// RepoLock is a per-repo lock shared by the workers using the same
+// storage, possibly on different hosts. It is a lock file created
+// exclusively, whose modification time is refreshed while it is held, so
+// it works on every platform and on network filesystems.
+type RepoLock struct {
+	path string
+	stop chan struct{}
+	done chan struct{}
+}
+
+func lockPath(repoPath string) string {
+	return filepath.Clean(repoPath) + LockSuffix
+}
+
+func isStale(info fs.FileInfo) bool {
+	return time.Since(info.ModTime()) > StaleAfter
+}
+
+// Locked reports whether the clone at repoPath is locked by a worker.
+func Locked(repoPath string) bool {
+	info, err := os.Stat(lockPath(repoPath))
+	return err == nil && !isStale(info)
+}
+
+// TryLock takes the lock of the clone at repoPath, it returns ErrLocked
+// without waiting if the lock is held.
+func TryLock(repoPath string) (*RepoLock, error) {
+	path := lockPath(repoPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if errors.Is(err, fs.ErrExist) {
+		if !takeOver(path) {
+			return nil, ErrLocked
+		}
+		f, err = os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if errors.Is(err, fs.ErrExist) {
+			return nil, ErrLocked
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	hostname, _ := os.Hostname()
+	fmt.Fprintf(f, "%s %d %s\n", hostname, os.Getpid(), time.Now().Format(time.RFC3339))
+	f.Close()
+
+	l := &RepoLock{path: path, stop: make(chan struct{}), done: make(chan struct{})}
+	go l.refresh()
+	return l, nil
+}
+
+// takeOver moves the lock file at path away if it is stale, so it can be
+// created again. The file is renamed before it is checked again, as it may
+// be taken over and created by another worker after it was found stale;
+// removing it by path would then remove the lock of that worker.
+func takeOver(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || !isStale(info) {
+		return false
+	}
+	hostname, _ := os.Hostname()
+	stalePath := fmt.Sprintf("%s.stale-%s-%d-%d", path, hostname, os.Getpid(), time.Now().UnixNano())
+	if err := os.Rename(path, stalePath); err != nil {
+		return false
+	}
+	defer os.Remove(stalePath)
+	moved, err := os.Stat(stalePath)
+	if err == nil && os.SameFile(info, moved) && isStale(moved) {
+		return true
+	}
+	// another worker holds the lock we moved, put it back unless the lock
+	// was already created again
+	os.Link(stalePath, path)
+	return false
+}
+
+// Lock takes the lock of the clone at repoPath, waiting until the lock is
+// released, becomes stale or ctx is done.
+func Lock(ctx context.Context, repoPath string) (*RepoLock, error) {
+	for {
+		l, err := TryLock(repoPath)
+		if !errors.Is(err, ErrLocked) {
+			return l, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for lock of %s: %w", repoPath, ctx.Err())
+		case <-time.After(PollInterval):
+		}
+	}
+}
+
+// refresh keeps the lock from becoming stale while it is held.
+func (l *RepoLock) refresh() {
+	defer close(l.done)
+	ticker := time.NewTicker(StaleAfter / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			now := time.Now()
+			os.Chtimes(l.path, now, now)
+		}
+	}
+}
+
+// Unlock releases the lock.
+func (l *RepoLock) Unlock() error {
+	close(l.stop)
+	<-l.done
+	if err := os.Remove(l.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package clonestore
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTryLock(t *testing.T) {
+	repo := filepath.Join(t.TempDir(), "github.com", "owner", "repo.git")
+
+	l, err := TryLock(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Locked(repo) {
+		t.Error("Locked() = false while the lock is held")
+	}
+	if _, err := TryLock(repo); !errors.Is(err, ErrLocked) {
+		t.Errorf("second TryLock() error = %v, want ErrLocked", err)
+	}
+	if err := l.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if Locked(repo) {
+		t.Error("Locked() = true after Unlock()")
+	}
+}
+
+func TestStaleLock(t *testing.T) {
+	repo := filepath.Join(t.TempDir(), "repo.git")
+	// a lock left by a killed worker
+	if err := os.WriteFile(repo+LockSuffix, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * StaleAfter)
+	if err := os.Chtimes(repo+LockSuffix, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	oldPoll := PollInterval
+	PollInterval = 10 * time.Millisecond
+	defer func() { PollInterval = oldPoll }()
+
+	l, err := Lock(ctx, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Unlock()
+}
+
+func TestLockExclusive(t *testing.T) {
+	repo := filepath.Join(t.TempDir(), "repo.git")
+	oldPoll := PollInterval
+	PollInterval = time.Millisecond
+	defer func() { PollInterval = oldPoll }()
+
+	var mu sync.Mutex
+	holders, maxHolders := 0, 0
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l, err := Lock(context.Background(), repo)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			holders++
+			maxHolders = max(maxHolders, holders)
+			mu.Unlock()
+
+			time.Sleep(5 * time.Millisecond)
+
+			mu.Lock()
+			holders--
+			mu.Unlock()
+			l.Unlock()
+		}()
+	}
+	wg.Wait()
+	if maxHolders != 1 {
+		t.Errorf("%d workers held the lock at the same time", maxHolders)
+	}
+}
+
+func TestStaleLockTakeOver(t *testing.T) {
+	repo := filepath.Join(t.TempDir(), "repo.git")
+	if err := os.WriteFile(repo+LockSuffix, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * StaleAfter)
+	if err := os.Chtimes(repo+LockSuffix, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	// workers finding the same stale lock must not take it over from each
+	// other
+	var mu sync.Mutex
+	var locks []*RepoLock
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l, err := TryLock(repo)
+			if errors.Is(err, ErrLocked) {
+				return
+			}
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			locks = append(locks, l)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if len(locks) != 1 {
+		t.Errorf("%d workers took over the stale lock, want 1", len(locks))
+	}
+	for _, l := range locks {
+		l.Unlock()
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(repo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("files left after Unlock(): %v", entries)
+	}
+}
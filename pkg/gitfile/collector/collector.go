@@ -8,18 +8,39 @@
 package collector
 
 import (
-	"fmt"
+	"context"
 
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/bundle"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/clonestore"
 	parser "github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser"
 	url "github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser/url"
+	gitUtil "github.com/HUSTSecLab/criticality_score/pkg/gitfile/util"
 	"github.com/HUSTSecLab/criticality_score/pkg/logger"
 
 	gogit "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/storage/memory"
 )
 
-// clone or update the repository, and collect metadata
-func Collect(u *url.RepoURL, storagePath string) (*gogit.Repository, error) {
+// clone or update the repository, and collect metadata. The clone is
+// returned locked, so it is not removed by the storage GC while it is
+// analyzed, the caller unlocks it when done.
+func Collect(u *url.RepoURL, storagePath string) (*gogit.Repository, *clonestore.RepoLock, error) {
+	// workers sharing the storage must not clone or fetch the same repo at
+	// the same time, the one coming later updates the fresh clone instead
+	lock, err := clonestore.Lock(context.Background(), gitUtil.GetGitRepositoryPath(storagePath, u))
+	if err != nil {
+		logger.Errorf("Failed to lock %s, %v", u.URL, err)
+		return nil, nil, err
+	}
+
+	cache := bundle.Default()
+	if cache != nil {
+		// restore the repo from its bundle, so it is updated instead of cloned
+		if _, err := cache.RestoreRepo(u, storagePath); err != nil {
+			logger.Warnf("Failed to restore bundle of %s, %v", u.URL, err)
+		}
+	}
+
 	r, err := Clone(u, storagePath)
 
 	if err == gogit.ErrRepositoryAlreadyExists {
@@ -31,7 +52,44 @@ func Collect(u *url.RepoURL, storagePath string) (*gogit.Repository, error) {
 		logger.Errorf("Failed to Clone %s, %v", u.URL, err)
 	}
 
-	return r, err
+	if err == nil && cache != nil {
+		if err := cache.UploadRepo(u, storagePath); err != nil {
+			logger.Warnf("Failed to upload bundle of %s, %v", u.URL, err)
+		}
+	}
+
+	if err != nil {
+		Unlock(u, lock)
+		return nil, nil, err
+	}
+	enforceQuota(gitUtil.GetGitRepositoryPath(storagePath, u), storagePath)
+	return r, lock, nil
+}
+
+// Unlock releases the lock of a clone returned by Collect.
+func Unlock(u *url.RepoURL, lock *clonestore.RepoLock) {
+	if err := lock.Unlock(); err != nil {
+		logger.Warnf("Failed to unlock %s, %v", u.URL, err)
+	}
+}
+
+// enforceQuota marks the repo as analyzed, and removes the least recently
+// analyzed clones if the storage is over its budget
+func enforceQuota(repoPath, storagePath string) {
+	if err := clonestore.Touch(repoPath); err != nil {
+		logger.Warnf("Failed to mark %s as analyzed, %v", repoPath, err)
+	}
+	quota := clonestore.Default()
+	if quota == nil {
+		return
+	}
+	removed, err := quota.Enforce(storagePath, repoPath)
+	if err != nil {
+		logger.Warnf("Failed to enforce storage quota, %v", err)
+	}
+	if len(removed) > 0 {
+		logger.Infof("Removed %d least recently analyzed clones", len(removed))
+	}
 }
 
 // mem clone the repository, and collect metadata
@@ -47,13 +105,16 @@ func EzCollect(u *url.RepoURL) (*gogit.Repository, error) {
 
 // only clone the repository, if it exists, return error
 func Clone(u *url.RepoURL, storagePath string) (*gogit.Repository, error) {
-	path := fmt.Sprintf("%s/%s%s", storagePath, u.Resource, u.Pathname)
+	path := gitUtil.GetGitRepositoryPath(storagePath, u)
 
 	r, err := gogit.PlainClone(path, false, &gogit.CloneOptions{
 		URL: u.URL,
 		// Progress:     os.Stdout,
 		SingleBranch: false,
 	})
+	if err != nil && err != gogit.ErrRepositoryAlreadyExists && !url.IsForge(u) {
+		return cloneCLI(u, path, err)
+	}
 
 	return r, err
 }
@@ -143,7 +204,8 @@ func Fetch(r *gogit.Repository, path string) error {
 */
 
 func Update(u *url.RepoURL, storagePath string) (*gogit.Repository, error) {
-	path := fmt.Sprintf("%s/%s%s", storagePath, u.Resource, u.Pathname)
+	path := gitUtil.GetGitRepositoryPath(storagePath, u)
+	forge := url.IsForge(u)
 	url := u.URL
 	r, err := Open(path)
 
@@ -157,6 +219,8 @@ func Update(u *url.RepoURL, storagePath string) (*gogit.Repository, error) {
 	// err := Fetch(r)
 	if err == gogit.NoErrAlreadyUpToDate {
 		err = nil
+	} else if err != nil && !forge {
+		return updateCLI(u, path, err)
 	} else {
 		logger.Errorf("Failed to pull %s, %v", path, err)
 	}
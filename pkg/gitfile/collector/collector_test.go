@@ -26,8 +26,11 @@ func TestCollect(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.input, func(t *testing.T) {
 			u := url.ParseURL(test.input)
-			_, err := Collect(&u, t.TempDir())
+			_, lock, err := Collect(&u, t.TempDir())
 			require.Equal(t, test.expected, err)
+			if lock != nil {
+				lock.Unlock()
+			}
 		})
 	}
 }
@@ -0,0 +1,57 @@
+package collector
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	url "github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser/url"
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+
+	gogit "github.com/go-git/go-git/v5"
+)
+
+// Forge-less hosts, e.g. cgit or gitweb instances, sometimes only serve the
+// dumb http protocol, which go-git does not speak. Their repos are cloned and
+// updated by the git cli instead when go-git fails.
+
+func runGit(dir string, args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	// never ask for credentials of private or removed repos
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// cloneCLI clones u into path by the git cli, the partial clone left by
+// go-git is removed first.
+func cloneCLI(u *url.RepoURL, path string, cause error) (*gogit.Repository, error) {
+	logger.Warnf("Failed to Clone %s by go-git, retrying by git, %v", u.URL, cause)
+	if err := os.RemoveAll(path); err != nil {
+		return nil, err
+	}
+	if err := runGit("", "clone", "--no-tags", u.URL, path); err != nil {
+		return nil, err
+	}
+	return Open(path)
+}
+
+// updateCLI fetches the repo at path by the git cli and resets the worktree
+// to the fetched branch, as Pull does with Force.
+func updateCLI(u *url.RepoURL, path string, cause error) (*gogit.Repository, error) {
+	logger.Warnf("Failed to Update %s by go-git, retrying by git, %v", u.URL, cause)
+	if err := runGit(path, "fetch", "--force", "origin"); err != nil {
+		return nil, err
+	}
+	if err := runGit(path, "reset", "--hard", "FETCH_HEAD"); err != nil {
+		return nil, err
+	}
+	return Open(path)
+}
@@ -0,0 +1,70 @@
+package collector
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	url "github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser/url"
+)
+
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=a", "GIT_AUTHOR_EMAIL=a@example.com",
+		"GIT_COMMITTER_NAME=a", "GIT_COMMITTER_EMAIL=a@example.com")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v: %s", args, err, out)
+	}
+}
+
+// a forge-less host only serving the dumb http protocol is cloned and
+// updated by the git cli
+func TestCollectDumbHTTP(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	root := t.TempDir()
+	work := filepath.Join(root, "work")
+	bare := filepath.Join(root, "srv", "project.git")
+	git(t, root, "init", "-q", "-b", "main", work)
+	git(t, work, "commit", "-q", "--allow-empty", "-m", "first")
+	git(t, root, "clone", "-q", "--bare", work, bare)
+	git(t, bare, "update-server-info")
+
+	server := httptest.NewServer(http.FileServer(http.Dir(filepath.Join(root, "srv"))))
+	defer server.Close()
+
+	u := url.ParseURL(server.URL + "/project.git")
+	storage := t.TempDir()
+	r, lock, err := Collect(&u, storage)
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	head, err := r.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock.Unlock()
+
+	git(t, work, "commit", "-q", "--allow-empty", "-m", "second")
+	git(t, work, "push", "-q", bare, "main")
+	git(t, bare, "update-server-info")
+	r, lock, err = Collect(&u, storage)
+	if err != nil {
+		t.Fatalf("Collect() update error = %v", err)
+	}
+	defer lock.Unlock()
+	updated, err := r.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Hash() == head.Hash() {
+		t.Errorf("Collect() did not update HEAD %s", head.Hash())
+	}
+}
@@ -0,0 +1,154 @@
+// Package history derives the metrics of a repo from its commit log, so
+// the raw log can be stored once and the metrics recomputed when their
+// formula changes, e.g. the window of the commit frequency, without cloning
+// the repo again.
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/analysis/bots"
+	"github.com/HUSTSecLab/criticality_score/pkg/analysis/timezone"
+)
+
+// DefaultWindow is the window of the commit frequency and the timezones.
+const DefaultWindow = 365 * 24 * time.Hour
+
+// ErrNoCommits is returned for logs without commits.
+var ErrNoCommits = errors.New("repo has no commits")
+
+// Commit is a commit of a log, Email is empty if the vcs only records a
+// user name, as Subversion does.
+type Commit struct {
+	Author string `json:"a"`
+	Email  string `json:"e,omitempty"`
+	// When is the author time, with the offset of the author
+	When time.Time `json:"t"`
+	// Committed is the commit time, zero if the vcs has no separate commit
+	// time, in which case When is used
+	Committed time.Time `json:"c"`
+}
+
+// committed returns the time the commit landed.
+func (c *Commit) committed() time.Time {
+	if c.Committed.IsZero() {
+		return c.When
+	}
+	return c.Committed
+}
+
+// Metrics are the metrics derived from a log.
+type Metrics struct {
+	CreatedSince          time.Time
+	UpdatedSince          time.Time
+	ContributorCount      int
+	OrgCount              int
+	CommitFrequency       float64
+	HumanContributorCount int
+	HumanCommitFrequency  float64
+	TimezoneDiversity     float64
+}
+
+// Summarize derives the metrics of the commits at now: contributors and
+// orgs count over the whole log, the commit frequency, i.e. commits per
+// week with 52 weeks a year, and the timezones over the window before now.
+// Bots are left out of the human counts and the timezones.
+func Summarize(commits []Commit, now time.Time, window time.Duration) (Metrics, error) {
+	var m Metrics
+	if len(commits) == 0 {
+		return m, ErrNoCommits
+	}
+	contributors := make(map[string]int)
+	humans := make(map[string]int)
+	orgs := make(map[string]int)
+	var commitCount, humanCommitCount float64
+	timezones := timezone.NewTracker()
+	since := now.Add(-window)
+
+	m.CreatedSince = commits[0].committed()
+	m.UpdatedSince = commits[0].committed()
+	for _, c := range commits {
+		at := c.committed()
+		if at.Before(m.CreatedSince) {
+			m.CreatedSince = at
+		}
+		if at.After(m.UpdatedSince) {
+			m.UpdatedSince = at
+		}
+
+		author := fmt.Sprintf("%s(%s)", c.Author, c.Email)
+		contributors[author]++
+		if c.Email != "" {
+			e := strings.Split(c.Email, "@")
+			orgs[e[len(e)-1]]++
+		}
+		bot := bots.IsBotAuthor(c.Author, c.Email)
+		if !bot {
+			humans[author]++
+		}
+
+		if at.After(since) && !at.After(now) {
+			commitCount++
+			if !bot {
+				humanCommitCount++
+			}
+		}
+		if c.When.After(since) && !c.When.After(now) && !bot {
+			timezones.Add(author, c.When)
+		}
+	}
+
+	weeks := window.Hours() / (365 * 24) * 52
+	m.ContributorCount = len(contributors)
+	m.OrgCount = len(orgs)
+	m.CommitFrequency = commitCount / weeks
+	m.HumanContributorCount = len(humans)
+	m.HumanCommitFrequency = humanCommitCount / weeks
+	m.TimezoneDiversity = timezones.Diversity()
+	return m, nil
+}
+
+// Encode returns the commits as gzip compressed json lines.
+func Encode(commits []Commit) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(w)
+	for i := range commits {
+		if err := enc.Encode(&commits[i]); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode returns the commits encoded by Encode.
+func Decode(data []byte) ([]Commit, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var commits []Commit
+	dec := json.NewDecoder(bufio.NewReader(r))
+	for {
+		var c Commit
+		if err := dec.Decode(&c); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		commits = append(commits, c)
+	}
+	return commits, nil
+}
@@ -0,0 +1,67 @@
+package history
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+
+var commits = []Commit{
+	{Author: "alice", Email: "alice@a.org", When: now.AddDate(0, -1, 0)},
+	{Author: "alice", Email: "alice@a.org", When: now.AddDate(0, -2, 0), Committed: now.AddDate(0, -1, 1)},
+	{Author: "dependabot[bot]", Email: "bot@b.org", When: now.AddDate(0, -3, 0)},
+	{Author: "bob", When: now.AddDate(-5, 0, 0)},
+}
+
+func TestSummarize(t *testing.T) {
+	m, err := Summarize(commits, now, DefaultWindow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.ContributorCount != 3 || m.HumanContributorCount != 2 || m.OrgCount != 2 {
+		t.Errorf("Summarize() contributors = %d, humans = %d, orgs = %d", m.ContributorCount, m.HumanContributorCount, m.OrgCount)
+	}
+	if got, want := fmt.Sprintf("%.4f %.4f", m.CommitFrequency, m.HumanCommitFrequency), fmt.Sprintf("%.4f %.4f", 3.0/52, 2.0/52); got != want {
+		t.Errorf("Summarize() frequencies = %s, want %s", got, want)
+	}
+	// the time a commit landed counts, not the time it was authored
+	if !m.CreatedSince.Equal(commits[3].When) || !m.UpdatedSince.Equal(commits[1].Committed) {
+		t.Errorf("Summarize() created %v updated %v", m.CreatedSince, m.UpdatedSince)
+	}
+	if _, err := Summarize(nil, now, DefaultWindow); err != ErrNoCommits {
+		t.Errorf("Summarize() of no commits error = %v", err)
+	}
+}
+
+func TestSummarizeWindow(t *testing.T) {
+	// a window of 10 weeks holds the two commits of alice
+	m, err := Summarize(commits, now, 70*24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprintf("%.4f", m.CommitFrequency), fmt.Sprintf("%.4f", 2/(70.0/365*52)); got != want {
+		t.Errorf("Summarize() frequency = %s, want %s", got, want)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	data, err := Encode(commits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(commits) {
+		t.Fatalf("Decode() = %d commits, want %d", len(got), len(commits))
+	}
+	for i := range got {
+		if got[i].Author != commits[i].Author || got[i].Email != commits[i].Email ||
+			!got[i].When.Equal(commits[i].When) || !got[i].Committed.Equal(commits[i].Committed) {
+			t.Errorf("Decode()[%d] = %+v, want %+v", i, got[i], commits[i])
+		}
+	}
+}
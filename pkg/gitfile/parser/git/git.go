@@ -15,9 +15,15 @@ import (
 	"strings"
 	"time"
 
+	"github.com/HUSTSecLab/criticality_score/pkg/analysis/bots"
+	"github.com/HUSTSecLab/criticality_score/pkg/analysis/disclosure"
+	"github.com/HUSTSecLab/criticality_score/pkg/analysis/testcode"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/history"
 	parser "github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser"
 	url "github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser/url"
 	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/maintainer"
+	"github.com/HUSTSecLab/criticality_score/pkg/manifest"
 	"github.com/go-git/go-git/v5"
 	gitconfig "github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
@@ -46,8 +52,34 @@ type Repo struct {
 	ContributorCount int
 	OrgCount         int
 	CommitFrequency  float64
+	// HumanContributorCount and HumanCommitFrequency leave out the commits
+	// of bots, see package analysis/bots
+	HumanContributorCount int
+	HumanCommitFrequency  float64
+	// Runtimes are the runtime constraints declared by the manifests
+	Runtimes []manifest.Runtime
+	// TimezoneDiversity is the effective number of timezones of the
+	// contributors of the last year, see package analysis/timezone
+	TimezoneDiversity float64
+	// Commits is the log the metrics above are derived from
+	Commits []history.Commit
+	// Contacts are the owners in CODEOWNERS and the emails of the security
+	// policy, see package maintainer
+	Contacts []maintainer.Contact
+	// Disclosure is the vulnerability disclosure policy of the repo
+	Disclosure disclosure.Policy
+	// UpdateTools are the configured dependency update tools separated by
+	// spaces, e.g. "dependabot renovate", see bots.UpdateTool
+	UpdateTools string
+	// Tests are the lines of the tests and of the other source files, see
+	// package analysis/testcode
+	Tests testcode.Counts
 }
 
+// maxConfigFileSize is the max size of a CODEOWNERS, security policy or
+// package.json which is parsed, larger ones are not written by hand.
+const maxConfigFileSize = 1 << 20
+
 func NewRepo() Repo {
 	return Repo{
 		Name:             parser.UNKNOWN_NAME,
@@ -62,6 +94,9 @@ func NewRepo() Repo {
 		ContributorCount: parser.UNKNOWN_COUNT,
 		OrgCount:         parser.UNKNOWN_COUNT,
 		CommitFrequency:  parser.UNKNOWN_FREQUENCY,
+
+		HumanContributorCount: parser.UNKNOWN_COUNT,
+		HumanCommitFrequency:  parser.UNKNOWN_FREQUENCY,
 	}
 }
 
@@ -306,8 +341,14 @@ func GetLanguages(filename string, filesize int64, l *map[string]int64) {
 	}
 }
 
-func GetEcosystem(filename string, filesize int64, e *map[string]int64) {
-	v, ok := parser.ECOSYSTEM_MAP[filename]
+// GetEcosystem counts the manifest at path, which is relative to the repo
+// root. Manifests in vendored directories or deeper than
+// manifest.DefaultMaxDepth are ignored.
+func GetEcosystem(path string, filesize int64, e *map[string]int64) {
+	if manifest.Skipped(path, manifest.DefaultMaxDepth) {
+		return
+	}
+	v, ok := manifest.EcosystemOf(filepath.Base(path))
 	if ok {
 		(*e)[v] += filesize
 	}
@@ -318,14 +359,16 @@ func GetLicense(f *object.File) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	cov := licensecheck.Scan([]byte(text))
+	return ScanLicense([]byte(text)), nil
+}
+
+// ScanLicense returns the id of the license in text, or UNKNOWN_LICENSE.
+func ScanLicense(text []byte) string {
+	cov := licensecheck.Scan(text)
 	if len(cov.Match) == 0 {
-		return parser.UNKNOWN_LICENSE, nil
+		return parser.UNKNOWN_LICENSE
 	}
-
-	license := cov.Match[0].ID
-
-	return license, nil
+	return cov.Match[0].ID
 }
 
 func getTopNKeys(m map[string]int64) []string {
@@ -345,6 +388,12 @@ func getTopNKeys(m map[string]int64) []string {
 	return keys
 }
 
+// JoinTopN returns the top N keys of m by value separated by spaces, or
+// an empty string if m is empty.
+func JoinTopN(m map[string]int64) string {
+	return strings.Join(getTopNKeys(m), " ")
+}
+
 func (repo *Repo) WalkLog(r *git.Repository) error {
 	cIter, err := r.Log(&git.LogOptions{
 		//* From:  ref.Hash(),
@@ -357,59 +406,42 @@ func (repo *Repo) WalkLog(r *git.Repository) error {
 		return err
 	}
 
-	contributors := make(map[string]int, 0)
-	orgs := make(map[string]int, 0)
-	var commit_count float64 = 0
-
-	latest_commit, err := cIter.Next()
-	if err != nil {
-		return err
-	}
-
-	author := fmt.Sprintf("%s(%s)", latest_commit.Author.Name, latest_commit.Author.Email)
-	e := strings.Split(latest_commit.Author.Email, "@")
-	org := e[len(e)-1]
-
-	repo.UpdatedSince = latest_commit.Committer.When
-	contributors[author]++
-	orgs[org]++
-
-	if latest_commit.Author.When.After(parser.LAST_YEAR) {
-		commit_count++
-	}
-
-	created_since := latest_commit.Committer.When
-
+	// the log is kept, so the metrics can be recomputed without cloning
+	// the repo again, see package history
+	repo.Commits = repo.Commits[:0]
 	err = cIter.ForEach(func(c *object.Commit) error {
-		author := fmt.Sprintf("%s(%s)", c.Author.Name, c.Author.Email)
-		e = strings.Split(c.Author.Email, "@")
-		org := e[len(e)-1]
-
-		//! It made sense that this `if`` statement is not necessary but sometimes there are errors
-		if created_since.After(c.Committer.When) {
-			created_since = c.Committer.When
-		}
-		contributors[author]++
-		orgs[org]++
-
-		if created_since.After(parser.LAST_YEAR) {
-			commit_count++
-		}
-
+		repo.Commits = append(repo.Commits, history.Commit{
+			Author:    c.Author.Name,
+			Email:     c.Author.Email,
+			When:      c.Author.When,
+			Committed: c.Committer.When,
+		})
 		return nil
 	})
 	if err != nil {
 		return err
 	}
 
-	repo.CreatedSince = created_since
-	repo.ContributorCount = len(contributors)
-	repo.OrgCount = len(orgs)
-	repo.CommitFrequency = commit_count / 52
-
+	m, err := history.Summarize(repo.Commits, parser.NOW, history.DefaultWindow)
+	if err != nil {
+		return err
+	}
+	repo.SetHistory(m)
 	return nil
 }
 
+// SetHistory sets the metrics derived from the log of the repo.
+func (repo *Repo) SetHistory(m history.Metrics) {
+	repo.CreatedSince = m.CreatedSince
+	repo.UpdatedSince = m.UpdatedSince
+	repo.ContributorCount = m.ContributorCount
+	repo.OrgCount = m.OrgCount
+	repo.CommitFrequency = m.CommitFrequency
+	repo.HumanContributorCount = m.HumanContributorCount
+	repo.HumanCommitFrequency = m.HumanCommitFrequency
+	repo.TimezoneDiversity = m.TimezoneDiversity
+}
+
 func (repo *Repo) WalkRepo(r *git.Repository) error {
 
 	ref, err := r.Head()
@@ -429,6 +461,7 @@ func (repo *Repo) WalkRepo(r *git.Repository) error {
 
 	languages := make(map[string]int64, 0)
 	ecosystems := make(map[string]int64, 0)
+	var updateTools []string
 
 	fIter := tree.Files()
 
@@ -436,7 +469,34 @@ func (repo *Repo) WalkRepo(r *git.Repository) error {
 		filename := filepath.Base(f.Name)
 		filesize := f.Size
 		GetLanguages(filename, filesize, &languages)
-		GetEcosystem(filename, filesize, &ecosystems)
+		GetEcosystem(f.Name, filesize, &ecosystems)
+		if testcode.IsSource(f.Name) && filesize <= testcode.MaxFileSize && !manifest.Vendored(f.Name) {
+			if lines, err := countLines(f); err == nil {
+				repo.Tests.Add(f.Name, lines)
+			}
+		}
+		if manifest.IsRuntimeManifest(filename) && !manifest.Skipped(f.Name, manifest.DefaultMaxDepth) {
+			if content, err := f.Contents(); err == nil {
+				if r, ok := manifest.RuntimeOf(f.Name, []byte(content)); ok {
+					repo.Runtimes = append(repo.Runtimes, r)
+				}
+			}
+		}
+		if tool, ok := bots.UpdateTool(f.Name); ok {
+			updateTools = append(updateTools, tool)
+		} else if f.Name == "package.json" && filesize <= maxConfigFileSize {
+			if content, err := f.Contents(); err == nil && bots.RenovateInPackageJSON([]byte(content)) {
+				updateTools = append(updateTools, bots.ToolRenovate)
+			}
+		}
+		if role, ok := maintainer.ContactFile(f.Name); ok && filesize <= maxConfigFileSize {
+			if content, err := f.Contents(); err == nil {
+				repo.Contacts = append(repo.Contacts, maintainer.ParseFile(role, content)...)
+				if role == maintainer.RoleSecurity {
+					repo.Disclosure.Merge(disclosure.Parse(content))
+				}
+			}
+		}
 		if repo.License == parser.UNKNOWN_LICENSE {
 			if _, ok := parser.LICENSE_FILENAMES[filename]; ok {
 				license, err := GetLicense(f)
@@ -453,26 +513,27 @@ func (repo *Repo) WalkRepo(r *git.Repository) error {
 		return err
 	}
 
-	l := ""
-	for _, s := range getTopNKeys(languages) {
-		l += fmt.Sprintf("%s ", s)
-	}
-
-	e := ""
-	for _, s := range getTopNKeys(ecosystems) {
-		e += fmt.Sprintf("%s ", s)
+	repo.Contacts = maintainer.Dedupe(repo.Contacts)
+	repo.UpdateTools = bots.FormatUpdateTools(updateTools)
+	if l := JoinTopN(languages); l != "" {
+		repo.Languages = l
 	}
-
-	if len(l) != 0 {
-		repo.Languages = l[:len(l)-1]
-	}
-	if len(e) != 0 {
-		repo.Ecosystems = e[:len(e)-1]
+	if e := JoinTopN(ecosystems); e != "" {
+		repo.Ecosystems = e
 	}
 
 	return nil
 }
 
+func countLines(f *object.File) (int64, error) {
+	r, err := f.Reader()
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+	return testcode.CountLines(r)
+}
+
 func (repo *Repo) Show() {
 	fmt.Printf(
 		"[%v]: %v\n"+
@@ -1476,25 +1476,3 @@ var LANGUAGE_FILENAMES = map[string]string{
 	".nanorc":                      "nanorc",
 	"nanorc":                       "nanorc",
 }
-
-var ECOSYSTEM_MAP = map[string]string{
-	"package.json":      "npm",
-	"package-lock.json": "npm",
-	".npmrc":            "npm",
-	"node_modules":      "npm",
-	"setup.py":          "pypi",
-	"Pipfile":           "pypi",
-	"Pipfile.lock":      "pypi",
-	"pyproject.toml":    "pypi",
-	//*	"requirements.txt":  "pypi",
-	"Cargo.toml":      "cargo",
-	"Cargo.lock":      "cargo",
-	"pom.xml":         "maven",
-	"build.gradle":    "gradle",
-	"settings.gradle": "gradle",
-	"go.sum":          "go",
-	"go.work.sum":     "go",
-	"go.mod":          "go",
-	"go.work":         "go",
-	//TODO: nuget
-}
@@ -0,0 +1,104 @@
+package url
+
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Forges are the hosts of code forges, whose repos are at
+// <host>/<owner>/<repo> and which have an API for metadata.
+var Forges = map[string]bool{
+	"github.com":    true,
+	"gitlab.com":    true,
+	"bitbucket.org": true,
+	"gitee.com":     true,
+}
+
+// IsForge reports whether u is hosted on a code forge. Repos of other
+// hosts, e.g. git.kernel.org or sourceware.org, are forge-less and only
+// have the metrics derived from their history.
+func IsForge(u *RepoURL) bool {
+	return Forges[strings.ToLower(u.Resource)]
+}
+
+// cgitPages are the pages cgit serves below a repo, e.g.
+// /linux.git/tree/README.
+var cgitPages = map[string]bool{
+	"about":    true,
+	"commit":   true,
+	"diff":     true,
+	"log":      true,
+	"patch":    true,
+	"plain":    true,
+	"refs":     true,
+	"snapshot": true,
+	"stats":    true,
+	"summary":  true,
+	"tag":      true,
+	"tree":     true,
+}
+
+// CloneURL returns the clone url of a link to a repo which is not on a
+// forge, false if the link does not look like a git repo:
+//
+//   - git:// urls are kept, e.g. git://git.kernel.org/pub/scm/git/git.git
+//   - gitweb pages are mapped to the repo next to the script, e.g.
+//     https://sourceware.org/git/?p=glibc.git;a=summary is
+//     https://sourceware.org/git/glibc.git
+//   - cgit pages are mapped to the repo, which cgit also serves for
+//     cloning, e.g. https://git.kernel.org/pub/scm/git/git.git/tree/README
+//     is https://git.kernel.org/pub/scm/git/git.git
+//   - other http urls are kept if their path ends with .git
+func CloneURL(link string) (string, bool) {
+	link = strings.TrimSpace(link)
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" {
+		return "", false
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "git":
+		if strings.Trim(u.Path, "/") == "" {
+			return "", false
+		}
+		u.RawQuery, u.Fragment = "", ""
+		return strings.TrimSuffix(u.String(), "/"), true
+	case "http", "https":
+	default:
+		return "", false
+	}
+
+	// gitweb separates its parameters by semicolons
+	for _, param := range strings.FieldsFunc(u.RawQuery, func(r rune) bool { return r == ';' || r == '&' }) {
+		if p, ok := strings.CutPrefix(param, "p="); ok {
+			repo, err := url.QueryUnescape(p)
+			if err != nil || repo == "" {
+				return "", false
+			}
+			dir := u.Path
+			if !strings.HasSuffix(dir, "/") {
+				dir = path.Dir(dir)
+			}
+			u.Path = path.Join(dir, repo)
+			u.RawQuery, u.Fragment = "", ""
+			return u.String(), true
+		}
+	}
+
+	// the repo is the path up to the first segment ending with .git, the
+	// segments after it are cgit pages
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i := range parts {
+		if !strings.HasSuffix(parts[i], ".git") || len(parts[i]) == len(".git") {
+			continue
+		}
+		if i+1 < len(parts) && !cgitPages[parts[i+1]] {
+			return "", false
+		}
+		u.Path = "/" + strings.Join(parts[:i+1], "/")
+		u.RawQuery, u.Fragment = "", ""
+		return u.String(), true
+	}
+	return "", false
+}
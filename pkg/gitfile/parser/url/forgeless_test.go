@@ -0,0 +1,52 @@
+package url
+
+import "testing"
+
+func TestCloneURL(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		ok    bool
+	}{
+		{"git://git.kernel.org/pub/scm/git/git.git", "git://git.kernel.org/pub/scm/git/git.git", true},
+		{"https://sourceware.org/git/?p=glibc.git;a=summary", "https://sourceware.org/git/glibc.git", true},
+		{"https://example.org/gitweb.cgi?p=tools/foo.git;a=tree", "https://example.org/tools/foo.git", true},
+		{"https://git.kernel.org/pub/scm/git/git.git/tree/README?h=main", "https://git.kernel.org/pub/scm/git/git.git", true},
+		{"https://git.kernel.org/pub/scm/linux/kernel/git/torvalds/linux.git/snapshot/linux-6.1.tar.gz", "https://git.kernel.org/pub/scm/linux/kernel/git/torvalds/linux.git", true},
+		{"https://git.savannah.gnu.org/cgit/emacs.git/", "https://git.savannah.gnu.org/cgit/emacs.git", true},
+		{"https://example.org/foo.git/releases/foo-1.0.tar.gz", "", false},
+		{"https://ftp.gnu.org/gnu/bash/bash-5.2.tar.gz", "", false},
+		{"https://example.org/.git", "", false},
+		{"git://example.org/", "", false},
+		{"ftp://example.org/foo.git", "", false},
+		{"foo.git", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := CloneURL(tt.input)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("CloneURL(%q) = %q, %v, want %q, %v", tt.input, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestIsForge(t *testing.T) {
+	for input, want := range map[string]bool{
+		"https://github.com/gin-gonic/gin":         true,
+		"https://GitLab.com/a/b.git":               true,
+		"git://git.kernel.org/pub/scm/git/git.git": false,
+		"https://sourceware.org/git/glibc.git":     false,
+	} {
+		u := ParseURL(input)
+		if got := IsForge(&u); got != want {
+			t.Errorf("IsForge(%q) = %v, want %v", input, got, want)
+		}
+	}
+}
+
+func TestParseURLGitweb(t *testing.T) {
+	// gitweb separates parameters by semicolons, which is not a valid query
+	u := ParseURL("https://sourceware.org/git/?p=glibc.git;a=summary")
+	if u.Resource != "sourceware.org" || u.Pathname != "/git" {
+		t.Errorf("ParseURL() = %q %q", u.Resource, u.Pathname)
+	}
+}
@@ -144,10 +144,8 @@ func ParseURL(input string) RepoURL {
 
 	re = regexp.MustCompile(`\/$`)
 
-	q, err := url.ParseQuery(output.Search)
-	if err != nil {
-		panic(err)
-	}
+	// the valid pairs are kept, e.g. of gitweb urls separated by semicolons
+	q, _ := url.ParseQuery(output.Search)
 
 	output.Query = q
 	output.URL = re.ReplaceAllString(output.URL, "")
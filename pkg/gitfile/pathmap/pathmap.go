@@ -0,0 +1,160 @@
+// Package pathmap maps repo urls to directories of the clone storage.
+//
+// The plain layout joins the host and the pathname of the url, e.g.
+// <storage>/github.com/owner/repo.git, which is easy to browse but may
+// collide on case-insensitive filesystems and may contain characters which
+// are invalid on Windows. The hashed layout shards the clones by the hash
+// of the normalized url, e.g. <storage>/3f/a9/owner_repo-3fa9c1d2e4b5, and
+// is portable across filesystems.
+package pathmap
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	url "github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser/url"
+)
+
+// Mapper maps a repo url to its directory in the clone storage.
+type Mapper interface {
+	Path(storagePath string, u *url.RepoURL) string
+}
+
+type Layout string
+
+const (
+	LayoutPlain  Layout = "plain"
+	LayoutHashed Layout = "hashed"
+)
+
+// MaxNameLength is the max length of the readable part of a hashed
+// directory name, so the whole path stays below the Windows limit.
+const MaxNameLength = 64
+
+// PlainMapper keeps the layout <storage>/<host>/<pathname>, it is the
+// layout of the existing clones on Linux.
+type PlainMapper struct{}
+
+var _ Mapper = PlainMapper{}
+
+// Path implements Mapper.
+func (PlainMapper) Path(storagePath string, u *url.RepoURL) string {
+	return filepath.Join(storagePath, u.Resource, filepath.FromSlash(u.Pathname))
+}
+
+// HashedMapper shards clones into <storage>/<h[0:2]>/<h[2:4]>/<name>-<h[0:12]>,
+// where h is the sha256 of the normalized url and name is a readable,
+// filesystem-safe form of the pathname.
+type HashedMapper struct{}
+
+var _ Mapper = HashedMapper{}
+
+// Normalize returns the key identifying a repo regardless of the case, the
+// scheme, the user and the .git suffix of its url, e.g.
+// github.com/owner/repo. Repos whose urls differ only in case are the same
+// repo on all the major forges.
+func Normalize(u *url.RepoURL) string {
+	p := strings.ReplaceAll(u.Pathname, "\\", "/")
+	p = strings.Trim(p, "/")
+	p = strings.TrimSuffix(p, ".git")
+	return strings.ToLower(u.Resource + "/" + p)
+}
+
+// Path implements Mapper.
+func (HashedMapper) Path(storagePath string, u *url.RepoURL) string {
+	key := Normalize(u)
+	sum := sha256.Sum256([]byte(key))
+	h := hex.EncodeToString(sum[:])
+
+	_, name, _ := strings.Cut(key, "/")
+	return filepath.Join(storagePath, h[0:2], h[2:4], fmt.Sprintf("%s-%s", SafeName(name), h[:12]))
+}
+
+// windows reserves these names regardless of the extension
+var reservedNames = map[string]bool{
+	"con": true, "prn": true, "aux": true, "nul": true,
+	"com1": true, "com2": true, "com3": true, "com4": true, "com5": true,
+	"com6": true, "com7": true, "com8": true, "com9": true,
+	"lpt1": true, "lpt2": true, "lpt3": true, "lpt4": true, "lpt5": true,
+	"lpt6": true, "lpt7": true, "lpt8": true, "lpt9": true,
+}
+
+// SafeName returns a lower case name which is valid on Windows, macOS and
+// Linux, separators and invalid characters are replaced with '_'.
+func SafeName(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r < 0x20, strings.ContainsRune(`<>:"/\|?*`, r):
+			b.WriteRune('_')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	name := b.String()
+	if len(name) > MaxNameLength {
+		name = name[:MaxNameLength]
+		// do not cut a multi-byte rune
+		name = strings.ToValidUTF8(name, "")
+	}
+	// windows trims trailing dots and spaces
+	name = strings.TrimRight(name, ". ")
+	if name == "" {
+		name = "_"
+	}
+	if base, _, _ := strings.Cut(name, "."); reservedNames[base] {
+		name = "_" + name
+	}
+	return name
+}
+
+// DefaultLayout is the layout used if none is configured, clones on
+// Linux keep the plain layout, Windows and macOS use the hashed layout as
+// their filesystems are case-insensitive by default.
+func DefaultLayout() Layout {
+	if runtime.GOOS == "linux" {
+		return LayoutPlain
+	}
+	return LayoutHashed
+}
+
+// New returns the mapper of layout, an empty layout is DefaultLayout.
+func New(layout Layout) (Mapper, error) {
+	if layout == "" {
+		layout = DefaultLayout()
+	}
+	switch layout {
+	case LayoutPlain:
+		return PlainMapper{}, nil
+	case LayoutHashed:
+		return HashedMapper{}, nil
+	default:
+		return nil, fmt.Errorf("unknown git storage layout %q, expect %s or %s", layout, LayoutPlain, LayoutHashed)
+	}
+}
+
+var defaultMapper Mapper
+
+// InitDefault sets the default mapper to the one of layout.
+func InitDefault(layout Layout) error {
+	m, err := New(layout)
+	if err != nil {
+		return err
+	}
+	defaultMapper = m
+	return nil
+}
+
+// Default returns the default mapper, which is the one of DefaultLayout
+// if InitDefault is not called.
+func Default() Mapper {
+	if defaultMapper == nil {
+		m, _ := New("")
+		return m
+	}
+	return defaultMapper
+}
@@ -0,0 +1,80 @@
+package pathmap
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	url "github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser/url"
+)
+
+func TestNormalize(t *testing.T) {
+	want := "github.com/owner/repo"
+	for _, link := range []string{
+		"https://github.com/owner/repo.git",
+		"https://GitHub.com/Owner/Repo",
+		"git@github.com:owner/repo.git",
+	} {
+		u := url.ParseURL(link)
+		if got := Normalize(&u); got != want {
+			t.Errorf("Normalize(%s) = %s, want %s", link, got, want)
+		}
+	}
+}
+
+func TestHashedMapper(t *testing.T) {
+	a := url.ParseURL("https://github.com/Owner/Repo.git")
+	b := url.ParseURL("https://github.com/owner/repo")
+	c := url.ParseURL("https://github.com/owner/other.git")
+
+	m := HashedMapper{}
+	pa, pb, pc := m.Path("storage", &a), m.Path("storage", &b), m.Path("storage", &c)
+	if pa != pb {
+		t.Errorf("urls differing in case map to %s and %s", pa, pb)
+	}
+	if pa == pc {
+		t.Errorf("different repos map to the same path %s", pa)
+	}
+
+	parts := strings.Split(filepath.ToSlash(pa), "/")
+	if len(parts) != 4 || len(parts[1]) != 2 || len(parts[2]) != 2 {
+		t.Fatalf("path %s is not sharded", pa)
+	}
+	if !strings.HasPrefix(parts[3], "owner_repo-"+parts[1]+parts[2]) {
+		t.Errorf("name of %s = %s", pa, parts[3])
+	}
+}
+
+func TestSafeName(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want string
+	}{
+		{arg: "owner/repo", want: "owner_repo"},
+		{arg: `a<b>c:d"e|f?g*h\i`, want: "a_b_c_d_e_f_g_h_i"},
+		{arg: "CON", want: "_con"},
+		{arg: "aux.git", want: "_aux.git"},
+		{arg: "repo. ", want: "repo"},
+		{arg: "", want: "_"},
+		{arg: strings.Repeat("x", 100), want: strings.Repeat("x", MaxNameLength)},
+	}
+	for _, tt := range tests {
+		if got := SafeName(tt.arg); got != tt.want {
+			t.Errorf("SafeName(%q) = %q, want %q", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New("flat"); err == nil {
+		t.Error("unknown layout should fail")
+	}
+	m, err := New(LayoutPlain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := url.ParseURL("https://github.com/owner/repo.git")
+	if got, want := m.Path("storage", &u), filepath.Join("storage", "github.com", "owner", "repo.git"); got != want {
+		t.Errorf("plain path = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,83 @@
+package probe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	url "github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser/url"
+	"github.com/HUSTSecLab/criticality_score/pkg/httpcache"
+)
+
+// ErrUnknownSize is returned by Lookup for repos whose forge does not report
+// the size of a repo.
+var ErrUnknownSize = errors.New("repo size is unknown")
+
+// GithubAPIURL is the base url of the GitHub REST API.
+var GithubAPIURL = "https://api.github.com"
+
+// Info is the metadata of a repo reported by its forge.
+type Info struct {
+	// Size is the size of the repo in bytes
+	Size      int64
+	CreatedAt time.Time
+}
+
+type githubRepo struct {
+	// size of the repo in kilobytes
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ownerRepo returns the owner and the name of the repo, the .git suffix
+// is removed.
+func ownerRepo(u *url.RepoURL) (string, string, bool) {
+	parts := strings.Split(strings.Trim(u.Pathname, "/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], strings.TrimSuffix(parts[1], ".git"), true
+}
+
+// Lookup queries the forge of u for the size of the repo, only GitHub is
+// supported, other forges return ErrUnknownSize.
+func Lookup(ctx context.Context, client *http.Client, token string, u *url.RepoURL) (*Info, error) {
+	if !strings.EqualFold(u.Resource, "github.com") {
+		return nil, ErrUnknownSize
+	}
+	owner, repo, ok := ownerRepo(u)
+	if !ok {
+		return nil, ErrUnknownSize
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		fmt.Sprintf("%s/repos/%s/%s", GithubAPIURL, owner, repo), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if token != "" {
+		req.Header.Set("Authorization", "token "+token)
+	}
+	if client == nil {
+		client = httpcache.Client()
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get %s/%s: %s", owner, repo, resp.Status)
+	}
+
+	var r githubRepo
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, err
+	}
+	return &Info{Size: r.Size * 1024, CreatedAt: r.CreatedAt}, nil
+}
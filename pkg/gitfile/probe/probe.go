@@ -0,0 +1,223 @@
+// Package probe collects the metadata of very large repos from their HEAD
+// only. Cloning the whole history of such repos takes hours and hundreds of
+// gigabytes, so the refs are listed by `git ls-remote`, and the tree of HEAD
+// is read from a shallow, blobless clone, which is removed afterwards.
+//
+// The contributor count and the commit frequency need the history, they
+// are left unknown by a probe.
+package probe
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	parser "github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser"
+	git "github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser/git"
+	url "github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser/url"
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+)
+
+// Result is the metadata collected by a probe.
+type Result struct {
+	// Repo has the fields which do not need the history, the others are
+	// unknown
+	Repo     git.Repo
+	Branches int
+	Tags     int
+	// Head is the hash of the commit HEAD points to
+	Head string
+}
+
+func runGit(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	// never ask for credentials of private or removed repos
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// ListRefs counts the branches and tags of the remote repo by
+// `git ls-remote`, without cloning it.
+func ListRefs(ctx context.Context, repoURL string) (branches int, tags int, err error) {
+	out, err := runGit(ctx, "", "ls-remote", "--heads", "--tags", repoURL)
+	if err != nil {
+		return 0, 0, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		_, ref, ok := strings.Cut(scanner.Text(), "\t")
+		if !ok {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(ref, "refs/heads/"):
+			branches++
+		// annotated tags are listed again with the peeled commit
+		case strings.HasPrefix(ref, "refs/tags/") && !strings.HasSuffix(ref, "^{}"):
+			tags++
+		}
+	}
+	return branches, tags, scanner.Err()
+}
+
+// Probe collects the metadata of the repo at u from its HEAD.
+func Probe(ctx context.Context, u *url.RepoURL) (*Result, error) {
+	result := &Result{Repo: git.NewRepo()}
+	repo := &result.Repo
+	repo.URL = u.URL
+	repo.Source = u.Resource
+	if owner, name, ok := ownerRepo(u); ok {
+		repo.Owner = owner
+		repo.Name = name
+	}
+
+	var err error
+	result.Branches, result.Tags, err = ListRefs(ctx, u.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "cs-probe-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	// only the commit and the trees of HEAD are fetched, blobs are fetched
+	// on demand
+	if _, err := runGit(ctx, "", "clone", "--quiet", "--depth", "1", "--filter=blob:none", "--no-checkout", u.URL, dir); err != nil {
+		return nil, err
+	}
+
+	out, err := runGit(ctx, dir, "log", "-1", "--format=%H %ct")
+	if err != nil {
+		return nil, err
+	}
+	head, ct, _ := strings.Cut(strings.TrimSpace(string(out)), " ")
+	result.Head = head
+	if sec, err := strconv.ParseInt(ct, 10, 64); err == nil {
+		repo.UpdatedSince = time.Unix(sec, 0)
+	}
+
+	out, err = runGit(ctx, dir, "ls-tree", "-r", "--name-only", "-z", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	languages := make(map[string]int64)
+	ecosystems := make(map[string]int64)
+	var licenseFile string
+	for _, name := range strings.Split(string(out), "\x00") {
+		if name == "" {
+			continue
+		}
+		// sizes would fetch every blob, so files are counted instead
+		base := path.Base(name)
+		git.GetLanguages(base, 1, &languages)
+		git.GetEcosystem(name, 1, &ecosystems)
+		// only the license at the root applies to the repo
+		if _, ok := parser.LICENSE_FILENAMES[name]; ok && licenseFile == "" {
+			licenseFile = name
+		}
+	}
+	if l := git.JoinTopN(languages); l != "" {
+		repo.Languages = l
+	}
+	if e := git.JoinTopN(ecosystems); e != "" {
+		repo.Ecosystems = e
+	}
+
+	if licenseFile != "" {
+		// fetches the single blob of the license
+		text, err := runGit(ctx, dir, "cat-file", "blob", "HEAD:"+licenseFile)
+		if err != nil {
+			logger.Warnf("Failed to read license of %s, %v", u.URL, err)
+		} else {
+			repo.License = git.ScanLicense(text)
+		}
+	}
+
+	return result, nil
+}
+
+// Config selects the repos collected by a probe.
+type Config struct {
+	// Threshold is the min size in bytes reported by the forge of a repo
+	// to probe it, 0 disables probing
+	Threshold int64
+	// Token is the GitHub token used to look up the size
+	Token string
+}
+
+// Prober probes the repos larger than the threshold.
+type Prober struct {
+	config Config
+	client *http.Client
+}
+
+// NewProber returns a prober looking up sizes with client, nil means the
+// default client of httpcache.
+func NewProber(config Config, client *http.Client) *Prober {
+	return &Prober{config: config, client: client}
+}
+
+// ShouldProbe reports whether the forge of u reports a size over the
+// threshold, repos of unknown size are not probed. The info reported by
+// the forge is returned, if any.
+func (p *Prober) ShouldProbe(ctx context.Context, u *url.RepoURL) (bool, *Info) {
+	if p.config.Threshold <= 0 {
+		return false, nil
+	}
+	info, err := Lookup(ctx, p.client, p.config.Token, u)
+	if err != nil {
+		if err != ErrUnknownSize {
+			logger.Warnf("Failed to look up size of %s, %v", u.URL, err)
+		}
+		return false, nil
+	}
+	return info.Size > p.config.Threshold, info
+}
+
+// Probe probes u, the creation time reported by the forge is used if info
+// is not nil, as it is not in a shallow clone.
+func (p *Prober) Probe(ctx context.Context, u *url.RepoURL, info *Info) (*Result, error) {
+	result, err := Probe(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	if info != nil && !info.CreatedAt.IsZero() {
+		result.Repo.CreatedSince = info.CreatedAt
+	}
+	return result, nil
+}
+
+var defaultProber *Prober
+
+// InitDefault initializes the default prober, probing is disabled if the
+// threshold is not positive.
+func InitDefault(config *Config) {
+	if config == nil || config.Threshold <= 0 {
+		defaultProber = nil
+		return
+	}
+	defaultProber = NewProber(*config, nil)
+}
+
+// Default returns the default prober, or nil if probing is disabled.
+func Default() *Prober {
+	return defaultProber
+}
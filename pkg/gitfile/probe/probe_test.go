@@ -0,0 +1,155 @@
+package probe
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	url "github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser/url"
+)
+
+const mitLicense = `MIT License
+
+Copyright (c) 2024 Example
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+`
+
+// newRemote creates a repo with two branches and two tags, one of them
+// annotated, and returns its file url.
+func newRemote(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	files := map[string]string{
+		"LICENSE":        mitLicense,
+		"main.go":        "package main\n",
+		"util/util.go":   "package util\n",
+		"go.mod":         "module example.com/repo\n",
+		"scripts/run.py": "print()\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(p), 0755)
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet", "-b", "main"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "init"},
+		{"branch", "dev"},
+		{"tag", "v1"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "tag", "-a", "v2", "-m", "v2"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	return "file://" + filepath.ToSlash(dir)
+}
+
+func TestListRefs(t *testing.T) {
+	remote := newRemote(t)
+	branches, tags, err := ListRefs(context.Background(), remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if branches != 2 || tags != 2 {
+		t.Errorf("ListRefs() = %d branches, %d tags, want 2, 2", branches, tags)
+	}
+}
+
+func TestProbe(t *testing.T) {
+	remote := newRemote(t)
+	u := url.RepoURL{URL: remote, Resource: "github.com", Pathname: "/owner/repo.git"}
+	result, err := Probe(context.Background(), &u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := result.Repo
+	if repo.Owner != "owner" || repo.Name != "repo" || repo.Source != "github.com" {
+		t.Errorf("Probe() repo = %s/%s/%s, want github.com/owner/repo", repo.Source, repo.Owner, repo.Name)
+	}
+	if repo.License != "MIT" {
+		t.Errorf("Probe() license = %q, want MIT", repo.License)
+	}
+	// two go files and one python file
+	if repo.Languages != "Go Python" {
+		t.Errorf("Probe() languages = %q, want %q", repo.Languages, "Go Python")
+	}
+	if repo.Ecosystems == "" {
+		t.Error("Probe() ecosystems is empty, want go.mod counted")
+	}
+	if repo.UpdatedSince.IsZero() || len(result.Head) != 40 {
+		t.Errorf("Probe() updated = %v, head = %q", repo.UpdatedSince, result.Head)
+	}
+	if result.Branches != 2 || result.Tags != 2 {
+		t.Errorf("Probe() = %d branches, %d tags, want 2, 2", result.Branches, result.Tags)
+	}
+}
+
+func TestShouldProbe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/large":
+			w.Write([]byte(`{"size": 5000000, "created_at": "2010-01-02T03:04:05Z"}`))
+		case "/repos/owner/small":
+			w.Write([]byte(`{"size": 100, "created_at": "2010-01-02T03:04:05Z"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	old := GithubAPIURL
+	GithubAPIURL = server.URL
+	defer func() { GithubAPIURL = old }()
+
+	p := NewProber(Config{Threshold: 1 << 30}, server.Client())
+	tests := []struct {
+		resource, pathname string
+		want               bool
+	}{
+		{"github.com", "/owner/large.git", true},
+		{"github.com", "/owner/small", false},
+		{"github.com", "/owner/missing", false},
+		{"gitlab.com", "/owner/large", false},
+	}
+	for _, tt := range tests {
+		u := url.RepoURL{Resource: tt.resource, Pathname: tt.pathname}
+		got, info := p.ShouldProbe(context.Background(), &u)
+		if got != tt.want {
+			t.Errorf("ShouldProbe(%s%s) = %v, want %v", tt.resource, tt.pathname, got, tt.want)
+		}
+		if got && info.CreatedAt.Year() != 2010 {
+			t.Errorf("ShouldProbe(%s%s) created = %v", tt.resource, tt.pathname, info.CreatedAt)
+		}
+	}
+
+	if got, _ := NewProber(Config{}, server.Client()).ShouldProbe(context.Background(), &url.RepoURL{Resource: "github.com", Pathname: "/owner/large"}); got {
+		t.Error("ShouldProbe() = true with threshold 0")
+	}
+}
@@ -2,14 +2,44 @@ package util
 
 import (
 	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	neturl "net/url"
 	"os"
-	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser/url"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/pathmap"
 )
 
+// Stdin is the path of the standard input.
+const Stdin = "-"
+
+// maxURLErrors is the number of invalid urls reported by ReadURLs.
+const maxURLErrors = 10
+
+// URLColumns are the header names of url columns, in order of preference,
+// which ReadURLs picks if no column is given.
+var URLColumns = []string{"git_link", "url", "repo.url", "repo_url", "repository", "repo", "link", "html_url"}
+
+// openInput opens path, Stdin is the standard input.
+func openInput(path string) (io.ReadCloser, error) {
+	if path == Stdin {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+// GetCSVInput returns the rows of a csv file, Stdin reads the standard
+// input.
 func GetCSVInput(path string) ([][]string, error) {
-	file, err := os.Open(path)
+	file, err := openInput(path)
 
 	if err != nil {
 		return nil, err
@@ -27,22 +57,280 @@ func GetCSVInput(path string) ([][]string, error) {
 	return urls, nil
 }
 
-func Save2CSV(outputPath string, content [][]string) error {
-	file, err := os.Create(outputPath)
+var scpURLPattern = regexp.MustCompile(`^[\w.-]+@[\w.-]+:[^/].*`)
+
+// CheckURL returns an error if s is not the url of a repository, e.g.
+// https://github.com/foo/bar or git@github.com:foo/bar.git.
+func CheckURL(s string) error {
+	if scpURLPattern.MatchString(s) {
+		return nil
+	}
+	u, err := neturl.Parse(s)
 	if err != nil {
-		return err
+		return fmt.Errorf("%q is not a url", s)
+	}
+	switch u.Scheme {
+	case "http", "https", "git", "ssh", "git+ssh", "svn", "hg":
+	default:
+		return fmt.Errorf("%q is not a url of a repository", s)
+	}
+	if u.Host == "" || strings.Trim(u.Path, "/") == "" {
+		return fmt.Errorf("%q has no host or path", s)
+	}
+	return nil
+}
+
+// ReadURLs returns the urls of a column of a csv file, Stdin reads the
+// standard input. See ParseURLs.
+func ReadURLs(path string, column string) ([]string, error) {
+	file, err := openInput(path)
+	if err != nil {
+		return nil, err
 	}
 	defer file.Close()
+	return ParseURLs(file, column)
+}
 
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+// urlColumn returns the index of column in header, a name or an index
+// from 1, and whether header is a header. An empty column picks the first
+// of URLColumns, or the first column.
+func urlColumn(header []string, column string) (int, bool, error) {
+	if column == "" {
+		for _, name := range URLColumns {
+			for i, h := range header {
+				if strings.EqualFold(strings.TrimSpace(h), name) {
+					return i, true, nil
+				}
+			}
+		}
+		return 0, len(header) > 0 && CheckURL(strings.TrimSpace(header[0])) != nil, nil
+	}
+	if n, err := strconv.Atoi(column); err == nil {
+		if n < 1 {
+			return 0, false, fmt.Errorf("column %d is not positive", n)
+		}
+		i := n - 1
+		return i, i < len(header) && CheckURL(strings.TrimSpace(header[i])) != nil, nil
+	}
+	for i, h := range header {
+		if strings.EqualFold(strings.TrimSpace(h), column) {
+			return i, true, nil
+		}
+	}
+	return 0, false, fmt.Errorf("no column %s in header %v", column, header)
+}
+
+// ParseURLs returns the urls of a column of csv rows, in their order.
+// column is a header name or an index from 1, empty picks the first of
+// URLColumns in the header, or the first column. The first row is a header
+// if it has the column name, or if its cell is not a url. Empty cells are
+// skipped, and invalid urls are errors naming their lines.
+func ParseURLs(r io.Reader, column string) ([]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
 
-	writer.WriteAll(content)
+	var ret []string
+	var errs []error
+	invalid := 0
+	col := -1
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if col < 0 {
+			i, header, err := urlColumn(row, column)
+			if err != nil {
+				return nil, err
+			}
+			col = i
+			if header {
+				continue
+			}
+		}
+		if col >= len(row) {
+			continue
+		}
+		link := strings.TrimSpace(row[col])
+		if link == "" {
+			continue
+		}
+		if err := CheckURL(link); err != nil {
+			invalid++
+			if len(errs) < maxURLErrors {
+				line, _ := reader.FieldPos(col)
+				errs = append(errs, fmt.Errorf("line %d: %w", line, err))
+			}
+			continue
+		}
+		ret = append(ret, link)
+	}
+	if invalid > len(errs) {
+		errs = append(errs, fmt.Errorf("and %d more invalid urls", invalid-len(errs)))
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return ret, nil
+}
 
+// Stdout is the path of the standard output.
+const Stdout = "-"
+
+// WriteMode is how an output file which exists is written.
+type WriteMode string
+
+const (
+	// ModeTruncate replaces the file, it is the default
+	ModeTruncate WriteMode = "truncate"
+	// ModeAppend appends to the file
+	ModeAppend WriteMode = "append"
+	// ModeRotate renames the file to <name>.<time><ext> by its modification
+	// time, and writes a new one
+	ModeRotate WriteMode = "rotate"
+)
+
+// WriteModes are all write modes.
+var WriteModes = []WriteMode{ModeTruncate, ModeAppend, ModeRotate}
+
+// rotatedPath returns the path an old output file is renamed to.
+func rotatedPath(path string, modTime time.Time) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + modTime.UTC().Format("20060102-150405") + ext
+}
+
+// OpenOutput opens path for writing by mode, Stdout is the standard
+// output. It returns whether the file already has content, which is only
+// kept by ModeAppend.
+func OpenOutput(path string, mode WriteMode) (*os.File, bool, error) {
+	if path == Stdout {
+		return os.Stdout, false, nil
+	}
+	fi, err := os.Stat(path)
+	exists := err == nil && fi.Size() > 0
+	switch mode {
+	case "", ModeTruncate:
+		f, err := os.Create(path)
+		return f, false, err
+	case ModeAppend:
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		return f, exists, err
+	case ModeRotate:
+		if exists {
+			if err := os.Rename(path, rotatedPath(path, fi.ModTime())); err != nil {
+				return nil, false, err
+			}
+		}
+		f, err := os.Create(path)
+		return f, false, err
+	}
+	return nil, false, fmt.Errorf("unknown write mode %q", mode)
+}
+
+// CSVWriter writes rows to a csv file as they come, so large outputs are
+// not kept in memory and rows written before a crash are kept. It is safe
+// for concurrent use.
+type CSVWriter struct {
+	mu   sync.Mutex
+	file *os.File
+	w    *csv.Writer
+}
+
+// NewCSVWriter opens path by mode and writes header, if any. Rows appended
+// to a file are written without a header, and the file must have the same
+// one, otherwise its columns would not match.
+func NewCSVWriter(path string, mode WriteMode, header []string) (*CSVWriter, error) {
+	if mode == ModeAppend && len(header) > 0 && path != Stdout {
+		if err := checkHeader(path, header); err != nil {
+			return nil, err
+		}
+	}
+	file, exists, err := OpenOutput(path, mode)
+	if err != nil {
+		return nil, err
+	}
+	ret := &CSVWriter{file: file, w: csv.NewWriter(file)}
+	if len(header) > 0 && !exists {
+		if err := ret.Write(header); err != nil {
+			ret.Close()
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// checkHeader returns an error if the csv file at path has another header
+// than header. A missing or empty file has any header.
+func checkHeader(path string, header []string) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	old, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if strings.Join(old, ",") != strings.Join(header, ",") {
+		return fmt.Errorf("can not append to %s, its header %v is not %v", path, old, header)
+	}
 	return nil
 }
 
+// Write writes a row and flushes it.
+func (w *CSVWriter) Write(row []string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.w.Write(row); err != nil {
+		return err
+	}
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// Close closes the file, stdout is not closed.
+func (w *CSVWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.w.Flush()
+	err := w.w.Error()
+	if w.file != os.Stdout {
+		if cerr := w.file.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// Save2CSV writes content to outputPath, replacing it. Large outputs are
+// written row by row with a CSVWriter instead.
+func Save2CSV(outputPath string, content [][]string) error {
+	w, err := NewCSVWriter(outputPath, ModeTruncate, nil)
+	if err != nil {
+		return err
+	}
+	for _, row := range content {
+		if err := w.Write(row); err != nil {
+			w.Close()
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// GetGitRepositoryPath returns the directory of the clone of u in
+// storagePath, which depends on the layout of the default mapper.
 func GetGitRepositoryPath(storagePath string, u *url.RepoURL) string {
-	// join path
-	return path.Join(storagePath, u.Resource, u.Pathname)
+	return pathmap.Default().Path(storagePath, u)
 }
@@ -1,11 +1,176 @@
 package util
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestGetCSVInput(t *testing.T) {
 
 }
 
 func TestSave2CSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.csv")
+	if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Save2CSV(path, [][]string{{"a", "b"}, {"c", "d,e"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, path); got != "a,b\nc,\"d,e\"\n" {
+		t.Errorf("Save2CSV() wrote %q", got)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestCSVWriter(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "links.csv")
+	header := []string{"git_link", "reason"}
+	write := func(mode WriteMode, rows ...string) error {
+		w, err := NewCSVWriter(path, mode, header)
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if err := w.Write([]string{row, "invalid"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return w.Close()
+	}
+
+	if err := write(ModeAppend, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := write(ModeAppend, "b"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, path); got != "git_link,reason\na,invalid\nb,invalid\n" {
+		t.Errorf("appended %q", got)
+	}
+
+	modTime := time.Date(2025, 1, 19, 8, 30, 0, 0, time.UTC)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	if err := write(ModeRotate, "c"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, path); got != "git_link,reason\nc,invalid\n" {
+		t.Errorf("rotated to %q", got)
+	}
+	if got := readFile(t, filepath.Join(dir, "links.20250119-083000.csv")); got != "git_link,reason\na,invalid\nb,invalid\n" {
+		t.Errorf("rotated file is %q", got)
+	}
+
+	if err := write(ModeTruncate, "d"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, path); got != "git_link,reason\nd,invalid\n" {
+		t.Errorf("truncated to %q", got)
+	}
+
+	header = []string{"git_link"}
+	if err := write(ModeAppend, "e"); err == nil {
+		t.Error("appending rows of another header error = nil")
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	for _, s := range []string{"https://github.com/foo/bar", "git://git.kernel.org/pub/scm/git/git.git", "git@github.com:foo/bar.git", "ssh://git@gitlab.com/foo/bar"} {
+		if err := CheckURL(s); err != nil {
+			t.Errorf("CheckURL(%q) = %v", s, err)
+		}
+	}
+	for _, s := range []string{"url", "github.com/foo/bar", "https://github.com", "ftp://example.com/foo", "/home/foo/bar"} {
+		if err := CheckURL(s); err == nil {
+			t.Errorf("CheckURL(%q) = nil", s)
+		}
+	}
+}
+
+func TestParseURLs(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		column  string
+		want    []string
+		wantErr string
+	}{
+		{
+			name:  "no header",
+			input: "https://github.com/foo/bar,1\n\nhttps://github.com/foo/baz,2\n",
+			want:  []string{"https://github.com/foo/bar", "https://github.com/foo/baz"},
+		},
+		{
+			name:  "header of first column",
+			input: "project,stars\nhttps://github.com/foo/bar,1\n",
+			want:  []string{"https://github.com/foo/bar"},
+		},
+		{
+			name:  "known header",
+			input: "name,repo.url,default_score\nbar,https://github.com/foo/bar,0.5\n",
+			want:  []string{"https://github.com/foo/bar"},
+		},
+		{
+			name:   "named column",
+			input:  "name,Homepage\nbar,https://gitlab.com/foo/bar\n",
+			column: "homepage",
+			want:   []string{"https://gitlab.com/foo/bar"},
+		},
+		{
+			name:   "column index",
+			input:  "bar,https://gitlab.com/foo/bar\nbaz,\n",
+			column: "2",
+			want:   []string{"https://gitlab.com/foo/bar"},
+		},
+		{
+			name:    "unknown column",
+			input:   "name,url\n",
+			column:  "link",
+			wantErr: "no column link",
+		},
+		{
+			name:    "invalid urls",
+			input:   "url\nhttps://github.com/foo/bar\nnot a url\n\"multi\nline\",x\nhttps://github.com\n",
+			wantErr: "line 3: \"not a url\" is not a url of a repository\nline 4: \"multi\\nline\" is not a url\nline 6: \"https://github.com\" has no host or path",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseURLs(strings.NewReader(tt.input), tt.column)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseURLs() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("ParseURLs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
 
+func TestParseURLsMaxErrors(t *testing.T) {
+	input := strings.Repeat("invalid\n", maxURLErrors+3)
+	_, err := ParseURLs(strings.NewReader("url\n"+input), "")
+	if err == nil || !strings.HasSuffix(err.Error(), "and 3 more invalid urls") {
+		t.Errorf("ParseURLs() error = %v", err)
+	}
 }
@@ -0,0 +1,71 @@
+package vcs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/history"
+)
+
+// hgTemplate prints the author and the date of every changeset, the date is
+// unix seconds and the offset in seconds west of UTC.
+const hgTemplate = "{author}\\x1f{date|hgdate}\\n"
+
+// ParseHgLog parses the output of `hg log` with hgTemplate.
+func ParseHgLog(data []byte) []history.Commit {
+	var commits []history.Commit
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		author, date, ok := strings.Cut(scanner.Text(), "\x1f")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(date)
+		if len(fields) != 2 {
+			continue
+		}
+		sec, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		offset, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		name, email := parseAuthor(author)
+		commits = append(commits, history.Commit{
+			Author: name,
+			Email:  email,
+			When:   time.Unix(sec, 0).In(time.FixedZone("", -offset)),
+		})
+	}
+	return commits
+}
+
+// HgLog clones the remote Mercurial repo into dir without a working copy,
+// or pulls it if already cloned, and reads its whole log.
+func HgLog(ctx context.Context, remote string, dir string) ([]history.Commit, error) {
+	if _, err := os.Stat(filepath.Join(dir, ".hg")); err == nil {
+		if _, err := run(ctx, dir, "hg", "pull", "--noninteractive", remote); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+			return nil, err
+		}
+		if _, err := run(ctx, "", "hg", "clone", "--noupdate", "--noninteractive", remote, dir); err != nil {
+			return nil, err
+		}
+	}
+	out, err := run(ctx, dir, "hg", "log", "--template", hgTemplate)
+	if err != nil {
+		return nil, err
+	}
+	return ParseHgLog(out), nil
+}
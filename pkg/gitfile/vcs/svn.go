@@ -0,0 +1,46 @@
+package vcs
+
+import (
+	"context"
+	"encoding/xml"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/history"
+)
+
+type svnLog struct {
+	Entries []struct {
+		Author string `xml:"author"`
+		Date   string `xml:"date"`
+	} `xml:"logentry"`
+}
+
+// ParseSvnLog parses the output of `svn log --xml`, revisions without an
+// author or a date, e.g. the ones created by cvs2svn, are skipped.
+// Subversion records dates in UTC, so the timezones of the authors are
+// unknown.
+func ParseSvnLog(data []byte) ([]history.Commit, error) {
+	var log svnLog
+	if err := xml.Unmarshal(data, &log); err != nil {
+		return nil, err
+	}
+	commits := make([]history.Commit, 0, len(log.Entries))
+	for _, e := range log.Entries {
+		when, err := time.Parse(time.RFC3339Nano, e.Date)
+		if err != nil || e.Author == "" {
+			continue
+		}
+		commits = append(commits, history.Commit{Author: e.Author, When: when})
+	}
+	return commits, nil
+}
+
+// SvnLog reads the whole log of the remote Subversion repo, without the
+// messages and changed paths.
+func SvnLog(ctx context.Context, remote string) ([]history.Commit, error) {
+	out, err := run(ctx, "", "svn", "log", "--xml", "--quiet", "--non-interactive", remote)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSvnLog(out)
+}
@@ -0,0 +1,119 @@
+// Package vcs computes the history metrics of repos which are not in git,
+// i.e. Subversion and Mercurial repos, from their native logs.
+//
+// Such a repo is configured by a git link with the scheme of its vcs, like
+// the vcs urls of pip, e.g. svn+https://svn.apache.org/repos/asf/subversion
+// or hg+https://hg.mozilla.org/mozilla-central. svn:// urls are Subversion
+// repos as well. Only the metrics derived from commits are computed, the
+// files are not checked out.
+package vcs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/mail"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/gitfile/history"
+	parser "github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser"
+	git "github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser/git"
+	url "github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser/url"
+)
+
+type Kind string
+
+const (
+	KindGit        Kind = "git"
+	KindSubversion Kind = "svn"
+	KindMercurial  Kind = "hg"
+)
+
+// KindOf returns the vcs of the link.
+func KindOf(u *url.RepoURL) Kind {
+	if len(u.Protocols) > 0 {
+		switch strings.ToLower(u.Protocols[0]) {
+		case "svn":
+			return KindSubversion
+		case "hg":
+			return KindMercurial
+		}
+	}
+	return KindGit
+}
+
+// RemoteURL returns the url understood by the vcs, i.e. without the vcs
+// prefix, e.g. https://hg.mozilla.org/mozilla-central. svn+ssh:// is a
+// native scheme of Subversion and kept.
+func RemoteURL(u *url.RepoURL) string {
+	link := strings.TrimSpace(u.URL)
+	if len(u.Protocols) > 1 && !strings.EqualFold(u.Protocols[0]+"+"+u.Protocols[1], "svn+ssh") {
+		return link[strings.Index(link, "+")+1:]
+	}
+	return link
+}
+
+// parseAuthor splits an author like "Name <email>".
+func parseAuthor(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	if a, err := mail.ParseAddress(s); err == nil {
+		return a.Name, a.Address
+	}
+	return s, ""
+}
+
+// Parse reads the log of the svn or hg repo at u and returns its history
+// metrics. Mercurial repos are cloned into dir without a working copy, or
+// pulled if already cloned, Subversion logs are read remotely.
+func Parse(ctx context.Context, u *url.RepoURL, dir string) (*git.Repo, error) {
+	remote := RemoteURL(u)
+	var commits []history.Commit
+	var err error
+	switch KindOf(u) {
+	case KindSubversion:
+		commits, err = SvnLog(ctx, remote)
+	case KindMercurial:
+		commits, err = HgLog(ctx, remote, dir)
+	default:
+		return nil, fmt.Errorf("%s is a git repo", u.URL)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	repo := git.NewRepo()
+	repo.URL = remote
+	repo.Source = u.Resource
+	path := strings.Split(strings.Trim(u.Pathname, "/"), "/")
+	repo.Name = path[len(path)-1]
+	if len(path) > 1 {
+		repo.Owner = path[len(path)-2]
+	}
+	m, err := history.Summarize(commits, parser.NOW, history.DefaultWindow)
+	if err != nil {
+		return nil, err
+	}
+	repo.SetHistory(m)
+	repo.Commits = commits
+	if KindOf(u) == KindSubversion {
+		// all dates are in UTC
+		repo.TimezoneDiversity = 0
+	}
+	return &repo, nil
+}
+
+func run(ctx context.Context, dir string, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	// the output of hg must not depend on the config of the user
+	cmd.Env = append(os.Environ(), "HGPLAIN=1")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w: %s", name, args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
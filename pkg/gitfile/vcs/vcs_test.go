@@ -0,0 +1,64 @@
+package vcs
+
+import (
+	"testing"
+
+	url "github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser/url"
+)
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		input  string
+		kind   Kind
+		remote string
+	}{
+		{"svn+https://svn.apache.org/repos/asf/subversion", KindSubversion, "https://svn.apache.org/repos/asf/subversion"},
+		{"svn://svn.example.org/project/trunk", KindSubversion, "svn://svn.example.org/project/trunk"},
+		{"svn+ssh://svn.example.org/project", KindSubversion, "svn+ssh://svn.example.org/project"},
+		{"hg+https://hg.mozilla.org/mozilla-central", KindMercurial, "https://hg.mozilla.org/mozilla-central"},
+		{"https://github.com/gin-gonic/gin.git", KindGit, "https://github.com/gin-gonic/gin.git"},
+		{"git+https://example.org/project.git", KindGit, "https://example.org/project.git"},
+	}
+	for _, tt := range tests {
+		u := url.ParseURL(tt.input)
+		if got := KindOf(&u); got != tt.kind {
+			t.Errorf("KindOf(%q) = %v, want %v", tt.input, got, tt.kind)
+		}
+		if got := RemoteURL(&u); got != tt.remote {
+			t.Errorf("RemoteURL(%q) = %q, want %q", tt.input, got, tt.remote)
+		}
+	}
+}
+
+func TestParseSvnLog(t *testing.T) {
+	data := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<log>
+<logentry revision="3"><author>alice</author><date>2024-06-01T10:00:00.000000Z</date></logentry>
+<logentry revision="2"><date>2024-05-01T10:00:00.000000Z</date></logentry>
+<logentry revision="1"><author>bob</author><date>2010-01-01T10:00:00.000000Z</date></logentry>
+</log>`)
+	commits, err := ParseSvnLog(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 2 || commits[0].Author != "alice" || commits[1].When.Year() != 2010 {
+		t.Errorf("ParseSvnLog() = %+v", commits)
+	}
+}
+
+func TestParseHgLog(t *testing.T) {
+	data := []byte("Alice <alice@example.com>\x1f1717236000 -28800\nbob\x1f1262340000 0\ngarbage\n")
+	commits := ParseHgLog(data)
+	if len(commits) != 2 {
+		t.Fatalf("ParseHgLog() = %+v", commits)
+	}
+	if commits[0].Author != "Alice" || commits[0].Email != "alice@example.com" {
+		t.Errorf("ParseHgLog() author = %q %q", commits[0].Author, commits[0].Email)
+	}
+	if _, offset := commits[0].When.Zone(); offset != 8*3600 {
+		t.Errorf("ParseHgLog() offset = %d, want +8h", offset)
+	}
+	if commits[1].Author != "bob" || commits[1].Email != "" {
+		t.Errorf("ParseHgLog() author = %q %q", commits[1].Author, commits[1].Email)
+	}
+}
@@ -0,0 +1,319 @@
+// Package normalize canonicalizes the urls of git repos, so the links of a
+// repo found in different sources are the same, e.g.
+// git@github.com:Foo/Bar.git, https://www.github.com/Foo/Bar/tree/main and
+// git+https://github.com/Foo/Bar.git#readme are all
+// https://github.com/Foo/Bar.
+//
+// URL returns the canonical url, CloneURL the url to clone and Key the key
+// to compare links by, which ignores the case of paths on the forges whose
+// paths are case insensitive.
+package normalize
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/errors"
+	giturl "github.com/HUSTSecLab/criticality_score/pkg/gitfile/parser/url"
+)
+
+var (
+	// ErrInvalid is wrapped by errors of links which are not urls.
+	ErrInvalid = errors.Mark(errors.New("invalid repo url"), errors.ParseError)
+	// ErrUnsupported is wrapped by errors of urls which are not of git
+	// repos, e.g. svn+https:// or ftp:// urls, or forge pages which are not
+	// in a repo, e.g. https://github.com/foo.
+	ErrUnsupported = errors.New("unsupported repo url")
+)
+
+// forges are the hosts whose repos are at <host>/<owner>/<repo>, with
+// whether their paths are case insensitive.
+var forges = map[string]bool{
+	"github.com":    true,
+	"gitee.com":     true,
+	"bitbucket.org": true,
+	"codeberg.org":  true,
+	// sourcehut repos are at git.sr.ht/~<user>/<repo>
+	"git.sr.ht": false,
+}
+
+// gitlabs are the GitLab instances, whose repos may be in subgroups, e.g.
+// gitlab.com/<group>/<subgroup>/<repo>. GitLab paths are case insensitive.
+var gitlabs = map[string]bool{
+	"gitlab.com":             true,
+	"salsa.debian.org":       true,
+	"gitlab.gnome.org":       true,
+	"gitlab.freedesktop.org": true,
+	"invent.kde.org":         true,
+	"gitlab.haskell.org":     true,
+	"framagit.org":           true,
+	"gitlab.archlinux.org":   true,
+}
+
+// hostAliases are hosts serving the repos of a forge under another name.
+var hostAliases = map[string]string{
+	"codeload.github.com":       "github.com",
+	"raw.githubusercontent.com": "github.com",
+	"ssh.github.com":            "github.com",
+	"altssh.gitlab.com":         "gitlab.com",
+	"altssh.bitbucket.org":      "bitbucket.org",
+}
+
+// shorthands are the repo shorthands of npm, e.g. github:foo/bar.
+var shorthands = map[string]string{
+	"github":    "github.com",
+	"gitlab":    "gitlab.com",
+	"bitbucket": "bitbucket.org",
+	"gist":      "",
+}
+
+// githubReserved are the first path segments of github.com which are not
+// owners.
+var githubReserved = map[string]bool{
+	"about":         true,
+	"apps":          true,
+	"collections":   true,
+	"enterprise":    true,
+	"explore":       true,
+	"features":      true,
+	"login":         true,
+	"marketplace":   true,
+	"notifications": true,
+	"orgs":          true,
+	"search":        true,
+	"settings":      true,
+	"sponsors":      true,
+	"topics":        true,
+	"users":         true,
+}
+
+// gitlabPages are the pages of older GitLab urls below a repo, without the
+// /-/ separator, e.g. gitlab.com/foo/bar/tree/main.
+var gitlabPages = map[string]bool{
+	"blob":           true,
+	"commits":        true,
+	"issues":         true,
+	"merge_requests": true,
+	"raw":            true,
+	"tags":           true,
+	"tree":           true,
+	"wikis":          true,
+}
+
+// hostPattern matches host names with a domain, e.g. github.com.
+var hostPattern = regexp.MustCompile(`^[\w-]+(?:\.[\w-]+)+$`)
+
+// scpPattern matches scp-like ssh urls, e.g. git@github.com:foo/bar.git.
+var scpPattern = regexp.MustCompile(`^(?:[\w.+-]+@)?([\w-]+(?:\.[\w-]+)+):([^/].*)$`)
+
+// IsForge reports whether host is a known forge or GitLab instance, whose
+// repos are cut from the paths of their pages.
+func IsForge(host string) bool {
+	host = canonicalHost(host)
+	_, forge := forges[host]
+	return forge || gitlabs[host]
+}
+
+func canonicalHost(host string) string {
+	host = strings.TrimPrefix(strings.ToLower(strings.TrimSuffix(host, ".")), "www.")
+	if alias, ok := hostAliases[host]; ok {
+		return alias
+	}
+	return host
+}
+
+// parse returns link as an url with a scheme, scp-like urls, npm shorthands
+// and urls without a scheme are rewritten, e.g. git@github.com:foo/bar to
+// ssh://git@github.com/foo/bar and github.com/foo/bar to
+// https://github.com/foo/bar.
+func parse(link string) (*url.URL, error) {
+	link = strings.TrimSpace(link)
+	if link == "" {
+		return nil, fmt.Errorf("%w: empty link", ErrInvalid)
+	}
+	if !strings.Contains(link, "://") {
+		if scheme, rest, ok := strings.Cut(link, ":"); ok {
+			if host, ok := shorthands[scheme]; ok {
+				if host == "" {
+					return nil, fmt.Errorf("%w: %q", ErrUnsupported, link)
+				}
+				link = "https://" + host + "/" + strings.TrimPrefix(rest, "/")
+			}
+		}
+	}
+	if !strings.Contains(link, "://") {
+		if m := scpPattern.FindStringSubmatch(link); m != nil {
+			link = "ssh://" + strings.TrimSuffix(link, ":"+m[2]) + "/" + m[2]
+		} else if first, _, _ := strings.Cut(link, "/"); hostPattern.MatchString(first) {
+			link = "https://" + link
+		} else {
+			return nil, fmt.Errorf("%w: %q has no scheme", ErrInvalid, link)
+		}
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+	if u.Host == "" || u.Hostname() == "" {
+		return nil, fmt.Errorf("%w: %q has no host", ErrInvalid, link)
+	}
+	return u, nil
+}
+
+// URL returns the canonical url of the repo of link:
+//
+//   - ssh, scp-like, git+https and git+ssh urls are https urls, git:// urls
+//     are https urls on forges and kept on other hosts
+//   - the user, port, query and fragment are dropped, the host is lower case
+//     and without www.
+//   - on forges, the url is cut to the repo and .git is dropped, e.g.
+//     https://github.com/foo/bar/tree/main is https://github.com/foo/bar;
+//     GitLab repos keep their subgroups, up to the /-/ separator of pages
+//   - on other hosts, gitweb and cgit pages are mapped to the repo by
+//     url.CloneURL of gitfile/parser/url and the path is kept otherwise,
+//     with .git as some servers need it
+//
+// The case of the path is kept, see Key to compare links.
+func URL(link string) (string, error) {
+	u, err := parse(link)
+	if err != nil {
+		return "", err
+	}
+	scheme := strings.ToLower(u.Scheme)
+	scheme = strings.TrimPrefix(scheme, "git+")
+	switch scheme {
+	case "http", "https", "ssh", "git":
+	default:
+		return "", fmt.Errorf("%w: %q is not a git url", ErrUnsupported, link)
+	}
+	host := canonicalHost(u.Hostname())
+
+	var parts []string
+	for _, p := range strings.Split(u.Path, "/") {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+
+	if _, ok := forges[host]; ok {
+		if len(parts) < 2 || (host == "github.com" && githubReserved[strings.ToLower(parts[0])]) ||
+			(host == "git.sr.ht" && !strings.HasPrefix(parts[0], "~")) {
+			return "", fmt.Errorf("%w: %q is not in a repo", ErrUnsupported, link)
+		}
+		return repoURL(host, parts[:2])
+	}
+	if gitlabs[host] {
+		end := len(parts)
+		for i, p := range parts {
+			if p == "-" || (i >= 2 && gitlabPages[p]) {
+				end = i
+				break
+			}
+			if strings.HasSuffix(p, ".git") {
+				end = i + 1
+				break
+			}
+		}
+		if end < 2 {
+			return "", fmt.Errorf("%w: %q is not in a repo", ErrUnsupported, link)
+		}
+		return repoURL(host, parts[:end])
+	}
+
+	// forge-less hosts
+	if len(parts) == 0 && !strings.Contains(u.RawQuery, "p=") {
+		return "", fmt.Errorf("%w: %q has no path", ErrUnsupported, link)
+	}
+	if scheme == "git" {
+		return "git://" + host + "/" + strings.Join(parts, "/"), nil
+	}
+	// the path is kept as it is for gitweb, whose repo is next to the script
+	clean := &url.URL{Scheme: "https", Host: host, Path: u.Path, RawQuery: u.RawQuery}
+	if cloneURL, ok := giturl.CloneURL(clean.String()); ok {
+		return cloneURL, nil
+	}
+	clean.Path, clean.RawQuery = "/"+strings.Join(parts, "/"), ""
+	return clean.String(), nil
+}
+
+func repoURL(host string, parts []string) (string, error) {
+	last := len(parts) - 1
+	parts[last] = strings.TrimSuffix(parts[last], ".git")
+	if parts[last] == "" {
+		return "", fmt.Errorf("%w: empty repo name", ErrUnsupported)
+	}
+	return "https://" + host + "/" + strings.Join(parts, "/"), nil
+}
+
+// CloneURL returns the canonical url of link to clone, the URL with .git on
+// forges, e.g. https://github.com/foo/bar.git.
+func CloneURL(link string) (string, error) {
+	ret, err := URL(link)
+	if err != nil {
+		return "", err
+	}
+	if u, _ := url.Parse(ret); IsForge(u.Host) {
+		ret += ".git"
+	}
+	return ret, nil
+}
+
+// Key returns the key of the repo of link, links of the same repo have the
+// same key, e.g. github.com/foo/bar for https://github.com/Foo/Bar.git. The
+// path is lower case on forges whose paths are case insensitive.
+func Key(link string) (string, error) {
+	ret, err := URL(link)
+	if err != nil {
+		return "", err
+	}
+	u, _ := url.Parse(ret)
+	path := u.Path
+	if forges[u.Host] || gitlabs[u.Host] {
+		path = strings.ToLower(path)
+	} else if !IsForge(u.Host) {
+		path = strings.TrimSuffix(path, ".git")
+	}
+	return u.Host + path, nil
+}
+
+// Owner returns the organization or user owning the repo of link: the host
+// and the first path segment on forges, e.g. github.com/curl for
+// https://github.com/curl/curl and gitlab.gnome.org/gnome for the repos of
+// its subgroups, in lower case on forges whose paths are case insensitive.
+// The repos of other hosts are owned by the host, e.g. git.kernel.org,
+// which mostly serves the repos of one community.
+func Owner(link string) (string, error) {
+	ret, err := URL(link)
+	if err != nil {
+		return "", err
+	}
+	u, _ := url.Parse(ret)
+	if !IsForge(u.Host) {
+		return u.Host, nil
+	}
+	owner, _, _ := strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
+	if forges[u.Host] || gitlabs[u.Host] {
+		owner = strings.ToLower(owner)
+	}
+	return u.Host + "/" + owner, nil
+}
+
+// Repo returns the clone url of link if it points to a repo: a page of a
+// repo on a forge, e.g. a release download, or a forge-less clone url or
+// repo page, see url.CloneURL of gitfile/parser/url. Other links, e.g.
+// tarballs of ftp.gnu.org, are not repos.
+func Repo(link string) (string, bool) {
+	u, err := parse(link)
+	if err != nil {
+		return "", false
+	}
+	if !IsForge(u.Hostname()) {
+		if _, ok := giturl.CloneURL(link); !ok {
+			return "", false
+		}
+	}
+	ret, err := CloneURL(link)
+	return ret, err == nil
+}
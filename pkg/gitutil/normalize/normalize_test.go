@@ -0,0 +1,135 @@
+package normalize
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestCorpus checks URL and Key against testdata/corpus.tsv, whose lines are
+// the input, the url and the key, ERR if the input is rejected.
+func TestCorpus(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "corpus.tsv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	n := 0
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.HasPrefix(scanner.Text(), "#") {
+			continue
+		}
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 3 {
+			t.Fatalf("corpus.tsv:%d: %d fields, want 3", line, len(fields))
+		}
+		in, wantURL, wantKey := fields[0], fields[1], fields[2]
+		n++
+
+		got, err := URL(in)
+		if wantURL == "ERR" {
+			if err == nil {
+				t.Errorf("corpus.tsv:%d: URL(%q) = %q, want an error", line, in, got)
+			}
+		} else if err != nil || got != wantURL {
+			t.Errorf("corpus.tsv:%d: URL(%q) = %q, %v, want %q", line, in, got, err, wantURL)
+		}
+
+		got, err = Key(in)
+		if wantKey == "ERR" {
+			if err == nil {
+				t.Errorf("corpus.tsv:%d: Key(%q) = %q, want an error", line, in, got)
+			}
+		} else if err != nil || got != wantKey {
+			t.Errorf("corpus.tsv:%d: Key(%q) = %q, %v, want %q", line, in, got, err, wantKey)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n < 100 {
+		t.Errorf("corpus.tsv has %d cases", n)
+	}
+}
+
+func TestCloneURL(t *testing.T) {
+	tests := []struct {
+		link, want string
+	}{
+		{"git@github.com:Foo/Bar.git", "https://github.com/Foo/Bar.git"},
+		{"https://gitlab.com/group/sub/project/-/tree/main", "https://gitlab.com/group/sub/project.git"},
+		{"https://git.sr.ht/~sircmpwn/hare", "https://git.sr.ht/~sircmpwn/hare.git"},
+		{"https://git.kernel.org/pub/scm/git/git.git/tree/", "https://git.kernel.org/pub/scm/git/git.git"},
+		{"git://git.kernel.org/pub/scm/git/git.git", "git://git.kernel.org/pub/scm/git/git.git"},
+		{"https://git.savannah.gnu.org/git/emacs", "https://git.savannah.gnu.org/git/emacs"},
+	}
+	for _, tt := range tests {
+		if got, err := CloneURL(tt.link); err != nil || got != tt.want {
+			t.Errorf("CloneURL(%q) = %q, %v, want %q", tt.link, got, err, tt.want)
+		}
+	}
+}
+
+func TestRepo(t *testing.T) {
+	tests := []struct {
+		link, want string
+		ok         bool
+	}{
+		{"https://github.com/jqlang/jq/releases/download/jq-1.7.1/jq-1.7.1.tar.gz", "https://github.com/jqlang/jq.git", true},
+		{"https://codeload.github.com/facebook/react/tar.gz/v18.3.1", "https://github.com/facebook/react.git", true},
+		{"https://gitlab.gnome.org/GNOME/glib/-/archive/2.80.0/glib-2.80.0.tar.gz", "https://gitlab.gnome.org/GNOME/glib.git", true},
+		{"https://bitbucket.org/multicoreware/x265_git/get/3.5.tar.gz", "https://bitbucket.org/multicoreware/x265_git.git", true},
+		{"https://git.kernel.org/pub/scm/git/git.git/snapshot/git-2.45.0.tar.gz", "https://git.kernel.org/pub/scm/git/git.git", true},
+		{"https://sourceware.org/git/?p=glibc.git;a=summary", "https://sourceware.org/git/glibc.git", true},
+		{"https://ftp.gnu.org/gnu/bash/bash-5.2.tar.gz", "", false},
+		{"https://www.openssl.org/source/openssl-3.3.0.tar.gz", "", false},
+		{"https://github.com/facebook", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := Repo(tt.link)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Repo(%q) = %q, %v, want %q, %v", tt.link, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestOwner(t *testing.T) {
+	tests := []struct {
+		link, want string
+	}{
+		{"git@github.com:Curl/curl.git", "github.com/curl"},
+		{"https://gitlab.gnome.org/GNOME/glib/-/tree/main", "gitlab.gnome.org/gnome"},
+		{"https://gitlab.com/group/sub/project", "gitlab.com/group"},
+		{"https://git.sr.ht/~SirCmpwn/hare", "git.sr.ht/~SirCmpwn"},
+		{"https://git.kernel.org/pub/scm/git/git.git", "git.kernel.org"},
+	}
+	for _, tt := range tests {
+		if got, err := Owner(tt.link); err != nil || got != tt.want {
+			t.Errorf("Owner(%q) = %q, %v, want %q", tt.link, got, err, tt.want)
+		}
+	}
+	if got, err := Owner("https://github.com/facebook"); err == nil {
+		t.Errorf("Owner() of an owner page = %q, want an error", got)
+	}
+}
+
+func TestIsForge(t *testing.T) {
+	for host, want := range map[string]bool{
+		"github.com":          true,
+		"WWW.GitHub.com":      true,
+		"codeload.github.com": true,
+		"salsa.debian.org":    true,
+		"git.sr.ht":           true,
+		"git.kernel.org":      false,
+		"example.org":         false,
+	} {
+		if got := IsForge(host); got != want {
+			t.Errorf("IsForge(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package graph
+
+import (
+	"sort"
+)
+
+// interval is the closed range [lo, hi] of labels.
+type interval struct {
+	lo, hi int32
+}
+
+// Ancestors is a compressed closure table of the transitive dependents,
+// the ancestors, of every node, which answers dependent counts and
+// reachability without walking the graph.
+//
+// Nodes of an SCC share their ancestors, so the table is kept per
+// component of the condensation. Components are labelled in post-order of a
+// depth first search over the dependents, so the ancestors found through
+// the search tree have consecutive labels, and the ancestors of a
+// component are stored as a few merged label intervals instead of a list
+// of nodes (Agrawal, Borgida and Jagadish, 1989).
+//
+// The table is built once from a graph and is not updated by changes of
+// the graph afterwards, a new table is built instead. It is safe for
+// concurrent queries.
+type Ancestors struct {
+	comp map[string]int32
+	// size, label and sets are indexed by component, sets are the labels
+	// of its ancestors, excluding itself, sorted and merged
+	size  []int
+	label []int32
+	sets  [][]interval
+	// weight[l] is the number of nodes of the components labelled below l
+	weight  []int
+	byLabel []int32
+}
+
+// NewAncestors builds the closure table of g.
+func NewAncestors(g *Graph) *Ancestors {
+	a := &Ancestors{}
+	a.build(g)
+	return a
+}
+
+// build computes the table of g.
+func (a *Ancestors) build(g *Graph) {
+	sccs := g.StronglyConnectedComponents()
+	n := len(sccs)
+	a.comp = make(map[string]int32, g.NodeCount())
+	a.size = make([]int, n)
+	for c, members := range sccs {
+		a.size[c] = len(members)
+		for _, name := range members {
+			a.comp[name] = int32(c)
+		}
+	}
+
+	// deps are the components each component depends on
+	deps := make([]map[int32]struct{}, n)
+	dependents := make([][]int32, n)
+	for c := range deps {
+		deps[c] = make(map[int32]struct{})
+	}
+	for _, members := range sccs {
+		for _, from := range members {
+			cf := a.comp[from]
+			for _, to := range g.Dependencies(from) {
+				ct := a.comp[to]
+				if ct == cf {
+					continue
+				}
+				if _, ok := deps[cf][ct]; !ok {
+					deps[cf][ct] = struct{}{}
+					dependents[ct] = append(dependents[ct], cf)
+				}
+			}
+		}
+	}
+
+	// post-order labels of a depth first search over the dependents,
+	// iterative, the graph may be too deep for recursion
+	a.label = make([]int32, n)
+	a.byLabel = make([]int32, 0, n)
+	visited := make([]bool, n)
+	type frame struct {
+		c int32
+		i int
+	}
+	for root := range sccs {
+		if visited[root] {
+			continue
+		}
+		visited[root] = true
+		stack := []frame{{c: int32(root)}}
+		for len(stack) > 0 {
+			f := &stack[len(stack)-1]
+			if f.i < len(dependents[f.c]) {
+				d := dependents[f.c][f.i]
+				f.i++
+				if !visited[d] {
+					visited[d] = true
+					stack = append(stack, frame{c: d})
+				}
+				continue
+			}
+			a.label[f.c] = int32(len(a.byLabel))
+			a.byLabel = append(a.byLabel, f.c)
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	// the dependents of a component have lower labels, so they are done
+	// before it
+	a.sets = make([][]interval, n)
+	for _, c := range a.byLabel {
+		var set []interval
+		for _, d := range dependents[c] {
+			set = append(set, a.sets[d]...)
+			set = append(set, interval{a.label[d], a.label[d]})
+		}
+		a.sets[c] = mergeIntervals(set)
+	}
+
+	a.weight = make([]int, n+1)
+	for l, c := range a.byLabel {
+		a.weight[l+1] = a.weight[l] + a.size[c]
+	}
+}
+
+// mergeIntervals sorts the intervals and merges the overlapping and
+// adjacent ones.
+func mergeIntervals(set []interval) []interval {
+	if len(set) == 0 {
+		return nil
+	}
+	sort.Slice(set, func(i, j int) bool { return set[i].lo < set[j].lo })
+	ret := set[:1]
+	for _, cur := range set[1:] {
+		last := &ret[len(ret)-1]
+		if cur.lo <= last.hi+1 {
+			last.hi = max(last.hi, cur.hi)
+		} else {
+			ret = append(ret, cur)
+		}
+	}
+	return ret
+}
+
+// containsLabel returns true if the merged intervals contain l.
+func containsLabel(set []interval, l int32) bool {
+	i := sort.Search(len(set), func(i int) bool { return set[i].hi >= l })
+	return i < len(set) && set[i].lo <= l
+}
+
+// condensedCount returns the number of nodes depending on the component,
+// excluding its own members.
+func (a *Ancestors) condensedCount(c int32) int {
+	n := 0
+	for _, s := range a.sets[c] {
+		n += a.weight[s.hi+1] - a.weight[s.lo]
+	}
+	return n
+}
+
+// Count returns the number of nodes depending on the node directly or
+// transitively, excluding the node itself, the same as the length of
+// Graph.ReverseClosure. Unknown nodes have no dependents.
+func (a *Ancestors) Count(name string) int {
+	c, ok := a.comp[name]
+	if !ok {
+		return 0
+	}
+	return a.condensedCount(c) + a.size[c] - 1
+}
+
+// Counts returns the counts of all the nodes, the same as
+// Graph.TransitiveDependentCounts.
+func (a *Ancestors) Counts(condense bool) map[string]int {
+	counts := make([]int, len(a.size))
+	for c := range counts {
+		counts[c] = a.condensedCount(int32(c))
+	}
+	ret := make(map[string]int, len(a.comp))
+	for name, c := range a.comp {
+		ret[name] = counts[c]
+		if !condense {
+			ret[name] += a.size[c] - 1
+		}
+	}
+	return ret
+}
+
+// DependsOn returns true if from depends on to directly or transitively,
+// that is to is in Graph.Closure of from, which excludes the node itself.
+func (a *Ancestors) DependsOn(from, to string) bool {
+	if from == to {
+		return false
+	}
+	cf, ok := a.comp[from]
+	if !ok {
+		return false
+	}
+	ct, ok := a.comp[to]
+	if !ok {
+		return false
+	}
+	if cf == ct {
+		return true
+	}
+	return containsLabel(a.sets[ct], a.label[cf])
+}
+
+// IntervalCount returns the number of intervals stored by the table, which
+// is at most the number of edges of the closure, and usually far less.
+func (a *Ancestors) IntervalCount() int {
+	n := 0
+	for _, set := range a.sets {
+		n += len(set)
+	}
+	return n
+}
@@ -0,0 +1,89 @@
+package graph
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// naiveCounts counts the dependents of every node by walking the graph.
+func naiveCounts(g *Graph, condense bool) map[string]int {
+	_, component := g.Condense()
+	ret := make(map[string]int)
+	for _, name := range g.Nodes() {
+		n := 0
+		for _, d := range g.ReverseClosure(name) {
+			if !condense || component[d] != component[name] {
+				n++
+			}
+		}
+		ret[name] = n
+	}
+	return ret
+}
+
+func randomGraph(r *rand.Rand, nodes, edges int) *Graph {
+	g := New()
+	for i := 0; i < nodes; i++ {
+		g.AddNode(fmt.Sprint(i))
+	}
+	for i := 0; i < edges; i++ {
+		g.AddEdge(fmt.Sprint(r.Intn(nodes)), fmt.Sprint(r.Intn(nodes)))
+	}
+	return g
+}
+
+func checkAncestors(t *testing.T, g *Graph) {
+	t.Helper()
+	a := NewAncestors(g)
+	for _, condense := range []bool{false, true} {
+		if got, want := a.Counts(condense), naiveCounts(g, condense); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Counts(%v) = %v, want %v", condense, got, want)
+		}
+	}
+	for _, from := range g.Nodes() {
+		reach := make(map[string]bool)
+		for _, to := range g.Closure(from) {
+			reach[to] = true
+		}
+		for _, to := range g.Nodes() {
+			if got := a.DependsOn(from, to); got != reach[to] {
+				t.Fatalf("DependsOn(%s, %s) = %v, want %v", from, to, got, reach[to])
+			}
+		}
+	}
+}
+
+func TestAncestors(t *testing.T) {
+	g := newTestGraph()
+	a := NewAncestors(g)
+	if got := a.Count("libc"); got != 4 {
+		t.Errorf("Count(libc) = %d, want 4", got)
+	}
+	if got := a.Count("missing"); got != 0 {
+		t.Errorf("Count(missing) = %d, want 0", got)
+	}
+	checkAncestors(t, g)
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 20; i++ {
+		checkAncestors(t, randomGraph(r, 30, 20+i*3))
+	}
+}
+
+func TestAncestorsCompression(t *testing.T) {
+	// a chain depending on a common root, every node has all the nodes
+	// above it as ancestors, which is a single interval
+	g := New()
+	for i := 1; i < 100; i++ {
+		g.AddEdge(fmt.Sprint(i), fmt.Sprint(i-1))
+	}
+	a := NewAncestors(g)
+	if got := a.IntervalCount(); got != 99 {
+		t.Errorf("IntervalCount() = %d, want 99", got)
+	}
+	if got := a.Count("0"); got != 99 {
+		t.Errorf("Count(0) = %d, want 99", got)
+	}
+}
@@ -0,0 +1,256 @@
+package graph
+
+import (
+	"runtime"
+	"slices"
+	"sort"
+)
+
+// Compact is an immutable dependency graph in compressed sparse row form,
+// which takes a fraction of the memory of Graph and can be memory-mapped
+// from a file, see WriteFile and OpenCompact. Nodes are numbered in the
+// order of their sorted names, the dependencies of node i are
+// deps[depOffsets[i]:depOffsets[i+1]] and its dependents are
+// revs[revOffsets[i]:revOffsets[i+1]], both sorted.
+//
+// A Compact is safe for concurrent use.
+type Compact struct {
+	names      []string
+	index      map[string]uint32
+	depOffsets []uint32
+	deps       []uint32
+	revOffsets []uint32
+	revs       []uint32
+
+	// unmap releases the memory-mapped file of the adjacency, if any
+	unmap func() error
+}
+
+// Reachable is a node reachable from another one within Depth hops.
+type Reachable struct {
+	Name  string
+	Depth int
+}
+
+// NewCompact returns the compact form of g.
+func NewCompact(g *Graph) *Compact {
+	c := &Compact{names: g.Nodes()}
+	c.buildIndex()
+	n := len(c.names)
+	c.depOffsets = make([]uint32, n+1)
+	c.revOffsets = make([]uint32, n+1)
+	c.deps = make([]uint32, 0, g.EdgeCount())
+	c.revs = make([]uint32, 0, g.EdgeCount())
+	for i, name := range c.names {
+		// sorted names have sorted indexes
+		for _, dep := range g.Dependencies(name) {
+			c.deps = append(c.deps, c.index[dep])
+		}
+		for _, rev := range g.Dependents(name) {
+			c.revs = append(c.revs, c.index[rev])
+		}
+		c.depOffsets[i+1] = uint32(len(c.deps))
+		c.revOffsets[i+1] = uint32(len(c.revs))
+	}
+	return c
+}
+
+func (c *Compact) buildIndex() {
+	c.index = make(map[string]uint32, len(c.names))
+	for i, name := range c.names {
+		c.index[name] = uint32(i)
+	}
+}
+
+// Close releases the memory-mapped file of the graph, the graph must not be
+// used afterwards. Graphs not memory-mapped need not be closed, and mapped
+// graphs are also released when they are garbage collected.
+func (c *Compact) Close() error {
+	if c.unmap == nil {
+		return nil
+	}
+	runtime.SetFinalizer(c, nil)
+	unmap := c.unmap
+	c.unmap = nil
+	c.depOffsets, c.deps, c.revOffsets, c.revs = nil, nil, nil, nil
+	return unmap()
+}
+
+func (c *Compact) NodeCount() int {
+	return len(c.names)
+}
+
+func (c *Compact) EdgeCount() int {
+	return len(c.deps)
+}
+
+func (c *Compact) HasNode(name string) bool {
+	_, ok := c.index[name]
+	return ok
+}
+
+// Nodes returns the names of all nodes, sorted.
+func (c *Compact) Nodes() []string {
+	return append([]string(nil), c.names...)
+}
+
+func (c *Compact) namesOf(ids []uint32) []string {
+	ret := make([]string, len(ids))
+	for i, id := range ids {
+		ret[i] = c.names[id]
+	}
+	return ret
+}
+
+// Dependencies returns the direct dependencies of the node.
+func (c *Compact) Dependencies(name string) []string {
+	i, ok := c.index[name]
+	if !ok {
+		return []string{}
+	}
+	defer runtime.KeepAlive(c)
+	return c.namesOf(c.deps[c.depOffsets[i]:c.depOffsets[i+1]])
+}
+
+// Dependents returns the nodes directly depending on the node.
+func (c *Compact) Dependents(name string) []string {
+	i, ok := c.index[name]
+	if !ok {
+		return []string{}
+	}
+	defer runtime.KeepAlive(c)
+	return c.namesOf(c.revs[c.revOffsets[i]:c.revOffsets[i+1]])
+}
+
+// bfs visits the nodes reachable from start by the adjacency within
+// maxDepth hops, 0 is unlimited, breadth first. visit is called once per
+// node with the node it was reached from, and stops the search if it
+// returns false. Every level is visited in the order of names, like the
+// queries of DistRelationshipRepository, so both find the same paths.
+func (c *Compact) bfs(start uint32, offsets, adj []uint32, maxDepth int, visit func(node, from uint32, depth int) bool) {
+	visited := make([]bool, len(c.names))
+	visited[start] = true
+	frontier := []uint32{start}
+	for depth := 1; len(frontier) > 0 && (maxDepth <= 0 || depth <= maxDepth); depth++ {
+		var next []uint32
+		for _, cur := range frontier {
+			for _, n := range adj[offsets[cur]:offsets[cur+1]] {
+				if visited[n] {
+					continue
+				}
+				visited[n] = true
+				if !visit(n, cur, depth) {
+					return
+				}
+				next = append(next, n)
+			}
+		}
+		slices.Sort(next)
+		frontier = next
+	}
+}
+
+// ReverseClosure returns all the nodes depending on the node directly or
+// transitively within maxDepth hops, 0 is unlimited, excluding the node
+// itself. They are ordered by depth, then by name.
+func (c *Compact) ReverseClosure(name string, maxDepth int) []Reachable {
+	start, ok := c.index[name]
+	if !ok {
+		return []Reachable{}
+	}
+	defer runtime.KeepAlive(c)
+	ret := make([]Reachable, 0)
+	c.bfs(start, c.revOffsets, c.revs, maxDepth, func(node, _ uint32, depth int) bool {
+		ret = append(ret, Reachable{Name: c.names[node], Depth: depth})
+		return true
+	})
+	sort.SliceStable(ret, func(i, j int) bool {
+		if ret[i].Depth != ret[j].Depth {
+			return ret[i].Depth < ret[j].Depth
+		}
+		return ret[i].Name < ret[j].Name
+	})
+	return ret
+}
+
+// ShortestPath returns the shortest dependency path from `from` to `to`,
+// including both ends, nil if `to` is not reachable within maxDepth hops,
+// 0 is unlimited. Ties are broken by the smaller names.
+func (c *Compact) ShortestPath(from, to string, maxDepth int) []string {
+	if from == to {
+		return []string{from}
+	}
+	start, ok := c.index[from]
+	if !ok {
+		return nil
+	}
+	end, ok := c.index[to]
+	if !ok {
+		return nil
+	}
+	defer runtime.KeepAlive(c)
+	parent := make(map[uint32]uint32)
+	found := false
+	c.bfs(start, c.depOffsets, c.deps, maxDepth, func(node, prev uint32, _ int) bool {
+		parent[node] = prev
+		found = node == end
+		return !found
+	})
+	if !found {
+		return nil
+	}
+	path := []string{to}
+	for cur := end; cur != start; {
+		cur = parent[cur]
+		path = append(path, c.names[cur])
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// PageRank is the same as Graph.PageRank, with the same results.
+func (c *Compact) PageRank(maxIterations int, dampingFactor float64) map[string]float64 {
+	defer runtime.KeepAlive(c)
+	n := len(c.names)
+	rank := make([]float64, n)
+	for i := range rank {
+		rank[i] = 1.0 / float64(n)
+	}
+	for it := 0; it < maxIterations; it++ {
+		next := make([]float64, n)
+		for i := range next {
+			next[i] = (1 - dampingFactor) / float64(n)
+		}
+		for i := 0; i < n; i++ {
+			deps := c.deps[c.depOffsets[i]:c.depOffsets[i+1]]
+			if len(deps) == 0 {
+				continue
+			}
+			share := dampingFactor * rank[i] / float64(len(deps))
+			for _, dep := range deps {
+				next[dep] += share
+			}
+		}
+		rank = next
+	}
+	ret := make(map[string]float64, n)
+	for i, name := range c.names {
+		ret[name] = rank[i]
+	}
+	return ret
+}
+
+// Graph returns a mutable copy of the graph, e.g. for Without.
+func (c *Compact) Graph() *Graph {
+	defer runtime.KeepAlive(c)
+	g := New()
+	for i, name := range c.names {
+		g.AddNode(name)
+		for _, dep := range c.deps[c.depOffsets[i]:c.depOffsets[i+1]] {
+			g.AddEdge(name, c.names[dep])
+		}
+	}
+	return g
+}
@@ -0,0 +1,165 @@
+package graph
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unsafe"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/errors"
+)
+
+// compactMagic starts the files written by WriteFile, the digit is the
+// version of the format.
+const compactMagic = "CSGRAPH1"
+
+// compactHeaderSize is the size of the magic and of the node count, edge
+// count, size of names and padding, all little-endian uint32.
+const compactHeaderSize = len(compactMagic) + 4*4
+
+// ErrInvalidCompact is returned by OpenCompact for files not written by
+// WriteFile.
+var ErrInvalidCompact = errors.Mark(errors.New("invalid compact graph file"), errors.ParseError)
+
+// WriteFile writes the graph to path, which OpenCompact can memory-map. The
+// file is written to a temporary file first and renamed, so readers never
+// see a partial file.
+func (c *Compact) WriteFile(path string) error {
+	defer runtime.KeepAlive(c)
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	names := strings.Join(c.names, "\n")
+	w := bufio.NewWriter(tmp)
+	w.WriteString(compactMagic)
+	for _, v := range []uint32{uint32(len(c.names)), uint32(len(c.deps)), uint32(len(names)), 0} {
+		binary.Write(w, binary.LittleEndian, v)
+	}
+	for _, section := range [][]uint32{c.depOffsets, c.deps, c.revOffsets, c.revs} {
+		if err := binary.Write(w, binary.LittleEndian, section); err != nil {
+			return err
+		}
+	}
+	w.WriteString(names)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// OpenCompact opens a graph written by WriteFile. The adjacency is
+// memory-mapped where supported, so it is shared by the page cache and
+// loaded lazily, the graph should be closed when no longer used.
+func OpenCompact(path string) (*Compact, error) {
+	data, unmap, err := mapFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := decodeCompact(data)
+	if err != nil {
+		if unmap != nil {
+			unmap()
+		}
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if unmap != nil {
+		c.unmap = unmap
+		runtime.SetFinalizer(c, (*Compact).Close)
+	}
+	return c, nil
+}
+
+// decodeCompact decodes data in the format of WriteFile. The adjacency
+// refers to data where the byte order allows it, names are always copied.
+func decodeCompact(data []byte) (*Compact, error) {
+	if len(data) < compactHeaderSize || string(data[:len(compactMagic)]) != compactMagic {
+		return nil, ErrInvalidCompact
+	}
+	header := data[len(compactMagic):compactHeaderSize]
+	n := uint64(binary.LittleEndian.Uint32(header[0:]))
+	m := uint64(binary.LittleEndian.Uint32(header[4:]))
+	namesLen := uint64(binary.LittleEndian.Uint32(header[8:]))
+	if uint64(len(data)) != uint64(compactHeaderSize)+4*(2*(n+1)+2*m)+namesLen {
+		return nil, ErrInvalidCompact
+	}
+
+	c := &Compact{}
+	rest := data[compactHeaderSize:]
+	next := func(count uint64) []uint32 {
+		section := rest[:4*count]
+		rest = rest[4*count:]
+		return uint32s(section)
+	}
+	c.depOffsets = next(n + 1)
+	c.deps = next(m)
+	c.revOffsets = next(n + 1)
+	c.revs = next(m)
+	if n > 0 {
+		c.names = strings.Split(string(rest), "\n")
+	}
+	if uint64(len(c.names)) != n {
+		return nil, ErrInvalidCompact
+	}
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	c.buildIndex()
+	return c, nil
+}
+
+// validate checks that offsets and nodes are in range, so a corrupted file
+// cannot make the queries panic.
+func (c *Compact) validate() error {
+	n := uint32(len(c.names))
+	for _, adj := range []struct{ offsets, nodes []uint32 }{
+		{c.depOffsets, c.deps},
+		{c.revOffsets, c.revs},
+	} {
+		if adj.offsets[0] != 0 || int(adj.offsets[n]) != len(adj.nodes) {
+			return ErrInvalidCompact
+		}
+		for i := uint32(0); i < n; i++ {
+			if adj.offsets[i] > adj.offsets[i+1] {
+				return ErrInvalidCompact
+			}
+		}
+		for _, node := range adj.nodes {
+			if node >= n {
+				return ErrInvalidCompact
+			}
+		}
+	}
+	return nil
+}
+
+// uint32s returns b as little-endian uint32s, without copying if the
+// machine is little-endian and b is aligned.
+func uint32s(b []byte) []uint32 {
+	if len(b) == 0 {
+		return []uint32{}
+	}
+	if nativeLittleEndian && uintptr(unsafe.Pointer(&b[0]))%unsafe.Alignof(uint32(0)) == 0 {
+		return unsafe.Slice((*uint32)(unsafe.Pointer(&b[0])), len(b)/4)
+	}
+	ret := make([]uint32, len(b)/4)
+	for i := range ret {
+		ret[i] = binary.LittleEndian.Uint32(b[4*i:])
+	}
+	return ret
+}
+
+var nativeLittleEndian = func() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}()
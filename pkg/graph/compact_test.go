@@ -0,0 +1,104 @@
+package graph
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func checkCompact(t *testing.T, c *Compact) {
+	t.Helper()
+	g := newTestGraph()
+
+	if got, want := c.Nodes(), g.Nodes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Nodes() = %v, want %v", got, want)
+	}
+	if got, want := c.EdgeCount(), g.EdgeCount(); got != want {
+		t.Errorf("EdgeCount() = %d, want %d", got, want)
+	}
+	if got, want := c.Dependencies("lib"), []string{"libc", "zlib"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Dependencies() = %v, want %v", got, want)
+	}
+	if got, want := c.Dependents("libc"), []string{"lib", "tool", "zlib"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Dependents() = %v, want %v", got, want)
+	}
+
+	want := []Reachable{{"lib", 1}, {"tool", 1}, {"zlib", 1}, {"app", 2}}
+	if got := c.ReverseClosure("libc", 0); !reflect.DeepEqual(got, want) {
+		t.Errorf("ReverseClosure() = %v, want %v", got, want)
+	}
+	if got := c.ReverseClosure("libc", 1); !reflect.DeepEqual(got, want[:3]) {
+		t.Errorf("ReverseClosure(maxDepth 1) = %v, want %v", got, want[:3])
+	}
+	if got := c.ReverseClosure("missing", 0); len(got) != 0 {
+		t.Errorf("ReverseClosure() = %v, want empty", got)
+	}
+
+	if got, want := c.ShortestPath("app", "libc", 0), []string{"app", "lib", "libc"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ShortestPath() = %v, want %v", got, want)
+	}
+	if got := c.ShortestPath("app", "libc", 1); got != nil {
+		t.Errorf("ShortestPath(maxDepth 1) = %v, want nil", got)
+	}
+	if got := c.ShortestPath("libc", "app", 0); got != nil {
+		t.Errorf("ShortestPath() = %v, want nil", got)
+	}
+
+	if got, want := c.PageRank(20, 0.85), g.PageRank(20, 0.85); !reflect.DeepEqual(got, want) {
+		t.Errorf("PageRank() = %v, want %v", got, want)
+	}
+	if got := c.Graph(); !reflect.DeepEqual(got, g) {
+		t.Errorf("Graph() differs from the original graph")
+	}
+}
+
+func TestCompact(t *testing.T) {
+	checkCompact(t, NewCompact(newTestGraph()))
+}
+
+func TestCompactFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.graph")
+	if err := NewCompact(newTestGraph()).WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	c, err := OpenCompact(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	checkCompact(t, c)
+
+	// an empty graph round trips too
+	empty := filepath.Join(t.TempDir(), "empty.graph")
+	if err := NewCompact(New()).WriteFile(empty); err != nil {
+		t.Fatal(err)
+	}
+	c, err = OpenCompact(empty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.NodeCount() != 0 || c.EdgeCount() != 0 {
+		t.Errorf("got %d nodes and %d edges, want an empty graph", c.NodeCount(), c.EdgeCount())
+	}
+	c.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, corrupt := range map[string][]byte{
+		"empty":     {},
+		"truncated": data[:len(data)-1],
+		"magic":     append([]byte("XXXXXXXX"), data[8:]...),
+	} {
+		bad := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(bad, corrupt, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := OpenCompact(bad); !errors.Is(err, ErrInvalidCompact) {
+			t.Errorf("OpenCompact(%s) = %v, want %v", name, err, ErrInvalidCompact)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package graph
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Export collects nodes and edges to be written as a graph file. The output
+// is deterministic: nodes are sorted by name and their names are used as
+// IDs, so the files of two runs can be diffed directly.
+type Export struct {
+	// ColorByPageRank fills DOT nodes with colors by their PageRank decile,
+	// from red (top 10%) to green (bottom 10%)
+	ColorByPageRank bool
+
+	nodes map[string]ExportNode
+	edges map[ExportEdge]struct{}
+}
+
+// ColorByPageRank is the default value of Export.ColorByPageRank, command
+// line tools may set it from flags.
+var ColorByPageRank = false
+
+// MaxLabelLength is the max number of characters of a label, longer labels
+// are truncated.
+const MaxLabelLength = 64
+
+type ExportNode struct {
+	Name         string
+	Label        string
+	Version      string
+	PageRank     float64
+	DependsCount int
+}
+
+type ExportEdge struct {
+	From  string
+	To    string
+	Label string
+}
+
+func NewExport() *Export {
+	return &Export{
+		ColorByPageRank: ColorByPageRank,
+		nodes:           make(map[string]ExportNode),
+		edges:           make(map[ExportEdge]struct{}),
+	}
+}
+
+// AddNode adds a node, if Label is empty, Name is used as the label.
+func (e *Export) AddNode(node ExportNode) {
+	if node.Label == "" {
+		node.Label = node.Name
+	}
+	e.nodes[node.Name] = node
+}
+
+// AddEdge adds an edge, edges with unknown nodes are skipped when writing.
+func (e *Export) AddEdge(from, to, label string) {
+	e.edges[ExportEdge{From: from, To: to, Label: label}] = struct{}{}
+}
+
+func (e *Export) sortedNodes() []ExportNode {
+	ret := make([]ExportNode, 0, len(e.nodes))
+	for _, n := range e.nodes {
+		ret = append(ret, n)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Name < ret[j].Name
+	})
+	return ret
+}
+
+func (e *Export) sortedEdges() []ExportEdge {
+	ret := make([]ExportEdge, 0, len(e.edges))
+	for edge := range e.edges {
+		_, fromOk := e.nodes[edge.From]
+		_, toOk := e.nodes[edge.To]
+		if fromOk && toOk {
+			ret = append(ret, edge)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].From != ret[j].From {
+			return ret[i].From < ret[j].From
+		}
+		if ret[i].To != ret[j].To {
+			return ret[i].To < ret[j].To
+		}
+		return ret[i].Label < ret[j].Label
+	})
+	return ret
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// cleanLabel collapses whitespaces including newlines into single spaces
+// and truncates the label to MaxLabelLength characters.
+func cleanLabel(label string) string {
+	label = strings.Join(strings.Fields(label), " ")
+	if r := []rune(label); len(r) > MaxLabelLength {
+		label = string(r[:MaxLabelLength-3]) + "..."
+	}
+	return label
+}
+
+// dotQuote returns s as a double-quoted DOT string.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\r", "")
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+// dotID quotes a node name to be used as a DOT ID.
+func dotID(name string) string {
+	return dotQuote(name)
+}
+
+// pageRankDeciles returns the decile of every node, 9 is the top 10%.
+func (e *Export) pageRankDeciles(nodes []ExportNode) map[string]int {
+	sorted := make([]ExportNode, len(nodes))
+	copy(sorted, nodes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].PageRank < sorted[j].PageRank
+	})
+
+	ret := make(map[string]int, len(sorted))
+	for i, n := range sorted {
+		ret[n.Name] = i * 10 / len(sorted)
+	}
+	return ret
+}
+
+// WriteDot writes the graph in Graphviz DOT format.
+func (e *Export) WriteDot(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	nodes := e.sortedNodes()
+	var deciles map[string]int
+	if e.ColorByPageRank {
+		deciles = e.pageRankDeciles(nodes)
+	}
+
+	bw.WriteString("digraph {\n")
+	for _, n := range nodes {
+		fmt.Fprintf(bw, "  %s [label=%s", dotID(n.Name), dotQuote(cleanLabel(n.Label)))
+		if n.Version != "" {
+			fmt.Fprintf(bw, ", version=%s", dotQuote(n.Version))
+		}
+		fmt.Fprintf(bw, ", pagerank=%s, depends_count=%d", formatFloat(n.PageRank), n.DependsCount)
+		if deciles != nil {
+			// rdylgn10 goes from red (1) to green (10)
+			fmt.Fprintf(bw, ", style=filled, colorscheme=rdylgn10, fillcolor=%d", 10-deciles[n.Name])
+		}
+		bw.WriteString("];\n")
+	}
+	for _, edge := range e.sortedEdges() {
+		if edge.Label == "" {
+			fmt.Fprintf(bw, "  %s -> %s;\n", dotID(edge.From), dotID(edge.To))
+		} else {
+			fmt.Fprintf(bw, "  %s -> %s [label=%s];\n", dotID(edge.From), dotID(edge.To), dotQuote(cleanLabel(edge.Label)))
+		}
+	}
+	bw.WriteString("}\n")
+
+	return bw.Flush()
+}
+
+func xmlEscape(s string) string {
+	var sb strings.Builder
+	xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
+
+// WriteGraphML writes the graph in GraphML format.
+func (e *Export) WriteGraphML(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	bw.WriteString(xml.Header)
+	bw.WriteString(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">` + "\n")
+	bw.WriteString(`  <key id="label" for="node" attr.name="label" attr.type="string"/>` + "\n")
+	bw.WriteString(`  <key id="version" for="node" attr.name="version" attr.type="string"/>` + "\n")
+	bw.WriteString(`  <key id="pagerank" for="node" attr.name="pagerank" attr.type="double"/>` + "\n")
+	bw.WriteString(`  <key id="depends_count" for="node" attr.name="depends_count" attr.type="int"/>` + "\n")
+	bw.WriteString(`  <key id="edge_label" for="edge" attr.name="label" attr.type="string"/>` + "\n")
+	bw.WriteString(`  <graph edgedefault="directed">` + "\n")
+
+	for _, n := range e.sortedNodes() {
+		fmt.Fprintf(bw, "    <node id=\"%s\">\n", xmlEscape(n.Name))
+		fmt.Fprintf(bw, "      <data key=\"label\">%s</data>\n", xmlEscape(cleanLabel(n.Label)))
+		if n.Version != "" {
+			fmt.Fprintf(bw, "      <data key=\"version\">%s</data>\n", xmlEscape(n.Version))
+		}
+		fmt.Fprintf(bw, "      <data key=\"pagerank\">%s</data>\n", formatFloat(n.PageRank))
+		fmt.Fprintf(bw, "      <data key=\"depends_count\">%d</data>\n", n.DependsCount)
+		bw.WriteString("    </node>\n")
+	}
+	for _, edge := range e.sortedEdges() {
+		if edge.Label == "" {
+			fmt.Fprintf(bw, "    <edge source=\"%s\" target=\"%s\"/>\n", xmlEscape(edge.From), xmlEscape(edge.To))
+		} else {
+			fmt.Fprintf(bw, "    <edge source=\"%s\" target=\"%s\">\n", xmlEscape(edge.From), xmlEscape(edge.To))
+			fmt.Fprintf(bw, "      <data key=\"edge_label\">%s</data>\n", xmlEscape(edge.Label))
+			bw.WriteString("    </edge>\n")
+		}
+	}
+
+	bw.WriteString("  </graph>\n</graphml>\n")
+	return bw.Flush()
+}
+
+// WriteFile writes the graph to path, the format is GraphML if the file
+// extension is .graphml, otherwise DOT.
+func (e *Export) WriteFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".graphml") {
+		return e.WriteGraphML(file)
+	}
+	return e.WriteDot(file)
+}
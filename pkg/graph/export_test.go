@@ -0,0 +1,113 @@
+package graph
+
+import (
+	"bytes"
+	"testing"
+)
+
+func newTestExport(reverse bool) *Export {
+	nodes := []ExportNode{
+		{Name: "zlib", PageRank: 0.25, DependsCount: 2},
+		{Name: "libc", Label: "libc@2.40", PageRank: 0.5, DependsCount: 3},
+		{Name: "curl", PageRank: 0.125, DependsCount: 0},
+	}
+	edges := []ExportEdge{
+		{From: "curl", To: "zlib"},
+		{From: "curl", To: "libc", Label: ">= 2.0"},
+		{From: "zlib", To: "libc"},
+		{From: "curl", To: "openssl"},
+	}
+
+	e := NewExport()
+	for i := range nodes {
+		if reverse {
+			i = len(nodes) - 1 - i
+		}
+		e.AddNode(nodes[i])
+	}
+	for i := range edges {
+		if reverse {
+			i = len(edges) - 1 - i
+		}
+		e.AddEdge(edges[i].From, edges[i].To, edges[i].Label)
+	}
+	return e
+}
+
+func TestWriteDot(t *testing.T) {
+	want := `digraph {
+  "curl" [label="curl", pagerank=0.125, depends_count=0];
+  "libc" [label="libc@2.40", pagerank=0.5, depends_count=3];
+  "zlib" [label="zlib", pagerank=0.25, depends_count=2];
+  "curl" -> "libc" [label=">= 2.0"];
+  "curl" -> "zlib";
+  "zlib" -> "libc";
+}
+`
+
+	for _, reverse := range []bool{false, true} {
+		var buf bytes.Buffer
+		if err := newTestExport(reverse).WriteDot(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != want {
+			t.Errorf("WriteDot() =\n%s\nwant\n%s", buf.String(), want)
+		}
+	}
+}
+
+func TestWriteGraphML(t *testing.T) {
+	var a, b bytes.Buffer
+	if err := newTestExport(false).WriteGraphML(&a); err != nil {
+		t.Fatal(err)
+	}
+	if err := newTestExport(true).WriteGraphML(&b); err != nil {
+		t.Fatal(err)
+	}
+	if a.String() != b.String() {
+		t.Errorf("WriteGraphML() is not deterministic")
+	}
+	if !bytes.Contains(a.Bytes(), []byte(`<data key="edge_label">&gt;= 2.0</data>`)) {
+		t.Errorf("WriteGraphML() does not escape edge label:\n%s", a.String())
+	}
+}
+
+func TestWriteDotEscape(t *testing.T) {
+	e := NewExport()
+	e.AddNode(ExportNode{
+		Name:    `say "hi"`,
+		Label:   "a \"quoted\"\ndescription with \\ backslash, which is also too long to be shown in a graph",
+		Version: "1.0",
+	})
+
+	var buf bytes.Buffer
+	if err := e.WriteDot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `digraph {
+  "say \"hi\"" [label="a \"quoted\" description with \\ backslash, which is also too lo...", version="1.0", pagerank=0, depends_count=0];
+}
+`
+	if buf.String() != want {
+		t.Errorf("WriteDot() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteDotColor(t *testing.T) {
+	e := newTestExport(false)
+	e.ColorByPageRank = true
+
+	var buf bytes.Buffer
+	if err := e.WriteDot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	// libc has the highest PageRank, curl has the lowest
+	for _, s := range []string{
+		`"libc" [label="libc@2.40", pagerank=0.5, depends_count=3, style=filled, colorscheme=rdylgn10, fillcolor=4]`,
+		`"curl" [label="curl", pagerank=0.125, depends_count=0, style=filled, colorscheme=rdylgn10, fillcolor=10]`,
+	} {
+		if !bytes.Contains(buf.Bytes(), []byte(s)) {
+			t.Errorf("WriteDot() does not contain %s:\n%s", s, buf.String())
+		}
+	}
+}
@@ -0,0 +1,178 @@
+// Package graph provides an in-memory dependency graph and the algorithms
+// shared by analysis tools, e.g. closures and PageRank.
+//
+// An edge from A to B means A depends on B. All the functions returning
+// node lists return them sorted, so the results are deterministic.
+package graph
+
+import (
+	"sort"
+)
+
+type Graph struct {
+	deps       map[string]map[string]struct{}
+	dependents map[string]map[string]struct{}
+}
+
+func New() *Graph {
+	return &Graph{
+		deps:       make(map[string]map[string]struct{}),
+		dependents: make(map[string]map[string]struct{}),
+	}
+}
+
+// AddNode adds a node without any edges, it does nothing if the node exists.
+func (g *Graph) AddNode(name string) {
+	if _, ok := g.deps[name]; ok {
+		return
+	}
+	g.deps[name] = make(map[string]struct{})
+	g.dependents[name] = make(map[string]struct{})
+}
+
+// AddEdge adds an edge meaning from depends on to, missing nodes are added.
+// Self loops are ignored.
+func (g *Graph) AddEdge(from, to string) {
+	g.AddNode(from)
+	g.AddNode(to)
+	if from == to {
+		return
+	}
+	g.deps[from][to] = struct{}{}
+	g.dependents[to][from] = struct{}{}
+}
+
+func (g *Graph) HasNode(name string) bool {
+	_, ok := g.deps[name]
+	return ok
+}
+
+func (g *Graph) NodeCount() int {
+	return len(g.deps)
+}
+
+func (g *Graph) EdgeCount() int {
+	n := 0
+	for _, d := range g.deps {
+		n += len(d)
+	}
+	return n
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	ret := make([]string, 0, len(m))
+	for k := range m {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func (g *Graph) Nodes() []string {
+	ret := make([]string, 0, len(g.deps))
+	for k := range g.deps {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// Dependencies returns the direct dependencies of the node.
+func (g *Graph) Dependencies(name string) []string {
+	return sortedKeys(g.deps[name])
+}
+
+// Dependents returns the nodes directly depending on the node.
+func (g *Graph) Dependents(name string) []string {
+	return sortedKeys(g.dependents[name])
+}
+
+// Without returns a copy of the graph with the given nodes and their edges removed.
+func (g *Graph) Without(names ...string) *Graph {
+	removed := make(map[string]struct{}, len(names))
+	for _, n := range names {
+		removed[n] = struct{}{}
+	}
+
+	ret := New()
+	for from, deps := range g.deps {
+		if _, ok := removed[from]; ok {
+			continue
+		}
+		ret.AddNode(from)
+		for to := range deps {
+			if _, ok := removed[to]; ok {
+				continue
+			}
+			ret.AddEdge(from, to)
+		}
+	}
+	return ret
+}
+
+func closure(start string, adj map[string]map[string]struct{}) []string {
+	visited := map[string]struct{}{start: {}}
+	queue := []string{start}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for next := range adj[cur] {
+			if _, ok := visited[next]; ok {
+				continue
+			}
+			visited[next] = struct{}{}
+			queue = append(queue, next)
+		}
+	}
+	delete(visited, start)
+	return sortedKeys(visited)
+}
+
+// Closure returns all the direct and transitive dependencies of the node,
+// excluding the node itself.
+func (g *Graph) Closure(name string) []string {
+	return closure(name, g.deps)
+}
+
+// ReverseClosure returns all the nodes depending on the node directly or
+// transitively, excluding the node itself.
+func (g *Graph) ReverseClosure(name string) []string {
+	return closure(name, g.dependents)
+}
+
+// PageRank computes PageRank of every node, where a node passes its rank
+// to its dependencies. It is RankDependencies with the uniform weighting
+// and all iterations run, as used by the distribution collectors by
+// default, see DefaultPageRankParams.
+func (g *Graph) PageRank(maxIterations int, dampingFactor float64) map[string]float64 {
+	// iterate in a fixed order, so that the float results are reproducible
+	nodes := g.Nodes()
+	deps := make(map[string][]string, len(nodes))
+	for _, name := range nodes {
+		deps[name] = g.Dependencies(name)
+	}
+
+	rank := make(map[string]float64, len(nodes))
+	n := float64(len(nodes))
+	for _, name := range nodes {
+		rank[name] = 1.0 / n
+	}
+
+	for i := 0; i < maxIterations; i++ {
+		newRank := make(map[string]float64, len(nodes))
+		for _, name := range nodes {
+			newRank[name] = (1 - dampingFactor) / n
+		}
+		for _, name := range nodes {
+			if len(deps[name]) == 0 {
+				continue
+			}
+			share := dampingFactor * rank[name] / float64(len(deps[name]))
+			for _, dep := range deps[name] {
+				newRank[dep] += share
+			}
+		}
+		rank = newRank
+	}
+	return rank
+}
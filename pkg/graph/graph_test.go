@@ -0,0 +1,89 @@
+package graph
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// newTestGraph returns a graph like:
+//
+//	app -> lib -> libc
+//	tool -> libc
+//	lib -> zlib -> libc
+func newTestGraph() *Graph {
+	g := New()
+	g.AddEdge("app", "lib")
+	g.AddEdge("lib", "libc")
+	g.AddEdge("lib", "zlib")
+	g.AddEdge("zlib", "libc")
+	g.AddEdge("tool", "libc")
+	return g
+}
+
+func TestClosure(t *testing.T) {
+	g := newTestGraph()
+
+	if got, want := g.Closure("app"), []string{"lib", "libc", "zlib"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Closure() = %v, want %v", got, want)
+	}
+	if got, want := g.ReverseClosure("zlib"), []string{"app", "lib"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ReverseClosure() = %v, want %v", got, want)
+	}
+	if got := g.ReverseClosure("app"); len(got) != 0 {
+		t.Errorf("ReverseClosure() = %v, want empty", got)
+	}
+}
+
+func TestWithout(t *testing.T) {
+	g := newTestGraph().Without("lib")
+
+	if g.HasNode("lib") {
+		t.Errorf("lib should be removed")
+	}
+	if got, want := g.ReverseClosure("libc"), []string{"tool", "zlib"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ReverseClosure() = %v, want %v", got, want)
+	}
+	if g.NodeCount() != 4 || g.EdgeCount() != 2 {
+		t.Errorf("got %d nodes and %d edges, want 4 and 2", g.NodeCount(), g.EdgeCount())
+	}
+}
+
+func TestPageRank(t *testing.T) {
+	rank := newTestGraph().PageRank(20, 0.85)
+
+	if rank["libc"] <= rank["zlib"] || rank["zlib"] <= rank["app"] {
+		t.Errorf("unexpected rank order: %v", rank)
+	}
+	if math.Abs(rank["app"]-rank["tool"]) > 1e-12 {
+		t.Errorf("app and tool should have the same rank: %v", rank)
+	}
+}
+
+func TestStronglyConnectedComponents(t *testing.T) {
+	g := newTestGraph()
+	// libc <-> zlib and lib -> libc
+	g.AddEdge("libc", "zlib")
+
+	if got, want := g.Cycles(), [][]string{{"libc", "zlib"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Cycles() = %v, want %v", got, want)
+	}
+	if got := len(g.StronglyConnectedComponents()); got != 4 {
+		t.Errorf("StronglyConnectedComponents() returned %d components, want 4", got)
+	}
+
+	dag, component := g.Condense()
+	if component["zlib"] != "libc" || dag.NodeCount() != 4 || len(dag.Cycles()) != 0 {
+		t.Errorf("unexpected condensation: %v", component)
+	}
+
+	naive := g.TransitiveDependentCounts(false)
+	condensed := g.TransitiveDependentCounts(true)
+	// naive: libc is depended by zlib, lib, app and tool
+	if naive["libc"] != 4 || condensed["libc"] != 3 || condensed["zlib"] != 3 {
+		t.Errorf("unexpected counts: naive %v, condensed %v", naive, condensed)
+	}
+	if naive["lib"] != 1 || condensed["lib"] != 1 {
+		t.Errorf("unexpected counts: naive %v, condensed %v", naive, condensed)
+	}
+}
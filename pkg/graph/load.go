@@ -0,0 +1,49 @@
+package graph
+
+import (
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+)
+
+// LoadDist loads the dependency graph of a distribution from the database.
+// Like the collectors, dependencies which are not packages of the
+// distribution are ignored.
+func LoadDist(ac storage.AppDatabaseContext, prefix repository.DistPackageTablePrefix) (*Graph, error) {
+	g := New()
+
+	query, args, err := sqlutil.From(repository.DistPackageTableName(prefix)).Select("package")
+	if err != nil {
+		return nil, err
+	}
+	rows, err := ac.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		g.AddNode(name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	relationships, err := repository.NewDistRelationshipRepository(ac, prefix).Query()
+	if err != nil {
+		return nil, err
+	}
+	for r := range relationships {
+		if r.FromPackage == nil || r.ToPackage == nil {
+			continue
+		}
+		if !g.HasNode(*r.FromPackage) || !g.HasNode(*r.ToPackage) {
+			continue
+		}
+		g.AddEdge(*r.FromPackage, *r.ToPackage)
+	}
+	return g, nil
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package graph
+
+import "os"
+
+// mapFile reads the whole file, memory-mapping is only supported on unix.
+func mapFile(path string) (data []byte, unmap func() error, err error) {
+	data, err = os.ReadFile(path)
+	return data, nil, err
+}
@@ -0,0 +1,205 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Weighting is how a node splits its rank among its dependencies.
+type Weighting string
+
+const (
+	// WeightUniform splits the rank evenly among the dependencies
+	WeightUniform Weighting = "uniform"
+	// WeightDependents splits the rank by the number of dependents of the
+	// dependencies, so widely used dependencies get a larger share
+	WeightDependents Weighting = "dependents"
+)
+
+// Weightings are the known weighting modes.
+var Weightings = []Weighting{WeightUniform, WeightDependents}
+
+// ErrInvalidPageRankParams is returned by PageRankParams.Validate.
+var ErrInvalidPageRankParams = errors.New("invalid PageRank parameters")
+
+// PageRankParams are the parameters of PageRank. Zero fields are unset,
+// they are taken from the defaults by Or.
+type PageRankParams struct {
+	// Iterations is the maximum number of power iterations
+	Iterations int `json:"iterations" mapstructure:"iterations"`
+	// Damping is the damping factor, the probability to follow a
+	// dependency instead of jumping to a random node
+	Damping float64 `json:"damping" mapstructure:"damping"`
+	// Epsilon stops the iterations once the ranks change less, in sum of
+	// absolute changes, all iterations are run if it is 0
+	Epsilon   float64   `json:"epsilon" mapstructure:"epsilon"`
+	Weighting Weighting `json:"weighting" mapstructure:"weighting"`
+}
+
+// DefaultPageRankParams are the parameters of the distribution collectors,
+// 20 iterations with damping factor 0.85.
+var DefaultPageRankParams = PageRankParams{
+	Iterations: 20,
+	Damping:    0.85,
+	Weighting:  WeightUniform,
+}
+
+// Or returns p with its unset fields taken from def.
+func (p PageRankParams) Or(def PageRankParams) PageRankParams {
+	if p.Iterations == 0 {
+		p.Iterations = def.Iterations
+	}
+	if p.Damping == 0 {
+		p.Damping = def.Damping
+	}
+	if p.Epsilon == 0 {
+		p.Epsilon = def.Epsilon
+	}
+	if p.Weighting == "" {
+		p.Weighting = def.Weighting
+	}
+	return p
+}
+
+// Validate returns an error wrapping ErrInvalidPageRankParams if a field
+// is out of range, unset fields are valid.
+func (p PageRankParams) Validate() error {
+	switch {
+	case p.Iterations < 0:
+		return fmt.Errorf("%w: negative iterations %d", ErrInvalidPageRankParams, p.Iterations)
+	case p.Damping < 0 || p.Damping >= 1:
+		return fmt.Errorf("%w: damping %v is not in [0, 1)", ErrInvalidPageRankParams, p.Damping)
+	case p.Epsilon < 0:
+		return fmt.Errorf("%w: negative epsilon %v", ErrInvalidPageRankParams, p.Epsilon)
+	case p.Weighting != "" && p.Weighting != WeightUniform && p.Weighting != WeightDependents:
+		return fmt.Errorf("%w: unknown weighting %q", ErrInvalidPageRankParams, p.Weighting)
+	}
+	return nil
+}
+
+func (p PageRankParams) String() string {
+	return fmt.Sprintf("iterations=%d damping=%v epsilon=%v weighting=%s", p.Iterations, p.Damping, p.Epsilon, p.Weighting)
+}
+
+// PageRankConfig is the config of the PageRank parameters of the
+// collectors.
+type PageRankConfig struct {
+	// Default are the parameters of every collector, unset fields are
+	// the defaults of the collector
+	Default PageRankParams
+	// Collectors are the parameters of single collectors, keyed by their
+	// names, e.g. debian or depsdev, unset fields are taken from Default
+	Collectors map[string]PageRankParams
+}
+
+var pageRankConfig *PageRankConfig
+
+// InitPageRank sets the config of PageRankParamsOf, nil leaves every
+// collector at its defaults.
+func InitPageRank(config *PageRankConfig) {
+	pageRankConfig = config
+}
+
+// PageRankParamsOf returns the parameters of the collector name, the
+// configured ones of the collector, then the configured defaults, then def
+// and DefaultPageRankParams.
+func PageRankParamsOf(name string, def PageRankParams) PageRankParams {
+	var p PageRankParams
+	if pageRankConfig != nil {
+		p = pageRankConfig.Collectors[name].Or(pageRankConfig.Default)
+	}
+	return p.Or(def).Or(DefaultPageRankParams)
+}
+
+// RankDependencies computes PageRank of the nodes of deps, which maps the
+// nodes to their dependencies, a node passes its rank to its dependencies
+// as split by the weighting of p. Dependencies which are not nodes of deps
+// are ignored. The iteration begins from the ranks of start, nodes not in
+// start begin with the same rank as if there were no start, so the ranks
+// of the last run of a slightly different graph shorten it. Unset
+// parameters are those of DefaultPageRankParams.
+//
+// The ranks and the number of iterations run are returned.
+func RankDependencies(deps map[string][]string, start map[string]float64, p PageRankParams) (map[string]float64, int) {
+	p = p.Or(DefaultPageRankParams)
+
+	// iterate in a fixed order, so that the float results are reproducible
+	nodes := make([]string, 0, len(deps))
+	for name := range deps {
+		nodes = append(nodes, name)
+	}
+	sort.Strings(nodes)
+	index := make(map[string]int, len(nodes))
+	for i, name := range nodes {
+		index[name] = i
+	}
+	edges := make([][]int, len(nodes))
+	dependents := make([]int, len(nodes))
+	for i, name := range nodes {
+		for _, dep := range deps[name] {
+			if j, ok := index[dep]; ok {
+				edges[i] = append(edges[i], j)
+				dependents[j]++
+			}
+		}
+	}
+	// weights[i][k] is the share of the rank of i passed to edges[i][k]
+	weights := make([][]float64, len(nodes))
+	for i, out := range edges {
+		var total float64
+		for _, j := range out {
+			if p.Weighting == WeightDependents {
+				total += float64(dependents[j])
+			} else {
+				total++
+			}
+		}
+		weights[i] = make([]float64, len(out))
+		for k, j := range out {
+			if p.Weighting == WeightDependents {
+				weights[i][k] = float64(dependents[j]) / total
+			} else {
+				weights[i][k] = 1 / total
+			}
+		}
+	}
+
+	n := float64(len(nodes))
+	rank := make([]float64, len(nodes))
+	for i, name := range nodes {
+		if r, ok := start[name]; ok {
+			rank[i] = r
+		} else {
+			rank[i] = 1 / n
+		}
+	}
+	run := 0
+	for run < p.Iterations {
+		next := make([]float64, len(nodes))
+		for i := range next {
+			next[i] = (1 - p.Damping) / n
+		}
+		for i, out := range edges {
+			for k, j := range out {
+				next[j] += p.Damping * rank[i] * weights[i][k]
+			}
+		}
+		var delta float64
+		for i := range next {
+			delta += math.Abs(next[i] - rank[i])
+		}
+		rank = next
+		run++
+		if delta < p.Epsilon {
+			break
+		}
+	}
+
+	ret := make(map[string]float64, len(nodes))
+	for i, name := range nodes {
+		ret[name] = rank[i]
+	}
+	return ret, run
+}
@@ -0,0 +1,76 @@
+package graph
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func testDeps() map[string][]string {
+	g := newTestGraph()
+	deps := make(map[string][]string)
+	for _, name := range g.Nodes() {
+		deps[name] = g.Dependencies(name)
+	}
+	// dependencies which are not nodes are ignored
+	deps["tool"] = append(deps["tool"], "missing")
+	return deps
+}
+
+func TestRankDependencies(t *testing.T) {
+	want := newTestGraph().PageRank(20, 0.85)
+	ranks, n := RankDependencies(testDeps(), nil, PageRankParams{})
+	if n != 20 {
+		t.Errorf("ran %d iterations, want 20", n)
+	}
+	for name, r := range want {
+		if math.Abs(ranks[name]-r) > 1e-12 {
+			t.Errorf("rank of %s = %v, want %v", name, ranks[name], r)
+		}
+	}
+
+	// epsilon stops once converged, warm starts converge sooner
+	p := PageRankParams{Iterations: 1000, Epsilon: 1e-12}
+	converged, n := RankDependencies(testDeps(), nil, p)
+	if n >= 1000 {
+		t.Errorf("did not converge in %d iterations", n)
+	}
+	if _, warm := RankDependencies(testDeps(), converged, p); warm >= n {
+		t.Errorf("warm start ran %d iterations, cold %d", warm, n)
+	}
+
+	// lib splits its rank by the dependents of libc and zlib, 3 and 1
+	weighted, _ := RankDependencies(testDeps(), nil, PageRankParams{Weighting: WeightDependents})
+	if weighted["zlib"] >= ranks["zlib"] || weighted["libc"] <= ranks["libc"] {
+		t.Errorf("weighted ranks %v, uniform %v", weighted, ranks)
+	}
+}
+
+func TestPageRankParams(t *testing.T) {
+	defer InitPageRank(nil)
+
+	depsdev := PageRankParams{Iterations: 100, Epsilon: 1e-9}
+	if got, want := PageRankParamsOf("depsdev", depsdev), (PageRankParams{100, 0.85, 1e-9, WeightUniform}); got != want {
+		t.Errorf("PageRankParamsOf() = %v, want %v", got, want)
+	}
+
+	InitPageRank(&PageRankConfig{
+		Default:    PageRankParams{Damping: 0.9},
+		Collectors: map[string]PageRankParams{"debian": {Iterations: 50, Weighting: WeightDependents}},
+	})
+	if got, want := PageRankParamsOf("debian", DefaultPageRankParams), (PageRankParams{50, 0.9, 0, WeightDependents}); got != want {
+		t.Errorf("PageRankParamsOf(debian) = %v, want %v", got, want)
+	}
+	if got, want := PageRankParamsOf("depsdev", depsdev), (PageRankParams{100, 0.9, 1e-9, WeightUniform}); got != want {
+		t.Errorf("PageRankParamsOf(depsdev) = %v, want %v", got, want)
+	}
+
+	for _, p := range []PageRankParams{{Iterations: -1}, {Damping: 1}, {Epsilon: -1}, {Weighting: "log"}} {
+		if err := p.Validate(); !errors.Is(err, ErrInvalidPageRankParams) {
+			t.Errorf("Validate(%v) = %v", p, err)
+		}
+	}
+	if err := DefaultPageRankParams.Validate(); err != nil {
+		t.Errorf("Validate(defaults) = %v", err)
+	}
+}
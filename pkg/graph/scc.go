@@ -0,0 +1,132 @@
+package graph
+
+import (
+	"sort"
+)
+
+// StronglyConnectedComponents returns the SCCs of the graph using Tarjan's
+// algorithm. Nodes in each component are sorted, and components are sorted
+// by size descending and then by their first node.
+func (g *Graph) StronglyConnectedComponents() [][]string {
+	nodes := g.Nodes()
+
+	index := make(map[string]int, len(nodes))
+	lowlink := make(map[string]int, len(nodes))
+	onStack := make(map[string]bool, len(nodes))
+	stack := make([]string, 0)
+	ret := make([][]string, 0)
+	next := 0
+
+	// frame is a manually managed call frame, the graph may be too deep
+	// for recursion
+	type frame struct {
+		node string
+		deps []string
+		i    int
+	}
+
+	for _, root := range nodes {
+		if _, ok := index[root]; ok {
+			continue
+		}
+
+		callStack := []*frame{{node: root, deps: g.Dependencies(root)}}
+		index[root], lowlink[root] = next, next
+		next++
+		stack = append(stack, root)
+		onStack[root] = true
+
+		for len(callStack) > 0 {
+			f := callStack[len(callStack)-1]
+
+			if f.i < len(f.deps) {
+				dep := f.deps[f.i]
+				f.i++
+				if _, ok := index[dep]; !ok {
+					index[dep], lowlink[dep] = next, next
+					next++
+					stack = append(stack, dep)
+					onStack[dep] = true
+					callStack = append(callStack, &frame{node: dep, deps: g.Dependencies(dep)})
+				} else if onStack[dep] {
+					lowlink[f.node] = min(lowlink[f.node], index[dep])
+				}
+				continue
+			}
+
+			// all dependencies visited, return from the frame
+			callStack = callStack[:len(callStack)-1]
+			if len(callStack) > 0 {
+				parent := callStack[len(callStack)-1].node
+				lowlink[parent] = min(lowlink[parent], lowlink[f.node])
+			}
+
+			if lowlink[f.node] == index[f.node] {
+				component := make([]string, 0)
+				for {
+					n := stack[len(stack)-1]
+					stack = stack[:len(stack)-1]
+					onStack[n] = false
+					component = append(component, n)
+					if n == f.node {
+						break
+					}
+				}
+				sort.Strings(component)
+				ret = append(ret, component)
+			}
+		}
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool {
+		if len(ret[i]) != len(ret[j]) {
+			return len(ret[i]) > len(ret[j])
+		}
+		return ret[i][0] < ret[j][0]
+	})
+	return ret
+}
+
+// Cycles returns the SCCs with more than one node, that is the groups of
+// packages depending on each other.
+func (g *Graph) Cycles() [][]string {
+	ret := make([][]string, 0)
+	for _, c := range g.StronglyConnectedComponents() {
+		if len(c) > 1 {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
+// Condense returns the condensation of the graph, which is a DAG where every
+// SCC is replaced by a single node named after its first member, and the
+// mapping from every node to the name of its component.
+func (g *Graph) Condense() (*Graph, map[string]string) {
+	component := make(map[string]string, len(g.deps))
+	for _, c := range g.StronglyConnectedComponents() {
+		for _, n := range c {
+			component[n] = c[0]
+		}
+	}
+
+	ret := New()
+	for from, deps := range g.deps {
+		ret.AddNode(component[from])
+		for to := range deps {
+			ret.AddEdge(component[from], component[to])
+		}
+	}
+	return ret, component
+}
+
+// TransitiveDependentCounts returns the number of packages depending on
+// each node directly or transitively, computed by the closure table of
+// Ancestors.
+//
+// If condense is true, members of the same SCC are not counted as
+// dependents of each other, otherwise every member of a cycle is counted as
+// a dependent of all the others, which inflates the counts of large cycles.
+func (g *Graph) TransitiveDependentCounts(condense bool) map[string]int {
+	return NewAncestors(g).Counts(condense)
+}
@@ -0,0 +1,194 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+)
+
+// ServiceConfig is the config of the shared graph service.
+type ServiceConfig struct {
+	Enabled bool
+	// Dir keeps the graphs as files which OpenCompact memory-maps, so
+	// processes share them and do not load them from the database, graphs
+	// are only kept in memory if empty
+	Dir string
+	// Refresh is the interval Run reloads the graphs from the database,
+	// 0 disables reloading
+	Refresh time.Duration
+}
+
+// Service shares the dependency graphs of distributions in memory, so
+// PageRank, closures and reachability queries do not load them from the
+// database every time. Graphs are loaded lazily from the files in the
+// directory of the service, written by the last Refresh, or from the
+// database otherwise.
+//
+// A Service is safe for concurrent use. Graphs returned by Get are never
+// changed, a refresh replaces them instead, so they can be used while the
+// graph is refreshed.
+type Service struct {
+	ac      storage.AppDatabaseContext
+	dir     string
+	refresh time.Duration
+
+	// loading serializes loading graphs, so a graph is loaded once by
+	// concurrent Get calls
+	loading sync.Mutex
+	mu      sync.RWMutex
+	graphs  map[repository.DistPackageTablePrefix]*Compact
+}
+
+func NewService(ac storage.AppDatabaseContext, config *ServiceConfig) *Service {
+	s := &Service{ac: ac, graphs: make(map[repository.DistPackageTablePrefix]*Compact)}
+	if config != nil {
+		s.dir = config.Dir
+		s.refresh = config.Refresh
+	}
+	return s
+}
+
+// Path returns the file keeping the graph of the distribution, or "" if the
+// service has no directory.
+func (s *Service) Path(prefix repository.DistPackageTablePrefix) string {
+	if s.dir == "" {
+		return ""
+	}
+	return filepath.Join(s.dir, string(prefix)+".graph")
+}
+
+func (s *Service) cached(prefix repository.DistPackageTablePrefix) *Compact {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.graphs[prefix]
+}
+
+func (s *Service) set(prefix repository.DistPackageTablePrefix, c *Compact) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.graphs[prefix] = c
+}
+
+// Get returns the graph of the distribution, loading it from its file or
+// the database on first use.
+func (s *Service) Get(prefix repository.DistPackageTablePrefix) (*Compact, error) {
+	if c := s.cached(prefix); c != nil {
+		return c, nil
+	}
+	s.loading.Lock()
+	defer s.loading.Unlock()
+	if c := s.cached(prefix); c != nil {
+		return c, nil
+	}
+
+	if path := s.Path(prefix); path != "" {
+		c, err := OpenCompact(path)
+		if err == nil {
+			s.set(prefix, c)
+			return c, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.Warnf("Failed to open graph %s, loading it from the database: %v", path, err)
+		}
+	}
+	return s.load(prefix)
+}
+
+// Refresh reloads the graphs of the distributions from the database and
+// writes their files, collectors call it after a run. The replaced graphs
+// stay valid for their users, mapped files are released once they are
+// garbage collected.
+func (s *Service) Refresh(prefixes ...repository.DistPackageTablePrefix) error {
+	s.loading.Lock()
+	defer s.loading.Unlock()
+	var errs []error
+	for _, prefix := range prefixes {
+		if _, err := s.load(prefix); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// load loads the graph from the database, s.loading must be held.
+func (s *Service) load(prefix repository.DistPackageTablePrefix) (*Compact, error) {
+	g, err := LoadDist(s.ac, prefix)
+	if err != nil {
+		return nil, err
+	}
+	c := NewCompact(g)
+	if path := s.Path(prefix); path != "" {
+		if err := os.MkdirAll(s.dir, 0o755); err != nil {
+			return nil, err
+		}
+		if err := c.WriteFile(path); err != nil {
+			return nil, err
+		}
+	}
+	s.set(prefix, c)
+	return c, nil
+}
+
+// Loaded returns the distributions whose graphs are loaded.
+func (s *Service) Loaded() []repository.DistPackageTablePrefix {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ret := make([]repository.DistPackageTablePrefix, 0, len(s.graphs))
+	for prefix := range s.graphs {
+		ret = append(ret, prefix)
+	}
+	return ret
+}
+
+// Run loads the graphs of the distributions at startup, then reloads the
+// loaded graphs from the database every refresh interval until ctx is
+// done. Failures are logged, the previous graph is kept.
+func (s *Service) Run(ctx context.Context, prefixes ...repository.DistPackageTablePrefix) {
+	for _, prefix := range prefixes {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, err := s.Get(prefix); err != nil {
+			logger.Warnf("Failed to load graph of %s: %v", prefix, err)
+		}
+	}
+	if s.refresh <= 0 {
+		return
+	}
+	ticker := time.NewTicker(s.refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Refresh(s.Loaded()...); err != nil {
+				logger.Warnf("Failed to refresh graphs: %v", err)
+			}
+		}
+	}
+}
+
+var defaultService *Service
+
+// InitDefault initializes the default service, it is disabled unless
+// config.Enabled is set.
+func InitDefault(ac storage.AppDatabaseContext, config *ServiceConfig) {
+	if config == nil || !config.Enabled {
+		defaultService = nil
+		return
+	}
+	defaultService = NewService(ac, config)
+}
+
+// Default returns the default service, or nil if it is disabled.
+func Default() *Service {
+	return defaultService
+}
@@ -0,0 +1,110 @@
+// Package homepage checks whether the homepages of packages are still
+// alive, so reports can flag critical packages whose upstream web presence
+// has vanished, e.g. an expired domain or a project site taken down.
+//
+// A homepage is probed by a HEAD request, and by a GET request of its first
+// byte if the server does not support HEAD. Probes are cheap but many
+// packages share a homepage, so callers check every url once per run and
+// skip urls checked recently, see HomepageLivenessRepository.
+package homepage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DefaultTimeout is the timeout of a probe of the default client.
+const DefaultTimeout = 15 * time.Second
+
+// userAgent identifies the checker, some sites reject requests without one.
+const userAgent = "criticality_score-homepage-checker"
+
+// ErrUnsupportedURL is the error of urls which are not absolute http(s)
+// urls.
+var ErrUnsupportedURL = errors.New("not a http(s) url")
+
+// Result is the result of a probe.
+type Result struct {
+	URL string
+	// StatusCode is the status of the final response after redirects, 0 if
+	// the request failed
+	StatusCode int
+	// Err is the error of a failed request, e.g. a dns or tls error
+	Err       error
+	CheckedAt time.Time
+}
+
+// Alive returns true if the site responded with a success or redirect, or
+// with a status which means it exists but refuses the checker, e.g. 403 of
+// sites blocking bots or 429 of rate limits.
+func (r *Result) Alive() bool {
+	if r.Err != nil {
+		return false
+	}
+	switch r.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusMethodNotAllowed, http.StatusTooManyRequests:
+		return true
+	}
+	return r.StatusCode > 0 && r.StatusCode < 400
+}
+
+// Checker probes homepages.
+type Checker struct {
+	client *http.Client
+}
+
+// NewChecker returns a checker sending requests by client, a client with
+// DefaultTimeout if nil. Redirects are followed by the client.
+func NewChecker(client *http.Client) *Checker {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultTimeout}
+	}
+	return &Checker{client: client}
+}
+
+// Check probes the homepage u. Failed requests are results with Err set,
+// not errors, as they are what the checker looks for.
+func (c *Checker) Check(ctx context.Context, u string) *Result {
+	result := &Result{URL: u, CheckedAt: time.Now()}
+	parsed, err := url.Parse(u)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		result.Err = ErrUnsupportedURL
+		return result
+	}
+
+	status, err := c.probe(ctx, http.MethodHead, u)
+	// servers not implementing HEAD, or rejecting it unlike GET
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented || status == http.StatusForbidden) {
+		status, err = c.probe(ctx, http.MethodGet, u)
+	}
+	result.StatusCode, result.Err = status, err
+	return result
+}
+
+// probe sends a request and returns the status, GET only reads the first
+// byte.
+func (c *Checker) probe(ctx context.Context, method, u string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if method == http.MethodGet {
+		req.Header.Set("Range", "bytes=0-0")
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<10))
+	// the first byte of a ranged GET
+	if resp.StatusCode == http.StatusPartialContent {
+		return http.StatusOK, nil
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,67 @@
+package homepage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("method = %s, want HEAD", r.Method)
+		}
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	})
+	// HEAD is not allowed, GET is served partially
+	mux.HandleFunc("/get-only", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if r.Header.Get("Range") != "bytes=0-0" {
+			t.Errorf("Range = %q, want the first byte", r.Header.Get("Range"))
+		}
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte("<"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	c := NewChecker(server.Client())
+	tests := []struct {
+		path   string
+		status int
+		alive  bool
+	}{
+		{"/ok", http.StatusOK, true},
+		{"/moved", http.StatusOK, true},
+		{"/gone", http.StatusGone, false},
+		{"/missing", http.StatusNotFound, false},
+		{"/get-only", http.StatusOK, true},
+	}
+	for _, tt := range tests {
+		r := c.Check(context.Background(), server.URL+tt.path)
+		if r.Err != nil || r.StatusCode != tt.status || r.Alive() != tt.alive {
+			t.Errorf("Check(%s) = %d, %v, alive %v, want %d, alive %v", tt.path, r.StatusCode, r.Err, r.Alive(), tt.status, tt.alive)
+		}
+	}
+
+	if r := c.Check(context.Background(), "ftp://example.org/"); !errors.Is(r.Err, ErrUnsupportedURL) || r.Alive() {
+		t.Errorf("Check(ftp) = %v, want %v", r.Err, ErrUnsupportedURL)
+	}
+
+	// connection refused
+	server.Close()
+	if r := c.Check(context.Background(), server.URL+"/ok"); r.Err == nil || r.Alive() {
+		t.Errorf("Check() of a closed server is alive")
+	}
+}
@@ -0,0 +1,48 @@
+package httpcache
+
+import (
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/samber/lo"
+)
+
+type dbStore struct {
+	repo repository.HTTPCacheRepository
+}
+
+// NewDBStore returns a store backed by the http_cache table of the default
+// database.
+func NewDBStore() Store {
+	return &dbStore{
+		repo: repository.NewHTTPCacheRepository(storage.GetDefaultAppDatabaseContext()),
+	}
+}
+
+// Get implements Store.
+func (d *dbStore) Get(url string) (*Entry, error) {
+	c, err := d.repo.GetByURL(url)
+	if err != nil || c == nil {
+		return nil, err
+	}
+	entry := &Entry{
+		URL:        url,
+		StatusCode: *c.StatusCode,
+		FetchedAt:  *c.FetchedAt,
+		ExpiresAt:  *c.ExpiresAt,
+	}
+	if c.Body != nil {
+		entry.Body = *c.Body
+	}
+	return entry, nil
+}
+
+// Put implements Store.
+func (d *dbStore) Put(entry *Entry) error {
+	return d.repo.InsertOrUpdate(&repository.HTTPCache{
+		URL:        &entry.URL,
+		StatusCode: &entry.StatusCode,
+		Body:       &entry.Body,
+		FetchedAt:  lo.ToPtr(entry.FetchedAt),
+		ExpiresAt:  lo.ToPtr(entry.ExpiresAt),
+	})
+}
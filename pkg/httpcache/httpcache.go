@@ -0,0 +1,202 @@
+// Package httpcache provides a read-through cache for HTTP GET responses of
+// upstream APIs like deps.dev and package registries, so that re-runs within
+// the TTL skip the network entirely.
+//
+// Error responses (4xx and 5xx) are cached as well but with a shorter TTL,
+// so that repeated failures of the same URL don't hammer upstream.
+//
+// In offline mode cached responses are served even if expired and GET
+// requests of urls which are not cached fail with ErrNotCached, so metrics
+// can be recomputed from the stored api snapshots without hitting the
+// network.
+//
+// Collectors usually use the default client, which is initialized by
+// config.ParseFlags when config.RegistHTTPCacheFlags is called:
+//
+//	resp, err := httpcache.Client().Get(url)
+package httpcache
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/rawresponse"
+)
+
+type Config struct {
+	// TTL of successful responses, 0 disables the cache
+	TTL time.Duration
+	// ErrorTTL of error responses, 0 means error responses are not cached
+	ErrorTTL time.Duration
+	// Offline serves GET requests only from the store, expired responses
+	// included
+	Offline bool
+}
+
+// ErrNotCached is returned in offline mode for GET requests of urls which
+// are not cached.
+var ErrNotCached = errors.New("response is not cached")
+
+// Entry is a cached response.
+type Entry struct {
+	URL        string
+	StatusCode int
+	Body       []byte
+	FetchedAt  time.Time
+	ExpiresAt  time.Time
+}
+
+// Store persists cached responses.
+type Store interface {
+	// Get returns the entry of url, or nil if it is not cached
+	Get(url string) (*Entry, error)
+	Put(entry *Entry) error
+}
+
+// Transport is a http.RoundTripper which serves GET requests from the store
+// if the cached response is not expired, and caches the responses fetched
+// from inner.
+type Transport struct {
+	inner  http.RoundTripper
+	store  Store
+	config Config
+	now    func() time.Time
+}
+
+func NewTransport(inner http.RoundTripper, store Store, config Config) *Transport {
+	if inner == nil {
+		inner = http.DefaultTransport
+	}
+	return &Transport{
+		inner:  inner,
+		store:  store,
+		config: config,
+		now:    time.Now,
+	}
+}
+
+func (t *Transport) ttl(statusCode int) time.Duration {
+	if statusCode >= http.StatusBadRequest {
+		return t.config.ErrorTTL
+	}
+	return t.config.TTL
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.Method != http.MethodGet || (t.config.TTL <= 0 && !t.config.Offline) {
+		return t.inner.RoundTrip(r)
+	}
+	url := r.URL.String()
+
+	entry, err := t.store.Get(url)
+	if t.config.Offline {
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			return nil, fmt.Errorf("%w: %s", ErrNotCached, url)
+		}
+		return entry.response(r), nil
+	}
+	if err == nil && entry != nil && t.now().Before(entry.ExpiresAt) {
+		return entry.response(r), nil
+	}
+
+	resp, err := t.inner.RoundTrip(r)
+	if err != nil {
+		return resp, err
+	}
+
+	ttl := t.ttl(resp.StatusCode)
+	if ttl <= 0 {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	now := t.now()
+	// failing to write the cache only costs a request next time
+	t.store.Put(&Entry{
+		URL:        url,
+		StatusCode: resp.StatusCode,
+		Body:       body,
+		FetchedAt:  now,
+		ExpiresAt:  now.Add(ttl),
+	})
+	return resp, nil
+}
+
+func (e *Entry) response(r *http.Request) *http.Response {
+	return &http.Response{
+		Status:        strconv.Itoa(e.StatusCode) + " " + http.StatusText(e.StatusCode),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"X-From-Cache": []string{"1"}},
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       r,
+	}
+}
+
+// MemoryStore keeps entries in memory, it is mostly used in tests.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*Entry
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*Entry)}
+}
+
+// Get implements Store.
+func (m *MemoryStore) Get(url string) (*Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.entries[url], nil
+}
+
+// Put implements Store.
+func (m *MemoryStore) Put(entry *Entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[entry.URL] = entry
+	return nil
+}
+
+var defaultClient = http.DefaultClient
+
+// InitDefault initializes the default client with a database backed store.
+// The responses are archived if rawresponse is enabled, cached ones
+// included, as the archive keeps the inputs of the run.
+func InitDefault(config *Config) {
+	var transport http.RoundTripper = http.DefaultTransport
+	if config != nil && (config.TTL > 0 || config.Offline) {
+		transport = NewTransport(http.DefaultTransport, NewDBStore(), *config)
+	}
+	transport = rawresponse.Wrap(transport)
+	if transport == http.DefaultTransport {
+		defaultClient = http.DefaultClient
+		return
+	}
+	defaultClient = &http.Client{Transport: transport}
+}
+
+// Client returns the default client, if it is not initialized, responses
+// are not cached.
+func Client() *http.Client {
+	return defaultClient
+}
@@ -0,0 +1,114 @@
+package httpcache
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTransport(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	transport := NewTransport(nil, NewMemoryStore(), Config{TTL: time.Hour, ErrorTTL: time.Minute})
+	transport.now = func() time.Time { return now }
+	client := &http.Client{Transport: transport}
+
+	get := func(path string) (int, string) {
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("Get(%s) failed: %v", path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	for i := 0; i < 3; i++ {
+		if code, body := get("/ok"); code != http.StatusOK || body != "hello" {
+			t.Errorf("got %d %q, want 200 \"hello\"", code, body)
+		}
+		if code, _ := get("/missing"); code != http.StatusNotFound {
+			t.Errorf("got %d, want 404", code)
+		}
+	}
+	if hits != 2 {
+		t.Errorf("upstream hit %d times, want 2", hits)
+	}
+
+	// the error response expires first
+	now = now.Add(2 * time.Minute)
+	get("/ok")
+	get("/missing")
+	if hits != 3 {
+		t.Errorf("upstream hit %d times, want 3", hits)
+	}
+
+	now = now.Add(2 * time.Hour)
+	get("/ok")
+	if hits != 4 {
+		t.Errorf("upstream hit %d times, want 4", hits)
+	}
+}
+
+func TestTransportDisabled(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewTransport(nil, NewMemoryStore(), Config{})}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if hits != 2 {
+		t.Errorf("upstream hit %d times, want 2", hits)
+	}
+}
+
+func TestTransportOffline(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer server.Close()
+
+	store := NewMemoryStore()
+	store.Put(&Entry{URL: server.URL + "/ok", StatusCode: http.StatusOK, Body: []byte("stale"), ExpiresAt: time.Now().Add(-time.Hour)})
+	client := &http.Client{Transport: NewTransport(nil, store, Config{Offline: true})}
+
+	// expired responses are served
+	resp, err := client.Get(server.URL + "/ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "stale" {
+		t.Errorf("got %q, want \"stale\"", body)
+	}
+
+	if _, err := client.Get(server.URL + "/missing"); !errors.Is(err, ErrNotCached) {
+		t.Errorf("Get() of an uncached url error = %v, want ErrNotCached", err)
+	}
+	if hits != 0 {
+		t.Errorf("upstream hit %d times, want 0", hits)
+	}
+}
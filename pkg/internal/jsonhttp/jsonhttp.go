@@ -0,0 +1,281 @@
+// Package jsonhttp is the http client shared by the clients of json APIs,
+// e.g. deps.dev, Libraries.io, Stack Exchange and our own API server. It
+// sends GET requests, throttles them, maps the status codes of responses to
+// errors and retries rate limited, server and network errors with a
+// backoff, honoring Retry-After.
+//
+// The API clients keep what is specific to their API, e.g. the urls, the
+// error bodies and their own sentinel errors, and wrap the ones of this
+// package for the common cases.
+package jsonhttp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/errors"
+)
+
+// MaxRetryAfter caps the delay asked by Retry-After.
+const MaxRetryAfter = 5 * time.Minute
+
+var (
+	// ErrNotFound is wrapped by errors of unknown items.
+	ErrNotFound = errors.Mark(errors.New("not found"), errors.NotFound)
+	// ErrRateLimited is wrapped by errors of requests still rate limited
+	// after all retries.
+	ErrRateLimited = errors.Mark(errors.New("rate limited"), errors.RateLimited)
+	// ErrServer is wrapped by 5xx errors still failing after all retries.
+	ErrServer = errors.Mark(errors.New("server error"), errors.Transient)
+	// ErrRequest is wrapped by errors of other status codes, network
+	// errors and malformed responses.
+	ErrRequest = errors.New("request failed")
+)
+
+type Config struct {
+	// MaxRetries is the number of retries of rate limited, server and
+	// network errors
+	MaxRetries int
+	// InitialBackoff is the delay before the first retry without
+	// Retry-After, doubled by every retry
+	InitialBackoff time.Duration
+	// Interval is the min delay between requests, not throttled if 0
+	Interval time.Duration
+	// Secrets are the query parameters left out of the urls in errors,
+	// e.g. api keys
+	Secrets []string
+	// Check returns the error of a response, DefaultCheck if nil. It is
+	// called with every response, so it can also read e.g. headers of
+	// successful ones. The url is the one of errors.
+	Check func(u string, resp *http.Response, body []byte) error
+}
+
+// StatusError is an error response of an API.
+type StatusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+	// Message is the message of the error body, if any
+	Message string
+	// RetryAfter is the delay asked by the Retry-After header, if any
+	RetryAfter time.Duration
+	// Err is the sentinel error wrapped, e.g. ErrNotFound
+	Err error
+}
+
+func (e *StatusError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("%s: %s returned %s: %s", e.Err, e.URL, e.Status, e.Message)
+	}
+	return fmt.Sprintf("%s: %s returned %s", e.Err, e.URL, e.Status)
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// NewStatusError returns the error of resp wrapping err, with the delay
+// asked by its Retry-After.
+func NewStatusError(u string, resp *http.Response, err error) *StatusError {
+	return &StatusError{
+		URL:        u,
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		RetryAfter: retryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		Err:        err,
+	}
+}
+
+// StatusErr returns the sentinel error of a status code which is not 200.
+func StatusErr(code int) error {
+	switch {
+	case code == http.StatusNotFound:
+		return ErrNotFound
+	case code == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case code >= 500:
+		return ErrServer
+	default:
+		return ErrRequest
+	}
+}
+
+// DefaultCheck accepts 200 responses, and returns a StatusError wrapping
+// the StatusErr of others.
+func DefaultCheck(u string, resp *http.Response, body []byte) error {
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	return NewStatusError(u, resp, StatusErr(resp.StatusCode))
+}
+
+// retryAfter parses a Retry-After header, in seconds or an http date, 0 if
+// it is not set or malformed.
+func retryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	var d time.Duration
+	if secs, err := strconv.Atoi(header); err == nil {
+		d = time.Duration(secs) * time.Second
+	} else if t, err := http.ParseTime(header); err == nil {
+		d = t.Sub(now)
+	}
+	return min(max(d, 0), MaxRetryAfter)
+}
+
+// Client sends the requests of an API client.
+type Client struct {
+	client *http.Client
+	config Config
+
+	// mu guards next, the earliest time of the next request
+	mu   sync.Mutex
+	next time.Time
+	// Sleep waits between requests and retries, replaced by tests
+	Sleep func(ctx context.Context, d time.Duration) error
+}
+
+// New returns a client sending requests by client, http.DefaultClient if
+// nil.
+func New(client *http.Client, config Config) *Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	config.MaxRetries = max(config.MaxRetries, 0)
+	if config.Check == nil {
+		config.Check = DefaultCheck
+	}
+	return &Client{client: client, config: config, Sleep: sleep}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// Hold delays the next request by d from now, e.g. for a backoff asked by
+// a response.
+func (c *Client) Hold(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if at := time.Now().Add(d); at.After(c.next) {
+		c.next = at
+	}
+}
+
+// throttle waits until the interval since the previous request and the
+// holds passed.
+func (c *Client) throttle(ctx context.Context) error {
+	c.mu.Lock()
+	now := time.Now()
+	at := now
+	if c.next.After(now) {
+		at = c.next
+	}
+	c.next = at.Add(c.config.Interval)
+	c.mu.Unlock()
+	if d := at.Sub(now); d > 0 {
+		return c.Sleep(ctx, d)
+	}
+	return nil
+}
+
+// redact leaves the secrets out of u.
+func (c *Client) redact(u string) string {
+	if len(c.config.Secrets) == 0 {
+		return u
+	}
+	parsed, err := url.Parse(u)
+	if err != nil {
+		u, _, _ = strings.Cut(u, "?")
+		return u
+	}
+	query := parsed.Query()
+	for _, s := range c.config.Secrets {
+		query.Del(s)
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// GetJSON decodes the successful response of u into v, see Get.
+func (c *Client) GetJSON(ctx context.Context, u string, v any) error {
+	body, err := c.Get(ctx, u)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("%w: decoding %s: %w", ErrRequest, c.redact(u), err)
+	}
+	return nil
+}
+
+// Get returns the body of the successful response of u, retrying rate
+// limited, server and network errors.
+func (c *Client) Get(ctx context.Context, u string) ([]byte, error) {
+	backoff := c.config.InitialBackoff
+	for attempt := 0; ; attempt++ {
+		body, err := c.do(ctx, u)
+		if err == nil || attempt >= c.config.MaxRetries || ctx.Err() != nil || !errors.Retryable(err) {
+			return body, err
+		}
+		delay := backoff
+		var se *StatusError
+		if errors.As(err, &se) && se.RetryAfter > 0 {
+			delay = se.RetryAfter
+		}
+		log.Printf("%v, retrying in %s", err, delay)
+		if err := c.Sleep(ctx, delay); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrRequest, err)
+		}
+		backoff *= 2
+	}
+}
+
+// do returns the body of the successful response of u.
+func (c *Client) do(ctx context.Context, u string) ([]byte, error) {
+	redacted := c.redact(u)
+	if err := c.throttle(ctx); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRequest, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrRequest, redacted)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		// the url of the error may have secrets
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			err = ue.Err
+		}
+		return nil, fmt.Errorf("%w: %s: %w", ErrRequest, redacted, errors.Mark(err, errors.Transient))
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading %s: %w", ErrRequest, redacted, errors.Mark(err, errors.Transient))
+	}
+	if err := c.config.Check(redacted, resp, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
@@ -0,0 +1,139 @@
+package jsonhttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc, config Config) (*Client, string, *[]time.Duration) {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	c := New(srv.Client(), config)
+	var sleeps []time.Duration
+	c.Sleep = func(ctx context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		return nil
+	}
+	return c, srv.URL, &sleeps
+}
+
+func TestGetJSON(t *testing.T) {
+	c, u, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "application/json" {
+			t.Errorf("Accept = %q", r.Header.Get("Accept"))
+		}
+		switch r.URL.Path {
+		case "/ok":
+			w.Write([]byte(`{"name": "serde"}`))
+		case "/malformed":
+			w.Write([]byte(`<html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}, Config{Secrets: []string{"key"}})
+
+	var ret struct{ Name string }
+	if err := c.GetJSON(context.Background(), u+"/ok?key=secret", &ret); err != nil || ret.Name != "serde" {
+		t.Errorf("GetJSON() = %+v, %v", ret, err)
+	}
+	err := c.GetJSON(context.Background(), u+"/malformed?key=secret&q=1", &ret)
+	if !errors.Is(err, ErrRequest) || !strings.Contains(err.Error(), "/malformed?q=1") {
+		t.Errorf("GetJSON() of a malformed response error = %v, want ErrRequest", err)
+	}
+	err = c.GetJSON(context.Background(), u+"/unknown?key=secret", &ret)
+	var se *StatusError
+	if !errors.Is(err, ErrNotFound) || !errors.As(err, &se) || se.StatusCode != http.StatusNotFound {
+		t.Errorf("GetJSON() of an unknown url error = %v, want ErrNotFound", err)
+	}
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("error %q leaks the key", err)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name string
+		// statuses are the status codes of the attempts, the last one is
+		// repeated
+		statuses   []int
+		retryAfter string
+		want       error
+		wantSleeps []time.Duration
+	}{
+		{"not found", []int{404}, "", ErrNotFound, nil},
+		{"bad request", []int{400}, "", ErrRequest, nil},
+		{"rate limited", []int{429}, "7", ErrRateLimited, []time.Duration{7 * time.Second, 7 * time.Second}},
+		{"server error", []int{503}, "", ErrServer, []time.Duration{time.Second, 2 * time.Second}},
+		{"recovered", []int{502, 200}, "", nil, []time.Duration{time.Second}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			c, u, sleeps := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				status := tt.statuses[min(int(attempts.Add(1))-1, len(tt.statuses)-1)]
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(status)
+				w.Write([]byte(`{}`))
+			}, Config{MaxRetries: 2, InitialBackoff: time.Second})
+
+			_, err := c.Get(context.Background(), u)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+			if !reflect.DeepEqual(*sleeps, tt.wantSleeps) {
+				t.Errorf("sleeps = %v, want %v", *sleeps, tt.wantSleeps)
+			}
+		})
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	c, u, sleeps := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}, Config{Interval: time.Minute})
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.Get(context.Background(), u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the second request waits for the interval
+	if len(*sleeps) != 1 || (*sleeps)[0] <= 0 || (*sleeps)[0] > time.Minute {
+		t.Errorf("sleeps = %v, want one throttle", *sleeps)
+	}
+
+	c.Hold(time.Hour)
+	if _, err := c.Get(context.Background(), u); err != nil {
+		t.Fatal(err)
+	}
+	if len(*sleeps) != 2 || (*sleeps)[1] <= time.Minute {
+		t.Errorf("sleeps = %v, want a hold of an hour", *sleeps)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 22, 0, 0, 0, 0, time.UTC)
+	tests := map[string]time.Duration{
+		"":                              0,
+		"30":                            30 * time.Second,
+		"-1":                            0,
+		"3600":                          MaxRetryAfter,
+		"Wed, 22 Jan 2025 00:01:00 GMT": time.Minute,
+		"soon":                          0,
+	}
+	for header, want := range tests {
+		if got := retryAfter(header, now); got != want {
+			t.Errorf("retryAfter(%q) = %s, want %s", header, got, want)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+// Package league ranks the projects of a group, e.g. the top Rust projects
+// or the top projects only packaged by Debian, by their latest scores.
+package league
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/score"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/HUSTSecLab/criticality_score/pkg/tagging"
+)
+
+// Kinds of leagues.
+const (
+	// KindLanguage groups projects by primary language
+	KindLanguage = "language"
+	// KindDistro groups projects by source distribution
+	KindDistro = "distro"
+	// KindDistroOnly groups projects only packaged by one distribution
+	KindDistroOnly = "distro-only"
+	// KindTag groups projects by tag, e.g. scientific
+	KindTag = "tag"
+)
+
+// Kinds are all kinds of leagues.
+var Kinds = []string{KindLanguage, KindDistro, KindDistroOnly, KindTag}
+
+// League is a group of projects.
+type League struct {
+	Kind string
+	Name string
+}
+
+// Parse returns the league of s, which is <kind>=<name>, e.g. language=Rust.
+func Parse(s string) (League, error) {
+	kind, name, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return League{}, fmt.Errorf("invalid league %q, want <kind>=<name>", s)
+	}
+	return New(kind, name)
+}
+
+// New returns the league of kind and name.
+func New(kind, name string) (League, error) {
+	kind = strings.ToLower(kind)
+	if !slices.Contains(Kinds, kind) {
+		return League{}, fmt.Errorf("unknown league kind %s, want one of %s", kind, strings.Join(Kinds, ", "))
+	}
+	if kind == KindTag {
+		if err := tagging.Validate(name); err != nil {
+			return League{}, err
+		}
+	}
+	return League{Kind: kind, Name: name}, nil
+}
+
+func (l League) String() string {
+	return l.Kind + "=" + l.Name
+}
+
+// Title returns the title of the league table.
+func (l League) Title() string {
+	if l.Kind == KindDistroOnly {
+		return fmt.Sprintf("Top %s-only projects", l.Name)
+	}
+	return fmt.Sprintf("Top %s projects", l.Name)
+}
+
+// Entry is a project of a league table.
+type Entry struct {
+	Rank    int     `json:"rank"`
+	GitLink string  `json:"link"`
+	Score   float64 `json:"score"`
+	// Percentile is the percentile rank within the league, and
+	// OverallPercentile among all projects
+	Percentile        float64  `json:"percentile"`
+	OverallPercentile *float64 `json:"overallPercentile"`
+}
+
+// member reports whether a score is in the league, tagged are the links of
+// the tag of a tag league.
+func (l League) member(s *repository.Score, tagged map[string]bool) bool {
+	switch l.Kind {
+	case KindLanguage:
+		return s.Language != nil && strings.EqualFold(*s.Language, l.Name)
+	case KindDistro:
+		return s.Distro != nil && strings.EqualFold(*s.Distro, l.Name)
+	case KindDistroOnly:
+		return s.Distro != nil && strings.EqualFold(*s.Distro, l.Name) && s.DistroCount != nil && *s.DistroCount == 1
+	case KindTag:
+		return tagged[*s.GitLink]
+	}
+	return false
+}
+
+// Table returns the top projects of the league among scores, all if top
+// is not positive. tagged are the links of the tag of a tag league.
+func (l League) Table(scores []*repository.Score, tagged map[string]bool, top int) []Entry {
+	members := make(map[string]*repository.Score)
+	values := make(map[string]float64)
+	for _, s := range scores {
+		if s.GitLink == nil || s.Score == nil || !l.member(s, tagged) {
+			continue
+		}
+		members[*s.GitLink] = s
+		values[*s.GitLink] = *s.Score
+	}
+	percentiles := score.Percentiles(values)
+
+	ret := make([]Entry, 0, len(members))
+	for link, s := range members {
+		ret = append(ret, Entry{
+			GitLink:           link,
+			Score:             *s.Score,
+			Percentile:        percentiles[link],
+			OverallPercentile: s.Percentile,
+		})
+	}
+	// ties are broken by git link like the published ranking
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Score != ret[j].Score {
+			return ret[i].Score > ret[j].Score
+		}
+		return ret[i].GitLink < ret[j].GitLink
+	})
+	if top > 0 && len(ret) > top {
+		ret = ret[:top]
+	}
+	for i := range ret {
+		ret[i].Rank = i + 1
+	}
+	return ret
+}
+
+// Load returns the top projects of the league by their latest scores.
+func (l League) Load(ac storage.AppDatabaseContext, top int) ([]Entry, error) {
+	var tagged map[string]bool
+	if l.Kind == KindTag {
+		links, err := repository.NewProjectTagRepository(ac).QueryLinks([]string{l.Name})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query tags: %w", err)
+		}
+		tagged = make(map[string]bool, len(links))
+		for _, link := range links {
+			tagged[link] = true
+		}
+	}
+	scores, err := repository.NewScoreRepository(ac).Query()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scores: %w", err)
+	}
+	var all []*repository.Score
+	for s := range scores {
+		all = append(all, s)
+	}
+	return l.Table(all, tagged, top), nil
+}
+
+// WriteTable writes the league table as markdown.
+func (l League) WriteTable(w io.Writer, entries []Entry) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s\n\n", l.Title())
+	if len(entries) == 0 {
+		b.WriteString("No projects.\n\n")
+	} else {
+		b.WriteString("| Rank | Project | Score | Percentile | Overall percentile |\n| ---: | --- | ---: | ---: | ---: |\n")
+		for _, e := range entries {
+			overall := "-"
+			if e.OverallPercentile != nil {
+				overall = fmt.Sprintf("%.1f", *e.OverallPercentile)
+			}
+			fmt.Fprintf(&b, "| %d | %s | %.4f | %.1f | %s |\n", e.Rank, e.GitLink, e.Score, e.Percentile, overall)
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
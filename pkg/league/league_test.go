@@ -0,0 +1,81 @@
+package league
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/samber/lo"
+)
+
+func TestParse(t *testing.T) {
+	l, err := Parse("Language=Rust")
+	if err != nil || l != (League{Kind: KindLanguage, Name: "Rust"}) {
+		t.Errorf("Parse() = %v, %v", l, err)
+	}
+	for _, s := range []string{"rust", "language=", "stars=1", "tag=Not A Tag"} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q) error = nil", s)
+		}
+	}
+}
+
+func newScore(link string, score float64, language, distro string, distros int) *repository.Score {
+	return &repository.Score{
+		GitLink:     &link,
+		Score:       &score,
+		Language:    &language,
+		Distro:      &distro,
+		DistroCount: &distros,
+		Percentile:  lo.ToPtr(50.0),
+	}
+}
+
+func TestTable(t *testing.T) {
+	scores := []*repository.Score{
+		newScore("a", 0.9, "Rust", "debian", 2),
+		newScore("b", 0.5, "rust", "debian", 1),
+		newScore("c", 0.7, "Go", "debian", 1),
+		newScore("d", 0.3, "Rust", "fedora", 1),
+	}
+	tests := []struct {
+		league League
+		top    int
+		want   []string
+	}{
+		{League{KindLanguage, "rust"}, 0, []string{"a", "b", "d"}},
+		{League{KindLanguage, "Rust"}, 2, []string{"a", "b"}},
+		{League{KindDistro, "debian"}, 0, []string{"a", "c", "b"}},
+		{League{KindDistroOnly, "debian"}, 0, []string{"c", "b"}},
+		{League{KindTag, "scientific"}, 0, []string{"c", "d"}},
+	}
+	tagged := map[string]bool{"c": true, "d": true}
+	for _, tt := range tests {
+		got := tt.league.Table(scores, tagged, tt.top)
+		links := make([]string, len(got))
+		for i, e := range got {
+			links[i] = e.GitLink
+			if e.Rank != i+1 {
+				t.Errorf("%s: rank of %s = %d, want %d", tt.league, e.GitLink, e.Rank, i+1)
+			}
+		}
+		if strings.Join(links, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: Table() = %v, want %v", tt.league, links, tt.want)
+		}
+	}
+
+	table := League{KindDistroOnly, "debian"}.Table(scores, nil, 0)
+	if table[0].Percentile != 100 || table[1].Percentile != 50 {
+		t.Errorf("percentiles = %v, %v, want 100, 50", table[0].Percentile, table[1].Percentile)
+	}
+	var buf bytes.Buffer
+	if err := (League{KindDistroOnly, "debian"}).WriteTable(&buf, table); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"## Top debian-only projects", "| 1 | c | 0.7000 | 100.0 | 50.0 |"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("WriteTable() = %s, want %q", buf.String(), want)
+		}
+	}
+}
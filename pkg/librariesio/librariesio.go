@@ -0,0 +1,119 @@
+// Package librariesio is a client of the Libraries.io API, whose SourceRank
+// and dependent counts of packages are a comparison signal to our scores
+// and fill gaps of packages deps.dev does not know.
+//
+// The API needs an api key and allows 60 requests per minute, requests are
+// throttled by Config.Interval and rate limited ones are retried.
+package librariesio
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/internal/jsonhttp"
+)
+
+const (
+	DefaultBaseURL    = "https://libraries.io/api"
+	DefaultMaxRetries = 3
+	// DefaultInterval keeps to the 60 requests per minute of the API
+	DefaultInterval = time.Second
+
+	// initialBackoff is the delay before the first retry, doubled by every
+	// retry
+	initialBackoff = 10 * time.Second
+)
+
+var (
+	// ErrNotFound is wrapped by errors of unknown packages.
+	ErrNotFound = jsonhttp.ErrNotFound
+	// ErrRateLimited is wrapped by errors of requests still rate limited
+	// after all retries.
+	ErrRateLimited = jsonhttp.ErrRateLimited
+	// ErrServer is wrapped by 5xx errors still failing after all retries.
+	ErrServer = jsonhttp.ErrServer
+	// ErrRequest is wrapped by errors of other status codes, network
+	// errors and malformed responses.
+	ErrRequest = jsonhttp.ErrRequest
+)
+
+type Config struct {
+	// BaseURL is the url of the API, DefaultBaseURL if empty
+	BaseURL string
+	// APIKey is the api key of a Libraries.io account
+	APIKey string
+	// MaxRetries is the number of retries of rate limited, server and
+	// network errors
+	MaxRetries int
+	// Interval is the min delay between requests, DefaultInterval if 0
+	Interval time.Duration
+}
+
+// Platforms are the Libraries.io platforms of the package types of deps.dev,
+// e.g. PYPI is Pypi.
+var Platforms = map[string]string{
+	"npm":   "NPM",
+	"go":    "Go",
+	"maven": "Maven",
+	"pypi":  "Pypi",
+	"nuget": "NuGet",
+	"cargo": "Cargo",
+}
+
+// Package is the part of a package of the API used.
+type Package struct {
+	Name     string `json:"name"`
+	Platform string `json:"platform"`
+	// Rank is the SourceRank of the package
+	Rank int `json:"rank"`
+	// DependentsCount is the number of packages depending on the package,
+	// and DependentReposCount the number of repos
+	DependentsCount     int    `json:"dependents_count"`
+	DependentReposCount int    `json:"dependent_repos_count"`
+	RepositoryURL       string `json:"repository_url"`
+}
+
+// Client is a client of the API.
+type Client struct {
+	http    *jsonhttp.Client
+	baseURL string
+	apiKey  string
+}
+
+// New returns a client sending requests by client, http.DefaultClient if
+// nil.
+func New(client *http.Client, config *Config) *Client {
+	interval := config.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	c := &Client{
+		http: jsonhttp.New(client, jsonhttp.Config{
+			MaxRetries:     config.MaxRetries,
+			InitialBackoff: initialBackoff,
+			Interval:       interval,
+			Secrets:        []string{"api_key"},
+		}),
+		baseURL: strings.TrimSuffix(config.BaseURL, "/"),
+		apiKey:  config.APIKey,
+	}
+	if c.baseURL == "" {
+		c.baseURL = DefaultBaseURL
+	}
+	return c
+}
+
+// GetPackage returns a package of a platform, e.g. NPM, see Platforms.
+func (c *Client) GetPackage(ctx context.Context, platform, name string) (*Package, error) {
+	// scoped npm names are escaped as %40scope%2Fname
+	name = strings.ReplaceAll(url.PathEscape(name), "@", "%40")
+	u := c.baseURL + "/" + url.PathEscape(platform) + "/" + name + "?" + url.Values{"api_key": {c.apiKey}}.Encode()
+	var ret Package
+	if err := c.http.GetJSON(ctx, u, &ret); err != nil {
+		return nil, err
+	}
+	return &ret, nil
+}
@@ -0,0 +1,45 @@
+package librariesio
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetPackage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("api_key") != "secret" {
+			t.Errorf("api_key = %q", r.URL.Query().Get("api_key"))
+		}
+		switch r.URL.EscapedPath() {
+		case "/NPM/%40babel%2Fcore":
+			w.Write([]byte(`{"name": "@babel/core", "platform": "NPM", "rank": 28, "dependents_count": 16000,
+				"dependent_repos_count": 2100000, "repository_url": "https://github.com/babel/babel"}`))
+		default:
+			http.Error(w, `{"error": "Not Found"}`, http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	// the throttle is tested by jsonhttp
+	c := New(server.Client(), &Config{BaseURL: server.URL, APIKey: "secret", Interval: time.Nanosecond})
+
+	pkg, err := c.GetPackage(context.Background(), "NPM", "@babel/core")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Rank != 28 || pkg.DependentsCount != 16000 || pkg.DependentReposCount != 2100000 {
+		t.Errorf("GetPackage() = %+v", pkg)
+	}
+
+	_, err = c.GetPackage(context.Background(), "NPM", "unknown")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetPackage() error = %v, want ErrNotFound", err)
+	}
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("error %q leaks the api key", err)
+	}
+}
@@ -0,0 +1,204 @@
+// Package compliance keeps enumerators within what each forge allows, so
+// that large enumerations don't get our IPs banned.
+//
+// A Guard is created for each forge. Before every request it checks the
+// robots.txt of the target host, waits for the crawl-delay or the interval
+// derived from the documented API rate limits, whichever is longer, and
+// consumes one request from the forge's budget. Budgets are tracked in the
+// database, so they are shared by all enumerators running against the same
+// forge.
+package compliance
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/errors"
+)
+
+var (
+	ErrDisallowed      = errors.New("disallowed by robots.txt")
+	ErrBudgetExhausted = errors.Mark(errors.New("request budget exhausted"), errors.RateLimited)
+)
+
+// UserAgent is sent when fetching robots.txt and used to select the
+// robots.txt group.
+const UserAgent = "criticality-score-enumerator"
+
+type Policy struct {
+	// Interval is the min time between two requests
+	Interval time.Duration
+	// Budget is the max number of requests in BudgetWindow, 0 means no limit
+	Budget       int
+	BudgetWindow time.Duration
+	// RespectRobots enables robots.txt checking, API hosts whose usage is
+	// governed by API terms instead may disable it
+	RespectRobots bool
+}
+
+// DefaultPolicies are derived from the documented rate limits of each forge,
+// with some margin.
+var DefaultPolicies = map[string]Policy{
+	// search API allows 30 requests per minute, GraphQL 5000 points per
+	// hour; api.github.com/robots.txt disallows all crawlers, the API terms
+	// apply instead
+	"github": {Interval: 2 * time.Second, Budget: 4500, BudgetWindow: time.Hour},
+	// gitlab.com allows 2000 authenticated API requests per minute
+	"gitlab": {Interval: time.Second, Budget: 20000, BudgetWindow: time.Hour, RespectRobots: true},
+	// bitbucket allows 1000 repository API requests per hour
+	"bitbucket": {Interval: 4 * time.Second, Budget: 900, BudgetWindow: time.Hour, RespectRobots: true},
+}
+
+// BudgetStore tracks the requests made in each budget window.
+type BudgetStore interface {
+	// Consume counts one request, it returns false if the limit of the
+	// window is reached
+	Consume(forge string, windowStart time.Time, limit int) (bool, error)
+}
+
+type Guard struct {
+	forge  string
+	policy Policy
+	budget BudgetStore
+	client *http.Client
+
+	mu     sync.Mutex
+	robots map[string]*Robots
+	next   time.Time
+
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+// NewGuard returns a guard of forge, budget may be nil if the policy has no
+// budget.
+func NewGuard(forge string, policy Policy, budget BudgetStore) *Guard {
+	return &Guard{
+		forge:  forge,
+		policy: policy,
+		budget: budget,
+		client: &http.Client{Timeout: 10 * time.Second},
+		robots: make(map[string]*Robots),
+		now:    time.Now,
+		sleep:  time.Sleep,
+	}
+}
+
+// NewDefaultGuard returns a guard of forge with its default policy.
+func NewDefaultGuard(forge string, budget BudgetStore) (*Guard, error) {
+	policy, ok := DefaultPolicies[forge]
+	if !ok {
+		return nil, fmt.Errorf("no compliance policy for %s", forge)
+	}
+	return NewGuard(forge, policy, budget), nil
+}
+
+// robotsOf returns the robots.txt rules of the host of u. A missing or
+// unreachable robots.txt allows everything.
+func (g *Guard) robotsOf(u *url.URL) *Robots {
+	host := u.Scheme + "://" + u.Host
+	if r, ok := g.robots[host]; ok {
+		return r
+	}
+
+	r := &Robots{}
+	req, err := http.NewRequest(http.MethodGet, host+"/robots.txt", nil)
+	if err == nil {
+		req.Header.Set("User-Agent", UserAgent)
+		if resp, err := g.client.Do(req); err == nil {
+			if resp.StatusCode == http.StatusOK {
+				if parsed, err := ParseRobots(resp.Body, UserAgent); err == nil {
+					r = parsed
+				}
+			}
+			resp.Body.Close()
+		}
+	}
+	g.robots[host] = r
+	return r
+}
+
+// Wait blocks until a request to rawURL is allowed. It returns
+// ErrDisallowed if robots.txt disallows the URL, and ErrBudgetExhausted if
+// the budget of the current window is used up.
+func (g *Guard) Wait(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	interval := g.policy.Interval
+	if g.policy.RespectRobots {
+		robots := g.robotsOf(u)
+		if !robots.Allowed(u.RequestURI()) {
+			return fmt.Errorf("%w: %s", ErrDisallowed, rawURL)
+		}
+		interval = max(interval, robots.CrawlDelay())
+	}
+
+	if g.policy.Budget > 0 && g.budget != nil {
+		window := g.now().Truncate(g.policy.BudgetWindow)
+		ok, err := g.budget.Consume(g.forge, window, g.policy.Budget)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%w: %s made %d requests since %s",
+				ErrBudgetExhausted, g.forge, g.policy.Budget, window.Format(time.RFC3339))
+		}
+	}
+
+	if d := g.next.Sub(g.now()); d > 0 {
+		g.sleep(d)
+	}
+	g.next = g.now().Add(interval)
+	return nil
+}
+
+type roundTripper struct {
+	inner http.RoundTripper
+	guard *Guard
+}
+
+// NewRoundTripper returns a http.RoundTripper which waits for guard before
+// every request.
+func NewRoundTripper(inner http.RoundTripper, guard *Guard) http.RoundTripper {
+	return &roundTripper{inner: inner, guard: guard}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (rt *roundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	if err := rt.guard.Wait(r.URL.String()); err != nil {
+		return nil, err
+	}
+	return rt.inner.RoundTrip(r)
+}
+
+// MemoryBudgetStore keeps budgets in memory, it is used when no database is
+// available and in tests.
+type MemoryBudgetStore struct {
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+func NewMemoryBudgetStore() *MemoryBudgetStore {
+	return &MemoryBudgetStore{requests: make(map[string]int)}
+}
+
+// Consume implements BudgetStore.
+func (m *MemoryBudgetStore) Consume(forge string, windowStart time.Time, limit int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := forge + "@" + windowStart.String()
+	if m.requests[key] >= limit {
+		return false, nil
+	}
+	m.requests[key]++
+	return true, nil
+}
@@ -0,0 +1,101 @@
+package compliance
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testRobots = `
+# comment
+User-agent: *
+Disallow: /private
+Allow: /private/public
+Disallow: /*.json$
+Crawl-delay: 10
+
+User-agent: other-bot
+User-agent: criticality-score
+Disallow: /
+Allow: /api/
+Crawl-delay: 0.5
+`
+
+func TestParseRobots(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		path      string
+		want      bool
+	}{
+		{"somebot", "/", true},
+		{"somebot", "/private/x", false},
+		{"somebot", "/private/public/x", true},
+		{"somebot", "/data.json", false},
+		{"somebot", "/data.json?x=1", true},
+		{"criticality-score-enumerator", "/", false},
+		{"criticality-score-enumerator", "/api/v4/projects?page=1", true},
+	}
+	for _, tt := range tests {
+		r, err := ParseRobots(strings.NewReader(testRobots), tt.userAgent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := r.Allowed(tt.path); got != tt.want {
+			t.Errorf("Allowed(%q) for %s = %v, want %v", tt.path, tt.userAgent, got, tt.want)
+		}
+	}
+
+	r, _ := ParseRobots(strings.NewReader(testRobots), "somebot")
+	if r.CrawlDelay() != 10*time.Second {
+		t.Errorf("CrawlDelay() = %v, want 10s", r.CrawlDelay())
+	}
+	r, _ = ParseRobots(strings.NewReader(testRobots), UserAgent)
+	if r.CrawlDelay() != 500*time.Millisecond {
+		t.Errorf("CrawlDelay() = %v, want 500ms", r.CrawlDelay())
+	}
+}
+
+func TestGuard(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			fmt.Fprint(w, testRobots)
+		}
+	}))
+	defer server.Close()
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var slept time.Duration
+	g := NewGuard("test", Policy{Interval: 100 * time.Millisecond, Budget: 2, BudgetWindow: time.Hour, RespectRobots: true},
+		NewMemoryBudgetStore())
+	g.now = func() time.Time { return now }
+	g.sleep = func(d time.Duration) {
+		slept += d
+		now = now.Add(d)
+	}
+
+	if err := g.Wait(server.URL + "/private"); !errors.Is(err, ErrDisallowed) {
+		t.Errorf("Wait() = %v, want ErrDisallowed", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := g.Wait(server.URL + "/api/x"); err != nil {
+			t.Fatalf("Wait() = %v", err)
+		}
+	}
+	// crawl-delay is longer than the interval
+	if slept != 500*time.Millisecond {
+		t.Errorf("slept %v, want 500ms", slept)
+	}
+	if err := g.Wait(server.URL + "/api/x"); !errors.Is(err, ErrBudgetExhausted) {
+		t.Errorf("Wait() = %v, want ErrBudgetExhausted", err)
+	}
+
+	// a new window has a new budget
+	now = now.Add(time.Hour)
+	if err := g.Wait(server.URL + "/api/x"); err != nil {
+		t.Errorf("Wait() = %v", err)
+	}
+}
@@ -0,0 +1,12 @@
+package compliance
+
+import (
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+)
+
+// NewDBBudgetStore returns a budget store backed by the
+// forge_request_budgets table.
+func NewDBBudgetStore(ac storage.AppDatabaseContext) BudgetStore {
+	return repository.NewForgeRequestBudgetRepository(ac)
+}
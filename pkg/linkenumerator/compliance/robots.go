@@ -0,0 +1,147 @@
+package compliance
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+	re      *regexp.Regexp
+}
+
+// Robots is the part of a robots.txt which applies to one user agent.
+type Robots struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// compileRobotsPattern converts a robots.txt path pattern with * and $
+// wildcards to a regex.
+func compileRobotsPattern(pattern string) *regexp.Regexp {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	for i, p := range parts {
+		parts[i] = regexp.QuoteMeta(p)
+	}
+	expr := "^" + strings.Join(parts, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
+
+// ParseRobots parses a robots.txt and returns the rules of the group which
+// matches userAgent best, the * group is used if no group names it.
+func ParseRobots(r io.Reader, userAgent string) (*Robots, error) {
+	groups := make([]*robotsGroup, 0)
+	var current *robotsGroup
+	// a group starts with consecutive user-agent lines
+	inAgents := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				current = &robotsGroup{}
+				groups = append(groups, current)
+				inAgents = true
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			continue
+		case "allow", "disallow":
+			// an empty disallow allows everything
+			if current != nil && value != "" {
+				current.rules = append(current.rules, robotsRule{
+					allow:   key == "allow",
+					pattern: value,
+					re:      compileRobotsPattern(value),
+				})
+			}
+		case "crawl-delay":
+			if current != nil {
+				if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+					current.crawlDelay = time.Duration(seconds * float64(time.Second))
+				}
+			}
+		}
+		inAgents = false
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	userAgent = strings.ToLower(userAgent)
+	var matched, wildcard *robotsGroup
+	for _, g := range groups {
+		for _, agent := range g.agents {
+			if agent == "*" {
+				if wildcard == nil {
+					wildcard = g
+				}
+			} else if matched == nil && userAgent != "" && strings.Contains(userAgent, agent) {
+				matched = g
+			}
+		}
+	}
+	if matched == nil {
+		matched = wildcard
+	}
+
+	ret := &Robots{}
+	if matched != nil {
+		ret.rules = matched.rules
+		ret.crawlDelay = matched.crawlDelay
+	}
+	return ret, nil
+}
+
+// Allowed reports whether path (with the query string if any) may be
+// fetched. The longest matching rule wins, and allow wins on ties.
+func (r *Robots) Allowed(path string) bool {
+	if path == "" {
+		path = "/"
+	}
+	allowed := true
+	longest := -1
+	for _, rule := range r.rules {
+		if !rule.re.MatchString(path) {
+			continue
+		}
+		if len(rule.pattern) > longest || (len(rule.pattern) == longest && rule.allow) {
+			longest = len(rule.pattern)
+			allowed = rule.allow
+		}
+	}
+	return allowed
+}
+
+// CrawlDelay returns the crawl-delay of the group, 0 if not set.
+func (r *Robots) CrawlDelay() time.Duration {
+	return r.crawlDelay
+}
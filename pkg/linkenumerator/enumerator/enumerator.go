@@ -3,6 +3,7 @@ package enumerator
 import (
 	"time"
 
+	"github.com/HUSTSecLab/criticality_score/pkg/linkenumerator/compliance"
 	"github.com/HUSTSecLab/criticality_score/pkg/linkenumerator/writer"
 	"github.com/imroc/req/v3"
 	"github.com/sirupsen/logrus"
@@ -11,6 +12,8 @@ import (
 type Enumerator interface {
 	SetWriter(writer writer.Writer)
 	SetToken(token string)
+	// SetGuard enables compliance mode, every request waits for the guard
+	SetGuard(guard *compliance.Guard)
 	Enumerate() error
 }
 
@@ -18,6 +21,7 @@ type enumeratorBase struct {
 	client *req.Client
 	token  string
 	writer writer.Writer
+	guard  *compliance.Guard
 }
 
 func newEnumeratorBase() enumeratorBase {
@@ -35,7 +39,18 @@ func (c *enumeratorBase) SetToken(token string) {
 	c.client.SetCommonBearerAuthToken(token)
 }
 
+func (c *enumeratorBase) SetGuard(guard *compliance.Guard) {
+	c.guard = guard
+}
+
 func (c *enumeratorBase) fetch(url string) (*req.Response, error) {
+	if c.guard != nil {
+		if err := c.guard.Wait(url); err != nil {
+			logrus.Errorf("[Enumerator] fetch skipped: %v", err)
+			return nil, err
+		}
+	}
+
 	res, err := c.client.R().Get(url)
 
 	if err != nil || res.GetStatusCode() != 200 {
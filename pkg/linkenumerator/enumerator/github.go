@@ -14,6 +14,7 @@ import (
 	"github.com/ossf/scorecard/v4/clients/githubrepo/roundtripper"
 	"github.com/ossf/scorecard/v4/log"
 
+	"github.com/HUSTSecLab/criticality_score/pkg/linkenumerator/compliance"
 	"github.com/HUSTSecLab/criticality_score/pkg/linkenumerator/githubapi"
 	"github.com/HUSTSecLab/criticality_score/pkg/linkenumerator/githubsearch"
 	"github.com/HUSTSecLab/criticality_score/pkg/logger"
@@ -112,6 +113,9 @@ func (c *githubEnumerator) Enumerate() error {
 	// Do this before opening the output file to avoid creating an empty file
 	// if we fail to authenticate, or connect to the authentication server.
 	rt := githubapi.NewRetryRoundTripper(roundtripper.NewTransport(ctx, rtLogger), logger.GetDefaultLogger())
+	if c.guard != nil {
+		rt = compliance.NewRoundTripper(rt, c.guard)
+	}
 	httpClient := &http.Client{
 		Transport: rt,
 	}
@@ -15,9 +15,9 @@
 package githubapi
 
 import (
-	"errors"
 	"fmt"
 
+	"github.com/HUSTSecLab/criticality_score/pkg/errors"
 	"github.com/google/go-github/v47/github"
 )
 
@@ -43,7 +43,7 @@ var (
 	// returns a single error with the type "NOT_FOUND".
 	//
 	// It should be used with errors.Is.
-	ErrGraphQLNotFound = errors.New("GraphQL resource not found")
+	ErrGraphQLNotFound = errors.Mark(errors.New("GraphQL resource not found"), errors.NotFound)
 
 	// ErrGraphQLForbidden is an error used to test when GitHub GraphQL query
 	// returns a single error with the type "FORBIDDEN".
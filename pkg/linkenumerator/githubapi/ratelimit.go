@@ -0,0 +1,25 @@
+package githubapi
+
+import "time"
+
+const (
+	// InitialBackoff is the delay of the first retry after a secondary rate
+	// limit without Retry-After.
+	InitialBackoff = time.Minute
+
+	// MaxBackoff caps the exponential backoff.
+	MaxBackoff = 30 * time.Minute
+
+	// rateLimitResetMargin is added to the primary rate limit reset time to
+	// tolerate clock skew.
+	rateLimitResetMargin = 5 * time.Second
+)
+
+// CappedBackoff implements retry.BackoffFn, it doubles the delay up to
+// MaxBackoff.
+func CappedBackoff(d time.Duration) time.Duration {
+	if d <= 0 {
+		return InitialBackoff
+	}
+	return min(d*2, MaxBackoff)
+}
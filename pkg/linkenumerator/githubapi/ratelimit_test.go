@@ -0,0 +1,24 @@
+package githubapi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCappedBackoff(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want time.Duration
+	}{
+		{0, time.Minute},
+		{time.Minute, 2 * time.Minute},
+		{4 * time.Minute, 8 * time.Minute},
+		{20 * time.Minute, MaxBackoff},
+		{MaxBackoff, MaxBackoff},
+	}
+	for _, test := range tests {
+		if got := CappedBackoff(test.d); got != test.want {
+			t.Errorf("CappedBackoff(%v) == %v, want %v", test.d, got, test.want)
+		}
+	}
+}
@@ -43,6 +43,7 @@ func NewRetryRoundTripper(rt http.RoundTripper, logger logger.AppLogger) http.Ro
 	s := &strategies{logger: logger}
 	return retry.NewRoundTripper(rt,
 		retry.InitialDelay(2*time.Minute),
+		retry.Backoff(CappedBackoff),
 		retry.RetryAfter(s.RetryAfter),
 		retry.Strategy(s.SecondaryRateLimit),
 		retry.Strategy(s.ServerError400),
@@ -108,11 +109,12 @@ func (s *strategies) ServerError400(r *http.Response) (retry.RetryStrategy, erro
 
 // SecondaryRateLimit implements retry.RetryStrategyFn.
 func (s *strategies) SecondaryRateLimit(r *http.Response) (retry.RetryStrategy, error) {
-	if r.StatusCode != http.StatusForbidden {
+	// secondary rate limits are reported as either 403 or 429
+	if r.StatusCode != http.StatusForbidden && r.StatusCode != http.StatusTooManyRequests {
 		return retry.NoRetry, nil
 	}
 	logger := s.logger
-	logger.Warn("403: forbidden detected", r.Request.RequestURI, r.Status)
+	logger.Warn("403/429: rate limit suspected", r.Request.RequestURI, r.Status)
 	errorResponse := &github.ErrorResponse{Response: r}
 	data, err := io.ReadAll(r.Body)
 	r.Body.Close()
@@ -148,6 +150,18 @@ func (s *strategies) RetryAfter(r *http.Response) time.Duration {
 		retryAfterSeconds, _ := strconv.ParseInt(v[0], 10, 64) // Error handling is noop.
 		return time.Duration(retryAfterSeconds) * time.Second
 	}
+	// Primary rate limit: no requests remaining until X-RateLimit-Reset,
+	// which is an epoch time in seconds.
+	if r.Header.Get("X-RateLimit-Remaining") == "0" {
+		resetSeconds, err := strconv.ParseInt(r.Header.Get("X-RateLimit-Reset"), 10, 64)
+		if err != nil {
+			return 0
+		}
+		s.logger.Warn("Primary rate limit hit, waiting for reset.")
+		if d := time.Until(time.Unix(resetSeconds, 0)) + rateLimitResetMargin; d > 0 {
+			return d
+		}
+	}
 	return 0
 }
 
@@ -75,6 +75,27 @@ func TestRetryAfter_ZeroTime(t *testing.T) {
 	}
 }
 
+func TestRetryAfter_PrimaryRateLimit(t *testing.T) {
+	reset := time.Now().Add(10 * time.Minute).Unix()
+	r := &http.Response{Header: http.Header{
+		http.CanonicalHeaderKey("X-RateLimit-Remaining"): {"0"},
+		http.CanonicalHeaderKey("X-RateLimit-Reset"):     {fmt.Sprint(reset)},
+	}}
+	if d := newTestStrategies(t).RetryAfter(r); d < 9*time.Minute || d > 11*time.Minute {
+		t.Fatalf("RetryAfter() == %v, want about 10m", d)
+	}
+}
+
+func TestRetryAfter_PrimaryRateLimitRemaining(t *testing.T) {
+	r := &http.Response{Header: http.Header{
+		http.CanonicalHeaderKey("X-RateLimit-Remaining"): {"10"},
+		http.CanonicalHeaderKey("X-RateLimit-Reset"):     {fmt.Sprint(time.Now().Add(time.Hour).Unix())},
+	}}
+	if d := newTestStrategies(t).RetryAfter(r); d != 0 {
+		t.Fatalf("RetryAfter() == %v, want 0", d)
+	}
+}
+
 func TestServerError(t *testing.T) {
 	u, _ := url.Parse("https://api.github.com/repos/example/example")
 	r := &http.Response{
@@ -332,6 +353,7 @@ func TestSecondaryRateLimit_StatusCodes(t *testing.T) {
 		{statusCode: http.StatusConflict, strategy: retry.NoRetry},
 		{statusCode: http.StatusFailedDependency, strategy: retry.NoRetry},
 		{statusCode: http.StatusGone, strategy: retry.NoRetry},
+		{statusCode: http.StatusTooManyRequests, strategy: retry.RetryWithInitialDelay},
 		{statusCode: http.StatusInternalServerError, strategy: retry.NoRetry},
 		{statusCode: http.StatusNotImplemented, strategy: retry.NoRetry},
 		{statusCode: http.StatusBadGateway, strategy: retry.NoRetry},
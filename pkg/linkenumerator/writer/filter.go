@@ -0,0 +1,27 @@
+package writer
+
+// FilterWriter writes the urls kept by a filter to another writer, e.g. the
+// urls in the scope of a deployment.
+type FilterWriter struct {
+	w    Writer
+	keep func(url string) bool
+}
+
+func NewFilterWriter(w Writer, keep func(url string) bool) *FilterWriter {
+	return &FilterWriter{w: w, keep: keep}
+}
+
+func (w *FilterWriter) Open() error {
+	return w.w.Open()
+}
+
+func (w *FilterWriter) Close() error {
+	return w.w.Close()
+}
+
+func (w *FilterWriter) Write(url string) error {
+	if !w.keep(url) {
+		return nil
+	}
+	return w.w.Write(url)
+}
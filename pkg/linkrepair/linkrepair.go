@@ -0,0 +1,200 @@
+// Package linkrepair revalidates the git links stored as join keys, so the
+// metrics of a repo are not split or lost when it moves:
+//
+//   - links drifted from their canonical form, e.g. http:// or .git, and
+//     links of hosts which moved, e.g. git.gnome.org, are rewritten
+//   - links are probed by the smart http endpoint of git, following
+//     redirects, so renamed and transferred repos are found
+//   - links of hosts which were shut down, e.g. code.google.com, and links
+//     answered by 404 are dead, and replacements are suggested from the
+//     homepages and the source repos of their packages
+package linkrepair
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/errors"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitutil/normalize"
+)
+
+// DefaultTimeout is the timeout of a probe of the default client.
+const DefaultTimeout = 15 * time.Second
+
+// userAgent is the user agent of git, some hosts only serve git clients.
+const userAgent = "git/2.43.0 (criticality_score-link-repair)"
+
+type Status string
+
+const (
+	// StatusOK is a link serving its repo
+	StatusOK Status = "ok"
+	// StatusMoved is a link whose repo is at Target
+	StatusMoved Status = "moved"
+	// StatusRepaired is a moved link rewritten to its target
+	StatusRepaired Status = "repaired"
+	// StatusDead is a link whose repo is gone
+	StatusDead Status = "dead"
+	// StatusUnknown is a link which could not be checked, e.g. rate limited
+	// or not served by http, it is left as it is
+	StatusUnknown Status = "unknown"
+)
+
+// ErrShutDown is the error of links of hosts which were shut down.
+var ErrShutDown = errors.Mark(errors.New("host was shut down"), errors.NotFound)
+
+// shutDown are the hosts which were shut down, their repos were archived
+// but can not be cloned.
+var shutDown = map[string]string{
+	"code.google.com": "Google Code was shut down in 2016",
+	"gitorious.org":   "Gitorious was shut down in 2015",
+}
+
+// movedHost is a host whose repos moved to another host.
+type movedHost struct {
+	pattern *regexp.Regexp
+	target  string
+}
+
+// movedHosts are matched against links without the scheme, the target
+// expands the submatches of the pattern.
+var movedHosts = []movedHost{
+	// cgit of GNOME, replaced by GitLab in 2018
+	{regexp.MustCompile(`^git\.gnome\.org/(?:browse/)?([\w.+-]+?)(?:\.git)?/?$`), "https://gitlab.gnome.org/GNOME/$1"},
+}
+
+// Result is the check of a link.
+type Result struct {
+	Link   string
+	Status Status
+	// Target is the link of the repo of a moved link
+	Target string
+	// StatusCode is the status of the final response, 0 if not probed or
+	// the request failed
+	StatusCode int
+	Err        error
+	CheckedAt  time.Time
+}
+
+// Rewrite returns the link of the repo of link without probing it: the
+// link on the new host of moved hosts, and the canonical link otherwise. It
+// returns ErrShutDown for hosts which were shut down.
+func Rewrite(link string) (string, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", err
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if reason, ok := shutDown[host]; ok {
+		return "", fmt.Errorf("%w: %s", ErrShutDown, reason)
+	}
+	rest := host + u.EscapedPath()
+	for _, m := range movedHosts {
+		if m.pattern.MatchString(rest) {
+			return m.pattern.ReplaceAllString(rest, m.target), nil
+		}
+	}
+	return normalize.URL(link)
+}
+
+// Checker checks links.
+type Checker struct {
+	client *http.Client
+}
+
+// NewChecker returns a checker sending requests by client, a client with
+// DefaultTimeout if nil. Redirects are followed by the client.
+func NewChecker(client *http.Client) *Checker {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultTimeout}
+	}
+	return &Checker{client: client}
+}
+
+// Check checks link, the link is rewritten by Rewrite and then probed.
+// Failed probes are results, not errors, as they are what the checker
+// looks for.
+func (c *Checker) Check(ctx context.Context, link string) *Result {
+	r := &Result{Link: link, Status: StatusUnknown, CheckedAt: time.Now()}
+	target, err := Rewrite(link)
+	if errors.Is(err, ErrShutDown) {
+		r.Status, r.Err = StatusDead, err
+		return r
+	}
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	u, _ := url.Parse(target)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		r.Err = fmt.Errorf("%s links are not probed", u.Scheme)
+		return r
+	}
+
+	final, status, err := c.probe(ctx, target)
+	r.StatusCode, r.Err = status, err
+	var dnsErr *net.DNSError
+	switch {
+	case errors.As(err, &dnsErr) && dnsErr.IsNotFound:
+		// the domain is gone, e.g. expired
+		r.Status = StatusDead
+	case err != nil:
+	case status == http.StatusOK:
+		r.Status = StatusOK
+		if canonical, err := normalize.URL(final); err == nil {
+			final = canonical
+		}
+		if sameRepo(final, target) {
+			// keep the case of the link, forges are case insensitive
+			final = target
+		}
+		if final != link {
+			r.Status, r.Target = StatusMoved, final
+		}
+	case status == http.StatusUnauthorized || status == http.StatusNotFound || status == http.StatusGone ||
+		status == http.StatusUnavailableForLegalReasons:
+		// forges ask missing repos for credentials, as they may be private
+		r.Status = StatusDead
+	}
+	return r
+}
+
+// sameRepo returns true if a and b are links of the same repo.
+func sameRepo(a, b string) bool {
+	ka, err := normalize.Key(a)
+	if err != nil {
+		return a == b
+	}
+	kb, err := normalize.Key(b)
+	return err == nil && ka == kb
+}
+
+// probe requests the refs of the repo at link by the smart http protocol
+// of git, and returns the link of the repo after redirects and the status
+// of the final response.
+func (c *Checker) probe(ctx context.Context, link string) (string, int, error) {
+	const refs = "/info/refs"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(link, "/")+refs+"?service=git-upload-pack", nil)
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<10))
+	final := *resp.Request.URL
+	final.RawQuery = ""
+	final.Path = strings.TrimSuffix(final.Path, refs)
+	final.RawPath = ""
+	return final.String(), resp.StatusCode, nil
+}
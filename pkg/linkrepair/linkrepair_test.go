@@ -0,0 +1,109 @@
+package linkrepair
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+// forge serves the refs of repos by path, and redirects moved repos, for
+// every host.
+func forge(t *testing.T, repos map[string]bool, moved map[string]string) *Checker {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		repo := r.Host + r.URL.Path[:len(r.URL.Path)-len("/info/refs")]
+		if to, ok := moved[repo]; ok {
+			http.Redirect(w, r, to+"/info/refs?"+r.URL.RawQuery, http.StatusMovedPermanently)
+			return
+		}
+		if !repos[repo] {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("001e# service=git-upload-pack\n"))
+	}))
+	t.Cleanup(srv.Close)
+	target, _ := url.Parse(srv.URL)
+	return NewChecker(&http.Client{Transport: rewriteTransport{target}})
+}
+
+// rewriteTransport sends every request to target, keeping the host.
+type rewriteTransport struct{ target *url.URL }
+
+func (t rewriteTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	req := r.Clone(r.Context())
+	req.Host = r.URL.Host
+	req.URL.Scheme, req.URL.Host = t.target.Scheme, t.target.Host
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err == nil {
+		resp.Request = r
+	}
+	return resp, err
+}
+
+func TestRewrite(t *testing.T) {
+	tests := []struct {
+		link    string
+		want    string
+		wantErr error
+	}{
+		{"http://www.github.com/foo/bar.git", "https://github.com/foo/bar", nil},
+		{"https://git.gnome.org/browse/gtk", "https://gitlab.gnome.org/GNOME/gtk", nil},
+		{"git://git.gnome.org/glib.git", "https://gitlab.gnome.org/GNOME/glib", nil},
+		{"https://code.google.com/p/foo", "", ErrShutDown},
+		{"https://gitorious.org/foo/bar.git", "", ErrShutDown},
+	}
+	for _, tt := range tests {
+		got, err := Rewrite(tt.link)
+		if got != tt.want || !errors.Is(err, tt.wantErr) {
+			t.Errorf("Rewrite(%s) = %s, %v, want %s, %v", tt.link, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	c := forge(t,
+		map[string]bool{"github.com/foo/bar": true, "github.com/new/bar": true, "gitlab.gnome.org/GNOME/gtk": true},
+		map[string]string{"github.com/old/bar": "https://github.com/new/bar"})
+	tests := []struct {
+		link   string
+		status Status
+		target string
+	}{
+		{"https://github.com/foo/bar", StatusOK, ""},
+		{"http://github.com/foo/bar.git", StatusMoved, "https://github.com/foo/bar"},
+		{"https://github.com/old/bar", StatusMoved, "https://github.com/new/bar"},
+		{"https://git.gnome.org/browse/gtk", StatusMoved, "https://gitlab.gnome.org/GNOME/gtk"},
+		{"https://github.com/gone/bar", StatusDead, ""},
+		{"https://code.google.com/p/foo", StatusDead, ""},
+		{"svn://svn.example.org/foo", StatusUnknown, ""},
+	}
+	for _, tt := range tests {
+		r := c.Check(context.Background(), tt.link)
+		if r.Status != tt.status || r.Target != tt.target {
+			t.Errorf("Check(%s) = %s %s (%v), want %s %s", tt.link, r.Status, r.Target, r.Err, tt.status, tt.target)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	c := forge(t,
+		map[string]bool{"github.com/foo/bar": true, "github.com/new/bar": true},
+		map[string]string{"github.com/old/bar": "https://github.com/new/bar"})
+	got := c.Suggest(context.Background(), "https://code.google.com/p/bar", []string{
+		"https://bar.example.org",             // not a repo
+		"https://github.com/foo/bar/releases", // a page of a repo
+		"git+https://github.com/foo/bar.git",  // the same repo
+		"https://github.com/gone/bar",         // dead
+		"https://github.com/old/bar",          // moved
+		"https://github.com/new/bar/issues",   // the target of the moved repo
+	})
+	want := []string{"https://github.com/foo/bar", "https://github.com/new/bar"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Suggest() = %v, want %v", got, want)
+	}
+}
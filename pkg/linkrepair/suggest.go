@@ -0,0 +1,83 @@
+package linkrepair
+
+import (
+	"context"
+	"errors"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/depsdevclient"
+	"github.com/HUSTSecLab/criticality_score/pkg/gitutil/normalize"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/samber/lo"
+)
+
+// Candidates returns the links which may replace link: the homepages of
+// the distribution packages of link, and the source repos and homepages of
+// its language ecosystem packages as known by deps.dev.
+func Candidates(ctx context.Context, ac storage.AppDatabaseContext, link string) ([]string, error) {
+	var ret []string
+	for _, prefix := range repository.DistPackageTablePrefixes {
+		pkgs, err := repository.NewDistPackageRepository(ac, prefix).GetByGitLink(link)
+		if err != nil {
+			return nil, err
+		}
+		for p := range pkgs {
+			if p.HomePage != nil && *p.HomePage != "" {
+				ret = append(ret, *p.HomePage)
+			}
+		}
+	}
+
+	pkgs, err := repository.NewLangEcosystemPackageRepository(ac).QueryByLink(link)
+	if err != nil {
+		return nil, err
+	}
+	for p := range pkgs {
+		if p.Type == nil || p.Package == nil || p.Version == nil {
+			continue
+		}
+		v, err := depsdevclient.Default().GetVersion(ctx, p.Type.String(), *p.Package, *p.Version)
+		if errors.Is(err, depsdevclient.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range v.Links {
+			if l.Label == "SOURCE_REPO" || l.Label == "HOMEPAGE" {
+				ret = append(ret, l.URL)
+			}
+		}
+	}
+	return lo.Uniq(ret), nil
+}
+
+// Suggest returns the candidates pointing to repos other than the repo of
+// link which are served, in the order of candidates. Links of moved repos
+// are replaced by their targets.
+func (c *Checker) Suggest(ctx context.Context, link string, candidates []string) []string {
+	var ret []string
+	seen := map[string]bool{}
+	for _, candidate := range candidates {
+		repo, ok := normalize.Repo(candidate)
+		if !ok {
+			continue
+		}
+		repo, err := normalize.URL(repo)
+		if err != nil || seen[repo] || sameRepo(repo, link) {
+			continue
+		}
+		seen[repo] = true
+		r := c.Check(ctx, repo)
+		switch r.Status {
+		case StatusOK:
+			ret = append(ret, repo)
+		case StatusMoved:
+			if !seen[r.Target] && !sameRepo(r.Target, link) {
+				seen[r.Target] = true
+				ret = append(ret, r.Target)
+			}
+		}
+	}
+	return ret
+}
@@ -7,10 +7,13 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
 )
 
 var visitedLinks = make(map[string]bool)
@@ -82,9 +85,12 @@ func Check(githubURL string) string {
 
 func FetchAllLinks(db *sql.DB, repolist []string) (map[string][][]string, error) {
 	links := make(map[string][][]string)
-	var query string
 	for _, repo := range repolist {
-		query = fmt.Sprintf("SELECT package, homepage FROM %s_packages WHERE (git_link = '' or git_link = NULL) and homepage != ''", repo)
+		table, err := sqlutil.Table(repository.DistPackageTableName(repository.DistPackageTablePrefix(repo)))
+		if err != nil {
+			return nil, err
+		}
+		query := "SELECT package, homepage FROM " + table + " WHERE (git_link = '' or git_link IS NULL) and homepage != ''"
 		rows, err := db.Query(query)
 		if err != nil {
 			return nil, err
@@ -233,6 +239,11 @@ func ProcessHomepage(packageName string, links []string, homepageURL string, url
 
 func UpdateBatch(db *sql.DB, batchSize int, resultMap map[string]map[string]string) error {
 	for repo, packages := range resultMap {
+		table, err := sqlutil.Table(repository.DistPackageTableName(repository.DistPackageTablePrefix(repo)))
+		if err != nil {
+			return err
+		}
+
 		var updateList []struct {
 			PackageName string
 			GitLink     string
@@ -244,6 +255,9 @@ func UpdateBatch(db *sql.DB, batchSize int, resultMap map[string]map[string]stri
 				GitLink     string
 			}{PackageName: packageName, GitLink: gitLink})
 		}
+		sort.Slice(updateList, func(i, j int) bool {
+			return updateList[i].PackageName < updateList[j].PackageName
+		})
 
 		for i := 0; i < len(updateList); i += batchSize {
 			end := i + batchSize
@@ -251,7 +265,7 @@ func UpdateBatch(db *sql.DB, batchSize int, resultMap map[string]map[string]stri
 				end = len(updateList)
 			}
 
-			query := "UPDATE " + repo + " SET git_link = CASE "
+			query := "UPDATE " + table + " SET git_link = CASE "
 			valueArgs := make([]interface{}, 0, 2*(end-i))
 
 			for idx, item := range updateList[i:end] {
@@ -266,8 +280,6 @@ func UpdateBatch(db *sql.DB, batchSize int, resultMap map[string]map[string]stri
 			}
 			query += strings.Join(valueStrings, ",") + ")"
 
-			valueArgs = append([]interface{}{repo}, valueArgs...)
-
 			_, err := db.Exec(query, valueArgs...)
 			if err != nil {
 				return fmt.Errorf("failed to execute batch update for repo %s: %v", repo, err)
@@ -15,6 +15,8 @@ import (
 	"time"
 
 	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/linkenumerator/githubapi"
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
 	"github.com/PuerkitoBio/goquery"
 )
@@ -81,8 +83,12 @@ func fetchGitLink(db *sql.DB) []string {
 
 func fetchDesTopic(gitLink string, GitHubToken string) *RepoInfo {
 	var owner, repo string
+	// the timeout applies to each attempt, so that waiting for rate limits
+	// is not cut short
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = time.Second * 10
 	client := &http.Client{
-		Timeout: time.Second * 10,
+		Transport: githubapi.NewRetryRoundTripper(transport, logger.GetDefaultLogger()),
 	}
 	if strings.HasSuffix(gitLink, ".git") {
 		gitLink = strings.TrimSuffix(gitLink, ".git")
@@ -1,10 +1,12 @@
 package llm_test
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/HUSTSecLab/criticality_score/pkg/llm"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
 )
 
 func TestUpdateBatch(t *testing.T) {
@@ -15,23 +17,26 @@ func TestUpdateBatch(t *testing.T) {
 	defer db.Close()
 
 	resultMap := map[string]map[string]string{
-		"repo1": {
+		"debian": {
 			"package1": "https://gitlink1.com",
 			"package2": "https://gitlink2.com",
 		},
-		"repo2": {
+		"arch": {
 			"package3": "https://gitlink3.com",
 		},
 	}
 
 	batchSize := 2
 
-	mock.ExpectExec(`UPDATE repo1 SET git_link = CASE`).
-		WithArgs("repo1", "package1", "https://gitlink1.com", "package2", "https://gitlink2.com").
+	// repositories are updated in map order
+	mock.MatchExpectationsInOrder(false)
+
+	mock.ExpectExec(`UPDATE "debian_packages" SET git_link = CASE`).
+		WithArgs("package1", "https://gitlink1.com", "package2", "https://gitlink2.com").
 		WillReturnResult(sqlmock.NewResult(1, 2))
 
-	mock.ExpectExec(`UPDATE repo2 SET git_link = CASE`).
-		WithArgs("repo2", "package3", "https://gitlink3.com").
+	mock.ExpectExec(`UPDATE "arch_packages" SET git_link = CASE`).
+		WithArgs("package3", "https://gitlink3.com").
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	err = llm.UpdateBatch(db, batchSize, resultMap)
@@ -39,6 +44,11 @@ func TestUpdateBatch(t *testing.T) {
 		t.Errorf("UpdateBatch failed: %v", err)
 	}
 
+	err = llm.UpdateBatch(db, batchSize, map[string]map[string]string{"repo1; DROP TABLE scores": {"p": "l"}})
+	if !errors.Is(err, sqlutil.ErrUnknownTable) {
+		t.Errorf("UpdateBatch with unknown repo: %v", err)
+	}
+
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("there were unmet expectations: %v", err)
 	}
@@ -0,0 +1,116 @@
+package mailinglist
+
+import (
+	"bufio"
+	"io"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/analysis/bots"
+)
+
+// Activity is the activity of the mailing lists of a project in a window.
+type Activity struct {
+	// Messages is the number of distinct messages
+	Messages int
+	// Senders is the number of distinct senders, bots are left out
+	Senders int
+	// Patches is the number of patches submitted, i.e. messages with a
+	// [PATCH] subject which are not replies
+	Patches int
+	// Reviewers is the number of distinct people giving Reviewed-by,
+	// Acked-by or Tested-by trailers
+	Reviewers int
+}
+
+var (
+	patchSubject  = regexp.MustCompile(`(?i)^\s*\[[^\]]*\bPATCH\b[^\]]*\]`)
+	reviewTrailer = regexp.MustCompile(`(?i)^\s*(Reviewed|Acked|Tested)-by:\s*(.+)$`)
+)
+
+// Tracker collects the activity of messages sent in a window, messages
+// posted to several lists of a project count once.
+type Tracker struct {
+	since     time.Time
+	until     time.Time
+	ids       map[string]bool
+	senders   map[string]bool
+	reviewers map[string]bool
+	messages  int
+	patches   int
+}
+
+// NewTracker returns a tracker of messages sent in [since, until).
+func NewTracker(since, until time.Time) *Tracker {
+	return &Tracker{
+		since:     since,
+		until:     until,
+		ids:       make(map[string]bool),
+		senders:   make(map[string]bool),
+		reviewers: make(map[string]bool),
+	}
+}
+
+// address returns the lower cased email of an address header, pipermail
+// obfuscates it as "user at example.com (Name)".
+func address(s string) string {
+	if a, err := mail.ParseAddress(s); err == nil {
+		return strings.ToLower(a.Address)
+	}
+	s = strings.Replace(s, " at ", "@", 1)
+	if a, err := mail.ParseAddress(s); err == nil {
+		return strings.ToLower(a.Address)
+	}
+	return ""
+}
+
+// Add records a message, messages sent out of the window, without a valid
+// date or seen before are ignored.
+func (t *Tracker) Add(msg *mail.Message) {
+	date, err := msg.Header.Date()
+	if err != nil || date.Before(t.since) || !date.Before(t.until) {
+		return
+	}
+	if id := msg.Header.Get("Message-Id"); id != "" {
+		if t.ids[id] {
+			return
+		}
+		t.ids[id] = true
+	}
+	t.messages++
+
+	from := address(msg.Header.Get("From"))
+	if from != "" && !bots.IsBot(from) {
+		t.senders[from] = true
+	}
+
+	subject := msg.Header.Get("Subject")
+	if patchSubject.MatchString(subject) {
+		t.patches++
+	}
+
+	scanner := bufio.NewScanner(io.LimitReader(msg.Body, maxMessageSize))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxMessageSize)
+	for scanner.Scan() {
+		// quoted trailers are counted by the message they were given in
+		m := reviewTrailer.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		if reviewer := address(m[2]); reviewer != "" && !bots.IsBot(reviewer) {
+			t.reviewers[reviewer] = true
+		}
+	}
+}
+
+// Activity returns the activity recorded.
+func (t *Tracker) Activity() Activity {
+	return Activity{
+		Messages:  t.messages,
+		Senders:   len(t.senders),
+		Patches:   t.patches,
+		Reviewers: len(t.reviewers),
+	}
+}
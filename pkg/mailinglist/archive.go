@@ -0,0 +1,171 @@
+// Package mailinglist ingests the public archives of mailing lists, so the
+// activity of projects coordinating by email, e.g. the Linux kernel or
+// Postgres, is not only measured by their git history.
+//
+// Three archive kinds are supported:
+//
+//   - public-inbox, e.g. https://lore.kernel.org/linux-mm/, whose search
+//     results are downloaded as one mbox
+//   - Mailman 2 (pipermail), e.g. https://mail.example.org/pipermail/dev/,
+//     which has one mbox per month
+//   - Mailman 3 (HyperKitty), e.g.
+//     https://mail.python.org/archives/list/python-dev@python.org/, which
+//     exports a date range as one mbox
+package mailinglist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrUnknownKind is returned for archives of an unknown kind.
+var ErrUnknownKind = errors.New("unknown mailing list archive kind")
+
+// Kind is the software serving an archive.
+type Kind string
+
+const (
+	KindPublicInbox Kind = "public-inbox"
+	KindMailman2    Kind = "mailman2"
+	KindHyperKitty  Kind = "hyperkitty"
+)
+
+// Archive is the archive of a mailing list.
+type Archive struct {
+	// URL is the root of the list, ending with a slash
+	URL  string
+	Kind Kind
+}
+
+// DetectKind guesses the kind of an archive from its url, public-inbox
+// unless the url looks like Mailman.
+func DetectKind(u string) Kind {
+	switch {
+	case strings.Contains(u, "/pipermail/"):
+		return KindMailman2
+	case strings.Contains(u, "/archives/list/"), strings.Contains(u, "/hyperkitty/list/"):
+		return KindHyperKitty
+	default:
+		return KindPublicInbox
+	}
+}
+
+// NewArchive returns the archive at u, the kind is detected if empty.
+func NewArchive(u string, kind Kind) (Archive, error) {
+	if kind == "" {
+		kind = DetectKind(u)
+	}
+	switch kind {
+	case KindPublicInbox, KindMailman2, KindHyperKitty:
+	default:
+		return Archive{}, fmt.Errorf("%w: %s", ErrUnknownKind, kind)
+	}
+	if _, err := url.ParseRequestURI(u); err != nil {
+		return Archive{}, err
+	}
+	if !strings.HasSuffix(u, "/") {
+		u += "/"
+	}
+	return Archive{URL: u, Kind: kind}, nil
+}
+
+// Fetcher downloads the messages of archives.
+type Fetcher struct {
+	client *http.Client
+}
+
+// NewFetcher returns a fetcher using client, nil uses a client without
+// timeout, as the mbox of a busy list may take minutes to download.
+func NewFetcher(client *http.Client) *Fetcher {
+	if client == nil {
+		client = &http.Client{}
+	}
+	return &Fetcher{client: client}
+}
+
+// Fetch calls fn with the messages of the archive sent in [since, until),
+// some kinds also return messages around the window, which fn has to skip.
+func (f *Fetcher) Fetch(ctx context.Context, a Archive, since, until time.Time, fn func(*mail.Message) error) error {
+	switch a.Kind {
+	case KindPublicInbox:
+		// the search results are only downloadable by POST
+		q := url.Values{
+			"q": {fmt.Sprintf("d:%s..%s", since.UTC().Format("20060102"), until.UTC().Format("20060102"))},
+			"x": {"m"},
+		}
+		err := f.fetch(ctx, http.MethodPost, a.URL+"?"+q.Encode(), fn)
+		// a search without results is not found
+		if errors.Is(err, errNotFound) {
+			return nil
+		}
+		return err
+	case KindMailman2:
+		for month := time.Date(since.Year(), since.Month(), 1, 0, 0, 0, 0, time.UTC); month.Before(until); month = month.AddDate(0, 1, 0) {
+			base := a.URL + month.Format("2006-January") + ".txt"
+			// small lists are archived without compression, and months
+			// without messages are not archived at all
+			err := f.fetch(ctx, http.MethodGet, base+".gz", fn)
+			if errors.Is(err, errNotFound) {
+				err = f.fetch(ctx, http.MethodGet, base, fn)
+			}
+			if err != nil && !errors.Is(err, errNotFound) {
+				return err
+			}
+		}
+		return nil
+	case KindHyperKitty:
+		// the list address is the last part of the url
+		name := a.URL[strings.LastIndex(strings.TrimSuffix(a.URL, "/"), "/")+1 : len(a.URL)-1]
+		q := url.Values{
+			"start": {since.UTC().Format("2006-01-02")},
+			"end":   {until.UTC().Format("2006-01-02")},
+		}
+		return f.fetch(ctx, http.MethodGet, a.URL+"export/"+url.PathEscape(name)+".mbox.gz?"+q.Encode(), fn)
+	}
+	return fmt.Errorf("%w: %s", ErrUnknownKind, a.Kind)
+}
+
+var errNotFound = errors.New("archive not found")
+
+// fetch reads the mbox at u.
+func (f *Fetcher) fetch(ctx context.Context, method, u string, fn func(*mail.Message) error) error {
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s %s", errNotFound, method, u)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: %s", method, u, resp.Status)
+	}
+	return ReadMbox(resp.Body, fn)
+}
+
+// Collect returns the activity of the archives of a project in [since,
+// until).
+func (f *Fetcher) Collect(ctx context.Context, archives []Archive, since, until time.Time) (Activity, error) {
+	tracker := NewTracker(since, until)
+	for _, a := range archives {
+		err := f.Fetch(ctx, a, since, until, func(msg *mail.Message) error {
+			tracker.Add(msg)
+			return nil
+		})
+		if err != nil {
+			return Activity{}, fmt.Errorf("%s: %w", a.URL, err)
+		}
+	}
+	return tracker.Activity(), nil
+}
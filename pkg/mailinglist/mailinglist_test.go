@@ -0,0 +1,167 @@
+package mailinglist
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+const mbox = `From alice@example.com Mon Jan  6 10:00:00 2025
+From: Alice <alice@example.com>
+Subject: [PATCH v2 1/2] mm: fix a leak
+Date: Mon, 6 Jan 2025 10:00:00 +0000
+Message-Id: <1@example.com>
+
+The patch.
+>From the changelog.
+
+From bob@example.com Tue Jan  7 10:00:00 2025
+From: bob at example.com (Bob)
+Subject: Re: [PATCH v2 1/2] mm: fix a leak
+Date: Tue, 7 Jan 2025 10:00:00 +0000
+Message-Id: <2@example.com>
+
+> Reviewed-by: Quoted <quoted@example.com>
+Reviewed-by: Bob <bob@example.com>
+Acked-by: Carol <carol@example.com>
+
+From bot@example.com Wed Jan  8 10:00:00 2025
+From: dependabot[bot] <49699333+dependabot[bot]@users.noreply.github.com>
+Subject: ping
+Date: Wed, 8 Jan 2025 10:00:00 +0000
+Message-Id: <3@example.com>
+
+From old@example.com Mon Jan  6 10:00:00 2020
+From: Old <old@example.com>
+Subject: [PATCH] too old
+Date: Mon, 6 Jan 2020 10:00:00 +0000
+Message-Id: <4@example.com>
+
+`
+
+var (
+	since = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	until = time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+)
+
+func gzipped(t *testing.T, s string) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadMbox(t *testing.T) {
+	for name, data := range map[string][]byte{"plain": []byte(mbox), "gzip": gzipped(t, mbox)} {
+		var subjects []string
+		err := ReadMbox(bytes.NewReader(data), func(msg *mail.Message) error {
+			subjects = append(subjects, msg.Header.Get("Subject"))
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%s: ReadMbox() error = %v", name, err)
+		}
+		if len(subjects) != 4 || subjects[0] != "[PATCH v2 1/2] mm: fix a leak" || subjects[3] != "[PATCH] too old" {
+			t.Errorf("%s: ReadMbox() subjects = %q", name, subjects)
+		}
+	}
+}
+
+func TestTracker(t *testing.T) {
+	tracker := NewTracker(since, until)
+	add := func() {
+		err := ReadMbox(strings.NewReader(mbox), func(msg *mail.Message) error {
+			tracker.Add(msg)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// messages cross-posted to another list count once
+	add()
+	add()
+
+	want := Activity{Messages: 3, Senders: 2, Patches: 1, Reviewers: 2}
+	if got := tracker.Activity(); got != want {
+		t.Errorf("Activity() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDetectKind(t *testing.T) {
+	tests := map[string]Kind{
+		"https://lore.kernel.org/linux-mm/":                            KindPublicInbox,
+		"https://mail.example.org/pipermail/dev/":                      KindMailman2,
+		"https://mail.python.org/archives/list/python-dev@python.org/": KindHyperKitty,
+		"https://lists.example.org/hyperkitty/list/dev@example.org/":   KindHyperKitty,
+	}
+	for u, want := range tests {
+		if got := DetectKind(u); got != want {
+			t.Errorf("DetectKind(%q) = %v, want %v", u, got, want)
+		}
+	}
+	if _, err := NewArchive("https://example.org/list/", "gmane"); err == nil {
+		t.Error("NewArchive() of an unknown kind error = nil")
+	}
+}
+
+func TestFetch(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.RequestURI())
+		switch r.URL.Path {
+		case "/linux-mm/", "/archives/list/dev@example.org/export/dev@example.org.mbox.gz":
+			w.Write(gzipped(t, mbox))
+		case "/pipermail/dev/2025-January.txt":
+			w.Write([]byte(mbox))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	fetcher := NewFetcher(server.Client())
+	for _, u := range []string{"/linux-mm", "/pipermail/dev/", "/archives/list/dev@example.org/"} {
+		a, err := NewArchive(server.URL+u, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := fetcher.Collect(context.Background(), []Archive{a}, since, until)
+		if err != nil {
+			t.Fatalf("Collect(%s) error = %v", u, err)
+		}
+		if got.Messages != 3 {
+			t.Errorf("Collect(%s) messages = %d, want 3", u, got.Messages)
+		}
+	}
+
+	want := []string{
+		"POST /linux-mm/?q=d%3A20240101..20250131&x=m",
+		"GET /pipermail/dev/2024-January.txt.gz",
+	}
+	for i, w := range want {
+		if requests[i] != w {
+			t.Errorf("request %d = %q, want %q", i, requests[i], w)
+		}
+	}
+	if last := requests[len(requests)-1]; last != "GET /archives/list/dev@example.org/export/dev@example.org.mbox.gz?end=2025-01-31&start=2024-01-01" {
+		t.Errorf("last request = %q", last)
+	}
+
+	// a missing list is an error, unlike a month without messages
+	a, _ := NewArchive(server.URL+"/archives/list/none@example.org/", "")
+	if _, err := fetcher.Collect(context.Background(), []Archive{a}, since, until); err == nil {
+		t.Error("Collect() of a missing list error = nil")
+	}
+}
@@ -0,0 +1,64 @@
+package mailinglist
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/mail"
+)
+
+// maxMessageSize limits the bytes of a message kept in memory, the rest of
+// a larger message, usually attachments, is dropped.
+const maxMessageSize = 1 << 20
+
+// ReadMbox calls fn with every message of the mbox in r, which may be gzip
+// compressed. Messages which can not be parsed are skipped.
+func ReadMbox(r io.Reader, fn func(*mail.Message) error) error {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		br = bufio.NewReader(gz)
+	}
+
+	var buf bytes.Buffer
+	flush := func() error {
+		defer buf.Reset()
+		if buf.Len() == 0 {
+			return nil
+		}
+		msg, err := mail.ReadMessage(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			return nil
+		}
+		return fn(msg)
+	}
+
+	// messages are separated by "From " lines, the From lines in bodies
+	// are escaped as ">From "
+	blank := true
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			if blank && bytes.HasPrefix(line, []byte("From ")) {
+				if ferr := flush(); ferr != nil {
+					return ferr
+				}
+			} else if buf.Len() < maxMessageSize {
+				buf.Write(line)
+			}
+			blank = len(bytes.TrimRight(line, "\r\n")) == 0
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return flush()
+}
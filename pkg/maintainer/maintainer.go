@@ -0,0 +1,257 @@
+// Package maintainer extracts the contacts of packages and projects, from
+// the metadata of distributions, e.g. the Maintainer and Uploaders of
+// Debian or the packager of an RPM, and from the files of repos, e.g.
+// CODEOWNERS and SECURITY.md. The contacts are stored in the maintainers
+// table to support outreach to critical but underfunded projects.
+package maintainer
+
+import (
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/samber/lo"
+)
+
+// Role is how a contact is related to a package or project.
+type Role string
+
+const (
+	// RoleMaintainer is the Maintainer of a Debian package
+	RoleMaintainer Role = "maintainer"
+	// RoleUploader is one of the Uploaders of a Debian package
+	RoleUploader Role = "uploader"
+	// RolePackager is the packager of an RPM
+	RolePackager Role = "packager"
+	// RoleCodeOwner is an owner in the CODEOWNERS of a repo
+	RoleCodeOwner Role = "codeowner"
+	// RoleSecurity is a contact of the security policy of a repo
+	RoleSecurity Role = "security"
+)
+
+// SourceGit is the source of the contacts found in repos, the contacts of
+// distribution packages have the table prefix of the distribution as
+// source.
+const SourceGit = "git"
+
+// Contact is a maintainer, at least one of Name, Email and Handle is set.
+type Contact struct {
+	Role  Role
+	Name  string
+	Email string
+	// Handle is the user or team of a forge without @, e.g. org/team
+	Handle string
+}
+
+// Key returns the identity of the contact, the lower cased email if any,
+// otherwise the handle or the name.
+func (c Contact) Key() string {
+	switch {
+	case c.Email != "":
+		return strings.ToLower(c.Email)
+	case c.Handle != "":
+		return "@" + strings.ToLower(c.Handle)
+	default:
+		return c.Name
+	}
+}
+
+// Dedupe returns the contacts without duplicated roles and keys, the first
+// is kept.
+func Dedupe(contacts []Contact) []Contact {
+	type key struct {
+		role Role
+		key  string
+	}
+	seen := make(map[key]bool, len(contacts))
+	ret := contacts[:0:0]
+	for _, c := range contacts {
+		k := key{c.Role, c.Key()}
+		if k.key == "" || seen[k] {
+			continue
+		}
+		seen[k] = true
+		ret = append(ret, c)
+	}
+	return ret
+}
+
+// validEmail returns true if s looks like an email, RPM packagers are
+// sometimes a url in angle brackets instead.
+func validEmail(s string) bool {
+	at := strings.LastIndexByte(s, '@')
+	return at > 0 && at < len(s)-1 && !strings.ContainsAny(s, " \t/<>")
+}
+
+// splitAddresses splits s at the commas outside quotes and angle brackets.
+func splitAddresses(s string) []string {
+	var ret []string
+	quoted, bracket := false, false
+	start := 0
+	for i, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == '<' && !quoted:
+			bracket = true
+		case r == '>' && !quoted:
+			bracket = false
+		case r == ',' && !quoted && !bracket:
+			ret = append(ret, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(ret, s[start:])
+}
+
+// ParseAddresses returns the contacts of a comma separated list of
+// addresses, e.g. the Uploaders of Debian
+//
+//	"Doe, Jane" <jane@example.org>, John Roe <john@example.org>
+//
+// Addresses may also be bare emails, emails followed by the name in
+// parentheses, or only names.
+func ParseAddresses(s string, role Role) []Contact {
+	var ret []Contact
+	for _, addr := range splitAddresses(s) {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+		c := Contact{Role: role}
+		if lt := strings.IndexByte(addr, '<'); lt >= 0 {
+			email := addr[lt+1:]
+			if gt := strings.IndexByte(email, '>'); gt >= 0 {
+				email = email[:gt]
+			}
+			if email = strings.TrimSpace(email); validEmail(email) {
+				c.Email = email
+			}
+			c.Name = addr[:lt]
+		} else if email, name, ok := strings.Cut(addr, "("); ok && validEmail(strings.TrimSpace(email)) {
+			c.Email = strings.TrimSpace(email)
+			c.Name = strings.TrimSuffix(name, ")")
+		} else if validEmail(addr) {
+			c.Email = addr
+		} else {
+			c.Name = addr
+		}
+		c.Name = strings.Trim(strings.TrimSpace(c.Name), `"`)
+		if c.Name != "" || c.Email != "" {
+			ret = append(ret, c)
+		}
+	}
+	return Dedupe(ret)
+}
+
+// ParseCodeowners returns the owners of a CODEOWNERS file, users and teams
+// are handles and the others are emails.
+func ParseCodeowners(content string) []Contact {
+	var ret []Contact
+	for _, line := range strings.Split(content, "\n") {
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		// the default owners of a section of gitlab, e.g. [Docs] @docs-team,
+		// have no pattern
+		if trimmed := strings.TrimPrefix(strings.TrimSpace(line), "^"); strings.HasPrefix(trimmed, "[") {
+			fields = append([]string{""}, strings.Fields(trimmed[strings.LastIndexByte(trimmed, ']')+1:])...)
+		}
+		if len(fields) < 2 {
+			continue
+		}
+		for _, owner := range fields[1:] {
+			switch {
+			case strings.HasPrefix(owner, "@") && len(owner) > 1:
+				ret = append(ret, Contact{Role: RoleCodeOwner, Handle: owner[1:]})
+			case validEmail(owner):
+				ret = append(ret, Contact{Role: RoleCodeOwner, Email: owner})
+			}
+		}
+	}
+	return Dedupe(ret)
+}
+
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`)
+
+// placeholderDomains are the domains of examples in security policies
+// copied from templates.
+var placeholderDomains = []string{"example.com", "example.org", "example.net", "domain.com"}
+
+// ParseSecurity returns the emails of a security policy, e.g. SECURITY.md
+// or security.txt.
+func ParseSecurity(content string) []Contact {
+	var ret []Contact
+	for _, email := range emailPattern.FindAllString(content, -1) {
+		domain := strings.ToLower(email[strings.LastIndexByte(email, '@')+1:])
+		placeholder := false
+		for _, d := range placeholderDomains {
+			placeholder = placeholder || domain == d
+		}
+		if !placeholder && !strings.HasSuffix(domain, "users.noreply.github.com") {
+			ret = append(ret, Contact{Role: RoleSecurity, Email: email})
+		}
+	}
+	return Dedupe(ret)
+}
+
+// contactDirs are the directories forges look up CODEOWNERS and security
+// policies in.
+var contactDirs = map[string]bool{".": true, ".github": true, ".gitlab": true, "docs": true, ".well-known": true}
+
+// ContactFile returns the role of the contacts in the file of a repo, if
+// it is a CODEOWNERS file or a security policy.
+func ContactFile(name string) (Role, bool) {
+	if !contactDirs[path.Dir(name)] {
+		return "", false
+	}
+	switch strings.ToLower(path.Base(name)) {
+	case "codeowners":
+		return RoleCodeOwner, true
+	case "security.md", "security.rst", "security.txt", "security":
+		return RoleSecurity, true
+	}
+	return "", false
+}
+
+// ParseFile returns the contacts of a file for which ContactFile is true.
+func ParseFile(role Role, content string) []Contact {
+	if role == RoleCodeOwner {
+		return ParseCodeowners(content)
+	}
+	return ParseSecurity(content)
+}
+
+// Save replaces the contacts of the subjects of source, packages of a
+// distribution or git links of SourceGit. Subjects without contacts are
+// cleared.
+func Save(repo repository.MaintainerRepository, source string, contacts map[string][]Contact, at time.Time) error {
+	subjects := make([]string, 0, len(contacts))
+	var rows []*repository.Maintainer
+	for subject, cs := range contacts {
+		subjects = append(subjects, subject)
+		for _, c := range Dedupe(cs) {
+			rows = append(rows, &repository.Maintainer{
+				Source:    &source,
+				Subject:   &subject,
+				Role:      lo.ToPtr(string(c.Role)),
+				Contact:   lo.ToPtr(c.Key()),
+				Name:      optional(c.Name),
+				Email:     optional(c.Email),
+				Handle:    optional(c.Handle),
+				UpdatedAt: &at,
+			})
+		}
+	}
+	return repo.Replace(source, subjects, rows)
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
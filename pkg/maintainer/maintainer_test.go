@@ -0,0 +1,96 @@
+package maintainer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAddresses(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []Contact
+	}{
+		{
+			`Debian QA Group <packages@qa.debian.org>`,
+			[]Contact{{Role: RoleUploader, Name: "Debian QA Group", Email: "packages@qa.debian.org"}},
+		},
+		{
+			`"Doe, Jane" <jane@example.org>, John Roe <john@example.org>, jane@example.org`,
+			[]Contact{
+				{Role: RoleUploader, Name: "Doe, Jane", Email: "jane@example.org"},
+				{Role: RoleUploader, Name: "John Roe", Email: "john@example.org"},
+			},
+		},
+		{
+			`jo@example.org (Jo Bloggs), Fedora Project`,
+			[]Contact{
+				{Role: RoleUploader, Name: "Jo Bloggs", Email: "jo@example.org"},
+				{Role: RoleUploader, Name: "Fedora Project"},
+			},
+		},
+		// a url is not an email
+		{
+			`CentOS BuildSystem <http://bugs.centos.org>`,
+			[]Contact{{Role: RoleUploader, Name: "CentOS BuildSystem"}},
+		},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := ParseAddresses(tt.in, RoleUploader); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseAddresses(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseCodeowners(t *testing.T) {
+	content := `# default owners
+*       @org/core jane@example.org
+/docs/  @writer # docs team
+[Build] @org/build
+^[Optional][2] @org/core
+*.go
+`
+	want := []Contact{
+		{Role: RoleCodeOwner, Handle: "org/core"},
+		{Role: RoleCodeOwner, Email: "jane@example.org"},
+		{Role: RoleCodeOwner, Handle: "writer"},
+		{Role: RoleCodeOwner, Handle: "org/build"},
+	}
+	if got := ParseCodeowners(content); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseCodeowners() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseSecurity(t *testing.T) {
+	content := `# Security Policy
+
+Please report vulnerabilities to [security@project.org](mailto:security@project.org),
+not to security@example.com as in the template, or to Security@Project.org.
+`
+	want := []Contact{{Role: RoleSecurity, Email: "security@project.org"}}
+	if got := ParseSecurity(content); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseSecurity() = %+v, want %+v", got, want)
+	}
+}
+
+func TestContactFile(t *testing.T) {
+	tests := []struct {
+		name string
+		role Role
+		ok   bool
+	}{
+		{"CODEOWNERS", RoleCodeOwner, true},
+		{".github/CODEOWNERS", RoleCodeOwner, true},
+		{"docs/CODEOWNERS", RoleCodeOwner, true},
+		{"SECURITY.md", RoleSecurity, true},
+		{".github/security.md", RoleSecurity, true},
+		{".well-known/security.txt", RoleSecurity, true},
+		{"vendor/lib/SECURITY.md", "", false},
+		{"README.md", "", false},
+	}
+	for _, tt := range tests {
+		if role, ok := ContactFile(tt.name); role != tt.role || ok != tt.ok {
+			t.Errorf("ContactFile(%q) = %q, %v, want %q, %v", tt.name, role, ok, tt.role, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package manifest
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Ecosystems recognized by Detect.
+const (
+	EcosystemNpm       = "npm"
+	EcosystemPyPI      = "pypi"
+	EcosystemCargo     = "cargo"
+	EcosystemMaven     = "maven"
+	EcosystemGradle    = "gradle"
+	EcosystemSbt       = "sbt"
+	EcosystemGo        = "go"
+	EcosystemNuGet     = "nuget"
+	EcosystemRubyGems  = "rubygems"
+	EcosystemComposer  = "composer"
+	EcosystemHex       = "hex"
+	EcosystemPub       = "pub"
+	EcosystemSwift     = "swift"
+	EcosystemCocoaPods = "cocoapods"
+)
+
+// DefaultMaxDepth is the default directory depth scanned by Detect, the
+// root is depth 0. It covers monorepo layouts like packages/foo/package.json.
+const DefaultMaxDepth = 2
+
+var markerFiles = map[string]string{
+	"package.json":          EcosystemNpm,
+	"package-lock.json":     EcosystemNpm,
+	"yarn.lock":             EcosystemNpm,
+	"pnpm-lock.yaml":        EcosystemNpm,
+	".npmrc":                EcosystemNpm,
+	"setup.py":              EcosystemPyPI,
+	"setup.cfg":             EcosystemPyPI,
+	"pyproject.toml":        EcosystemPyPI,
+	"Pipfile":               EcosystemPyPI,
+	"Pipfile.lock":          EcosystemPyPI,
+	"poetry.lock":           EcosystemPyPI,
+	"Cargo.toml":            EcosystemCargo,
+	"Cargo.lock":            EcosystemCargo,
+	"pom.xml":               EcosystemMaven,
+	"build.gradle":          EcosystemGradle,
+	"build.gradle.kts":      EcosystemGradle,
+	"settings.gradle":       EcosystemGradle,
+	"settings.gradle.kts":   EcosystemGradle,
+	"build.sbt":             EcosystemSbt,
+	"go.mod":                EcosystemGo,
+	"go.sum":                EcosystemGo,
+	"go.work":               EcosystemGo,
+	"go.work.sum":           EcosystemGo,
+	"packages.config":       EcosystemNuGet,
+	"packages.lock.json":    EcosystemNuGet,
+	"Directory.Build.props": EcosystemNuGet,
+	"Gemfile":               EcosystemRubyGems,
+	"Gemfile.lock":          EcosystemRubyGems,
+	"composer.json":         EcosystemComposer,
+	"composer.lock":         EcosystemComposer,
+	"mix.exs":               EcosystemHex,
+	"rebar.config":          EcosystemHex,
+	"pubspec.yaml":          EcosystemPub,
+	"Package.swift":         EcosystemSwift,
+	"Podfile":               EcosystemCocoaPods,
+}
+
+var markerExtensions = map[string]string{
+	".csproj":  EcosystemNuGet,
+	".fsproj":  EcosystemNuGet,
+	".vbproj":  EcosystemNuGet,
+	".nuspec":  EcosystemNuGet,
+	".gemspec": EcosystemRubyGems,
+	".podspec": EcosystemCocoaPods,
+}
+
+// skippedDirs contain vendored or generated files, manifests inside them
+// belong to dependencies rather than the repo itself.
+var skippedDirs = map[string]bool{
+	".git":         true,
+	"node_modules": true,
+	"vendor":       true,
+	"third_party":  true,
+	"target":       true,
+	"build":        true,
+	"dist":         true,
+	".venv":        true,
+	"venv":         true,
+	"Pods":         true,
+}
+
+// EcosystemOf returns the ecosystem of a manifest or lock file name.
+func EcosystemOf(filename string) (string, bool) {
+	if e, ok := markerFiles[filename]; ok {
+		return e, true
+	}
+	e, ok := markerExtensions[filepath.Ext(filename)]
+	return e, ok
+}
+
+// Skipped reports whether a slash separated path relative to the repo root
+// is out of maxDepth or inside a vendored directory.
+func Skipped(path string, maxDepth int) bool {
+	dirs := strings.Split(filepath.ToSlash(path), "/")
+	dirs = dirs[:len(dirs)-1]
+	if len(dirs) > maxDepth {
+		return true
+	}
+	return vendored(dirs)
+}
+
+// Vendored reports whether a slash separated path relative to the repo
+// root is inside a vendored or generated directory, e.g. node_modules.
+func Vendored(path string) bool {
+	dirs := strings.Split(filepath.ToSlash(path), "/")
+	return vendored(dirs[:len(dirs)-1])
+}
+
+func vendored(dirs []string) bool {
+	for _, d := range dirs {
+		if skippedDirs[d] {
+			return true
+		}
+	}
+	return false
+}
+
+type Detection struct {
+	Ecosystem string
+	// Path is the manifest file relative to the repository root
+	Path string
+}
+
+// Detect scans dir up to maxDepth and returns every manifest found, sorted
+// by path. A repo may have several ecosystems, e.g. a Go service with a npm
+// frontend.
+func Detect(dir string, maxDepth int) []Detection {
+	ret := make([]Detection, 0)
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, relErr := filepath.Rel(dir, path)
+		if relErr != nil || rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if skippedDirs[d.Name()] || strings.Count(rel, "/") >= maxDepth {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if e, ok := EcosystemOf(d.Name()); ok {
+			ret = append(ret, Detection{Ecosystem: e, Path: rel})
+		}
+		return nil
+	})
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Path < ret[j].Path
+	})
+	return ret
+}
+
+// Ecosystems returns the sorted distinct ecosystems detected in dir.
+func Ecosystems(dir string, maxDepth int) []string {
+	seen := make(map[string]bool)
+	ret := make([]string, 0)
+	for _, d := range Detect(dir, maxDepth) {
+		if !seen[d.Ecosystem] {
+			seen[d.Ecosystem] = true
+			ret = append(ret, d.Ecosystem)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
@@ -0,0 +1,70 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files ...string) {
+	t.Helper()
+	for _, f := range files {
+		path := filepath.Join(dir, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir,
+		"build.gradle.kts",
+		"settings.gradle",
+		"go.mod",
+		"web/package.json",
+		"web/node_modules/left-pad/package.json",
+		"packages/core/composer.json",
+		"packages/core/deep/Gemfile",
+		"src/App.csproj",
+		"README.md",
+	)
+
+	want := []Detection{
+		{Ecosystem: EcosystemGradle, Path: "build.gradle.kts"},
+		{Ecosystem: EcosystemGo, Path: "go.mod"},
+		{Ecosystem: EcosystemComposer, Path: "packages/core/composer.json"},
+		{Ecosystem: EcosystemGradle, Path: "settings.gradle"},
+		{Ecosystem: EcosystemNuGet, Path: "src/App.csproj"},
+		{Ecosystem: EcosystemNpm, Path: "web/package.json"},
+	}
+	if got := Detect(dir, DefaultMaxDepth); !reflect.DeepEqual(got, want) {
+		t.Errorf("Detect() = %v, want %v", got, want)
+	}
+
+	if got, want := Ecosystems(dir, 0), []string{EcosystemGo, EcosystemGradle}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Ecosystems() = %v, want %v", got, want)
+	}
+}
+
+func TestSkipped(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"package.json", false},
+		{"a/b/package.json", false},
+		{"a/b/c/package.json", true},
+		{"node_modules/x/package.json", true},
+		{"vendor/go.mod", true},
+	}
+	for _, tt := range tests {
+		if got := Skipped(tt.path, DefaultMaxDepth); got != tt.want {
+			t.Errorf("Skipped(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Package manifest reads the packages declared by the manifest files of a
+// repository, e.g. the name in package.json or the Maven coordinates in
+// pom.xml, so that a repository can be mapped to its published packages
+// when registries don't know the repository URL.
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Systems use the same names as deps.dev.
+const (
+	SystemNpm   = "NPM"
+	SystemMaven = "MAVEN"
+	SystemCargo = "CARGO"
+	SystemPyPI  = "PYPI"
+	SystemGo    = "GO"
+)
+
+type Package struct {
+	System string
+	Name   string
+	// Path is the manifest file relative to the repository root
+	Path string
+}
+
+type parser func(content []byte) (system string, name string)
+
+var parsers = map[string]parser{
+	"package.json":   parsePackageJSON,
+	"pom.xml":        parsePomXML,
+	"Cargo.toml":     parseCargoToml,
+	"pyproject.toml": parsePyprojectToml,
+	"setup.cfg":      parseSetupCfg,
+	"go.mod":         parseGoMod,
+}
+
+// Packages returns the packages declared by the manifests in dir up to
+// maxDepth, sorted by manifest path.
+func Packages(dir string, maxDepth int) []Package {
+	ret := make([]Package, 0)
+	seen := make(map[Package]bool)
+	for _, d := range Detect(dir, maxDepth) {
+		parse, ok := parsers[filepath.Base(d.Path)]
+		if !ok {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(d.Path)))
+		if err != nil {
+			continue
+		}
+		system, name := parse(content)
+		if name == "" {
+			continue
+		}
+		p := Package{System: system, Name: name, Path: d.Path}
+		if !seen[p] {
+			seen[p] = true
+			ret = append(ret, p)
+		}
+	}
+	return ret
+}
+
+func parsePackageJSON(content []byte) (string, string) {
+	var pkg struct {
+		Name    string `json:"name"`
+		Private bool   `json:"private"`
+	}
+	if json.Unmarshal(content, &pkg) != nil || pkg.Private {
+		return SystemNpm, ""
+	}
+	return SystemNpm, pkg.Name
+}
+
+func parsePomXML(content []byte) (string, string) {
+	var pom struct {
+		GroupID    string `xml:"groupId"`
+		ArtifactID string `xml:"artifactId"`
+		Parent     struct {
+			GroupID string `xml:"groupId"`
+		} `xml:"parent"`
+	}
+	if xml.Unmarshal(content, &pom) != nil {
+		return SystemMaven, ""
+	}
+	groupID := strings.TrimSpace(pom.GroupID)
+	if groupID == "" {
+		// the group id is inherited from the parent
+		groupID = strings.TrimSpace(pom.Parent.GroupID)
+	}
+	artifactID := strings.TrimSpace(pom.ArtifactID)
+	// properties like ${project.groupId} can't be resolved without Maven
+	if groupID == "" || artifactID == "" || strings.Contains(groupID+artifactID, "${") {
+		return SystemMaven, ""
+	}
+	return SystemMaven, groupID + ":" + artifactID
+}
+
+// iniValue returns the value of key in section of a TOML or INI file. It
+// only understands the simple `key = "value"` form used by manifests.
+func iniValue(content []byte, section, key string) string {
+	current := ""
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			current = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		if current != section {
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(k) != key {
+			continue
+		}
+		v = strings.TrimSpace(v)
+		if i := strings.Index(v, " #"); i >= 0 {
+			v = strings.TrimSpace(v[:i])
+		}
+		return strings.Trim(v, `"'`)
+	}
+	return ""
+}
+
+func parseCargoToml(content []byte) (string, string) {
+	return SystemCargo, iniValue(content, "package", "name")
+}
+
+func parsePyprojectToml(content []byte) (string, string) {
+	if name := iniValue(content, "project", "name"); name != "" {
+		return SystemPyPI, name
+	}
+	return SystemPyPI, iniValue(content, "tool.poetry", "name")
+}
+
+func parseSetupCfg(content []byte) (string, string) {
+	return SystemPyPI, iniValue(content, "metadata", "name")
+}
+
+func parseGoMod(content []byte) (string, string) {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "module" {
+			return SystemGo, strings.Trim(fields[1], `"`)
+		}
+	}
+	return SystemGo, ""
+}
@@ -0,0 +1,50 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPackages(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"package.json": `{"name": "@babel/core", "version": "7.0.0"}`,
+		"pom.xml": `<project>
+  <parent><groupId>com.google.guava</groupId></parent>
+  <artifactId>guava</artifactId>
+  <dependencies><dependency><groupId>junit</groupId><artifactId>junit</artifactId></dependency></dependencies>
+</project>`,
+		"Cargo.toml":     "[workspace]\nmembers = [\"a\"]\n\n[package]\nname = \"serde\" # comment\nversion = \"1.0.0\"\n",
+		"pyproject.toml": "[build-system]\nrequires = []\n\n[tool.poetry]\nname = \"poetry-demo\"\n",
+		"go.mod":         "module github.com/example/demo\n\ngo 1.23\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// sorted by path
+	want := []Package{
+		{System: SystemCargo, Name: "serde", Path: "Cargo.toml"},
+		{System: SystemGo, Name: "github.com/example/demo", Path: "go.mod"},
+		{System: SystemNpm, Name: "@babel/core", Path: "package.json"},
+		{System: SystemMaven, Name: "com.google.guava:guava", Path: "pom.xml"},
+		{System: SystemPyPI, Name: "poetry-demo", Path: "pyproject.toml"},
+	}
+	if got := Packages(dir, 0); !reflect.DeepEqual(got, want) {
+		t.Errorf("Packages() = %v, want %v", got, want)
+	}
+}
+
+func TestPackagesSkipped(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"name": "root", "private": true}`), 0644)
+	os.WriteFile(filepath.Join(dir, "pom.xml"), []byte(`<project><groupId>${g}</groupId><artifactId>a</artifactId></project>`), 0644)
+
+	if got := Packages(dir, 0); len(got) != 0 {
+		t.Errorf("Packages() = %v, want empty", got)
+	}
+}
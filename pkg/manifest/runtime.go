@@ -0,0 +1,144 @@
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Runtimes declared by manifests.
+const (
+	RuntimeGo     = "go"
+	RuntimePython = "python"
+	RuntimeNode   = "node"
+)
+
+// Runtime is a runtime version constraint declared by a manifest, e.g.
+// `requires-python = ">=3.8"` in pyproject.toml.
+type Runtime struct {
+	Name string
+	// Constraint is the version constraint in the syntax of the ecosystem,
+	// e.g. ">=3.8, <4" of python or ">=14 <17" of npm
+	Constraint string
+	// Path is the manifest file relative to the repository root
+	Path string
+}
+
+type runtimeParser func(content []byte) (name string, constraint string)
+
+var runtimeParsers = map[string]runtimeParser{
+	"go.mod":         goModRuntime,
+	"package.json":   packageJSONRuntime,
+	"pyproject.toml": pyprojectRuntime,
+	"setup.cfg":      setupCfgRuntime,
+	"setup.py":       setupPyRuntime,
+}
+
+// IsRuntimeManifest reports whether the file name is a manifest which may
+// declare a runtime constraint, so a walker only reads these files.
+func IsRuntimeManifest(filename string) bool {
+	_, ok := runtimeParsers[filename]
+	return ok
+}
+
+// RuntimeOf returns the runtime constraint declared by the manifest at the
+// slash separated path, false if it declares none.
+func RuntimeOf(p string, content []byte) (Runtime, bool) {
+	parse, ok := runtimeParsers[path.Base(p)]
+	if !ok {
+		return Runtime{}, false
+	}
+	name, constraint := parse(content)
+	constraint = strings.TrimSpace(constraint)
+	if constraint == "" {
+		return Runtime{}, false
+	}
+	return Runtime{Name: name, Constraint: constraint, Path: p}, true
+}
+
+// Runtimes returns the runtime constraints declared by the manifests in
+// dir up to maxDepth, sorted by manifest path.
+func Runtimes(dir string, maxDepth int) []Runtime {
+	ret := make([]Runtime, 0)
+	for _, d := range Detect(dir, maxDepth) {
+		if !IsRuntimeManifest(path.Base(d.Path)) {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(d.Path)))
+		if err != nil {
+			continue
+		}
+		if r, ok := RuntimeOf(d.Path, content); ok {
+			ret = append(ret, r)
+		}
+	}
+	return ret
+}
+
+// FormatRuntimes returns the distinct constraints sorted by runtime, e.g.
+// "node >=14; python >=3.8", the manifest paths are dropped.
+func FormatRuntimes(runtimes []Runtime) string {
+	seen := make(map[string]bool)
+	items := make([]string, 0, len(runtimes))
+	for _, r := range runtimes {
+		item := r.Name + " " + r.Constraint
+		if !seen[item] {
+			seen[item] = true
+			items = append(items, item)
+		}
+	}
+	sort.Strings(items)
+	return strings.Join(items, "; ")
+}
+
+// goModRuntime reads the go directive, which is the min go version.
+func goModRuntime(content []byte) (string, string) {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "go" {
+			return RuntimeGo, ">=" + fields[1]
+		}
+	}
+	return RuntimeGo, ""
+}
+
+func packageJSONRuntime(content []byte) (string, string) {
+	var pkg struct {
+		Engines map[string]interface{} `json:"engines"`
+	}
+	if json.Unmarshal(content, &pkg) != nil {
+		return RuntimeNode, ""
+	}
+	// engines is sometimes written as an array by old packages
+	node, _ := pkg.Engines["node"].(string)
+	return RuntimeNode, node
+}
+
+func pyprojectRuntime(content []byte) (string, string) {
+	if v := iniValue(content, "project", "requires-python"); v != "" {
+		return RuntimePython, v
+	}
+	return RuntimePython, iniValue(content, "tool.poetry.dependencies", "python")
+}
+
+func setupCfgRuntime(content []byte) (string, string) {
+	return RuntimePython, iniValue(content, "options", "python_requires")
+}
+
+var pythonRequiresPattern = regexp.MustCompile(`python_requires\s*=\s*["']([^"']+)["']`)
+
+// setupPyRuntime only understands a literal python_requires argument.
+func setupPyRuntime(content []byte) (string, string) {
+	m := pythonRequiresPattern.FindSubmatch(content)
+	if m == nil {
+		return RuntimePython, ""
+	}
+	return RuntimePython, string(m[1])
+}
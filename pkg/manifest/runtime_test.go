@@ -0,0 +1,48 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRuntimes(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":                    "module github.com/example/demo\n\ngo 1.21\n\ntoolchain go1.22.1\n",
+		"web/package.json":          `{"name": "web", "engines": {"node": ">=14 <17"}}`,
+		"pyproject.toml":            "[project]\nname = \"demo\"\nrequires-python = \">=3.8\"\n",
+		"tools/setup.py":            "setup(\n    name='tools',\n    python_requires=\"~=3.7\",\n)\n",
+		"legacy/setup.cfg":          "[metadata]\nname = legacy\n\n[options]\npython_requires = >=2.7, !=3.0.*\n",
+		"poetry/pyproject.toml":     "[tool.poetry.dependencies]\npython = \"^3.9\"\n",
+		"old/package.json":          `{"name": "old", "engines": ["node >= 0.8"]}`,
+		"node_modules/package.json": `{"engines": {"node": "^10"}}`,
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(p), 0755)
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// sorted by path, vendored manifests and invalid engines are ignored
+	want := []Runtime{
+		{Name: RuntimeGo, Constraint: ">=1.21", Path: "go.mod"},
+		{Name: RuntimePython, Constraint: ">=2.7, !=3.0.*", Path: "legacy/setup.cfg"},
+		{Name: RuntimePython, Constraint: "^3.9", Path: "poetry/pyproject.toml"},
+		{Name: RuntimePython, Constraint: ">=3.8", Path: "pyproject.toml"},
+		{Name: RuntimePython, Constraint: "~=3.7", Path: "tools/setup.py"},
+		{Name: RuntimeNode, Constraint: ">=14 <17", Path: "web/package.json"},
+	}
+	got := Runtimes(dir, DefaultMaxDepth)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Runtimes() = %v, want %v", got, want)
+	}
+
+	wantFormat := "go >=1.21; node >=14 <17; python >=2.7, !=3.0.*; python >=3.8; python ^3.9; python ~=3.7"
+	if f := FormatRuntimes(append(got, got[0])); f != wantFormat {
+		t.Errorf("FormatRuntimes() = %q, want %q", f, wantFormat)
+	}
+}
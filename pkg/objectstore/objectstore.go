@@ -0,0 +1,166 @@
+// Package objectstore stores files in S3-compatible object storage, e.g.
+// AWS S3, MinIO or Ceph, without depending on any cloud SDK.
+package objectstore
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/errors"
+)
+
+var ErrNotFound = errors.Mark(errors.New("object not found"), errors.NotFound)
+
+// Store is a flat key-value store of files.
+type Store interface {
+	// Get returns the content of key, or ErrNotFound
+	Get(key string) (io.ReadCloser, error)
+	// Put stores size bytes read from r as key
+	Put(key string, r io.Reader, size int64) error
+	// URI returns the location of key, e.g. s3://bucket/key
+	URI(key string) string
+}
+
+// Config is the config of an S3-compatible store.
+type Config struct {
+	// Endpoint is the URL of the service, e.g. https://s3.amazonaws.com or
+	// http://minio:9000
+	Endpoint  string
+	Bucket    string
+	Region    string
+	AccessKey string
+	SecretKey string
+}
+
+// S3Store is a Store backed by an S3-compatible bucket. Objects are
+// addressed in path style, which is supported by all S3-compatible
+// services.
+type S3Store struct {
+	config Config
+	client *http.Client
+	now    func() time.Time
+}
+
+var _ Store = (*S3Store)(nil)
+
+func NewS3Store(config Config) *S3Store {
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	config.Endpoint = strings.TrimSuffix(config.Endpoint, "/")
+	return &S3Store{config: config, client: http.DefaultClient, now: time.Now}
+}
+
+func (s *S3Store) objectURL(key string) string {
+	segments := strings.Split(strings.TrimPrefix(key, "/"), "/")
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+	return s.config.Endpoint + "/" + url.PathEscape(s.config.Bucket) + "/" + strings.Join(segments, "/")
+}
+
+func (s *S3Store) do(req *http.Request) (*http.Response, error) {
+	signV4(req, unsignedPayload, s.config.AccessKey, s.config.SecretKey, s.config.Region, "s3", s.now())
+	return s.client.Do(req)
+}
+
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("%s %s returned %s: %s", resp.Request.Method, resp.Request.URL, resp.Status, body)
+}
+
+// Get implements Store.
+func (s *S3Store) Get(key string) (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, s.objectURL(key), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+	return resp.Body, nil
+}
+
+// Put implements Store.
+func (s *S3Store) Put(key string, r io.Reader, size int64) error {
+	req, err := http.NewRequest(http.MethodPut, s.objectURL(key), r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+	return nil
+}
+
+// URI implements Store.
+func (s *S3Store) URI(key string) string {
+	return "s3://" + s.config.Bucket + "/" + strings.TrimPrefix(key, "/")
+}
+
+// DirStore is a Store backed by a local directory, e.g. a mounted volume.
+type DirStore struct {
+	Dir string
+}
+
+var _ Store = (*DirStore)(nil)
+
+func (d *DirStore) path(key string) string {
+	return filepath.Join(d.Dir, filepath.FromSlash(strings.TrimPrefix(key, "/")))
+}
+
+// Get implements Store.
+func (d *DirStore) Get(key string) (io.ReadCloser, error) {
+	file, err := os.Open(d.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+// Put implements Store.
+func (d *DirStore) Put(key string, r io.Reader, size int64) error {
+	path := d.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".put-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// URI implements Store.
+func (d *DirStore) URI(key string) string {
+	return d.path(key)
+}
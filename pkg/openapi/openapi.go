@@ -0,0 +1,361 @@
+// Package openapi generates the OpenAPI 3 specification of go-restful web
+// services from their route definitions, so the specification can not
+// drift from the routes:
+//
+//   - Doc and Notes of a route are its summary and description, and the
+//     name of its function is its operation id
+//   - parameters are typed by their DataType, e.g. "integer"
+//   - Writes is the model of the 200 response, and Returns the other
+//     responses, the models are described by their json fields
+//   - Metadata(KeyTags, []string{...}) groups the routes
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/emicklei/go-restful"
+)
+
+// Version is the version of OpenAPI of the documents.
+const Version = "3.0.3"
+
+// KeyTags is the key of the route metadata of the tags of an operation.
+const KeyTags = "openapi.tags"
+
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Servers    []Server            `json:"servers,omitempty"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Server struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem maps the lower case methods of a path to their operations.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string               `json:"operationId,omitempty"`
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+	Deprecated  bool                 `json:"deprecated,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]*Header   `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is the subset of JSON schema describing go types.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Default              string             `json:"default,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+// Build returns the document of the routes of services.
+func Build(info Info, services ...*restful.WebService) *Document {
+	b := &builder{
+		doc: &Document{
+			OpenAPI:    Version,
+			Info:       info,
+			Paths:      make(map[string]PathItem),
+			Components: Components{Schemas: make(map[string]*Schema)},
+		},
+		names: make(map[reflect.Type]string),
+		types: make(map[string]reflect.Type),
+	}
+	for _, service := range services {
+		for _, route := range service.Routes() {
+			item := b.doc.Paths[route.Path]
+			if item == nil {
+				item = make(PathItem)
+				b.doc.Paths[route.Path] = item
+			}
+			item[strings.ToLower(route.Method)] = b.operation(route)
+		}
+	}
+	return b.doc
+}
+
+// AddResponse adds a response to every operation without a response of
+// the code, e.g. of a filter of every route.
+func (d *Document) AddResponse(code int, r *Response) {
+	for _, item := range d.Paths {
+		for _, op := range item {
+			if _, ok := op.Responses[strconv.Itoa(code)]; !ok {
+				op.Responses[strconv.Itoa(code)] = r
+			}
+		}
+	}
+}
+
+// Handler serves the document as json.
+func Handler(doc *Document) http.Handler {
+	body, err := json.MarshalIndent(doc, "", "  ")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	})
+}
+
+type builder struct {
+	doc *Document
+	// names are the component names of struct types, types the types of
+	// the names
+	names map[reflect.Type]string
+	types map[string]reflect.Type
+}
+
+func (b *builder) operation(route restful.Route) *Operation {
+	op := &Operation{
+		OperationID: route.Operation,
+		Summary:     route.Doc,
+		Description: route.Notes,
+		Responses:   make(map[string]*Response),
+		Deprecated:  route.Deprecated,
+	}
+	if tags, ok := route.Metadata[KeyTags].([]string); ok {
+		op.Tags = tags
+	}
+	for _, p := range route.ParameterDocs {
+		if param := parameter(p.Data()); param != nil {
+			op.Parameters = append(op.Parameters, param)
+		}
+	}
+
+	mime := restful.MIME_JSON
+	if len(route.Produces) > 0 {
+		mime = route.Produces[0]
+	}
+	ok := &Response{Description: "OK"}
+	switch {
+	case route.WriteSample != nil:
+		ok.Content = map[string]MediaType{mime: {Schema: b.schema(reflect.TypeOf(route.WriteSample))}}
+	case mime != restful.MIME_JSON:
+		ok.Content = map[string]MediaType{mime: {Schema: &Schema{Type: "string"}}}
+	}
+	op.Responses["200"] = ok
+
+	codes := make([]int, 0, len(route.ResponseErrors))
+	for code := range route.ResponseErrors {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		e := route.ResponseErrors[code]
+		r := &Response{Description: e.Message}
+		if e.Model != nil {
+			r.Content = map[string]MediaType{mime: {Schema: b.schema(reflect.TypeOf(e.Model))}}
+		} else if code >= 400 {
+			// errors of go-restful are plain text
+			r.Content = map[string]MediaType{"text/plain": {Schema: &Schema{Type: "string"}}}
+		}
+		op.Responses[strconv.Itoa(code)] = r
+	}
+	return op
+}
+
+var parameterKinds = map[int]string{
+	restful.PathParameterKind:   "path",
+	restful.QueryParameterKind:  "query",
+	restful.HeaderParameterKind: "header",
+}
+
+// parameter returns the parameter of data, nil for body and form
+// parameters, which the routes do not have.
+func parameter(data restful.ParameterData) *Parameter {
+	in, ok := parameterKinds[data.Kind]
+	if !ok {
+		return nil
+	}
+	s := &Schema{Type: data.DataType, Format: data.DataFormat, Default: data.DefaultValue}
+	switch data.DataType {
+	case "", "string":
+		s.Type = "string"
+	case "int":
+		s.Type = "integer"
+	case "int32", "int64":
+		s.Type, s.Format = "integer", data.DataType
+	case "float", "float64", "double":
+		s.Type, s.Format = "number", "double"
+	case "bool":
+		s.Type = "boolean"
+	}
+	for v := range data.AllowableValues {
+		s.Enum = append(s.Enum, v)
+	}
+	sort.Strings(s.Enum)
+	return &Parameter{
+		Name:        data.Name,
+		In:          in,
+		Description: data.Description,
+		// path parameters are always required by OpenAPI
+		Required: data.Required || in == "path",
+		Schema:   s,
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schema returns the schema of t, structs are components referred to.
+func (b *builder) schema(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Pointer {
+		s := b.schema(t.Elem())
+		if s.Ref != "" {
+			// siblings of $ref are ignored by OpenAPI 3.0
+			return s
+		}
+		s.Nullable = true
+		return s
+	}
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Int32, t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case t.Kind() == reflect.Int64 || t.Kind() == reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case t.Kind() == reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case t.Kind() == reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case t.Kind() == reflect.String:
+		return &Schema{Type: "string"}
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return &Schema{Type: "string", Format: "byte"}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		return &Schema{Type: "array", Items: b.schema(t.Elem())}
+	case t.Kind() == reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schema(t.Elem())}
+	case t.Kind() == reflect.Struct && t.Name() == "":
+		// anonymous structs are inlined
+		s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		b.fields(t, s)
+		sort.Strings(s.Required)
+		return s
+	case t.Kind() == reflect.Struct:
+		return &Schema{Ref: "#/components/schemas/" + b.component(t)}
+	}
+	// interfaces are any value
+	return &Schema{}
+}
+
+// component adds the schema of the struct t to the components, and
+// returns its name.
+func (b *builder) component(t reflect.Type) string {
+	if name, ok := b.names[t]; ok {
+		return name
+	}
+	name := componentName(t)
+	if _, taken := b.types[name]; taken {
+		pkg := t.PkgPath()
+		name = exported(pkg[strings.LastIndex(pkg, "/")+1:]) + name
+	}
+	b.names[t], b.types[name] = name, t
+
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	// added before its fields, so recursive types refer to it
+	b.doc.Components.Schemas[name] = s
+	b.fields(t, s)
+	sort.Strings(s.Required)
+	return name
+}
+
+// fields adds the json fields of the struct t to s, the fields of
+// embedded structs are inlined as by encoding/json.
+func (b *builder) fields(t reflect.Type, s *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			b.fields(f.Type, s)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s.Properties[name] = b.schema(f.Type)
+		if f.Type.Kind() != reflect.Pointer && !strings.Contains(opts, "omitempty") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// componentName returns the name of the component of a named struct,
+// without the VO suffix of view objects, e.g. Tag of tagVO.
+func componentName(t reflect.Type) string {
+	return exported(strings.TrimSuffix(t.Name(), "VO"))
+}
+
+func exported(s string) string {
+	r := []rune(s)
+	if len(r) == 0 {
+		return s
+	}
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
@@ -0,0 +1,113 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emicklei/go-restful"
+)
+
+type itemVO struct {
+	Name    string     `json:"name"`
+	Score   *float64   `json:"score"`
+	Tags    []string   `json:"tags,omitempty"`
+	Updated *time.Time `json:"updated"`
+	Parent  *itemVO    `json:"parent"`
+	Ignored string     `json:"-"`
+}
+
+type pageVO struct {
+	Total int      `json:"total"`
+	Data  []itemVO `json:"data"`
+}
+
+func getItems(request *restful.Request, response *restful.Response) {}
+
+func testService() *restful.WebService {
+	service := new(restful.WebService)
+	service.Path("/v1").Produces(restful.MIME_JSON)
+	service.Route(service.GET("/items/{kind}").To(getItems).
+		Doc("items of a kind").
+		Metadata(KeyTags, []string{"items"}).
+		Param(service.PathParameter("kind", "kind of the items")).
+		Param(service.QueryParameter("take", "number of items").DataType("integer").DefaultValue("100")).
+		Writes(pageVO{}).
+		Returns(http.StatusNotFound, "unknown kind", nil))
+	service.Route(service.GET("/badge").To(getItems).Produces("image/svg+xml"))
+	return service
+}
+
+func TestBuild(t *testing.T) {
+	doc := Build(Info{Title: "test", Version: "v1"}, testService())
+	doc.AddResponse(http.StatusTooManyRequests, &Response{Description: "rate limited"})
+
+	op := doc.Paths["/v1/items/{kind}"]["get"]
+	if op == nil {
+		t.Fatalf("paths %v", doc.Paths)
+	}
+	if op.OperationID != "getItems" || op.Summary != "items of a kind" || !reflect.DeepEqual(op.Tags, []string{"items"}) {
+		t.Errorf("operation %+v", op)
+	}
+	if p := op.Parameters[0]; p.In != "path" || !p.Required || p.Schema.Type != "string" {
+		t.Errorf("path parameter %+v", p)
+	}
+	if p := op.Parameters[1]; p.In != "query" || p.Required || p.Schema.Type != "integer" || p.Schema.Default != "100" {
+		t.Errorf("query parameter %+v", p)
+	}
+	if s := op.Responses["200"].Content[restful.MIME_JSON].Schema; s.Ref != "#/components/schemas/Page" {
+		t.Errorf("200 schema %+v", s)
+	}
+	if _, ok := op.Responses["404"].Content["text/plain"]; !ok {
+		t.Errorf("404 response %+v", op.Responses["404"])
+	}
+	if op.Responses["429"] == nil {
+		t.Error("429 response is not added")
+	}
+	if s := doc.Paths["/v1/badge"]["get"].Responses["200"].Content["image/svg+xml"].Schema; s == nil || s.Type != "string" {
+		t.Errorf("svg schema %+v", s)
+	}
+
+	item := doc.Components.Schemas["Item"]
+	if item == nil {
+		t.Fatalf("components %v", doc.Components.Schemas)
+	}
+	if !reflect.DeepEqual(item.Required, []string{"name"}) {
+		t.Errorf("required %v", item.Required)
+	}
+	if s := item.Properties["score"]; s.Type != "number" || !s.Nullable {
+		t.Errorf("score %+v", s)
+	}
+	if s := item.Properties["updated"]; s.Format != "date-time" {
+		t.Errorf("updated %+v", s)
+	}
+	if s := item.Properties["parent"]; s.Ref != "#/components/schemas/Item" {
+		t.Errorf("parent %+v", s)
+	}
+	if _, ok := item.Properties["Ignored"]; ok {
+		t.Error("ignored field is described")
+	}
+	if s := doc.Components.Schemas["Page"].Properties["data"]; s.Type != "array" || s.Items.Ref != "#/components/schemas/Item" {
+		t.Errorf("data %+v", s)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	doc := Build(Info{Title: "test", Version: "v1"}, testService())
+	w := httptest.NewRecorder()
+	Handler(doc).ServeHTTP(w, httptest.NewRequest("GET", "/openapi.json", nil))
+	var got map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || got["openapi"] != Version {
+		t.Errorf("openapi.json = %s, %v", w.Body, err)
+	}
+
+	w = httptest.NewRecorder()
+	SwaggerUI("test", "/openapi.json").ServeHTTP(w, httptest.NewRequest("GET", "/docs/", nil))
+	if !strings.Contains(w.Body.String(), `url: "/openapi.json"`) {
+		t.Errorf("swagger ui = %s", w.Body)
+	}
+}
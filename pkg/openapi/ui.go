@@ -0,0 +1,38 @@
+package openapi
+
+import (
+	"html/template"
+	"net/http"
+)
+
+// SwaggerUIVersion is the version of swagger-ui-dist loaded from the CDN.
+const SwaggerUIVersion = "5.17.14"
+
+var swaggerUI = template.Must(template.New("ui").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>{{.Title}}</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@{{.Version}}/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@{{.Version}}/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.onload = () => {
+      window.ui = SwaggerUIBundle({url: {{.SpecURL}}, dom_id: "#swagger-ui"});
+    };
+  </script>
+</body>
+</html>
+`))
+
+// SwaggerUI serves Swagger UI of the document at specURL. The assets of
+// Swagger UI are loaded by the browser from unpkg.com, so they are not
+// shipped with the server.
+func SwaggerUI(title, specURL string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		swaggerUI.Execute(w, struct{ Title, Version, SpecURL string }{title, SwaggerUIVersion, specURL})
+	})
+}
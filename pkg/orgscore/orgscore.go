@@ -0,0 +1,105 @@
+// Package orgscore aggregates the scores of the projects by the
+// organization or user owning them, see normalize.Owner, so reports can
+// name the organizations maintaining the most critical software.
+package orgscore
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/gitutil/normalize"
+)
+
+// DefaultTop is the default number of the top projects counted by
+// Org.TopProjects.
+const DefaultTop = 1000
+
+// Org is the aggregated scores of the projects of an owner.
+type Org struct {
+	Owner string
+	// Rank is the rank of the org by Sum, 1 is the highest
+	Rank     int
+	Projects int
+	// Sum and Max are the sum and the max of the scores of the projects,
+	// TopLink is the project of the max
+	Sum     float64
+	Max     float64
+	TopLink string
+	// TopProjects is the number of projects among the top n projects of
+	// all owners
+	TopProjects int
+}
+
+// Aggregate aggregates the scores of links by their owners, counting the
+// projects among the top n of all scores. The orgs are ordered by rank,
+// links without an owner are skipped.
+func Aggregate(scores map[string]float64, top int) []*Org {
+	links := make([]string, 0, len(scores))
+	owners := make(map[string]string, len(scores))
+	for link := range scores {
+		owner, err := normalize.Owner(link)
+		if err != nil {
+			continue
+		}
+		links = append(links, link)
+		owners[link] = owner
+	}
+	// ties are ranked by link, so the top projects are stable
+	sort.Slice(links, func(i, j int) bool {
+		if scores[links[i]] != scores[links[j]] {
+			return scores[links[i]] > scores[links[j]]
+		}
+		return links[i] < links[j]
+	})
+
+	orgs := make(map[string]*Org)
+	for i, link := range links {
+		owner := owners[link]
+		org, ok := orgs[owner]
+		if !ok {
+			// the links are by descending score, the first is the max
+			org = &Org{Owner: owner, Max: scores[link], TopLink: link}
+			orgs[owner] = org
+		}
+		org.Projects++
+		org.Sum += scores[link]
+		if i < top {
+			org.TopProjects++
+		}
+	}
+
+	ret := make([]*Org, 0, len(orgs))
+	for _, org := range orgs {
+		ret = append(ret, org)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Sum != ret[j].Sum {
+			return ret[i].Sum > ret[j].Sum
+		}
+		return ret[i].Owner < ret[j].Owner
+	})
+	for i, org := range ret {
+		org.Rank = i + 1
+	}
+	return ret
+}
+
+// WriteTable writes the orgs as a markdown table.
+func WriteTable(w io.Writer, orgs []*Org, top int) error {
+	var b strings.Builder
+	b.WriteString("## Organizations\n\n")
+	if len(orgs) == 0 {
+		b.WriteString("No organizations.\n\n")
+	} else {
+		fmt.Fprintf(&b, "| Rank | Organization | Projects | Sum | Max | In the top %d | Top project |\n| ---: | --- | ---: | ---: | ---: | ---: | --- |\n", top)
+		for _, org := range orgs {
+			fmt.Fprintf(&b, "| %d | %s | %d | %.4f | %.4f | %d | %s |\n",
+				org.Rank, org.Owner, org.Projects, org.Sum, org.Max, org.TopProjects, org.TopLink)
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,50 @@
+package orgscore
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestAggregate(t *testing.T) {
+	scores := map[string]float64{
+		"https://github.com/curl/curl":         0.9,
+		"https://github.com/Curl/trurl":        0.3,
+		"https://github.com/madler/zlib":       0.95,
+		"https://git.kernel.org/pub/scm/git/x": 0.5,
+		"https://git.kernel.org/pub/scm/git/y": 0.4,
+		// links without an owner are not in the top projects
+		"not a link": 1,
+	}
+	orgs := Aggregate(scores, 2)
+	if len(orgs) != 3 {
+		t.Fatalf("Aggregate() = %d orgs, want 3", len(orgs))
+	}
+
+	want := []Org{
+		{Owner: "github.com/curl", Rank: 1, Projects: 2, Sum: 1.2, Max: 0.9, TopLink: "https://github.com/curl/curl", TopProjects: 1},
+		{Owner: "github.com/madler", Rank: 2, Projects: 1, Sum: 0.95, Max: 0.95, TopLink: "https://github.com/madler/zlib", TopProjects: 1},
+		{Owner: "git.kernel.org", Rank: 3, Projects: 2, Sum: 0.9, Max: 0.5, TopLink: "https://git.kernel.org/pub/scm/git/x"},
+	}
+	for i, org := range orgs {
+		w := want[i]
+		if org.Owner != w.Owner || org.Projects != w.Projects || org.TopProjects != w.TopProjects ||
+			org.TopLink != w.TopLink || org.Max != w.Max || math.Abs(org.Sum-w.Sum) > 1e-9 {
+			t.Errorf("orgs[%d] = %+v, want %+v", i, *org, w)
+		}
+		if org.Rank != i+1 {
+			t.Errorf("orgs[%d].Rank = %d", i, org.Rank)
+		}
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	var b strings.Builder
+	orgs := []*Org{{Owner: "github.com/curl", Rank: 1, Projects: 2, Sum: 1.2, Max: 0.9, TopLink: "https://github.com/curl/curl", TopProjects: 1}}
+	if err := WriteTable(&b, orgs, 100); err != nil {
+		t.Fatal(err)
+	}
+	if want := "| 1 | github.com/curl | 2 | 1.2000 | 0.9000 | 1 | https://github.com/curl/curl |\n"; !strings.Contains(b.String(), want) {
+		t.Errorf("WriteTable() = %s, want a row %s", b.String(), want)
+	}
+}
@@ -0,0 +1,271 @@
+// Package output abstracts where collectors write their rows, so a collector
+// can run without a database, e.g. to pipe json lines into a file:
+//
+//	supply-chain-collector --output jsonl --output-path signals.jsonl --input links.csv
+//
+// A row is a pointer to a struct of package repository, e.g.
+// *repository.GitMetric, whose pointer fields are the columns. Nil fields
+// are not collected and are left out of json lines and empty in csv files.
+//
+// The postgres sink is the database code of the collector, other sinks are
+// selected by config.RegistOutputFlags.
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	gitUtil "github.com/HUSTSecLab/criticality_score/pkg/gitfile/util"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+)
+
+type Format string
+
+const (
+	// FormatPostgres writes rows to the database, it is the default
+	FormatPostgres Format = "postgres"
+	// FormatJSONL writes one json object per row to a file
+	FormatJSONL Format = "jsonl"
+	// FormatCSV writes one csv file per table to a directory
+	FormatCSV Format = "csv"
+	// FormatStdout writes json lines to stdout
+	FormatStdout Format = "stdout"
+)
+
+// Formats are all formats.
+var Formats = []Format{FormatPostgres, FormatJSONL, FormatCSV, FormatStdout}
+
+// TableKey is the key of the table of a json line.
+const TableKey = "_table"
+
+type Config struct {
+	Format Format
+	// Path is the file of jsonl, "-" or empty for stdout, and the
+	// directory of csv
+	Path string
+	// Mode is how existing files are written, truncated if empty
+	Mode gitUtil.WriteMode
+}
+
+// Sink is where a collector writes its rows. Sinks are safe for concurrent
+// use.
+type Sink interface {
+	// Write writes a row of table
+	Write(table string, row any) error
+	// Close flushes the rows written
+	Close() error
+}
+
+// SinkFunc adapts the database code of a collector to a Sink.
+type SinkFunc func(table string, row any) error
+
+// Write implements Sink.
+func (f SinkFunc) Write(table string, row any) error {
+	return f(table, row)
+}
+
+// Close implements Sink.
+func (f SinkFunc) Close() error {
+	return nil
+}
+
+// Open returns the sink of config, db is the sink of FormatPostgres.
+func Open(config *Config, db Sink) (Sink, error) {
+	if config == nil {
+		return db, nil
+	}
+	switch config.Format {
+	case "", FormatPostgres:
+		return db, nil
+	case FormatStdout:
+		return NewJSONLSink(os.Stdout), nil
+	case FormatJSONL:
+		if config.Path == "" || config.Path == "-" {
+			return NewJSONLSink(os.Stdout), nil
+		}
+		file, _, err := gitUtil.OpenOutput(config.Path, config.Mode)
+		if err != nil {
+			return nil, err
+		}
+		return NewJSONLSink(file), nil
+	case FormatCSV:
+		if config.Path == "" {
+			return nil, fmt.Errorf("csv output needs a directory")
+		}
+		if err := os.MkdirAll(config.Path, 0755); err != nil {
+			return nil, err
+		}
+		return NewCSVSink(config.Path, config.Mode), nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q", config.Format)
+	}
+}
+
+// ReadLinks returns the links of a column of a csv file, the input of
+// collectors running without a database. See util.ParseURLs for column.
+func ReadLinks(path string, column string) ([]string, error) {
+	return gitUtil.ReadURLs(path, column)
+}
+
+// column is a column of a row.
+type column struct {
+	name  string
+	value any
+}
+
+// columns returns the columns of row in the order of the fields, nil
+// fields have a nil value.
+func columns(row any) ([]column, error) {
+	v := reflect.ValueOf(row)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil, fmt.Errorf("nil row")
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("row of %T is not a struct", row)
+	}
+	t := v.Type()
+	ret := make([]column, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		fv := v.Field(i)
+		var value any
+		if fv.Kind() == reflect.Pointer {
+			if !fv.IsNil() {
+				value = fv.Elem().Interface()
+			}
+		} else {
+			value = fv.Interface()
+		}
+		ret = append(ret, column{name: sqlutil.ColumnName(f), value: value})
+	}
+	return ret, nil
+}
+
+// JSONLSink writes a json object per row, with the table in TableKey and
+// the columns which are not nil.
+type JSONLSink struct {
+	mu  sync.Mutex
+	w   io.Writer
+	enc *json.Encoder
+}
+
+func NewJSONLSink(w io.Writer) *JSONLSink {
+	return &JSONLSink{w: w, enc: json.NewEncoder(w)}
+}
+
+// Write implements Sink.
+func (s *JSONLSink) Write(table string, row any) error {
+	cols, err := columns(row)
+	if err != nil {
+		return err
+	}
+	obj := map[string]any{TableKey: table}
+	for _, c := range cols {
+		if c.value != nil {
+			obj[c.name] = c.value
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(obj)
+}
+
+// Close implements Sink, stdout is not closed.
+func (s *JSONLSink) Close() error {
+	if c, ok := s.w.(io.Closer); ok && s.w != os.Stdout {
+		return c.Close()
+	}
+	return nil
+}
+
+// CSVSink writes the rows of every table to <dir>/<table>.csv, with a
+// header of all columns of the first row.
+type CSVSink struct {
+	mu     sync.Mutex
+	dir    string
+	mode   gitUtil.WriteMode
+	tables map[string]*gitUtil.CSVWriter
+}
+
+func NewCSVSink(dir string, mode gitUtil.WriteMode) *CSVSink {
+	return &CSVSink{
+		dir:    dir,
+		mode:   mode,
+		tables: make(map[string]*gitUtil.CSVWriter),
+	}
+}
+
+// cell formats a value of a csv file, lists are separated by spaces.
+func cell(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case []byte:
+		return string(v)
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Slice {
+		items := make([]string, rv.Len())
+		for i := range items {
+			items[i] = fmt.Sprint(rv.Index(i).Interface())
+		}
+		return strings.Join(items, " ")
+	}
+	return fmt.Sprint(value)
+}
+
+// Write implements Sink.
+func (s *CSVSink) Write(table string, row any) error {
+	cols, err := columns(row)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.tables[table]
+	if !ok {
+		header := make([]string, len(cols))
+		for i, c := range cols {
+			header[i] = c.name
+		}
+		w, err = gitUtil.NewCSVWriter(filepath.Join(s.dir, table+".csv"), s.mode, header)
+		if err != nil {
+			return err
+		}
+		s.tables[table] = w
+	}
+	record := make([]string, len(cols))
+	for i, c := range cols {
+		record[i] = cell(c.value)
+	}
+	return w.Write(record)
+}
+
+// Close implements Sink.
+func (s *CSVSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ret error
+	for _, w := range s.tables {
+		if err := w.Close(); err != nil && ret == nil {
+			ret = err
+		}
+	}
+	return ret
+}
@@ -0,0 +1,130 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	gitUtil "github.com/HUSTSecLab/criticality_score/pkg/gitfile/util"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/lib/pq"
+	"github.com/samber/lo"
+)
+
+func metric(link string) *repository.GitMetric {
+	return &repository.GitMetric{
+		GitLink:          lo.ToPtr(link),
+		ContributorCount: lo.ToPtr(3),
+		Language:         &pq.StringArray{"Go", "C"},
+		UpdatedSince:     lo.ToPtr(time.Date(2025, 1, 19, 0, 0, 0, 0, time.UTC)),
+	}
+}
+
+func TestJSONLSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewJSONLSink(&buf)
+	if err := sink.Write(repository.GitMetricTableName, metric("https://github.com/gin-gonic/gin")); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Write(repository.GitMetricTableName, "not a row"); err == nil {
+		t.Error("Write() of a string error = nil")
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got[TableKey] != "git_metrics" || got["git_link"] != "https://github.com/gin-gonic/gin" ||
+		got["contributor_count"] != 3.0 || got["updated_since"] != "2025-01-19T00:00:00Z" {
+		t.Errorf("line = %v", got)
+	}
+	// nil columns are not collected
+	if _, ok := got["commit_frequency"]; ok {
+		t.Errorf("line = %v, want no commit_frequency", got)
+	}
+}
+
+func TestCSVSink(t *testing.T) {
+	dir := t.TempDir()
+	sink, err := Open(&Config{Format: FormatCSV, Path: dir}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, link := range []string{"https://github.com/a/a", "https://github.com/b/b"} {
+		if err := sink.Write(repository.GitMetricTableName, metric(link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "git_metrics.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "id,git_link,") {
+		t.Fatalf("csv = %q", data)
+	}
+	if !strings.Contains(lines[2], ",https://github.com/b/b,") || !strings.Contains(lines[2], ",Go C,") || !strings.Contains(lines[2], ",2025-01-19T00:00:00Z,") {
+		t.Errorf("row = %q", lines[2])
+	}
+
+	// a second run appends its rows without a header
+	sink, err = Open(&Config{Format: FormatCSV, Path: dir, Mode: gitUtil.ModeAppend}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Write(repository.GitMetricTableName, metric("https://github.com/c/c")); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err = os.ReadFile(filepath.Join(dir, "git_metrics.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines = strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 4 || !strings.Contains(lines[3], ",https://github.com/c/c,") {
+		t.Errorf("appended csv = %q", data)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	db := SinkFunc(func(string, any) error { return nil })
+	for _, format := range []Format{"", FormatPostgres} {
+		sink, err := Open(&Config{Format: format}, db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := sink.(SinkFunc); !ok {
+			t.Errorf("Open(%q) = %T, want the database sink", format, sink)
+		}
+	}
+	if _, err := Open(&Config{Format: FormatCSV}, db); err == nil {
+		t.Error("Open() of csv without a directory error = nil")
+	}
+	if _, err := Open(&Config{Format: "parquet"}, db); err == nil {
+		t.Error("Open() of an unknown format error = nil")
+	}
+}
+
+func TestReadLinks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "links.csv")
+	if err := os.WriteFile(path, []byte("https://github.com/a/a,extra\n\n https://github.com/b/b \n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	links, err := ReadLinks(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(links) != 2 || links[0] != "https://github.com/a/a" || links[1] != "https://github.com/b/b" {
+		t.Errorf("ReadLinks() = %q", links)
+	}
+}
@@ -0,0 +1,217 @@
+package output
+
+import (
+	"sync"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/errors"
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+)
+
+// ErrClosed is returned by writes to a closed queue.
+var ErrClosed = errors.New("output queue is closed")
+
+// BatchFunc writes rows of a table at once, e.g. the database code of a
+// collector updating the rows and marking their links collected.
+type BatchFunc func(table string, rows []any) error
+
+type QueueConfig struct {
+	// BatchSize is the max number of rows written at once, 1 if not positive
+	BatchSize int
+	// QueueSize is the max number of rows waiting for the writer of a
+	// table, writes block when it is full
+	QueueSize int
+}
+
+// QueueStats are the counters of the writer of a table.
+type QueueStats struct {
+	Rows    int
+	Batches int
+	// Retries are the batches retried after a transient error, e.g. a
+	// deadlock or a lost connection
+	Retries int
+	// Failed are the rows failed to be written
+	Failed int
+}
+
+// maxRetries is the max number of retries of a batch failed by a
+// transient error.
+const maxRetries = 3
+
+type queued struct {
+	row  any
+	done chan error
+}
+
+type tableWriter struct {
+	rows  chan queued
+	stats QueueStats
+}
+
+// Queue is a sink writing the rows of every table by a single goroutine,
+// so concurrent workers do not contend for the locks of hot tables.
+//
+// Rows waiting when the writer is free are written at once, up to
+// BatchSize, so batches grow with the load without delaying rows when the
+// load is low. Write blocks until its row is written and returns its error,
+// so a slow database slows the workers down instead of piling up rows. If a
+// batch fails, its rows are written one by one, so only failing rows
+// return an error.
+type Queue struct {
+	write  BatchFunc
+	config QueueConfig
+
+	// mu guards closed and writers, it is held by writes while they wait
+	// for the queue, so the writers only lock statsMu
+	mu      sync.RWMutex
+	closed  bool
+	writers map[string]*tableWriter
+	wg      sync.WaitGroup
+	statsMu sync.Mutex
+}
+
+var _ Sink = (*Queue)(nil)
+
+// NewQueue returns a queue writing rows by write.
+func NewQueue(write BatchFunc, config *QueueConfig) *Queue {
+	q := &Queue{write: write, writers: make(map[string]*tableWriter)}
+	if config != nil {
+		q.config = *config
+	}
+	q.config.BatchSize = max(q.config.BatchSize, 1)
+	q.config.QueueSize = max(q.config.QueueSize, 0)
+	return q
+}
+
+// Write implements Sink.
+func (q *Queue) Write(table string, row any) error {
+	done := make(chan error, 1)
+	if err := q.enqueue(table, queued{row: row, done: done}); err != nil {
+		return err
+	}
+	return <-done
+}
+
+func (q *Queue) enqueue(table string, r queued) error {
+	q.mu.RLock()
+	w, ok := q.writers[table]
+	if !ok && !q.closed {
+		q.mu.RUnlock()
+		q.mu.Lock()
+		if w, ok = q.writers[table]; !ok && !q.closed {
+			w = &tableWriter{rows: make(chan queued, q.config.QueueSize)}
+			q.writers[table] = w
+			q.wg.Add(1)
+			go q.run(table, w)
+		}
+		q.mu.Unlock()
+		q.mu.RLock()
+	}
+	defer q.mu.RUnlock()
+	if q.closed {
+		return ErrClosed
+	}
+	// blocks while the queue of the table is full
+	w.rows <- r
+	return nil
+}
+
+func (q *Queue) run(table string, w *tableWriter) {
+	defer q.wg.Done()
+	for first := range w.rows {
+		batch := []queued{first}
+	fill:
+		for len(batch) < q.config.BatchSize {
+			select {
+			case r, ok := <-w.rows:
+				if !ok {
+					break fill
+				}
+				batch = append(batch, r)
+			default:
+				break fill
+			}
+		}
+		q.flush(table, w, batch)
+	}
+}
+
+func (q *Queue) flush(table string, w *tableWriter, batch []queued) {
+	rows := make([]any, len(batch))
+	for i, r := range batch {
+		rows[i] = r.row
+	}
+	err := q.writeRetry(table, w, rows)
+	if err != nil && len(batch) > 1 {
+		for _, r := range batch {
+			err := q.writeRetry(table, w, []any{r.row})
+			q.done(w, r, err)
+		}
+		return
+	}
+	for _, r := range batch {
+		q.done(w, r, err)
+	}
+}
+
+func (q *Queue) done(w *tableWriter, r queued, err error) {
+	q.statsMu.Lock()
+	w.stats.Rows++
+	if err != nil {
+		w.stats.Failed++
+	}
+	q.statsMu.Unlock()
+	r.done <- err
+}
+
+// writeRetry writes rows, and retries them if the batch failed by a
+// transient error, see errors.Retryable, e.g. the database aborted it to
+// resolve a deadlock.
+func (q *Queue) writeRetry(table string, w *tableWriter, rows []any) error {
+	for attempt := 0; ; attempt++ {
+		err := q.write(table, rows)
+		retry := err != nil && errors.Retryable(err) && attempt < maxRetries
+		q.statsMu.Lock()
+		w.stats.Batches++
+		if retry {
+			w.stats.Retries++
+		}
+		q.statsMu.Unlock()
+		if !retry {
+			return err
+		}
+		time.Sleep(time.Duration(attempt+1) * 100 * time.Millisecond)
+	}
+}
+
+// Stats returns the counters of the writer of every table.
+func (q *Queue) Stats() map[string]QueueStats {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	q.statsMu.Lock()
+	defer q.statsMu.Unlock()
+	ret := make(map[string]QueueStats, len(q.writers))
+	for table, w := range q.writers {
+		ret[table] = w.stats
+	}
+	return ret
+}
+
+// Close implements Sink, it waits for the rows queued to be written.
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+	for _, w := range q.writers {
+		close(w.rows)
+	}
+	q.mu.Unlock()
+	q.wg.Wait()
+	for table, s := range q.Stats() {
+		logger.Infof("Wrote %d rows of %s in %d batches, %d retried, %d failed", s.Rows, table, s.Batches, s.Retries, s.Failed)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestQueue(t *testing.T) {
+	var mu sync.Mutex
+	written := make(map[string]bool)
+	release := make(chan struct{})
+	first := true
+	q := NewQueue(func(table string, rows []any) error {
+		mu.Lock()
+		defer mu.Unlock()
+		// the first batch is held, so the other rows wait in the queue
+		if first {
+			first = false
+			mu.Unlock()
+			<-release
+			mu.Lock()
+		}
+		for _, row := range rows {
+			if row == "bad" {
+				return errors.New("bad row")
+			}
+		}
+		for _, row := range rows {
+			written[table+"/"+row.(string)] = true
+		}
+		return nil
+	}, &QueueConfig{BatchSize: 10, QueueSize: 20})
+
+	var wg sync.WaitGroup
+	errs := make(map[string]error)
+	var errsMu sync.Mutex
+	write := func(row string) {
+		defer wg.Done()
+		err := q.Write("git_metrics", row)
+		errsMu.Lock()
+		errs[row] = err
+		errsMu.Unlock()
+	}
+	wg.Add(1)
+	go write("first")
+	for i := 0; i < 9; i++ {
+		wg.Add(1)
+		go write(fmt.Sprint(i))
+	}
+	wg.Add(1)
+	go write("bad")
+	close(release)
+	wg.Wait()
+
+	for row, err := range errs {
+		if (err != nil) != (row == "bad") {
+			t.Errorf("Write(%s) error = %v", row, err)
+		}
+		if row != "bad" && !written["git_metrics/"+row] {
+			t.Errorf("%s is not written", row)
+		}
+	}
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+	stats := q.Stats()["git_metrics"]
+	if stats.Rows != 11 || stats.Failed != 1 || stats.Batches >= 11+11 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+	if err := q.Write("git_metrics", "late"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Write() after Close() error = %v, want ErrClosed", err)
+	}
+}
+
+func TestQueueRetry(t *testing.T) {
+	attempts := 0
+	q := NewQueue(func(table string, rows []any) error {
+		attempts++
+		if attempts == 1 {
+			return &pq.Error{Code: "40P01", Message: "deadlock detected"}
+		}
+		return nil
+	}, nil)
+	defer q.Close()
+	if err := q.Write("git_metrics", "row"); err != nil || attempts != 2 {
+		t.Errorf("Write() = %v after %d attempts, want a retried deadlock", err, attempts)
+	}
+	if s := q.Stats()["git_metrics"]; s.Retries != 1 {
+		t.Errorf("Stats() = %+v, want 1 retry", s)
+	}
+}
@@ -0,0 +1,162 @@
+// Package priority orders the repositories handled by a collector, so that
+// the most critical ones always have recent metrics when the api quota only
+// allows refreshing part of them, while the long tail is collected later.
+//
+// The priority of a repository is its score weighted by how stale its
+// metrics are:
+//
+//	priority = max(score, MinWeight) * (1 - 2^(-age/HalfLife))
+//
+// Repositories never collected have a staleness of 1. A top ranked
+// repository is thus refreshed long before its metrics are as old as the
+// ones of the tail, and MinWeight makes sure unscored repositories are
+// still collected eventually.
+//
+// Collectors usually use the default scheduler, which is initialized by
+// config.ParseFlags when config.RegistPriorityFlags is called:
+//
+//	links, err = priority.Schedule(ac, repository.SignalGitMetadata, links)
+package priority
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+)
+
+// MinWeight is the weight of repositories without a score or with a score
+// lower than it.
+const MinWeight = 0.01
+
+type Config struct {
+	// HalfLife is the age at which metrics are considered half fresh,
+	// 0 disables ordering
+	HalfLife time.Duration
+	// Limit is the max number of repositories to keep, 0 means no limit
+	Limit int
+}
+
+// Item is a repository to schedule.
+type Item struct {
+	Link  string
+	Score float64
+	// CollectedAt is zero if the repository is never collected
+	CollectedAt time.Time
+}
+
+// Staleness returns 1 - 2^(-age/halfLife), which grows from 0 right after
+// a collection towards 1.
+func Staleness(age, halfLife time.Duration) float64 {
+	if age <= 0 {
+		return 0
+	}
+	if halfLife <= 0 {
+		return 1
+	}
+	return 1 - math.Exp2(-float64(age)/float64(halfLife))
+}
+
+type Scheduler struct {
+	halfLife time.Duration
+	limit    int
+}
+
+func NewScheduler(config *Config) *Scheduler {
+	if config == nil {
+		return &Scheduler{}
+	}
+	return &Scheduler{halfLife: config.HalfLife, limit: config.Limit}
+}
+
+// Enabled returns true if the scheduler may reorder or drop some items.
+func (s *Scheduler) Enabled() bool {
+	return s != nil && (s.halfLife > 0 || s.limit > 0)
+}
+
+// Priority returns the priority of the item at now.
+func (s *Scheduler) Priority(item *Item, now time.Time) float64 {
+	staleness := 1.0
+	if !item.CollectedAt.IsZero() {
+		staleness = Staleness(now.Sub(item.CollectedAt), s.halfLife)
+	}
+	return math.Max(item.Score, MinWeight) * staleness
+}
+
+// Order returns the links of items by priority in descending order, ties
+// keep the order of items. At most limit links are returned.
+func (s *Scheduler) Order(items []Item, now time.Time) []string {
+	sorted := make([]Item, len(items))
+	copy(sorted, items)
+	if s != nil && s.halfLife > 0 {
+		priorities := make(map[string]float64, len(items))
+		for i := range items {
+			priorities[items[i].Link] = s.Priority(&items[i], now)
+		}
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return priorities[sorted[i].Link] > priorities[sorted[j].Link]
+		})
+	}
+	if s != nil && s.limit > 0 && len(sorted) > s.limit {
+		sorted = sorted[:s.limit]
+	}
+
+	ret := make([]string, len(sorted))
+	for i, item := range sorted {
+		ret[i] = item.Link
+	}
+	return ret
+}
+
+// Load returns the items of links with their latest scores and the
+// collected time of the signal.
+func Load(ac storage.AppDatabaseContext, signal repository.Signal, links []string) ([]Item, error) {
+	scores, err := repository.NewScoreRepository(ac).Query()
+	if err != nil {
+		return nil, err
+	}
+	scoreMap := make(map[string]float64)
+	for score := range scores {
+		if score.GitLink != nil && score.Score != nil {
+			scoreMap[*score.GitLink] = *score.Score
+		}
+	}
+
+	collectedAt, err := repository.NewCollectionTimestampRepository(ac).QueryCollectedAt(signal, links)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]Item, len(links))
+	for i, link := range links {
+		items[i] = Item{Link: link, Score: scoreMap[link], CollectedAt: collectedAt[link]}
+	}
+	return items, nil
+}
+
+var defaultScheduler *Scheduler
+
+// InitDefault initializes the default scheduler used by Schedule.
+func InitDefault(config *Config) {
+	defaultScheduler = NewScheduler(config)
+}
+
+// Default returns the default scheduler, if it is not initialized,
+// scheduling is disabled.
+func Default() *Scheduler {
+	return defaultScheduler
+}
+
+// Schedule orders links with the default scheduler by the signal.
+func Schedule(ac storage.AppDatabaseContext, signal repository.Signal, links []string) ([]string, error) {
+	if !defaultScheduler.Enabled() {
+		return links, nil
+	}
+	items, err := Load(ac, signal, links)
+	if err != nil {
+		return nil, err
+	}
+	return defaultScheduler.Order(items, time.Now()), nil
+}
@@ -0,0 +1,58 @@
+package priority
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStaleness(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		age, halfLife time.Duration
+		want          float64
+	}{
+		{0, day, 0},
+		{-time.Hour, day, 0},
+		{day, day, 0.5},
+		{2 * day, day, 0.75},
+		{day, 0, 1},
+	}
+	for _, tt := range tests {
+		if got := Staleness(tt.age, tt.halfLife); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Staleness(%s, %s) = %v, want %v", tt.age, tt.halfLife, got, tt.want)
+		}
+	}
+}
+
+func TestOrder(t *testing.T) {
+	now := time.Date(2025, 1, 14, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	items := []Item{
+		{Link: "tail-never", Score: 0.1},
+		{Link: "top-fresh", Score: 0.9, CollectedAt: now.Add(-time.Hour)},
+		{Link: "top-stale", Score: 0.9, CollectedAt: now.Add(-7 * day)},
+		{Link: "tail-stale", Score: 0.1, CollectedAt: now.Add(-30 * day)},
+		{Link: "unscored-never"},
+	}
+
+	tests := []struct {
+		name   string
+		config *Config
+		want   []string
+	}{
+		{"disabled", nil, []string{"tail-never", "top-fresh", "top-stale", "tail-stale", "unscored-never"}},
+		{"limit only", &Config{Limit: 2}, []string{"tail-never", "top-fresh"}},
+		{"ordered", &Config{HalfLife: 7 * day}, []string{"top-stale", "tail-never", "tail-stale", "unscored-never", "top-fresh"}},
+		{"ordered with limit", &Config{HalfLife: 7 * day, Limit: 1}, []string{"top-stale"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewScheduler(tt.config).Order(items, now)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Order() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
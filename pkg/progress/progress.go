@@ -0,0 +1,364 @@
+// Package progress reports the progress of long collector runs. A run has
+// phases, e.g. download, parse and save, each with a number of items done
+// out of a total.
+//
+// On a terminal, the TUI view redraws a progress bar per phase with its
+// throughput and ETA, and the most recent errors. Otherwise, e.g. in cron
+// jobs or when the output is piped, the progress is logged periodically.
+//
+// Collectors use the default tracker, which is initialized by
+// config.ParseFlags when config.RegistProgressFlags is called. The methods
+// of a nil tracker or phase do nothing but log errors, so collectors report
+// progress whether it is enabled or not:
+//
+//	phase := progress.Default().Phase("save", len(pkgs))
+//	for _, pkg := range pkgs {
+//		if err := save(pkg); err != nil {
+//			progress.Default().Error(err)
+//		}
+//		phase.Add(1)
+//	}
+//	phase.Done()
+package progress
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultInterval is the interval of progress logs without the TUI
+	DefaultInterval = 30 * time.Second
+	// MaxErrors is the number of recent errors shown by the TUI
+	MaxErrors = 5
+
+	// refresh is the redraw interval of the TUI
+	refresh = 200 * time.Millisecond
+	// barWidth is the width of progress bars
+	barWidth = 30
+)
+
+type Config struct {
+	// TUI enables the TUI view if the output is a terminal
+	TUI bool
+	// Interval is the interval of progress logs without the TUI, 0 logs
+	// only the start and the end of phases
+	Interval time.Duration
+}
+
+// Tracker tracks the phases of a run.
+type Tracker struct {
+	mu     sync.Mutex
+	phases []*Phase
+	errors []string
+	// errorCount counts all errors, errors only keeps the recent ones
+	errorCount int
+
+	out      io.Writer
+	tui      bool
+	interval time.Duration
+	// lines is the number of lines of the last view, which are redrawn
+	lines int
+	// logOutput is the output of the standard logger before the TUI
+	logOutput io.Writer
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+	now  func() time.Time
+}
+
+// Phase is a step of a run.
+type Phase struct {
+	tracker *Tracker
+	name    string
+	total   int
+	done    int
+	start   time.Time
+	end     time.Time
+}
+
+// IsTerminal reports whether f is a terminal.
+func IsTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// New returns a tracker writing to out. The TUI view is only used if it is
+// enabled and out is a terminal.
+func New(out *os.File, config *Config) *Tracker {
+	return newTracker(out, config.TUI && IsTerminal(out), config.Interval)
+}
+
+func newTracker(out io.Writer, tui bool, interval time.Duration) *Tracker {
+	return &Tracker{out: out, tui: tui, interval: interval, now: time.Now}
+}
+
+// Start starts redrawing the TUI view, or logging the progress. The
+// standard logger writes above the TUI view until Stop.
+func (t *Tracker) Start() {
+	if t == nil || t.stop != nil {
+		return
+	}
+	t.stop = make(chan struct{})
+	interval := t.interval
+	if t.tui {
+		interval = refresh
+		t.logOutput = log.Writer()
+		log.SetOutput(&logWriter{t})
+	}
+	if interval <= 0 {
+		return
+	}
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-t.stop:
+				return
+			case <-ticker.C:
+				t.mu.Lock()
+				if t.tui {
+					t.redraw()
+				} else {
+					t.logProgress()
+				}
+				t.mu.Unlock()
+			}
+		}
+	}()
+}
+
+// Stop stops the tracker, the TUI view is drawn a last time and kept.
+func (t *Tracker) Stop() {
+	if t == nil || t.stop == nil {
+		return
+	}
+	close(t.stop)
+	t.wg.Wait()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.tui {
+		t.redraw()
+		log.SetOutput(t.logOutput)
+	} else if t.errorCount > 0 {
+		log.Printf("%d errors", t.errorCount)
+	}
+}
+
+// Phase starts a phase of total items, 0 if unknown.
+func (t *Tracker) Phase(name string, total int) *Phase {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p := &Phase{tracker: t, name: name, total: total, start: t.now()}
+	t.phases = append(t.phases, p)
+	if !t.tui {
+		if total > 0 {
+			log.Printf("%s: started, %d items", name, total)
+		} else {
+			log.Printf("%s: started", name)
+		}
+	}
+	return p
+}
+
+// Error records an error, the TUI view shows the recent ones, otherwise
+// they are logged.
+func (t *Tracker) Error(err error) {
+	if err == nil {
+		return
+	}
+	if t == nil {
+		log.Print(err)
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.errorCount++
+	if !t.tui {
+		log.Print(err)
+		return
+	}
+	t.errors = append(t.errors, err.Error())
+	if len(t.errors) > MaxErrors {
+		t.errors = t.errors[len(t.errors)-MaxErrors:]
+	}
+}
+
+// Add marks n more items as done.
+func (p *Phase) Add(n int) {
+	if p == nil {
+		return
+	}
+	p.tracker.mu.Lock()
+	defer p.tracker.mu.Unlock()
+	p.done += n
+}
+
+// SetTotal sets the total once it is known.
+func (p *Phase) SetTotal(total int) {
+	if p == nil {
+		return
+	}
+	p.tracker.mu.Lock()
+	defer p.tracker.mu.Unlock()
+	p.total = total
+}
+
+// Done ends the phase.
+func (p *Phase) Done() {
+	if p == nil {
+		return
+	}
+	t := p.tracker
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !p.end.IsZero() {
+		return
+	}
+	p.end = t.now()
+	if !t.tui {
+		log.Printf("%s: done, %d items in %s", p.name, p.done, p.end.Sub(p.start).Round(time.Millisecond))
+	}
+}
+
+// rate returns the items done per second.
+func (p *Phase) rate(now time.Time) float64 {
+	if !p.end.IsZero() {
+		now = p.end
+	}
+	elapsed := now.Sub(p.start).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(p.done) / elapsed
+}
+
+// eta returns the estimated time left, false if unknown.
+func (p *Phase) eta(now time.Time) (time.Duration, bool) {
+	rate := p.rate(now)
+	if p.total <= 0 || rate <= 0 || !p.end.IsZero() {
+		return 0, false
+	}
+	left := float64(p.total-p.done) / rate
+	return time.Duration(left * float64(time.Second)).Round(time.Second), true
+}
+
+// line returns the progress of the phase, with a bar if bar is true.
+func (p *Phase) line(now time.Time, bar bool) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-12s ", p.name)
+	if bar {
+		filled := 0
+		if p.total > 0 {
+			filled = min(barWidth*p.done/p.total, barWidth)
+		} else if !p.end.IsZero() {
+			filled = barWidth
+		}
+		fmt.Fprintf(&b, "[%s%s] ", strings.Repeat("#", filled), strings.Repeat(".", barWidth-filled))
+	}
+	if p.total > 0 {
+		fmt.Fprintf(&b, "%3d%% %d/%d", 100*p.done/p.total, p.done, p.total)
+	} else {
+		fmt.Fprintf(&b, "%d", p.done)
+	}
+	fmt.Fprintf(&b, " %.1f/s", p.rate(now))
+	switch eta, ok := p.eta(now); {
+	case !p.end.IsZero():
+		fmt.Fprintf(&b, " done in %s", p.end.Sub(p.start).Round(time.Second))
+	case ok:
+		fmt.Fprintf(&b, " ETA %s", eta)
+	}
+	return b.String()
+}
+
+// View returns the TUI view.
+func (t *Tracker) View() string {
+	if t == nil {
+		return ""
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.view()
+}
+
+func (t *Tracker) view() string {
+	now := t.now()
+	var b strings.Builder
+	for _, p := range t.phases {
+		b.WriteString(p.line(now, true))
+		b.WriteByte('\n')
+	}
+	if t.errorCount > 0 {
+		fmt.Fprintf(&b, "errors: %d, recent:\n", t.errorCount)
+		for _, e := range t.errors {
+			// one line per error, so the view can be redrawn
+			e, _, _ = strings.Cut(e, "\n")
+			fmt.Fprintf(&b, "  %s\n", e)
+		}
+	}
+	return b.String()
+}
+
+// redraw replaces the last view by the current one.
+func (t *Tracker) redraw() {
+	t.clear()
+	v := t.view()
+	t.lines = strings.Count(v, "\n")
+	io.WriteString(t.out, v)
+}
+
+// clear erases the last view, the cursor is moved to its first line.
+func (t *Tracker) clear() {
+	if t.lines > 0 {
+		fmt.Fprintf(t.out, "\x1b[%dA\x1b[J", t.lines)
+		t.lines = 0
+	}
+}
+
+func (t *Tracker) logProgress() {
+	now := t.now()
+	for _, p := range t.phases {
+		if p.end.IsZero() {
+			log.Print(p.line(now, false))
+		}
+	}
+}
+
+// logWriter writes logs above the TUI view.
+type logWriter struct {
+	t *Tracker
+}
+
+func (w *logWriter) Write(b []byte) (int, error) {
+	w.t.mu.Lock()
+	defer w.t.mu.Unlock()
+	w.t.clear()
+	n, err := w.t.out.Write(b)
+	w.t.redraw()
+	return n, err
+}
+
+var defaultTracker *Tracker
+
+// InitDefault initializes and starts the default tracker, writing to
+// stderr.
+func InitDefault(config *Config) {
+	defaultTracker = New(os.Stderr, config)
+	defaultTracker.Start()
+}
+
+// Default returns the default tracker, nil if it is not initialized.
+func Default() *Tracker {
+	return defaultTracker
+}
@@ -0,0 +1,93 @@
+package progress
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNilTracker(t *testing.T) {
+	var tracker *Tracker
+	tracker.Start()
+	p := tracker.Phase("save", 10)
+	p.Add(1)
+	p.SetTotal(20)
+	p.Done()
+	tracker.Stop()
+	if v := tracker.View(); v != "" {
+		t.Errorf("View() = %q", v)
+	}
+}
+
+func TestView(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	tracker := newTracker(&bytes.Buffer{}, true, 0)
+	tracker.now = func() time.Time { return now }
+
+	download := tracker.Phase("download", 0)
+	download.Add(3)
+	now = now.Add(2 * time.Second)
+	download.Done()
+	save := tracker.Phase("save", 100)
+	now = now.Add(10 * time.Second)
+	save.Add(25)
+	for i := 0; i < MaxErrors+1; i++ {
+		tracker.Error(errors.New("error " + string(rune('a'+i)) + "\nstack"))
+	}
+
+	lines := strings.Split(strings.TrimSuffix(tracker.View(), "\n"), "\n")
+	want := []string{
+		"download     [" + strings.Repeat("#", barWidth) + "] 3 1.5/s done in 2s",
+		"save         [#######" + strings.Repeat(".", barWidth-7) + "]  25% 25/100 2.5/s ETA 30s",
+		"errors: 6, recent:",
+		"  error b",
+	}
+	if len(lines) != 3+MaxErrors {
+		t.Fatalf("View() = %q", lines)
+	}
+	for i, w := range want {
+		if lines[i] != w {
+			t.Errorf("View() line %d = %q, want %q", i, lines[i], w)
+		}
+	}
+}
+
+func TestRedraw(t *testing.T) {
+	var out bytes.Buffer
+	tracker := newTracker(&out, true, 0)
+	tracker.Phase("save", 2).Add(1)
+	tracker.redraw()
+	out.Reset()
+	tracker.redraw()
+	if !strings.HasPrefix(out.String(), "\x1b[1A\x1b[J") {
+		t.Errorf("redraw() = %q, want the last view erased", out.String())
+	}
+}
+
+func TestPlainLogs(t *testing.T) {
+	var logs bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	defer log.SetFlags(log.Flags())
+	log.SetOutput(&logs)
+	log.SetFlags(0)
+
+	tracker := newTracker(&bytes.Buffer{}, false, 0)
+	tracker.Start()
+	p := tracker.Phase("save", 2)
+	tracker.Error(errors.New("failed to save a"))
+	p.Add(1)
+	tracker.logProgress()
+	p.Add(1)
+	p.Done()
+	tracker.Stop()
+
+	for _, want := range []string{"save: started, 2 items", "failed to save a", "save          50% 1/2", "save: done, 2 items", "1 errors"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("logs = %q, want %q", logs.String(), want)
+		}
+	}
+}
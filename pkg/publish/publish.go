@@ -0,0 +1,334 @@
+// Package publish renders the latest scores into static artifacts, which can
+// be hosted on object storage or GitHub Pages like the dataset of the
+// upstream criticality_score project.
+package publish
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/schema"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+)
+
+const (
+	AllProjectsFile = "all_projects.csv"
+	IndexFile       = "index.json"
+	EcosystemsDir   = "ecosystems"
+	TagsDir         = "tags"
+
+	// DefaultTop is the number of projects in the top file
+	DefaultTop = 200
+
+	// Dataset is the name of the artifacts in their schema descriptor
+	Dataset = "criticality_score_projects"
+	// SchemaVersion is the version of the artifacts, bump the minor version
+	// when columns are added, and the major version when columns are
+	// removed or changed, see package schema
+	SchemaVersion = "1.1"
+)
+
+// Project is a row of the published artifacts.
+type Project struct {
+	Rank         int        `json:"rank"`
+	GitLink      string     `json:"git_link"`
+	Ecosystems   []string   `json:"ecosystems"`
+	Languages    []string   `json:"languages"`
+	License      string     `json:"license"`
+	Score        float64    `json:"score"`
+	DistScore    float64    `json:"dist_score"`
+	LangEcoScore float64    `json:"lang_eco_score"`
+	GitScore     float64    `json:"git_score"`
+	UpdateTime   *time.Time `json:"update_time,omitempty"`
+	Tags         []string   `json:"tags,omitempty"`
+	// Runtimes are the declared runtime constraints, EOLRuntime is true if
+	// the project is pinned to end-of-life runtimes
+	Runtimes   string `json:"runtimes,omitempty"`
+	EOLRuntime *bool  `json:"eol_runtime,omitempty"`
+	// SecurityPolicy is false if the project has no vulnerability
+	// disclosure policy, see package analysis/disclosure
+	SecurityPolicy *bool `json:"security_policy,omitempty"`
+}
+
+// Index describes the published artifacts.
+type Index struct {
+	GeneratedAt   time.Time      `json:"generated_at"`
+	SchemaVersion string         `json:"schema_version"`
+	Projects      int            `json:"projects"`
+	Files         map[string]int `json:"files"`
+}
+
+var csvHeader = []string{
+	"rank", "git_link", "ecosystems", "languages", "license",
+	"score", "dist_score", "lang_eco_score", "git_score", "update_time",
+}
+
+// Descriptor returns the schema descriptor of the artifacts. The json files
+// are lists of projects, the csv file has a subset of their fields.
+func Descriptor() (*schema.Descriptor, error) {
+	fields, err := schema.JSONFieldsOf[Project]()
+	if err != nil {
+		return nil, err
+	}
+	csvFields, err := schema.Select(fields, csvHeader...)
+	if err != nil {
+		return nil, err
+	}
+	return &schema.Descriptor{
+		Dataset: Dataset,
+		Version: SchemaVersion,
+		Tables: []schema.Table{
+			{Name: AllProjectsFile, Format: schema.FormatCSV, Fields: csvFields},
+			{Name: "top_<top>.json", Format: schema.FormatJSON, Fields: fields},
+			{Name: filepath.ToSlash(filepath.Join(EcosystemsDir, "<ecosystem>.json")), Format: schema.FormatJSON, Fields: fields},
+			{Name: filepath.ToSlash(filepath.Join(TagsDir, "<tag>.json")), Format: schema.FormatJSON, Fields: fields},
+		},
+	}, nil
+}
+
+func deref[T any](p *T) T {
+	var zero T
+	if p == nil {
+		return zero
+	}
+	return *p
+}
+
+// Load returns the latest score of every project, with the ecosystems and
+// languages from git metrics and the tags of the project.
+func Load(ac storage.AppDatabaseContext) ([]*Project, error) {
+	tags, err := repository.NewProjectTagRepository(ac).Query()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+	tagMap := make(map[string][]string)
+	for t := range tags {
+		if t.GitLink != nil && t.Tag != nil {
+			tagMap[*t.GitLink] = append(tagMap[*t.GitLink], *t.Tag)
+		}
+	}
+
+	metrics, err := repository.NewGitMetricsRepository(ac).Query()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query git metrics: %w", err)
+	}
+	metricMap := make(map[string]*repository.GitMetric)
+	for m := range metrics {
+		if m.GitLink != nil {
+			metricMap[*m.GitLink] = m
+		}
+	}
+
+	scores, err := repository.NewScoreRepository(ac).Query()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scores: %w", err)
+	}
+
+	ret := make([]*Project, 0)
+	for s := range scores {
+		if s.GitLink == nil || s.Score == nil {
+			continue
+		}
+		p := &Project{
+			GitLink:      *s.GitLink,
+			Score:        *s.Score,
+			DistScore:    deref(s.DistScore),
+			LangEcoScore: deref(s.DevScore),
+			GitScore:     deref(s.GitScore),
+			UpdateTime:   s.UpdateTime,
+			Tags:         tagMap[*s.GitLink],
+		}
+		if m, ok := metricMap[*s.GitLink]; ok {
+			p.Ecosystems = strings.Fields(deref(m.EcoSystem))
+			if m.Language != nil {
+				p.Languages = []string(*m.Language)
+			}
+			p.License = deref(m.License)
+			p.Runtimes = deref(m.Runtimes)
+			p.EOLRuntime = m.EOLRuntime
+			p.SecurityPolicy = m.SecurityPolicy
+		}
+		ret = append(ret, p)
+	}
+	return ret, nil
+}
+
+// Rank sorts projects by score descending and sets their ranks, ties are
+// broken by git link so the artifacts are deterministic.
+func Rank(projects []*Project) {
+	sort.SliceStable(projects, func(i, j int) bool {
+		if projects[i].Score != projects[j].Score {
+			return projects[i].Score > projects[j].Score
+		}
+		return projects[i].GitLink < projects[j].GitLink
+	})
+	for i, p := range projects {
+		p.Rank = i + 1
+	}
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-z0-9_+-]+`)
+
+// ecosystemFileName returns the file name of an ecosystem, e.g. npm.json.
+func ecosystemFileName(ecosystem string) string {
+	name := unsafeFileChars.ReplaceAllString(strings.ToLower(ecosystem), "_")
+	return filepath.Join(EcosystemsDir, name+".json")
+}
+
+// tagFileName returns the file name of the top projects of a tag, e.g.
+// tags/acme.json.
+func tagFileName(tag string) string {
+	name := unsafeFileChars.ReplaceAllString(strings.ToLower(tag), "_")
+	return filepath.Join(TagsDir, name+".json")
+}
+
+// group copies projects into groups by key, the rank of every copy is
+// within its group, and at most limit projects are kept in every group if
+// limit > 0.
+func group(projects []*Project, keys func(*Project) []string, limit int) map[string][]*Project {
+	ret := make(map[string][]*Project)
+	for _, p := range projects {
+		for _, k := range keys(p) {
+			if limit > 0 && len(ret[k]) >= limit {
+				continue
+			}
+			cp := *p
+			cp.Rank = len(ret[k]) + 1
+			ret[k] = append(ret[k], &cp)
+		}
+	}
+	return ret
+}
+
+// Write ranks projects and writes all artifacts into dir:
+//
+//   - all_projects.csv: all projects
+//   - top_<top>.json: the top projects
+//   - ecosystems/<ecosystem>.json: projects of every ecosystem, ranked
+//     within the ecosystem
+//   - tags/<tag>.json: the top projects of every tag, ranked within the tag
+//   - index.json: the generated time, the schema version and the number of
+//     rows of every file
+//   - schema.json: the schema descriptor of the files
+func Write(dir string, projects []*Project, top int, now time.Time) (*Index, error) {
+	if err := os.MkdirAll(filepath.Join(dir, EcosystemsDir), 0755); err != nil {
+		return nil, err
+	}
+	descriptor, err := Descriptor()
+	if err != nil {
+		return nil, err
+	}
+	if err := descriptor.Write(filepath.Join(dir, schema.File)); err != nil {
+		return nil, err
+	}
+
+	Rank(projects)
+	index := &Index{
+		GeneratedAt:   now,
+		SchemaVersion: SchemaVersion,
+		Projects:      len(projects),
+		Files:         make(map[string]int),
+	}
+
+	if err := writeCSV(filepath.Join(dir, AllProjectsFile), projects); err != nil {
+		return nil, err
+	}
+	index.Files[AllProjectsFile] = len(projects)
+
+	topProjects := projects[:min(top, len(projects))]
+	topFile := fmt.Sprintf("top_%d.json", top)
+	if err := writeJSON(filepath.Join(dir, topFile), topProjects); err != nil {
+		return nil, err
+	}
+	index.Files[topFile] = len(topProjects)
+
+	byEcosystem := group(projects, func(p *Project) []string { return p.Ecosystems }, 0)
+	for e, list := range byEcosystem {
+		file := ecosystemFileName(e)
+		if err := writeJSON(filepath.Join(dir, file), list); err != nil {
+			return nil, err
+		}
+		index.Files[filepath.ToSlash(file)] = len(list)
+	}
+
+	byTag := group(projects, func(p *Project) []string { return p.Tags }, top)
+	if len(byTag) > 0 {
+		if err := os.MkdirAll(filepath.Join(dir, TagsDir), 0755); err != nil {
+			return nil, err
+		}
+	}
+	for tag, list := range byTag {
+		file := tagFileName(tag)
+		if err := writeJSON(filepath.Join(dir, file), list); err != nil {
+			return nil, err
+		}
+		index.Files[filepath.ToSlash(file)] = len(list)
+	}
+
+	if err := writeJSON(filepath.Join(dir, IndexFile), index); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func writeCSV(path string, projects []*Project) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	w.Write(csvHeader)
+	for _, p := range projects {
+		updateTime := ""
+		if p.UpdateTime != nil {
+			updateTime = p.UpdateTime.UTC().Format(time.RFC3339)
+		}
+		w.Write([]string{
+			strconv.Itoa(p.Rank),
+			p.GitLink,
+			strings.Join(p.Ecosystems, " "),
+			strings.Join(p.Languages, " "),
+			p.License,
+			formatFloat(p.Score),
+			formatFloat(p.DistScore),
+			formatFloat(p.LangEcoScore),
+			formatFloat(p.GitScore),
+			updateTime,
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+func writeJSON(path string, v any) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	enc := json.NewEncoder(file)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	return file.Close()
+}
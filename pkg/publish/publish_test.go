@@ -0,0 +1,125 @@
+package publish
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/schema"
+)
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	projects := []*Project{
+		{GitLink: "https://github.com/a/lib", Ecosystems: []string{"npm"}, Score: 0.5, Tags: []string{"acme"}},
+		{GitLink: "https://github.com/b/core", Ecosystems: []string{"npm", "pypi"}, Score: 0.9},
+		{GitLink: "https://github.com/c/tool", Score: 0.5, Tags: []string{"acme"}},
+		{GitLink: "https://github.com/d/util", Score: 0.1, Tags: []string{"acme"}},
+	}
+	now := time.Date(2025, 1, 13, 0, 0, 0, 0, time.UTC)
+
+	index, err := Write(dir, projects, 2, now)
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	wantFiles := map[string]int{
+		"all_projects.csv":     4,
+		"top_2.json":           2,
+		"ecosystems/npm.json":  2,
+		"ecosystems/pypi.json": 1,
+		"tags/acme.json":       2,
+	}
+	if !reflect.DeepEqual(index.Files, wantFiles) {
+		t.Errorf("Files = %v, want %v", index.Files, wantFiles)
+	}
+
+	file, err := os.Open(filepath.Join(dir, AllProjectsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var links []string
+	for _, row := range rows[1:] {
+		links = append(links, row[0]+" "+row[1])
+	}
+	// ties are broken by git link
+	wantLinks := []string{"1 https://github.com/b/core", "2 https://github.com/a/lib", "3 https://github.com/c/tool", "4 https://github.com/d/util"}
+	if !reflect.DeepEqual(links, wantLinks) {
+		t.Errorf("all_projects.csv = %v, want %v", links, wantLinks)
+	}
+
+	var npm []Project
+	data, err := os.ReadFile(filepath.Join(dir, "ecosystems/npm.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &npm); err != nil {
+		t.Fatal(err)
+	}
+	if len(npm) != 2 || npm[1].GitLink != "https://github.com/a/lib" || npm[1].Rank != 2 {
+		t.Errorf("unexpected npm.json: %+v", npm)
+	}
+
+	// tags only keep the top projects, ranked within the tag
+	var acme []Project
+	data, err = os.ReadFile(filepath.Join(dir, "tags/acme.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &acme); err != nil {
+		t.Fatal(err)
+	}
+	if len(acme) != 2 || acme[0].GitLink != "https://github.com/a/lib" || acme[0].Rank != 1 ||
+		acme[1].GitLink != "https://github.com/c/tool" || acme[1].Rank != 2 {
+		t.Errorf("unexpected acme.json: %+v", acme)
+	}
+}
+
+func TestEcosystemFileName(t *testing.T) {
+	tests := map[string]string{
+		"npm":       "ecosystems/npm.json",
+		"C#/NuGet":  "ecosystems/c_nuget.json",
+		"../passwd": "ecosystems/_passwd.json",
+	}
+	for in, want := range tests {
+		if got := filepath.ToSlash(ecosystemFileName(in)); got != want {
+			t.Errorf("ecosystemFileName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+var updateSchema = flag.Bool("update-schema", false, "update testdata/schema.json, the schema of the last release")
+
+// TestSchemaCompatible fails if the artifacts change without a new
+// SchemaVersion. After bumping it, update the released schema with
+//
+//	go test ./pkg/publish -run TestSchemaCompatible -update-schema
+func TestSchemaCompatible(t *testing.T) {
+	current, err := Descriptor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join("testdata", schema.File)
+	if *updateSchema {
+		if err := current.Write(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	released, err := schema.Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := schema.Check(released, current); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,157 @@
+// Package ratelimit limits the requests of every client of the public API,
+// e.g. by IP, with token buckets, so a single client can not exhaust the
+// database behind the API server.
+package ratelimit
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Config struct {
+	// Rate is the requests per second of every client, 0 disables the
+	// limit
+	Rate float64
+	// Burst is the requests a client can send at once
+	Burst int
+	// TrustProxy takes the client IP from X-Forwarded-For, only set it
+	// behind a CDN or a reverse proxy, otherwise clients can spoof it
+	TrustProxy bool
+	// ProxyHops is the number of proxies in front of the server, every one
+	// of them appends the address it got the request from to
+	// X-Forwarded-For, 1 if not set
+	ProxyHops int
+}
+
+// sweepInterval is the interval idle buckets are dropped.
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter is a token bucket limiter of many clients.
+type Limiter struct {
+	rate  float64
+	burst float64
+	// hops is the number of trusted proxies, 0 if X-Forwarded-For is not
+	// trusted
+	hops int
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// New returns the limiter of config, nil if the limit is disabled. A nil
+// limiter allows all requests.
+func New(config *Config) *Limiter {
+	if config == nil || config.Rate <= 0 {
+		return nil
+	}
+	burst := float64(config.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	hops := 0
+	if config.TrustProxy {
+		hops = max(config.ProxyHops, 1)
+	}
+	return &Limiter{
+		rate:    config.Rate,
+		burst:   burst,
+		hops:    hops,
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+var defaultLimiter *Limiter
+
+// InitDefault initializes the default limiter, it is called by
+// config.ParseFlags when config.RegistAPIServerFlags is called.
+func InitDefault(config *Config) {
+	defaultLimiter = New(config)
+}
+
+// Default returns the default limiter, nil if the limit is disabled.
+func Default() *Limiter {
+	return defaultLimiter
+}
+
+// Allow takes a token of the client key, if it has none it returns false
+// and the time until the next token.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// AllowRequest is Allow of the client IP of r.
+func (l *Limiter) AllowRequest(r *http.Request) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	return l.Allow(ClientIP(r, l.hops))
+}
+
+// sweep drops the buckets refilled since, which are the same as new ones.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// ClientIP returns the IP of the client of r. Behind hops proxies it is the
+// hops-th address of X-Forwarded-For from the right, the one the outermost
+// proxy got the request from, the addresses on its left are sent by the
+// client and can be anything. With hops 0, or fewer addresses than hops, it
+// is the remote address of r.
+func ClientIP(r *http.Request, hops int) string {
+	if hops > 0 {
+		var forwarded []string
+		for _, v := range r.Header.Values("X-Forwarded-For") {
+			for _, ip := range strings.Split(v, ",") {
+				forwarded = append(forwarded, strings.TrimSpace(ip))
+			}
+		}
+		if len(forwarded) >= hops {
+			if ip := forwarded[len(forwarded)-hops]; ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
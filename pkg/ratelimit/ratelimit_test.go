@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAllow(t *testing.T) {
+	now := time.Now()
+	l := New(&Config{Rate: 2, Burst: 3})
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("request %d of the burst is limited", i)
+		}
+	}
+	ok, wait := l.Allow("a")
+	if ok || wait != 500*time.Millisecond {
+		t.Errorf("Allow() after the burst = %v, %v, want false, 500ms", ok, wait)
+	}
+	if ok, _ := l.Allow("b"); !ok {
+		t.Errorf("other clients are limited")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if ok, _ := l.Allow("a"); !ok {
+		t.Errorf("refilled token is limited")
+	}
+
+	// the idle buckets are dropped
+	now = now.Add(time.Hour)
+	l.Allow("c")
+	if len(l.buckets) != 1 {
+		t.Errorf("buckets after sweep = %d, want 1", len(l.buckets))
+	}
+
+	var disabled *Limiter = New(&Config{})
+	if ok, _ := disabled.Allow("a"); !ok {
+		t.Errorf("disabled limiter limits")
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.2")
+	if ip := ClientIP(r, 0); ip != "10.0.0.1" {
+		t.Errorf("ClientIP() = %s, want 10.0.0.1", ip)
+	}
+	if ip := ClientIP(r, 1); ip != "10.0.0.2" {
+		t.Errorf("ClientIP() behind proxy = %s, want 10.0.0.2", ip)
+	}
+	if ip := ClientIP(r, 2); ip != "203.0.113.7" {
+		t.Errorf("ClientIP() behind 2 proxies = %s, want 203.0.113.7", ip)
+	}
+	if ip := ClientIP(r, 3); ip != "10.0.0.1" {
+		t.Errorf("ClientIP() behind 3 proxies = %s, want 10.0.0.1", ip)
+	}
+}
+
+func TestClientIPSpoofed(t *testing.T) {
+	// the client sends its own X-Forwarded-For, the proxy appends the
+	// address it got the request from
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Add("X-Forwarded-For", "1.2.3.4, 5.6.7.8")
+	r.Header.Add("X-Forwarded-For", "203.0.113.7")
+	if ip := ClientIP(r, 1); ip != "203.0.113.7" {
+		t.Errorf("ClientIP() of spoofed request = %s, want 203.0.113.7", ip)
+	}
+
+	l := New(&Config{Rate: 1, Burst: 1, TrustProxy: true})
+	for _, prefix := range []string{"1.1.1.1", "2.2.2.2", "3.3.3.3"} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = "10.0.0.1:1234"
+		r.Header.Set("X-Forwarded-For", prefix+", 203.0.113.7")
+		ok, _ := l.AllowRequest(r)
+		if prefix == "1.1.1.1" && !ok {
+			t.Errorf("first request is limited")
+		}
+		if prefix != "1.1.1.1" && ok {
+			t.Errorf("request spoofing %s is not limited", prefix)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package rawresponse
+
+import (
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/samber/lo"
+)
+
+type dbStore struct {
+	repo repository.RawResponseRepository
+}
+
+// NewDBStore returns a store backed by the raw_responses table of the
+// default database.
+func NewDBStore() Store {
+	return &dbStore{
+		repo: repository.NewRawResponseRepository(storage.GetDefaultAppDatabaseContext()),
+	}
+}
+
+// Put implements Store.
+func (d *dbStore) Put(record *Record) error {
+	request, err := Compress(record.Request)
+	if err != nil {
+		return err
+	}
+	body, err := Compress(record.Body)
+	if err != nil {
+		return err
+	}
+	data := &repository.RawResponse{
+		RunID:      &record.RunID,
+		Method:     &record.Method,
+		URL:        &record.URL,
+		StatusCode: &record.StatusCode,
+		Body:       &body,
+		FetchedAt:  lo.ToPtr(record.FetchedAt),
+	}
+	if request != nil {
+		data.Request = &request
+	}
+	return d.repo.Insert(data)
+}
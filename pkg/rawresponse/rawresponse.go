@@ -0,0 +1,199 @@
+// Package rawresponse archives the raw json responses of upstream APIs like
+// GitHub, deps.dev and package registries, keyed by the id of the run of the
+// collector, so parsed rows can be audited against the exact inputs and a
+// parse bug can be debugged or fixed by reprocessing the archived responses.
+//
+// Requests and bodies are archived gzip compressed, headers are not, so
+// tokens are never archived. Responses which are not json, e.g. tarballs,
+// are skipped.
+//
+// Archiving is disabled by default. It is enabled by config.ParseFlags when
+// config.RegistResponseArchiveFlags is called and --archive-responses is
+// set, the clients of httpcache.Client() and collectors wrapping their
+// transports by Wrap archive their responses then.
+package rawresponse
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+)
+
+// RunIDFormat is the format of generated run ids, the start time of the
+// run in UTC.
+const RunIDFormat = "20060102-150405"
+
+type Config struct {
+	Enabled bool
+	// RunID keys the responses archived by the run, generated from the
+	// start time if empty
+	RunID string
+}
+
+// Record is an archived response, Request is the body of the request, e.g.
+// the query of a GraphQL request, nil for GET requests.
+type Record struct {
+	RunID      string
+	Method     string
+	URL        string
+	Request    []byte
+	StatusCode int
+	Body       []byte
+	FetchedAt  time.Time
+}
+
+// Store persists archived responses.
+type Store interface {
+	Put(record *Record) error
+}
+
+// Transport is a http.RoundTripper which archives the json responses of
+// inner.
+type Transport struct {
+	inner http.RoundTripper
+	store Store
+	runID string
+	now   func() time.Time
+}
+
+func NewTransport(inner http.RoundTripper, store Store, runID string) *Transport {
+	if inner == nil {
+		inner = http.DefaultTransport
+	}
+	return &Transport{
+		inner: inner,
+		store: store,
+		runID: runID,
+		now:   time.Now,
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	var request []byte
+	if r.Body != nil && r.Body != http.NoBody {
+		body, err := io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		request = body
+		// RoundTrip must not modify the request
+		r = r.Clone(r.Context())
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	resp, err := t.inner.RoundTrip(r)
+	if err != nil {
+		return resp, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	if !json.Valid(body) {
+		return resp, nil
+	}
+	// failing to archive a response does not fail the collection
+	if err := t.store.Put(&Record{
+		RunID:      t.runID,
+		Method:     r.Method,
+		URL:        r.URL.String(),
+		Request:    request,
+		StatusCode: resp.StatusCode,
+		Body:       body,
+		FetchedAt:  t.now(),
+	}); err != nil {
+		logger.Warnf("Archiving the response of %s Failed: %v", r.URL, err)
+	}
+	return resp, nil
+}
+
+// Compress returns data gzip compressed, nil for nil.
+func Compress(data []byte) ([]byte, error) {
+	if data == nil {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress returns data compressed by Compress.
+func Decompress(data []byte) ([]byte, error) {
+	if data == nil {
+		return nil, nil
+	}
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// MemoryStore keeps records in memory, it is mostly used in tests.
+type MemoryStore struct {
+	mu      sync.Mutex
+	Records []*Record
+}
+
+// Put implements Store.
+func (m *MemoryStore) Put(record *Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Records = append(m.Records, record)
+	return nil
+}
+
+var (
+	defaultStore Store
+	defaultRunID string
+)
+
+// InitDefault enables archiving to the database if config is enabled.
+func InitDefault(config *Config) {
+	if config == nil || !config.Enabled {
+		defaultStore = nil
+		return
+	}
+	defaultRunID = config.RunID
+	if defaultRunID == "" {
+		defaultRunID = time.Now().UTC().Format(RunIDFormat)
+	}
+	defaultStore = NewDBStore()
+	logger.Infof("Archiving api responses of run %s", defaultRunID)
+}
+
+// RunID returns the id of the run, empty if archiving is disabled.
+func RunID() string {
+	if defaultStore == nil {
+		return ""
+	}
+	return defaultRunID
+}
+
+// Wrap returns inner archiving its responses if archiving is enabled, or
+// inner itself otherwise.
+func Wrap(inner http.RoundTripper) http.RoundTripper {
+	if defaultStore == nil {
+		return inner
+	}
+	return NewTransport(inner, defaultStore, defaultRunID)
+}
@@ -0,0 +1,86 @@
+package rawresponse
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/graphql":
+			body, _ := io.ReadAll(r.Body)
+			w.Write([]byte(`{"echo":` + string(body) + `}`))
+		case "/tarball":
+			w.Write([]byte("\x1f\x8b not json"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"Not Found"}`))
+		}
+	}))
+	defer server.Close()
+
+	store := &MemoryStore{}
+	client := &http.Client{Transport: NewTransport(nil, store, "run-1")}
+
+	resp, err := client.Post(server.URL+"/graphql", "application/json", strings.NewReader(`{"query":"{}"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	// the caller reads the same body
+	if string(body) != `{"echo":{"query":"{}"}}` {
+		t.Errorf("body = %q", body)
+	}
+	for _, path := range []string{"/tarball", "/missing"} {
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	if len(store.Records) != 2 {
+		t.Fatalf("archived %d responses, want 2", len(store.Records))
+	}
+	r := store.Records[0]
+	if r.RunID != "run-1" || r.Method != http.MethodPost || string(r.Request) != `{"query":"{}"}` || string(r.Body) != string(body) {
+		t.Errorf("record = %+v", r)
+	}
+	// error responses are archived as well
+	if r := store.Records[1]; r.StatusCode != http.StatusNotFound || r.Request != nil {
+		t.Errorf("record = %+v", r)
+	}
+}
+
+func TestCompress(t *testing.T) {
+	data := []byte(strings.Repeat(`{"name":"gin"}`, 100))
+	compressed, err := Compress(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(compressed) >= len(data) {
+		t.Errorf("Compress() = %d bytes, want less than %d", len(compressed), len(data))
+	}
+	got, err := Decompress(compressed)
+	if err != nil || string(got) != string(data) {
+		t.Errorf("Decompress() = %q, %v", got, err)
+	}
+	if got, err := Compress(nil); got != nil || err != nil {
+		t.Errorf("Compress(nil) = %v, %v", got, err)
+	}
+}
+
+func TestWrapDisabled(t *testing.T) {
+	InitDefault(&Config{})
+	if rt := Wrap(http.DefaultTransport); rt != http.DefaultTransport {
+		t.Errorf("Wrap() = %T, want the inner transport", rt)
+	}
+	if RunID() != "" {
+		t.Errorf("RunID() = %q, want empty", RunID())
+	}
+}
@@ -0,0 +1,171 @@
+// Package respcache is a read-through in-memory cache of the responses of
+// the API server, so dashboards and badges embedded in many pages are
+// served without querying the database for every request. Concurrent
+// requests of the same uncached key are filled once.
+package respcache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+type Config struct {
+	// TTL of cached responses, 0 disables the cache
+	TTL time.Duration
+	// MaxEntries is the number of cached responses, the least recently
+	// used ones are evicted
+	MaxEntries int
+}
+
+// Entry is a response.
+type Entry struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	// ETag is the strong validator of the body
+	ETag     string
+	StoredAt time.Time
+}
+
+// ETag returns the etag of body.
+func ETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+type item struct {
+	key   string
+	entry *Entry
+}
+
+// Cache is a LRU cache of responses.
+type Cache struct {
+	config Config
+
+	mu    sync.Mutex
+	items map[string]*list.Element
+	order *list.List
+	group singleflight.Group
+	now   func() time.Time
+}
+
+// New returns the cache of config, nil if the cache is disabled. A nil
+// cache fills every request.
+func New(config *Config) *Cache {
+	if config == nil || config.TTL <= 0 {
+		return nil
+	}
+	return &Cache{
+		config: *config,
+		items:  make(map[string]*list.Element),
+		order:  list.New(),
+		now:    time.Now,
+	}
+}
+
+var defaultCache *Cache
+
+// InitDefault initializes the default cache, it is called by
+// config.ParseFlags when config.RegistAPIServerFlags is called.
+func InitDefault(config *Config) {
+	defaultCache = New(config)
+}
+
+// Default returns the default cache, nil if the cache is disabled.
+func Default() *Cache {
+	return defaultCache
+}
+
+// TTL returns the ttl of cached responses, 0 if the cache is disabled.
+func (c *Cache) TTL() time.Duration {
+	if c == nil {
+		return 0
+	}
+	return c.config.TTL
+}
+
+// Get returns the cached response of key, or fills it. Only responses of
+// 200 are cached, others are returned to the concurrent requests of the
+// fill and dropped.
+func (c *Cache) Get(key string, fill func() (*Entry, error)) (entry *Entry, hit bool, err error) {
+	if c == nil {
+		entry, err = fill()
+		if err == nil {
+			entry.ETag = ETag(entry.Body)
+		}
+		return entry, false, err
+	}
+	if entry := c.lookup(key); entry != nil {
+		return entry, true, nil
+	}
+	v, err, _ := c.group.Do(key, func() (interface{}, error) {
+		entry, err := fill()
+		if err != nil {
+			return nil, err
+		}
+		entry.ETag = ETag(entry.Body)
+		entry.StoredAt = c.now()
+		if entry.StatusCode == http.StatusOK {
+			c.store(key, entry)
+		}
+		return entry, nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return v.(*Entry), false, nil
+}
+
+// Cached returns whether the response of key is cached and not expired.
+func (c *Cache) Cached(key string) bool {
+	return c != nil && c.lookup(key) != nil
+}
+
+func (c *Cache) lookup(key string) *Entry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if !ok {
+		return nil
+	}
+	it := e.Value.(*item)
+	if c.now().Sub(it.entry.StoredAt) >= c.config.TTL {
+		c.order.Remove(e)
+		delete(c.items, key)
+		return nil
+	}
+	c.order.MoveToFront(e)
+	return it.entry
+}
+
+func (c *Cache) store(key string, entry *Entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[key]; ok {
+		e.Value.(*item).entry = entry
+		c.order.MoveToFront(e)
+		return
+	}
+	c.items[key] = c.order.PushFront(&item{key: key, entry: entry})
+	for c.config.MaxEntries > 0 && c.order.Len() > c.config.MaxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*item).key)
+	}
+}
+
+// Len returns the number of cached responses, expired ones included.
+func (c *Cache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
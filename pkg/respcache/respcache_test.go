@@ -0,0 +1,62 @@
+package respcache
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestGet(t *testing.T) {
+	now := time.Now()
+	c := New(&Config{TTL: time.Minute, MaxEntries: 2})
+	c.now = func() time.Time { return now }
+
+	fills := 0
+	fill := func(status int) func() (*Entry, error) {
+		return func() (*Entry, error) {
+			fills++
+			return &Entry{StatusCode: status, Body: []byte("body")}, nil
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		entry, hit, err := c.Get("/a", fill(http.StatusOK))
+		if err != nil || string(entry.Body) != "body" || entry.ETag != ETag([]byte("body")) {
+			t.Fatalf("Get() = %+v, %v", entry, err)
+		}
+		if hit != (i > 0) {
+			t.Errorf("request %d hit = %v", i, hit)
+		}
+	}
+	if fills != 1 {
+		t.Errorf("fills = %d, want 1", fills)
+	}
+
+	// errors are not cached
+	c.Get("/missing", fill(http.StatusNotFound))
+	c.Get("/missing", fill(http.StatusNotFound))
+	if fills != 3 {
+		t.Errorf("fills of errors = %d, want 3", fills)
+	}
+	if _, _, err := c.Get("/err", func() (*Entry, error) { return nil, errors.New("failed") }); err == nil {
+		t.Errorf("error of fill is not returned")
+	}
+
+	// the least recently used entry is evicted
+	c.Get("/b", fill(http.StatusOK))
+	c.Get("/a", fill(http.StatusOK))
+	c.Get("/c", fill(http.StatusOK))
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", c.Len())
+	}
+	if _, hit, _ := c.Get("/a", fill(http.StatusOK)); !hit {
+		t.Errorf("recently used entry is evicted")
+	}
+
+	// expired entries are filled again
+	now = now.Add(time.Minute)
+	if _, hit, _ := c.Get("/a", fill(http.StatusOK)); hit {
+		t.Errorf("expired entry is served")
+	}
+}
@@ -0,0 +1,153 @@
+// Package sampling narrows down the packages or repositories handled by a
+// collector, so that the whole pipeline can be run against a small subset
+// for quick end-to-end tests.
+//
+// A Sampler first keeps items whose key matches the filter regex (if any),
+// then picks at most Size of them at random (if Size > 0). A zero Config
+// disables sampling and every item is kept.
+//
+// Collectors usually use the default sampler, which is initialized by
+// config.ParseFlags when config.RegistSampleFlags is called:
+//
+//	packages = sampling.Map(packages)
+//	links = sampling.Slice(links)
+package sampling
+
+import (
+	"math/rand"
+	"regexp"
+	"sort"
+	"time"
+)
+
+type Config struct {
+	// Size is the max number of items to keep, 0 means no limit
+	Size int
+	// Filter is a regex, only items whose key matches it are kept
+	Filter string
+	// Seed is used for random sampling, 0 means a time based seed
+	Seed int64
+}
+
+type Sampler struct {
+	size   int
+	filter *regexp.Regexp
+	rnd    *rand.Rand
+}
+
+func NewSampler(config *Config) (*Sampler, error) {
+	s := &Sampler{}
+	if config == nil {
+		return s, nil
+	}
+
+	s.size = config.Size
+	if config.Filter != "" {
+		re, err := regexp.Compile(config.Filter)
+		if err != nil {
+			return nil, err
+		}
+		s.filter = re
+	}
+
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	s.rnd = rand.New(rand.NewSource(seed))
+
+	return s, nil
+}
+
+// Enabled returns true if the sampler may drop some items.
+func (s *Sampler) Enabled() bool {
+	return s != nil && (s.size > 0 || s.filter != nil)
+}
+
+// pick returns the indices of keys to keep, in ascending order.
+func (s *Sampler) pick(keys []string) []int {
+	idx := make([]int, 0, len(keys))
+	for i, k := range keys {
+		if s.filter == nil || s.filter.MatchString(k) {
+			idx = append(idx, i)
+		}
+	}
+
+	if s.size > 0 && len(idx) > s.size {
+		s.rnd.Shuffle(len(idx), func(i, j int) {
+			idx[i], idx[j] = idx[j], idx[i]
+		})
+		idx = idx[:s.size]
+		sort.Ints(idx)
+	}
+	return idx
+}
+
+// SliceFunc returns the sampled items of the slice, key is used for filter matching.
+func SliceFunc[T any](s *Sampler, items []T, key func(T) string) []T {
+	if !s.Enabled() {
+		return items
+	}
+
+	keys := make([]string, len(items))
+	for i, item := range items {
+		keys[i] = key(item)
+	}
+
+	ret := make([]T, 0)
+	for _, i := range s.pick(keys) {
+		ret = append(ret, items[i])
+	}
+	return ret
+}
+
+// MapFunc returns a new map which only contains the sampled entries,
+// key is used for filter matching.
+func MapFunc[K comparable, V any](s *Sampler, m map[K]V, key func(K) string) map[K]V {
+	if !s.Enabled() {
+		return m
+	}
+
+	// sort keys first, so the result only depends on the seed
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return key(keys[i]) < key(keys[j])
+	})
+
+	ret := make(map[K]V)
+	for _, k := range SliceFunc(s, keys, key) {
+		ret[k] = m[k]
+	}
+	return ret
+}
+
+var defaultSampler *Sampler
+
+// InitDefault initializes the default sampler used by Slice and Map.
+func InitDefault(config *Config) error {
+	s, err := NewSampler(config)
+	if err != nil {
+		return err
+	}
+	defaultSampler = s
+	return nil
+}
+
+// Default returns the default sampler, if it is not initialized,
+// sampling is disabled.
+func Default() *Sampler {
+	return defaultSampler
+}
+
+// Slice samples a string slice with the default sampler.
+func Slice(items []string) []string {
+	return SliceFunc(defaultSampler, items, func(s string) string { return s })
+}
+
+// Map samples a map with string keys with the default sampler.
+func Map[V any](m map[string]V) map[string]V {
+	return MapFunc(defaultSampler, m, func(s string) string { return s })
+}
@@ -0,0 +1,63 @@
+package sampling
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDisabled(t *testing.T) {
+	items := []string{"a", "b", "c"}
+
+	var nilSampler *Sampler
+	if got := SliceFunc(nilSampler, items, func(s string) string { return s }); len(got) != 3 {
+		t.Errorf("nil sampler kept %d items, want 3", len(got))
+	}
+
+	s, err := NewSampler(&Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Enabled() {
+		t.Errorf("empty config should not enable sampling")
+	}
+}
+
+func TestFilter(t *testing.T) {
+	s, err := NewSampler(&Config{Filter: "^lib"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := SliceFunc(s, []string{"libc", "bash", "libssl", "zlib"}, func(s string) string { return s })
+	want := []string{"libc", "libssl"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("SliceFunc() = %v, want %v", got, want)
+	}
+
+	if _, err := NewSampler(&Config{Filter: "("}); err == nil {
+		t.Errorf("NewSampler() with invalid regex should fail")
+	}
+}
+
+func TestSizeAndSeed(t *testing.T) {
+	m := make(map[string]int)
+	for i := 0; i < 100; i++ {
+		m[fmt.Sprintf("pkg%03d", i)] = i
+	}
+
+	s1, _ := NewSampler(&Config{Size: 10, Seed: 42})
+	s2, _ := NewSampler(&Config{Size: 10, Seed: 42})
+	r1 := MapFunc(s1, m, func(s string) string { return s })
+	r2 := MapFunc(s2, m, func(s string) string { return s })
+
+	if len(r1) != 10 {
+		t.Fatalf("MapFunc() kept %d items, want 10", len(r1))
+	}
+	for k, v := range r1 {
+		if m[k] != v {
+			t.Errorf("MapFunc() changed value of %s", k)
+		}
+		if _, ok := r2[k]; !ok {
+			t.Errorf("same seed should produce same sample, %s missing", k)
+		}
+	}
+}
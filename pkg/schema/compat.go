@@ -0,0 +1,146 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Change is a difference between two descriptors.
+type Change struct {
+	Table string
+	// Field is empty for changes of a table
+	Field    string
+	Detail   string
+	Breaking bool
+}
+
+func (c Change) String() string {
+	name := c.Table
+	if c.Field != "" {
+		name += "." + c.Field
+	}
+	kind := "compatible"
+	if c.Breaking {
+		kind = "breaking"
+	}
+	return fmt.Sprintf("%s: %s (%s)", name, c.Detail, kind)
+}
+
+// modeBreaking reports whether consumers of a field of mode old break on
+// mode new. A nullable field may become required, as consumers handle both.
+func modeBreaking(old, new string) bool {
+	return old != new && !(old == ModeNullable && new == ModeRequired)
+}
+
+// Diff returns the changes from old to new.
+func Diff(old, new *Descriptor) []Change {
+	var ret []Change
+	newTables := make(map[string]Table, len(new.Tables))
+	for _, t := range new.Tables {
+		newTables[t.Name] = t
+	}
+	oldTables := make(map[string]bool, len(old.Tables))
+
+	for _, ot := range old.Tables {
+		oldTables[ot.Name] = true
+		nt, ok := newTables[ot.Name]
+		if !ok {
+			ret = append(ret, Change{Table: ot.Name, Detail: "table removed", Breaking: true})
+			continue
+		}
+		if ot.Format != nt.Format {
+			ret = append(ret, Change{Table: ot.Name, Detail: fmt.Sprintf("format changed from %s to %s", ot.Format, nt.Format), Breaking: true})
+		}
+
+		// the remaining columns of a csv file must keep their order, as
+		// consumers may read them by position
+		var kept []string
+		for _, of := range ot.Fields {
+			i := indexOf(nt.Fields, of.Name)
+			if i < 0 {
+				ret = append(ret, Change{Table: ot.Name, Field: of.Name, Detail: "field removed", Breaking: true})
+				continue
+			}
+			kept = append(kept, of.Name)
+			nf := nt.Fields[i]
+			if of.Type != nf.Type {
+				ret = append(ret, Change{Table: ot.Name, Field: of.Name, Detail: fmt.Sprintf("type changed from %s to %s", of.Type, nf.Type), Breaking: true})
+			}
+			if of.Mode != nf.Mode {
+				ret = append(ret, Change{Table: ot.Name, Field: of.Name, Detail: fmt.Sprintf("mode changed from %s to %s", of.Mode, nf.Mode), Breaking: modeBreaking(of.Mode, nf.Mode)})
+			}
+		}
+		lastKept := -1
+		for i, f := range nt.Fields {
+			if indexOf(ot.Fields, f.Name) >= 0 {
+				lastKept = i
+			}
+		}
+		for i, f := range nt.Fields {
+			if indexOf(ot.Fields, f.Name) < 0 {
+				// new columns of a csv file are appended, otherwise they
+				// shift the positions of the old ones
+				breaking := ot.Format == FormatCSV && i < lastKept
+				ret = append(ret, Change{Table: ot.Name, Field: f.Name, Detail: "field added", Breaking: breaking})
+			}
+		}
+		if ot.Format == FormatCSV && nt.Format == FormatCSV {
+			var order []string
+			for _, f := range nt.Fields {
+				if indexOf(ot.Fields, f.Name) >= 0 {
+					order = append(order, f.Name)
+				}
+			}
+			if strings.Join(order, ",") != strings.Join(kept, ",") {
+				ret = append(ret, Change{Table: ot.Name, Detail: "columns reordered", Breaking: true})
+			}
+		}
+	}
+	for _, nt := range new.Tables {
+		if !oldTables[nt.Name] {
+			ret = append(ret, Change{Table: nt.Name, Detail: "table added"})
+		}
+	}
+	return ret
+}
+
+// Check returns an error if the version of new does not follow the changes
+// from old: breaking changes need a new major version, and compatible ones
+// a new minor version.
+func Check(old, new *Descriptor) error {
+	if old.Dataset != new.Dataset {
+		return fmt.Errorf("dataset %s is not %s", new.Dataset, old.Dataset)
+	}
+	oldMajor, oldMinor, err := ParseVersion(old.Version)
+	if err != nil {
+		return err
+	}
+	newMajor, newMinor, err := ParseVersion(new.Version)
+	if err != nil {
+		return err
+	}
+
+	changes := Diff(old, new)
+	var breaking []string
+	for _, c := range changes {
+		if c.Breaking {
+			breaking = append(breaking, c.String())
+		}
+	}
+
+	switch {
+	case newMajor < oldMajor || (newMajor == oldMajor && newMinor < oldMinor):
+		return fmt.Errorf("version %s of %s is older than %s", new.Version, new.Dataset, old.Version)
+	case len(breaking) > 0 && newMajor == oldMajor:
+		return fmt.Errorf("breaking changes of %s need a major version after %s:\n  %s",
+			new.Dataset, old.Version, strings.Join(breaking, "\n  "))
+	case len(changes) > 0 && newMajor == oldMajor && newMinor == oldMinor:
+		all := make([]string, len(changes))
+		for i, c := range changes {
+			all[i] = c.String()
+		}
+		return fmt.Errorf("changes of %s need a minor version after %s:\n  %s",
+			new.Dataset, old.Version, strings.Join(all, "\n  "))
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+// Package schema describes the columns of the published datasets, so their
+// consumers can rely on them. A dataset has a version of the form
+// <major>.<minor>:
+//
+//   - a compatible change, e.g. a new column, bumps the minor version
+//   - a breaking change, e.g. a removed or retyped column, bumps the major
+//     version
+//
+// The descriptor of a dataset is generated from the Go structs of its rows
+// and published next to the data as schema.json. Check compares it with the
+// descriptor of the last release, so columns are never changed silently.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+	"github.com/lib/pq"
+)
+
+// File is the name of the published descriptor.
+const File = "schema.json"
+
+// Types of fields, which are the types of BigQuery.
+const (
+	TypeString    = "STRING"
+	TypeInteger   = "INTEGER"
+	TypeFloat     = "FLOAT"
+	TypeBoolean   = "BOOLEAN"
+	TypeTimestamp = "TIMESTAMP"
+)
+
+// Modes of fields.
+const (
+	ModeNullable = "NULLABLE"
+	ModeRequired = "REQUIRED"
+	ModeRepeated = "REPEATED"
+)
+
+// Formats of tables.
+const (
+	FormatCSV    = "csv"
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+)
+
+// Field is a column of a table.
+type Field struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Mode string `json:"mode"`
+}
+
+// Table is a file, or a group of files of the same rows, of a dataset.
+type Table struct {
+	Name   string `json:"name"`
+	Format string `json:"format"`
+	// Fields are in the order of the columns of csv files
+	Fields []Field `json:"fields"`
+}
+
+// Descriptor describes a dataset.
+type Descriptor struct {
+	Dataset string  `json:"dataset"`
+	Version string  `json:"version"`
+	Tables  []Table `json:"tables"`
+}
+
+var (
+	timeType        = reflect.TypeOf(time.Time{})
+	stringArrayType = reflect.TypeOf(pq.StringArray{})
+)
+
+// fieldType returns the type and mode of a struct field. Pointers are
+// nullable, slices are repeated and other fields are required.
+func fieldType(t reflect.Type) (string, string, error) {
+	mode := ModeRequired
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		mode = ModeNullable
+	}
+	if t == stringArrayType {
+		return TypeString, ModeRepeated, nil
+	}
+	if t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 {
+		typ, _, err := fieldType(t.Elem())
+		return typ, ModeRepeated, err
+	}
+	if t == timeType {
+		return TypeTimestamp, mode, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return TypeString, mode, nil
+	case reflect.Bool:
+		return TypeBoolean, mode, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return TypeInteger, mode, nil
+	case reflect.Float32, reflect.Float64:
+		return TypeFloat, mode, nil
+	}
+	return "", "", fmt.Errorf("unsupported field type %s", t)
+}
+
+// fieldsOf returns the fields of the struct T, name returns the name of a
+// struct field and whether it is a column.
+func fieldsOf[T any](name func(f reflect.StructField) (string, bool)) ([]Field, error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	ret := make([]Field, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		n, ok := name(f)
+		if !ok {
+			continue
+		}
+		typ, mode, err := fieldType(f.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", t.Name(), f.Name, err)
+		}
+		ret = append(ret, Field{Name: n, Type: typ, Mode: mode})
+	}
+	return ret, nil
+}
+
+// ColumnsOf returns the fields of a repository struct, named by their
+// database columns.
+func ColumnsOf[T any]() ([]Field, error) {
+	return fieldsOf[T](func(f reflect.StructField) (string, bool) {
+		return sqlutil.ColumnName(f), f.Tag.Get("ignore") != "true"
+	})
+}
+
+// JSONFieldsOf returns the fields of a struct encoded as json, named by
+// their json tags. Fields with omitempty may be missing and are nullable.
+func JSONFieldsOf[T any]() ([]Field, error) {
+	fields, err := fieldsOf[T](func(f reflect.StructField) (string, bool) {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" {
+			name = f.Name
+		}
+		return name, name != "-"
+	})
+	if err != nil {
+		return nil, err
+	}
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	omitempty := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("json")
+		if name, opts, _ := strings.Cut(tag, ","); strings.Contains(opts, "omitempty") {
+			omitempty[name] = true
+		}
+	}
+	for i := range fields {
+		if omitempty[fields[i].Name] && fields[i].Mode == ModeRequired {
+			fields[i].Mode = ModeNullable
+		}
+	}
+	return fields, nil
+}
+
+// Select returns the fields of names in their order, e.g. the columns of a
+// csv file.
+func Select(fields []Field, names ...string) ([]Field, error) {
+	ret := make([]Field, 0, len(names))
+	for _, name := range names {
+		i := indexOf(fields, name)
+		if i < 0 {
+			return nil, fmt.Errorf("unknown field %s", name)
+		}
+		ret = append(ret, fields[i])
+	}
+	return ret, nil
+}
+
+func indexOf(fields []Field, name string) int {
+	for i, f := range fields {
+		if f.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Read reads a descriptor from a file.
+func Read(path string) (*Descriptor, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var d Descriptor
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("invalid schema %s: %w", path, err)
+	}
+	return &d, nil
+}
+
+// Marshal returns the indented json of the descriptor.
+func (d *Descriptor) Marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// Write writes the descriptor to a file.
+func (d *Descriptor) Write(path string) error {
+	data, err := d.Marshal()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// ParseVersion returns the major and minor version of v.
+func ParseVersion(v string) (int, int, error) {
+	major, minor, ok := strings.Cut(v, ".")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid version %q, want <major>.<minor>", v)
+	}
+	ma, err := strconv.Atoi(major)
+	if err != nil || ma < 0 {
+		return 0, 0, fmt.Errorf("invalid major version %q", v)
+	}
+	mi, err := strconv.Atoi(minor)
+	if err != nil || mi < 0 {
+		return 0, 0, fmt.Errorf("invalid minor version %q", v)
+	}
+	return ma, mi, nil
+}
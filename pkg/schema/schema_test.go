@@ -0,0 +1,116 @@
+package schema
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type row struct {
+	ID        *int64 `pk:"true"`
+	GitLink   *string
+	Language  *pq.StringArray
+	UpdatedAt *time.Time `column:"update_time"`
+	Internal  *string    `ignore:"true"`
+}
+
+type project struct {
+	Rank    int      `json:"rank"`
+	Tags    []string `json:"tags,omitempty"`
+	License string   `json:"license,omitempty"`
+	Score   *float64 `json:"score"`
+	Hidden  string   `json:"-"`
+}
+
+func TestFieldsOf(t *testing.T) {
+	columns, err := ColumnsOf[row]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Field{
+		{Name: "id", Type: TypeInteger, Mode: ModeNullable},
+		{Name: "git_link", Type: TypeString, Mode: ModeNullable},
+		{Name: "language", Type: TypeString, Mode: ModeRepeated},
+		{Name: "update_time", Type: TypeTimestamp, Mode: ModeNullable},
+	}
+	if !reflect.DeepEqual(columns, want) {
+		t.Errorf("ColumnsOf() = %v, want %v", columns, want)
+	}
+
+	fields, err := JSONFieldsOf[project]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []Field{
+		{Name: "rank", Type: TypeInteger, Mode: ModeRequired},
+		{Name: "tags", Type: TypeString, Mode: ModeRepeated},
+		{Name: "license", Type: TypeString, Mode: ModeNullable},
+		{Name: "score", Type: TypeFloat, Mode: ModeNullable},
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("JSONFieldsOf() = %v, want %v", fields, want)
+	}
+
+	if _, err := Select(fields, "score", "rank"); err != nil {
+		t.Errorf("Select() error = %v", err)
+	}
+	if _, err := Select(fields, "missing"); err == nil {
+		t.Error("Select() of a missing field error = nil")
+	}
+}
+
+func descriptor(version string, format string, fields ...string) *Descriptor {
+	d := &Descriptor{Dataset: "d", Version: version, Tables: []Table{{Name: "t", Format: format}}}
+	for _, f := range fields {
+		name, typ, _ := strings.Cut(f, ":")
+		mode := ModeNullable
+		if typ == "" {
+			typ = TypeString
+		}
+		if strings.HasSuffix(typ, "!") {
+			typ, mode = strings.TrimSuffix(typ, "!"), ModeRequired
+		}
+		d.Tables[0].Fields = append(d.Tables[0].Fields, Field{Name: name, Type: typ, Mode: mode})
+	}
+	return d
+}
+
+func TestCheck(t *testing.T) {
+	old := descriptor("1.2", FormatCSV, "a", "b", "c:INTEGER")
+	tests := []struct {
+		name    string
+		new     *Descriptor
+		wantErr bool
+	}{
+		{"unchanged", descriptor("1.2", FormatCSV, "a", "b", "c:INTEGER"), false},
+		{"appended without bump", descriptor("1.2", FormatCSV, "a", "b", "c:INTEGER", "d"), true},
+		{"appended", descriptor("1.3", FormatCSV, "a", "b", "c:INTEGER", "d"), false},
+		{"inserted into csv", descriptor("1.3", FormatCSV, "a", "d", "b", "c:INTEGER"), true},
+		{"inserted into csv with major", descriptor("2.0", FormatCSV, "a", "d", "b", "c:INTEGER"), false},
+		{"removed", descriptor("1.3", FormatCSV, "a", "b"), true},
+		{"removed with major", descriptor("2.0", FormatCSV, "a", "b"), false},
+		{"retyped", descriptor("1.3", FormatCSV, "a", "b", "c:FLOAT"), true},
+		{"reordered", descriptor("1.3", FormatCSV, "b", "a", "c:INTEGER"), true},
+		{"nullable to required", descriptor("1.3", FormatCSV, "a", "b", "c:INTEGER!"), false},
+		{"older", descriptor("1.1", FormatCSV, "a", "b", "c:INTEGER"), true},
+		{"invalid version", descriptor("1", FormatCSV, "a", "b", "c:INTEGER"), true},
+	}
+	for _, tt := range tests {
+		err := Check(old, tt.new)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Check() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	// json objects are read by name, so fields may be inserted anywhere
+	if err := Check(descriptor("1.0", FormatJSON, "a", "b"), descriptor("1.1", FormatJSON, "a", "d", "b")); err != nil {
+		t.Errorf("Check() of json error = %v", err)
+	}
+	// a required field may become nullable only with a major version
+	if err := Check(descriptor("1.0", FormatJSON, "a:STRING!"), descriptor("1.1", FormatJSON, "a")); err == nil {
+		t.Error("Check() of required to nullable error = nil")
+	}
+}
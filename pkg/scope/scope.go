@@ -0,0 +1,266 @@
+// Package scope narrows down the pipeline to the ecosystems, distributions
+// and languages a deployment cares about, e.g. only Python and C projects,
+// so enumerators, collectors and the scorer do not spend their quota on
+// projects out of scope.
+//
+// Every dimension has an allowlist and a blocklist: an empty allowlist
+// allows all values, and a value in the blocklist is never allowed. Names,
+// e.g. git links and enumerated urls, are matched by the include and
+// exclude regexes.
+//
+// Commands usually use the default scope, which is initialized by
+// config.ParseFlags when config.RegistScopeFlags is called:
+//
+//	links = scope.Slice(links)
+package scope
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+)
+
+type Config struct {
+	// Ecosystems are the language ecosystems, e.g. npm and pypi
+	Ecosystems        []string
+	ExcludeEcosystems []string
+	// Distros are the distributions, e.g. debian
+	Distros        []string
+	ExcludeDistros []string
+	// Languages are the primary languages of repos, e.g. Python
+	Languages        []string
+	ExcludeLanguages []string
+	// Include and Exclude are regexes of names
+	Include string
+	Exclude string
+}
+
+// list is an allowlist and a blocklist of lower cased values.
+type list struct {
+	allow map[string]bool
+	block map[string]bool
+}
+
+func newList(allow, block []string) list {
+	set := func(values []string) map[string]bool {
+		if len(values) == 0 {
+			return nil
+		}
+		ret := make(map[string]bool, len(values))
+		for _, v := range values {
+			ret[strings.ToLower(strings.TrimSpace(v))] = true
+		}
+		return ret
+	}
+	return list{allow: set(allow), block: set(block)}
+}
+
+func (l list) enabled() bool {
+	return l.allow != nil || l.block != nil
+}
+
+// contains returns true if v is allowed.
+func (l list) contains(v string) bool {
+	v = strings.ToLower(v)
+	return !l.block[v] && (l.allow == nil || l.allow[v])
+}
+
+// any returns true if none of values is blocked and any is allowed.
+// Unknown values, i.e. none, are allowed, as they are not collected yet.
+func (l list) any(values []string) bool {
+	allowed := l.allow == nil || len(values) == 0
+	for _, v := range values {
+		v = strings.ToLower(v)
+		if l.block[v] {
+			return false
+		}
+		allowed = allowed || l.allow[v]
+	}
+	return allowed
+}
+
+// Project is a project matched by the scope, unknown fields are empty.
+type Project struct {
+	GitLink    string
+	Languages  []string
+	Ecosystems []string
+	Distros    []string
+}
+
+type Scope struct {
+	ecosystems list
+	distros    list
+	languages  list
+	include    *regexp.Regexp
+	exclude    *regexp.Regexp
+
+	// ac loads the languages and the ecosystems of links once, see Slice
+	ac       storage.AppDatabaseContext
+	once     sync.Once
+	projects map[string]*Project
+}
+
+// New returns the scope of config.
+func New(config *Config) (*Scope, error) {
+	s := &Scope{}
+	if config == nil {
+		return s, nil
+	}
+	s.ecosystems = newList(config.Ecosystems, config.ExcludeEcosystems)
+	s.distros = newList(config.Distros, config.ExcludeDistros)
+	s.languages = newList(config.Languages, config.ExcludeLanguages)
+	var err error
+	if config.Include != "" {
+		if s.include, err = regexp.Compile(config.Include); err != nil {
+			return nil, err
+		}
+	}
+	if config.Exclude != "" {
+		if s.exclude, err = regexp.Compile(config.Exclude); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Enabled returns true if the scope may drop some items.
+func (s *Scope) Enabled() bool {
+	return s != nil && (s.ecosystems.enabled() || s.distros.enabled() || s.languages.enabled() ||
+		s.include != nil || s.exclude != nil)
+}
+
+// Ecosystem returns true if the language ecosystem is in scope.
+func (s *Scope) Ecosystem(name string) bool {
+	return s == nil || s.ecosystems.contains(name)
+}
+
+// Distro returns true if the distribution is in scope.
+func (s *Scope) Distro(name string) bool {
+	return s == nil || s.distros.contains(name)
+}
+
+// Language returns true if the language is in scope.
+func (s *Scope) Language(name string) bool {
+	return s == nil || s.languages.contains(name)
+}
+
+// Languages returns the allowed and the blocked languages, lower cased.
+func (s *Scope) Languages() (allow []string, block []string) {
+	if s == nil {
+		return nil, nil
+	}
+	for v := range s.languages.allow {
+		allow = append(allow, v)
+	}
+	for v := range s.languages.block {
+		block = append(block, v)
+	}
+	return allow, block
+}
+
+// Name returns true if the name, e.g. a git link, matches the include
+// regex and not the exclude one.
+func (s *Scope) Name(name string) bool {
+	if s == nil {
+		return true
+	}
+	if s.include != nil && !s.include.MatchString(name) {
+		return false
+	}
+	return s.exclude == nil || !s.exclude.MatchString(name)
+}
+
+// Project returns true if the project is in scope. A project is out of
+// scope if any of its languages, ecosystems or distributions is blocked,
+// or if it has some in a dimension with an allowlist but none is allowed.
+func (s *Scope) Project(p *Project) bool {
+	if !s.Enabled() {
+		return true
+	}
+	return s.Name(p.GitLink) && s.languages.any(p.Languages) &&
+		s.ecosystems.any(p.Ecosystems) && s.distros.any(p.Distros)
+}
+
+// load loads the languages and the ecosystems of the latest git metrics
+// of every link.
+func (s *Scope) load() {
+	s.projects = make(map[string]*Project)
+	if s.ac == nil || !(s.languages.enabled() || s.ecosystems.enabled()) {
+		return
+	}
+	metrics, err := repository.NewGitMetricsRepository(s.ac).QueryScopes()
+	if err != nil {
+		logger.Errorf("Failed to load the languages of repos, the scope only matches names: %v", err)
+		return
+	}
+	for m := range metrics {
+		p := &Project{GitLink: *m.GitLink}
+		if m.Language != nil {
+			p.Languages = *m.Language
+		}
+		if m.EcoSystem != nil {
+			p.Ecosystems = strings.Fields(*m.EcoSystem)
+		}
+		s.projects[p.GitLink] = p
+	}
+}
+
+// Link returns true if the project of link is in scope, by the languages
+// and the ecosystems of its latest git metrics. Links without metrics are
+// only matched by name.
+func (s *Scope) Link(link string) bool {
+	if !s.Enabled() {
+		return true
+	}
+	s.once.Do(s.load)
+	if p, ok := s.projects[link]; ok {
+		return s.Project(p)
+	}
+	return s.Name(link)
+}
+
+// SliceFunc returns the items whose link is in scope.
+func SliceFunc[T any](s *Scope, items []T, link func(T) string) []T {
+	if !s.Enabled() {
+		return items
+	}
+	ret := make([]T, 0)
+	for _, item := range items {
+		if s.Link(link(item)) {
+			ret = append(ret, item)
+		}
+	}
+	if dropped := len(items) - len(ret); dropped > 0 {
+		logger.Infof("Skipping %d of %d repos out of scope", dropped, len(items))
+	}
+	return ret
+}
+
+var defaultScope *Scope
+
+// InitDefault initializes the default scope used by Slice, the languages
+// and the ecosystems of links are loaded from ac when needed.
+func InitDefault(ac storage.AppDatabaseContext, config *Config) error {
+	s, err := New(config)
+	if err != nil {
+		return err
+	}
+	s.ac = ac
+	defaultScope = s
+	return nil
+}
+
+// Default returns the default scope, if it is not initialized, all items
+// are in scope.
+func Default() *Scope {
+	return defaultScope
+}
+
+// Slice filters links with the default scope.
+func Slice(links []string) []string {
+	return SliceFunc(defaultScope, links, func(s string) string { return s })
+}
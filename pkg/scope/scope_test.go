@@ -0,0 +1,65 @@
+package scope
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProject(t *testing.T) {
+	s, err := New(&Config{
+		Languages:         []string{"Python", "C"},
+		ExcludeEcosystems: []string{"npm"},
+		Exclude:           `/forks?/`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		p    Project
+		want bool
+	}{
+		{"allowed language", Project{GitLink: "https://github.com/a/a", Languages: []string{"python"}}, true},
+		{"other language", Project{GitLink: "https://github.com/a/a", Languages: []string{"Go"}}, false},
+		{"blocked ecosystem", Project{GitLink: "https://github.com/a/a", Languages: []string{"C"}, Ecosystems: []string{"pypi", "NPM"}}, false},
+		{"excluded name", Project{GitLink: "https://github.com/fork/a", Languages: []string{"C"}}, false},
+		{"unknown language", Project{GitLink: "https://github.com/a/a", Distros: []string{"debian"}}, true},
+	}
+	for _, tt := range tests {
+		if got := s.Project(&tt.p); got != tt.want {
+			t.Errorf("%s: Project(%+v) = %v, want %v", tt.name, tt.p, got, tt.want)
+		}
+	}
+
+	if !s.Ecosystem("pypi") || s.Ecosystem("npm") || !s.Distro("debian") || s.Language("go") {
+		t.Errorf("single values of %+v", s)
+	}
+	if _, err := New(&Config{Include: "("}); err == nil {
+		t.Errorf("New() of an invalid regex returns no error")
+	}
+}
+
+func TestSlice(t *testing.T) {
+	links := []string{"https://github.com/a/a", "https://github.com/b/b", "https://github.com/c/c", "https://gitlab.com/d/d"}
+
+	var disabled *Scope
+	if got := SliceFunc(disabled, links, func(s string) string { return s }); !reflect.DeepEqual(got, links) {
+		t.Errorf("disabled scope = %v, want %v", got, links)
+	}
+
+	s, err := New(&Config{Languages: []string{"c"}, Include: `^https://github\.com/`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// links without metrics are only matched by name
+	s.once.Do(func() {
+		s.projects = map[string]*Project{
+			"https://github.com/a/a": {GitLink: "https://github.com/a/a", Languages: []string{"C"}},
+			"https://github.com/b/b": {GitLink: "https://github.com/b/b", Languages: []string{"Rust"}},
+		}
+	})
+	want := []string{"https://github.com/a/a", "https://github.com/c/c"}
+	if got := SliceFunc(s, links, func(s string) string { return s }); !reflect.DeepEqual(got, want) {
+		t.Errorf("SliceFunc() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,274 @@
+package score
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Ranks returns the rank of every project by score descending, the highest
+// score is rank 1. Tied projects share the average of their ranks.
+func Ranks(scores map[string]float64) map[string]float64 {
+	links := make([]string, 0, len(scores))
+	for link := range scores {
+		links = append(links, link)
+	}
+	sort.Slice(links, func(i, j int) bool {
+		if scores[links[i]] != scores[links[j]] {
+			return scores[links[i]] > scores[links[j]]
+		}
+		return links[i] < links[j]
+	})
+
+	ret := make(map[string]float64, len(links))
+	for i := 0; i < len(links); {
+		j := i
+		for j < len(links) && scores[links[j]] == scores[links[i]] {
+			j++
+		}
+		// ranks i+1 to j are tied
+		rank := float64(i+1+j) / 2
+		for k := i; k < j; k++ {
+			ret[links[k]] = rank
+		}
+		i = j
+	}
+	return ret
+}
+
+// common returns the values of the projects in both a and b.
+func common(a, b map[string]float64) (map[string]float64, map[string]float64) {
+	ca := make(map[string]float64)
+	cb := make(map[string]float64)
+	for link, v := range a {
+		if w, ok := b[link]; ok {
+			ca[link], cb[link] = v, w
+		}
+	}
+	return ca, cb
+}
+
+// pearson returns the Pearson correlation of x and y, by the same keys.
+func pearson(x, y map[string]float64) float64 {
+	n := float64(len(x))
+	var mx, my float64
+	for k := range x {
+		mx += x[k]
+		my += y[k]
+	}
+	mx, my = mx/n, my/n
+	var sxy, sxx, syy float64
+	for k := range x {
+		dx, dy := x[k]-mx, y[k]-my
+		sxy += dx * dy
+		sxx += dx * dx
+		syy += dy * dy
+	}
+	if sxx == 0 || syy == 0 {
+		return math.NaN()
+	}
+	return sxy / math.Sqrt(sxx*syy)
+}
+
+// Spearman returns the Spearman rank correlation of the scores of the
+// projects in both a and b, and the number of those projects. It is NaN
+// for less than 2 projects or if all scores of either are equal.
+func Spearman(a, b map[string]float64) (float64, int) {
+	ca, cb := common(a, b)
+	if len(ca) < 2 {
+		return math.NaN(), len(ca)
+	}
+	return pearson(Ranks(ca), Ranks(cb)), len(ca)
+}
+
+// Kendall returns the Kendall tau-b rank correlation of the scores of the
+// projects in both a and b, and the number of those projects. It is NaN
+// for less than 2 projects or if all scores of either are equal.
+func Kendall(a, b map[string]float64) (float64, int) {
+	ca, cb := common(a, b)
+	n := len(ca)
+	if n < 2 {
+		return math.NaN(), n
+	}
+	type pair struct{ x, y float64 }
+	pairs := make([]pair, 0, n)
+	for link := range ca {
+		pairs = append(pairs, pair{ca[link], cb[link]})
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].x != pairs[j].x {
+			return pairs[i].x < pairs[j].x
+		}
+		return pairs[i].y < pairs[j].y
+	})
+
+	// Knight's algorithm: count the pairs tied in x, in both x and y, and
+	// in y, and the discordant pairs as the swaps of sorting by y
+	ties := func(equal func(i, j int) bool) int64 {
+		var ret int64
+		for i := 0; i < n; {
+			j := i + 1
+			for j < n && equal(i, j) {
+				j++
+			}
+			t := int64(j - i)
+			ret += t * (t - 1) / 2
+			i = j
+		}
+		return ret
+	}
+	tiedX := ties(func(i, j int) bool { return pairs[i].x == pairs[j].x })
+	tiedXY := ties(func(i, j int) bool { return pairs[i] == pairs[j] })
+
+	var swaps int64
+	buf := make([]pair, n)
+	for width := 1; width < n; width *= 2 {
+		for lo := 0; lo < n-width; lo += 2 * width {
+			mid, hi := lo+width, min(lo+2*width, n)
+			i, j, k := lo, mid, lo
+			for i < mid && j < hi {
+				if pairs[j].y < pairs[i].y {
+					swaps += int64(mid - i)
+					buf[k] = pairs[j]
+					j++
+				} else {
+					buf[k] = pairs[i]
+					i++
+				}
+				k++
+			}
+			k += copy(buf[k:], pairs[i:mid])
+			copy(buf[k:], pairs[j:hi])
+			copy(pairs[lo:hi], buf[lo:hi])
+		}
+	}
+	tiedY := ties(func(i, j int) bool { return pairs[i].y == pairs[j].y })
+
+	total := int64(n) * int64(n-1) / 2
+	if total == tiedX || total == tiedY {
+		return math.NaN(), n
+	}
+	diff := float64(total - tiedX - tiedY + tiedXY - 2*swaps)
+	return diff / math.Sqrt(float64(total-tiedX)*float64(total-tiedY)), n
+}
+
+// ranked returns the links of scores by descending score, ties are broken
+// by git link like the published ranking.
+func ranked(scores map[string]float64) []string {
+	links := make([]string, 0, len(scores))
+	for link := range scores {
+		links = append(links, link)
+	}
+	sort.Slice(links, func(i, j int) bool {
+		if scores[links[i]] != scores[links[j]] {
+			return scores[links[i]] > scores[links[j]]
+		}
+		return links[i] < links[j]
+	})
+	return links
+}
+
+// positions returns the rank of every link of scores, 1 is the highest.
+func positions(scores map[string]float64) map[string]int {
+	links := ranked(scores)
+	ret := make(map[string]int, len(links))
+	for i, link := range links {
+		ret[link] = i + 1
+	}
+	return ret
+}
+
+// Shift is the change of the rank of a project.
+type Shift struct {
+	GitLink string
+	From    int
+	To      int
+}
+
+// RankShifts returns the rank changes of the projects in both base and
+// candidate, the largest first. Ranks are positions, ties are broken by
+// git link like the published ranking.
+func RankShifts(base, candidate map[string]float64) []Shift {
+	ca, cb := common(base, candidate)
+	from, to := positions(ca), positions(cb)
+
+	ret := make([]Shift, 0, len(from))
+	for link := range from {
+		if from[link] != to[link] {
+			ret = append(ret, Shift{GitLink: link, From: from[link], To: to[link]})
+		}
+	}
+	abs := func(s Shift) int {
+		if s.To > s.From {
+			return s.To - s.From
+		}
+		return s.From - s.To
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if abs(ret[i]) != abs(ret[j]) {
+			return abs(ret[i]) > abs(ret[j])
+		}
+		return ret[i].GitLink < ret[j].GitLink
+	})
+	return ret
+}
+
+// Comparison compares the ranking of a candidate formula with the base one.
+type Comparison struct {
+	Base      string
+	Candidate string
+	Projects  int
+	Spearman  float64
+	Kendall   float64
+	// Changed is the number of projects whose rank changed
+	Changed int
+	// Gains and Drops are the largest rank changes up and down
+	Gains []Shift
+	Drops []Shift
+}
+
+// Compare compares the scores of candidate with base, keeping top gains
+// and drops.
+func Compare(baseName string, base map[string]float64, candidateName string, candidate map[string]float64, top int) *Comparison {
+	rho, n := Spearman(base, candidate)
+	tau, _ := Kendall(base, candidate)
+	ret := &Comparison{Base: baseName, Candidate: candidateName, Projects: n, Spearman: rho, Kendall: tau}
+	shifts := RankShifts(base, candidate)
+	ret.Changed = len(shifts)
+	for _, s := range shifts {
+		if s.To < s.From && len(ret.Gains) < top {
+			ret.Gains = append(ret.Gains, s)
+		}
+		if s.To > s.From && len(ret.Drops) < top {
+			ret.Drops = append(ret.Drops, s)
+		}
+	}
+	return ret
+}
+
+// WriteReport writes the comparison as markdown.
+func (c *Comparison) WriteReport(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s vs %s\n\n", c.Candidate, c.Base)
+	fmt.Fprintf(&b, "- Projects: %d\n", c.Projects)
+	fmt.Fprintf(&b, "- Spearman correlation: %.4f\n", c.Spearman)
+	fmt.Fprintf(&b, "- Kendall correlation: %.4f\n", c.Kendall)
+	fmt.Fprintf(&b, "- Rank changed: %d\n\n", c.Changed)
+	for _, list := range []struct {
+		title  string
+		shifts []Shift
+	}{{"Largest gains", c.Gains}, {"Largest drops", c.Drops}} {
+		if len(list.shifts) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "### %s\n\n| Project | %s | %s | Shift |\n| --- | ---: | ---: | ---: |\n", list.title, c.Base, c.Candidate)
+		for _, s := range list.shifts {
+			fmt.Fprintf(&b, "| %s | %d | %d | %+d |\n", s.GitLink, s.From, s.To, s.From-s.To)
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
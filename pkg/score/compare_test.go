@@ -0,0 +1,128 @@
+package score
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestRanks(t *testing.T) {
+	got := Ranks(map[string]float64{"a": 3, "b": 2, "c": 2, "d": 1})
+	want := map[string]float64{"a": 1, "b": 2.5, "c": 2.5, "d": 4}
+	for link, rank := range want {
+		if got[link] != rank {
+			t.Errorf("Ranks()[%s] = %v, want %v", link, got[link], rank)
+		}
+	}
+}
+
+func TestSpearman(t *testing.T) {
+	base := map[string]float64{"a": 4, "b": 3, "c": 2, "d": 1}
+	tests := []struct {
+		name      string
+		candidate map[string]float64
+		want      float64
+		n         int
+	}{
+		{"identical ranking", map[string]float64{"a": 40, "b": 30, "c": 20, "d": 10}, 1, 4},
+		{"reversed ranking", map[string]float64{"a": 1, "b": 2, "c": 3, "d": 4}, -1, 4},
+		{"common projects", map[string]float64{"a": 2, "b": 1, "e": 3}, 1, 2},
+	}
+	for _, tt := range tests {
+		got, n := Spearman(base, tt.candidate)
+		if math.Abs(got-tt.want) > 1e-9 || n != tt.n {
+			t.Errorf("%s: Spearman() = %v, %d, want %v, %d", tt.name, got, n, tt.want, tt.n)
+		}
+	}
+	if got, _ := Spearman(base, map[string]float64{"a": 1}); !math.IsNaN(got) {
+		t.Errorf("Spearman() of one project = %v, want NaN", got)
+	}
+}
+
+// kendall is the O(n^2) definition of tau-b.
+func kendall(a, b map[string]float64) float64 {
+	ca, cb := common(a, b)
+	links := make([]string, 0, len(ca))
+	for link := range ca {
+		links = append(links, link)
+	}
+	sign := func(f float64) float64 {
+		if f > 0 {
+			return 1
+		} else if f < 0 {
+			return -1
+		}
+		return 0
+	}
+	var sum, nx, ny float64
+	for i := range links {
+		for j := i + 1; j < len(links); j++ {
+			dx := sign(ca[links[i]] - ca[links[j]])
+			dy := sign(cb[links[i]] - cb[links[j]])
+			sum += dx * dy
+			nx += dx * dx
+			ny += dy * dy
+		}
+	}
+	return sum / math.Sqrt(nx*ny)
+}
+
+func TestKendall(t *testing.T) {
+	base := map[string]float64{"a": 4, "b": 3, "c": 2, "d": 1}
+	tests := []struct {
+		name      string
+		candidate map[string]float64
+		want      float64
+	}{
+		{"identical ranking", map[string]float64{"a": 40, "b": 30, "c": 20, "d": 10}, 1},
+		{"reversed ranking", map[string]float64{"a": 1, "b": 2, "c": 3, "d": 4}, -1},
+		{"one swap", map[string]float64{"a": 4, "b": 2, "c": 3, "d": 1}, 4.0 / 6},
+	}
+	for _, tt := range tests {
+		if got, n := Kendall(base, tt.candidate); math.Abs(got-tt.want) > 1e-9 || n != 4 {
+			t.Errorf("%s: Kendall() = %v, %d, want %v, 4", tt.name, got, n, tt.want)
+		}
+	}
+
+	// with ties
+	r := rand.New(rand.NewSource(1))
+	a, b := make(map[string]float64), make(map[string]float64)
+	for i := 0; i < 200; i++ {
+		link := fmt.Sprint(i)
+		a[link] = float64(r.Intn(20))
+		b[link] = a[link] + float64(r.Intn(10))
+	}
+	got, _ := Kendall(a, b)
+	if want := kendall(a, b); math.Abs(got-want) > 1e-9 {
+		t.Errorf("Kendall() with ties = %v, want %v", got, want)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	base := map[string]float64{"a": 4, "b": 3, "c": 2, "d": 1}
+	candidate := map[string]float64{"a": 4, "b": 1, "c": 2, "d": 3}
+	c := Compare("default", base, "new", candidate, 1)
+
+	if c.Projects != 4 || c.Changed != 2 {
+		t.Errorf("Compare() projects = %d, changed = %d, want 4, 2", c.Projects, c.Changed)
+	}
+	if len(c.Gains) != 1 || c.Gains[0] != (Shift{GitLink: "d", From: 4, To: 2}) {
+		t.Errorf("Compare() gains = %v", c.Gains)
+	}
+	if len(c.Drops) != 1 || c.Drops[0] != (Shift{GitLink: "b", From: 2, To: 4}) {
+		t.Errorf("Compare() drops = %v", c.Drops)
+	}
+
+	var buf bytes.Buffer
+	if err := c.WriteReport(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"## new vs default", "| d | 4 | 2 | +2 |", "| b | 2 | 4 | -2 |"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("WriteReport() = %s, want %q", buf.String(), want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package score
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+)
+
+// Change is a project of the diff of two runs, the score and the rank of
+// the run it is missing from are 0.
+type Change struct {
+	GitLink   string
+	FromScore float64
+	ToScore   float64
+	FromRank  int
+	ToRank    int
+}
+
+// Delta is the change of the score.
+func (c Change) Delta() float64 { return c.ToScore - c.FromScore }
+
+// RunDiff is the diff of the scores of two scoring runs, e.g. for the
+// changelogs of published datasets.
+type RunDiff struct {
+	From string
+	To   string
+	// FromProjects and ToProjects are the numbers of scored projects
+	FromProjects int
+	ToProjects   int
+	// Added and Removed are the projects scored by one run only, by rank
+	Added   []Change
+	Removed []Change
+	// Changes are the largest score changes of the projects of both runs
+	Changes []Change
+	// Top is the size of the top list, Entrants are the projects in the top
+	// of To but not of From, by rank
+	Top      int
+	Entrants []Change
+}
+
+// LoadRun returns the scores of a scoring run by git link.
+func LoadRun(ac storage.AppDatabaseContext, runID string) (map[string]float64, error) {
+	rows, err := repository.NewScoreRepository(ac).QueryByRun(runID)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[string]float64)
+	for s := range rows {
+		if s.GitLink != nil && s.Score != nil {
+			ret[*s.GitLink] = *s.Score
+		}
+	}
+	return ret, nil
+}
+
+// DiffRuns returns the diff of the scores of two runs, keeping the changes
+// largest changes and the entrants of the top projects.
+func DiffRuns(fromID string, from map[string]float64, toID string, to map[string]float64, top, changes int) *RunDiff {
+	fromRanks, toRanks := positions(from), positions(to)
+	change := func(link string) Change {
+		return Change{GitLink: link, FromScore: from[link], ToScore: to[link], FromRank: fromRanks[link], ToRank: toRanks[link]}
+	}
+	ret := &RunDiff{From: fromID, To: toID, FromProjects: len(from), ToProjects: len(to), Top: top}
+
+	for _, link := range ranked(to) {
+		c := change(link)
+		switch {
+		case c.FromRank == 0:
+			ret.Added = append(ret.Added, c)
+		case c.FromScore != c.ToScore:
+			ret.Changes = append(ret.Changes, c)
+		}
+		if c.ToRank <= top && (c.FromRank == 0 || c.FromRank > top) {
+			ret.Entrants = append(ret.Entrants, c)
+		}
+	}
+	for _, link := range ranked(from) {
+		if toRanks[link] == 0 {
+			ret.Removed = append(ret.Removed, change(link))
+		}
+	}
+
+	sort.SliceStable(ret.Changes, func(i, j int) bool {
+		return math.Abs(ret.Changes[i].Delta()) > math.Abs(ret.Changes[j].Delta())
+	})
+	if len(ret.Changes) > changes {
+		ret.Changes = ret.Changes[:changes]
+	}
+	return ret
+}
+
+// rank formats the rank of a change, - if the project is not ranked.
+func rank(r int) string {
+	if r == 0 {
+		return "-"
+	}
+	return strconv.Itoa(r)
+}
+
+// WriteMarkdown writes the diff as markdown, listing limit projects of
+// every section.
+func (d *RunDiff) WriteMarkdown(w io.Writer, limit int) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Changes from %s to %s\n\n", d.From, d.To)
+	fmt.Fprintf(&b, "- Projects: %d (%+d)\n", d.ToProjects, d.ToProjects-d.FromProjects)
+	fmt.Fprintf(&b, "- Added: %d\n", len(d.Added))
+	fmt.Fprintf(&b, "- Removed: %d\n", len(d.Removed))
+	fmt.Fprintf(&b, "- New in the top %d: %d\n\n", d.Top, len(d.Entrants))
+	for _, section := range []struct {
+		title   string
+		changes []Change
+	}{
+		{fmt.Sprintf("New in the top %d", d.Top), d.Entrants},
+		{"Largest score changes", d.Changes},
+		{"Added", d.Added},
+		{"Removed", d.Removed},
+	} {
+		if len(section.changes) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "## %s\n\n| Project | %s score | %s score | Change | %s rank | %s rank |\n| --- | ---: | ---: | ---: | ---: | ---: |\n",
+			section.title, d.From, d.To, d.From, d.To)
+		for i, c := range section.changes {
+			if i == limit {
+				fmt.Fprintf(&b, "\n... and %d more\n", len(section.changes)-limit)
+				break
+			}
+			fmt.Fprintf(&b, "| %s | %.4f | %.4f | %+.4f | %s | %s |\n", c.GitLink, c.FromScore, c.ToScore, c.Delta(), rank(c.FromRank), rank(c.ToRank))
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteCSV writes the projects of the diff as csv, the change column is
+// entrant, changed, added or removed, so entrants may be listed twice.
+func (d *RunDiff) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"change", "git_link", "from_score", "to_score", "delta", "from_rank", "to_rank"})
+	for _, section := range []struct {
+		name    string
+		changes []Change
+	}{{"entrant", d.Entrants}, {"changed", d.Changes}, {"added", d.Added}, {"removed", d.Removed}} {
+		for _, c := range section.changes {
+			cw.Write([]string{
+				section.name, c.GitLink,
+				strconv.FormatFloat(c.FromScore, 'f', -1, 64),
+				strconv.FormatFloat(c.ToScore, 'f', -1, 64),
+				strconv.FormatFloat(c.Delta(), 'f', -1, 64),
+				strconv.Itoa(c.FromRank), strconv.Itoa(c.ToRank),
+			})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,46 @@
+package score
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffRuns(t *testing.T) {
+	from := map[string]float64{"a": 0.9, "b": 0.8, "c": 0.7, "d": 0.1}
+	to := map[string]float64{"a": 0.9, "c": 0.95, "d": 0.5, "e": 0.85}
+
+	d := DiffRuns("r1", from, "r2", to, 2, 1)
+	if want := []Change{{GitLink: "e", ToScore: 0.85, ToRank: 3}}; !reflect.DeepEqual(d.Added, want) {
+		t.Errorf("Added = %+v, want %+v", d.Added, want)
+	}
+	if want := []Change{{GitLink: "b", FromScore: 0.8, FromRank: 2}}; !reflect.DeepEqual(d.Removed, want) {
+		t.Errorf("Removed = %+v, want %+v", d.Removed, want)
+	}
+	// d changed more than c, only one change is kept
+	if len(d.Changes) != 1 || d.Changes[0].GitLink != "d" {
+		t.Errorf("Changes = %+v", d.Changes)
+	}
+	if len(d.Entrants) != 1 || d.Entrants[0].GitLink != "c" || d.Entrants[0].FromRank != 3 || d.Entrants[0].ToRank != 1 {
+		t.Errorf("Entrants = %+v", d.Entrants)
+	}
+
+	var md strings.Builder
+	if err := d.WriteMarkdown(&md, 10); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# Changes from r1 to r2", "- Added: 1", "| c | 0.7000 | 0.9500 | +0.2500 | 3 | 1 |", "| b | 0.8000 | 0.0000 | -0.8000 | 2 | - |"} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("markdown does not contain %q:\n%s", want, md.String())
+		}
+	}
+
+	var csv strings.Builder
+	if err := d.WriteCSV(&csv); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(csv.String()), "\n")
+	if len(lines) != 5 || lines[1] != "entrant,c,0.7,0.95,0.25,3,1" || lines[4] != "removed,b,0.8,0,-0.8,2,0" {
+		t.Errorf("csv =\n%s", csv.String())
+	}
+}
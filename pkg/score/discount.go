@@ -0,0 +1,81 @@
+package score
+
+import (
+	"log"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/freshness"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+)
+
+// Discounts are the factors of the git metadata score and the language
+// ecosystem score of a project by the freshness and the confidence of their
+// signals, see package freshness.
+type Discounts struct {
+	Git     float64
+	LangEco float64
+}
+
+// NewDiscounts returns the discounts of a project by the provenance of its
+// signals, signals without provenance are not discounted.
+func NewDiscounts(policy freshness.Policy, provenance map[repository.Signal]*repository.SignalProvenance, now time.Time) Discounts {
+	return Discounts{
+		Git:     policy.Discount(provenance[repository.SignalGitMetadata], now),
+		LangEco: policy.Discount(provenance[repository.SignalLangEcosystem], now),
+	}
+}
+
+// SetDiscounts sets the discounts of the git metadata and the language
+// ecosystem metadata of every project with provenance.
+func SetDiscounts(gitMap map[string]*GitMetadata, langEcoMap map[string]*LangEcoMetadata,
+	provenance map[string]map[repository.Signal]*repository.SignalProvenance, policy freshness.Policy, now time.Time) {
+	for link, prov := range provenance {
+		d := NewDiscounts(policy, prov, now)
+		if m, ok := gitMap[link]; ok && m != nil {
+			m.Discount = &d.Git
+		}
+		if m, ok := langEcoMap[link]; ok && m != nil {
+			m.Discount = &d.LangEco
+		}
+	}
+}
+
+// discountOf is 1 for metadata without a discount.
+func discountOf(d *float64) float64 {
+	if d == nil {
+		return 1
+	}
+	return *d
+}
+
+// applyDiscounts discounts the git metadata score and the language
+// ecosystem score before they are weighted into the score.
+func (linkScore *LinkScore) applyDiscounts(gitMetadata *GitMetadata, langEcoMetadata *LangEcoMetadata) {
+	linkScore.GitDiscount, linkScore.LangEcoDiscount = 1, 1
+	if gitMetadata != nil {
+		linkScore.GitDiscount = discountOf(gitMetadata.Discount)
+	}
+	if langEcoMetadata != nil {
+		linkScore.LangEcoDiscount = discountOf(langEcoMetadata.Discount)
+	}
+	linkScore.GitMetadataScore.GitMetadataScore *= linkScore.GitDiscount
+	linkScore.LangEcoScore.LangEcoScore *= linkScore.LangEcoDiscount
+}
+
+// FetchProvenance returns the provenance of the signals of every project.
+func FetchProvenance(ac storage.AppDatabaseContext) map[string]map[repository.Signal]*repository.SignalProvenance {
+	repo := repository.NewSignalProvenanceRepository(ac)
+	provIter, err := repo.Query()
+	if err != nil {
+		log.Fatalf("Failed to fetch signal provenance: %v", err)
+	}
+	ret := make(map[string]map[repository.Signal]*repository.SignalProvenance)
+	for prov := range provIter {
+		if ret[*prov.GitLink] == nil {
+			ret[*prov.GitLink] = make(map[repository.Signal]*repository.SignalProvenance)
+		}
+		ret[*prov.GitLink][*prov.Signal] = prov
+	}
+	return ret
+}
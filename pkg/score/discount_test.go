@@ -0,0 +1,53 @@
+package score
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/freshness"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/samber/lo"
+)
+
+func TestSetDiscounts(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	gitMap := map[string]*GitMetadata{
+		"a": {CreatedSince: now.AddDate(-5, 0, 0), UpdatedSince: now, ContributorCount: 100, Org_Count: 3},
+		"b": {CreatedSince: now.AddDate(-5, 0, 0), UpdatedSince: now, ContributorCount: 100, Org_Count: 3},
+	}
+	langEcoMap := map[string]*LangEcoMetadata{"a": {}, "b": {}}
+	provenance := map[string]map[repository.Signal]*repository.SignalProvenance{
+		"a": {
+			repository.SignalGitMetadata: {
+				Source:      lo.ToPtr(freshness.SourceProbe),
+				CollectedAt: lo.ToPtr(now),
+				Confidence:  lo.ToPtr(freshness.Confidence(freshness.SourceProbe)),
+			},
+		},
+	}
+	SetDiscounts(gitMap, langEcoMap, provenance, freshness.DefaultPolicy, now)
+
+	if gitMap["a"].Discount == nil || math.Abs(*gitMap["a"].Discount-0.6) > 1e-9 {
+		t.Errorf("git discount of a = %v, want 0.6", gitMap["a"].Discount)
+	}
+	// signals without provenance are not discounted
+	if d := langEcoMap["a"].Discount; d == nil || *d != 1 {
+		t.Errorf("lang eco discount of a = %v, want 1", d)
+	}
+	if gitMap["b"].Discount != nil {
+		t.Errorf("git discount of b = %v, want nil", *gitMap["b"].Discount)
+	}
+
+	var discounted, full LinkScore
+	discounted.GitMetadataScore.CalculateGitMetadataScore(gitMap["a"], weights)
+	discounted.applyDiscounts(gitMap["a"], langEcoMap["a"])
+	full.GitMetadataScore.CalculateGitMetadataScore(gitMap["b"], weights)
+	full.applyDiscounts(gitMap["b"], langEcoMap["b"])
+	if full.GitDiscount != 1 || full.LangEcoDiscount != 1 {
+		t.Errorf("discounts of b = %v, %v, want 1", full.GitDiscount, full.LangEcoDiscount)
+	}
+	if got, want := discounted.GitMetadataScore.GitMetadataScore, 0.6*full.GitMetadataScore.GitMetadataScore; math.Abs(got-want) > 1e-9 {
+		t.Errorf("discounted git score = %v, want %v", got, want)
+	}
+}
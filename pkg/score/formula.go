@@ -0,0 +1,82 @@
+package score
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DefaultFormula is the formula of the default weights.
+const DefaultFormula = "default"
+
+// Formula is a named weighting, e.g. a proposed one, which is computed side
+// by side with the stored one, so the rankings can be compared before
+// switching to it.
+type Formula struct {
+	// Weights override the default weights, weights not set are kept
+	Weights Weights `mapstructure:"weights"`
+}
+
+// NewFormulas returns the profiles of every formula and of DefaultFormula.
+// The profiles override the weights of every formula in the same way they
+// override the default weights.
+func NewFormulas(formulas map[string]Formula, profiles map[string]Profile) (map[string]*Profiles, error) {
+	if _, ok := formulas[DefaultFormula]; ok {
+		return nil, fmt.Errorf("formula %s is reserved for the default weights", DefaultFormula)
+	}
+	def, err := newProfiles(weights, profiles)
+	if err != nil {
+		return nil, err
+	}
+	ret := map[string]*Profiles{DefaultFormula: def}
+	for name, formula := range formulas {
+		base, err := merge(weights, formula.Weights)
+		if err != nil {
+			return nil, fmt.Errorf("formula %s: %w", name, err)
+		}
+		if ret[name], err = newProfiles(base, profiles); err != nil {
+			return nil, fmt.Errorf("formula %s: %w", name, err)
+		}
+	}
+	return ret, nil
+}
+
+// ScoreLink returns the score of a project with the weights of its profile,
+// the git metadata and the language ecosystem scores are discounted by the
+// discounts of their metadata.
+func ScoreLink(profiles *Profiles, gitMetadata *GitMetadata, distMetadata *DistMetadata, langEcoMetadata *LangEcoMetadata) *LinkScore {
+	profile, weights := profiles.SelectProject(gitMetadata, distMetadata)
+
+	distScore := NewDistScore()
+	distScore.CalculateDistMerics(distMetadata, PackageList[distMetadata.Type])
+	distScore.CalculateDistScore(weights)
+
+	langEcoScore := NewLangEcoScore()
+	langEcoScore.CalulateLangEcoMeritcs(langEcoMetadata, PackageCounts[langEcoMetadata.Type])
+	langEcoScore.CalculateLangEcoScore(weights)
+
+	gitMetadataScore := NewGitMetadataScore()
+	gitMetadataScore.CalculateGitMetadataScore(gitMetadata, weights)
+
+	linkScore := NewLinkScore(gitMetadataScore, distScore, langEcoScore)
+	linkScore.Profile = profile
+	linkScore.applyDiscounts(gitMetadata, langEcoMetadata)
+	if gitMetadata != nil {
+		linkScore.Language = gitMetadata.Language
+	}
+	if distMetadata != nil && len(distMetadata.Distros) > 0 {
+		linkScore.Distro = distMetadata.Type.String()
+		linkScore.DistroCount = len(distMetadata.Distros)
+	}
+	linkScore.CalculateScore(weights)
+	return linkScore
+}
+
+// FormulaNames returns the names of formulas, sorted.
+func FormulaNames(formulas map[string]*Profiles) []string {
+	ret := make([]string, 0, len(formulas))
+	for name := range formulas {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
@@ -0,0 +1,56 @@
+package score
+
+import (
+	"testing"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+)
+
+func TestFormulas(t *testing.T) {
+	formulas, err := NewFormulas(map[string]Formula{
+		"fewer-commits": {Weights: Weights{"gitmetadatascore": {"commit_frequency": 0.5}}},
+	}, map[string]Profile{
+		"distro": {
+			Ecosystems: []string{"debian"},
+			Weights:    Weights{"gitMetadataScore": {"contributor_count": 1}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewFormulas() error = %v", err)
+	}
+	if names := FormulaNames(formulas); len(names) != 2 || names[0] != DefaultFormula || names[1] != "fewer-commits" {
+		t.Errorf("FormulaNames() = %v", names)
+	}
+
+	// profiles override the weights of every formula
+	name, w := formulas["fewer-commits"].Select("debian")
+	if name != "distro" || w["gitMetadataScore"]["commit_frequency"] != 0.5 || w["gitMetadataScore"]["contributor_count"] != 1 {
+		t.Errorf("Select(debian) of fewer-commits = %s, %v", name, w["gitMetadataScore"])
+	}
+	if _, w := formulas[DefaultFormula].Select("debian"); w["gitMetadataScore"]["commit_frequency"] != weights["gitMetadataScore"]["commit_frequency"] {
+		t.Errorf("Select(debian) of default = %v", w["gitMetadataScore"])
+	}
+}
+
+func TestFormulasInvalid(t *testing.T) {
+	tests := map[string]map[string]Formula{
+		"unknown weight": {"f": {Weights: Weights{"distScore": {"stars": 1}}}},
+		"reserved name":  {DefaultFormula: {}},
+	}
+	for name, formulas := range tests {
+		if _, err := NewFormulas(formulas, nil); err == nil {
+			t.Errorf("%s: NewFormulas() error = nil", name)
+		}
+	}
+}
+
+func TestScoreLink(t *testing.T) {
+	profiles, err := NewProfiles(map[string]Profile{"debian": {}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := ScoreLink(profiles, &GitMetadata{}, &DistMetadata{Type: repository.Debian}, &LangEcoMetadata{})
+	if got.Profile != "debian" {
+		t.Errorf("ScoreLink() profile = %s, want debian", got.Profile)
+	}
+}
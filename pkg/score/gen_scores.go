@@ -2,7 +2,10 @@ package score
 
 import (
 	"log"
+	"maps"
 	"math"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
@@ -17,6 +20,23 @@ type LinkScore struct {
 	DistId           int64
 	DistScore        DistScore
 	Score            float64
+	// Profile is the scoring profile of the weights
+	Profile string
+	// Language is the primary language, Distro is the source distribution
+	// and DistroCount is the number of distributions packaging the project
+	Language    string
+	Distro      string
+	DistroCount int
+	// Percentiles are the percentile ranks overall, within Language and
+	// within Distro, see SetPercentiles
+	Percentile         float64
+	LanguagePercentile float64
+	DistroPercentile   float64
+	// GitDiscount and LangEcoDiscount are the factors of GitMetadataScore
+	// and LangEcoScore by the freshness and the confidence of their
+	// signals, 1 if not discounted
+	GitDiscount     float64
+	LangEcoDiscount float64
 }
 
 type GitMetadata struct {
@@ -26,6 +46,21 @@ type GitMetadata struct {
 	ContributorCount int
 	CommitFrequency  float64
 	Org_Count        int
+	// StackOverflowQuestions is 0 if not collected
+	StackOverflowQuestions int
+	// TestsPerKLOC are the lines of tests per 1000 lines of other code, 0
+	// if not collected
+	TestsPerKLOC float64
+	// DependentsGrowth is the latest growth of the dependents per window
+	// of trend-calculator, 0 if not computed, see SetTrends
+	DependentsGrowth float64
+	// Ecosystems are the language ecosystems, the largest first
+	Ecosystems []string
+	// Language is the largest language
+	Language string
+	// Discount is the factor of the git metadata score, nil if not
+	// discounted, see SetDiscounts
+	Discount *float64
 }
 
 type GitMetadataScore struct {
@@ -38,12 +73,17 @@ type DistMetadata struct {
 	DepCount int
 	PageRank float64
 	Type     repository.DistType
+	// Distros are the distributions packaging the project, Type first
+	Distros []repository.DistType
 }
 
 type LangEcoMetadata struct {
 	Id       int64
 	Type     repository.LangEcosystemType
 	DepCount int
+	// Discount is the factor of the language ecosystem score, nil if not
+	// discounted, see SetDiscounts
+	Discount *float64
 }
 
 type DistScore struct {
@@ -60,14 +100,24 @@ type LangEcoScore struct {
 	LangEcoScore    float64
 }
 
-// Define weights (αi) and max thresholds (Ti)
-var weights = map[string]map[string]float64{
+// Define weights (αi) and max thresholds (Ti), weights are the weights of
+// DefaultProfile
+var weights = Weights{
 	"gitMetadataScore": {
 		"created_since":     1,
 		"updated_since":     -1,
 		"contributor_count": 2,
 		"commit_frequency":  1,
 		"org_count":         1,
+		// stackoverflow_questions is optional, it is only collected for
+		// projects with configured tags
+		"stackoverflow_questions": 0,
+		// tests_per_kloc is a quality signal for scoring experiments, it
+		// is only weighted by formulas or profiles
+		"tests_per_kloc": 0,
+		// dependents_growth tells fast-rising projects from plateaued
+		// ones, it is only weighted by formulas or profiles
+		"dependents_growth": 0,
 		"gitMetadataScore":  1,
 	},
 	"distScore": {
@@ -83,12 +133,15 @@ var weights = map[string]map[string]float64{
 
 var thresholds = map[string]map[string]float64{
 	"gitMetadataScore": {
-		"created_since":     120,
-		"updated_since":     120,
-		"contributor_count": 40000,
-		"commit_frequency":  1000,
-		"org_count":         8400,
-		"gitMetadataScore":  1,
+		"created_since":           120,
+		"updated_since":           120,
+		"contributor_count":       40000,
+		"commit_frequency":        1000,
+		"org_count":               8400,
+		"stackoverflow_questions": 5000,
+		"tests_per_kloc":          1000,
+		"dependents_growth":       1,
+		"gitMetadataScore":        1,
 	},
 	"distScore": {
 		"dist_impact":   1,
@@ -135,6 +188,16 @@ func (distMetadata *DistMetadata) PraseDistMetadata(distLink *repository.DistLin
 	distMetadata.DepCount = *distLink.DepCount
 	distMetadata.PageRank = *distLink.PageRank
 	distMetadata.Type = *distLink.Type
+	distMetadata.Distros = []repository.DistType{distMetadata.Type}
+}
+
+// merge adds the dependents of the package of other to the project.
+func (distMetadata *DistMetadata) merge(other *DistMetadata) {
+	distMetadata.DepCount += other.DepCount
+	distMetadata.PageRank += other.PageRank
+	if !slices.Contains(distMetadata.Distros, other.Type) {
+		distMetadata.Distros = append(distMetadata.Distros, other.Type)
+	}
 }
 
 func (distScore *DistScore) CalculateDistMerics(distMetadata *DistMetadata, distRepoCount int) {
@@ -154,10 +217,29 @@ func (gitMetadata *GitMetadata) ParseMetadata(gitMetic *repository.GitMetric) {
 	gitMetadata.UpdatedSince = *gitMetic.UpdatedSince
 	gitMetadata.ContributorCount = *gitMetic.ContributorCount
 	gitMetadata.CommitFrequency = *gitMetic.CommitFrequency
+	// bots inflate the activity, prefer the counts without them
+	if gitMetic.HumanContributorCount != nil {
+		gitMetadata.ContributorCount = *gitMetic.HumanContributorCount
+	}
+	if gitMetic.HumanCommitFrequency != nil {
+		gitMetadata.CommitFrequency = *gitMetic.HumanCommitFrequency
+	}
 	gitMetadata.Org_Count = *gitMetic.OrgCount
+	if gitMetic.StackOverflowQuestions != nil {
+		gitMetadata.StackOverflowQuestions = *gitMetic.StackOverflowQuestions
+	}
+	if gitMetic.TestsPerKLOC != nil {
+		gitMetadata.TestsPerKLOC = *gitMetic.TestsPerKLOC
+	}
+	if gitMetic.EcoSystem != nil {
+		gitMetadata.Ecosystems = strings.Fields(*gitMetic.EcoSystem)
+	}
+	if gitMetic.Language != nil && len(*gitMetic.Language) > 0 {
+		gitMetadata.Language = (*gitMetic.Language)[0]
+	}
 }
 
-func (langEcoScore *LangEcoScore) CalculateLangEcoScore() {
+func (langEcoScore *LangEcoScore) CalculateLangEcoScore(weights Weights) {
 	langEcoScore.LangEcoScore = weights["lang_eco_score"]["lang_eco_impact"] * langEcoScore.LangEcoImpact
 }
 
@@ -165,16 +247,16 @@ func NewLangEcoScore() *LangEcoScore {
 	return &LangEcoScore{}
 }
 
-func (gitMetadataScore *GitMetadataScore) CalculateGitMetadataScore(gitMetadata *GitMetadata) {
+func (gitMetadataScore *GitMetadataScore) CalculateGitMetadataScore(gitMetadata *GitMetadata, weights Weights) {
 	var score float64
 	var createdSinceScore, updatedSinceScore, contributorCountScore, commitFrequencyScore, orgCountScore float64
 
-	monthsSinceCreation := time.Since(gitMetadata.CreatedSince).Hours() / (24 * 30)
+	monthsSinceCreation := now().Sub(gitMetadata.CreatedSince).Hours() / (24 * 30)
 	normalized := math.Log(monthsSinceCreation+1) / math.Log(math.Max(monthsSinceCreation, thresholds["gitMetadataScore"]["created_since"])+1)
 	createdSinceScore = weights["gitMetadataScore"]["created_since"] * normalized
 	score += createdSinceScore
 
-	monthsSinceUpdate := time.Since(gitMetadata.UpdatedSince).Hours() / (24 * 30)
+	monthsSinceUpdate := now().Sub(gitMetadata.UpdatedSince).Hours() / (24 * 30)
 	normalized = math.Log(monthsSinceUpdate+1) / math.Log(math.Max(monthsSinceUpdate, thresholds["gitMetadataScore"]["updated_since"])+1)
 	updatedSinceScore = weights["gitMetadataScore"]["updated_since"] * normalized
 	score += updatedSinceScore
@@ -191,6 +273,14 @@ func (gitMetadataScore *GitMetadataScore) CalculateGitMetadataScore(gitMetadata
 	orgCountScore = weights["gitMetadataScore"]["org_count"] * normalized
 	score += orgCountScore
 
+	score += weights["gitMetadataScore"]["stackoverflow_questions"] *
+		LogNormalize(float64(gitMetadata.StackOverflowQuestions), thresholds["gitMetadataScore"]["stackoverflow_questions"])
+	score += weights["gitMetadataScore"]["tests_per_kloc"] *
+		LogNormalize(gitMetadata.TestsPerKLOC, thresholds["gitMetadataScore"]["tests_per_kloc"])
+	// shrinking projects are scored like plateaued ones
+	score += weights["gitMetadataScore"]["dependents_growth"] *
+		LogNormalize(math.Max(gitMetadata.DependentsGrowth, 0), thresholds["gitMetadataScore"]["dependents_growth"])
+
 	gitMetadataScore.GitMetadataScore = score
 	gitMetadataScore.Id = gitMetadata.Id
 }
@@ -199,10 +289,10 @@ func NewGitMetadata() *GitMetadata {
 	return &GitMetadata{}
 }
 
-func (distScore *DistScore) CalculateDistScore() {
+func (distScore *DistScore) CalculateDistScore(weights Weights) {
 	distScore.DistScore = weights["distScore"]["dist_impact"]*distScore.DistImpact + weights["distScore"]["dist_pagerank"]*distScore.DistPageRank
 }
-func (linkScore *LinkScore) CalculateScore() {
+func (linkScore *LinkScore) CalculateScore(weights Weights) {
 	score := 0.0
 
 	score += weights["gitMetadataScore"]["gitMetadataScore"] * linkScore.GitMetadataScore.GitMetadataScore
@@ -211,9 +301,11 @@ func (linkScore *LinkScore) CalculateScore() {
 
 	score += weights["distScore"]["distScore"] * linkScore.DistScore.DistScore
 
+	// summed in a fixed order, so the scores of a run can be replayed bit
+	// for bit
 	var totalnum float64
-	for nameScore, value := range weights {
-		for nameSubScore := range value {
+	for _, nameScore := range slices.Sorted(maps.Keys(weights)) {
+		for _, nameSubScore := range slices.Sorted(maps.Keys(weights[nameScore])) {
 			if nameSubScore != nameScore {
 				totalnum += weights["gitMetadataScore"][nameSubScore]
 			}
@@ -295,8 +387,7 @@ func FetchDistMetadata(ac storage.AppDatabaseContext) map[string]*DistMetadata {
 		distMetadata := NewDistMetadata()
 		distMetadata.PraseDistMetadata(link)
 		if exists, ok := distMap[*link.GitLink]; ok && exists != nil {
-			distMap[*link.GitLink].DepCount += distMetadata.DepCount
-			distMap[*link.GitLink].PageRank += distMetadata.PageRank
+			exists.merge(distMetadata)
 		} else {
 			distMap[*link.GitLink] = distMetadata
 		}
@@ -319,7 +410,24 @@ func UpdatePackageList(ac storage.AppDatabaseContext) {
 	}
 }
 
-func UpdateScore(ac storage.AppDatabaseContext, packageScore map[string]*LinkScore) {
+func nonEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// nonEmptyPercentile is nil for projects without a group.
+func nonEmptyPercentile(group string, p float64) *float64 {
+	if group == "" {
+		return nil
+	}
+	return &p
+}
+
+// UpdateScore stores the scores of a run, runID and configHash are empty
+// for scores which are not of a scoring run.
+func UpdateScore(ac storage.AppDatabaseContext, packageScore map[string]*LinkScore, runID, configHash string) {
 	repo := repository.NewScoreRepository(ac)
 	scores := []*repository.Score{}
 	for link, linkScore := range packageScore {
@@ -332,6 +440,18 @@ func UpdateScore(ac storage.AppDatabaseContext, packageScore map[string]*LinkSco
 			DistScore: &linkScore.DistScore.DistScore,
 			DevScore:  &linkScore.LangEcoScore.LangEcoScore,
 			GitScore:  &linkScore.GitMetadataScore.GitMetadataScore,
+			Profile:   &linkScore.Profile,
+
+			Language:           nonEmpty(linkScore.Language),
+			Distro:             nonEmpty(linkScore.Distro),
+			DistroCount:        &linkScore.DistroCount,
+			Percentile:         &linkScore.Percentile,
+			LanguagePercentile: nonEmptyPercentile(linkScore.Language, linkScore.LanguagePercentile),
+			DistroPercentile:   nonEmptyPercentile(linkScore.Distro, linkScore.DistroPercentile),
+			GitDiscount:        &linkScore.GitDiscount,
+			LangEcoDiscount:    &linkScore.LangEcoDiscount,
+			RunID:              nonEmpty(runID),
+			ConfigHash:         nonEmpty(configHash),
 		}
 		scores = append(scores, &score)
 	}
@@ -355,8 +475,7 @@ func FetchDistMetadataSingle(ac storage.AppDatabaseContext, link string) map[str
 		distMetadata := NewDistMetadata()
 		distMetadata.PraseDistMetadata(link)
 		if exists, ok := distMap[*link.GitLink]; ok && exists != nil {
-			distMap[*link.GitLink].DepCount += distMetadata.DepCount
-			distMap[*link.GitLink].PageRank += distMetadata.PageRank
+			exists.merge(distMetadata)
 		} else {
 			distMap[*link.GitLink] = distMetadata
 		}
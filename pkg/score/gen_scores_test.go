@@ -3,6 +3,10 @@ package score
 import (
 	"math"
 	"testing"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/samber/lo"
 )
 
 func TestCalculateDistScore(t *testing.T) {
@@ -12,7 +16,7 @@ func TestCalculateDistScore(t *testing.T) {
 	}
 
 	expectedScore := (weights["distScore"]["dist_impact"] * distScore.DistImpact) + (weights["distScore"]["dist_pagerank"] * distScore.DistPageRank)
-	distScore.CalculateDistScore()
+	distScore.CalculateDistScore(weights)
 
 	if distScore.DistScore != expectedScore {
 		t.Errorf("Expected score %v, but got %v", expectedScore, distScore.DistScore)
@@ -30,3 +34,87 @@ func TestLogNormalize(t *testing.T) {
 		t.Errorf("Expected %v, but got %v", expected, actual)
 	}
 }
+
+func TestParseMetadataPrefersHumanCounts(t *testing.T) {
+	metric := &repository.GitMetric{
+		ID:               lo.ToPtr(int64(1)),
+		CreatedSince:     lo.ToPtr(time.Now()),
+		UpdatedSince:     lo.ToPtr(time.Now()),
+		ContributorCount: lo.ToPtr(10),
+		CommitFrequency:  lo.ToPtr(5.0),
+		OrgCount:         lo.ToPtr(1),
+	}
+
+	var raw GitMetadata
+	raw.ParseMetadata(metric)
+	if raw.ContributorCount != 10 || raw.CommitFrequency != 5 {
+		t.Errorf("Expected the raw counts 10 and 5, but got %v and %v", raw.ContributorCount, raw.CommitFrequency)
+	}
+
+	metric.HumanContributorCount = lo.ToPtr(7)
+	metric.HumanCommitFrequency = lo.ToPtr(2.0)
+	var human GitMetadata
+	human.ParseMetadata(metric)
+	if human.ContributorCount != 7 || human.CommitFrequency != 2 {
+		t.Errorf("Expected the human counts 7 and 2, but got %v and %v", human.ContributorCount, human.CommitFrequency)
+	}
+}
+
+func TestStackOverflowQuestionsOptional(t *testing.T) {
+	metadata := &GitMetadata{CreatedSince: time.Now(), UpdatedSince: time.Now(), ContributorCount: 10}
+	var without, with GitMetadataScore
+	without.CalculateGitMetadataScore(metadata, weights)
+	metadata.StackOverflowQuestions = 1000
+	with.CalculateGitMetadataScore(metadata, weights)
+	// the months since creation differ by the time between the calls
+	if math.Abs(with.GitMetadataScore-without.GitMetadataScore) > 1e-9 {
+		t.Errorf("Expected the questions to be ignored by default, but got %v and %v", with.GitMetadataScore, without.GitMetadataScore)
+	}
+
+	enabled, err := merge(weights, Weights{"gitMetadataScore": {"stackoverflow_questions": 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	with.CalculateGitMetadataScore(metadata, enabled)
+	if with.GitMetadataScore <= without.GitMetadataScore {
+		t.Errorf("Expected the questions to raise the score, but got %v and %v", with.GitMetadataScore, without.GitMetadataScore)
+	}
+}
+
+func TestDependentsGrowthOptional(t *testing.T) {
+	now := time.Now()
+	gitMap := map[string]*GitMetadata{
+		"rising":    {CreatedSince: now, UpdatedSince: now, ContributorCount: 10},
+		"shrinking": {CreatedSince: now, UpdatedSince: now, ContributorCount: 10},
+		"plateaued": {CreatedSince: now, UpdatedSince: now, ContributorCount: 10},
+	}
+	SetTrends(gitMap, map[string]*repository.MetricTrend{
+		"rising":    {DependentsGrowth: lo.ToPtr(0.5)},
+		"shrinking": {DependentsGrowth: lo.ToPtr(-0.5)},
+		"plateaued": {StarsGrowth: lo.ToPtr(0.5)},
+		"unknown":   {DependentsGrowth: lo.ToPtr(0.5)},
+	})
+	if gitMap["rising"].DependentsGrowth != 0.5 || gitMap["plateaued"].DependentsGrowth != 0 {
+		t.Fatalf("Expected the dependents growth 0.5 and 0, but got %v and %v", gitMap["rising"].DependentsGrowth, gitMap["plateaued"].DependentsGrowth)
+	}
+
+	score := func(link string, w Weights) float64 {
+		var s GitMetadataScore
+		s.CalculateGitMetadataScore(gitMap[link], w)
+		return s.GitMetadataScore
+	}
+	if r, p := score("rising", weights), score("plateaued", weights); math.Abs(r-p) > 1e-9 {
+		t.Errorf("Expected the growth to be ignored by default, but got %v and %v", r, p)
+	}
+
+	enabled, err := merge(weights, Weights{"gitMetadataScore": {"dependents_growth": 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, p := score("rising", enabled), score("plateaued", enabled); r <= p {
+		t.Errorf("Expected the growth to raise the score, but got %v and %v", r, p)
+	}
+	if s, p := score("shrinking", enabled), score("plateaued", enabled); math.Abs(s-p) > 1e-9 {
+		t.Errorf("Expected shrinking projects to be scored like plateaued ones, but got %v and %v", s, p)
+	}
+}
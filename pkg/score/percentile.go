@@ -0,0 +1,62 @@
+package score
+
+import "sort"
+
+// Percentiles returns the percentile rank of every project, the percentage
+// of projects scored the same or lower, so the top project is 100.
+func Percentiles(scores map[string]float64) map[string]float64 {
+	values := make([]float64, 0, len(scores))
+	for _, s := range scores {
+		values = append(values, s)
+	}
+	sort.Float64s(values)
+
+	n := float64(len(values))
+	ret := make(map[string]float64, len(scores))
+	for link, s := range scores {
+		// the number of scores not above s
+		atMost := sort.Search(len(values), func(i int) bool { return values[i] > s })
+		ret[link] = float64(atMost) / n * 100
+	}
+	return ret
+}
+
+// PercentilesBy returns the percentile rank of every project within its
+// group, projects without a group have none.
+func PercentilesBy(scores map[string]float64, group func(link string) string) map[string]float64 {
+	groups := make(map[string]map[string]float64)
+	for link, s := range scores {
+		g := group(link)
+		if g == "" {
+			continue
+		}
+		if groups[g] == nil {
+			groups[g] = make(map[string]float64)
+		}
+		groups[g][link] = s
+	}
+	ret := make(map[string]float64, len(scores))
+	for _, members := range groups {
+		for link, p := range Percentiles(members) {
+			ret[link] = p
+		}
+	}
+	return ret
+}
+
+// SetPercentiles sets the percentile ranks of the scores overall, within
+// their primary language and within their source distribution.
+func SetPercentiles(packageScore map[string]*LinkScore) {
+	scores := make(map[string]float64, len(packageScore))
+	for link, s := range packageScore {
+		scores[link] = s.Score
+	}
+	overall := Percentiles(scores)
+	byLanguage := PercentilesBy(scores, func(link string) string { return packageScore[link].Language })
+	byDistro := PercentilesBy(scores, func(link string) string { return packageScore[link].Distro })
+	for link, s := range packageScore {
+		s.Percentile = overall[link]
+		s.LanguagePercentile = byLanguage[link]
+		s.DistroPercentile = byDistro[link]
+	}
+}
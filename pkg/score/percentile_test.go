@@ -0,0 +1,43 @@
+package score
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPercentiles(t *testing.T) {
+	got := Percentiles(map[string]float64{"a": 4, "b": 2, "c": 2, "d": 1})
+	want := map[string]float64{"a": 100, "b": 75, "c": 75, "d": 25}
+	for link, p := range want {
+		if got[link] != p {
+			t.Errorf("Percentiles()[%s] = %v, want %v", link, got[link], p)
+		}
+	}
+}
+
+func TestSetPercentiles(t *testing.T) {
+	packageScore := map[string]*LinkScore{
+		"a": {Score: 4, Language: "Rust", Distro: "debian"},
+		"b": {Score: 3, Language: "Go"},
+		"c": {Score: 2, Language: "Rust", Distro: "debian"},
+		"d": {Score: 1, Language: "Go", Distro: "debian"},
+	}
+	SetPercentiles(packageScore)
+
+	tests := []struct {
+		link                      string
+		overall, language, distro float64
+	}{
+		{"a", 100, 100, 100},
+		{"b", 75, 100, 0},
+		{"c", 50, 50, 200.0 / 3},
+		{"d", 25, 50, 100.0 / 3},
+	}
+	for _, tt := range tests {
+		s := packageScore[tt.link]
+		if math.Abs(s.Percentile-tt.overall) > 1e-9 || math.Abs(s.LanguagePercentile-tt.language) > 1e-9 || math.Abs(s.DistroPercentile-tt.distro) > 1e-9 {
+			t.Errorf("%s: percentiles = %v, %v, %v, want %v, %v, %v", tt.link,
+				s.Percentile, s.LanguagePercentile, s.DistroPercentile, tt.overall, tt.language, tt.distro)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package score
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultProfile is the profile of projects without a profile of their
+// ecosystems, it has the default weights.
+const DefaultProfile = "default"
+
+// Weights are the weights (αi) of the scores, by score and metric, e.g.
+// weights["gitMetadataScore"]["contributor_count"].
+type Weights map[string]map[string]float64
+
+// Profile overrides the default weights for the projects of some
+// ecosystems, e.g. stars matter less for projects only packaged by Debian.
+type Profile struct {
+	// Ecosystems are the language ecosystems, e.g. npm, or distributions,
+	// e.g. debian, of the profile, the name of the profile if empty
+	Ecosystems []string `mapstructure:"ecosystems"`
+	// Weights override the default weights, weights not set are kept
+	Weights Weights `mapstructure:"weights"`
+}
+
+// Profiles selects the weights of a project by its ecosystems.
+type Profiles struct {
+	weights     map[string]Weights
+	byEcosystem map[string]string
+}
+
+// lookup returns the key of m equal to name ignoring case, config files
+// read by viper have lower case keys.
+func lookup[V any](m map[string]V, name string) (string, bool) {
+	if _, ok := m[name]; ok {
+		return name, true
+	}
+	for k := range m {
+		if strings.EqualFold(k, name) {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+// merge returns a copy of base overridden by override, weights unknown to
+// base are errors as they are typos.
+func merge(base, override Weights) (Weights, error) {
+	ret := make(Weights, len(base))
+	for score, metrics := range base {
+		ret[score] = make(map[string]float64, len(metrics))
+		for metric, w := range metrics {
+			ret[score][metric] = w
+		}
+	}
+	for score, metrics := range override {
+		s, ok := lookup(ret, score)
+		if !ok {
+			return nil, fmt.Errorf("unknown score %s", score)
+		}
+		for metric, w := range metrics {
+			m, ok := lookup(ret[s], metric)
+			if !ok {
+				return nil, fmt.Errorf("unknown weight %s.%s", s, metric)
+			}
+			ret[s][m] = w
+		}
+	}
+	return ret, nil
+}
+
+// NewProfiles returns the profiles by name, with the default weights of
+// DefaultProfile. An ecosystem may only belong to one profile.
+func NewProfiles(profiles map[string]Profile) (*Profiles, error) {
+	return newProfiles(weights, profiles)
+}
+
+// newProfiles returns the profiles overriding base.
+func newProfiles(base Weights, profiles map[string]Profile) (*Profiles, error) {
+	ret := &Profiles{
+		weights:     map[string]Weights{DefaultProfile: base},
+		byEcosystem: make(map[string]string),
+	}
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		profile := profiles[name]
+		if name == DefaultProfile {
+			return nil, fmt.Errorf("profile %s is reserved for the default weights", DefaultProfile)
+		}
+		w, err := merge(base, profile.Weights)
+		if err != nil {
+			return nil, fmt.Errorf("profile %s: %w", name, err)
+		}
+		ret.weights[name] = w
+
+		ecosystems := profile.Ecosystems
+		if len(ecosystems) == 0 {
+			ecosystems = []string{name}
+		}
+		for _, e := range ecosystems {
+			e = strings.ToLower(e)
+			if other, ok := ret.byEcosystem[e]; ok {
+				return nil, fmt.Errorf("ecosystem %s is in profiles %s and %s", e, other, name)
+			}
+			ret.byEcosystem[e] = name
+		}
+	}
+	return ret, nil
+}
+
+// DefaultProfiles only has DefaultProfile.
+func DefaultProfiles() *Profiles {
+	p, _ := NewProfiles(nil)
+	return p
+}
+
+// Select returns the profile of a project and its weights. ecosystems are
+// the language ecosystems of the project, the largest first, and the
+// distributions packaging it. The first ecosystem with a profile wins, and
+// DefaultProfile is used if none has one.
+func (p *Profiles) Select(ecosystems ...string) (string, Weights) {
+	for _, e := range ecosystems {
+		if name, ok := p.byEcosystem[strings.ToLower(e)]; ok {
+			return name, p.weights[name]
+		}
+	}
+	return DefaultProfile, p.weights[DefaultProfile]
+}
+
+// SelectProject returns the profile of a project by its language
+// ecosystems in git metrics, then by its distribution. Either may be nil.
+func (p *Profiles) SelectProject(gitMetadata *GitMetadata, distMetadata *DistMetadata) (string, Weights) {
+	var ecosystems []string
+	if gitMetadata != nil {
+		ecosystems = append(ecosystems, gitMetadata.Ecosystems...)
+	}
+	if distMetadata != nil {
+		ecosystems = append(ecosystems, distMetadata.Type.String())
+	}
+	return p.Select(ecosystems...)
+}
+
+// Names returns the names of the profiles, sorted.
+func (p *Profiles) Names() []string {
+	ret := make([]string, 0, len(p.weights))
+	for name := range p.weights {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
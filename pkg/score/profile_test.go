@@ -0,0 +1,62 @@
+package score
+
+import (
+	"testing"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+)
+
+func TestProfiles(t *testing.T) {
+	profiles, err := NewProfiles(map[string]Profile{
+		"distro": {
+			Ecosystems: []string{"debian", "Ubuntu"},
+			// keys of config files read by viper are lower case
+			Weights: Weights{"gitmetadatascore": {"contributor_count": 0.5}},
+		},
+		"npm": {Weights: Weights{"distScore": {"distScore": 1}}},
+	})
+	if err != nil {
+		t.Fatalf("NewProfiles() error = %v", err)
+	}
+
+	tests := []struct {
+		ecosystems []string
+		want       string
+	}{
+		{nil, DefaultProfile},
+		{[]string{"pypi"}, DefaultProfile},
+		{[]string{"npm", "debian"}, "npm"},
+		{[]string{"pypi", "ubuntu"}, "distro"},
+	}
+	for _, tt := range tests {
+		if got, _ := profiles.Select(tt.ecosystems...); got != tt.want {
+			t.Errorf("Select(%v) = %s, want %s", tt.ecosystems, got, tt.want)
+		}
+	}
+
+	name, w := profiles.SelectProject(&GitMetadata{}, &DistMetadata{Type: repository.Debian})
+	if name != "distro" || w["gitMetadataScore"]["contributor_count"] != 0.5 || w["gitMetadataScore"]["org_count"] != weights["gitMetadataScore"]["org_count"] {
+		t.Errorf("SelectProject() = %s, %v", name, w["gitMetadataScore"])
+	}
+	// overrides do not change the default weights
+	if _, w := profiles.Select(); w["gitMetadataScore"]["contributor_count"] != 2 {
+		t.Errorf("default contributor_count = %v, want 2", w["gitMetadataScore"]["contributor_count"])
+	}
+}
+
+func TestProfilesInvalid(t *testing.T) {
+	tests := map[string]map[string]Profile{
+		"unknown score":  {"p": {Weights: Weights{"stars": {"count": 1}}}},
+		"unknown weight": {"p": {Weights: Weights{"distScore": {"stars": 1}}}},
+		"reserved name":  {DefaultProfile: {}},
+		"shared ecosystem": {
+			"a": {Ecosystems: []string{"debian"}},
+			"b": {Ecosystems: []string{"Debian"}},
+		},
+	}
+	for name, profiles := range tests {
+		if _, err := NewProfiles(profiles); err == nil {
+			t.Errorf("%s: NewProfiles() error = nil", name)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package score
+
+import (
+	"log"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/scope"
+)
+
+// ScopeLinks returns the links whose projects are in scope, by the
+// languages and the ecosystems of their repos and the distributions and the
+// language ecosystems packaging them.
+func ScopeLinks(s *scope.Scope, links []string, git map[string]*GitMetadata, dist map[string]*DistMetadata, langEco map[string]*LangEcoMetadata) []string {
+	if !s.Enabled() {
+		return links
+	}
+	ret := make([]string, 0, len(links))
+	for _, link := range links {
+		p := &scope.Project{GitLink: link}
+		if m := git[link]; m != nil {
+			if m.Language != "" {
+				p.Languages = []string{m.Language}
+			}
+			p.Ecosystems = append(p.Ecosystems, m.Ecosystems...)
+		}
+		if m := langEco[link]; m != nil {
+			p.Ecosystems = append(p.Ecosystems, m.Type.String())
+		}
+		if m := dist[link]; m != nil {
+			for _, t := range m.Distros {
+				p.Distros = append(p.Distros, t.String())
+			}
+		}
+		if s.Project(p) {
+			ret = append(ret, link)
+		}
+	}
+	log.Printf("Scoring %d of %d projects in scope", len(ret), len(links))
+	return ret
+}
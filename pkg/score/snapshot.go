@@ -0,0 +1,194 @@
+package score
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/freshness"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/samber/lo"
+)
+
+// now is the time projects are scored at, see Pin.
+var now = time.Now
+
+// Pin scores projects at t instead of the current time, the start of a run,
+// so the ages of the projects of a run can be replayed.
+func Pin(t time.Time) {
+	now = func() time.Time { return t }
+}
+
+// Config is the config of the scorer of a run, the scores of a run only
+// depend on it and on the inputs of the run.
+type Config struct {
+	Formula string `json:"formula"`
+	// Weights are the weights of every profile of the formula, Ecosystems
+	// the profiles of ecosystems
+	Weights    map[string]Weights `json:"weights"`
+	Ecosystems map[string]string  `json:"ecosystems"`
+	// Thresholds and PackageCounts are not configurable, they are kept as
+	// they change with the code
+	Thresholds    map[string]map[string]float64        `json:"thresholds"`
+	PackageCounts map[repository.LangEcosystemType]int `json:"package_counts"`
+	// Decay is the policy of the discounts, which are already applied to
+	// the inputs
+	Decay freshness.Policy `json:"decay"`
+}
+
+// NewConfig returns the config of the stored formula.
+func NewConfig(formula string, profiles *Profiles, decay freshness.Policy) *Config {
+	return &Config{
+		Formula:       formula,
+		Weights:       profiles.weights,
+		Ecosystems:    profiles.byEcosystem,
+		Thresholds:    thresholds,
+		PackageCounts: PackageCounts,
+		Decay:         decay,
+	}
+}
+
+// Hash returns the hash of the config, the keys of maps are marshaled in
+// order so equal configs have equal hashes.
+func (c *Config) Hash() (string, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Profiles returns the profiles of the config.
+func (c *Config) Profiles() *Profiles {
+	return &Profiles{weights: c.Weights, byEcosystem: c.Ecosystems}
+}
+
+// apply sets the thresholds and the package counts of the config.
+func (c *Config) apply() {
+	if c.Thresholds != nil {
+		thresholds = c.Thresholds
+	}
+	if c.PackageCounts != nil {
+		PackageCounts = c.PackageCounts
+	}
+}
+
+// Inputs are the metadata of a project scored by a run, with discounts.
+type Inputs struct {
+	Git     *GitMetadata     `json:"git,omitempty"`
+	Dist    *DistMetadata    `json:"dist,omitempty"`
+	LangEco *LangEcoMetadata `json:"lang_eco,omitempty"`
+}
+
+// Run is the config and the inputs of a scoring run.
+type Run struct {
+	ID        string
+	StartedAt time.Time
+	Config    *Config
+	// DistCounts are the number of packages of every distribution, see
+	// PackageList
+	DistCounts map[repository.DistType]int
+	Inputs     map[string]*Inputs
+}
+
+// NewRun returns the run of the inputs of links, the current PackageList
+// and the config. startedAt is truncated to the precision of the database.
+func NewRun(id string, startedAt time.Time, config *Config, links []string,
+	gitMap map[string]*GitMetadata, distMap map[string]*DistMetadata, langEcoMap map[string]*LangEcoMetadata) *Run {
+	r := &Run{
+		ID:         id,
+		StartedAt:  startedAt.UTC().Truncate(time.Microsecond),
+		Config:     config,
+		DistCounts: maps.Clone(PackageList),
+		Inputs:     make(map[string]*Inputs, len(links)),
+	}
+	for _, link := range links {
+		r.Inputs[link] = &Inputs{Git: gitMap[link], Dist: distMap[link], LangEco: langEcoMap[link]}
+	}
+	return r
+}
+
+// Score pins the time, the package counts and the thresholds of the run
+// and scores its projects, with percentiles.
+func (r *Run) Score() map[string]*LinkScore {
+	Pin(r.StartedAt)
+	r.Config.apply()
+	maps.Copy(PackageList, r.DistCounts)
+
+	profiles := r.Config.Profiles()
+	ret := make(map[string]*LinkScore, len(r.Inputs))
+	for link, in := range r.Inputs {
+		ret[link] = ScoreLink(profiles, in.Git, in.Dist, in.LangEco)
+	}
+	SetPercentiles(ret)
+	return ret
+}
+
+// Save stores the config and the inputs of the run.
+func (r *Run) Save(ac storage.AppDatabaseContext) error {
+	config, err := json.Marshal(r.Config)
+	if err != nil {
+		return err
+	}
+	hash, err := r.Config.Hash()
+	if err != nil {
+		return err
+	}
+	distCounts, err := json.Marshal(r.DistCounts)
+	if err != nil {
+		return err
+	}
+	inputs := make([]*repository.ScoreSnapshotInput, 0, len(r.Inputs))
+	for link, in := range r.Inputs {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("inputs of %s: %w", link, err)
+		}
+		inputs = append(inputs, &repository.ScoreSnapshotInput{
+			RunID:   &r.ID,
+			GitLink: lo.ToPtr(link),
+			Inputs:  lo.ToPtr(string(b)),
+		})
+	}
+	return repository.NewScoreSnapshotRepository(ac).Insert(&repository.ScoreSnapshot{
+		RunID:      &r.ID,
+		StartedAt:  &r.StartedAt,
+		ConfigHash: &hash,
+		Config:     lo.ToPtr(string(config)),
+		DistCounts: lo.ToPtr(string(distCounts)),
+	}, inputs)
+}
+
+// LoadRunSnapshot returns the run of id, nil if the run has no snapshot, e.g. it
+// is older than snapshots.
+func LoadRunSnapshot(ac storage.AppDatabaseContext, id string) (*Run, error) {
+	repo := repository.NewScoreSnapshotRepository(ac)
+	snapshot, err := repo.GetByRunID(id)
+	if err != nil || snapshot == nil {
+		return nil, err
+	}
+	r := &Run{ID: id, StartedAt: snapshot.StartedAt.UTC(), Inputs: make(map[string]*Inputs)}
+	if err := json.Unmarshal([]byte(*snapshot.Config), &r.Config); err != nil {
+		return nil, fmt.Errorf("config of run %s: %w", id, err)
+	}
+	if err := json.Unmarshal([]byte(*snapshot.DistCounts), &r.DistCounts); err != nil {
+		return nil, fmt.Errorf("dist counts of run %s: %w", id, err)
+	}
+	inputs, err := repo.QueryInputs(id)
+	if err != nil {
+		return nil, err
+	}
+	for in := range inputs {
+		var v Inputs
+		if err := json.Unmarshal([]byte(*in.Inputs), &v); err != nil {
+			return nil, fmt.Errorf("inputs of %s: %w", *in.GitLink, err)
+		}
+		r.Inputs[*in.GitLink] = &v
+	}
+	return r, nil
+}
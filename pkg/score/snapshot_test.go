@@ -0,0 +1,78 @@
+package score
+
+import (
+	"encoding/json"
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/freshness"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+)
+
+func TestRunReplay(t *testing.T) {
+	// replays set the globals of the run
+	defer func(list map[repository.DistType]int, th map[string]map[string]float64, counts map[repository.LangEcosystemType]int) {
+		now, PackageList, thresholds, PackageCounts = time.Now, list, th, counts
+	}(maps.Clone(PackageList), thresholds, PackageCounts)
+
+	discount := 0.8
+	gitMap := map[string]*GitMetadata{
+		"a": {CreatedSince: time.Date(2015, 3, 1, 0, 0, 0, 123000, time.UTC), UpdatedSince: time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC),
+			ContributorCount: 120, CommitFrequency: 3.7, Org_Count: 9, Ecosystems: []string{"npm"}, Discount: &discount},
+		"b": {CreatedSince: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), UpdatedSince: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			ContributorCount: 3, CommitFrequency: 0.1, Org_Count: 1},
+	}
+	distMap := map[string]*DistMetadata{
+		"a": {DepCount: 10, PageRank: 0.013, Type: repository.Debian, Distros: []repository.DistType{repository.Debian}},
+		"b": {DepCount: 1, PageRank: 0.0007, Type: repository.Arch, Distros: []repository.DistType{repository.Arch}},
+	}
+	langEcoMap := map[string]*LangEcoMetadata{
+		"a": {Type: repository.Npm, DepCount: 4000},
+		"b": {Type: repository.Pypi, DepCount: 2},
+	}
+	PackageList[repository.Debian], PackageList[repository.Arch] = 1000, 300
+
+	profiles := DefaultProfiles()
+	config := NewConfig(DefaultFormula, profiles, freshness.DefaultPolicy)
+	run := NewRun("20250130-020000", time.Date(2025, 1, 30, 2, 0, 0, 123456789, time.UTC), config, []string{"a", "b"}, gitMap, distMap, langEcoMap)
+	want := run.Score()
+
+	// the snapshot as stored and loaded
+	var replay Run
+	for _, v := range []struct{ in, out any }{
+		{run.Config, &replay.Config},
+		{run.DistCounts, &replay.DistCounts},
+		{run.Inputs, &replay.Inputs},
+	} {
+		b, err := json.Marshal(v.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(b, v.out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	replay.StartedAt = run.StartedAt
+
+	// the current package counts do not matter
+	PackageList[repository.Debian] = 5
+	got := replay.Score()
+	for link, w := range want {
+		if got[link].Score != w.Score || got[link].Percentile != w.Percentile || got[link].GitDiscount != w.GitDiscount {
+			t.Errorf("replayed score of %s = %+v, want %+v", link, got[link], w)
+		}
+	}
+
+	h1, err := config.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h2, _ := replay.Config.Hash(); h1 != h2 {
+		t.Errorf("hash of the replayed config = %s, want %s", h2, h1)
+	}
+	other := NewConfig(DefaultFormula, profiles, freshness.Policy{})
+	if h3, _ := other.Hash(); h3 == h1 {
+		t.Errorf("configs of other decay policies have the same hash")
+	}
+}
@@ -0,0 +1,32 @@
+package score
+
+import (
+	"log"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+)
+
+// SetTrends sets the dependents growth of the git metadata of every project
+// with a trend.
+func SetTrends(gitMap map[string]*GitMetadata, trends map[string]*repository.MetricTrend) {
+	for link, t := range trends {
+		if m, ok := gitMap[link]; ok && m != nil && t.DependentsGrowth != nil {
+			m.DependentsGrowth = *t.DependentsGrowth
+		}
+	}
+}
+
+// FetchTrends returns the latest trend of every project.
+func FetchTrends(ac storage.AppDatabaseContext) map[string]*repository.MetricTrend {
+	repo := repository.NewMetricTrendRepository(ac)
+	trendIter, err := repo.Query()
+	if err != nil {
+		log.Fatalf("Failed to fetch metric trends: %v", err)
+	}
+	ret := make(map[string]*repository.MetricTrend)
+	for t := range trendIter {
+		ret[*t.GitLink] = t
+	}
+	return ret
+}
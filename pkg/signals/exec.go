@@ -0,0 +1,75 @@
+package signals
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Exec is a collector run as an executable, so collectors need not be
+// written in Go or linked into the binary. The executable is run once per
+// project with the JSON of the Project on stdin, e.g.
+// {"link":"https://github.com/madler/zlib"}, and prints the JSON object of
+// the Values on stdout, nothing or {} if it does not support the project. A non-zero
+// exit status is a failure, with stderr as its message.
+type Exec struct {
+	name string
+	path string
+	args []string
+}
+
+var _ SignalCollector = (*Exec)(nil)
+
+// NewExec returns the collector name running the executable at path with
+// args.
+func NewExec(name, path string, args ...string) *Exec {
+	return &Exec{name: name, path: path, args: args}
+}
+
+// ParseExec parses the spec name=path of an executable collector, the path
+// may be followed by arguments separated by spaces.
+func ParseExec(spec string) (*Exec, error) {
+	name, command, ok := strings.Cut(spec, "=")
+	fields := strings.Fields(command)
+	if !ok || !validName.MatchString(name) || len(fields) == 0 {
+		return nil, fmt.Errorf("invalid executable collector %q, want name=path", spec)
+	}
+	return NewExec(name, fields[0], fields[1:]...), nil
+}
+
+// Name implements SignalCollector.
+func (e *Exec) Name() string {
+	return e.name
+}
+
+// Supports implements SignalCollector, the executable tells by its output.
+func (e *Exec) Supports(p Project) bool {
+	return true
+}
+
+// Collect implements SignalCollector.
+func (e *Exec) Collect(ctx context.Context, p Project) (Values, error) {
+	input, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, e.path, e.args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", e.name, err, strings.TrimSpace(stderr.String()))
+	}
+	if len(bytes.TrimSpace(stdout.Bytes())) == 0 {
+		return nil, nil
+	}
+	var ret Values
+	if err := json.Unmarshal(stdout.Bytes(), &ret); err != nil {
+		return nil, fmt.Errorf("%s: decoding output: %w", e.name, err)
+	}
+	return ret, ret.Validate()
+}
@@ -0,0 +1,258 @@
+// Package runner is the command running the registered signal collectors,
+// shared by cmd/signal-collector and the binaries of third parties, which
+// import their collectors for the side effect of registering them:
+//
+//	import (
+//		_ "example.com/acme/signals/fuzzing"
+//		"github.com/HUSTSecLab/criticality_score/pkg/signals/runner"
+//	)
+//
+//	func main() {
+//		runner.Main()
+//	}
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/config"
+	"github.com/HUSTSecLab/criticality_score/pkg/failure"
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/output"
+	"github.com/HUSTSecLab/criticality_score/pkg/priority"
+	"github.com/HUSTSecLab/criticality_score/pkg/sampling"
+	"github.com/HUSTSecLab/criticality_score/pkg/scope"
+	"github.com/HUSTSecLab/criticality_score/pkg/signals"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+	"github.com/HUSTSecLab/criticality_score/pkg/tagging"
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/samber/lo"
+	"github.com/spf13/pflag"
+)
+
+var (
+	flagCollectors = pflag.StringSlice("collectors", nil, "names of the collectors to run, default is all registered ones")
+	flagExec       = pflag.StringArray("exec", nil, "run an executable as a collector, name=path [args...], see signals.Exec; repeatable")
+	flagList       = pflag.Bool("list", false, "list the registered collectors and exit")
+	flagJobsCount  = pflag.IntP("jobs", "j", 8, "jobs count")
+	flagDryRun     = pflag.Bool("dry-run", false, "only print the values, do not update the database")
+)
+
+// getLinks returns the known git links.
+func getLinks(ac storage.AppDatabaseContext) ([]string, error) {
+	query, args, err := sqlutil.From(repository.GitRepositoryTableName).Select("git_link")
+	if err != nil {
+		return nil, err
+	}
+	rows, err := ac.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ret []string
+	for rows.Next() {
+		var link string
+		if err := rows.Scan(&link); err != nil {
+			return nil, err
+		}
+		ret = append(ret, link)
+	}
+	return ret, rows.Err()
+}
+
+// task is a project to collect by a collector.
+type task struct {
+	collector signals.SignalCollector
+	link      string
+}
+
+// plan returns the tasks of the links supported by every collector,
+// skipping the links collected by it since fresh if not nil.
+func plan(collectors []signals.SignalCollector, links []string, fresh func(collector string) (map[string]bool, error)) ([]task, error) {
+	var ret []task
+	for _, c := range collectors {
+		var skip map[string]bool
+		if fresh != nil {
+			var err error
+			if skip, err = fresh(c.Name()); err != nil {
+				return nil, err
+			}
+		}
+		n := 0
+		for _, link := range links {
+			if !skip[link] && c.Supports(signals.Project{GitLink: link}) {
+				ret = append(ret, task{collector: c, link: link})
+				n++
+			}
+		}
+		logger.Infof("%s: %d links to collect, %d collected recently", c.Name(), n, len(skip))
+	}
+	return ret, nil
+}
+
+// rows returns the rows of the values of link collected at.
+func rows(collector, link string, values signals.Values, at time.Time) []*repository.SignalValue {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ret := make([]*repository.SignalValue, len(names))
+	for i, name := range names {
+		ret[i] = &repository.SignalValue{
+			GitLink:     lo.ToPtr(link),
+			Collector:   lo.ToPtr(collector),
+			Name:        lo.ToPtr(name),
+			Value:       lo.ToPtr(values[name]),
+			CollectedAt: lo.ToPtr(at),
+		}
+	}
+	return ret
+}
+
+// Main parses the flags and runs the registered collectors, and those of
+// --exec, on the known git links, or on the links of the input file
+// without a database.
+func Main() {
+	pflag.Usage = func() {
+		fmt.Println("This tool runs the registered signal collectors on every project they support.")
+		fmt.Printf("Usage: %s [options...]\n", os.Args[0])
+		pflag.PrintDefaults()
+	}
+
+	config.RegistCommonFlags(pflag.CommandLine)
+	config.RegistSampleFlags(pflag.CommandLine)
+	config.RegistTagFlags(pflag.CommandLine)
+	config.RegistScopeFlags(pflag.CommandLine)
+	config.RegistFreshnessFlags(pflag.CommandLine)
+	config.RegistPriorityFlags(pflag.CommandLine)
+	config.RegistOutputFlags(pflag.CommandLine)
+	config.RegistFailureFlags(pflag.CommandLine)
+	config.ParseFlags(pflag.CommandLine)
+	defer failure.Default().Exit()
+
+	for _, spec := range *flagExec {
+		e, err := signals.ParseExec(spec)
+		if err != nil {
+			failure.Default().Fatal(err)
+		}
+		// Register panics on names registered twice, e.g. of a bundled
+		// collector or of another --exec
+		if _, err := signals.Get(e.Name()); err == nil {
+			failure.Default().Fatal(fmt.Errorf("--exec %q: collector %s is already registered", spec, e.Name()))
+		}
+		signals.Register(e)
+	}
+	if *flagList {
+		for _, name := range signals.Names() {
+			fmt.Println(name)
+		}
+		return
+	}
+	collectors, err := signals.Get(*flagCollectors...)
+	if err != nil {
+		failure.Default().Fatal(err)
+	}
+	if len(collectors) == 0 {
+		failure.Default().Fatal(fmt.Errorf("no signal collector registered"))
+	}
+
+	ctx := context.Background()
+	ac := storage.GetDefaultAppDatabaseContext()
+	valueRepo := repository.NewSignalValueRepository(ac)
+
+	var links []string
+	if config.UsesDatabase() {
+		links, err = getLinks(ac)
+	} else {
+		links, err = output.ReadLinks(config.GetInputPath(), config.GetInputURLColumn())
+	}
+	if err != nil {
+		failure.Default().Fatal(fmt.Errorf("failed to fetch git links: %w", err))
+	}
+	links = sampling.Slice(scope.Slice(tagging.Slice(links)))
+	if config.UsesDatabase() {
+		links, err = priority.Schedule(ac, repository.SignalValues, links)
+		if err != nil {
+			failure.Default().Fatal(err)
+		}
+	}
+	logger.Infof("%d links in total", len(links))
+
+	var fresh func(string) (map[string]bool, error)
+	if window := config.GetFreshnessWindow(); window > 0 && config.UsesDatabase() {
+		fresh = func(collector string) (map[string]bool, error) {
+			collected, err := valueRepo.QueryCollectedSince(collector, time.Now().Add(-window))
+			return lo.SliceToMap(collected, func(link string) (string, bool) { return link, true }), err
+		}
+	}
+	tasks, err := plan(collectors, links, fresh)
+	if err != nil {
+		failure.Default().Fatal(err)
+	}
+
+	// the values of a project share their collection time, so values of
+	// previous collections are dropped even if a batch splits them, and
+	// their links are marked collected in the same transaction
+	sink, err := output.Open(config.GetOutputConfig(), output.NewQueue(func(_ string, batch []any) error {
+		values := make([]*repository.SignalValue, len(batch))
+		links := make([]string, len(batch))
+		for i, row := range batch {
+			values[i] = row.(*repository.SignalValue)
+			links[i] = *values[i].GitLink
+		}
+		return storage.InBatch(ac, func(ac storage.AppDatabaseContext) error {
+			if err := repository.NewSignalValueRepository(ac).Replace(values); err != nil {
+				return err
+			}
+			return repository.NewCollectionTimestampRepository(ac).MarkCollected(repository.SignalValues, links, time.Now())
+		})
+	}, config.GetQueueConfig()))
+	if err != nil {
+		failure.Default().Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(tasks))
+	gopool.SetCap(int32(*flagJobsCount))
+	for _, t := range tasks {
+		gopool.Go(func() {
+			defer wg.Done()
+			name := t.collector.Name()
+			values, err := t.collector.Collect(ctx, signals.Project{GitLink: t.link})
+			if err == nil {
+				err = values.Validate()
+			}
+			if err != nil {
+				logger.Errorf("Collecting %s of %s Failed: %v", name, t.link, err)
+				failure.Default().Fail(t.link, err)
+				return
+			}
+
+			for _, row := range rows(name, t.link, values, time.Now()) {
+				if *flagDryRun {
+					fmt.Printf("%s\t%s\t%s=%v\n", t.link, name, *row.Name, *row.Value)
+					continue
+				}
+				if err := sink.Write(repository.SignalValueTableName, row); err != nil {
+					logger.Errorf("Update %s of %s Failed: %v", name, t.link, err)
+					failure.Default().Fail(t.link, err)
+					return
+				}
+			}
+			failure.Default().Success()
+		})
+	}
+	wg.Wait()
+	if err := sink.Close(); err != nil {
+		failure.Default().Fatal(err)
+	}
+}
@@ -0,0 +1,51 @@
+package runner
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/signals"
+)
+
+type githubCollector struct{ name string }
+
+func (c *githubCollector) Name() string { return c.name }
+func (c *githubCollector) Supports(p signals.Project) bool {
+	return strings.HasPrefix(p.GitLink, "https://github.com/")
+}
+func (c *githubCollector) Collect(context.Context, signals.Project) (signals.Values, error) {
+	return nil, nil
+}
+
+func TestPlan(t *testing.T) {
+	a, b := &githubCollector{"a"}, &githubCollector{"b"}
+	links := []string{"https://github.com/madler/zlib", "https://gitee.com/openeuler/kernel", "https://github.com/curl/curl"}
+	fresh := func(collector string) (map[string]bool, error) {
+		if collector == "b" {
+			return map[string]bool{"https://github.com/curl/curl": true}, nil
+		}
+		return nil, nil
+	}
+	tasks, err := plan([]signals.SignalCollector{a, b}, links, fresh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, task := range tasks {
+		got = append(got, task.collector.Name()+" "+task.link)
+	}
+	want := []string{"a https://github.com/madler/zlib", "a https://github.com/curl/curl", "b https://github.com/madler/zlib"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("plan() = %v, want %v", got, want)
+	}
+}
+
+func TestRows(t *testing.T) {
+	at := time.Date(2025, 2, 4, 0, 0, 0, 0, time.UTC)
+	got := rows("scorecard", "https://github.com/madler/zlib", signals.Values{"score": 5.9, "checks.maintained": 10}, at)
+	if len(got) != 2 || *got[0].Name != "checks.maintained" || *got[1].Value != 5.9 || !got[1].CollectedAt.Equal(at) {
+		t.Errorf("rows() = %+v", got)
+	}
+}
@@ -0,0 +1,120 @@
+// Package scorecard collects the OpenSSF Scorecard results of repos from
+// the API of its weekly scans, the aggregate score and the score of every
+// check, e.g. checks.maintained. It registers itself as the scorecard
+// collector of package signals.
+package scorecard
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/signals"
+)
+
+// Name is the name of the collector.
+const Name = "scorecard"
+
+// DefaultBaseURL is the API of the scans of the OpenSSF.
+const DefaultBaseURL = "https://api.securityscorecards.dev"
+
+// hosts are the forges scanned by the OpenSSF.
+var hosts = map[string]bool{"github.com": true, "gitlab.com": true}
+
+func init() {
+	signals.Register(New(nil, DefaultBaseURL))
+}
+
+// Collector collects the Scorecard results of github and gitlab repos.
+type Collector struct {
+	client  *http.Client
+	baseURL string
+}
+
+var _ signals.SignalCollector = (*Collector)(nil)
+
+// New returns a collector requesting baseURL by client, http.DefaultClient
+// if nil.
+func New(client *http.Client, baseURL string) *Collector {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Collector{client: client, baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// result is the response of the API, a score is -1 if the check was
+// inconclusive.
+type result struct {
+	Score  float64 `json:"score"`
+	Checks []struct {
+		Name  string  `json:"name"`
+		Score float64 `json:"score"`
+	} `json:"checks"`
+}
+
+// project returns the project of a link in the API, e.g.
+// github.com/madler/zlib.
+func project(link string) (string, bool) {
+	u, err := url.Parse(link)
+	if err != nil || !hosts[strings.ToLower(u.Host)] {
+		return "", false
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", false
+	}
+	return strings.ToLower(u.Host) + "/" + parts[0] + "/" + strings.TrimSuffix(parts[1], ".git"), true
+}
+
+// Name implements signals.SignalCollector.
+func (c *Collector) Name() string {
+	return Name
+}
+
+// Supports implements signals.SignalCollector.
+func (c *Collector) Supports(p signals.Project) bool {
+	_, ok := project(p.GitLink)
+	return ok
+}
+
+// Collect implements signals.SignalCollector. Repos never scanned have no
+// values.
+func (c *Collector) Collect(ctx context.Context, p signals.Project) (signals.Values, error) {
+	name, ok := project(p.GitLink)
+	if !ok {
+		return nil, fmt.Errorf("unsupported link %s", p.GitLink)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/projects/"+name, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("scorecard of %s: %s", name, resp.Status)
+	}
+
+	var r result
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, fmt.Errorf("decoding scorecard of %s: %w", name, err)
+	}
+	ret := signals.Values{}
+	if r.Score >= 0 {
+		ret["score"] = r.Score
+	}
+	for _, check := range r.Checks {
+		if check.Score >= 0 {
+			ret["checks."+strings.ToLower(check.Name)] = check.Score
+		}
+	}
+	return ret, ret.Validate()
+}
@@ -0,0 +1,64 @@
+package scorecard
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/signals"
+)
+
+func TestSupports(t *testing.T) {
+	c := New(nil, DefaultBaseURL)
+	tests := []struct {
+		link string
+		want bool
+	}{
+		{"https://github.com/madler/zlib", true},
+		{"https://gitlab.com/gitlab-org/gitlab.git", true},
+		{"https://gitlab.com/gnutls", false},
+		{"https://gitee.com/openeuler/kernel", false},
+	}
+	for _, tt := range tests {
+		if got := c.Supports(signals.Project{GitLink: tt.link}); got != tt.want {
+			t.Errorf("Supports(%s) = %v, want %v", tt.link, got, tt.want)
+		}
+	}
+	if !slices.Contains(signals.Names(), Name) {
+		t.Errorf("%s is not registered", Name)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/projects/github.com/madler/zlib":
+			w.Write([]byte(`{"score": 5.9, "checks": [
+				{"name": "Maintained", "score": 10},
+				{"name": "Code-Review", "score": 6},
+				{"name": "Packaging", "score": -1}]}`))
+		case "/projects/github.com/broken/repo":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	c := New(srv.Client(), srv.URL+"/")
+	ctx := context.Background()
+
+	got, err := c.Collect(ctx, signals.Project{GitLink: "https://github.com/madler/zlib"})
+	want := signals.Values{"score": 5.9, "checks.maintained": 10, "checks.code-review": 6}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Collect() = %v, %v, want %v", got, err, want)
+	}
+	if got, err := c.Collect(ctx, signals.Project{GitLink: "https://github.com/never/scanned"}); err != nil || len(got) != 0 {
+		t.Errorf("Collect() of a repo never scanned = %v, %v", got, err)
+	}
+	if _, err := c.Collect(ctx, signals.Project{GitLink: "https://github.com/broken/repo"}); err == nil {
+		t.Error("Collect() of a server error is not an error")
+	}
+}
@@ -0,0 +1,122 @@
+// Package signals lets new signals be collected without modifying the
+// core. A SignalCollector computes named values of projects, e.g. the
+// OpenSSF Scorecard score; it is registered by name, usually in the init
+// function of its package, like the drivers of database/sql:
+//
+//	func init() {
+//		signals.Register(&Collector{})
+//	}
+//
+// A binary importing the package for its side effects, e.g. the
+// signal-collector command or one built by a third party around
+// runner.Main, runs the collector on every project it supports and stores
+// the values in signal_values. Collectors written in other languages are
+// separate executables run by Exec.
+package signals
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/errors"
+)
+
+// ErrUnknown is returned for names which are not registered.
+var ErrUnknown = errors.New("unknown signal collector")
+
+// ErrInvalidValues wraps the errors of values which cannot be stored.
+var ErrInvalidValues = errors.New("invalid signal values")
+
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// Project is a project a signal is collected of.
+type Project struct {
+	// GitLink is the normalized git link, e.g. https://github.com/madler/zlib
+	GitLink string `json:"link"`
+}
+
+// Values are the values of the signals of a project by their names, e.g.
+// score or checks.maintained. A collector may return no values for a
+// project it supports, e.g. if its source knows nothing of it.
+type Values map[string]float64
+
+// Validate returns an error wrapping ErrInvalidValues if a name is not
+// lower case letters, digits and ._- or a value is not finite.
+func (v Values) Validate() error {
+	for name, value := range v {
+		if !validName.MatchString(name) {
+			return fmt.Errorf("%w: name %q", ErrInvalidValues, name)
+		}
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return fmt.Errorf("%w: %s is %v", ErrInvalidValues, name, value)
+		}
+	}
+	return nil
+}
+
+// SignalCollector collects signals of projects. Collect is called
+// concurrently for different projects.
+type SignalCollector interface {
+	// Name is the name of the collector, lower case letters, digits and
+	// ._-, the values are stored under it
+	Name() string
+	// Supports reports whether the collector can collect the project, e.g.
+	// by the forge of its link, without any request
+	Supports(p Project) bool
+	Collect(ctx context.Context, p Project) (Values, error)
+}
+
+var (
+	mu         sync.RWMutex
+	collectors = make(map[string]SignalCollector)
+)
+
+// Register registers a collector by its name. It panics if the name is
+// invalid or already registered, which are errors of the program.
+func Register(c SignalCollector) {
+	mu.Lock()
+	defer mu.Unlock()
+	name := c.Name()
+	if !validName.MatchString(name) {
+		panic(fmt.Sprintf("signals: invalid collector name %q", name))
+	}
+	if _, ok := collectors[name]; ok {
+		panic("signals: Register called twice for collector " + name)
+	}
+	collectors[name] = c
+}
+
+// Names returns the names of the registered collectors, sorted.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	ret := make([]string, 0, len(collectors))
+	for name := range collectors {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// Get returns the registered collectors of names, all of them if names is
+// empty. The error wraps ErrUnknown.
+func Get(names ...string) ([]SignalCollector, error) {
+	if len(names) == 0 {
+		names = Names()
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	ret := make([]SignalCollector, 0, len(names))
+	for _, name := range names {
+		c, ok := collectors[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknown, name)
+		}
+		ret = append(ret, c)
+	}
+	return ret, nil
+}
@@ -0,0 +1,90 @@
+package signals
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type constCollector struct {
+	name   string
+	values Values
+}
+
+func (c *constCollector) Name() string { return c.name }
+func (c *constCollector) Supports(p Project) bool {
+	return strings.HasPrefix(p.GitLink, "https://github.com/")
+}
+func (c *constCollector) Collect(context.Context, Project) (Values, error) { return c.values, nil }
+
+func TestRegister(t *testing.T) {
+	c := &constCollector{name: "test-const", values: Values{"a": 1}}
+	Register(c)
+	if !slices.Contains(Names(), "test-const") {
+		t.Errorf("Names() = %v", Names())
+	}
+	got, err := Get("test-const")
+	if err != nil || len(got) != 1 || got[0] != c {
+		t.Errorf("Get() = %v, %v", got, err)
+	}
+	if _, err := Get("test-const", "test-missing"); !errors.Is(err, ErrUnknown) {
+		t.Errorf("Get() of an unknown collector error = %v, want ErrUnknown", err)
+	}
+
+	for _, name := range []string{"test-const", "Test", ""} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Register(%q) does not panic", name)
+				}
+			}()
+			Register(&constCollector{name: name})
+		}()
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := (Values{"score": 7.5, "checks.code-review": 10}).Validate(); err != nil {
+		t.Error(err)
+	}
+	for _, v := range []Values{{"Score": 1}, {"": 1}, {"score": math.NaN()}, {"score": math.Inf(1)}} {
+		if err := v.Validate(); !errors.Is(err, ErrInvalidValues) {
+			t.Errorf("Validate(%v) error = %v, want ErrInvalidValues", v, err)
+		}
+	}
+}
+
+func TestExec(t *testing.T) {
+	e := NewExec("zlib-only", "sh", "-c", `read p; case $p in *zlib*) echo '{"stars": 2}';; esac`)
+	ctx := context.Background()
+	got, err := e.Collect(ctx, Project{GitLink: "https://github.com/madler/zlib"})
+	if err != nil || !reflect.DeepEqual(got, Values{"stars": 2}) {
+		t.Errorf("Collect() = %v, %v", got, err)
+	}
+	if got, err := e.Collect(ctx, Project{GitLink: "https://github.com/curl/curl"}); err != nil || got != nil {
+		t.Errorf("Collect() of an unsupported project = %v, %v", got, err)
+	}
+
+	failing := NewExec("failing", "sh", "-c", "echo boom >&2; exit 1")
+	if _, err := failing.Collect(ctx, Project{}); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("Collect() error = %v, want the stderr", err)
+	}
+	invalid := NewExec("invalid", "sh", "-c", `echo '{"Stars": 1}'`)
+	if _, err := invalid.Collect(ctx, Project{}); !errors.Is(err, ErrInvalidValues) {
+		t.Errorf("Collect() error = %v, want ErrInvalidValues", err)
+	}
+
+	if e, err := ParseExec("foo=/opt/foo --fast"); err != nil || e.name != "foo" || e.path != "/opt/foo" ||
+		!reflect.DeepEqual(e.args, []string{"--fast"}) {
+		t.Errorf("ParseExec() = %+v, %v", e, err)
+	}
+	for _, spec := range []string{"foo=", "/opt/foo", "Foo=/opt/foo"} {
+		if _, err := ParseExec(spec); err == nil {
+			t.Errorf("ParseExec(%q) is not an error", spec)
+		}
+	}
+}
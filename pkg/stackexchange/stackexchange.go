@@ -0,0 +1,169 @@
+// Package stackexchange counts the questions of tags on Stack Overflow, or
+// another site of the Stack Exchange network, as a proxy of the user base
+// of projects.
+//
+// The API allows 300 requests per day without a key and 10000 with one.
+// The backoff asked by responses is honored, throttled and server errors
+// are retried. An exhausted quota is a throttle_violation too, which is
+// only lifted the next day.
+package stackexchange
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/errors"
+	"github.com/HUSTSecLab/criticality_score/pkg/internal/jsonhttp"
+)
+
+const (
+	DefaultBaseURL    = "https://api.stackexchange.com/2.3"
+	DefaultSite       = "stackoverflow"
+	DefaultMaxRetries = 3
+	// MaxTags is the largest number of tags of a count, the limit of the
+	// search of the API
+	MaxTags = 5
+
+	// initialBackoff is the delay before the first retry, doubled by every
+	// retry
+	initialBackoff = 10 * time.Second
+)
+
+var (
+	// ErrThrottled is wrapped by errors of requests still throttled after
+	// all retries.
+	ErrThrottled = errors.Mark(errors.New("throttled"), errors.RateLimited)
+	// ErrServer is wrapped by 5xx errors still failing after all retries.
+	ErrServer = jsonhttp.ErrServer
+	// ErrRequest is wrapped by errors of other status codes, network
+	// errors and malformed responses, e.g. an unknown site.
+	ErrRequest = jsonhttp.ErrRequest
+)
+
+type Config struct {
+	// BaseURL is the url of the API with the version, DefaultBaseURL if
+	// empty
+	BaseURL string
+	// Key is the key of a registered app, which raises the daily quota
+	Key string
+	// Site is the site of the questions, DefaultSite if empty
+	Site string
+	// MaxRetries is the number of retries of throttled, server and network
+	// errors
+	MaxRetries int
+}
+
+// Client is a client of the API.
+type Client struct {
+	http    *jsonhttp.Client
+	baseURL string
+	key     string
+	site    string
+}
+
+// New returns a client sending requests by client, http.DefaultClient if
+// nil.
+func New(client *http.Client, config *Config) *Client {
+	c := &Client{
+		baseURL: strings.TrimSuffix(config.BaseURL, "/"),
+		key:     config.Key,
+		site:    config.Site,
+	}
+	c.http = jsonhttp.New(client, jsonhttp.Config{
+		MaxRetries:     config.MaxRetries,
+		InitialBackoff: initialBackoff,
+		Secrets:        []string{"key"},
+		Check:          c.check,
+	})
+	if c.baseURL == "" {
+		c.baseURL = DefaultBaseURL
+	}
+	if c.site == "" {
+		c.site = DefaultSite
+	}
+	return c
+}
+
+// wrapper is the common wrapper of the responses of the API, the fields
+// not selected by the filter are left out, errors are always answered in
+// full.
+type wrapper struct {
+	Total        int    `json:"total"`
+	Backoff      int    `json:"backoff"`
+	ErrorID      int    `json:"error_id"`
+	ErrorName    string `json:"error_name"`
+	ErrorMessage string `json:"error_message"`
+}
+
+// filter is the built-in filter of the API returning only the total of a
+// query, not the questions.
+const filter = "total"
+
+// QuestionCount returns the number of questions created in [since, until)
+// with any of tags, a question with several of them counts once. There are
+// MaxTags tags at most.
+func (c *Client) QuestionCount(ctx context.Context, tags []string, since, until time.Time) (int, error) {
+	if len(tags) == 0 || len(tags) > MaxTags {
+		return 0, fmt.Errorf("%w: %d tags, want 1 to %d", ErrRequest, len(tags), MaxTags)
+	}
+	for _, tag := range tags {
+		if tag == "" || strings.ContainsAny(tag, "; ") {
+			return 0, fmt.Errorf("%w: invalid tag %q", ErrRequest, tag)
+		}
+	}
+	query := url.Values{
+		"site":     {c.site},
+		"tagged":   {strings.Join(tags, ";")},
+		"fromdate": {strconv.FormatInt(since.Unix(), 10)},
+		// todate is inclusive
+		"todate": {strconv.FormatInt(until.Unix()-1, 10)},
+		"filter": {filter},
+	}
+	if c.key != "" {
+		query.Set("key", c.key)
+	}
+
+	var ret wrapper
+	if err := c.http.GetJSON(ctx, c.baseURL+"/search/advanced?"+query.Encode(), &ret); err != nil {
+		return 0, err
+	}
+	return ret.Total, nil
+}
+
+// check returns the error of a response, whose body is a wrapper even for
+// errors, and holds the next request for the backoff it asks.
+func (c *Client) check(u string, resp *http.Response, body []byte) error {
+	var ret wrapper
+	if err := json.Unmarshal(body, &ret); err != nil {
+		if resp.StatusCode >= 500 {
+			return jsonhttp.NewStatusError(u, resp, ErrServer)
+		}
+		return fmt.Errorf("%w: decoding %s: %w", ErrRequest, u, err)
+	}
+	if ret.Backoff > 0 {
+		c.http.Hold(time.Duration(ret.Backoff) * time.Second)
+	}
+
+	// errors are answered with an error_id, which is mostly the status
+	// code, e.g. 502 throttle_violation
+	var err error
+	switch code := max(ret.ErrorID, resp.StatusCode); {
+	case ret.ErrorID == 0 && resp.StatusCode == http.StatusOK:
+		return nil
+	case ret.ErrorName == "throttle_violation" || code == http.StatusTooManyRequests:
+		err = ErrThrottled
+	case code >= 500:
+		err = ErrServer
+	default:
+		err = ErrRequest
+	}
+	se := jsonhttp.NewStatusError(u, resp, err)
+	se.Message = strings.TrimSpace(ret.ErrorName + " " + ret.ErrorMessage)
+	return se
+}
@@ -0,0 +1,98 @@
+package stackexchange
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQuestionCount(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := since.AddDate(1, 0, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/search/advanced" || q.Get("site") != "stackoverflow" || q.Get("filter") != "total" ||
+			q.Get("key") != "secret" || q.Get("fromdate") != "1704067200" || q.Get("todate") != "1735689599" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		switch q.Get("tagged") {
+		case "curl;libcurl":
+			w.Write([]byte(`{"total": 1234, "backoff": 5}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error_id": 400, "error_name": "bad_parameter", "error_message": "site is required"}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	c := New(server.Client(), &Config{BaseURL: server.URL, Key: "secret"})
+	var sleeps []time.Duration
+	c.http.Sleep = func(ctx context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		return nil
+	}
+
+	n, err := c.QuestionCount(context.Background(), []string{"curl", "libcurl"}, since, until)
+	if err != nil || n != 1234 {
+		t.Fatalf("QuestionCount() = %d, %v, want 1234", n, err)
+	}
+
+	_, err = c.QuestionCount(context.Background(), []string{"unknown"}, since, until)
+	if !errors.Is(err, ErrRequest) || !strings.Contains(err.Error(), "site is required") {
+		t.Errorf("QuestionCount() error = %v, want ErrRequest", err)
+	}
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("error %q leaks the key", err)
+	}
+
+	// the second request waits for the backoff of the first
+	if len(sleeps) != 1 || sleeps[0] <= 0 || sleeps[0] > 5*time.Second {
+		t.Errorf("sleeps = %v, want one backoff", sleeps)
+	}
+}
+
+func TestQuestionCountInvalidTags(t *testing.T) {
+	// invalid tags are rejected without a request
+	c := New(nil, &Config{})
+	for _, tags := range [][]string{nil, {"a", "b", "c", "d", "e", "f"}, {"a;b"}, {""}} {
+		if _, err := c.QuestionCount(context.Background(), tags, time.Now(), time.Now()); !errors.Is(err, ErrRequest) {
+			t.Errorf("QuestionCount(%q) error = %v, want ErrRequest", tags, err)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		want    error
+		message string
+	}{
+		{"ok", 200, `{"total": 7}`, nil, ""},
+		{"throttled", 400, `{"error_id": 502, "error_name": "throttle_violation", "error_message": "too many requests from this IP"}`,
+			ErrThrottled, "throttle_violation too many requests from this IP"},
+		{"too many requests", 429, `{}`, ErrThrottled, ""},
+		{"bad parameter", 400, `{"error_id": 400, "error_name": "bad_parameter", "error_message": "site is required"}`,
+			ErrRequest, "bad_parameter site is required"},
+		{"server error", 503, `<html>unavailable</html>`, ErrServer, ""},
+		{"malformed", 200, `<html>`, ErrRequest, ""},
+	}
+	c := New(nil, &Config{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			rec.WriteHeader(tt.status)
+			err := c.check("/search/advanced", rec.Result(), []byte(tt.body))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("check() = %v, want %v", err, tt.want)
+			}
+			if tt.message != "" && !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("check() = %v, want the message %q", err, tt.message)
+			}
+		})
+	}
+}
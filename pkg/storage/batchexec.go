@@ -26,6 +26,28 @@ type batchExecContext struct {
 	sentences      string
 	args           []interface{}
 	sentencesCount int
+	statements     []batchStatement
+}
+
+// batchStatement is a sentence with its own args, statements are executed
+// one by one in a transaction because postgres does not accept multiple
+// sentences in a single prepared statement.
+type batchStatement struct {
+	sentence string
+	args     []interface{}
+}
+
+// batchResult sums up the results of all statements of a batch.
+type batchResult struct {
+	rowsAffected int64
+}
+
+func (r batchResult) LastInsertId() (int64, error) {
+	return 0, fmt.Errorf("LastInsertId is not supported by batch execution")
+}
+
+func (r batchResult) RowsAffected() (int64, error) {
+	return r.rowsAffected, nil
 }
 
 func (ctx *batchExecContext) GetSentences() string {
@@ -40,19 +62,45 @@ func (ctx *batchExecContext) Clear() {
 	ctx.sentences = ""
 	ctx.args = make([]interface{}, 0)
 	ctx.sentencesCount = 0
+	ctx.statements = nil
 }
 
+// Commit executes all appended sentences in a single transaction, and
+// clears the context whether it succeeds or not. The connection pool is
+// shared, so it is never closed here.
 func (ctx *batchExecContext) Commit() (sql.Result, error) {
+	statements := ctx.statements
+	ctx.Clear()
+	if len(statements) == 0 {
+		return batchResult{}, nil
+	}
+
 	conn, err := ctx.appDb.GetDatabaseConnection()
 	if err != nil {
 		return nil, err
 	}
-	defer conn.Close()
 
-	ret, err := conn.Exec(ctx.sentences, ctx.args...)
-	ctx.Clear()
+	tx, err := conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var ret batchResult
+	for _, st := range statements {
+		r, err := tx.Exec(st.sentence, st.args...)
+		if err != nil {
+			return nil, err
+		}
+		if n, err := r.RowsAffected(); err == nil {
+			ret.rowsAffected += n
+		}
+	}
 
-	return ret, err
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return ret, nil
 }
 
 // AppendExec appends a sentence to the batch execution context.
@@ -61,7 +109,7 @@ func (ctx *batchExecContext) AppendExec(sentence string, args ...interface{}) er
 	s := sentence
 	start := len(ctx.args)
 
-	// check if the min $n is greater than or equal to 0
+	// check if the min $n is greater than or equal to 1
 	// and max $n is less than or equal to len(args)
 	// if not, return an error
 
@@ -79,7 +127,7 @@ func (ctx *batchExecContext) AppendExec(sentence string, args ...interface{}) er
 		if err != nil {
 			return fmt.Errorf("invalid placeholder $%s in sentence %s", nStr, sentence)
 		}
-		if n < 0 || n >= len(args) {
+		if n < 1 || n > len(args) {
 			return fmt.Errorf("invalid placeholder $%d in sentence %s", n, sentence)
 		}
 		toReplace[i] = start + n
@@ -88,12 +136,13 @@ func (ctx *batchExecContext) AppendExec(sentence string, args ...interface{}) er
 	// replace all placeholders with $n in the sentence
 	for i := len(matches) - 1; i >= 0; i-- {
 		n := toReplace[i]
-		s = s[:matches[i][0]] + "$" + strconv.Itoa(n+1) + s[matches[i][1]:]
+		s = s[:matches[i][0]] + "$" + strconv.Itoa(n) + s[matches[i][1]:]
 	}
 
-	ctx.sentences += sentence + ";"
+	ctx.sentences += s + ";"
 	ctx.args = append(ctx.args, args...)
 	ctx.sentencesCount++
+	ctx.statements = append(ctx.statements, batchStatement{sentence: sentence, args: args})
 
 	if ctx.config.AutoCommit && ctx.sentencesCount >= ctx.config.AutoCommitSize {
 		_, err := ctx.Commit()
@@ -104,3 +153,34 @@ func (ctx *batchExecContext) AppendExec(sentence string, args ...interface{}) er
 
 	return nil
 }
+
+// batchedContext appends the statements executed to a batch instead.
+type batchedContext struct {
+	AppDatabaseContext
+	batch BatchExecContext
+}
+
+func (c *batchedContext) Exec(query string, args ...interface{}) (sql.Result, error) {
+	if err := c.batch.AppendExec(query, args...); err != nil {
+		return nil, err
+	}
+	return batchResult{}, nil
+}
+
+// InBatch runs fn with a context whose Exec appends the statements to a
+// batch of ac, then commits them in a single transaction, e.g. to write
+// rows and mark them collected at once. Queries are not batched, they do
+// not see the statements executed before. Nothing is committed if fn
+// fails. In the context of an InBatch, the statements join its batch.
+func InBatch(ac AppDatabaseContext, fn func(ac AppDatabaseContext) error) error {
+	if _, ok := ac.(*batchedContext); ok {
+		return fn(ac)
+	}
+	batch := ac.NewBatchExecContext(&BatchExecContextConfig{})
+	if err := fn(&batchedContext{AppDatabaseContext: ac, batch: batch}); err != nil {
+		batch.Clear()
+		return err
+	}
+	_, err := batch.Commit()
+	return err
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestAppendExec(t *testing.T) {
+	ctx := NewAppDatabaseWithDb(nil).NewBatchExecContext(&BatchExecContextConfig{})
+
+	if err := ctx.AppendExec("UPDATE t SET a = $1 WHERE id = $2", "x", 1); err != nil {
+		t.Fatalf("AppendExec() error = %v", err)
+	}
+	if err := ctx.AppendExec("UPDATE t SET b = $2 WHERE id = $1", 2, "y"); err != nil {
+		t.Fatalf("AppendExec() error = %v", err)
+	}
+
+	wantSentences := "UPDATE t SET a = $1 WHERE id = $2;UPDATE t SET b = $4 WHERE id = $3;"
+	if got := ctx.GetSentences(); got != wantSentences {
+		t.Errorf("GetSentences() = %q, want %q", got, wantSentences)
+	}
+	if got, want := ctx.GetArgs(), []interface{}{"x", 1, 2, "y"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetArgs() = %v, want %v", got, want)
+	}
+
+	for _, sentence := range []string{"SELECT $0", "SELECT $2"} {
+		if err := ctx.AppendExec(sentence, 1); err == nil {
+			t.Errorf("AppendExec(%q) should fail", sentence)
+		}
+	}
+
+	ctx.Clear()
+	if ctx.GetSentences() != "" || len(ctx.GetArgs()) != 0 {
+		t.Errorf("Clear() should remove all sentences")
+	}
+	if _, err := ctx.Commit(); err != nil {
+		t.Errorf("Commit() of an empty batch error = %v", err)
+	}
+}
+
+// recordedBatch records the statements of its commits.
+type recordedBatch struct {
+	BatchExecContext
+	commits *[]string
+}
+
+func (b *recordedBatch) Commit() (sql.Result, error) {
+	*b.commits = append(*b.commits, b.GetSentences())
+	b.Clear()
+	return batchResult{}, nil
+}
+
+type recordedContext struct {
+	AppDatabaseContext
+	commits []string
+}
+
+func (c *recordedContext) NewBatchExecContext(config *BatchExecContextConfig) BatchExecContext {
+	return &recordedBatch{BatchExecContext: c.AppDatabaseContext.NewBatchExecContext(config), commits: &c.commits}
+}
+
+func TestInBatch(t *testing.T) {
+	ac := &recordedContext{AppDatabaseContext: NewAppDatabaseWithDb(nil)}
+
+	err := InBatch(ac, func(ac AppDatabaseContext) error {
+		if _, err := ac.Exec("UPDATE t SET a = $1", 1); err != nil {
+			return err
+		}
+		return InBatch(ac, func(ac AppDatabaseContext) error {
+			_, err := ac.Exec("UPDATE u SET b = $1", 2)
+			return err
+		})
+	})
+	if err != nil {
+		t.Fatalf("InBatch() error = %v", err)
+	}
+	if want := []string{"UPDATE t SET a = $1;UPDATE u SET b = $2;"}; !reflect.DeepEqual(ac.commits, want) {
+		t.Errorf("commits = %q, want %q", ac.commits, want)
+	}
+
+	ac.commits = nil
+	failed := errors.New("failed")
+	err = InBatch(ac, func(ac AppDatabaseContext) error {
+		ac.Exec("UPDATE t SET a = $1", 1)
+		return failed
+	})
+	if err != failed {
+		t.Errorf("InBatch() error = %v, want %v", err, failed)
+	}
+	if len(ac.commits) != 0 {
+		t.Errorf("failed batch is committed: %q", ac.commits)
+	}
+}
@@ -7,4 +7,8 @@ type Config struct {
 	Password string
 	Database string
 	UseSSL   bool
+	// ReadOnly makes every transaction of the connections read-only, so
+	// a component only running SELECT can not write even if its user
+	// is granted to
+	ReadOnly bool
 }
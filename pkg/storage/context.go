@@ -3,6 +3,7 @@ package storage
 import (
 	"database/sql"
 	"fmt"
+	"sync"
 
 	"github.com/HUSTSecLab/criticality_score/pkg/logger"
 	_ "github.com/lib/pq"
@@ -16,6 +17,7 @@ type AppDatabaseContext interface {
 	Exec(query string, args ...interface{}) (sql.Result, error)
 	Query(query string, args ...interface{}) (*sql.Rows, error)
 	QueryRow(query string, args ...interface{}) *sql.Row
+	Prepare(query string) (*sql.Stmt, error)
 	Close() error
 }
 
@@ -23,6 +25,9 @@ type appDatabaseContext struct {
 	config       *Config
 	enableSQLLog bool
 	db           *sql.DB
+
+	stmtsMu sync.Mutex
+	stmts   map[string]*sql.Stmt
 }
 
 func NewAppDatabase(config *Config) AppDatabaseContext {
@@ -34,11 +39,20 @@ func NewAppDatabaseWithDb(db *sql.DB) AppDatabaseContext {
 	return &appDatabaseContext{config: nil, db: db}
 }
 
+// connString returns the connection string of lib/pq, unknown keys are
+// sent to the server as run-time parameters.
+func (config *Config) connString() string {
+	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		config.Host, config.Port, config.User, config.Password, config.Database)
+	if config.ReadOnly {
+		connStr += " default_transaction_read_only=on"
+	}
+	return connStr
+}
+
 func (appDb *appDatabaseContext) ensureDatabaseConnection() error {
 	if appDb.db == nil {
-		connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-			appDb.config.Host, appDb.config.Port, appDb.config.User, appDb.config.Password, appDb.config.Database)
-		db, err := sql.Open("postgres", connStr)
+		db, err := sql.Open("postgres", appDb.config.connString())
 		if err != nil {
 			return err
 		}
@@ -106,7 +120,42 @@ func (app *appDatabaseContext) QueryRow(query string, args ...interface{}) *sql.
 	return conn.QueryRow(query, args...)
 }
 
+// Prepare returns the prepared statement of query, statements are cached
+// by the query text and closed with the context.
+func (app *appDatabaseContext) Prepare(query string) (*sql.Stmt, error) {
+	app.stmtsMu.Lock()
+	defer app.stmtsMu.Unlock()
+
+	if stmt, ok := app.stmts[query]; ok {
+		return stmt, nil
+	}
+	if app.enableSQLLog {
+		logger.Info("Prepare SQL: ", query)
+	}
+
+	conn, err := app.GetDatabaseConnection()
+	if err != nil {
+		return nil, err
+	}
+	stmt, err := conn.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	if app.stmts == nil {
+		app.stmts = make(map[string]*sql.Stmt)
+	}
+	app.stmts[query] = stmt
+	return stmt, nil
+}
+
 func (app *appDatabaseContext) Close() error {
+	app.stmtsMu.Lock()
+	for _, stmt := range app.stmts {
+		stmt.Close()
+	}
+	app.stmts = nil
+	app.stmtsMu.Unlock()
+
 	if app.db != nil {
 		return app.db.Close()
 	}
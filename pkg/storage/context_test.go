@@ -0,0 +1,32 @@
+package storage
+
+import "testing"
+
+func TestConnString(t *testing.T) {
+	cfg := &Config{Host: "db", Port: "5432", User: "reader", Password: "secret", Database: "app"}
+	want := "host=db port=5432 user=reader password=secret dbname=app sslmode=disable"
+	if got := cfg.connString(); got != want {
+		t.Errorf("connString() = %q, want %q", got, want)
+	}
+
+	cfg.ReadOnly = true
+	if got := cfg.connString(); got != want+" default_transaction_read_only=on" {
+		t.Errorf("read-only connString() = %q", got)
+	}
+}
+
+func TestDefaultReadOnly(t *testing.T) {
+	InitDefaultDatabaseContext(&Config{User: "writer"})
+	defer InitDefaultReadOnlyDatabaseContext(nil)
+
+	InitDefaultReadOnlyDatabaseContext(nil)
+	if GetDefaultReadOnlyAppDatabaseContext() != GetDefaultAppDatabaseContext() {
+		t.Error("read-only context should fall back to the default context")
+	}
+
+	InitDefaultReadOnlyDatabaseContext(&Config{User: "reader"})
+	cfg := GetDefaultReadOnlyAppDatabaseContext().GetConfig()
+	if cfg.User != "reader" || !cfg.ReadOnly {
+		t.Errorf("read-only config = %+v", cfg)
+	}
+}
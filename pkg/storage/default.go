@@ -13,6 +13,10 @@ const (
 
 var defaultAppDatabase AppDatabaseContext
 
+// defaultReadOnlyAppDatabase is used by components only running SELECT,
+// e.g. the api server and reports.
+var defaultReadOnlyAppDatabase AppDatabaseContext
+
 func GetDefaultConfig() (*Config, error) {
 	if defaultAppDatabase == nil {
 		return nil, fmt.Errorf("default app database is not initialized")
@@ -33,3 +37,32 @@ func GetDefaultAppDatabaseContext() AppDatabaseContext {
 
 	return defaultAppDatabase
 }
+
+// InitDefaultReadOnlyDatabaseContext initializes the read-only context, a
+// nil cfg makes read-only components use the default context.
+func InitDefaultReadOnlyDatabaseContext(cfg *Config) {
+	if cfg == nil {
+		defaultReadOnlyAppDatabase = nil
+		return
+	}
+	cfgCopy := *cfg
+	cfgCopy.ReadOnly = true
+	defaultReadOnlyAppDatabase = NewAppDatabase(&cfgCopy)
+}
+
+// GetDefaultReadOnlyAppDatabaseContext returns the context of components
+// only running SELECT, which is the default context if no read-only user
+// is configured.
+func GetDefaultReadOnlyAppDatabaseContext() AppDatabaseContext {
+	if defaultReadOnlyAppDatabase == nil {
+		return GetDefaultAppDatabaseContext()
+	}
+	return defaultReadOnlyAppDatabase
+}
+
+// WrapDefaultDatabaseContext replaces the default context by wrap of it,
+// e.g. to spool its writes. Read-only components without a read-only
+// user use the wrapped context as well.
+func WrapDefaultDatabaseContext(wrap func(AppDatabaseContext) AppDatabaseContext) {
+	defaultAppDatabase = wrap(GetDefaultAppDatabaseContext())
+}
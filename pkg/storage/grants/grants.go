@@ -0,0 +1,310 @@
+// Package grants documents the least privileges every component needs, and
+// generates the SQL granting them to one role per component, so collectors
+// and read-only components no longer share a superuser connection.
+//
+// The roles can not log in, database users are granted the role of the
+// component they run, e.g.
+//
+//	GRANT cs_apiserver TO api_user;
+//
+// Update Components when a component starts using a new table.
+package grants
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+)
+
+type Privilege string
+
+const (
+	Select Privilege = "SELECT"
+	Insert Privilege = "INSERT"
+	Update Privilege = "UPDATE"
+	Delete Privilege = "DELETE"
+)
+
+// Grant grants privileges on tables.
+type Grant struct {
+	Tables     []string
+	Privileges []Privilege
+}
+
+// Component is a tool connecting to the database.
+type Component struct {
+	// Name is the name of the binary
+	Name string
+	// ReadOnly is true if the component uses the read-only connection
+	ReadOnly bool
+	Grants   []Grant
+}
+
+// Role returns the role of the component, e.g. cs_apiserver.
+func (c *Component) Role() string {
+	return "cs_" + strings.ReplaceAll(c.Name, "-", "_")
+}
+
+func read(tables ...string) Grant {
+	return Grant{Tables: tables, Privileges: []Privilege{Select}}
+}
+
+// upsert is required by InsertOrUpdate of repositories.
+func upsert(tables ...string) Grant {
+	return Grant{Tables: tables, Privileges: []Privilege{Select, Insert, Update}}
+}
+
+var distPrefixes = []repository.DistPackageTablePrefix{
+	repository.DistLinkTablePrefixAlpine,
+	repository.DistLinkTablePrefixArchlinux,
+	repository.DistLinkTablePrefixAur,
+	repository.DistLinkTablePrefixCentos,
+	repository.DistLinkTablePrefixDebian,
+	repository.DistLinkTablePrefixDeepin,
+	repository.DistLinkTablePrefixFedora,
+	repository.DistLinkTablePrefixGentoo,
+	repository.DistLinkTablePrefixHomebrew,
+	repository.DistLinkTablePrefixNix,
+	repository.DistLinkTablePrefixUbuntu,
+}
+
+// distTables returns the package and relationship tables of every
+// distribution.
+func distTables() []string {
+	ret := make([]string, 0, 2*len(distPrefixes))
+	for _, p := range distPrefixes {
+		ret = append(ret, repository.DistPackageTableName(p), repository.DistRelationshipTableName(p))
+	}
+	return ret
+}
+
+// Components are the components with their privileges.
+var Components = []Component{
+	{
+		Name:     "apiserver",
+		ReadOnly: true,
+		Grants: []Grant{
+			read(repository.GitMetricProdTableName, repository.GitRepositoryTableName, repository.ProjectTagTableName),
+			read(repository.PopularitySnapshotTableName, repository.MetricTrendTableName, repository.OrgScoreTableName),
+			read(repository.ProjectLabelTableName, repository.SignalValueTableName),
+			read(distTables()...),
+			read(repository.MaterializedViewNames...),
+		},
+	},
+	{
+		Name:     "dataset-exporter",
+		ReadOnly: true,
+		Grants:   []Grant{read(repository.ScoreTableName, repository.GitMetricTableName)},
+	},
+	{
+		Name:     "funding-allocator",
+		ReadOnly: true,
+		Grants:   []Grant{read(repository.ScoreTableName, repository.GitMetricTableName, repository.ProjectTagTableName)},
+	},
+	{
+		Name:     "impact-simulator",
+		ReadOnly: true,
+		Grants:   []Grant{read(distTables()...)},
+	},
+	{
+		Name:     "typosquat-detector",
+		ReadOnly: true,
+		Grants:   []Grant{read(distTables()...)},
+	},
+	{
+		Name:     "freshness-monitor",
+		ReadOnly: true,
+		Grants:   []Grant{read(repository.CollectorRunTableName, repository.CollectionTimestampTableName)},
+	},
+	{
+		Name:     "database-validator",
+		ReadOnly: true,
+		Grants:   []Grant{read(repository.GitMetricTableName)},
+	},
+	{
+		Name: "scores-caculator",
+		Grants: []Grant{
+			read(repository.GitMetricTableName, repository.LangEcosystemTableName,
+				repository.DistDependencyTableName, repository.ProjectTagTableName, repository.MetricTrendTableName),
+			read(distTables()...),
+			upsert(repository.ScoreTableName, repository.OrgScoreTableName),
+		},
+	},
+	{
+		Name: "lang-ecosystem-collector",
+		Grants: []Grant{
+			read(repository.GitMetricTableName, repository.ScoreTableName, repository.ProjectTagTableName),
+			upsert(repository.LangEcosystemTableName, repository.LangEcosystemPackageTableName,
+				repository.CollectionTimestampTableName, repository.CollectorRunTableName),
+			{Tables: []string{repository.HTTPCacheTableName}, Privileges: []Privilege{Select, Insert, Update, Delete}},
+			{Tables: []string{repository.RawResponseTableName}, Privileges: []Privilege{Insert}},
+		},
+	},
+	{
+		Name: "git-metadata-collector",
+		Grants: []Grant{
+			read(repository.ScoreTableName, repository.ProjectTagTableName),
+			upsert(repository.GitMetricTableName, repository.CollectionTimestampTableName, repository.CommitLogTableName),
+			{Tables: []string{repository.RepoArchiveTableName}, Privileges: []Privilege{Select, Insert}},
+			{Tables: []string{repository.RawResponseTableName}, Privileges: []Privilege{Insert}},
+		},
+	},
+	{
+		Name: "metrics-recomputer",
+		Grants: []Grant{
+			read(repository.ScoreTableName, repository.ProjectTagTableName, repository.CommitLogTableName),
+			{Tables: []string{repository.GitMetricTableName}, Privileges: []Privilege{Select, Update}},
+		},
+	},
+	{
+		Name: "maintenance-classifier",
+		Grants: []Grant{
+			{Tables: []string{repository.GitMetricTableName, repository.GitMetricProdTableName}, Privileges: []Privilege{Select, Update}},
+		},
+	},
+	{
+		Name: "supply-chain-collector",
+		Grants: []Grant{
+			read(repository.GitRepositoryTableName, repository.ScoreTableName, repository.ProjectTagTableName),
+			{Tables: []string{repository.GitMetricTableName}, Privileges: []Privilege{Select, Update}},
+			upsert(repository.CollectionTimestampTableName),
+			{Tables: []string{repository.RawResponseTableName}, Privileges: []Privilege{Insert}},
+		},
+	},
+	{
+		Name: "mailing-list-collector",
+		Grants: []Grant{
+			read(repository.ScoreTableName, repository.ProjectTagTableName),
+			{Tables: []string{repository.GitMetricTableName}, Privileges: []Privilege{Select, Update}},
+			upsert(repository.CollectionTimestampTableName),
+		},
+	},
+	{
+		Name: "best-practices-collector",
+		Grants: []Grant{
+			read(repository.GitRepositoryTableName, repository.ScoreTableName, repository.ProjectTagTableName),
+			{Tables: []string{repository.GitMetricTableName}, Privileges: []Privilege{Select, Update}},
+			upsert(repository.CollectionTimestampTableName),
+			{Tables: []string{repository.HTTPCacheTableName}, Privileges: []Privilege{Select, Insert, Update, Delete}},
+			{Tables: []string{repository.RawResponseTableName}, Privileges: []Privilege{Insert}},
+		},
+	},
+	{
+		Name: "wikidata-collector",
+		Grants: []Grant{
+			read(repository.GitRepositoryTableName, repository.ScoreTableName, repository.ProjectTagTableName),
+			{Tables: []string{repository.GitMetricTableName}, Privileges: []Privilege{Select, Update}},
+			upsert(repository.CollectionTimestampTableName),
+		},
+	},
+	{
+		Name: "stackoverflow-collector",
+		Grants: []Grant{
+			read(repository.ScoreTableName, repository.ProjectTagTableName),
+			{Tables: []string{repository.GitMetricTableName}, Privileges: []Privilege{Select, Update}},
+			upsert(repository.CollectionTimestampTableName),
+		},
+	},
+	{
+		Name: "librariesio-collector",
+		Grants: []Grant{
+			read(repository.LangEcosystemPackageTableName, repository.ProjectTagTableName),
+			upsert(repository.LibrariesIOPackageTableName),
+		},
+	},
+	{
+		Name: "trend-calculator",
+		Grants: []Grant{
+			read(repository.GitMetricTableName, repository.LangEcosystemTableName, repository.DistDependencyTableName,
+				repository.PopularitySnapshotTableName),
+			{Tables: []string{repository.MetricSnapshotTableName}, Privileges: []Privilege{Select, Insert}},
+			upsert(repository.MetricTrendTableName),
+		},
+	},
+	{
+		Name: "popularity-collector",
+		Grants: []Grant{
+			read(repository.GitRepositoryTableName, repository.ScoreTableName, repository.ProjectTagTableName),
+			{Tables: []string{repository.PopularitySnapshotTableName}, Privileges: []Privilege{Select, Insert, Delete}},
+			{Tables: []string{repository.RawResponseTableName}, Privileges: []Privilege{Insert}},
+		},
+	},
+	{
+		Name: "project-tags",
+		Grants: []Grant{
+			{Tables: []string{repository.ProjectTagTableName}, Privileges: []Privilege{Select, Insert, Delete}},
+		},
+	},
+	{
+		Name: "signal-collector",
+		Grants: []Grant{
+			read(repository.GitRepositoryTableName, repository.GitMetricTableName, repository.ProjectTagTableName),
+			{Tables: []string{repository.SignalValueTableName}, Privileges: []Privilege{Select, Insert, Update, Delete}},
+		},
+	},
+	{
+		Name: "project-labels",
+		Grants: []Grant{
+			read(repository.MaintainerTableName, repository.CommitLogTableName),
+			upsert(repository.ProjectLabelTableName),
+		},
+	},
+}
+
+// quoteLiteral quotes a string literal of SQL.
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// SQL returns the statements creating the role of every component and
+// granting its privileges. The statements can be applied repeatedly.
+func SQL(components []Component) string {
+	var b strings.Builder
+	b.WriteString("-- Generated by `database-migrator grants`, see pkg/storage/grants.\n")
+	for _, c := range components {
+		role := c.Role()
+		fmt.Fprintf(&b, "\n-- %s", c.Name)
+		if c.ReadOnly {
+			b.WriteString(" (read-only)")
+		}
+		fmt.Fprintf(&b, "\nDO $$\nBEGIN\n    IF NOT EXISTS (SELECT FROM pg_roles WHERE rolname = %s) THEN\n        CREATE ROLE %s NOLOGIN;\n    END IF;\nEND\n$$;\n",
+			quoteLiteral(role), role)
+		fmt.Fprintf(&b, "GRANT USAGE ON SCHEMA public TO %s;\n", role)
+
+		// merge privileges of the same table, so every table has one GRANT
+		privileges := make(map[string]map[Privilege]bool)
+		writes := false
+		for _, g := range c.Grants {
+			for _, t := range g.Tables {
+				if privileges[t] == nil {
+					privileges[t] = make(map[Privilege]bool)
+				}
+				for _, p := range g.Privileges {
+					privileges[t][p] = true
+					writes = writes || p == Insert
+				}
+			}
+		}
+		tables := make([]string, 0, len(privileges))
+		for t := range privileges {
+			tables = append(tables, t)
+		}
+		sort.Strings(tables)
+		for _, t := range tables {
+			var ps []string
+			for _, p := range []Privilege{Select, Insert, Update, Delete} {
+				if privileges[t][p] {
+					ps = append(ps, string(p))
+				}
+			}
+			fmt.Fprintf(&b, "GRANT %s ON %s TO %s;\n", strings.Join(ps, ", "), t, role)
+		}
+		if writes {
+			// serial ids need the sequences
+			fmt.Fprintf(&b, "GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public TO %s;\n", role)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,45 @@
+package grants
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSQL(t *testing.T) {
+	sql := SQL([]Component{
+		{Name: "reader", ReadOnly: true, Grants: []Grant{read("b", "a")}},
+		{Name: "my-writer", Grants: []Grant{read("a"), upsert("a"), {Tables: []string{"c"}, Privileges: []Privilege{Delete}}}},
+	})
+
+	for _, want := range []string{
+		"-- reader (read-only)\n",
+		"IF NOT EXISTS (SELECT FROM pg_roles WHERE rolname = 'cs_reader') THEN\n        CREATE ROLE cs_reader NOLOGIN;",
+		"GRANT SELECT ON a TO cs_reader;\nGRANT SELECT ON b TO cs_reader;\n",
+		"GRANT SELECT, INSERT, UPDATE ON a TO cs_my_writer;\nGRANT DELETE ON c TO cs_my_writer;\n",
+		"GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public TO cs_my_writer;",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("SQL() does not contain %q:\n%s", want, sql)
+		}
+	}
+	if strings.Contains(sql, "SEQUENCES IN SCHEMA public TO cs_reader") {
+		t.Error("read-only components should not use sequences")
+	}
+}
+
+func TestComponents(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, c := range Components {
+		if seen[c.Name] {
+			t.Errorf("duplicated component %s", c.Name)
+		}
+		seen[c.Name] = true
+		for _, g := range c.Grants {
+			for _, p := range g.Privileges {
+				if c.ReadOnly && p != Select {
+					t.Errorf("read-only component %s is granted %s", c.Name, p)
+				}
+			}
+		}
+	}
+}
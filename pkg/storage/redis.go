@@ -19,7 +19,7 @@ func InitRedis() (*redis.Client, error) {
 func SetKeyValue(rdb *redis.Client, key, value string) error {
 	err := rdb.Set(context.Background(), key, value, 0).Err()
 	if err != nil {
-		return fmt.Errorf("could not set key '%s': %v", key, err)
+		return fmt.Errorf("could not set key '%s': %w", key, err)
 	}
 	return nil
 }
@@ -30,7 +30,7 @@ func GetKeyValue(rdb *redis.Client, key string) (string, error) {
 		if err == redis.Nil {
 			return "", fmt.Errorf("key '%s' does not exist", key)
 		}
-		return "", fmt.Errorf("could not get key '%s': %v", key, err)
+		return "", fmt.Errorf("could not get key '%s': %w", key, err)
 	}
 	return val, nil
 }
@@ -38,7 +38,7 @@ func GetKeyValue(rdb *redis.Client, key string) (string, error) {
 func PersistData(rdb *redis.Client) error {
 	err := rdb.BgSave(context.Background()).Err()
 	if err != nil {
-		return fmt.Errorf("could not trigger RDB save: %v", err)
+		return fmt.Errorf("could not trigger RDB save: %w", err)
 	}
 	return nil
 }
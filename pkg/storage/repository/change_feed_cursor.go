@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+)
+
+// ChangeFeedCursorRepository stores where the change feeds of registries
+// are read up to, so a restarted service resumes there.
+type ChangeFeedCursorRepository interface {
+	/** QUERY **/
+
+	// Get returns the cursor of a feed, nil if the feed is not read yet
+	Get(feed string) (*ChangeFeedCursor, error)
+
+	/** INSERT/UPDATE **/
+
+	// NOTE: update_time will be updated automatically
+	Set(feed string, cursor string) error
+}
+
+type ChangeFeedCursor struct {
+	Feed       *string `pk:"true"`
+	Cursor     *string
+	UpdateTime *time.Time
+}
+
+const ChangeFeedCursorTableName = "change_feed_cursors"
+
+type changeFeedCursorRepository struct {
+	appDb storage.AppDatabaseContext
+}
+
+var _ ChangeFeedCursorRepository = (*changeFeedCursorRepository)(nil)
+
+func NewChangeFeedCursorRepository(appDb storage.AppDatabaseContext) ChangeFeedCursorRepository {
+	return &changeFeedCursorRepository{appDb: appDb}
+}
+
+// Get implements ChangeFeedCursorRepository.
+func (c *changeFeedCursorRepository) Get(feed string) (*ChangeFeedCursor, error) {
+	return sqlutil.QueryCommonFirst[ChangeFeedCursor](c.appDb, ChangeFeedCursorTableName, "WHERE feed = $1", feed)
+}
+
+// Set implements ChangeFeedCursorRepository.
+func (c *changeFeedCursorRepository) Set(feed string, cursor string) error {
+	if feed == "" || cursor == "" {
+		return ErrInvalidInput
+	}
+	_, err := c.appDb.Exec(`INSERT INTO `+ChangeFeedCursorTableName+` (feed, cursor, update_time)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (feed) DO UPDATE SET cursor = EXCLUDED.cursor, update_time = EXCLUDED.update_time`,
+		feed, cursor, time.Now())
+	return err
+}
@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+	"github.com/lib/pq"
+)
+
+// Signal is a family of metrics collected together, every signal has a
+// <signal>_collected_at column in collection_timestamps.
+type Signal string
+
+const (
+	SignalGitMetadata   Signal = "git_metadata"
+	SignalLangEcosystem Signal = "lang_ecosystem"
+	SignalSupplyChain   Signal = "supply_chain"
+	SignalMailingList   Signal = "mailing_list"
+	SignalBestPractices Signal = "best_practices"
+	SignalWikidata      Signal = "wikidata"
+	SignalStackOverflow Signal = "stackoverflow"
+	// SignalValues are the values of the pluggable collectors of package
+	// signals, collected at the latest collection by any of them
+	SignalValues Signal = "signal_values"
+)
+
+// Signals are all the signals, in the order of the columns.
+var Signals = []Signal{
+	SignalGitMetadata, SignalLangEcosystem, SignalSupplyChain, SignalMailingList,
+	SignalBestPractices, SignalWikidata, SignalStackOverflow, SignalValues,
+}
+
+func (s Signal) valid() bool {
+	return s == SignalGitMetadata || s == SignalLangEcosystem || s == SignalSupplyChain ||
+		s == SignalMailingList || s == SignalBestPractices || s == SignalWikidata ||
+		s == SignalStackOverflow || s == SignalValues
+}
+
+func (s Signal) column() string {
+	return string(s) + "_collected_at"
+}
+
+type CollectionTimestampRepository interface {
+	/** QUERY **/
+
+	QueryByLink(link string) (*CollectionTimestamp, error)
+	// FilterStale returns the links whose signal is never collected or
+	// collected before since, in the order of links
+	FilterStale(signal Signal, links []string, since time.Time) ([]string, error)
+	// QueryCollectedAt returns the collected time of the signal of links,
+	// links never collected are not in the map
+	QueryCollectedAt(signal Signal, links []string) (map[string]time.Time, error)
+	// QueryFreshness returns how many links have the signal collected, and
+	// how many of them were collected before since
+	QueryFreshness(signal Signal, since time.Time) (*SignalFreshness, error)
+
+	/** INSERT/UPDATE **/
+
+	// MarkCollected sets the collected time of the signal of links to at
+	MarkCollected(signal Signal, links []string, at time.Time) error
+}
+
+type CollectionTimestamp struct {
+	GitLink                  *string `pk:"true"`
+	GitMetadataCollectedAt   *time.Time
+	LangEcosystemCollectedAt *time.Time
+	SupplyChainCollectedAt   *time.Time
+	MailingListCollectedAt   *time.Time
+	BestPracticesCollectedAt *time.Time
+	WikidataCollectedAt      *time.Time
+	StackOverflowCollectedAt *time.Time
+	SignalValuesCollectedAt  *time.Time
+}
+
+// SignalFreshness is the freshness of a signal over all links.
+type SignalFreshness struct {
+	// Collected is the number of links whose signal was ever collected
+	Collected int
+	// Stale is the number of links whose signal was collected before the
+	// time of the query
+	Stale int
+	// Oldest and Latest are the first and the last collected times, nil if
+	// the signal was never collected
+	Oldest *time.Time
+	Latest *time.Time
+}
+
+const CollectionTimestampTableName = "collection_timestamps"
+
+type collectionTimestampRepository struct {
+	appDb storage.AppDatabaseContext
+}
+
+var _ CollectionTimestampRepository = (*collectionTimestampRepository)(nil)
+
+func NewCollectionTimestampRepository(appDb storage.AppDatabaseContext) CollectionTimestampRepository {
+	return &collectionTimestampRepository{appDb: appDb}
+}
+
+// QueryByLink implements CollectionTimestampRepository.
+func (c *collectionTimestampRepository) QueryByLink(link string) (*CollectionTimestamp, error) {
+	return sqlutil.QueryCommonFirst[CollectionTimestamp](c.appDb, CollectionTimestampTableName,
+		"WHERE git_link = $1", link)
+}
+
+// FilterStale implements CollectionTimestampRepository.
+func (c *collectionTimestampRepository) FilterStale(signal Signal, links []string, since time.Time) ([]string, error) {
+	if !signal.valid() {
+		return nil, ErrInvalidInput
+	}
+	if len(links) == 0 {
+		return links, nil
+	}
+
+	rows, err := c.appDb.Query(`SELECT l.git_link FROM UNNEST($1::text[]) WITH ORDINALITY AS l(git_link, idx)
+		LEFT JOIN `+CollectionTimestampTableName+` t ON t.git_link = l.git_link
+		WHERE t.`+signal.column()+` IS NULL OR t.`+signal.column()+` < $2
+		ORDER BY l.idx`, pq.Array(links), since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ret := make([]string, 0, len(links))
+	for rows.Next() {
+		var link string
+		if err := rows.Scan(&link); err != nil {
+			return nil, err
+		}
+		ret = append(ret, link)
+	}
+	return ret, rows.Err()
+}
+
+// QueryCollectedAt implements CollectionTimestampRepository.
+func (c *collectionTimestampRepository) QueryCollectedAt(signal Signal, links []string) (map[string]time.Time, error) {
+	if !signal.valid() {
+		return nil, ErrInvalidInput
+	}
+	ret := make(map[string]time.Time)
+	if len(links) == 0 {
+		return ret, nil
+	}
+
+	rows, err := c.appDb.Query(`SELECT git_link, `+signal.column()+` FROM `+CollectionTimestampTableName+`
+		WHERE git_link = ANY($1::text[]) AND `+signal.column()+` IS NOT NULL`, pq.Array(links))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var link string
+		var at time.Time
+		if err := rows.Scan(&link, &at); err != nil {
+			return nil, err
+		}
+		ret[link] = at
+	}
+	return ret, rows.Err()
+}
+
+// QueryFreshness implements CollectionTimestampRepository.
+func (c *collectionTimestampRepository) QueryFreshness(signal Signal, since time.Time) (*SignalFreshness, error) {
+	if !signal.valid() {
+		return nil, ErrInvalidInput
+	}
+	ret := &SignalFreshness{}
+	err := c.appDb.QueryRow(`SELECT COUNT(`+signal.column()+`), COUNT(*) FILTER (WHERE `+signal.column()+` < $1),
+		MIN(`+signal.column()+`), MAX(`+signal.column()+`) FROM `+CollectionTimestampTableName, since).
+		Scan(&ret.Collected, &ret.Stale, &ret.Oldest, &ret.Latest)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// MarkCollected implements CollectionTimestampRepository.
+func (c *collectionTimestampRepository) MarkCollected(signal Signal, links []string, at time.Time) error {
+	if !signal.valid() {
+		return ErrInvalidInput
+	}
+	if len(links) == 0 {
+		return nil
+	}
+	_, err := c.appDb.Exec(`INSERT INTO `+CollectionTimestampTableName+` (git_link, `+signal.column()+`)
+		SELECT DISTINCT UNNEST($1::text[]), $2::timestamp
+		ON CONFLICT (git_link) DO UPDATE SET `+signal.column()+` = EXCLUDED.`+signal.column(),
+		pq.Array(links), at)
+	return err
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"iter"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+)
+
+type CollectorRunRepository interface {
+	/** QUERY **/
+
+	// Query returns the last runs of all collectors
+	Query() (iter.Seq[*CollectorRun], error)
+
+	/** INSERT/UPDATE **/
+
+	// Record records a run of the collector at the time, which succeeded if
+	// runErr is nil, the last success is kept if it failed
+	Record(collector string, at time.Time, runErr error) error
+}
+
+// CollectorRun is the last run of a collector of a whole ecosystem, e.g.
+// debian or lang-ecosystem.
+type CollectorRun struct {
+	Collector     *string `pk:"true"`
+	LastRunAt     *time.Time
+	LastSuccessAt *time.Time
+	// LastError is the error of the last run, nil if it succeeded
+	LastError *string
+}
+
+const CollectorRunTableName = "collector_runs"
+
+type collectorRunRepository struct {
+	appDb storage.AppDatabaseContext
+}
+
+var _ CollectorRunRepository = (*collectorRunRepository)(nil)
+
+func NewCollectorRunRepository(appDb storage.AppDatabaseContext) CollectorRunRepository {
+	return &collectorRunRepository{appDb: appDb}
+}
+
+// Query implements CollectorRunRepository.
+func (r *collectorRunRepository) Query() (iter.Seq[*CollectorRun], error) {
+	return sqlutil.QueryCommon[CollectorRun](r.appDb, CollectorRunTableName, "ORDER BY collector")
+}
+
+// Record implements CollectorRunRepository.
+func (r *collectorRunRepository) Record(collector string, at time.Time, runErr error) error {
+	if collector == "" {
+		return ErrInvalidInput
+	}
+	var success *time.Time
+	var message *string
+	if runErr == nil {
+		success = &at
+	} else {
+		s := runErr.Error()
+		message = &s
+	}
+	_, err := r.appDb.Exec(`INSERT INTO `+CollectorRunTableName+` (collector, last_run_at, last_success_at, last_error)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (collector) DO UPDATE SET last_run_at = EXCLUDED.last_run_at,
+			last_success_at = COALESCE(EXCLUDED.last_success_at, `+CollectorRunTableName+`.last_success_at),
+			last_error = EXCLUDED.last_error`,
+		collector, at, success, message)
+	return err
+}
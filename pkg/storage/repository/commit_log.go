@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+)
+
+type CommitLogRepository interface {
+	/** QUERY **/
+
+	// GetByLink returns the latest commit log of the link
+	GetByLink(link string) (*CommitLog, error)
+	// QueryLinks returns the links which have a commit log
+	QueryLinks() ([]string, error)
+
+	/** INSERT/UPDATE **/
+
+	// InsertOrUpdate replaces the commit log of the same link
+	InsertOrUpdate(data *CommitLog) error
+}
+
+// CommitLog is the raw commit log of a repo, the commits are encoded by
+// history.Encode.
+type CommitLog struct {
+	GitLink     *string `pk:"true"`
+	Commits     *[]byte
+	CommitCount *int
+	CollectedAt *time.Time
+}
+
+const CommitLogTableName = "commit_logs"
+
+type commitLogRepository struct {
+	appDb storage.AppDatabaseContext
+}
+
+var _ CommitLogRepository = (*commitLogRepository)(nil)
+
+func NewCommitLogRepository(appDb storage.AppDatabaseContext) CommitLogRepository {
+	return &commitLogRepository{appDb: appDb}
+}
+
+// GetByLink implements CommitLogRepository.
+func (c *commitLogRepository) GetByLink(link string) (*CommitLog, error) {
+	return sqlutil.QueryCommonFirst[CommitLog](c.appDb, CommitLogTableName, "WHERE git_link = $1", link)
+}
+
+// QueryLinks implements CommitLogRepository.
+func (c *commitLogRepository) QueryLinks() ([]string, error) {
+	rows, err := c.appDb.Query(`SELECT git_link FROM ` + CommitLogTableName + ` ORDER BY git_link`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ret := make([]string, 0)
+	for rows.Next() {
+		var link string
+		if err := rows.Scan(&link); err != nil {
+			return nil, err
+		}
+		ret = append(ret, link)
+	}
+	return ret, rows.Err()
+}
+
+// InsertOrUpdate implements CommitLogRepository.
+func (c *commitLogRepository) InsertOrUpdate(data *CommitLog) error {
+	if data.GitLink == nil || *data.GitLink == "" || data.Commits == nil || data.CommitCount == nil {
+		return ErrInvalidInput
+	}
+	collectedAt := time.Now()
+	if data.CollectedAt != nil {
+		collectedAt = *data.CollectedAt
+	}
+	_, err := c.appDb.Exec(`INSERT INTO `+CommitLogTableName+` (git_link, commits, commit_count, collected_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (git_link) DO UPDATE
+		SET commits = EXCLUDED.commits, commit_count = EXCLUDED.commit_count,
+			collected_at = EXCLUDED.collected_at`,
+		*data.GitLink, *data.Commits, *data.CommitCount, collectedAt)
+	return err
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"fmt"
 	"iter"
 	"time"
 
@@ -46,6 +47,20 @@ const (
 	Ubuntu
 )
 
+// String returns the table prefix of the distribution, e.g. debian.
+func (t DistType) String() string {
+	prefixes := []DistPackageTablePrefix{
+		DistLinkTablePrefixDebian, DistLinkTablePrefixArchlinux, DistLinkTablePrefixHomebrew,
+		DistLinkTablePrefixNix, DistLinkTablePrefixAlpine, DistLinkTablePrefixCentos,
+		DistLinkTablePrefixAur, DistLinkTablePrefixDeepin, DistLinkTablePrefixFedora,
+		DistLinkTablePrefixGentoo, DistLinkTablePrefixUbuntu,
+	}
+	if t < 0 || int(t) >= len(prefixes) {
+		return fmt.Sprintf("DistType(%d)", int(t))
+	}
+	return string(prefixes[t])
+}
+
 type DistLinkInfo struct {
 	ID         *int64 `generated:"true"`
 	GitLink    *string
@@ -70,35 +70,43 @@ func NewDistPackageRepository(appDb storage.AppDatabaseContext, prefix DistPacka
 	return &distPackageRepository{ctx: appDb, prefix: prefix}
 }
 
+func (d *distPackageRepository) tableName() string {
+	return DistPackageTableName(d.prefix)
+}
+
 // BatchInsert implements DistPackageRepository.
 func (d *distPackageRepository) BatchInsert(packageInfos []*DistPackage) error {
-	return sqlutil.BatchInsert(d.ctx, string(d.prefix)+DistPackageTableNameAppendix, packageInfos)
+	return sqlutil.BatchInsert(d.ctx, d.tableName(), packageInfos)
 }
 
 // BatchUpdate implements DistPackageRepository.
 func (d *distPackageRepository) BatchUpdate(packageInfos []*DistPackage) error {
-	return sqlutil.BatchUpdate(d.ctx, string(d.prefix)+DistPackageTableNameAppendix, packageInfos)
+	return sqlutil.BatchUpdate(d.ctx, d.tableName(), packageInfos)
 }
 
 // Delete implements DistPackageRepository.
 func (d *distPackageRepository) Delete(name string) error {
-	return sqlutil.Delete(d.ctx, string(d.prefix)+DistPackageTableNameAppendix, &DistPackage{Package: &name})
+	return sqlutil.Delete(d.ctx, d.tableName(), &DistPackage{Package: &name})
 }
 
 // DeleteAll implements DistPackageRepository.
 func (d *distPackageRepository) DeleteAll() error {
-	_, err := d.ctx.Exec("DELETE FROM " + string(d.prefix) + DistPackageTableNameAppendix)
+	query, args, err := sqlutil.From(d.tableName()).Delete()
+	if err != nil {
+		return err
+	}
+	_, err = d.ctx.Exec(query, args...)
 	return err
 }
 
 // GetByGitLink implements DistPackageRepository.
 func (d *distPackageRepository) GetByGitLink(gitLink string) (iter.Seq[*DistPackage], error) {
-	return sqlutil.QueryCommon[DistPackage](d.ctx, string(d.prefix)+DistPackageTableNameAppendix, "WHERE git_link = $1", gitLink)
+	return sqlutil.QueryCommon[DistPackage](d.ctx, d.tableName(), "WHERE git_link = $1", gitLink)
 }
 
 // GetByName implements DistPackageRepository.
 func (d *distPackageRepository) GetByName(name string) (*DistPackage, error) {
-	return sqlutil.QueryCommonFirst[DistPackage](d.ctx, string(d.prefix)+DistPackageTableNameAppendix, "WHERE package = $1", name)
+	return sqlutil.QueryCommonFirst[DistPackage](d.ctx, d.tableName(), "WHERE package = $1", name)
 }
 
 // Insert implements DistPackageRepository.
@@ -108,12 +116,12 @@ func (d *distPackageRepository) Insert(packageInfo *DistPackage) error {
 	}
 	packageInfo.GitLink = nil
 
-	return sqlutil.Insert(d.ctx, string(d.prefix)+DistPackageTableNameAppendix, packageInfo)
+	return sqlutil.Insert(d.ctx, d.tableName(), packageInfo)
 }
 
 // Query implements DistPackageRepository.
 func (d *distPackageRepository) Query() (iter.Seq[*DistPackage], error) {
-	return sqlutil.QueryCommon[DistPackage](d.ctx, string(d.prefix)+DistPackageTableNameAppendix, "")
+	return sqlutil.QueryCommon[DistPackage](d.ctx, d.tableName(), "")
 }
 
 // Update implements DistPackageRepository.
@@ -123,11 +131,20 @@ func (d *distPackageRepository) Update(packageInfos *DistPackage) error {
 	}
 	packageInfos.GitLink = nil
 
-	return sqlutil.Update(d.ctx, string(d.prefix)+DistPackageTableNameAppendix, packageInfos)
+	return sqlutil.Update(d.ctx, d.tableName(), packageInfos)
 }
 
 // UpdateGitLink implements DistPackageRepository.
 func (d *distPackageRepository) UpdateGitLink(name string, gitLink string) error {
-	_, err := d.ctx.Exec("UPDATE "+string(d.prefix)+DistPackageTableNameAppendix+" SET git_link = $1 WHERE package = $2", gitLink, name)
+	query, args, err := sqlutil.From(d.tableName()).Where("package", name).Update("git_link", gitLink)
+	if err != nil {
+		return err
+	}
+	// called once per package by the collectors, so the statement is prepared
+	stmt, err := d.ctx.Prepare(query)
+	if err != nil {
+		return err
+	}
+	_, err = stmt.Exec(args...)
 	return err
 }
@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"fmt"
+	"iter"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+	"github.com/lib/pq"
+)
+
+type DistRelationshipRepository interface {
+	/** QUERY **/
+
+	Query() (iter.Seq[*DistRelationship], error)
+	// QueryReverseClosure returns all packages which depend on name directly
+	// or transitively, within maxDepth hops, ordered by depth
+	QueryReverseClosure(name string, maxDepth int) (iter.Seq[*DistReachablePackage], error)
+	// GetShortestPath returns the shortest dependency path from `from` to `to`,
+	// including both ends, nil is returned if `to` is not reachable within maxDepth hops
+	GetShortestPath(from, to string, maxDepth int) ([]string, error)
+}
+
+const DistRelationshipTableNameAppendix = "_relationships"
+
+// MaxReachabilityDepth is the upper bound of maxDepth of reachability queries.
+const MaxReachabilityDepth = 64
+
+// DistPackageTablePrefixes lists all the known distribution table prefixes.
+var DistPackageTablePrefixes = []DistPackageTablePrefix{
+	DistLinkTablePrefixAlpine,
+	DistLinkTablePrefixArchlinux,
+	DistLinkTablePrefixAur,
+	DistLinkTablePrefixCentos,
+	DistLinkTablePrefixDebian,
+	DistLinkTablePrefixDeepin,
+	DistLinkTablePrefixFedora,
+	DistLinkTablePrefixGentoo,
+	DistLinkTablePrefixHomebrew,
+	DistLinkTablePrefixNix,
+	DistLinkTablePrefixUbuntu,
+}
+
+// ParseDistPackageTablePrefix checks whether s is a known distribution table prefix.
+func ParseDistPackageTablePrefix(s string) (DistPackageTablePrefix, bool) {
+	for _, p := range DistPackageTablePrefixes {
+		if string(p) == s {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// DistRelationship means FromPackage depends on ToPackage.
+type DistRelationship struct {
+	FromPackage *string `pk:"true" column:"frompackage"`
+	ToPackage   *string `pk:"true" column:"topackage"`
+}
+
+type DistReachablePackage struct {
+	Package *string
+	Depth   *int
+}
+
+type distRelationshipRepository struct {
+	ctx    storage.AppDatabaseContext
+	prefix DistPackageTablePrefix
+}
+
+var _ DistRelationshipRepository = (*distRelationshipRepository)(nil)
+
+// NewDistRelationshipRepository creates a new DistRelationshipRepository.
+func NewDistRelationshipRepository(appDb storage.AppDatabaseContext, prefix DistPackageTablePrefix) DistRelationshipRepository {
+	return &distRelationshipRepository{ctx: appDb, prefix: prefix}
+}
+
+func (d *distRelationshipRepository) tableName() string {
+	return DistRelationshipTableName(d.prefix)
+}
+
+func normalizeDepth(maxDepth int) int {
+	if maxDepth <= 0 || maxDepth > MaxReachabilityDepth {
+		return MaxReachabilityDepth
+	}
+	return maxDepth
+}
+
+// Query implements DistRelationshipRepository.
+func (d *distRelationshipRepository) Query() (iter.Seq[*DistRelationship], error) {
+	return sqlutil.QueryCommon[DistRelationship](d.ctx, d.tableName(), "")
+}
+
+// QueryReverseClosure implements DistRelationshipRepository.
+func (d *distRelationshipRepository) QueryReverseClosure(name string, maxDepth int) (iter.Seq[*DistReachablePackage], error) {
+	if name == "" {
+		return nil, ErrInvalidInput
+	}
+
+	table, err := sqlutil.Table(d.tableName())
+	if err != nil {
+		return nil, err
+	}
+
+	// UNION removes duplicated (package, depth) pairs, and the depth limit
+	// makes the recursion terminate even if there are cycles
+	query := fmt.Sprintf(`WITH RECURSIVE r(package, depth) AS (
+		SELECT frompackage, 1 FROM %[1]s WHERE topackage = $1
+		UNION
+		SELECT rel.frompackage, r.depth + 1
+		FROM %[1]s rel JOIN r ON rel.topackage = r.package
+		WHERE r.depth < $2
+	)
+	SELECT package, MIN(depth) AS depth FROM r
+	WHERE package <> $1
+	GROUP BY package
+	ORDER BY depth, package`, table)
+
+	return sqlutil.Query[DistReachablePackage](d.ctx, query, name, normalizeDepth(maxDepth))
+}
+
+// GetShortestPath implements DistRelationshipRepository.
+func (d *distRelationshipRepository) GetShortestPath(from, to string, maxDepth int) ([]string, error) {
+	if from == "" || to == "" {
+		return nil, ErrInvalidInput
+	}
+	if from == to {
+		return []string{from}, nil
+	}
+
+	// breadth first search, one query per level
+	parent := map[string]string{from: ""}
+	frontier := []string{from}
+
+	query, _, err := sqlutil.From(d.tableName()).
+		WhereAny("frompackage", nil).
+		OrderBy("frompackage", "topackage").
+		Select("frompackage", "topackage")
+	if err != nil {
+		return nil, err
+	}
+	// the same query runs once per level
+	stmt, err := d.ctx.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+
+	for depth := 0; depth < normalizeDepth(maxDepth) && len(frontier) > 0; depth++ {
+		rows, err := stmt.Query(pq.Array(frontier))
+		if err != nil {
+			return nil, err
+		}
+
+		next := make([]string, 0)
+		for rows.Next() {
+			var f, t string
+			if err := rows.Scan(&f, &t); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if _, ok := parent[t]; ok {
+				continue
+			}
+			parent[t] = f
+			next = append(next, t)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+
+		if _, ok := parent[to]; ok {
+			path := []string{to}
+			for p := parent[to]; p != ""; p = parent[p] {
+				path = append([]string{p}, path...)
+			}
+			return path, nil
+		}
+		frontier = next
+	}
+	return nil, nil
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"fmt"
+	"math/bits"
+	"time"
+)
+
+// Channel is a channel distributing repos, a language ecosystem, a
+// distribution or binary release assets. It is the bit of the channel in
+// Channels.
+type Channel int
+
+const (
+	// channels of the language ecosystems are their LangEcosystemType,
+	// channels of the distributions start at distChannels
+	distChannels Channel = 16
+	// ChannelBinaryRelease is binary assets of the recent releases of the
+	// repo, see package analysis/supplychain
+	ChannelBinaryRelease Channel = 32
+)
+
+// LangEcosystemChannel returns the channel of the language ecosystem.
+func LangEcosystemChannel(t LangEcosystemType) Channel {
+	return Channel(t)
+}
+
+// DistChannel returns the channel of the distribution.
+func DistChannel(t DistType) Channel {
+	return distChannels + Channel(t)
+}
+
+// String returns the name of the ecosystem or the distribution, e.g. npm
+// or debian, or release for binary release assets.
+func (c Channel) String() string {
+	switch {
+	case c == ChannelBinaryRelease:
+		return "release"
+	case c >= 0 && c < distChannels:
+		return LangEcosystemType(c).String()
+	case c >= distChannels && c < ChannelBinaryRelease:
+		return DistType(c - distChannels).String()
+	}
+	return fmt.Sprintf("Channel(%d)", int(c))
+}
+
+// Channels is a bitmap of channels.
+type Channels int64
+
+// Has reports whether c has the channel.
+func (c Channels) Has(channel Channel) bool {
+	return channel >= 0 && channel < 64 && c&(1<<channel) != 0
+}
+
+// List returns the channels of c in the order of their bits.
+func (c Channels) List() []Channel {
+	ret := make([]Channel, 0, bits.OnesCount64(uint64(c)))
+	for v := uint64(c); v != 0; v &= v - 1 {
+		ret = append(ret, Channel(bits.TrailingZeros64(v)))
+	}
+	return ret
+}
+
+// Names returns the names of the channels of c in the order of their bits.
+func (c Channels) Names() []string {
+	channels := c.List()
+	ret := make([]string, len(channels))
+	for i, channel := range channels {
+		ret[i] = channel.String()
+	}
+	return ret
+}
+
+// DistributionChannels is a row of the distribution channels view, the
+// channels distributing a repo by our own tables. Breadth is the number
+// of the channels.
+type DistributionChannels struct {
+	GitLink    *string
+	Channels   *Channels
+	Breadth    *int
+	UpdateTime *time.Time
+}
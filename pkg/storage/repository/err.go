@@ -1,7 +1,9 @@
 package repository
 
-import "errors"
+import "github.com/HUSTSecLab/criticality_score/pkg/errors"
 
 var (
-	ErrInvalidInput = errors.New("invalid input")
+	ErrInvalidInput = errors.Mark(errors.New("invalid input"), errors.DataIntegrity)
+	// ErrConflict is returned when a write would duplicate a unique key
+	ErrConflict = errors.Mark(errors.New("conflict"), errors.DataIntegrity)
 )
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"database/sql"
+	"iter"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+)
+
+type ForgeRequestBudgetRepository interface {
+	/** QUERY **/
+
+	// QueryByForge returns the budgets of a forge, latest window first
+	QueryByForge(forge string) (iter.Seq[*ForgeRequestBudget], error)
+
+	/** INSERT/UPDATE **/
+
+	// Consume counts one request in the window, it returns false without
+	// counting if limit requests have been made in the window already
+	Consume(forge string, windowStart time.Time, limit int) (bool, error)
+}
+
+type ForgeRequestBudget struct {
+	Forge       *string    `pk:"true"`
+	WindowStart *time.Time `pk:"true"`
+	Requests    *int
+}
+
+const ForgeRequestBudgetTableName = "forge_request_budgets"
+
+type forgeRequestBudgetRepository struct {
+	appDb storage.AppDatabaseContext
+}
+
+var _ ForgeRequestBudgetRepository = (*forgeRequestBudgetRepository)(nil)
+
+func NewForgeRequestBudgetRepository(appDb storage.AppDatabaseContext) ForgeRequestBudgetRepository {
+	return &forgeRequestBudgetRepository{appDb: appDb}
+}
+
+// QueryByForge implements ForgeRequestBudgetRepository.
+func (f *forgeRequestBudgetRepository) QueryByForge(forge string) (iter.Seq[*ForgeRequestBudget], error) {
+	return sqlutil.QueryCommon[ForgeRequestBudget](f.appDb, ForgeRequestBudgetTableName,
+		"WHERE forge = $1 ORDER BY window_start DESC", forge)
+}
+
+// Consume implements ForgeRequestBudgetRepository.
+func (f *forgeRequestBudgetRepository) Consume(forge string, windowStart time.Time, limit int) (bool, error) {
+	if forge == "" || limit <= 0 {
+		return false, ErrInvalidInput
+	}
+	// the update is skipped by the WHERE clause once the limit is reached,
+	// so concurrent enumerators share the budget
+	var requests int
+	err := f.appDb.QueryRow(`INSERT INTO `+ForgeRequestBudgetTableName+` (forge, window_start, requests)
+		VALUES ($1, $2, 1)
+		ON CONFLICT (forge, window_start) DO UPDATE
+		SET requests = `+ForgeRequestBudgetTableName+`.requests + 1
+		WHERE `+ForgeRequestBudgetTableName+`.requests < $3
+		RETURNING requests`, forge, windowStart, limit).Scan(&requests)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -1,8 +1,11 @@
 package repository
 
 import (
+	"database/sql"
 	"fmt"
 	"iter"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
@@ -15,6 +18,9 @@ type GitMetricsRepository interface {
 	/** QUERY **/
 	Query() (iter.Seq[*GitMetric], error)
 	QueryByLink(link string) (*GitMetric, error)
+	// QueryScopes returns the languages and the ecosystems of the latest
+	// metrics of every link, only GitLink, Language and EcoSystem are set
+	QueryScopes() (iter.Seq[*GitMetric], error)
 
 	/** INSERT/UPDATE **/
 	// NOTE: update_time will be updated automatically
@@ -22,6 +28,20 @@ type GitMetricsRepository interface {
 	// NOTE: update_time will be updated automatically
 	// and the data will not copy from old data
 	BatchInsertOrUpdate(data []*GitMetric) error
+	// NOTE: only the latest record of the link will be updated, and the
+	// row of the link in git_metrics_prod, so the API serves the risk
+	// without waiting for the next copy of git_metrics
+	UpdateMaintenanceRisk(link string, risk string) error
+	// NOTE: only the latest record of the link will be updated
+	UpdateAdvisoryCount(link string, count int) error
+	// NOTE: only the latest records of the links of data will be updated,
+	// by one statement, columns are the columns of GitMetric written, e.g.
+	// wikidata_item, nil clears a column
+	BatchUpdateColumns(data []*GitMetric, columns ...string) error
+	// NOTE: only the latest record of the link will be updated, the
+	// fields derived from the commit log are written, see package
+	// gitfile/history
+	UpdateHistoryMetrics(link string, data *GitMetric) error
 }
 
 type GitMetric struct {
@@ -32,15 +52,76 @@ type GitMetric struct {
 	UpdatedSince     *time.Time
 	ContributorCount *int
 	CommitFrequency  *float64
-	OrgCount         *int
-	License          *string
-	Language         *pq.StringArray
-	CloneValid       *bool
-	UpdateTime       *time.Time
+	// HumanContributorCount and HumanCommitFrequency leave out bots, e.g.
+	// dependabot, the raw counts above include them
+	HumanContributorCount *int
+	HumanCommitFrequency  *float64
+	OrgCount              *int
+	License               *string
+	Language              *pq.StringArray
+	CloneValid            *bool
+	MaintenanceRisk       *string
+	// Runtimes are the declared runtime constraints, e.g. "python >=3.8",
+	// EOLRuntime is true if any of them only allows end-of-life releases
+	Runtimes   *string
+	EOLRuntime *bool `column:"eol_runtime"`
+	// TimezoneDiversity is the effective number of timezones of the
+	// contributors of the last year
+	TimezoneDiversity *float64
+	// SecurityPolicy is true if the repo has a security policy, e.g.
+	// SECURITY.md, SecurityContact is where to report vulnerabilities and
+	// EmbargoPolicy is true if reports are kept private until a fix, see
+	// package analysis/disclosure
+	SecurityPolicy  *bool
+	SecurityContact *string
+	EmbargoPolicy   *bool
+	// DependencyUpdateTools are the configured dependency update tools,
+	// e.g. "dependabot renovate", empty if there is none, see package
+	// analysis/bots
+	DependencyUpdateTools *string
+	// TestFileCount and TestLOC are the test files and their lines, and
+	// TestsPerKLOC the lines of tests per 1000 lines of other source code,
+	// NULL if the repo has no source files, see package analysis/testcode
+	TestFileCount *int
+	TestLOC       *int64   `column:"test_loc"`
+	TestsPerKLOC  *float64 `column:"tests_per_kloc"`
+	// supply-chain signals, see package analysis/supplychain
+	SignedCommitRatio *float64
+	SignedTagRatio    *float64
+	SignedReleases    *bool
+	BinaryReleases    *bool
+	SLSAProvenance    *bool `column:"slsa_provenance"`
+	Sigstore          *bool
+	OSSFuzz           *bool `column:"oss_fuzz"`
+	ClusterFuzzLite   *bool
+	// AdvisoryCount is the number of security advisories of the latest
+	// versions of the packages published from the repo, see package depsdev
+	AdvisoryCount *int
+	// BestPracticesBadge is the OpenSSF Best Practices badge level, see
+	// package bestpractices
+	BestPracticesBadge *string
+	// WikidataItem is the Wikidata item of the repo, and WikidataSitelinks
+	// its number of Wikipedia articles, see package wikidata
+	WikidataItem      *string
+	WikidataSitelinks *int
+	// StackOverflowQuestions is the number of Stack Overflow questions of
+	// the tags of the repo in the last year, see package stackexchange
+	StackOverflowQuestions *int `column:"stackoverflow_questions"`
+	// activity of the mailing lists of the last year, see package
+	// mailinglist
+	MailingListMessages  *int
+	MailingListSenders   *int
+	MailingListPatches   *int
+	MailingListReviewers *int
+	UpdateTime           *time.Time
 }
 
 const GitMetricTableName = "git_metrics"
 
+// GitMetricProdTableName is the copy of git_metrics published by the API,
+// it is recreated from git_metrics by githubmetrics.
+const GitMetricProdTableName = "git_metrics_prod"
+
 type gitmetricsRepository struct {
 	appDb storage.AppDatabaseContext
 }
@@ -74,11 +155,171 @@ func (g *gitmetricsRepository) Query() (iter.Seq[*GitMetric], error) {
 	return sqlutil.QueryCommon[GitMetric](g.appDb, subQuery, "")
 }
 
+// QueryScopes implements GitMetricsRepository.
+func (g *gitmetricsRepository) QueryScopes() (iter.Seq[*GitMetric], error) {
+	return sqlutil.Query[GitMetric](g.appDb, fmt.Sprintf(`SELECT DISTINCT ON (git_link)
+		git_link, language, ecosystem
+	FROM %s
+	ORDER BY git_link, id DESC`, GitMetricTableName))
+}
+
 // QueryByLink implements GitMetricsRepository.
 func (g *gitmetricsRepository) QueryByLink(link string) (*GitMetric, error) {
 	return sqlutil.QueryCommonFirst[GitMetric](g.appDb, GitMetricTableName, "WHERE git_link = $1 ORDER BY id DESC", link)
 }
 
+// UpdateMaintenanceRisk implements GitMetricsRepository.
+func (g *gitmetricsRepository) UpdateMaintenanceRisk(link string, risk string) error {
+	if link == "" {
+		return ErrInvalidInput
+	}
+	db, err := g.appDb.GetDatabaseConnection()
+	if err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(fmt.Sprintf(`UPDATE %[1]s SET maintenance_risk = $1
+	WHERE id = (SELECT MAX(id) FROM %[1]s WHERE git_link = $2)`, GitMetricTableName), risk, link)
+	if err != nil {
+		return err
+	}
+	// git_metrics_prod has a row per link
+	_, err = tx.Exec(`UPDATE `+GitMetricProdTableName+` SET maintenance_risk = $1 WHERE git_link = $2`, risk, link)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateAdvisoryCount implements GitMetricsRepository.
+func (g *gitmetricsRepository) UpdateAdvisoryCount(link string, count int) error {
+	if link == "" {
+		return ErrInvalidInput
+	}
+	_, err := g.appDb.Exec(fmt.Sprintf(`UPDATE %[1]s SET advisory_count = $1
+	WHERE id = (SELECT MAX(id) FROM %[1]s WHERE git_link = $2)`, GitMetricTableName), count, link)
+	return err
+}
+
+// BatchUpdateColumns implements GitMetricsRepository.
+func (g *gitmetricsRepository) BatchUpdateColumns(data []*GitMetric, columns ...string) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if len(columns) == 0 {
+		return ErrInvalidInput
+	}
+	fields := make(map[string]int)
+	t := reflect.TypeFor[GitMetric]()
+	for i := range t.NumField() {
+		fields[sqlutil.ColumnName(t.Field(i))] = i
+	}
+
+	links := make([]string, len(data))
+	for i, d := range data {
+		if d == nil || d.GitLink == nil || *d.GitLink == "" {
+			return ErrInvalidInput
+		}
+		links[i] = *d.GitLink
+	}
+	args := []any{pq.Array(links)}
+	var sets, arrays []string
+	for _, column := range columns {
+		idx, ok := fields[column]
+		if !ok || column == "git_link" || column == "id" {
+			return ErrInvalidInput
+		}
+		values, typ, err := gitMetricColumn(data, idx)
+		if err != nil {
+			return err
+		}
+		args = append(args, pq.Array(values))
+		arrays = append(arrays, fmt.Sprintf("$%d::%s[]", len(args), typ))
+		sets = append(sets, fmt.Sprintf("%[1]s = t.%[1]s", column))
+	}
+	_, err := g.appDb.Exec(fmt.Sprintf(`UPDATE %[1]s SET %[2]s
+	FROM UNNEST($1::text[], %[3]s) AS t(git_link, %[4]s)
+	WHERE %[1]s.id = (SELECT MAX(id) FROM %[1]s WHERE git_link = t.git_link)`,
+		GitMetricTableName, strings.Join(sets, ", "), strings.Join(arrays, ", "), strings.Join(columns, ", ")),
+		args...)
+	return err
+}
+
+// gitMetricColumn returns the values of the field idx of data, and their
+// array element type.
+func gitMetricColumn(data []*GitMetric, idx int) (any, string, error) {
+	field := func(d *GitMetric) reflect.Value {
+		return reflect.ValueOf(d).Elem().Field(idx)
+	}
+	switch reflect.TypeFor[GitMetric]().Field(idx).Type {
+	case reflect.TypeFor[*string]():
+		ret := make([]sql.NullString, len(data))
+		for i, d := range data {
+			if v := field(d); !v.IsNil() {
+				ret[i] = sql.NullString{String: v.Elem().String(), Valid: true}
+			}
+		}
+		return ret, "text", nil
+	case reflect.TypeFor[*int]():
+		ret := make([]sql.NullInt64, len(data))
+		for i, d := range data {
+			if v := field(d); !v.IsNil() {
+				ret[i] = sql.NullInt64{Int64: v.Elem().Int(), Valid: true}
+			}
+		}
+		return ret, "bigint", nil
+	case reflect.TypeFor[*float64]():
+		ret := make([]sql.NullFloat64, len(data))
+		for i, d := range data {
+			if v := field(d); !v.IsNil() {
+				ret[i] = sql.NullFloat64{Float64: v.Elem().Float(), Valid: true}
+			}
+		}
+		return ret, "double precision", nil
+	case reflect.TypeFor[*bool]():
+		ret := make([]sql.NullBool, len(data))
+		for i, d := range data {
+			if v := field(d); !v.IsNil() {
+				ret[i] = sql.NullBool{Bool: v.Elem().Bool(), Valid: true}
+			}
+		}
+		return ret, "boolean", nil
+	case reflect.TypeFor[*time.Time]():
+		ret := make([]sql.NullTime, len(data))
+		for i, d := range data {
+			if v := field(d); !v.IsNil() {
+				ret[i] = sql.NullTime{Time: v.Elem().Interface().(time.Time), Valid: true}
+			}
+		}
+		return ret, "timestamp", nil
+	}
+	return nil, "", fmt.Errorf("%w: column of %s", ErrInvalidInput, reflect.TypeFor[GitMetric]().Field(idx).Type)
+}
+
+// UpdateHistoryMetrics implements GitMetricsRepository.
+func (g *gitmetricsRepository) UpdateHistoryMetrics(link string, data *GitMetric) error {
+	if link == "" || data == nil {
+		return ErrInvalidInput
+	}
+	_, err := g.appDb.Exec(fmt.Sprintf(`UPDATE %[1]s SET
+		created_since = $1,
+		updated_since = $2,
+		contributor_count = $3,
+		commit_frequency = $4,
+		human_contributor_count = $5,
+		human_commit_frequency = $6,
+		timezone_diversity = $7
+	WHERE id = (SELECT MAX(id) FROM %[1]s WHERE git_link = $8)`, GitMetricTableName),
+		data.CreatedSince, data.UpdatedSince, data.ContributorCount, data.CommitFrequency,
+		data.HumanContributorCount, data.HumanCommitFrequency, data.TimezoneDiversity, link)
+	return err
+}
+
 func NewGitMetricsRepository(appDb storage.AppDatabaseContext) GitMetricsRepository {
 	return &gitmetricsRepository{appDb: appDb}
 }
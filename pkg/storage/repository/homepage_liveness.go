@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"iter"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+	"github.com/lib/pq"
+)
+
+// HomepageLivenessRepository stores the last probe of every homepage of the
+// distribution packages, see package homepage.
+type HomepageLivenessRepository interface {
+	/** QUERY **/
+
+	Query() (iter.Seq[*HomepageLiveness], error)
+	// QueryDue returns the distinct homepages of the packages of a
+	// distribution which were not checked since checkedBefore
+	QueryDue(prefix DistPackageTablePrefix, checkedBefore time.Time) ([]string, error)
+	// QueryDead returns the packages of a distribution whose homepage was
+	// checked but has not been alive since aliveBefore, the most critical
+	// first
+	QueryDead(prefix DistPackageTablePrefix, aliveBefore time.Time, limit int) (iter.Seq[*DeadHomepagePackage], error)
+
+	/** INSERT/UPDATE **/
+
+	// Record saves the probes, LastAliveAt is set to CheckedAt of alive
+	// ones and kept otherwise
+	Record(probes []*HomepageLiveness) error
+}
+
+type HomepageLiveness struct {
+	Homepage *string `pk:"true"`
+	// StatusCode is the status of the final response, nil if the request
+	// failed
+	StatusCode  *int
+	Error       *string
+	Alive       *bool
+	CheckedAt   *time.Time
+	LastAliveAt *time.Time
+}
+
+// DeadHomepagePackage is a package whose homepage is dead.
+type DeadHomepagePackage struct {
+	Package    *string
+	Homepage   *string
+	GitLink    *string
+	PageRank   *float64
+	StatusCode *int
+	Error      *string
+	// LastAliveAt is nil if the homepage was never seen alive
+	LastAliveAt *time.Time
+}
+
+const HomepageLivenessTableName = "homepage_liveness"
+
+type homepageLivenessRepository struct {
+	appDb storage.AppDatabaseContext
+}
+
+var _ HomepageLivenessRepository = (*homepageLivenessRepository)(nil)
+
+func NewHomepageLivenessRepository(appDb storage.AppDatabaseContext) HomepageLivenessRepository {
+	return &homepageLivenessRepository{appDb: appDb}
+}
+
+// Query implements HomepageLivenessRepository.
+func (h *homepageLivenessRepository) Query() (iter.Seq[*HomepageLiveness], error) {
+	return sqlutil.QueryCommon[HomepageLiveness](h.appDb, HomepageLivenessTableName, "")
+}
+
+// QueryDue implements HomepageLivenessRepository.
+func (h *homepageLivenessRepository) QueryDue(prefix DistPackageTablePrefix, checkedBefore time.Time) ([]string, error) {
+	table, err := sqlutil.Table(DistPackageTableName(prefix))
+	if err != nil {
+		return nil, err
+	}
+	rows, err := h.appDb.Query(fmt.Sprintf(`SELECT DISTINCT p.homepage FROM %s p
+		LEFT JOIN %s l ON l.homepage = p.homepage
+		WHERE p.homepage IS NOT NULL AND p.homepage <> ''
+			AND (l.checked_at IS NULL OR l.checked_at < $1)`,
+		table, HomepageLivenessTableName), checkedBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ret []string
+	for rows.Next() {
+		var homepage string
+		if err := rows.Scan(&homepage); err != nil {
+			return nil, err
+		}
+		ret = append(ret, homepage)
+	}
+	return ret, rows.Err()
+}
+
+// QueryDead implements HomepageLivenessRepository.
+func (h *homepageLivenessRepository) QueryDead(prefix DistPackageTablePrefix, aliveBefore time.Time, limit int) (iter.Seq[*DeadHomepagePackage], error) {
+	if limit <= 0 {
+		return nil, ErrInvalidInput
+	}
+	table, err := sqlutil.Table(DistPackageTableName(prefix))
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf(`SELECT p.package, p.homepage, p.git_link, p.page_rank,
+			l.status_code, l.error, l.last_alive_at
+		FROM %s p JOIN %s l ON l.homepage = p.homepage
+		WHERE NOT l.alive AND (l.last_alive_at IS NULL OR l.last_alive_at < $1)
+		ORDER BY p.page_rank DESC NULLS LAST, p.package
+		LIMIT $2`, table, HomepageLivenessTableName)
+	return sqlutil.Query[DeadHomepagePackage](h.appDb, query, aliveBefore, limit)
+}
+
+// Record implements HomepageLivenessRepository.
+func (h *homepageLivenessRepository) Record(probes []*HomepageLiveness) error {
+	if len(probes) == 0 {
+		return nil
+	}
+	homepages := make([]string, len(probes))
+	codes := make([]sql.NullInt64, len(probes))
+	errs := make([]sql.NullString, len(probes))
+	alive := make([]bool, len(probes))
+	// formatted keeping the wall clock, as pq does for timestamp arguments
+	checkedAt := make([]string, len(probes))
+	for i, p := range probes {
+		if p.Homepage == nil || p.Alive == nil || p.CheckedAt == nil {
+			return ErrInvalidInput
+		}
+		homepages[i], alive[i] = *p.Homepage, *p.Alive
+		checkedAt[i] = p.CheckedAt.Format(time.RFC3339Nano)
+		if p.StatusCode != nil {
+			codes[i] = sql.NullInt64{Int64: int64(*p.StatusCode), Valid: true}
+		}
+		if p.Error != nil {
+			errs[i] = sql.NullString{String: *p.Error, Valid: true}
+		}
+	}
+	_, err := h.appDb.Exec(`INSERT INTO `+HomepageLivenessTableName+`
+			(homepage, status_code, error, alive, checked_at, last_alive_at)
+		SELECT DISTINCT ON (homepage) homepage, status_code, error, alive, checked_at,
+			CASE WHEN alive THEN checked_at END
+		FROM UNNEST($1::text[], $2::int[], $3::text[], $4::bool[], $5::timestamp[])
+			AS t(homepage, status_code, error, alive, checked_at)
+		ORDER BY homepage, checked_at DESC
+		ON CONFLICT (homepage) DO UPDATE SET status_code = EXCLUDED.status_code,
+			error = EXCLUDED.error, alive = EXCLUDED.alive, checked_at = EXCLUDED.checked_at,
+			last_alive_at = COALESCE(EXCLUDED.last_alive_at, `+HomepageLivenessTableName+`.last_alive_at)`,
+		pq.Array(homepages), pq.Array(codes), pq.Array(errs), pq.Array(alive), pq.Array(checkedAt))
+	return err
+}
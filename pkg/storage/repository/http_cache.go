@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+)
+
+type HTTPCacheRepository interface {
+	/** QUERY **/
+
+	// GetByURL returns the cached response of url, expired ones included
+	GetByURL(url string) (*HTTPCache, error)
+
+	/** INSERT/UPDATE **/
+
+	// InsertOrUpdate replaces the cached response of the same url
+	InsertOrUpdate(data *HTTPCache) error
+	// DeleteExpired removes all responses expired before now
+	DeleteExpired() error
+}
+
+type HTTPCache struct {
+	URL        *string `pk:"true" column:"url"`
+	StatusCode *int
+	Body       *[]byte
+	FetchedAt  *time.Time
+	ExpiresAt  *time.Time
+}
+
+const HTTPCacheTableName = "http_cache"
+
+type httpCacheRepository struct {
+	appDb storage.AppDatabaseContext
+}
+
+var _ HTTPCacheRepository = (*httpCacheRepository)(nil)
+
+func NewHTTPCacheRepository(appDb storage.AppDatabaseContext) HTTPCacheRepository {
+	return &httpCacheRepository{appDb: appDb}
+}
+
+// GetByURL implements HTTPCacheRepository.
+func (h *httpCacheRepository) GetByURL(url string) (*HTTPCache, error) {
+	return sqlutil.QueryCommonFirst[HTTPCache](h.appDb, HTTPCacheTableName, "WHERE url = $1", url)
+}
+
+// InsertOrUpdate implements HTTPCacheRepository.
+func (h *httpCacheRepository) InsertOrUpdate(data *HTTPCache) error {
+	if data.URL == nil || *data.URL == "" || data.StatusCode == nil || data.ExpiresAt == nil {
+		return ErrInvalidInput
+	}
+	fetchedAt := time.Now()
+	if data.FetchedAt != nil {
+		fetchedAt = *data.FetchedAt
+	}
+	_, err := h.appDb.Exec(`INSERT INTO `+HTTPCacheTableName+` (url, status_code, body, fetched_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (url) DO UPDATE
+		SET status_code = EXCLUDED.status_code, body = EXCLUDED.body,
+			fetched_at = EXCLUDED.fetched_at, expires_at = EXCLUDED.expires_at`,
+		*data.URL, *data.StatusCode, data.Body, fetchedAt, *data.ExpiresAt)
+	return err
+}
+
+// DeleteExpired implements HTTPCacheRepository.
+func (h *httpCacheRepository) DeleteExpired() error {
+	_, err := h.appDb.Exec(`DELETE FROM `+HTTPCacheTableName+` WHERE expires_at < $1`, time.Now())
+	return err
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"fmt"
 	"iter"
 	"time"
 
@@ -34,6 +35,15 @@ const (
 	Cargo
 )
 
+// String returns the lower cased name of the ecosystem, e.g. npm.
+func (t LangEcosystemType) String() string {
+	names := []string{"npm", "go", "maven", "pypi", "nuget", "cargo"}
+	if t < 0 || int(t) >= len(names) {
+		return fmt.Sprintf("LangEcosystemType(%d)", int(t))
+	}
+	return names[t]
+}
+
 type langEcoLinkRepository struct {
 	appDb storage.AppDatabaseContext
 }
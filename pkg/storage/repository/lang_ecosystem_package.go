@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"fmt"
+	"iter"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+	"github.com/lib/pq"
+	"github.com/samber/lo"
+)
+
+// LangEcosystemPackageRepository stores the dependents of every package
+// published from a repo, which are aggregated into LangEcosystem.
+type LangEcosystemPackageRepository interface {
+	/** QUERY **/
+
+	// QueryByLink returns the latest breakdown of a repo, sorted by type and
+	// dependents descending
+	QueryByLink(link string) (iter.Seq[*LangEcosystemPackage], error)
+	// QueryLatest returns the latest record of every package of every repo
+	QueryLatest() (iter.Seq[*LangEcosystemPackage], error)
+
+	/** INSERT/UPDATE **/
+
+	// NOTE: update_time will be updated automatically
+	BatchInsert(data []*LangEcosystemPackage) error
+}
+
+type LangEcosystemPackage struct {
+	ID       *int64 `generated:"true"`
+	GitLink  *string
+	Type     *LangEcosystemType
+	Package  *string
+	Version  *string
+	DepCount *int
+	// Licenses are the licenses declared by the version, and AdvisoryCount
+	// the number of its security advisories, as known by deps.dev
+	Licenses      *pq.StringArray
+	AdvisoryCount *int
+	UpdateTime    *time.Time
+}
+
+const LangEcosystemPackageTableName = "lang_ecosystem_packages"
+
+type langEcosystemPackageRepository struct {
+	appDb storage.AppDatabaseContext
+}
+
+var _ LangEcosystemPackageRepository = (*langEcosystemPackageRepository)(nil)
+
+func NewLangEcosystemPackageRepository(appDb storage.AppDatabaseContext) LangEcosystemPackageRepository {
+	return &langEcosystemPackageRepository{appDb: appDb}
+}
+
+// QueryByLink implements LangEcosystemPackageRepository.
+func (l *langEcosystemPackageRepository) QueryByLink(link string) (iter.Seq[*LangEcosystemPackage], error) {
+	latest := fmt.Sprintf(`(SELECT DISTINCT ON (git_link, type, package) * FROM %s
+		WHERE git_link = $1 ORDER BY git_link, type, package, id DESC) t`, LangEcosystemPackageTableName)
+	return sqlutil.QueryCommon[LangEcosystemPackage](l.appDb, latest, "ORDER BY type, dep_count DESC NULLS LAST", link)
+}
+
+// QueryLatest implements LangEcosystemPackageRepository.
+func (l *langEcosystemPackageRepository) QueryLatest() (iter.Seq[*LangEcosystemPackage], error) {
+	latest := fmt.Sprintf(`(SELECT DISTINCT ON (git_link, type, package) * FROM %s
+		ORDER BY git_link, type, package, id DESC) t`, LangEcosystemPackageTableName)
+	return sqlutil.QueryCommon[LangEcosystemPackage](l.appDb, latest, "")
+}
+
+// BatchInsert implements LangEcosystemPackageRepository.
+func (l *langEcosystemPackageRepository) BatchInsert(data []*LangEcosystemPackage) error {
+	now := time.Now()
+	for _, d := range data {
+		if d.GitLink == nil || *d.GitLink == "" || d.Type == nil || d.Package == nil || *d.Package == "" {
+			return ErrInvalidInput
+		}
+		d.UpdateTime = lo.ToPtr(now)
+	}
+	return sqlutil.BatchInsert(l.appDb, LangEcosystemPackageTableName, data)
+}
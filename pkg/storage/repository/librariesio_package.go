@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"fmt"
+	"iter"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+)
+
+// LibrariesIOPackageRepository stores the SourceRank and dependent counts of
+// packages by Libraries.io, a comparison signal to the dependents of
+// deps.dev in LangEcosystemPackage.
+type LibrariesIOPackageRepository interface {
+	/** QUERY **/
+
+	// QueryByLink returns the packages published from a repo, sorted by
+	// type and SourceRank descending
+	QueryByLink(link string) (iter.Seq[*LibrariesIOPackage], error)
+
+	/** INSERT/UPDATE **/
+
+	// InsertOrUpdate replaces the package of the same type and name,
+	// update_time will be updated automatically
+	InsertOrUpdate(data *LibrariesIOPackage) error
+}
+
+type LibrariesIOPackage struct {
+	Type    *LangEcosystemType `pk:"true"`
+	Package *string            `pk:"true"`
+	GitLink *string
+	// SourceRank is the rank of Libraries.io, DependentsCount the number of
+	// dependent packages and DependentReposCount of dependent repos
+	SourceRank          *int
+	DependentsCount     *int
+	DependentReposCount *int
+	UpdateTime          *time.Time
+}
+
+const LibrariesIOPackageTableName = "librariesio_packages"
+
+type librariesIOPackageRepository struct {
+	appDb storage.AppDatabaseContext
+}
+
+var _ LibrariesIOPackageRepository = (*librariesIOPackageRepository)(nil)
+
+func NewLibrariesIOPackageRepository(appDb storage.AppDatabaseContext) LibrariesIOPackageRepository {
+	return &librariesIOPackageRepository{appDb: appDb}
+}
+
+// QueryByLink implements LibrariesIOPackageRepository.
+func (l *librariesIOPackageRepository) QueryByLink(link string) (iter.Seq[*LibrariesIOPackage], error) {
+	return sqlutil.QueryCommon[LibrariesIOPackage](l.appDb, LibrariesIOPackageTableName,
+		"WHERE git_link = $1 ORDER BY type, source_rank DESC NULLS LAST", link)
+}
+
+// InsertOrUpdate implements LibrariesIOPackageRepository.
+func (l *librariesIOPackageRepository) InsertOrUpdate(data *LibrariesIOPackage) error {
+	if data.Type == nil || data.Package == nil || *data.Package == "" {
+		return ErrInvalidInput
+	}
+	_, err := l.appDb.Exec(fmt.Sprintf(`INSERT INTO %s
+		(type, package, git_link, source_rank, dependents_count, dependent_repos_count, update_time)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (type, package) DO UPDATE
+		SET git_link = EXCLUDED.git_link, source_rank = EXCLUDED.source_rank,
+			dependents_count = EXCLUDED.dependents_count,
+			dependent_repos_count = EXCLUDED.dependent_repos_count,
+			update_time = EXCLUDED.update_time`, LibrariesIOPackageTableName),
+		*data.Type, *data.Package, data.GitLink, data.SourceRank, data.DependentsCount,
+		data.DependentReposCount, time.Now())
+	return err
+}
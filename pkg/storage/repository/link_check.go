@@ -0,0 +1,235 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"iter"
+	"strings"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/errors"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+	"github.com/lib/pq"
+)
+
+// LinkCheckRepository stores the last check of every git link of
+// git_metrics, see package linkrepair.
+type LinkCheckRepository interface {
+	/** QUERY **/
+
+	// QueryDue returns the distinct git links of git_metrics which were not
+	// checked since checkedBefore
+	QueryDue(checkedBefore time.Time) ([]string, error)
+	// QueryByStatus returns the checks of a status, the links of the highest
+	// latest scores first
+	QueryByStatus(status string, limit int) (iter.Seq[*LinkCheck], error)
+
+	/** INSERT/UPDATE **/
+
+	// Record saves the checks, LastOkAt is set to CheckedAt of checks whose
+	// status is ok and kept otherwise
+	Record(checks []*LinkCheck) error
+	// RenameGitLink replaces oldLink by newLink in the tables of the
+	// current state of repos, in a transaction, the history and snapshots
+	// are kept under oldLink. ErrConflict is returned if newLink is already
+	// in one of the tables, nothing is renamed then.
+	RenameGitLink(oldLink, newLink string) error
+}
+
+type LinkCheck struct {
+	GitLink *string `pk:"true"`
+	Status  *string
+	// Target is the link the repo moved to
+	Target *string
+	// StatusCode is the status of the final response, nil if not probed
+	// or the request failed
+	StatusCode  *int
+	Error       *string
+	Suggestions *pq.StringArray
+	CheckedAt   *time.Time
+	LastOkAt    *time.Time
+}
+
+const LinkCheckTableName = "link_checks"
+
+// linkCheckOK is the status of links serving their repo, see
+// linkrepair.StatusOK.
+const linkCheckOK = "ok"
+
+type linkCheckRepository struct {
+	appDb storage.AppDatabaseContext
+}
+
+var _ LinkCheckRepository = (*linkCheckRepository)(nil)
+
+func NewLinkCheckRepository(appDb storage.AppDatabaseContext) LinkCheckRepository {
+	return &linkCheckRepository{appDb: appDb}
+}
+
+// QueryDue implements LinkCheckRepository.
+func (l *linkCheckRepository) QueryDue(checkedBefore time.Time) ([]string, error) {
+	rows, err := l.appDb.Query(fmt.Sprintf(`SELECT DISTINCT m.git_link FROM %s m
+		LEFT JOIN %s c ON c.git_link = m.git_link
+		WHERE m.git_link IS NOT NULL AND m.git_link <> ''
+			AND (c.checked_at IS NULL OR c.checked_at < $1)`,
+		GitMetricTableName, LinkCheckTableName), checkedBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ret []string
+	for rows.Next() {
+		var link string
+		if err := rows.Scan(&link); err != nil {
+			return nil, err
+		}
+		ret = append(ret, link)
+	}
+	return ret, rows.Err()
+}
+
+// QueryByStatus implements LinkCheckRepository.
+func (l *linkCheckRepository) QueryByStatus(status string, limit int) (iter.Seq[*LinkCheck], error) {
+	if status == "" || limit <= 0 {
+		return nil, ErrInvalidInput
+	}
+	query := fmt.Sprintf(`SELECT c.* FROM %s c
+		LEFT JOIN (SELECT DISTINCT ON (git_link) git_link, score FROM %s ORDER BY git_link, id DESC) s
+			ON s.git_link = c.git_link
+		WHERE c.status = $1
+		ORDER BY s.score DESC NULLS LAST, c.git_link
+		LIMIT $2`, LinkCheckTableName, ScoreTableName)
+	return sqlutil.Query[LinkCheck](l.appDb, query, status, limit)
+}
+
+// Record implements LinkCheckRepository.
+func (l *linkCheckRepository) Record(checks []*LinkCheck) error {
+	if len(checks) == 0 {
+		return nil
+	}
+	links := make([]string, len(checks))
+	statuses := make([]string, len(checks))
+	targets := make([]sql.NullString, len(checks))
+	codes := make([]sql.NullInt64, len(checks))
+	errs := make([]sql.NullString, len(checks))
+	// joined by newlines, as pq does not support arrays of arrays
+	suggestions := make([]string, len(checks))
+	// formatted keeping the wall clock, as pq does for timestamp arguments
+	checkedAt := make([]string, len(checks))
+	for i, c := range checks {
+		if c.GitLink == nil || c.Status == nil || *c.Status == "" || c.CheckedAt == nil {
+			return ErrInvalidInput
+		}
+		links[i], statuses[i] = *c.GitLink, *c.Status
+		checkedAt[i] = c.CheckedAt.Format(time.RFC3339Nano)
+		if c.Target != nil {
+			targets[i] = sql.NullString{String: *c.Target, Valid: true}
+		}
+		if c.StatusCode != nil {
+			codes[i] = sql.NullInt64{Int64: int64(*c.StatusCode), Valid: true}
+		}
+		if c.Error != nil {
+			errs[i] = sql.NullString{String: *c.Error, Valid: true}
+		}
+		if c.Suggestions != nil {
+			suggestions[i] = strings.Join(*c.Suggestions, "\n")
+		}
+	}
+	_, err := l.appDb.Exec(`INSERT INTO `+LinkCheckTableName+`
+			(git_link, status, target, status_code, error, suggestions, checked_at, last_ok_at)
+		SELECT DISTINCT ON (git_link) git_link, status, target, status_code, error,
+			string_to_array(NULLIF(suggestions, ''), E'\n'), checked_at,
+			CASE WHEN status = $8 THEN checked_at END
+		FROM UNNEST($1::text[], $2::text[], $3::text[], $4::int[], $5::text[], $6::text[], $7::timestamp[])
+			AS t(git_link, status, target, status_code, error, suggestions, checked_at)
+		ORDER BY git_link, checked_at DESC
+		ON CONFLICT (git_link) DO UPDATE SET status = EXCLUDED.status, target = EXCLUDED.target,
+			status_code = EXCLUDED.status_code, error = EXCLUDED.error,
+			suggestions = EXCLUDED.suggestions, checked_at = EXCLUDED.checked_at,
+			last_ok_at = COALESCE(EXCLUDED.last_ok_at, `+LinkCheckTableName+`.last_ok_at)`,
+		pq.Array(links), pq.Array(statuses), pq.Array(targets), pq.Array(codes), pq.Array(errs),
+		pq.Array(suggestions), pq.Array(checkedAt), linkCheckOK)
+	return err
+}
+
+// gitLinkStateTables returns the tables of the current state of repos,
+// renamed by RenameGitLink. git_metrics and the ecosystem tables are
+// appended to, but are read by the latest row of a link, so they are the
+// current state as well. The history and the snapshots, e.g.
+// git_metrics_history, scores and the snapshots of scores, metrics and
+// popularity, stay under the old link, and the repaired check of the old
+// link records its target.
+func gitLinkStateTables() []string {
+	ret := []string{
+		CollectionTimestampTableName,
+		CommitLogTableName,
+		DistDependencyTableName,
+		GitMetricTableName,
+		GitMetricProdTableName,
+		GitRepositoryTableName,
+		LangEcosystemTableName,
+		LangEcosystemPackageTableName,
+		LibrariesIOPackageTableName,
+		ProjectTagTableName,
+		SignalProvenanceTableName,
+		SignalValueTableName,
+	}
+	for _, prefix := range DistPackageTablePrefixes {
+		ret = append(ret, DistPackageTableName(prefix))
+	}
+	for _, platform := range PlatformLinkTablePrefixes {
+		ret = append(ret, PlatformLinkTableName(platform))
+	}
+	return ret
+}
+
+// RenameGitLink implements LinkCheckRepository.
+func (l *linkCheckRepository) RenameGitLink(oldLink, newLink string) error {
+	if oldLink == "" || newLink == "" || oldLink == newLink {
+		return ErrInvalidInput
+	}
+	names := gitLinkStateTables()
+	tables := make([]string, len(names))
+	for i, name := range names {
+		table, err := sqlutil.Table(name)
+		if err != nil {
+			return err
+		}
+		tables[i] = table
+	}
+
+	db, err := l.appDb.GetDatabaseConnection()
+	if err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// both links were collected if newLink is known, the rows are not
+	// merged as they may disagree
+	for i, table := range tables {
+		var exists bool
+		if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM `+table+` WHERE git_link = $1)`, newLink).Scan(&exists); err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("%w: %s is already in %s", ErrConflict, newLink, names[i])
+		}
+	}
+	for i, table := range tables {
+		_, err := tx.Exec(`UPDATE `+table+` SET git_link = $2 WHERE git_link = $1`, oldLink, newLink)
+		// newLink was written since it was checked
+		if errors.Of(err) == errors.DataIntegrity {
+			return fmt.Errorf("%w: %s is already in %s: %w", ErrConflict, newLink, names[i], err)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
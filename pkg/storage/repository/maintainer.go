@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"database/sql"
+	"iter"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+	"github.com/lib/pq"
+)
+
+// MaintainerRepository stores the contacts of distribution packages and of
+// repos, see package maintainer.
+type MaintainerRepository interface {
+	/** QUERY **/
+
+	Query() (iter.Seq[*Maintainer], error)
+	// QueryBySubject returns the contacts of a package of a distribution,
+	// or of a git link if source is git
+	QueryBySubject(source, subject string) (iter.Seq[*Maintainer], error)
+	// QueryByEmail returns every package and repo an email is a contact of
+	QueryByEmail(email string) (iter.Seq[*Maintainer], error)
+
+	/** INSERT/UPDATE **/
+
+	// Replace replaces the contacts of the subjects of source by
+	// maintainers in one transaction
+	Replace(source string, subjects []string, maintainers []*Maintainer) error
+}
+
+type Maintainer struct {
+	// Source is the table prefix of the distribution, or git
+	Source *string `pk:"true"`
+	// Subject is the package, or the git link if Source is git
+	Subject *string `pk:"true"`
+	Role    *string `pk:"true"`
+	// Contact is the identity of the contact, the lower cased email, the
+	// handle prefixed by @, or the name
+	Contact   *string `pk:"true"`
+	Name      *string
+	Email     *string
+	Handle    *string
+	UpdatedAt *time.Time
+}
+
+const MaintainerTableName = "maintainers"
+
+type maintainerRepository struct {
+	appDb storage.AppDatabaseContext
+}
+
+var _ MaintainerRepository = (*maintainerRepository)(nil)
+
+func NewMaintainerRepository(appDb storage.AppDatabaseContext) MaintainerRepository {
+	return &maintainerRepository{appDb: appDb}
+}
+
+// Query implements MaintainerRepository.
+func (m *maintainerRepository) Query() (iter.Seq[*Maintainer], error) {
+	return sqlutil.QueryCommon[Maintainer](m.appDb, MaintainerTableName, "")
+}
+
+// QueryBySubject implements MaintainerRepository.
+func (m *maintainerRepository) QueryBySubject(source, subject string) (iter.Seq[*Maintainer], error) {
+	return sqlutil.QueryCommon[Maintainer](m.appDb, MaintainerTableName,
+		"WHERE source = $1 AND subject = $2 ORDER BY role, contact", source, subject)
+}
+
+// QueryByEmail implements MaintainerRepository.
+func (m *maintainerRepository) QueryByEmail(email string) (iter.Seq[*Maintainer], error) {
+	return sqlutil.QueryCommon[Maintainer](m.appDb, MaintainerTableName,
+		"WHERE lower(email) = lower($1) ORDER BY source, subject", email)
+}
+
+// Replace implements MaintainerRepository.
+func (m *maintainerRepository) Replace(source string, subjects []string, maintainers []*Maintainer) error {
+	if source == "" {
+		return ErrInvalidInput
+	}
+	if len(subjects) == 0 {
+		return nil
+	}
+	for _, mt := range maintainers {
+		if mt.Source == nil || *mt.Source != source || mt.Subject == nil || mt.Role == nil ||
+			mt.Contact == nil || mt.UpdatedAt == nil {
+			return ErrInvalidInput
+		}
+	}
+
+	db, err := m.appDb.GetDatabaseConnection()
+	if err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM `+MaintainerTableName+` WHERE source = $1 AND subject = ANY($2)`,
+		source, pq.Array(subjects))
+	if err != nil {
+		return err
+	}
+	if len(maintainers) > 0 {
+		cols := make([][]sql.NullString, 7)
+		for i := range cols {
+			cols[i] = make([]sql.NullString, len(maintainers))
+		}
+		for i, mt := range maintainers {
+			// formatted keeping the wall clock, as pq does for timestamp
+			// arguments
+			updatedAt := mt.UpdatedAt.Format(time.RFC3339Nano)
+			for j, v := range []*string{mt.Subject, mt.Role, mt.Contact, mt.Name, mt.Email, mt.Handle, &updatedAt} {
+				if v != nil {
+					cols[j][i] = sql.NullString{String: *v, Valid: true}
+				}
+			}
+		}
+		_, err = tx.Exec(`INSERT INTO `+MaintainerTableName+`
+				(source, subject, role, contact, name, email, handle, updated_at)
+			SELECT $1, subject, role, contact, name, email, handle, updated_at::timestamp
+			FROM UNNEST($2::text[], $3::text[], $4::text[], $5::text[], $6::text[], $7::text[], $8::text[])
+				AS t(subject, role, contact, name, email, handle, updated_at)
+			ON CONFLICT DO NOTHING`,
+			source, pq.Array(cols[0]), pq.Array(cols[1]), pq.Array(cols[2]), pq.Array(cols[3]),
+			pq.Array(cols[4]), pq.Array(cols[5]), pq.Array(cols[6]))
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+	"github.com/samber/lo"
+)
+
+type MaterializedViewRepository interface {
+	/** QUERY **/
+
+	// ListTopProjects returns a page of the top projects of the ecosystem,
+	// the options are checked against TopProjectListSpec
+	ListTopProjects(ecosystem string, opts sqlutil.ListOptions) (*sqlutil.Page[EcosystemTopProject], error)
+	QueryDistroSummaries() (iter.Seq[*DistroSummary], error)
+	QueryCoverageStats() (iter.Seq[*CoverageStat], error)
+	// GetDistributionChannels returns the channels distributing the repo
+	// link, nil if no channel distributes it
+	GetDistributionChannels(link string) (*DistributionChannels, error)
+
+	/** INSERT/UPDATE **/
+
+	// Refresh refreshes the views without blocking readers, all views are
+	// refreshed if views is empty. Only the owner of the views can refresh
+	// them.
+	Refresh(views ...string) error
+}
+
+// EcosystemTopProject is a row of the top projects per ecosystem view.
+type EcosystemTopProject struct {
+	Ecosystem  *string
+	Rank       *int64
+	GitLink    *string
+	Score      *float64
+	DistScore  *float64
+	DevScore   *float64
+	GitScore   *float64
+	UpdateTime *time.Time
+}
+
+// DistroSummary is a row of the distribution summaries view.
+type DistroSummary struct {
+	Type         *DistType
+	Projects     *int64
+	Dependents   *int64
+	AvgPageRank  *float64
+	AvgDistScore *float64
+	UpdateTime   *time.Time
+}
+
+// CoverageStat is a row of the coverage view, the coverage of the pipeline
+// for an ecosystem, so blind spots can be seen.
+type CoverageStat struct {
+	// Source is distribution or language
+	Source    *string
+	Ecosystem *string
+	// Packages is the number of packages collected, the other counts are
+	// the packages with a resolved git link, with git metrics of it, and
+	// with dependents of it
+	Packages        *int64
+	WithGitLink     *int64
+	WithGitMetrics  *int64
+	WithDependents  *int64
+	GitLinkShare    *float64
+	GitMetricsShare *float64
+	DependentsShare *float64
+	// UpdateTime is the time of the last refresh
+	UpdateTime *time.Time
+}
+
+// TopProjectListSpec is the spec of ListTopProjects, the names of the
+// fields are those of the API. The view keeps the top 1000 projects of
+// every ecosystem.
+var TopProjectListSpec = &sqlutil.ListSpec{
+	Fields: map[string]string{
+		"rank":         "rank",
+		"link":         "git_link",
+		"score":        "score",
+		"distScore":    "dist_score",
+		"langEcoScore": "dev_score",
+		"gitScore":     "git_score",
+		"updateTime":   "update_time",
+	},
+	Sortable:     []string{"score", "distScore", "langEcoScore", "gitScore"},
+	DefaultSort:  "rank",
+	Key:          "rank",
+	DefaultLimit: 100,
+	MaxLimit:     1000,
+}
+
+const (
+	TopProjectsPerEcosystemViewName = "mv_top_projects_per_ecosystem"
+	DistroSummaryViewName           = "mv_distro_summaries"
+	CoverageStatsViewName           = "mv_coverage_stats"
+	DistributionChannelsViewName    = "mv_distribution_channels"
+)
+
+// MaterializedViewNames are all materialized views, in the order of refresh.
+var MaterializedViewNames = []string{
+	TopProjectsPerEcosystemViewName,
+	DistroSummaryViewName,
+	CoverageStatsViewName,
+	DistributionChannelsViewName,
+}
+
+type materializedViewRepository struct {
+	appDb storage.AppDatabaseContext
+}
+
+var _ MaterializedViewRepository = (*materializedViewRepository)(nil)
+
+func NewMaterializedViewRepository(appDb storage.AppDatabaseContext) MaterializedViewRepository {
+	return &materializedViewRepository{appDb: appDb}
+}
+
+// ListTopProjects implements MaterializedViewRepository.
+func (m *materializedViewRepository) ListTopProjects(ecosystem string, opts sqlutil.ListOptions) (*sqlutil.Page[EcosystemTopProject], error) {
+	return sqlutil.List[EcosystemTopProject](m.appDb, TopProjectListSpec, TopProjectsPerEcosystemViewName,
+		"ecosystem = $1", opts, ecosystem)
+}
+
+// QueryDistroSummaries implements MaterializedViewRepository.
+func (m *materializedViewRepository) QueryDistroSummaries() (iter.Seq[*DistroSummary], error) {
+	return sqlutil.QueryCommon[DistroSummary](m.appDb, DistroSummaryViewName, "ORDER BY type")
+}
+
+// QueryCoverageStats implements MaterializedViewRepository.
+func (m *materializedViewRepository) QueryCoverageStats() (iter.Seq[*CoverageStat], error) {
+	return sqlutil.QueryCommon[CoverageStat](m.appDb, CoverageStatsViewName, "ORDER BY source, ecosystem")
+}
+
+// GetDistributionChannels implements MaterializedViewRepository.
+func (m *materializedViewRepository) GetDistributionChannels(link string) (*DistributionChannels, error) {
+	return sqlutil.QueryCommonFirst[DistributionChannels](m.appDb, DistributionChannelsViewName, "WHERE git_link = $1", link)
+}
+
+// Refresh implements MaterializedViewRepository.
+func (m *materializedViewRepository) Refresh(views ...string) error {
+	if len(views) == 0 {
+		views = MaterializedViewNames
+	}
+	var errs []error
+	for _, view := range views {
+		if !lo.Contains(MaterializedViewNames, view) {
+			errs = append(errs, fmt.Errorf("%w: unknown view %s", ErrInvalidInput, view))
+			continue
+		}
+		// CONCURRENTLY requires the unique index created with the view
+		if _, err := m.appDb.Exec("REFRESH MATERIALIZED VIEW CONCURRENTLY " + view); err != nil {
+			errs = append(errs, fmt.Errorf("refresh %s: %w", view, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"fmt"
+	"iter"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+)
+
+type MetricSnapshotRepository interface {
+	/** QUERY **/
+
+	// Query all snapshots taken after since, ordered by link and snapshot time
+	QuerySince(since time.Time) (iter.Seq[*MetricSnapshot], error)
+	QueryByLink(link string) (iter.Seq[*MetricSnapshot], error)
+
+	/** INSERT/UPDATE **/
+
+	BatchInsert(data []*MetricSnapshot) error
+	// TakeSnapshot copies the current dependents and commit frequency
+	// of all links into a new snapshot
+	TakeSnapshot() error
+}
+
+type MetricSnapshot struct {
+	ID              *int64 `pk:"true" generated:"true"`
+	GitLink         *string
+	Dependents      *int
+	CommitFrequency *float64
+	SnapshotTime    *time.Time
+}
+
+const MetricSnapshotTableName = "metric_snapshots"
+
+type metricSnapshotRepository struct {
+	appDb storage.AppDatabaseContext
+}
+
+var _ MetricSnapshotRepository = (*metricSnapshotRepository)(nil)
+
+func NewMetricSnapshotRepository(appDb storage.AppDatabaseContext) MetricSnapshotRepository {
+	return &metricSnapshotRepository{appDb: appDb}
+}
+
+// QuerySince implements MetricSnapshotRepository.
+func (m *metricSnapshotRepository) QuerySince(since time.Time) (iter.Seq[*MetricSnapshot], error) {
+	return sqlutil.QueryCommon[MetricSnapshot](m.appDb, MetricSnapshotTableName,
+		"WHERE snapshot_time >= $1 ORDER BY git_link, snapshot_time", since)
+}
+
+// QueryByLink implements MetricSnapshotRepository.
+func (m *metricSnapshotRepository) QueryByLink(link string) (iter.Seq[*MetricSnapshot], error) {
+	return sqlutil.QueryCommon[MetricSnapshot](m.appDb, MetricSnapshotTableName,
+		"WHERE git_link = $1 ORDER BY snapshot_time", link)
+}
+
+// BatchInsert implements MetricSnapshotRepository.
+func (m *metricSnapshotRepository) BatchInsert(data []*MetricSnapshot) error {
+	for _, d := range data {
+		if d.GitLink == nil || *d.GitLink == "" || d.SnapshotTime == nil {
+			return ErrInvalidInput
+		}
+	}
+	return sqlutil.BatchInsert(m.appDb, MetricSnapshotTableName, data)
+}
+
+// TakeSnapshot implements MetricSnapshotRepository.
+func (m *metricSnapshotRepository) TakeSnapshot() error {
+	_, err := m.appDb.Exec(fmt.Sprintf(`INSERT INTO %s (git_link, dependents, commit_frequency, snapshot_time)
+	SELECT gm.git_link,
+		COALESCE(le.dep_count, 0) + COALESCE(dd.dep_count, 0),
+		gm.commit_frequency,
+		NOW()
+	FROM (SELECT DISTINCT ON (git_link) git_link, commit_frequency
+		FROM %s ORDER BY git_link, id DESC) gm
+	LEFT JOIN (SELECT git_link, SUM(dep_count) AS dep_count
+		FROM (SELECT DISTINCT ON (git_link, type) git_link, dep_count
+			FROM %s ORDER BY git_link, type, id DESC) t
+		GROUP BY git_link) le ON gm.git_link = le.git_link
+	LEFT JOIN (SELECT git_link, SUM(dep_count) AS dep_count
+		FROM (SELECT DISTINCT ON (git_link, type) git_link, dep_count
+			FROM %s ORDER BY git_link, type, id DESC) t
+		GROUP BY git_link) dd ON gm.git_link = dd.git_link`,
+		MetricSnapshotTableName, GitMetricTableName, LangEcosystemTableName, DistDependencyTableName))
+	return err
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"fmt"
+	"iter"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+	"github.com/samber/lo"
+)
+
+type MetricTrendRepository interface {
+	/** QUERY **/
+
+	// Query the latest trend of all links
+	Query() (iter.Seq[*MetricTrend], error)
+	GetByLink(link string) (*MetricTrend, error)
+	// QueryTopMovers returns the latest trends with the highest dependents growth
+	QueryTopMovers(limit int) (iter.Seq[*MetricTrend], error)
+
+	/** INSERT/UPDATE **/
+
+	// NOTE: update_time will be updated automatically
+	BatchInsertOrUpdate(data []*MetricTrend) error
+}
+
+type MetricTrend struct {
+	ID                    *int64 `pk:"true" generated:"true"`
+	GitLink               *string
+	DependentsGrowth      *float64
+	StarsGrowth           *float64
+	ForksGrowth           *float64
+	CommitFrequencyGrowth *float64
+	UpdateTime            *time.Time
+}
+
+const MetricTrendTableName = "metric_trends"
+
+type metricTrendRepository struct {
+	appDb storage.AppDatabaseContext
+}
+
+var _ MetricTrendRepository = (*metricTrendRepository)(nil)
+
+func NewMetricTrendRepository(appDb storage.AppDatabaseContext) MetricTrendRepository {
+	return &metricTrendRepository{appDb: appDb}
+}
+
+func latestMetricTrendQuery() string {
+	return fmt.Sprintf(`(SELECT DISTINCT ON (git_link) * FROM %s ORDER BY git_link, id DESC) t`, MetricTrendTableName)
+}
+
+// Query implements MetricTrendRepository.
+func (m *metricTrendRepository) Query() (iter.Seq[*MetricTrend], error) {
+	return sqlutil.QueryCommon[MetricTrend](m.appDb, latestMetricTrendQuery(), "")
+}
+
+// GetByLink implements MetricTrendRepository.
+func (m *metricTrendRepository) GetByLink(link string) (*MetricTrend, error) {
+	return sqlutil.QueryCommonFirst[MetricTrend](m.appDb, MetricTrendTableName,
+		"WHERE git_link = $1 ORDER BY id DESC", link)
+}
+
+// QueryTopMovers implements MetricTrendRepository.
+func (m *metricTrendRepository) QueryTopMovers(limit int) (iter.Seq[*MetricTrend], error) {
+	return sqlutil.QueryCommon[MetricTrend](m.appDb, latestMetricTrendQuery(),
+		"WHERE dependents_growth IS NOT NULL ORDER BY dependents_growth DESC LIMIT $1", limit)
+}
+
+// BatchInsertOrUpdate implements MetricTrendRepository.
+func (m *metricTrendRepository) BatchInsertOrUpdate(data []*MetricTrend) error {
+	for _, d := range data {
+		if d.GitLink == nil || *d.GitLink == "" {
+			return ErrInvalidInput
+		}
+		d.UpdateTime = lo.ToPtr(time.Now())
+	}
+	return sqlutil.BatchInsert(m.appDb, MetricTrendTableName, data)
+}
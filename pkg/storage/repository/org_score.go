@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+	"github.com/samber/lo"
+)
+
+// OrgScoreRepository stores the scores of the projects aggregated by their
+// owners, see package orgscore.
+type OrgScoreRepository interface {
+	/** QUERY **/
+
+	// List returns a page of the org scores of the latest run, the options
+	// are checked against OrgScoreListSpec
+	List(opts sqlutil.ListOptions) (*sqlutil.Page[OrgScore], error)
+
+	/** INSERT/UPDATE **/
+
+	// NOTE: update_time will be updated automatically
+	BatchInsert(data []*OrgScore) error
+}
+
+type OrgScore struct {
+	RunID *string `pk:"true"`
+	Owner *string `pk:"true"`
+	Rank  *int
+	// Projects is the number of scored projects of the owner, TopProjects
+	// the number of them among the top projects of all owners
+	Projects    *int
+	ScoreSum    *float64
+	ScoreMax    *float64
+	TopLink     *string
+	TopProjects *int
+	UpdateTime  *time.Time
+}
+
+const OrgScoreTableName = "org_scores"
+
+// OrgScoreListSpec is the spec of OrgScoreRepository.List, the names of
+// the fields are those of the API.
+var OrgScoreListSpec = &sqlutil.ListSpec{
+	Fields: map[string]string{
+		"rank":        "rank",
+		"owner":       "owner",
+		"projects":    "projects",
+		"scoreSum":    "score_sum",
+		"scoreMax":    "score_max",
+		"topLink":     "top_link",
+		"topProjects": "top_projects",
+		"updateTime":  "update_time",
+	},
+	Sortable:     []string{"rank", "projects", "scoreSum", "scoreMax", "topProjects"},
+	DefaultSort:  "rank",
+	Key:          "owner",
+	DefaultLimit: 100,
+	MaxLimit:     1000,
+}
+
+// latestOrgScores are the org scores of the latest run, run ids sort by
+// time.
+var latestOrgScores = `(SELECT * FROM ` + OrgScoreTableName + `
+	WHERE run_id = (SELECT MAX(run_id) FROM ` + OrgScoreTableName + `)) o`
+
+type orgScoreRepository struct {
+	appDb storage.AppDatabaseContext
+}
+
+var _ OrgScoreRepository = (*orgScoreRepository)(nil)
+
+func NewOrgScoreRepository(appDb storage.AppDatabaseContext) OrgScoreRepository {
+	return &orgScoreRepository{appDb: appDb}
+}
+
+// List implements OrgScoreRepository.
+func (o *orgScoreRepository) List(opts sqlutil.ListOptions) (*sqlutil.Page[OrgScore], error) {
+	return sqlutil.List[OrgScore](o.appDb, OrgScoreListSpec, latestOrgScores, "", opts)
+}
+
+// BatchInsert implements OrgScoreRepository.
+func (o *orgScoreRepository) BatchInsert(data []*OrgScore) error {
+	for _, d := range data {
+		if d.RunID == nil || *d.RunID == "" || d.Owner == nil || *d.Owner == "" {
+			return ErrInvalidInput
+		}
+		d.UpdateTime = lo.ToPtr(time.Now())
+	}
+	return sqlutil.BatchInsert(o.appDb, OrgScoreTableName, data)
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"iter"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+)
+
+type PageRankRunRepository interface {
+	/** QUERY **/
+
+	// QueryByCollector returns the runs of a collector, the latest first
+	QueryByCollector(collector string) (iter.Seq[*PageRankRun], error)
+
+	/** INSERT/UPDATE **/
+
+	Insert(data *PageRankRun) error
+}
+
+// PageRankRun is the PageRank parameters of a run of a collector, see
+// graph.PageRankParams.
+type PageRankRun struct {
+	ID         *int64 `pk:"true" generated:"true"`
+	Collector  *string
+	Iterations *int
+	Damping    *float64
+	Epsilon    *float64
+	Weighting  *string
+	// Packages is the number of ranked packages
+	Packages *int
+	RunTime  *time.Time
+}
+
+const PageRankRunTableName = "page_rank_runs"
+
+type pageRankRunRepository struct {
+	appDb storage.AppDatabaseContext
+}
+
+var _ PageRankRunRepository = (*pageRankRunRepository)(nil)
+
+func NewPageRankRunRepository(appDb storage.AppDatabaseContext) PageRankRunRepository {
+	return &pageRankRunRepository{appDb: appDb}
+}
+
+// QueryByCollector implements PageRankRunRepository.
+func (r *pageRankRunRepository) QueryByCollector(collector string) (iter.Seq[*PageRankRun], error) {
+	return sqlutil.QueryCommon[PageRankRun](r.appDb, PageRankRunTableName,
+		"WHERE collector = $1 ORDER BY run_time DESC", collector)
+}
+
+// Insert implements PageRankRunRepository.
+func (r *pageRankRunRepository) Insert(data *PageRankRun) error {
+	if data.Collector == nil || *data.Collector == "" || data.Iterations == nil || data.Damping == nil ||
+		data.Epsilon == nil || data.Weighting == nil || data.Packages == nil || data.RunTime == nil {
+		return ErrInvalidInput
+	}
+	return sqlutil.Insert(r.appDb, PageRankRunTableName, data)
+}
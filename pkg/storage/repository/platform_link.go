@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
 )
 
 type PlatformLinkRepository interface {
@@ -36,13 +37,23 @@ func NewPlatformLinkRepository(appDb storage.AppDatabaseContext, platform Platfo
 	}
 }
 
-func getPlatformTableName(platform PlatformLinkTablePrefix) string {
-	return fmt.Sprintf("%s_links", platform)
+func (r *platformLinkRepository) tables() (table string, tmp string, err error) {
+	name := PlatformLinkTableName(r.Platform)
+	if table, err = sqlutil.Table(name); err != nil {
+		return "", "", err
+	}
+	if tmp, err = sqlutil.Table(name + "_tmp"); err != nil {
+		return "", "", err
+	}
+	return table, tmp, nil
 }
 
 func (r *platformLinkRepository) IsLinkInPlatform(link string) (bool, error) {
-	query := fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s WHERE link = $1)`, getPlatformTableName(r.Platform))
-	row := r.AppDb.QueryRow(query, link)
+	query, args, err := sqlutil.From(PlatformLinkTableName(r.Platform)).Where("git_link", link).Exists()
+	if err != nil {
+		return false, err
+	}
+	row := r.AppDb.QueryRow(query, args...)
 
 	var exists bool
 	if err := row.Scan(&exists); err != nil {
@@ -53,12 +64,15 @@ func (r *platformLinkRepository) IsLinkInPlatform(link string) (bool, error) {
 }
 
 func (r *platformLinkRepository) BeginTemp() error {
-	tn := getPlatformTableName(r.Platform)
+	tn, tmp, err := r.tables()
+	if err != nil {
+		return err
+	}
 	query := fmt.Sprintf(`
-		DROP TABLE IF EXSITS %s_tmp;
-		CREATE TABLE %s_tmp AS TABLE %s WITH NO DATA;
-	`, tn, tn, tn)
-	_, err := r.AppDb.Exec(query)
+		DROP TABLE IF EXISTS %s;
+		CREATE TABLE %s AS TABLE %s WITH NO DATA;
+	`, tmp, tmp, tn)
+	_, err = r.AppDb.Exec(query)
 	return err
 }
 
@@ -67,29 +81,30 @@ func (r *platformLinkRepository) BatchInsertTemp(links []string) error {
 	if len(links) == 0 {
 		return nil
 	}
-	query := fmt.Sprintf(`INSERT INTO %s_tmp (git_link) VALUES`, getPlatformTableName(r.Platform))
-	args := make([]interface{}, 0, len(links))
-	for i, link := range links {
-		if i == 0 {
-			query += fmt.Sprintf(`($%d)`, i+1)
-		} else {
-			query += fmt.Sprintf(`, ($%d)`, i+1)
-		}
-		args = append(args, link)
+	rows := make([][]interface{}, 0, len(links))
+	for _, link := range links {
+		rows = append(rows, []interface{}{link})
+	}
+	query, args, err := sqlutil.From(PlatformLinkTableName(r.Platform)+"_tmp").InsertValues([]string{"git_link"}, rows)
+	if err != nil {
+		return err
 	}
-	_, err := r.AppDb.Exec(query, args...)
+	_, err = r.AppDb.Exec(query, args...)
 	return err
 }
 
 // CommitTemp implements PlatformLinkRepository.
 func (r *platformLinkRepository) CommitTemp() error {
-	tn := getPlatformTableName(r.Platform)
+	tn, tmp, err := r.tables()
+	if err != nil {
+		return err
+	}
 	query := fmt.Sprintf(`
-		DELETE FROM %s;
-		INSERT INTO %s (SELECT * FROM %s_tmp);
-		DROP TABLE %s_tmp;
-	`, tn, tn, tn, tn)
-	_, err := r.AppDb.Exec(query)
+		DELETE FROM %[1]s;
+		INSERT INTO %[1]s (SELECT * FROM %[2]s);
+		DROP TABLE %[2]s;
+	`, tn, tmp)
+	_, err = r.AppDb.Exec(query)
 	return err
 
 }
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"iter"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+	"github.com/lib/pq"
+)
+
+// PopularitySnapshotRepository stores the stars and forks of the repos, a
+// snapshot per collection, the time series of the growth rates and the
+// charts of the web UI.
+type PopularitySnapshotRepository interface {
+	/** QUERY **/
+
+	// Query all snapshots taken after since, ordered by link and snapshot time
+	QuerySince(since time.Time) (iter.Seq[*PopularitySnapshot], error)
+	QueryByLink(link string) (iter.Seq[*PopularitySnapshot], error)
+
+	/** INSERT/UPDATE **/
+
+	BatchInsert(data []*PopularitySnapshot) error
+	// Delete deletes the snapshots of ids, see trend.Compact
+	Delete(ids []int64) error
+}
+
+type PopularitySnapshot struct {
+	ID           *int64 `pk:"true" generated:"true"`
+	GitLink      *string
+	Stars        *int
+	Forks        *int
+	SnapshotTime *time.Time
+}
+
+const PopularitySnapshotTableName = "popularity_snapshots"
+
+type popularitySnapshotRepository struct {
+	appDb storage.AppDatabaseContext
+}
+
+var _ PopularitySnapshotRepository = (*popularitySnapshotRepository)(nil)
+
+func NewPopularitySnapshotRepository(appDb storage.AppDatabaseContext) PopularitySnapshotRepository {
+	return &popularitySnapshotRepository{appDb: appDb}
+}
+
+// QuerySince implements PopularitySnapshotRepository.
+func (p *popularitySnapshotRepository) QuerySince(since time.Time) (iter.Seq[*PopularitySnapshot], error) {
+	return sqlutil.QueryCommon[PopularitySnapshot](p.appDb, PopularitySnapshotTableName,
+		"WHERE snapshot_time >= $1 ORDER BY git_link, snapshot_time", since)
+}
+
+// QueryByLink implements PopularitySnapshotRepository.
+func (p *popularitySnapshotRepository) QueryByLink(link string) (iter.Seq[*PopularitySnapshot], error) {
+	return sqlutil.QueryCommon[PopularitySnapshot](p.appDb, PopularitySnapshotTableName,
+		"WHERE git_link = $1 ORDER BY snapshot_time", link)
+}
+
+// BatchInsert implements PopularitySnapshotRepository.
+func (p *popularitySnapshotRepository) BatchInsert(data []*PopularitySnapshot) error {
+	for _, d := range data {
+		if d.GitLink == nil || *d.GitLink == "" || d.SnapshotTime == nil {
+			return ErrInvalidInput
+		}
+	}
+	return sqlutil.BatchInsert(p.appDb, PopularitySnapshotTableName, data)
+}
+
+// Delete implements PopularitySnapshotRepository.
+func (p *popularitySnapshotRepository) Delete(ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := p.appDb.Exec(`DELETE FROM `+PopularitySnapshotTableName+` WHERE id = ANY($1::bigint[])`, pq.Array(ids))
+	return err
+}
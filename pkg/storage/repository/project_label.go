@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"iter"
+	"slices"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+	"github.com/lib/pq"
+)
+
+// ProjectLabelRepository stores the country and affiliation labels of the
+// projects, see package affiliation. Labels are versioned by source: every
+// Replace is a new version of its source and retires the labels of the
+// previous one.
+type ProjectLabelRepository interface {
+	/** QUERY **/
+
+	// QueryByLink returns the current labels of a project
+	QueryByLink(link string) (iter.Seq[*ProjectLabel], error)
+	// QueryCurrent returns the current labels of all projects, ordered by
+	// link
+	QueryCurrent() (iter.Seq[*ProjectLabel], error)
+	// QueryHistory returns every label a project ever had, the latest first
+	QueryHistory(link string) (iter.Seq[*ProjectLabel], error)
+	// QuerySources returns the sources with their latest versions
+	QuerySources() ([]*ProjectLabelSource, error)
+
+	/** INSERT/UPDATE **/
+
+	// Replace retires the current labels of source and inserts labels as
+	// its next version in one transaction, returning the version
+	Replace(source string, labels []*ProjectLabel) (int, error)
+	// Retire retires the current labels of source
+	Retire(source string) error
+}
+
+type ProjectLabel struct {
+	ID      *int64 `pk:"true" generated:"true"`
+	GitLink *string
+	// Kind is affiliation.Kind, country or affiliation
+	Kind       *string
+	Value      *string
+	Confidence *float64
+	// Source is where the label comes from, the name given to an import of
+	// a csv file or the source of an affiliation.Heuristic
+	Source    *string
+	Version   *int
+	CreatedAt *time.Time
+	// RetiredAt is when a later version of the source replaced the label,
+	// nil for current labels
+	RetiredAt *time.Time
+}
+
+// ProjectLabelSource is a source of labels with its latest version.
+type ProjectLabelSource struct {
+	Source    *string
+	Version   *int
+	Labels    *int
+	CreatedAt *time.Time
+	// RetiredAt is set if the labels of the source are all retired
+	RetiredAt *time.Time
+}
+
+const ProjectLabelTableName = "project_labels"
+
+type projectLabelRepository struct {
+	appDb storage.AppDatabaseContext
+}
+
+var _ ProjectLabelRepository = (*projectLabelRepository)(nil)
+
+func NewProjectLabelRepository(appDb storage.AppDatabaseContext) ProjectLabelRepository {
+	return &projectLabelRepository{appDb: appDb}
+}
+
+// QueryByLink implements ProjectLabelRepository.
+func (p *projectLabelRepository) QueryByLink(link string) (iter.Seq[*ProjectLabel], error) {
+	return sqlutil.QueryCommon[ProjectLabel](p.appDb, ProjectLabelTableName,
+		"WHERE git_link = $1 AND retired_at IS NULL ORDER BY kind, confidence DESC, source", link)
+}
+
+// QueryCurrent implements ProjectLabelRepository.
+func (p *projectLabelRepository) QueryCurrent() (iter.Seq[*ProjectLabel], error) {
+	return sqlutil.QueryCommon[ProjectLabel](p.appDb, ProjectLabelTableName,
+		"WHERE retired_at IS NULL ORDER BY git_link, kind, confidence DESC, source")
+}
+
+// QueryHistory implements ProjectLabelRepository.
+func (p *projectLabelRepository) QueryHistory(link string) (iter.Seq[*ProjectLabel], error) {
+	return sqlutil.QueryCommon[ProjectLabel](p.appDb, ProjectLabelTableName,
+		"WHERE git_link = $1 ORDER BY created_at DESC, source, kind", link)
+}
+
+// QuerySources implements ProjectLabelRepository.
+func (p *projectLabelRepository) QuerySources() ([]*ProjectLabelSource, error) {
+	sources, err := sqlutil.Query[ProjectLabelSource](p.appDb, `SELECT DISTINCT ON (source)
+			source, version, COUNT(*) OVER (PARTITION BY source, version) AS labels,
+			created_at, retired_at
+		FROM `+ProjectLabelTableName+`
+		ORDER BY source, version DESC`)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Collect(sources), nil
+}
+
+// Replace implements ProjectLabelRepository.
+func (p *projectLabelRepository) Replace(source string, labels []*ProjectLabel) (int, error) {
+	if source == "" {
+		return 0, ErrInvalidInput
+	}
+	for _, l := range labels {
+		if l.GitLink == nil || l.Kind == nil || l.Value == nil || l.Confidence == nil {
+			return 0, ErrInvalidInput
+		}
+	}
+
+	db, err := p.appDb.GetDatabaseConnection()
+	if err != nil {
+		return 0, err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// locks the source so concurrent imports do not take the same version
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext($1))`, ProjectLabelTableName+"/"+source); err != nil {
+		return 0, err
+	}
+	var version int
+	err = tx.QueryRow(`SELECT COALESCE(MAX(version), 0) + 1 FROM `+ProjectLabelTableName+` WHERE source = $1`,
+		source).Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	_, err = tx.Exec(`UPDATE `+ProjectLabelTableName+` SET retired_at = $2 WHERE source = $1 AND retired_at IS NULL`,
+		source, now)
+	if err != nil {
+		return 0, err
+	}
+	if len(labels) > 0 {
+		links := make([]string, len(labels))
+		kinds := make([]string, len(labels))
+		values := make([]string, len(labels))
+		confidences := make([]float64, len(labels))
+		for i, l := range labels {
+			links[i], kinds[i], values[i], confidences[i] = *l.GitLink, *l.Kind, *l.Value, *l.Confidence
+		}
+		_, err = tx.Exec(`INSERT INTO `+ProjectLabelTableName+`
+				(git_link, kind, value, confidence, source, version, created_at)
+			SELECT git_link, kind, value, confidence, $1, $2, $3
+			FROM UNNEST($4::text[], $5::text[], $6::text[], $7::float8[])
+				AS t(git_link, kind, value, confidence)`,
+			source, version, now, pq.Array(links), pq.Array(kinds), pq.Array(values), pq.Array(confidences))
+		if err != nil {
+			return 0, err
+		}
+	}
+	return version, tx.Commit()
+}
+
+// Retire implements ProjectLabelRepository.
+func (p *projectLabelRepository) Retire(source string) error {
+	if source == "" {
+		return ErrInvalidInput
+	}
+	_, err := p.appDb.Exec(`UPDATE `+ProjectLabelTableName+` SET retired_at = $2 WHERE source = $1 AND retired_at IS NULL`,
+		source, time.Now())
+	return err
+}
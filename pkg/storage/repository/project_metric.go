@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+)
+
+// ProjectMetricRepository reads the published metrics and scores of the
+// projects, the rows of git_metrics_prod with a score.
+type ProjectMetricRepository interface {
+	/** QUERY **/
+
+	// List returns a page of the projects, only the projects of tag if it
+	// is not empty. The options are checked against ProjectMetricListSpec.
+	List(tag string, opts sqlutil.ListOptions) (*sqlutil.Page[ProjectMetric], error)
+	// Count returns the number of the projects listed by List.
+	Count(tag string) (int, error)
+}
+
+// ProjectMetric is a published project, Industry is the class of the
+// industry and Domestic is of the git repository. DistributionBreadth is
+// the number of channels distributing it, see DistributionChannels.
+type ProjectMetric struct {
+	GitLink             *string
+	Ecosystem           *string
+	CreatedSince        *time.Time
+	UpdatedSince        *time.Time
+	ContributorCount    *int
+	OrgCount            *int
+	CommitFrequency     *float64
+	DepsdevCount        *int
+	DistImpact          *float64
+	License             *string
+	Language            *string
+	Industry            *string
+	Domestic            *bool
+	Scores              *float64
+	MaintenanceRisk     *string
+	DistributionBreadth *int
+}
+
+// ProjectMetricListSpec is the spec of ProjectMetricRepository.List, the
+// names of the fields are those of the API.
+var ProjectMetricListSpec = &sqlutil.ListSpec{
+	Fields: map[string]string{
+		"link":                "git_link",
+		"ecosystems":          "ecosystem",
+		"createdSince":        "created_since",
+		"updatedSince":        "updated_since",
+		"contributorCount":    "contributor_count",
+		"orgCount":            "org_count",
+		"commitFrequency":     "commit_frequency",
+		"depsDevCount":        "depsdev_count",
+		"depsDistroScore":     "dist_impact",
+		"license":             "license",
+		"language":            "language",
+		"industry":            "industry",
+		"domestic":            "domestic",
+		"score":               "scores",
+		"maintenanceRisk":     "maintenance_risk",
+		"distributionBreadth": "distribution_breadth",
+	},
+	Sortable: []string{
+		"score", "createdSince", "updatedSince", "contributorCount", "orgCount",
+		"commitFrequency", "depsDevCount", "depsDistroScore", "distributionBreadth",
+	},
+	DefaultSort:  "-score",
+	Key:          "link",
+	DefaultLimit: 100,
+	MaxLimit:     10000,
+}
+
+// projectMetricsFrom joins the domestic flag and the distribution breadth
+// and classifies the industry, so every field of the spec is a column.
+const projectMetricsFrom = `(SELECT
+		gm.git_link,
+		gm.ecosystem,
+		gm.created_since,
+		gm.updated_since,
+		gm.contributor_count,
+		gm.org_count,
+		gm.commit_frequency,
+		gm.depsdev_count,
+		gm.dist_impact,
+		gm.license,
+		gm.language,
+		CASE WHEN gm.industry IS NULL
+			THEN 'unknown'
+			WHEN gm.industry = 0
+			THEN 'test0'
+			ELSE 'other'
+		END AS industry,
+		gr.domestic,
+		gm.scores,
+		gm.maintenance_risk,
+		dc.breadth AS distribution_breadth
+	FROM ` + GitMetricProdTableName + ` gm
+	LEFT JOIN ` + GitRepositoryTableName + ` gr ON gm.git_link = gr.git_link
+	LEFT JOIN ` + DistributionChannelsViewName + ` dc ON gm.git_link = dc.git_link
+	WHERE gm.scores IS NOT NULL) pm`
+
+// an empty tag matches all projects
+const projectMetricsTagFilter = `$1 = '' OR git_link IN (SELECT git_link FROM ` + ProjectTagTableName + ` WHERE tag = $1)`
+
+type projectMetricRepository struct {
+	appDb storage.AppDatabaseContext
+}
+
+var _ ProjectMetricRepository = (*projectMetricRepository)(nil)
+
+func NewProjectMetricRepository(appDb storage.AppDatabaseContext) ProjectMetricRepository {
+	return &projectMetricRepository{appDb: appDb}
+}
+
+// List implements ProjectMetricRepository.
+func (r *projectMetricRepository) List(tag string, opts sqlutil.ListOptions) (*sqlutil.Page[ProjectMetric], error) {
+	return sqlutil.List[ProjectMetric](r.appDb, ProjectMetricListSpec, projectMetricsFrom, projectMetricsTagFilter, opts, tag)
+}
+
+// Count implements ProjectMetricRepository.
+func (r *projectMetricRepository) Count(tag string) (int, error) {
+	return sqlutil.Count(r.appDb, projectMetricsFrom, projectMetricsTagFilter, tag)
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"iter"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+	"github.com/lib/pq"
+)
+
+type ProjectTagRepository interface {
+	/** QUERY **/
+
+	Query() (iter.Seq[*ProjectTag], error)
+	// QueryTags returns every tag with the number of its links
+	QueryTags() (map[string]int, error)
+	// QueryLinks returns the links with any of the tags
+	QueryLinks(tags []string) ([]string, error)
+
+	/** INSERT/UPDATE **/
+
+	// AddLinks tags links, links already tagged are ignored
+	AddLinks(tag string, links []string) error
+	// RemoveLinks untags links, all links are untagged if links is nil
+	RemoveLinks(tag string, links []string) error
+}
+
+// ProjectTag puts a project into a named list, e.g. the dependencies of a
+// company, so it can be collected and reported separately.
+type ProjectTag struct {
+	Tag       *string    `pk:"true"`
+	GitLink   *string    `pk:"true"`
+	CreatedAt *time.Time `generated:"true"`
+}
+
+const ProjectTagTableName = "project_tags"
+
+type projectTagRepository struct {
+	appDb storage.AppDatabaseContext
+}
+
+var _ ProjectTagRepository = (*projectTagRepository)(nil)
+
+func NewProjectTagRepository(appDb storage.AppDatabaseContext) ProjectTagRepository {
+	return &projectTagRepository{appDb: appDb}
+}
+
+// Query implements ProjectTagRepository.
+func (p *projectTagRepository) Query() (iter.Seq[*ProjectTag], error) {
+	return sqlutil.QueryCommon[ProjectTag](p.appDb, ProjectTagTableName, "ORDER BY tag, git_link")
+}
+
+// QueryTags implements ProjectTagRepository.
+func (p *projectTagRepository) QueryTags() (map[string]int, error) {
+	rows, err := p.appDb.Query(`SELECT tag, COUNT(*) FROM ` + ProjectTagTableName + ` GROUP BY tag`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ret := make(map[string]int)
+	for rows.Next() {
+		var tag string
+		var count int
+		if err := rows.Scan(&tag, &count); err != nil {
+			return nil, err
+		}
+		ret[tag] = count
+	}
+	return ret, rows.Err()
+}
+
+// QueryLinks implements ProjectTagRepository.
+func (p *projectTagRepository) QueryLinks(tags []string) ([]string, error) {
+	rows, err := p.appDb.Query(`SELECT DISTINCT git_link FROM `+ProjectTagTableName+`
+		WHERE tag = ANY($1::text[]) ORDER BY git_link`, pq.Array(tags))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ret := make([]string, 0)
+	for rows.Next() {
+		var link string
+		if err := rows.Scan(&link); err != nil {
+			return nil, err
+		}
+		ret = append(ret, link)
+	}
+	return ret, rows.Err()
+}
+
+// AddLinks implements ProjectTagRepository.
+func (p *projectTagRepository) AddLinks(tag string, links []string) error {
+	if tag == "" {
+		return ErrInvalidInput
+	}
+	if len(links) == 0 {
+		return nil
+	}
+	_, err := p.appDb.Exec(`INSERT INTO `+ProjectTagTableName+` (tag, git_link)
+		SELECT $1, UNNEST($2::text[])
+		ON CONFLICT (tag, git_link) DO NOTHING`, tag, pq.Array(links))
+	return err
+}
+
+// RemoveLinks implements ProjectTagRepository.
+func (p *projectTagRepository) RemoveLinks(tag string, links []string) error {
+	if tag == "" {
+		return ErrInvalidInput
+	}
+	if links == nil {
+		_, err := p.appDb.Exec(`DELETE FROM `+ProjectTagTableName+` WHERE tag = $1`, tag)
+		return err
+	}
+	_, err := p.appDb.Exec(`DELETE FROM `+ProjectTagTableName+`
+		WHERE tag = $1 AND git_link = ANY($2::text[])`, tag, pq.Array(links))
+	return err
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"iter"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+)
+
+type RawResponseRepository interface {
+	/** QUERY **/
+
+	// QueryByRun returns the responses archived by a run, in the order
+	// they were fetched
+	QueryByRun(runID string) (iter.Seq[*RawResponse], error)
+	// QueryByURL returns the responses of url archived by a run
+	QueryByURL(runID string, url string) (iter.Seq[*RawResponse], error)
+
+	/** INSERT/UPDATE **/
+
+	Insert(data *RawResponse) error
+	// DeleteRun removes all responses archived by a run
+	DeleteRun(runID string) error
+}
+
+// RawResponse is a raw api response archived by a run of a collector, the
+// request and the body are gzip compressed, see package rawresponse.
+type RawResponse struct {
+	ID         *int64 `pk:"true" generated:"true"`
+	RunID      *string
+	Method     *string
+	URL        *string `column:"url"`
+	Request    *[]byte
+	StatusCode *int
+	Body       *[]byte
+	FetchedAt  *time.Time
+}
+
+const RawResponseTableName = "raw_responses"
+
+type rawResponseRepository struct {
+	appDb storage.AppDatabaseContext
+}
+
+var _ RawResponseRepository = (*rawResponseRepository)(nil)
+
+func NewRawResponseRepository(appDb storage.AppDatabaseContext) RawResponseRepository {
+	return &rawResponseRepository{appDb: appDb}
+}
+
+// QueryByRun implements RawResponseRepository.
+func (r *rawResponseRepository) QueryByRun(runID string) (iter.Seq[*RawResponse], error) {
+	return sqlutil.QueryCommon[RawResponse](r.appDb, RawResponseTableName,
+		"WHERE run_id = $1 ORDER BY id", runID)
+}
+
+// QueryByURL implements RawResponseRepository.
+func (r *rawResponseRepository) QueryByURL(runID string, url string) (iter.Seq[*RawResponse], error) {
+	return sqlutil.QueryCommon[RawResponse](r.appDb, RawResponseTableName,
+		"WHERE run_id = $1 AND url = $2 ORDER BY id", runID, url)
+}
+
+// Insert implements RawResponseRepository.
+func (r *rawResponseRepository) Insert(data *RawResponse) error {
+	if data.RunID == nil || *data.RunID == "" || data.Method == nil || data.URL == nil ||
+		data.StatusCode == nil || data.Body == nil || data.FetchedAt == nil {
+		return ErrInvalidInput
+	}
+	return sqlutil.Insert(r.appDb, RawResponseTableName, data)
+}
+
+// DeleteRun implements RawResponseRepository.
+func (r *rawResponseRepository) DeleteRun(runID string) error {
+	_, err := r.appDb.Exec(`DELETE FROM `+RawResponseTableName+` WHERE run_id = $1`, runID)
+	return err
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"iter"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+)
+
+type RepoArchiveRepository interface {
+	/** QUERY **/
+
+	// Query all archives of the link, ordered by archive time
+	QueryByLink(link string) (iter.Seq[*RepoArchive], error)
+	// Query the latest archive of the link, nil if it is never archived
+	QueryLatest(link string) (*RepoArchive, error)
+
+	/** INSERT/UPDATE **/
+
+	Insert(data *RepoArchive) error
+}
+
+// RepoArchive is a git bundle snapshot of a repo.
+type RepoArchive struct {
+	ID         *int64 `pk:"true" generated:"true"`
+	GitLink    *string
+	ArchivedAt *time.Time
+	// RefsHash is the sha256 of all refs of the repo, an archive is only
+	// created if the refs changed
+	RefsHash *string
+	// Sha256 is the sha256 of the bundle file
+	Sha256   *string `column:"sha256"`
+	Size     *int64
+	Location *string
+}
+
+const RepoArchiveTableName = "repo_archives"
+
+type repoArchiveRepository struct {
+	appDb storage.AppDatabaseContext
+}
+
+var _ RepoArchiveRepository = (*repoArchiveRepository)(nil)
+
+func NewRepoArchiveRepository(appDb storage.AppDatabaseContext) RepoArchiveRepository {
+	return &repoArchiveRepository{appDb: appDb}
+}
+
+// QueryByLink implements RepoArchiveRepository.
+func (r *repoArchiveRepository) QueryByLink(link string) (iter.Seq[*RepoArchive], error) {
+	return sqlutil.QueryCommon[RepoArchive](r.appDb, RepoArchiveTableName,
+		"WHERE git_link = $1 ORDER BY archived_at", link)
+}
+
+// QueryLatest implements RepoArchiveRepository.
+func (r *repoArchiveRepository) QueryLatest(link string) (*RepoArchive, error) {
+	return sqlutil.QueryCommonFirst[RepoArchive](r.appDb, RepoArchiveTableName,
+		"WHERE git_link = $1 ORDER BY archived_at DESC", link)
+}
+
+// Insert implements RepoArchiveRepository.
+func (r *repoArchiveRepository) Insert(data *RepoArchive) error {
+	if data.GitLink == nil || *data.GitLink == "" || data.ArchivedAt == nil ||
+		data.RefsHash == nil || data.Sha256 == nil || data.Location == nil {
+		return ErrInvalidInput
+	}
+	return sqlutil.Insert(r.appDb, RepoArchiveTableName, data)
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"iter"
+	"slices"
 	"time"
 
 	"github.com/HUSTSecLab/criticality_score/pkg/storage"
@@ -13,7 +14,16 @@ type ScoreRepository interface {
 	/** QUERY **/
 
 	Query() (iter.Seq[*Score], error)
-	GetByGitLink(distID int64) (*Score, error)
+	// GetByGitLink returns the latest score of a repo
+	GetByGitLink(link string) (*Score, error)
+	// GetRank returns the rank of the latest score of a repo among the
+	// latest scores of all repos, 1 is the highest, and the number of
+	// ranked repos. The rank is 0 if the repo has no score.
+	GetRank(link string) (rank int, total int, err error)
+	// QueryRuns returns the scoring runs, the latest first
+	QueryRuns() ([]*ScoreRun, error)
+	// QueryByRun returns the scores of a scoring run
+	QueryByRun(runID string) (iter.Seq[*Score], error)
 
 	/** INSERT/UPDATE **/
 
@@ -38,6 +48,35 @@ type Score struct {
 	GitScore   *float64
 	Score      *float64
 	UpdateTime *time.Time
+	// Profile is the scoring profile of the weights
+	Profile *string
+	// Language is the primary language, Distro is the source distribution
+	// and DistroCount is the number of distributions packaging the project
+	Language    *string
+	Distro      *string
+	DistroCount *int
+	// Percentile is the percentage of projects scored the same or lower,
+	// overall and within Language and Distro
+	Percentile         *float64
+	LanguagePercentile *float64
+	DistroPercentile   *float64
+	// GitDiscount and LangEcoDiscount are the factors of GitScore and
+	// DevScore by the freshness and the confidence of their signals
+	GitDiscount     *float64
+	LangEcoDiscount *float64
+	// RunID is the scoring run of the score, nil for scores of single
+	// projects, e.g. by git-metrics-fixer. ConfigHash is the hash of the
+	// config of the scorer, see score.Config
+	RunID      *string
+	ConfigHash *string
+}
+
+// ScoreRun is a scoring run.
+type ScoreRun struct {
+	RunID *string
+	// UpdateTime is the time the scores of the run were stored
+	UpdateTime *time.Time
+	Projects   *int
 }
 
 const ScoreTableName = "scores"
@@ -57,11 +96,46 @@ func (s *scoreRepository) BatchInsertOrUpdate(scores []*Score) error {
 	return sqlutil.BatchInsert(s.appDb, ScoreTableName, scores)
 }
 
-// BatchIntLink implements ScoreRepository.
-func (s *scoreRepository) GetByGitLink(distID int64) (*Score, error) {
+// GetByGitLink implements ScoreRepository.
+func (s *scoreRepository) GetByGitLink(link string) (*Score, error) {
 	return sqlutil.QueryCommonFirst[Score](s.appDb, ScoreTableName,
-		`WHERE git_link = $1 ORDER BY DESC ID`,
-		distID)
+		`WHERE git_link = $1 ORDER BY id DESC`,
+		link)
+}
+
+// GetRank implements ScoreRepository.
+func (s *scoreRepository) GetRank(link string) (int, int, error) {
+	var rank, total int
+	err := s.appDb.QueryRow(`WITH latest AS (
+			SELECT DISTINCT ON (git_link) git_link, score FROM `+ScoreTableName+`
+			WHERE score IS NOT NULL
+			ORDER BY git_link, id DESC
+		)
+		SELECT COALESCE((SELECT COUNT(*) + 1 FROM latest
+				WHERE score > (SELECT score FROM latest WHERE git_link = $1)), 0),
+			(SELECT COUNT(*) FROM latest)`, link).Scan(&rank, &total)
+	return rank, total, err
+}
+
+// QueryRuns implements ScoreRepository.
+func (s *scoreRepository) QueryRuns() ([]*ScoreRun, error) {
+	runs, err := sqlutil.Query[ScoreRun](s.appDb, `SELECT run_id, MAX(update_time) AS update_time, COUNT(*) AS projects
+		FROM `+ScoreTableName+`
+		WHERE run_id IS NOT NULL
+		GROUP BY run_id
+		ORDER BY MAX(update_time) DESC, run_id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Collect(runs), nil
+}
+
+// QueryByRun implements ScoreRepository.
+func (s *scoreRepository) QueryByRun(runID string) (iter.Seq[*Score], error) {
+	if runID == "" {
+		return nil, ErrInvalidInput
+	}
+	return sqlutil.QueryCommon[Score](s.appDb, ScoreTableName, `WHERE run_id = $1`, runID)
 }
 
 // InsertOrUpdate implements ScoreRepository.
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"iter"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+)
+
+// ScoreSnapshotRepository stores the config and the inputs of scoring runs,
+// to replay them.
+type ScoreSnapshotRepository interface {
+	/** QUERY **/
+
+	// GetByRunID returns the snapshot of a run, nil if the run has none
+	GetByRunID(runID string) (*ScoreSnapshot, error)
+	// QueryInputs returns the inputs of the projects of a run
+	QueryInputs(runID string) (iter.Seq[*ScoreSnapshotInput], error)
+
+	/** INSERT/UPDATE **/
+
+	// Insert stores the snapshot and the inputs of a run
+	Insert(snapshot *ScoreSnapshot, inputs []*ScoreSnapshotInput) error
+}
+
+type ScoreSnapshot struct {
+	RunID *string `pk:"true"`
+	// StartedAt is the time the scores of the run are computed at
+	StartedAt  *time.Time
+	ConfigHash *string
+	// Config is the json of the config of the scorer, DistCounts the json
+	// of the number of packages of every distribution
+	Config     *string
+	DistCounts *string
+}
+
+type ScoreSnapshotInput struct {
+	RunID   *string `pk:"true"`
+	GitLink *string `pk:"true"`
+	// Inputs is the json of the metadata scored
+	Inputs *string
+}
+
+const (
+	ScoreSnapshotTableName      = "score_snapshots"
+	ScoreSnapshotInputTableName = "score_snapshot_inputs"
+)
+
+type scoreSnapshotRepository struct {
+	appDb storage.AppDatabaseContext
+}
+
+var _ ScoreSnapshotRepository = (*scoreSnapshotRepository)(nil)
+
+func NewScoreSnapshotRepository(appDb storage.AppDatabaseContext) ScoreSnapshotRepository {
+	return &scoreSnapshotRepository{appDb: appDb}
+}
+
+// GetByRunID implements ScoreSnapshotRepository.
+func (s *scoreSnapshotRepository) GetByRunID(runID string) (*ScoreSnapshot, error) {
+	return sqlutil.QueryCommonFirst[ScoreSnapshot](s.appDb, ScoreSnapshotTableName,
+		"WHERE run_id = $1", runID)
+}
+
+// QueryInputs implements ScoreSnapshotRepository.
+func (s *scoreSnapshotRepository) QueryInputs(runID string) (iter.Seq[*ScoreSnapshotInput], error) {
+	return sqlutil.QueryCommon[ScoreSnapshotInput](s.appDb, ScoreSnapshotInputTableName,
+		"WHERE run_id = $1", runID)
+}
+
+// Insert implements ScoreSnapshotRepository.
+func (s *scoreSnapshotRepository) Insert(snapshot *ScoreSnapshot, inputs []*ScoreSnapshotInput) error {
+	if snapshot.RunID == nil || *snapshot.RunID == "" {
+		return ErrInvalidInput
+	}
+	for _, in := range inputs {
+		if in.RunID == nil || *in.RunID != *snapshot.RunID {
+			return ErrInvalidInput
+		}
+	}
+	if err := sqlutil.Insert(s.appDb, ScoreSnapshotTableName, snapshot); err != nil {
+		return err
+	}
+	return sqlutil.BatchInsert(s.appDb, ScoreSnapshotInputTableName, inputs)
+}
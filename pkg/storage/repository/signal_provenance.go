@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"iter"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+	"github.com/lib/pq"
+)
+
+// SignalProvenanceRepository stores where every signal of a repo was
+// collected from, when, and how far it can be trusted, so the scorer can
+// discount stale or partial signals, see package freshness.
+type SignalProvenanceRepository interface {
+	/** QUERY **/
+
+	Query() (iter.Seq[*SignalProvenance], error)
+	// QueryByLink returns the provenance of every signal of a repo, sorted
+	// by signal
+	QueryByLink(link string) (iter.Seq[*SignalProvenance], error)
+
+	/** INSERT/UPDATE **/
+
+	// Record replaces the provenance of the signal of links
+	Record(signal Signal, links []string, source string, confidence float64, at time.Time) error
+}
+
+type SignalProvenance struct {
+	GitLink *string `pk:"true"`
+	Signal  *Signal `pk:"true"`
+	// Source is how the signal was collected, e.g. git-clone or probe
+	Source      *string
+	CollectedAt *time.Time
+	// Confidence is in [0, 1], 1 if every metric of the signal was
+	// collected from the source
+	Confidence *float64
+}
+
+const SignalProvenanceTableName = "signal_provenance"
+
+type signalProvenanceRepository struct {
+	appDb storage.AppDatabaseContext
+}
+
+var _ SignalProvenanceRepository = (*signalProvenanceRepository)(nil)
+
+func NewSignalProvenanceRepository(appDb storage.AppDatabaseContext) SignalProvenanceRepository {
+	return &signalProvenanceRepository{appDb: appDb}
+}
+
+// Query implements SignalProvenanceRepository.
+func (s *signalProvenanceRepository) Query() (iter.Seq[*SignalProvenance], error) {
+	return sqlutil.QueryCommon[SignalProvenance](s.appDb, SignalProvenanceTableName, "")
+}
+
+// QueryByLink implements SignalProvenanceRepository.
+func (s *signalProvenanceRepository) QueryByLink(link string) (iter.Seq[*SignalProvenance], error) {
+	return sqlutil.QueryCommon[SignalProvenance](s.appDb, SignalProvenanceTableName,
+		"WHERE git_link = $1 ORDER BY signal", link)
+}
+
+// Record implements SignalProvenanceRepository.
+func (s *signalProvenanceRepository) Record(signal Signal, links []string, source string, confidence float64, at time.Time) error {
+	if !signal.valid() || source == "" || confidence < 0 || confidence > 1 {
+		return ErrInvalidInput
+	}
+	if len(links) == 0 {
+		return nil
+	}
+	_, err := s.appDb.Exec(`INSERT INTO `+SignalProvenanceTableName+` (git_link, signal, source, collected_at, confidence)
+		SELECT DISTINCT UNNEST($1::text[]), $2, $3, $4::timestamp, $5
+		ON CONFLICT (git_link, signal) DO UPDATE SET source = EXCLUDED.source,
+			collected_at = EXCLUDED.collected_at, confidence = EXCLUDED.confidence`,
+		pq.Array(links), string(signal), source, at, confidence)
+	return err
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"iter"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+	"github.com/lib/pq"
+)
+
+// SignalValueRepository stores the values of the signals collected by the
+// pluggable collectors, see package signals.
+type SignalValueRepository interface {
+	/** QUERY **/
+
+	// QueryByLink returns the values of a project, sorted by collector and
+	// name
+	QueryByLink(link string) (iter.Seq[*SignalValue], error)
+	// QueryCollectedSince returns the links whose values of collector were
+	// collected at or after since
+	QueryCollectedSince(collector string, since time.Time) ([]string, error)
+
+	/** INSERT/UPDATE **/
+
+	// Replace upserts values and deletes the values of the same links by
+	// the same collectors collected before them in one transaction, so
+	// signals a collector no longer reports are dropped
+	Replace(values []*SignalValue) error
+}
+
+type SignalValue struct {
+	GitLink *string `pk:"true"`
+	// Collector is the name of the signals.SignalCollector
+	Collector   *string `pk:"true"`
+	Name        *string `pk:"true"`
+	Value       *float64
+	CollectedAt *time.Time
+}
+
+const SignalValueTableName = "signal_values"
+
+type signalValueRepository struct {
+	appDb storage.AppDatabaseContext
+}
+
+var _ SignalValueRepository = (*signalValueRepository)(nil)
+
+func NewSignalValueRepository(appDb storage.AppDatabaseContext) SignalValueRepository {
+	return &signalValueRepository{appDb: appDb}
+}
+
+// QueryByLink implements SignalValueRepository.
+func (s *signalValueRepository) QueryByLink(link string) (iter.Seq[*SignalValue], error) {
+	return sqlutil.QueryCommon[SignalValue](s.appDb, SignalValueTableName,
+		"WHERE git_link = $1 ORDER BY collector, name", link)
+}
+
+// QueryCollectedSince implements SignalValueRepository.
+func (s *signalValueRepository) QueryCollectedSince(collector string, since time.Time) ([]string, error) {
+	rows, err := s.appDb.Query(`SELECT DISTINCT git_link FROM `+SignalValueTableName+`
+		WHERE collector = $1 AND collected_at >= $2`, collector, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ret := make([]string, 0)
+	for rows.Next() {
+		var link string
+		if err := rows.Scan(&link); err != nil {
+			return nil, err
+		}
+		ret = append(ret, link)
+	}
+	return ret, rows.Err()
+}
+
+// Replace implements SignalValueRepository.
+func (s *signalValueRepository) Replace(values []*SignalValue) error {
+	if len(values) == 0 {
+		return nil
+	}
+	gitLinks := make([]string, len(values))
+	collectors := make([]string, len(values))
+	names := make([]string, len(values))
+	vals := make([]float64, len(values))
+	// formatted keeping the wall clock, as pq does for timestamp arguments
+	collectedAt := make([]string, len(values))
+	for i, v := range values {
+		if v.GitLink == nil || v.Collector == nil || *v.Collector == "" || v.Name == nil ||
+			v.Value == nil || v.CollectedAt == nil {
+			return ErrInvalidInput
+		}
+		gitLinks[i], collectors[i], names[i], vals[i] = *v.GitLink, *v.Collector, *v.Name, *v.Value
+		collectedAt[i] = v.CollectedAt.Format(time.RFC3339Nano)
+	}
+
+	return storage.InBatch(s.appDb, func(ac storage.AppDatabaseContext) error {
+		_, err := ac.Exec(`DELETE FROM `+SignalValueTableName+` v
+			USING UNNEST($1::text[], $2::text[], $3::text[]) AS t(git_link, collector, collected_at)
+			WHERE v.git_link = t.git_link AND v.collector = t.collector AND v.collected_at < t.collected_at::timestamp`,
+			pq.Array(gitLinks), pq.Array(collectors), pq.Array(collectedAt))
+		if err != nil {
+			return err
+		}
+		_, err = ac.Exec(`INSERT INTO `+SignalValueTableName+` (git_link, collector, name, value, collected_at)
+			SELECT git_link, collector, name, value, collected_at::timestamp
+			FROM UNNEST($1::text[], $2::text[], $3::text[], $4::float8[], $5::text[])
+				AS t(git_link, collector, name, value, collected_at)
+			ON CONFLICT (git_link, collector, name) DO UPDATE SET value = EXCLUDED.value,
+				collected_at = EXCLUDED.collected_at`,
+			pq.Array(gitLinks), pq.Array(collectors), pq.Array(names), pq.Array(vals), pq.Array(collectedAt))
+		return err
+	})
+}
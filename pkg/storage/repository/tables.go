@@ -0,0 +1,80 @@
+package repository
+
+import "github.com/HUSTSecLab/criticality_score/pkg/storage/sqlutil"
+
+// GitRepositoryTableName is the union of all the git links known to the
+// collectors, it is written by git-metrics-sync.
+const GitRepositoryTableName = "git_repositories"
+
+// PlatformLinkTablePrefixes lists all the known platform table prefixes.
+var PlatformLinkTablePrefixes = []PlatformLinkTablePrefix{
+	PlatformLinkTablePrefixGithub,
+	PlatformLinkTablePrefixGitlab,
+	PlatformLinkTablePrefixBitbucket,
+	PlatformLinkTablePrefixGitee,
+}
+
+// DistPackageTableName returns the package table of a distribution, e.g.
+// debian_packages.
+func DistPackageTableName(prefix DistPackageTablePrefix) string {
+	return string(prefix) + DistPackageTableNameAppendix
+}
+
+// DistRelationshipTableName returns the relationship table of a
+// distribution, e.g. debian_relationships.
+func DistRelationshipTableName(prefix DistPackageTablePrefix) string {
+	return string(prefix) + DistRelationshipTableNameAppendix
+}
+
+// PlatformLinkTableName returns the link table of a platform, e.g.
+// github_links.
+func PlatformLinkTableName(platform PlatformLinkTablePrefix) string {
+	return string(platform) + "_links"
+}
+
+// register all the tables, so that the names built from prefixes can be
+// checked by sqlutil.Table before they are written into a query
+func init() {
+	sqlutil.RegisterTable(
+		ChangeFeedCursorTableName,
+		CollectionTimestampTableName,
+		CommitLogTableName,
+		CollectorRunTableName,
+		DistDependencyTableName,
+		ForgeRequestBudgetTableName,
+		GitMetricTableName,
+		GitMetricProdTableName,
+		GitRepositoryTableName,
+		HomepageLivenessTableName,
+		HTTPCacheTableName,
+		LangEcosystemTableName,
+		LangEcosystemPackageTableName,
+		LibrariesIOPackageTableName,
+		LinkCheckTableName,
+		MaintainerTableName,
+		MetricSnapshotTableName,
+		MetricTrendTableName,
+		OrgScoreTableName,
+		PageRankRunTableName,
+		PopularitySnapshotTableName,
+		ProjectLabelTableName,
+		ProjectTagTableName,
+		RawResponseTableName,
+		RepoArchiveTableName,
+		ScoreTableName,
+		ScoreSnapshotTableName,
+		ScoreSnapshotInputTableName,
+		SignalProvenanceTableName,
+		SignalValueTableName,
+		WorkflowHistoryTableName,
+		TopProjectsPerEcosystemViewName,
+		DistroSummaryViewName,
+	)
+	for _, prefix := range DistPackageTablePrefixes {
+		sqlutil.RegisterTable(DistPackageTableName(prefix), DistRelationshipTableName(prefix))
+	}
+	for _, platform := range PlatformLinkTablePrefixes {
+		name := PlatformLinkTableName(platform)
+		sqlutil.RegisterTable(name, name+"_tmp")
+	}
+}
@@ -0,0 +1,22 @@
+//go:build unix
+
+package spool
+
+import (
+	"os"
+	"syscall"
+)
+
+// canLock is true if the writers lock their spool files, so the replay can
+// tell the files of running writers.
+const canLock = true
+
+// tryLock takes the exclusive lock of f without waiting, false if another
+// writer or replay holds it. The lock is released by closing f.
+func tryLock(f *os.File) (bool, error) {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		return false, nil
+	}
+	return err == nil, err
+}
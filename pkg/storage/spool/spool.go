@@ -0,0 +1,613 @@
+// Package spool buffers the writes of a collector on local disk while the
+// database is unreachable, so a run does not lose its work to a restart of
+// postgres. Wrap returns a context whose Exec and batches append their
+// statements to a spool file instead of failing while the database can
+// not be reached, e.g. refuses connections; Replay executes the spooled
+// statements once the database is back.
+//
+// A spool file is JSON lines, a record per failed Exec or batch commit,
+// and is named after the component and the start of its run. Files being
+// written end with .open, they are closed by Close of the context. The
+// writer holds a lock of its file until it is closed, so the replay skips
+// the files of running writers, and replays the open files of writers
+// which died.
+//
+// Once a write is spooled, the later writes of the run are spooled as well
+// without trying the database, and so are all writes of a run starting
+// while spool files are left, so the replay keeps the order of the writes
+// and older rows do not overwrite newer ones.
+//
+// Only Exec and batches are spooled, statements prepared by Prepare and
+// queries of connections from GetDatabaseConnection bypass the spool, and
+// reads fail as before.
+package spool
+
+import (
+	"bufio"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/errors"
+	"github.com/HUSTSecLab/criticality_score/pkg/logger"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/lib/pq"
+)
+
+const (
+	// Ext is the extension of spool files, OpenExt of the files being
+	// written and RejectedExt of the records rejected by the database
+	Ext         = ".jsonl"
+	OpenExt     = Ext + ".open"
+	RejectedExt = ".rejected"
+)
+
+// ErrSpooled is returned by RowsAffected of the result of a spooled Exec,
+// the number of rows is unknown until the statement is replayed.
+var ErrSpooled = errors.New("statement is spooled, rows affected are unknown")
+
+// Arg is an argument of a statement, the driver value of it in text so no
+// precision is lost, e.g. of int64 and time.
+type Arg struct {
+	// Type is null, int, float, bool, bytes, string or time
+	Type  string `json:"t"`
+	Value string `json:"v,omitempty"`
+}
+
+// Statement is a spooled statement with its arguments.
+type Statement struct {
+	Query string `json:"query"`
+	Args  []Arg  `json:"args,omitempty"`
+}
+
+// Record is a failed Exec or batch commit, its statements are replayed in
+// a transaction.
+type Record struct {
+	Time       time.Time   `json:"time"`
+	Statements []Statement `json:"statements"`
+}
+
+// NewStatement converts the arguments of query to their driver values, as
+// the driver would before sending them.
+func NewStatement(query string, args ...interface{}) (Statement, error) {
+	st := Statement{Query: query, Args: make([]Arg, 0, len(args))}
+	for i, a := range args {
+		v, err := driver.DefaultParameterConverter.ConvertValue(a)
+		if err != nil {
+			return st, fmt.Errorf("arg $%d: %w", i+1, err)
+		}
+		var arg Arg
+		switch v := v.(type) {
+		case nil:
+			arg = Arg{Type: "null"}
+		case int64:
+			arg = Arg{Type: "int", Value: strconv.FormatInt(v, 10)}
+		case float64:
+			arg = Arg{Type: "float", Value: strconv.FormatFloat(v, 'g', -1, 64)}
+		case bool:
+			arg = Arg{Type: "bool", Value: strconv.FormatBool(v)}
+		case []byte:
+			arg = Arg{Type: "bytes", Value: base64.StdEncoding.EncodeToString(v)}
+		case string:
+			arg = Arg{Type: "string", Value: v}
+		case time.Time:
+			arg = Arg{Type: "time", Value: v.Format(time.RFC3339Nano)}
+		default:
+			return st, fmt.Errorf("arg $%d: unsupported type %T", i+1, v)
+		}
+		st.Args = append(st.Args, arg)
+	}
+	return st, nil
+}
+
+// Values returns the driver values of the arguments.
+func (st Statement) Values() ([]interface{}, error) {
+	ret := make([]interface{}, 0, len(st.Args))
+	for i, a := range st.Args {
+		var v interface{}
+		var err error
+		switch a.Type {
+		case "null":
+		case "int":
+			v, err = strconv.ParseInt(a.Value, 10, 64)
+		case "float":
+			v, err = strconv.ParseFloat(a.Value, 64)
+		case "bool":
+			v, err = strconv.ParseBool(a.Value)
+		case "bytes":
+			v, err = base64.StdEncoding.DecodeString(a.Value)
+		case "string":
+			v = a.Value
+		case "time":
+			v, err = time.Parse(time.RFC3339Nano, a.Value)
+		default:
+			err = fmt.Errorf("unknown type %q", a.Type)
+		}
+		if err != nil {
+			return nil, errors.Mark(fmt.Errorf("arg $%d: %w", i+1, err), errors.ParseError)
+		}
+		ret = append(ret, v)
+	}
+	return ret, nil
+}
+
+// Spool is a spool file of a run, it is created with the first record.
+type Spool struct {
+	dir  string
+	name string
+
+	mu      sync.Mutex
+	file    *os.File
+	records int
+
+	// behind is true if spool files of earlier runs were left at the first
+	// write, checked once
+	behindOnce sync.Once
+	behind     bool
+}
+
+// New returns the spool of a run of component in dir.
+func New(dir, component string) *Spool {
+	name := fmt.Sprintf("%s-%s-%d", component, time.Now().UTC().Format("20060102T150405"), os.Getpid())
+	return &Spool{dir: dir, name: name}
+}
+
+// Path returns the path of the spool file while it is open.
+func (s *Spool) Path() string {
+	return filepath.Join(s.dir, s.name+OpenExt)
+}
+
+// Records returns the number of records appended.
+func (s *Spool) Records() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.records
+}
+
+// Append appends a record of statements, it is synced to disk before
+// Append returns.
+func (s *Spool) Append(statements ...Statement) error {
+	line, err := json.Marshal(Record{Time: time.Now().UTC(), Statements: statements})
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		if err := s.create(); err != nil {
+			return err
+		}
+		logger.Warnf("Spooling writes to %s, run `spool replay` once the database is back", s.Path())
+	}
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	s.records++
+	return s.file.Sync()
+}
+
+// create creates the spool file locked, it is locked before it is renamed
+// to Path, so the replay never sees it unlocked.
+func (s *Spool) create() error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+	tmp := s.Path() + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_APPEND|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if ok, err := tryLock(file); !ok {
+		file.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to lock %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, s.Path()); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return err
+	}
+	s.file = file
+	return nil
+}
+
+// spooling returns true if the writes are spooled without trying the
+// database, once a write of the run is spooled, or if spool files of
+// earlier runs are left at the first write.
+func (s *Spool) spooling() bool {
+	s.behindOnce.Do(func() {
+		files, err := List(s.dir)
+		if err != nil {
+			logger.Warnf("Failed to list the spool files of %s: %v", s.dir, err)
+			return
+		}
+		if len(files) > 0 {
+			s.behind = true
+			logger.Warnf("%d spool files of %s are not replayed, the writes of this run are spooled after them", len(files), s.dir)
+		}
+	})
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.behind || s.file != nil
+}
+
+// Close closes the spool file, so it is replayed as a closed file. It is
+// renamed before the lock is released by closing it.
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	closed := filepath.Join(s.dir, s.name+Ext)
+	err := os.Rename(s.Path(), closed)
+	err = errors.Join(err, s.file.Close())
+	s.file = nil
+	if err != nil {
+		return err
+	}
+	logger.Warnf("Spooled %d records to %s", s.records, closed)
+	return nil
+}
+
+// spooledResult is the result of a spooled Exec.
+type spooledResult struct{}
+
+func (spooledResult) LastInsertId() (int64, error) { return 0, ErrSpooled }
+func (spooledResult) RowsAffected() (int64, error) { return 0, ErrSpooled }
+
+// spooledContext spools the statements of Exec and of batches which fail
+// as the database is unreachable, and of all later ones.
+type spooledContext struct {
+	storage.AppDatabaseContext
+	spool *Spool
+}
+
+// Wrap returns ac spooling its failed writes to s.
+func Wrap(ac storage.AppDatabaseContext, s *Spool) storage.AppDatabaseContext {
+	return &spooledContext{AppDatabaseContext: ac, spool: s}
+}
+
+// unreachable returns true if err is retryable as the database can not be
+// reached, e.g. a refused or lost connection. Deadlocks and serialization
+// failures are retried by the callers instead.
+func unreachable(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code.Class() == "40" {
+		return false
+	}
+	return errors.Retryable(err)
+}
+
+// spoolOnError spools the statements if the database is unreachable, and
+// returns the error of the write otherwise.
+func (c *spooledContext) spoolOnError(err error, statements []batchStatement) error {
+	if err == nil || !unreachable(err) {
+		return err
+	}
+	if serr := c.append(statements); serr != nil {
+		return errors.Join(err, serr)
+	}
+	return nil
+}
+
+// append spools the statements as a record.
+func (c *spooledContext) append(statements []batchStatement) error {
+	record := make([]Statement, 0, len(statements))
+	for _, b := range statements {
+		st, err := NewStatement(b.query, b.args...)
+		if err != nil {
+			return err
+		}
+		record = append(record, st)
+	}
+	if err := c.spool.Append(record...); err != nil {
+		return fmt.Errorf("failed to spool: %w", err)
+	}
+	return nil
+}
+
+func (c *spooledContext) Exec(query string, args ...interface{}) (sql.Result, error) {
+	if c.spool.spooling() {
+		if err := c.append([]batchStatement{{query, args}}); err != nil {
+			return nil, err
+		}
+		return spooledResult{}, nil
+	}
+	r, err := c.AppDatabaseContext.Exec(query, args...)
+	if err == nil {
+		return r, nil
+	}
+	if err := c.spoolOnError(err, []batchStatement{{query, args}}); err != nil {
+		return nil, err
+	}
+	return spooledResult{}, nil
+}
+
+func (c *spooledContext) NewBatchExecContext(config *storage.BatchExecContextConfig) storage.BatchExecContext {
+	// the batch commits itself, so the statements of auto commits are
+	// spooled as well
+	return &spooledBatch{
+		BatchExecContext: c.AppDatabaseContext.NewBatchExecContext(&storage.BatchExecContextConfig{}),
+		ctx:              c,
+		config:           config,
+	}
+}
+
+func (c *spooledContext) Close() error {
+	return errors.Join(c.spool.Close(), c.AppDatabaseContext.Close())
+}
+
+type batchStatement struct {
+	query string
+	args  []interface{}
+}
+
+// spooledBatch spools the statements of a failed commit as one record, so
+// they are replayed in a transaction as they would have been committed.
+type spooledBatch struct {
+	storage.BatchExecContext
+	ctx        *spooledContext
+	config     *storage.BatchExecContextConfig
+	statements []batchStatement
+}
+
+func (b *spooledBatch) AppendExec(sentence string, args ...interface{}) error {
+	if err := b.BatchExecContext.AppendExec(sentence, args...); err != nil {
+		return err
+	}
+	b.statements = append(b.statements, batchStatement{sentence, args})
+	if b.config != nil && b.config.AutoCommit && len(b.statements) >= b.config.AutoCommitSize {
+		_, err := b.Commit()
+		return err
+	}
+	return nil
+}
+
+func (b *spooledBatch) Commit() (sql.Result, error) {
+	statements := b.statements
+	b.statements = nil
+	if b.ctx.spool.spooling() {
+		b.BatchExecContext.Clear()
+		if len(statements) == 0 {
+			return spooledResult{}, nil
+		}
+		if err := b.ctx.append(statements); err != nil {
+			return nil, err
+		}
+		return spooledResult{}, nil
+	}
+	r, err := b.BatchExecContext.Commit()
+	if err == nil {
+		return r, nil
+	}
+	if err := b.ctx.spoolOnError(err, statements); err != nil {
+		return nil, err
+	}
+	return spooledResult{}, nil
+}
+
+func (b *spooledBatch) Clear() {
+	b.BatchExecContext.Clear()
+	b.statements = nil
+}
+
+// File is a spool file.
+type File struct {
+	Path    string
+	Records int
+	// Open is true if the file is being written, or its writer died
+	// without closing it
+	Open    bool
+	ModTime time.Time
+}
+
+// List returns the spool files of dir, in the order of replay.
+func List(dir string) ([]File, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ret []File
+	for _, e := range entries {
+		name := e.Name()
+		open := strings.HasSuffix(name, OpenExt)
+		if e.IsDir() || !open && !strings.HasSuffix(name, Ext) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		f := File{Path: filepath.Join(dir, name), Open: open, ModTime: info.ModTime()}
+		if f.Records, err = countLines(f.Path); err != nil {
+			return nil, err
+		}
+		ret = append(ret, f)
+	}
+	// the names start with the component and the start of the run
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Path < ret[j].Path })
+	return ret, nil
+}
+
+func countLines(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	n := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 64<<20)
+	for scanner.Scan() {
+		n++
+	}
+	return n, scanner.Err()
+}
+
+// ReplayReport is the result of Replay.
+type ReplayReport struct {
+	Files      int
+	Records    int
+	Statements int
+	// Rejected is the number of records rejected by the database, e.g.
+	// violating a constraint, they are moved to files with RejectedExt
+	Rejected int
+	// Skipped is the number of files which are still written or replayed
+	// by another replay, or are gone since they were listed
+	Skipped int
+}
+
+// Replay executes the records of the spool files of dir, every record in a
+// transaction, with ac which must not be wrapped. Replayed files are
+// removed. Records rejected by the database are moved aside and the replay
+// goes on, but a retryable error stops the replay and keeps the remaining
+// records, so Replay can be run again until it succeeds.
+//
+// Files locked by their writer or another replay are skipped, and so are
+// all open files on platforms without locks.
+func Replay(ac storage.AppDatabaseContext, dir string) (*ReplayReport, error) {
+	files, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+	report := &ReplayReport{}
+	for _, f := range files {
+		if f.Open && !canLock {
+			report.Skipped++
+			continue
+		}
+		replayed, err := replayLocked(ac, f.Path, report)
+		if err != nil {
+			return report, fmt.Errorf("replay %s: %w", f.Path, err)
+		}
+		if !replayed {
+			report.Skipped++
+			continue
+		}
+		report.Files++
+	}
+	return report, nil
+}
+
+// replayLocked replays the file at path holding its lock, false if it is
+// locked or gone, e.g. closed by its writer since it was listed.
+func replayLocked(ac storage.AppDatabaseContext, path string, report *ReplayReport) (bool, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	if ok, err := tryLock(file); !ok {
+		return false, err
+	}
+	// the file may be renamed or removed before it is locked
+	locked, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	if current, err := os.Stat(path); err != nil || !os.SameFile(locked, current) {
+		return false, nil
+	}
+	return true, replayFile(ac, path, report)
+}
+
+func replayFile(ac storage.AppDatabaseContext, path string, report *ReplayReport) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	rejectedPath := strings.TrimSuffix(strings.TrimSuffix(path, ".open"), Ext) + RejectedExt
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+		n, err := replayRecord(ac, line)
+		switch {
+		case err == nil:
+			report.Records++
+			report.Statements += n
+		case errors.Retryable(err):
+			// keep the rest for the next replay, the file is closed now
+			rest := strings.Join(lines[i:], "\n") + "\n"
+			closed := strings.TrimSuffix(path, ".open")
+			if werr := writeFile(closed, rest); werr != nil {
+				return errors.Join(err, werr)
+			}
+			if closed != path {
+				os.Remove(path)
+			}
+			return err
+		default:
+			logger.Warnf("Record %d of %s is rejected: %v", i+1, path, err)
+			if werr := appendFile(rejectedPath, line+"\n"); werr != nil {
+				return errors.Join(err, werr)
+			}
+			report.Rejected++
+		}
+	}
+	return os.Remove(path)
+}
+
+// replayRecord executes the statements of a record in a transaction, and
+// returns the number of statements.
+func replayRecord(ac storage.AppDatabaseContext, line string) (int, error) {
+	var r Record
+	if err := json.Unmarshal([]byte(line), &r); err != nil {
+		// e.g. the last line of a writer which died while writing it
+		return 0, errors.Mark(err, errors.ParseError)
+	}
+	conn, err := ac.GetDatabaseConnection()
+	if err != nil {
+		return 0, err
+	}
+	tx, err := conn.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	for _, st := range r.Statements {
+		args, err := st.Values()
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(st.Query, args...); err != nil {
+			return 0, err
+		}
+	}
+	return len(r.Statements), tx.Commit()
+}
+
+// writeFile replaces the file at path by data.
+func writeFile(path, data string) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(data), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func appendFile(path, data string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = file.WriteString(data)
+	return errors.Join(err, file.Close())
+}
@@ -0,0 +1,198 @@
+package spool
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/lib/pq"
+)
+
+func TestStatement(t *testing.T) {
+	at := time.Date(2025, 1, 2, 3, 4, 5, 6, time.UTC)
+	link := "https://github.com/madler/zlib"
+	st, err := NewStatement("UPDATE t SET a = $1, b = $2, c = $3, d = $4, e = $5, f = $6",
+		int64(1)<<60, 0.1, &link, (*string)(nil), at, pq.Array([]string{"a", "b"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := st.Values()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{int64(1) << 60, 0.1, link, nil, at, "{\"a\",\"b\"}"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Values() = %#v, want %#v", got, want)
+	}
+}
+
+func newMock(t *testing.T) (storage.AppDatabaseContext, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return storage.NewAppDatabaseWithDb(db), mock
+}
+
+func TestWrap(t *testing.T) {
+	dir := t.TempDir()
+	ac, mock := newMock(t)
+	s := New(dir, "test")
+	spooled := Wrap(ac, s)
+
+	// rejected, not spooled
+	mock.ExpectExec("INSERT INTO a").WillReturnError(&pq.Error{Code: "23505"})
+	if _, err := spooled.Exec("INSERT INTO a VALUES ($1)", 1); err == nil {
+		t.Error("Exec() of a duplicate key is not an error")
+	}
+
+	// deadlocks are retried by the callers
+	mock.ExpectExec("INSERT INTO a").WillReturnError(&pq.Error{Code: "40P01"})
+	if _, err := spooled.Exec("INSERT INTO a VALUES ($1)", 1); err == nil {
+		t.Error("Exec() of a deadlock is not an error")
+	}
+
+	// the statements of a batch are one record
+	mock.ExpectBegin().WillReturnError(&pq.Error{Code: "08006"})
+	batch := spooled.NewBatchExecContext(&storage.BatchExecContextConfig{AutoCommit: true, AutoCommitSize: 2})
+	if err := batch.AppendExec("INSERT INTO b VALUES ($1)", "x"); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.AppendExec("INSERT INTO b VALUES ($1)", "y"); err != nil {
+		t.Fatalf("auto commit error = %v", err)
+	}
+
+	// once spooled, later writes are spooled without the database, so they
+	// are replayed in order
+	r, err := spooled.Exec("INSERT INTO a VALUES ($1)", 1)
+	if err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+	if _, err := r.RowsAffected(); !errors.Is(err, ErrSpooled) {
+		t.Errorf("RowsAffected() error = %v, want ErrSpooled", err)
+	}
+	batch = spooled.NewBatchExecContext(nil)
+	if err := batch.AppendExec("INSERT INTO b VALUES ($1)", "z"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := batch.Commit(); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if s.Records() != 3 {
+		t.Errorf("Records() = %d, want 3", s.Records())
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := List(dir)
+	if err != nil || len(files) != 1 || files[0].Open || files[0].Records != 3 {
+		t.Fatalf("List() = %+v, %v", files, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+
+	// a run starting while files are left spools behind them
+	ac, mock = newMock(t)
+	s = New(dir, "test")
+	if _, err := Wrap(ac, s).Exec("INSERT INTO a VALUES ($1)", 2); err != nil {
+		t.Fatal(err)
+	}
+	if s.Records() != 1 {
+		t.Errorf("Records() of a run behind a spool file = %d, want 1", s.Records())
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func writeSpool(t *testing.T, path string, records ...[]Statement) {
+	s := &Spool{dir: filepath.Dir(path), name: strings.TrimSuffix(filepath.Base(path), Ext)}
+	for _, r := range records {
+		if err := s.Append(r...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	dir := t.TempDir()
+	insert := func(v string) Statement {
+		st, _ := NewStatement("INSERT INTO a VALUES ($1)", v)
+		return st
+	}
+	path := filepath.Join(dir, "test-1"+Ext)
+	writeSpool(t, path, []Statement{insert("x"), insert("y")}, []Statement{insert("dup")}, []Statement{insert("z")})
+	// an open file of a running writer is locked and skipped
+	running := &Spool{dir: dir, name: "test-2"}
+	if err := running.Append(insert("running")); err != nil {
+		t.Fatal(err)
+	}
+	defer running.Close()
+	// an open file of a writer which died is replayed
+	dead := filepath.Join(dir, "test-3"+OpenExt)
+	if err := os.WriteFile(dead, []byte(`{"statements":[{"query":"INSERT INTO a VALUES ($1)","args":[{"t":"string","v":"w"}]}]}`+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ac, mock := newMock(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO a").WithArgs("x").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO a").WithArgs("y").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO a").WithArgs("dup").WillReturnError(&pq.Error{Code: "23505"})
+	mock.ExpectRollback()
+	mock.ExpectBegin().WillReturnError(syscall.ECONNREFUSED)
+
+	report, err := Replay(ac, dir)
+	if err == nil {
+		t.Fatal("Replay() of an unreachable database is not an error")
+	}
+	if report.Records != 1 || report.Statements != 2 || report.Rejected != 1 || report.Files != 0 {
+		t.Errorf("report = %+v", report)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "test-1"+RejectedExt)); !strings.Contains(string(data), `"dup"`) {
+		t.Errorf("rejected = %s", data)
+	}
+
+	// the rest is replayed by the next replay
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO a").WithArgs("z").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO a").WithArgs("w").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if report, err = Replay(ac, dir); err != nil {
+		t.Fatal(err)
+	}
+	if report.Records != 2 || report.Files != 2 || report.Skipped != 1 {
+		t.Errorf("report = %+v", report)
+	}
+	for _, p := range []string{path, dead} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("replayed file %s is not removed: %v", p, err)
+		}
+	}
+	if _, err := os.Stat(running.Path()); err != nil {
+		t.Errorf("open file of a running writer: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,297 @@
+package sqlutil
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+)
+
+// ErrInvalidOption is returned when the options of a list query are not
+// allowed by its spec, e.g. an unknown sort field or a malformed cursor.
+var ErrInvalidOption = errors.New("invalid list option")
+
+// ListOptions are the options of a list query given by a client. Only
+// the names whitelisted by the ListSpec of the query are accepted, so the
+// options can be taken from query parameters as they are.
+type ListOptions struct {
+	// Limit is the number of rows of the page, zero is the default limit
+	// of the spec
+	Limit int
+	// Offset skips rows, it can not be combined with Cursor
+	Offset int
+	// Cursor is the Next of the previous page, the page starts after the
+	// last row of the previous page
+	Cursor string
+	// Sort is the name of a sortable field, descending with a "-" prefix,
+	// e.g. "-score". Empty is the default sort of the spec.
+	Sort string
+	// Fields are the names of the selected fields, all fields if empty.
+	// The sort field and the key are always selected.
+	Fields []string
+}
+
+// ListSpec is the whitelist of a list query, the names of its fields and
+// how they can be sorted. The names are the names of the fields for the
+// clients, e.g. the json names of the API, and are mapped to columns.
+type ListSpec struct {
+	// Fields maps the names of fields to their columns
+	Fields map[string]string
+	// Sortable are the names of the fields the rows can be sorted by
+	Sortable []string
+	// DefaultSort is the sort of options without Sort, e.g. "-score"
+	DefaultSort string
+	// Key is the name of a unique and not null field, the rows are sorted
+	// by it after the sort field so the order and the cursors are stable
+	Key string
+	// DefaultLimit is the limit of options without Limit, MaxLimit is the
+	// largest limit, so no list query is unbounded
+	DefaultLimit int
+	MaxLimit     int
+}
+
+// Page is a page of a list query.
+type Page[T any] struct {
+	Items []*T
+	// Next is the cursor of the next page, empty on the last page
+	Next string
+}
+
+// cursor is the position after a row, the values of the sort field and
+// the key of the row. Sort is checked, so a cursor can not be used with
+// another order.
+type cursor struct {
+	Sort   string `json:"s"`
+	Values []any  `json:"v"`
+}
+
+func encodeCursor(c *cursor) (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(s string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidOption)
+	}
+	var c cursor
+	d := json.NewDecoder(bytes.NewReader(data))
+	// numbers are kept as their text, so int64 keys do not lose precision
+	d.UseNumber()
+	if err := d.Decode(&c); err != nil || len(c.Values) != 2 {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidOption)
+	}
+	for i, v := range c.Values {
+		if n, ok := v.(json.Number); ok {
+			c.Values[i] = n.String()
+		}
+	}
+	return &c, nil
+}
+
+// listQuery is a list query checked against its spec.
+type listQuery struct {
+	sort    string
+	sortCol string
+	keyCol  string
+	desc    bool
+	columns []string
+	limit   int
+	offset  int
+	after   *cursor
+}
+
+// plan checks the options against the spec and resolves them to columns.
+func (s *ListSpec) plan(opts ListOptions) (*listQuery, error) {
+	q := &listQuery{sort: opts.Sort, limit: opts.Limit, offset: opts.Offset}
+	if q.sort == "" {
+		q.sort = s.DefaultSort
+	}
+	name, desc := strings.CutPrefix(q.sort, "-")
+	if !slices.Contains(s.Sortable, name) && name != s.Key {
+		return nil, fmt.Errorf("%w: can not sort by %q", ErrInvalidOption, name)
+	}
+	q.desc = desc
+
+	var err error
+	if q.sortCol, err = Column(s.Fields[name]); err != nil {
+		return nil, err
+	}
+	if q.keyCol, err = Column(s.Fields[s.Key]); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case q.limit == 0:
+		q.limit = s.DefaultLimit
+	case q.limit < 0 || q.limit > s.MaxLimit:
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidOption, s.MaxLimit)
+	}
+	if q.offset < 0 {
+		return nil, fmt.Errorf("%w: negative offset", ErrInvalidOption)
+	}
+	if opts.Cursor != "" {
+		if q.offset != 0 {
+			return nil, fmt.Errorf("%w: offset can not be combined with a cursor", ErrInvalidOption)
+		}
+		if q.after, err = decodeCursor(opts.Cursor); err != nil {
+			return nil, err
+		}
+		if q.after.Sort != q.sort {
+			return nil, fmt.Errorf("%w: the cursor is of the sort %q", ErrInvalidOption, q.after.Sort)
+		}
+	}
+
+	fields := opts.Fields
+	if len(fields) == 0 {
+		for name := range s.Fields {
+			fields = append(fields, name)
+		}
+		slices.Sort(fields)
+	}
+	for _, name := range fields {
+		column, ok := s.Fields[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidOption, name)
+		}
+		quoted, err := Column(column)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(q.columns, quoted) {
+			q.columns = append(q.columns, quoted)
+		}
+	}
+	for _, quoted := range []string{q.sortCol, q.keyCol} {
+		if !slices.Contains(q.columns, quoted) {
+			q.columns = append(q.columns, quoted)
+		}
+	}
+	return q, nil
+}
+
+// sql returns the query of the page, where is the condition of the rows
+// with the placeholders of args, more placeholders are appended to args.
+// One more row than the limit is selected to know if there is a next
+// page.
+func (q *listQuery) sql(from, where string, args []any) (string, []any) {
+	placeholder := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	var conds []string
+	if where != "" {
+		conds = append(conds, "("+where+")")
+	}
+	if q.after != nil {
+		// nulls are sorted last in both directions
+		cmp := ">"
+		if q.desc {
+			cmp = "<"
+		}
+		v, k := q.after.Values[0], q.after.Values[1]
+		switch {
+		case q.sortCol == q.keyCol:
+			conds = append(conds, fmt.Sprintf("%s %s %s", q.keyCol, cmp, placeholder(k)))
+		case v == nil:
+			conds = append(conds, fmt.Sprintf("(%s IS NULL AND %s %s %s)", q.sortCol, q.keyCol, cmp, placeholder(k)))
+		default:
+			pv := placeholder(v)
+			conds = append(conds, fmt.Sprintf("(%s %s %s OR (%s = %s AND %s %s %s) OR %s IS NULL)",
+				q.sortCol, cmp, pv, q.sortCol, pv, q.keyCol, cmp, placeholder(k), q.sortCol))
+		}
+	}
+
+	dir := "ASC"
+	if q.desc {
+		dir = "DESC"
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(q.columns, ", "), from)
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s NULLS LAST", q.sortCol, dir)
+	if q.sortCol != q.keyCol {
+		query += fmt.Sprintf(", %s %s", q.keyCol, dir)
+	}
+	query += fmt.Sprintf(" LIMIT %s", placeholder(q.limit+1))
+	if q.offset > 0 {
+		query += fmt.Sprintf(" OFFSET %s", placeholder(q.offset))
+	}
+	return query, args
+}
+
+// next returns the cursor after row.
+func (q *listQuery) next(row any) (string, error) {
+	v := reflect.ValueOf(row).Elem()
+	cToF := getTypeColumnToFieldInfo(v.Type())
+	c := &cursor{Sort: q.sort}
+	for _, quoted := range []string{q.sortCol, q.keyCol} {
+		f, ok := cToF[strings.Trim(quoted, `"`)]
+		if !ok {
+			return "", fmt.Errorf("%w: %s is not a column of %s", ErrInvalidColumn, quoted, v.Type())
+		}
+		var value any
+		if field := v.Field(f.idx); !field.IsNil() {
+			value = field.Elem().Interface()
+			if t, ok := value.(time.Time); ok {
+				// keep the precision of timestamps
+				value = t.Format(time.RFC3339Nano)
+			}
+		}
+		c.Values = append(c.Values, value)
+	}
+	return encodeCursor(c)
+}
+
+// List returns a page of the rows of from matching where, sorted, limited
+// and with the fields selected by opts. where is a condition without
+// WHERE, empty for all rows, using the placeholders of args. The columns
+// of the spec must be columns of T; the fields that are not selected are
+// nil.
+func List[T any](ctx storage.AppDatabaseContext, spec *ListSpec, from, where string, opts ListOptions, args ...any) (*Page[T], error) {
+	q, err := spec.plan(opts)
+	if err != nil {
+		return nil, err
+	}
+	query, args := q.sql(from, where, args)
+	rows, err := Query[T](ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	page := &Page[T]{Items: make([]*T, 0, q.limit)}
+	for row := range rows {
+		page.Items = append(page.Items, row)
+	}
+	if len(page.Items) > q.limit {
+		page.Items = page.Items[:q.limit]
+		if page.Next, err = q.next(page.Items[q.limit-1]); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// Count returns the number of rows of from matching where, the total of
+// the pages of a list query.
+func Count(ctx storage.AppDatabaseContext, from, where string, args ...any) (int, error) {
+	query := "SELECT COUNT(*) FROM " + from
+	if where != "" {
+		query += " WHERE " + where
+	}
+	var n int
+	err := ctx.QueryRow(query, args...).Scan(&n)
+	return n, err
+}
@@ -0,0 +1,124 @@
+package sqlutil
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type listRow struct {
+	GitLink *string
+	Score   *float64
+	License *string
+}
+
+var testSpec = &ListSpec{
+	Fields:       map[string]string{"link": "git_link", "score": "score", "license": "license"},
+	Sortable:     []string{"score"},
+	DefaultSort:  "-score",
+	Key:          "link",
+	DefaultLimit: 2,
+	MaxLimit:     10,
+}
+
+func TestListQuery(t *testing.T) {
+	after, _ := encodeCursor(&cursor{Sort: "-score", Values: []any{0.5, "b"}})
+	afterNull, _ := encodeCursor(&cursor{Sort: "-score", Values: []any{nil, "b"}})
+	afterLink, _ := encodeCursor(&cursor{Sort: "link", Values: []any{"b", "b"}})
+
+	tests := []struct {
+		name  string
+		opts  ListOptions
+		query string
+		args  []any
+	}{
+		{
+			name:  "default",
+			query: `SELECT "license", "git_link", "score" FROM t WHERE (score > $1) ORDER BY "score" DESC NULLS LAST, "git_link" DESC LIMIT $2`,
+			args:  []any{0, 3},
+		},
+		{
+			name:  "offset",
+			opts:  ListOptions{Limit: 5, Offset: 10, Sort: "score", Fields: []string{"link"}},
+			query: `SELECT "git_link", "score" FROM t WHERE (score > $1) ORDER BY "score" ASC NULLS LAST, "git_link" ASC LIMIT $2 OFFSET $3`,
+			args:  []any{0, 6, 10},
+		},
+		{
+			name:  "cursor",
+			opts:  ListOptions{Cursor: after, Fields: []string{"score"}},
+			query: `SELECT "score", "git_link" FROM t WHERE (score > $1) AND ("score" < $2 OR ("score" = $2 AND "git_link" < $3) OR "score" IS NULL) ORDER BY "score" DESC NULLS LAST, "git_link" DESC LIMIT $4`,
+			args:  []any{0, "0.5", "b", 3},
+		},
+		{
+			name:  "cursor in nulls",
+			opts:  ListOptions{Cursor: afterNull, Fields: []string{"score"}},
+			query: `SELECT "score", "git_link" FROM t WHERE (score > $1) AND ("score" IS NULL AND "git_link" < $2) ORDER BY "score" DESC NULLS LAST, "git_link" DESC LIMIT $3`,
+			args:  []any{0, "b", 3},
+		},
+		{
+			name:  "sort by key",
+			opts:  ListOptions{Cursor: afterLink, Sort: "link", Fields: []string{"link"}},
+			query: `SELECT "git_link" FROM t WHERE (score > $1) AND "git_link" > $2 ORDER BY "git_link" ASC NULLS LAST LIMIT $3`,
+			args:  []any{0, "b", 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := testSpec.plan(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			query, args := q.sql("t", "score > $1", []any{0})
+			if query != tt.query {
+				t.Errorf("query = %s\nwant %s", query, tt.query)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("args = %v, want %v", args, tt.args)
+			}
+		})
+	}
+}
+
+func TestListOptionsInvalid(t *testing.T) {
+	cursor, _ := encodeCursor(&cursor{Sort: "score", Values: []any{0.5, "b"}})
+	for _, opts := range []ListOptions{
+		{Sort: "license"},
+		{Sort: "-score; DROP TABLE scores"},
+		{Fields: []string{"password"}},
+		{Limit: 11},
+		{Limit: -1},
+		{Offset: -1},
+		{Cursor: "not a cursor"},
+		{Cursor: cursor},
+		{Cursor: cursor, Sort: "score", Offset: 1},
+	} {
+		if _, err := testSpec.plan(opts); !errors.Is(err, ErrInvalidOption) {
+			t.Errorf("plan(%+v) error = %v, want ErrInvalidOption", opts, err)
+		}
+	}
+}
+
+func TestListNext(t *testing.T) {
+	q, err := testSpec.plan(ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	link, score := "https://github.com/madler/zlib", 0.75
+	next, err := q.next(&listRow{GitLink: &link, Score: &score})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := decodeCursor(next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (&cursor{Sort: "-score", Values: []any{"0.75", link}}); !reflect.DeepEqual(c, want) {
+		t.Errorf("cursor = %+v, want %+v", c, want)
+	}
+
+	// the cursor of the last row leads to the next page
+	q, err = testSpec.plan(ListOptions{Cursor: next})
+	if err != nil || q.after == nil {
+		t.Errorf("plan() of the next cursor = %+v, %v", q, err)
+	}
+}
@@ -0,0 +1,217 @@
+package sqlutil
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/lib/pq"
+)
+
+// ErrUnknownTable is returned when a table is not registered by
+// RegisterTable, which means it must not be interpolated into a query.
+var ErrUnknownTable = errors.New("unknown table")
+
+// ErrInvalidColumn is returned when a column is not a plain identifier.
+var ErrInvalidColumn = errors.New("invalid column")
+
+var identifierPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+var (
+	tablesMu sync.RWMutex
+	tables   = make(map[string]struct{})
+)
+
+// RegisterTable adds tables to the registry of names that can be used as
+// identifiers in queries. The repository package registers all of its
+// tables, so callers usually do not need to call this.
+func RegisterTable(names ...string) {
+	tablesMu.Lock()
+	defer tablesMu.Unlock()
+	for _, name := range names {
+		if !identifierPattern.MatchString(name) {
+			panic(fmt.Sprintf("sqlutil: invalid table name %q", name))
+		}
+		tables[name] = struct{}{}
+	}
+}
+
+// Tables returns the sorted names of all registered tables.
+func Tables() []string {
+	tablesMu.RLock()
+	defer tablesMu.RUnlock()
+	ret := make([]string, 0, len(tables))
+	for name := range tables {
+		ret = append(ret, name)
+	}
+	slices.Sort(ret)
+	return ret
+}
+
+// Table returns the quoted identifier of a registered table, table names
+// which come from flags or loops over distributions must be passed
+// through Table instead of being formatted into the query directly.
+func Table(name string) (string, error) {
+	tablesMu.RLock()
+	_, ok := tables[name]
+	tablesMu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownTable, name)
+	}
+	return pq.QuoteIdentifier(name), nil
+}
+
+// Column returns the quoted identifier of a column, the column must be a
+// plain lower case identifier.
+func Column(name string) (string, error) {
+	if !identifierPattern.MatchString(name) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidColumn, name)
+	}
+	return pq.QuoteIdentifier(name), nil
+}
+
+// Builder builds a statement on a registered table. Only the table and
+// column identifiers are written into the query, they are checked by
+// Table and Column, and every value becomes a placeholder.
+type Builder struct {
+	table   string
+	err     error
+	where   []string
+	args    []interface{}
+	orderBy []string
+	limit   int
+}
+
+// From starts a statement on table, the error of an unknown table is
+// returned when the statement is built.
+func From(table string) *Builder {
+	quoted, err := Table(table)
+	return &Builder{table: quoted, err: err}
+}
+
+func (b *Builder) column(name string) string {
+	quoted, err := Column(name)
+	if err != nil && b.err == nil {
+		b.err = err
+	}
+	return quoted
+}
+
+func (b *Builder) placeholder(value interface{}) string {
+	b.args = append(b.args, value)
+	return fmt.Sprintf("$%d", len(b.args))
+}
+
+// Where adds the condition column = value.
+func (b *Builder) Where(column string, value interface{}) *Builder {
+	b.where = append(b.where, fmt.Sprintf("%s = %s", b.column(column), b.placeholder(value)))
+	return b
+}
+
+// WhereAny adds the condition column = ANY(values).
+func (b *Builder) WhereAny(column string, values []string) *Builder {
+	b.where = append(b.where, fmt.Sprintf("%s = ANY(%s)", b.column(column), b.placeholder(pq.Array(values))))
+	return b
+}
+
+// OrderBy sorts the result by the columns in ascending order.
+func (b *Builder) OrderBy(columns ...string) *Builder {
+	for _, c := range columns {
+		b.orderBy = append(b.orderBy, b.column(c))
+	}
+	return b
+}
+
+// Limit limits the number of rows, zero means no limit.
+func (b *Builder) Limit(n int) *Builder {
+	b.limit = n
+	return b
+}
+
+func (b *Builder) whereClause() string {
+	if len(b.where) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(b.where, " AND ")
+}
+
+// Select returns the query selecting columns and its arguments.
+func (b *Builder) Select(columns ...string) (string, []interface{}, error) {
+	cols := make([]string, 0, len(columns))
+	for _, c := range columns {
+		cols = append(cols, b.column(c))
+	}
+	if b.err != nil {
+		return "", nil, b.err
+	}
+	if len(cols) == 0 {
+		return "", nil, fmt.Errorf("%w: no column to select", ErrInvalidColumn)
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s%s", strings.Join(cols, ", "), b.table, b.whereClause())
+	if len(b.orderBy) > 0 {
+		query += " ORDER BY " + strings.Join(b.orderBy, ", ")
+	}
+	if b.limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", b.limit)
+	}
+	return query, b.args, nil
+}
+
+// Exists returns the query checking whether any row matches.
+func (b *Builder) Exists() (string, []interface{}, error) {
+	if b.err != nil {
+		return "", nil, b.err
+	}
+	return fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s%s)", b.table, b.whereClause()), b.args, nil
+}
+
+// Update returns the query setting column to value on the matched rows,
+// the conditions must be added before Update is called.
+func (b *Builder) Update(column string, value interface{}) (string, []interface{}, error) {
+	col := b.column(column)
+	if b.err != nil {
+		return "", nil, b.err
+	}
+	// the value is the last placeholder, the conditions keep their numbers
+	set := b.placeholder(value)
+	return fmt.Sprintf("UPDATE %s SET %s = %s%s", b.table, col, set, b.whereClause()), b.args, nil
+}
+
+// Delete returns the query deleting the matched rows, or all rows if no
+// condition is added.
+func (b *Builder) Delete() (string, []interface{}, error) {
+	if b.err != nil {
+		return "", nil, b.err
+	}
+	return fmt.Sprintf("DELETE FROM %s%s", b.table, b.whereClause()), b.args, nil
+}
+
+// InsertValues returns the query inserting rows into columns, each row
+// must have one value per column.
+func (b *Builder) InsertValues(columns []string, rows [][]interface{}) (string, []interface{}, error) {
+	cols := make([]string, 0, len(columns))
+	for _, c := range columns {
+		cols = append(cols, b.column(c))
+	}
+	if b.err != nil {
+		return "", nil, b.err
+	}
+	if len(cols) == 0 || len(rows) == 0 {
+		return "", nil, fmt.Errorf("%w: nothing to insert", ErrInvalidColumn)
+	}
+	values := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if len(row) != len(cols) {
+			return "", nil, fmt.Errorf("%w: got %d values for %d columns", ErrInvalidColumn, len(row), len(cols))
+		}
+		ph := make([]string, 0, len(row))
+		for _, v := range row {
+			ph = append(ph, b.placeholder(v))
+		}
+		values = append(values, "("+strings.Join(ph, ", ")+")")
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", b.table, strings.Join(cols, ", "), strings.Join(values, ", ")), b.args, nil
+}
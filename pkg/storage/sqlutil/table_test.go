@@ -0,0 +1,102 @@
+package sqlutil
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func init() {
+	RegisterTable("debian_packages")
+}
+
+func TestTable(t *testing.T) {
+	got, err := Table("debian_packages")
+	if err != nil || got != `"debian_packages"` {
+		t.Errorf("Table() = %q, %v", got, err)
+	}
+
+	for _, name := range []string{"unknown", "debian_packages; DROP TABLE scores", `debian_packages"`} {
+		if _, err := Table(name); !errors.Is(err, ErrUnknownTable) {
+			t.Errorf("Table(%q) error = %v, want ErrUnknownTable", name, err)
+		}
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func() (string, []interface{}, error)
+		query string
+		args  []interface{}
+		err   error
+	}{
+		{
+			name: "select",
+			build: func() (string, []interface{}, error) {
+				return From("debian_packages").Where("package", "vim").OrderBy("git_link").Limit(1).Select("package", "git_link")
+			},
+			query: `SELECT "package", "git_link" FROM "debian_packages" WHERE "package" = $1 ORDER BY "git_link" LIMIT 1`,
+			args:  []interface{}{"vim"},
+		},
+		{
+			name: "update",
+			build: func() (string, []interface{}, error) {
+				return From("debian_packages").Where("package", "vim").Update("git_link", "https://github.com/vim/vim.git")
+			},
+			query: `UPDATE "debian_packages" SET "git_link" = $2 WHERE "package" = $1`,
+			args:  []interface{}{"vim", "https://github.com/vim/vim.git"},
+		},
+		{
+			name: "insert",
+			build: func() (string, []interface{}, error) {
+				return From("debian_packages").InsertValues([]string{"package"}, [][]interface{}{{"vim"}, {"nano"}})
+			},
+			query: `INSERT INTO "debian_packages" ("package") VALUES ($1), ($2)`,
+			args:  []interface{}{"vim", "nano"},
+		},
+		{
+			name: "delete all",
+			build: func() (string, []interface{}, error) {
+				return From("debian_packages").Delete()
+			},
+			query: `DELETE FROM "debian_packages"`,
+		},
+		{
+			name: "unknown table",
+			build: func() (string, []interface{}, error) {
+				return From("users").Select("id")
+			},
+			err: ErrUnknownTable,
+		},
+		{
+			name: "invalid column",
+			build: func() (string, []interface{}, error) {
+				return From("debian_packages").Where("package = package OR 1", 1).Select("package")
+			},
+			err: ErrInvalidColumn,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args, err := tt.build()
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Errorf("error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if query != tt.query {
+				t.Errorf("query = %s, want %s", query, tt.query)
+			}
+			if len(args) != 0 || len(tt.args) != 0 {
+				if !reflect.DeepEqual(args, tt.args) {
+					t.Errorf("args = %v, want %v", args, tt.args)
+				}
+			}
+		})
+	}
+}
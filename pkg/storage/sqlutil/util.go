@@ -28,6 +28,12 @@ func getFieldColumnName(f reflect.StructField) string {
 	return column
 }
 
+// ColumnName returns the column name of a struct field, which is the
+// column tag or the snake case of the field name.
+func ColumnName(f reflect.StructField) string {
+	return getFieldColumnName(f)
+}
+
 type fieldInfo struct {
 	idx         int
 	isPk        bool
@@ -339,6 +345,9 @@ func QueryCommonFirst[T any](ctx storage.AppDatabaseContext, from string, afterF
 }
 
 func Insert[T any](ctx storage.AppDatabaseContext, into string, data *T) error {
+	if _, err := Table(into); err != nil {
+		return err
+	}
 	insertSentence, values, err := getInsertQueryAndArgs[T](into, data)
 	if err != nil {
 		return err
@@ -348,22 +357,30 @@ func Insert[T any](ctx storage.AppDatabaseContext, into string, data *T) error {
 }
 
 func BatchInsert[T any](ctx storage.AppDatabaseContext, into string, data []*T) error {
+	if _, err := Table(into); err != nil {
+		return err
+	}
 	batchCtx := ctx.NewBatchExecContext(&storage.BatchExecContextConfig{
 		AutoCommit:     true,
 		AutoCommitSize: 1000,
 	})
-	defer batchCtx.Commit()
 	for _, d := range data {
 		insertSentence, args, err := getInsertQueryAndArgs[T](into, d)
 		if err != nil {
 			return err
 		}
-		batchCtx.AppendExec(insertSentence, args...)
+		if err := batchCtx.AppendExec(insertSentence, args...); err != nil {
+			return err
+		}
 	}
-	return nil
+	_, err := batchCtx.Commit()
+	return err
 }
 
 func Update[T any](ctx storage.AppDatabaseContext, tableName string, data *T) error {
+	if _, err := Table(tableName); err != nil {
+		return err
+	}
 	updateSentence, values, err := getUpdateQueryAndArgs[T](tableName, data)
 	if err != nil {
 		return err
@@ -373,22 +390,30 @@ func Update[T any](ctx storage.AppDatabaseContext, tableName string, data *T) er
 }
 
 func BatchUpdate[T any](ctx storage.AppDatabaseContext, tableName string, data []*T) error {
+	if _, err := Table(tableName); err != nil {
+		return err
+	}
 	batchCtx := ctx.NewBatchExecContext(&storage.BatchExecContextConfig{
 		AutoCommit:     true,
 		AutoCommitSize: 1000,
 	})
-	defer batchCtx.Commit()
 	for _, d := range data {
 		updateSentence, args, err := getUpdateQueryAndArgs[T](tableName, d)
 		if err != nil {
 			return err
 		}
-		batchCtx.AppendExec(updateSentence, args...)
+		if err := batchCtx.AppendExec(updateSentence, args...); err != nil {
+			return err
+		}
 	}
-	return nil
+	_, err := batchCtx.Commit()
+	return err
 }
 
 func Delete[T any](ctx storage.AppDatabaseContext, tableName string, data *T) error {
+	if _, err := Table(tableName); err != nil {
+		return err
+	}
 	deleteSentence, values, err := getDeleteQueryAndArgs[T](tableName, data)
 	if err != nil {
 		return err
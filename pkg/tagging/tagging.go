@@ -0,0 +1,121 @@
+// Package tagging narrows down the repositories handled by a command to the
+// projects with some tags, so that a tenant, e.g. a company scoring its own
+// dependencies, can run the pipeline separately from the global set.
+//
+// Tags are managed with the project-tags tool and stored in the
+// project_tags table. Commands usually use the default filter, which is
+// initialized by config.ParseFlags when config.RegistTagFlags is called:
+//
+//	links = tagging.Slice(links)
+package tagging
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/storage"
+	"github.com/HUSTSecLab/criticality_score/pkg/storage/repository"
+)
+
+type Config struct {
+	// Tags keeps projects with any of the tags, empty keeps all projects
+	Tags []string
+}
+
+var validTag = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.:-]*$`)
+
+// Validate returns an error if tag is not a valid tag name, tags are used in
+// file names of reports, so only letters, digits and _.:- are allowed.
+func Validate(tag string) error {
+	if !validTag.MatchString(tag) {
+		return fmt.Errorf("invalid tag %q, only letters, digits and _.:- are allowed", tag)
+	}
+	return nil
+}
+
+type Filter struct {
+	tags  []string
+	links map[string]bool
+}
+
+// NewFilter loads the links of the tags in config.
+func NewFilter(ac storage.AppDatabaseContext, config *Config) (*Filter, error) {
+	if config == nil || len(config.Tags) == 0 {
+		return &Filter{}, nil
+	}
+	for _, tag := range config.Tags {
+		if err := Validate(tag); err != nil {
+			return nil, err
+		}
+	}
+
+	links, err := repository.NewProjectTagRepository(ac).QueryLinks(config.Tags)
+	if err != nil {
+		return nil, err
+	}
+	return NewFilterOf(config.Tags, links), nil
+}
+
+// NewFilterOf returns a filter keeping the links.
+func NewFilterOf(tags []string, links []string) *Filter {
+	f := &Filter{tags: tags, links: make(map[string]bool, len(links))}
+	for _, link := range links {
+		f.links[link] = true
+	}
+	return f
+}
+
+// Enabled returns true if the filter may drop some items.
+func (f *Filter) Enabled() bool {
+	return f != nil && f.links != nil
+}
+
+// Tags returns the tags of the filter.
+func (f *Filter) Tags() []string {
+	if f == nil {
+		return nil
+	}
+	return f.tags
+}
+
+// Contains returns true if the link is kept by the filter.
+func (f *Filter) Contains(link string) bool {
+	return !f.Enabled() || f.links[link]
+}
+
+// SliceFunc returns the items whose link is kept by the filter.
+func SliceFunc[T any](f *Filter, items []T, link func(T) string) []T {
+	if !f.Enabled() {
+		return items
+	}
+	ret := make([]T, 0)
+	for _, item := range items {
+		if f.links[link(item)] {
+			ret = append(ret, item)
+		}
+	}
+	return ret
+}
+
+var defaultFilter *Filter
+
+// InitDefault initializes the default filter used by Slice.
+func InitDefault(ac storage.AppDatabaseContext, config *Config) error {
+	f, err := NewFilter(ac, config)
+	if err != nil {
+		return err
+	}
+	defaultFilter = f
+	return nil
+}
+
+// Default returns the default filter, if it is not initialized, filtering
+// is disabled.
+func Default() *Filter {
+	return defaultFilter
+}
+
+// Slice filters links with the default filter.
+func Slice(links []string) []string {
+	return SliceFunc(defaultFilter, links, func(s string) string { return s })
+}
@@ -0,0 +1,44 @@
+package tagging
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := map[string]bool{
+		"acme":           true,
+		"acme:prod-deps": true,
+		"v1.2_deps":      true,
+		"":               false,
+		"-acme":          false,
+		"acme deps":      false,
+		"../etc":         false,
+	}
+	for tag, valid := range tests {
+		if err := Validate(tag); (err == nil) != valid {
+			t.Errorf("Validate(%q) error = %v, want valid %v", tag, err, valid)
+		}
+	}
+}
+
+func TestSlice(t *testing.T) {
+	links := []string{"https://github.com/a/a", "https://github.com/b/b", "https://github.com/c/c"}
+
+	var disabled *Filter
+	if got := SliceFunc(disabled, links, func(s string) string { return s }); !reflect.DeepEqual(got, links) {
+		t.Errorf("disabled filter = %v, want %v", got, links)
+	}
+
+	f := NewFilterOf([]string{"acme"}, []string{"https://github.com/c/c", "https://github.com/a/a"})
+	want := []string{"https://github.com/a/a", "https://github.com/c/c"}
+	if got := SliceFunc(f, links, func(s string) string { return s }); !reflect.DeepEqual(got, want) {
+		t.Errorf("SliceFunc() = %v, want %v", got, want)
+	}
+
+	// a tag without links keeps nothing
+	empty := NewFilterOf([]string{"none"}, nil)
+	if got := SliceFunc(empty, links, func(s string) string { return s }); len(got) != 0 {
+		t.Errorf("empty tag = %v, want nothing", got)
+	}
+}
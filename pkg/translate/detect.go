@@ -0,0 +1,61 @@
+package translate
+
+import "unicode"
+
+// scriptLanguages are the scripts of letters with the language they are
+// guessed to be written in, the first matching script of a letter counts.
+var scriptLanguages = []struct {
+	script   *unicode.RangeTable
+	language string
+	// weight is the number of latin letters a letter is worth, as letters
+	// of ideographic and syllabic scripts are about a word
+	weight int
+}{
+	{unicode.Hiragana, "ja", 3},
+	{unicode.Katakana, "ja", 3},
+	{unicode.Han, "zh", 3},
+	{unicode.Hangul, "ko", 3},
+	{unicode.Cyrillic, "ru", 1},
+	{unicode.Arabic, "ar", 1},
+	{unicode.Greek, "el", 1},
+	{unicode.Hebrew, "he", 1},
+	{unicode.Thai, "th", 3},
+	{unicode.Devanagari, "hi", 1},
+	{unicode.Latin, "en", 1},
+}
+
+// Detect guesses the language of text, a BCP 47 base tag, e.g. zh, from the
+// scripts of its letters, "" if it has none. Latin is taken for English,
+// the language of almost all package descriptions, and Cyrillic for
+// Russian. Han is Chinese unless the text has kana, which only Japanese
+// mixes with Han, so technical terms in latin letters within a Chinese
+// description do not make it English.
+func Detect(text string) string {
+	weights := make(map[string]int)
+	kana := false
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		for _, s := range scriptLanguages {
+			if unicode.Is(s.script, r) {
+				weights[s.language] += s.weight
+				kana = kana || s.language == "ja"
+				break
+			}
+		}
+	}
+	if kana {
+		weights["ja"] += weights["zh"]
+		delete(weights, "zh")
+	}
+
+	ret, max := "", 0
+	// in the order of scripts, so ties are stable
+	for _, s := range scriptLanguages {
+		if w := weights[s.language]; w > max {
+			ret, max = s.language, w
+		}
+	}
+	return ret
+}
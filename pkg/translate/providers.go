@@ -0,0 +1,132 @@
+package translate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+)
+
+// LibreTranslate is a provider by the api of LibreTranslate.
+type LibreTranslate struct {
+	client  *http.Client
+	baseURL string
+	apiKey  string
+}
+
+var _ Provider = (*LibreTranslate)(nil)
+
+// NewLibreTranslate returns a provider sending requests to the LibreTranslate
+// instance at baseURL by client, apiKey is only needed by instances
+// requiring one.
+func NewLibreTranslate(client *http.Client, baseURL, apiKey string) *LibreTranslate {
+	return &LibreTranslate{client: client, baseURL: strings.TrimSuffix(baseURL, "/"), apiKey: apiKey}
+}
+
+// Translate implements Provider.
+func (l *LibreTranslate) Translate(ctx context.Context, text, from, to string) (string, error) {
+	source := "auto"
+	if from != "" {
+		source = base(from)
+	}
+	req := map[string]string{"q": text, "source": source, "target": base(to), "format": "text"}
+	if l.apiKey != "" {
+		req["api_key"] = l.apiKey
+	}
+	var resp struct {
+		TranslatedText string `json:"translatedText"`
+		Error          string `json:"error"`
+	}
+	if err := post(ctx, l.client, l.baseURL+"/translate", req, &resp); err != nil {
+		return "", err
+	}
+	if resp.Error != "" {
+		return "", fmt.Errorf("libretranslate: %s", resp.Error)
+	}
+	return resp.TranslatedText, nil
+}
+
+// LLM is a provider by the generate api of ollama.
+type LLM struct {
+	client  *http.Client
+	baseURL string
+	model   string
+}
+
+var _ Provider = (*LLM)(nil)
+
+// NewLLM returns a provider prompting model of the ollama server at baseURL
+// by client.
+func NewLLM(client *http.Client, baseURL, model string) *LLM {
+	return &LLM{client: client, baseURL: strings.TrimSuffix(baseURL, "/"), model: model}
+}
+
+// llmPrompt asks for the bare translation, as the response is served as it
+// is.
+const llmPrompt = "Translate the following description of a software package into %s. " +
+	"Keep names of software, commands and technical terms as they are. " +
+	"Reply with the translation only, without quotes or explanations.\n\n%s"
+
+// Translate implements Provider.
+func (l *LLM) Translate(ctx context.Context, text, from, to string) (string, error) {
+	name := to
+	if tag, err := language.Parse(to); err == nil {
+		name = display.English.Tags().Name(tag)
+	}
+	req := map[string]any{
+		"model":  l.model,
+		"prompt": fmt.Sprintf(llmPrompt, name, text),
+		"stream": false,
+	}
+	var resp struct {
+		Response string `json:"response"`
+		Error    string `json:"error"`
+	}
+	if err := post(ctx, l.client, l.baseURL+"/api/generate", req, &resp); err != nil {
+		return "", err
+	}
+	if resp.Error != "" {
+		return "", fmt.Errorf("llm: %s", resp.Error)
+	}
+	return strings.TrimSpace(resp.Response), nil
+}
+
+// base returns the base language of tag, e.g. zh of zh-CN, as providers
+// only know base languages.
+func base(tag string) string {
+	t, err := language.Parse(tag)
+	if err != nil {
+		return tag
+	}
+	b, _ := t.Base()
+	return b.String()
+}
+
+// post posts body as json to url and decodes the json response into v.
+func post(ctx context.Context, client *http.Client, url string, body, v any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		return fmt.Errorf("%s: %s: %s", url, resp.Status, bytes.TrimSpace(msg))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,235 @@
+// Package translate detects the language of package descriptions, and
+// translates them by a configured provider, so the API can serve them in
+// the language of its users, e.g. Chinese, besides the English most
+// descriptions are written in.
+package translate
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+const (
+	// ProviderLibreTranslate translates by the api of LibreTranslate, which
+	// can be self-hosted
+	ProviderLibreTranslate = "libretranslate"
+	// ProviderLLM translates by the generate api of ollama, as the LLM of
+	// the industry classifier
+	ProviderLLM = "llm"
+)
+
+// Providers are the names of the providers.
+var Providers = []string{ProviderLibreTranslate, ProviderLLM}
+
+// DefaultTimeout is the timeout of a translation.
+const DefaultTimeout = 30 * time.Second
+
+type Config struct {
+	// Language is the language descriptions are translated to when a
+	// request does not ask for one, a BCP 47 tag, e.g. zh, none if empty
+	Language string
+	// Provider is the name of the provider, translation is disabled if
+	// empty
+	Provider string
+	// URL is the base url of the provider
+	URL    string
+	APIKey string
+	// Model is the model of ProviderLLM
+	Model   string
+	Timeout time.Duration
+	// CacheSize is the number of translations kept in memory, the least
+	// recently used ones are evicted
+	CacheSize int
+}
+
+// Provider translates texts.
+type Provider interface {
+	// Translate translates text from the language from, "" if unknown, to
+	// the language to, both BCP 47 tags
+	Translate(ctx context.Context, text, from, to string) (string, error)
+}
+
+// Description is a description with its language and its translation.
+type Description struct {
+	Text string
+	// Language is the detected language of Text, "" if unknown
+	Language string
+	// Translation is Text in TranslationLanguage, empty if Text is already
+	// in the language asked for or it was not translated
+	Translation         string
+	TranslationLanguage string
+}
+
+// Translator translates descriptions, a nil translator only detects their
+// language.
+type Translator struct {
+	provider Provider
+	language string
+
+	mu        sync.Mutex
+	cacheSize int
+	items     map[cacheKey]*list.Element
+	order     *list.List
+}
+
+type cacheKey struct {
+	text, to string
+}
+
+type cacheEntry struct {
+	key         cacheKey
+	translation string
+}
+
+// New returns a translator of config, nil if translation is disabled.
+// Requests are sent by client, a client with config.Timeout if nil.
+func New(config *Config, client *http.Client) (*Translator, error) {
+	if config == nil || config.Provider == "" {
+		return nil, nil
+	}
+	if config.Language != "" {
+		if _, err := language.Parse(config.Language); err != nil {
+			return nil, fmt.Errorf("invalid language %q: %w", config.Language, err)
+		}
+	}
+	if client == nil {
+		timeout := config.Timeout
+		if timeout <= 0 {
+			timeout = DefaultTimeout
+		}
+		client = &http.Client{Timeout: timeout}
+	}
+
+	var p Provider
+	switch config.Provider {
+	case ProviderLibreTranslate:
+		p = NewLibreTranslate(client, config.URL, config.APIKey)
+	case ProviderLLM:
+		p = NewLLM(client, config.URL, config.Model)
+	default:
+		return nil, fmt.Errorf("unknown translation provider: %s", config.Provider)
+	}
+	return NewWithProvider(p, config.Language, config.CacheSize), nil
+}
+
+// NewWithProvider returns a translator by p to the default language,
+// caching cacheSize translations.
+func NewWithProvider(p Provider, language string, cacheSize int) *Translator {
+	return &Translator{
+		provider:  p,
+		language:  language,
+		cacheSize: cacheSize,
+		items:     make(map[cacheKey]*list.Element),
+		order:     list.New(),
+	}
+}
+
+var defaultTranslator *Translator
+
+// InitDefault initializes the default translator, it is called by
+// config.ParseFlags when config.RegistTranslationFlags is called.
+func InitDefault(config *Config) error {
+	t, err := New(config, nil)
+	if err != nil {
+		return err
+	}
+	defaultTranslator = t
+	return nil
+}
+
+// Default returns the default translator, nil if translation is disabled.
+func Default() *Translator {
+	return defaultTranslator
+}
+
+// Language returns the default language of the translations, "" if
+// descriptions are not translated by default.
+func (t *Translator) Language() string {
+	if t == nil {
+		return ""
+	}
+	return t.language
+}
+
+// SameLanguage returns true if the BCP 47 tags a and b are of the same
+// language, e.g. zh and zh-CN.
+func SameLanguage(a, b string) bool {
+	ta, err := language.Parse(a)
+	if err != nil {
+		return false
+	}
+	tb, err := language.Parse(b)
+	if err != nil {
+		return false
+	}
+	ba, _ := ta.Base()
+	bb, _ := tb.Base()
+	return ba == bb
+}
+
+// Translate detects the language of text, and translates it to the
+// language to, the default language if empty. Text is only translated if
+// the translator is enabled and its language is not to. The description
+// is returned with the error of a failed translation, without the
+// translation.
+func (t *Translator) Translate(ctx context.Context, text, to string) (*Description, error) {
+	d := &Description{Text: text, Language: Detect(text)}
+	if t == nil || text == "" {
+		return d, nil
+	}
+	if to == "" {
+		to = t.language
+	}
+	if to == "" || d.Language != "" && SameLanguage(d.Language, to) {
+		return d, nil
+	}
+
+	key := cacheKey{text: text, to: to}
+	if translation, ok := t.get(key); ok {
+		d.Translation, d.TranslationLanguage = translation, to
+		return d, nil
+	}
+	translation, err := t.provider.Translate(ctx, text, d.Language, to)
+	if err != nil {
+		return d, err
+	}
+	t.put(key, translation)
+	d.Translation, d.TranslationLanguage = translation, to
+	return d, nil
+}
+
+func (t *Translator) get(key cacheKey) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e, ok := t.items[key]
+	if !ok {
+		return "", false
+	}
+	t.order.MoveToFront(e)
+	return e.Value.(*cacheEntry).translation, true
+}
+
+func (t *Translator) put(key cacheKey, translation string) {
+	if t.cacheSize <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if e, ok := t.items[key]; ok {
+		e.Value.(*cacheEntry).translation = translation
+		t.order.MoveToFront(e)
+		return
+	}
+	t.items[key] = t.order.PushFront(&cacheEntry{key: key, translation: translation})
+	for t.order.Len() > t.cacheSize {
+		oldest := t.order.Back()
+		t.order.Remove(oldest)
+		delete(t.items, oldest.Value.(*cacheEntry).key)
+	}
+}
@@ -0,0 +1,118 @@
+package translate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"GNU C Library: Shared libraries", "en"},
+		{"一个用于 Linux 的高性能 HTTP 服务器", "zh"},
+		{"Go 语言编写的分布式 key-value 存储", "zh"},
+		{"高速な JSON パーサー", "ja"},
+		{"한국어 입력기", "ko"},
+		{"Утилита для резервного копирования", "ru"},
+		{"1.2.3 - 2024", ""},
+	}
+	for _, tt := range tests {
+		if got := Detect(tt.text); got != tt.want {
+			t.Errorf("Detect(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+// fakeProvider translates to the target language tag and counts calls.
+type fakeProvider struct {
+	calls int
+	err   error
+}
+
+func (f *fakeProvider) Translate(ctx context.Context, text, from, to string) (string, error) {
+	f.calls++
+	return "[" + from + "->" + to + "] " + text, f.err
+}
+
+func TestTranslate(t *testing.T) {
+	ctx := context.Background()
+	p := &fakeProvider{}
+	tr := NewWithProvider(p, "zh", 10)
+
+	d, err := tr.Translate(ctx, "A fast HTTP server", "")
+	if err != nil || d.Language != "en" || d.Translation != "[en->zh] A fast HTTP server" || d.TranslationLanguage != "zh" {
+		t.Fatalf("Translate() = %+v, %v", d, err)
+	}
+	// cached
+	if _, err := tr.Translate(ctx, "A fast HTTP server", ""); err != nil || p.calls != 1 {
+		t.Errorf("calls = %d, %v, want cached", p.calls, err)
+	}
+	// already in the language
+	d, _ = tr.Translate(ctx, "高性能服务器", "zh-CN")
+	if d.Translation != "" || p.calls != 1 {
+		t.Errorf("Translate() of chinese to zh-CN = %+v", d)
+	}
+	// another language asked for
+	d, _ = tr.Translate(ctx, "高性能服务器", "en")
+	if d.Translation != "[zh->en] 高性能服务器" || p.calls != 2 {
+		t.Errorf("Translate() to en = %+v", d)
+	}
+
+	// failed translations keep the description
+	p.err = errors.New("unavailable")
+	d, err = tr.Translate(ctx, "Another server", "")
+	if err == nil || d.Text != "Another server" || d.Translation != "" {
+		t.Errorf("Translate() failing = %+v, %v", d, err)
+	}
+
+	// a nil translator only detects
+	var none *Translator
+	d, err = none.Translate(ctx, "A fast HTTP server", "zh")
+	if err != nil || d.Language != "en" || d.Translation != "" || none.Language() != "" {
+		t.Errorf("nil Translate() = %+v, %v", d, err)
+	}
+}
+
+func TestProviders(t *testing.T) {
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = nil
+		json.NewDecoder(r.Body).Decode(&got)
+		switch r.URL.Path {
+		case "/translate":
+			w.Write([]byte(`{"translatedText": "快速的 HTTP 服务器"}`))
+		case "/api/generate":
+			w.Write([]byte(`{"response": " 快速的 HTTP 服务器\n", "done": true}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	s, err := NewLibreTranslate(srv.Client(), srv.URL+"/", "key").Translate(ctx, "A fast HTTP server", "", "zh-CN")
+	if err != nil || s != "快速的 HTTP 服务器" {
+		t.Errorf("LibreTranslate = %q, %v", s, err)
+	}
+	if got["source"] != "auto" || got["target"] != "zh" || got["api_key"] != "key" {
+		t.Errorf("LibreTranslate request = %v", got)
+	}
+
+	s, err = NewLLM(srv.Client(), srv.URL, "qwen").Translate(ctx, "A fast HTTP server", "en", "zh")
+	if err != nil || s != "快速的 HTTP 服务器" {
+		t.Errorf("LLM = %q, %v", s, err)
+	}
+	if got["model"] != "qwen" || got["stream"] != false {
+		t.Errorf("LLM request = %v", got)
+	}
+
+	if _, err := NewLLM(srv.Client(), srv.URL+"/missing", "qwen").Translate(ctx, "x", "en", "zh"); err == nil {
+		t.Error("LLM of a missing server did not fail")
+	}
+}
@@ -0,0 +1,128 @@
+// Package wikidata finds the Wikidata items of repos and their number of
+// sitelinks, i.e. of Wikipedia articles and other Wikimedia pages about
+// them, as a public-visibility signal for scoring experiments.
+//
+// All software items with a source code repository (P1324), or whose
+// official website (P856) is a repo on a forge, are fetched by one SPARQL
+// query into an Index, and repos are matched by their normalized urls.
+package wikidata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/HUSTSecLab/criticality_score/pkg/gitutil/normalize"
+)
+
+const DefaultEndpoint = "https://query.wikidata.org/sparql"
+
+// userAgent identifies the collector, as required by the user agent policy
+// of Wikimedia.
+const userAgent = "criticality_score (https://github.com/HUSTSecLab/criticality_score)"
+
+// query selects the items with a repo, official websites are only taken if
+// they are on a forge, most websites are not repos.
+const query = `SELECT ?item ?url ?sitelinks WHERE {
+  { ?item wdt:P1324 ?url . }
+  UNION
+  { ?item wdt:P856 ?url .
+    FILTER(REGEX(STR(?url), "^https?://(www\\.)?(github\\.com|gitlab\\.com|bitbucket\\.org|codeberg\\.org|gitee\\.com)/[^/]+/[^/]+/?$")) }
+  ?item wikibase:sitelinks ?sitelinks .
+}`
+
+// Item is a Wikidata item.
+type Item struct {
+	// ID is the id of the item, e.g. Q2005 for the Linux kernel
+	ID string
+	// Sitelinks is the number of Wikipedia articles and other Wikimedia
+	// pages about the item
+	Sitelinks int
+}
+
+// Index finds the items of repos.
+type Index struct {
+	// items maps the normalize.Key of repos to their items
+	items map[string]Item
+}
+
+// sparqlResults is the SPARQL 1.1 json results format.
+type sparqlResults struct {
+	Results struct {
+		Bindings []map[string]struct {
+			Value string `json:"value"`
+		} `json:"bindings"`
+	} `json:"results"`
+}
+
+// ReadIndex reads the index from the json results of the query. If several
+// items claim a repo, the one with the most sitelinks wins, e.g. a software
+// and its developer.
+func ReadIndex(r io.Reader) (*Index, error) {
+	var results sparqlResults
+	if err := json.NewDecoder(r).Decode(&results); err != nil {
+		return nil, fmt.Errorf("decoding sparql results: %w", err)
+	}
+
+	ret := &Index{items: map[string]Item{}}
+	for _, b := range results.Results.Bindings {
+		key, err := normalize.Key(b["url"].Value)
+		if err != nil {
+			continue
+		}
+		sitelinks, _ := strconv.Atoi(b["sitelinks"].Value)
+		item := Item{
+			// items are entity urls, e.g. http://www.wikidata.org/entity/Q2005
+			ID:        b["item"].Value[strings.LastIndex(b["item"].Value, "/")+1:],
+			Sitelinks: sitelinks,
+		}
+		if old, ok := ret.items[key]; !ok || item.Sitelinks > old.Sitelinks {
+			ret.items[key] = item
+		}
+	}
+	return ret, nil
+}
+
+// LoadIndex runs the query at endpoint, DefaultEndpoint if empty.
+func LoadIndex(ctx context.Context, client *http.Client, endpoint string) (*Index, error) {
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint,
+		strings.NewReader(url.Values{"query": {query}}.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/sparql-results+json")
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("querying %s: %s", endpoint, resp.Status)
+	}
+	return ReadIndex(resp.Body)
+}
+
+// Len returns the number of repos with an item.
+func (x *Index) Len() int {
+	return len(x.items)
+}
+
+// Lookup returns the item of the repo of link.
+func (x *Index) Lookup(link string) (Item, bool) {
+	key, err := normalize.Key(link)
+	if err != nil {
+		return Item{}, false
+	}
+	item, ok := x.items[key]
+	return item, ok
+}
@@ -0,0 +1,66 @@
+package wikidata
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIndex(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "results.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	index, err := ReadIndex(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", index.Len())
+	}
+
+	tests := []struct {
+		link string
+		want Item
+		ok   bool
+	}{
+		{"https://git.kernel.org/pub/scm/linux/kernel/git/torvalds/linux", Item{"Q2005", 212}, true},
+		// the item with the most sitelinks wins
+		{"git@github.com:Curl/curl.git", Item{"Q286306", 48}, true},
+		{"https://github.com/python/cpython", Item{"Q28865", 3}, true},
+		{"https://github.com/foo/bar", Item{}, false},
+	}
+	for _, tt := range tests {
+		if got, ok := index.Lookup(tt.link); got != tt.want || ok != tt.ok {
+			t.Errorf("Lookup(%q) = %+v, %v, want %+v, %v", tt.link, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestLoadIndex(t *testing.T) {
+	results, err := os.ReadFile(filepath.Join("testdata", "results.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.PostFormValue("query"), "wdt:P1324") || r.UserAgent() != userAgent {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Write(results)
+	}))
+	defer server.Close()
+
+	index, err := LoadIndex(context.Background(), server.Client(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := index.Lookup("https://github.com/curl/curl"); !ok {
+		t.Error("curl has no item")
+	}
+}